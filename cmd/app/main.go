@@ -17,10 +17,11 @@ func main() {
 	log := logger.NewLogger(config)
 	redis := redis.NewRedis(log, config)
     sqlDB := database.NewDatabase(log, config)
+    replicas := database.NewReplicas(log, config)
 	monitoring := monitor.NewMonitoring(log, config)
 	validation := validation.NewValidation()
 	defer monitoring.Shutdown()
 
-    server := app.NewApp(log, config, sqlDB, web, validation, redis)
+    server := app.NewApp(log, config, sqlDB, replicas, web, validation, redis)
 	server.Run()
 }
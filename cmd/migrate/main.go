@@ -0,0 +1,146 @@
+// Command migrate applies the migrations embedded from db/migration to the
+// database in config.yml, without the external migrate CLI:
+//
+//	go run ./cmd/migrate up
+//	go run ./cmd/migrate down [N|all]   # N defaults to 1
+//	go run ./cmd/migrate goto VERSION
+//	go run ./cmd/migrate status
+//	go run ./cmd/migrate create NAME    # new empty up/down files in -dir
+//
+// Concurrent runs, including apps started with database.migrate_on_startup,
+// wait for each other instead of racing.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"text/tabwriter"
+
+	"go-starter-template/db/migration"
+	"go-starter-template/db/migrator"
+	"go-starter-template/internal/config/database"
+	"go-starter-template/internal/config/env"
+	"go-starter-template/internal/config/logger"
+)
+
+func main() {
+	dir := flag.String("dir", "db/migration", "directory create writes new migrations to")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: migrate [-dir DIR] up | down [N|all] | goto VERSION | status | create NAME")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	args := flag.Args()
+	if len(args) == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if args[0] == "create" {
+		if len(args) != 2 {
+			fail(fmt.Errorf("create needs a migration name"))
+		}
+		if err := create(*dir, args[1]); err != nil {
+			fail(err)
+		}
+		return
+	}
+
+	config := env.NewConfig()
+	log := logger.NewLogger(config)
+	sqlDB := database.NewDatabase(log, config)
+	defer sqlDB.Close()
+
+	m, err := migrator.New(sqlDB, log, migration.FS)
+	if err != nil {
+		fail(err)
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	switch {
+	case args[0] == "up" && len(args) == 1:
+		err = m.Up(ctx)
+	case args[0] == "down" && len(args) <= 2:
+		if len(args) == 2 && args[1] == "all" {
+			err = m.Goto(ctx, 0)
+			break
+		}
+		steps := 1
+		if len(args) == 2 {
+			if steps, err = strconv.Atoi(args[1]); err != nil {
+				fail(fmt.Errorf("invalid step count %q", args[1]))
+			}
+		}
+		err = m.Down(ctx, steps)
+	case args[0] == "goto" && len(args) == 2:
+		version, parseErr := strconv.ParseUint(args[1], 10, 64)
+		if parseErr != nil {
+			fail(fmt.Errorf("invalid version %q", args[1]))
+		}
+		err = m.Goto(ctx, version)
+	case args[0] == "status" && len(args) == 1:
+		err = status(ctx, m)
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		fail(err)
+	}
+}
+
+func status(ctx context.Context, m *migrator.Migrator) error {
+	current, dirty, statuses, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("current version: %d", current)
+	if dirty {
+		fmt.Print(" (dirty)")
+	}
+	fmt.Println()
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tSTATE")
+	for _, s := range statuses {
+		state := "pending"
+		if s.Applied {
+			state = "applied"
+		}
+		fmt.Fprintf(w, "%06d\t%s\t%s\n", s.Version, s.Name, state)
+	}
+	return w.Flush()
+}
+
+var migrationName = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+// create writes empty up and down files numbered after the newest in dir.
+func create(dir, name string) error {
+	if !migrationName.MatchString(name) {
+		return fmt.Errorf("migration name %q must be lowercase letters, digits and underscores", name)
+	}
+	existing, err := migrator.New(nil, nil, os.DirFS(dir))
+	if err != nil {
+		return err
+	}
+	base := filepath.Join(dir, fmt.Sprintf("%06d_%s", existing.Latest()+1, name))
+	for _, path := range []string{base + ".up.sql", base + ".down.sql"} {
+		if err := os.WriteFile(path, nil, 0o644); err != nil {
+			return err
+		}
+		fmt.Println(path)
+	}
+	return nil
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "migrate:", err)
+	os.Exit(1)
+}
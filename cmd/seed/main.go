@@ -1,16 +1,126 @@
+// Command seed applies a seed profile or file to the database in config.yml:
+//
+//	go run ./cmd/seed                     # dev profile
+//	go run ./cmd/seed -profile demo -dry-run
+//	go run ./cmd/seed -file seeds.json
+//	go run ./cmd/seed -fresh              # wipe users, roles and permissions first
+//	go run ./cmd/seed -generate 1000000 -seed 42
+//
+// Seeding is idempotent; the changes made (or, with -dry-run, planned) are
+// printed one per line. -generate then bulk-loads that many fake users for
+// load testing, granted the seeded roles and permissions; the same -seed
+// produces the same users.
 package main
 
 import (
-    "go-starter-template/db/seeder"
-    "go-starter-template/internal/config/database"
-    "go-starter-template/internal/config/env"
-    "go-starter-template/internal/config/logger"
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"golang.org/x/crypto/bcrypt"
+
+	"go-starter-template/db/seeder"
+	"go-starter-template/internal/config/database"
+	"go-starter-template/internal/config/env"
+	"go-starter-template/internal/config/logger"
 )
 
 func main() {
-    config := env.NewConfig()
-    log := logger.NewLogger(config)
-    sqlDB := database.NewDatabase(log, config)
+	profile := flag.String("profile", "dev", fmt.Sprintf("built-in seed profile, one of %v", seeder.Profiles))
+	file := flag.String("file", "", "YAML or JSON seed file to apply instead of a profile")
+	dryRun := flag.Bool("dry-run", false, "print the planned changes without applying them")
+	fresh := flag.Bool("fresh", false, "delete all users, roles and permissions before seeding")
+	generate := flag.Int("generate", 0, "number of fake users to bulk-load after seeding")
+	seed := flag.Int64("seed", 1, "random seed for -generate; the same seed yields the same users")
+	password := flag.String("password", "password", "password shared by the generated users")
+	batch := flag.Int("batch", 10_000, "users per COPY batch for -generate")
+	flag.Parse()
+
+	var (
+		seeds *seeder.File
+		err   error
+	)
+	if *file != "" {
+		seeds, err = seeder.LoadFile(*file)
+	} else {
+		seeds, err = seeder.LoadProfile(*profile)
+	}
+	if err != nil {
+		fail(err)
+	}
+
+	config := env.NewConfig()
+	log := logger.NewLogger(config)
+	sqlDB := database.NewDatabase(log, config)
+	defer sqlDB.Close()
+
+	actions, err := seeder.New(sqlDB, log).Apply(context.Background(), seeds, seeder.Options{DryRun: *dryRun, Fresh: *fresh})
+	if err != nil {
+		fail(err)
+	}
+	switch {
+	case len(actions) == 0:
+		fmt.Println("nothing to do, database already matches")
+	case *dryRun:
+		fmt.Println("planned changes (dry run):")
+	}
+	for _, action := range actions {
+		fmt.Println(action)
+	}
+
+	if *generate > 0 {
+		if *dryRun {
+			fmt.Printf("would generate %d users with seed %d\n", *generate, *seed)
+			return
+		}
+		opts := seeder.GenerateOptions{Users: *generate, Seed: *seed, Password: *password, BatchSize: *batch}
+		if err := generateUsers(config.Database.DSN, sqlDB, opts); err != nil {
+			fail(err)
+		}
+	}
+}
+
+// generateUsers bulk-loads fake users over a plain pgx connection, since
+// COPY is not available through database/sql.
+func generateUsers(dsn string, sqlDB *sql.DB, opts seeder.GenerateOptions) error {
+	ctx := context.Background()
+	grantable, err := seeder.LoadGrantable(ctx, sqlDB)
+	if err != nil {
+		return err
+	}
+	generator, err := seeder.NewGenerator(opts, grantable, bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	conn, err := pgx.Connect(ctx, dsn)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(ctx)
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	start := time.Now()
+	n, err := generator.CopyTo(ctx, tx)
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+	fmt.Printf("generated %d users in %s\n", n, time.Since(start).Round(time.Millisecond))
+	return nil
+}
 
-    seeder.Seed(sqlDB)
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "seed:", err)
+	os.Exit(1)
 }
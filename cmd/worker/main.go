@@ -0,0 +1,57 @@
+// Command worker runs the jobs enqueued by the app when jobs.enabled is set:
+//
+//	go run ./cmd/worker
+//
+// It runs until SIGINT or SIGTERM, then stops claiming jobs and gives the
+// running ones jobs.shutdown_timeout seconds to finish.
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"go-starter-template/internal/config/env"
+	"go-starter-template/internal/config/logger"
+	"go-starter-template/internal/config/monitor"
+	"go-starter-template/internal/config/redis"
+	"go-starter-template/internal/notify"
+	"go-starter-template/internal/queue"
+)
+
+func main() {
+	config := env.NewConfig()
+	log := logger.NewLogger(config)
+	redis := redis.NewRedis(log, config)
+	monitoring := monitor.NewMonitoring(log, config)
+	defer monitoring.Shutdown()
+
+	jobs := queue.NewClient(redis, config.GetJobPrefix(), config.GetJobMaxAttempts())
+	jobs.LimitDead(config.GetJobDeadRetention(), config.GetJobDeadMaxLen())
+	worker := queue.NewWorker(jobs, log, queue.WorkerOptions{
+		Concurrency:  config.GetJobConcurrency(),
+		PollInterval: config.GetJobPollInterval(),
+		Lease:        config.GetJobLease(),
+		Backoff:      config.GetJobBackoff(),
+		MaxBackoff:   config.GetJobMaxBackoff(),
+	})
+
+	dispatcher, err := notify.New(config)
+	if err != nil {
+		log.Fatalf("Failed to set up notifications: %v", err)
+	}
+	notify.RegisterJobs(worker, dispatcher)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	worker.Start(context.Background())
+	<-ctx.Done()
+
+	log.Info("shutting down job worker")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), config.GetJobShutdownTimeout())
+	defer cancel()
+	if err := worker.Stop(shutdownCtx); err != nil {
+		log.WithError(err).Warn("running jobs were interrupted and will be retried")
+	}
+}
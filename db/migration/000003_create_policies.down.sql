@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_policies_action;
+DROP TABLE IF EXISTS policies;
\ No newline at end of file
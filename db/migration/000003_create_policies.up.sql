@@ -0,0 +1,14 @@
+CREATE TABLE policies (
+    uuid VARCHAR PRIMARY KEY,
+    name VARCHAR NOT NULL UNIQUE,
+    description VARCHAR NOT NULL DEFAULT '',
+    action VARCHAR NOT NULL,
+    effect VARCHAR NOT NULL CHECK (effect IN ('allow', 'deny')),
+    condition TEXT NOT NULL DEFAULT 'true',
+    priority INTEGER NOT NULL DEFAULT 0,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_policies_action ON policies (action);
@@ -0,0 +1,5 @@
+-- Emails only need to be unique among active users so a soft-deleted user's
+-- email can be registered again.
+ALTER TABLE users DROP CONSTRAINT users_email_key;
+
+CREATE UNIQUE INDEX idx_users_email_active ON users (email) WHERE deleted_at IS NULL;
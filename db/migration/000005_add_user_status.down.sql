@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_users_status;
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS suspended_until,
+    DROP COLUMN IF EXISTS status_reason,
+    DROP COLUMN IF EXISTS status;
@@ -0,0 +1,6 @@
+ALTER TABLE users
+    ADD COLUMN status VARCHAR NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'suspended', 'pending')),
+    ADD COLUMN status_reason VARCHAR NOT NULL DEFAULT '',
+    ADD COLUMN suspended_until TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX idx_users_status ON users (status);
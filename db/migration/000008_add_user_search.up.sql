@@ -0,0 +1,25 @@
+-- pg_trgm is a contrib extension that some hosted databases do not ship or
+-- let us create. Search falls back to ILIKE without it, so do not fail here.
+DO $$
+BEGIN
+    CREATE EXTENSION IF NOT EXISTS pg_trgm;
+EXCEPTION WHEN OTHERS THEN
+    RAISE NOTICE 'pg_trgm unavailable, skipping trigram indexes: %', SQLERRM;
+END
+$$;
+
+-- '@' and '.' are replaced so the parts of an email are separate lexemes
+ALTER TABLE users ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (
+    to_tsvector('simple', coalesce(name, '') || ' ' || translate(coalesce(email, ''), '@.', '  '))
+) STORED;
+CREATE INDEX idx_users_search_vector ON users USING GIN (search_vector);
+
+-- Trigram indexes also serve the existing name/email ILIKE filters
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM pg_extension WHERE extname = 'pg_trgm') THEN
+        CREATE INDEX idx_users_name_trgm ON users USING GIN (name gin_trgm_ops);
+        CREATE INDEX idx_users_email_trgm ON users USING GIN (email gin_trgm_ops);
+    END IF;
+END
+$$;
@@ -0,0 +1,18 @@
+-- Domain events written in the same transaction as the change they describe
+-- and published by the outbox relay. id orders events, per aggregate too,
+-- since an aggregate's events are appended after its row is locked.
+CREATE TABLE outbox (
+    id BIGSERIAL PRIMARY KEY,
+    uuid VARCHAR NOT NULL UNIQUE,
+    aggregate_type VARCHAR NOT NULL,
+    aggregate_id VARCHAR NOT NULL,
+    event_type VARCHAR NOT NULL,
+    payload JSONB NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    published_at TIMESTAMP WITH TIME ZONE,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX idx_outbox_unpublished ON outbox (id) WHERE published_at IS NULL;
+CREATE INDEX idx_outbox_published_at ON outbox (published_at) WHERE published_at IS NOT NULL;
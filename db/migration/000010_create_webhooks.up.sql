@@ -0,0 +1,31 @@
+CREATE TABLE webhook_subscriptions (
+    uuid VARCHAR PRIMARY KEY,
+    url VARCHAR NOT NULL,
+    description VARCHAR NOT NULL DEFAULT '',
+    events JSONB NOT NULL,
+    secret VARCHAR NOT NULL,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    consecutive_failures INTEGER NOT NULL DEFAULT 0,
+    disabled_reason VARCHAR NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE webhook_deliveries (
+    uuid VARCHAR PRIMARY KEY,
+    subscription_uuid VARCHAR NOT NULL REFERENCES webhook_subscriptions (uuid) ON DELETE CASCADE,
+    event_id VARCHAR NOT NULL,
+    event_type VARCHAR NOT NULL,
+    payload JSONB NOT NULL,
+    status VARCHAR NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'succeeded', 'failed')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    response_status INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    next_attempt_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    delivered_at TIMESTAMP WITH TIME ZONE,
+    -- the outbox delivers at least once; an event is recorded once per subscription
+    UNIQUE (subscription_uuid, event_id)
+);
+
+CREATE INDEX idx_webhook_deliveries_subscription ON webhook_deliveries (subscription_uuid, created_at DESC);
@@ -0,0 +1,34 @@
+-- Append-only record of security-relevant and administrative actions. Each
+-- row's hash covers its content and the previous row's hash, so editing or
+-- removing a row breaks the chain from there on.
+CREATE TABLE audit_events (
+    id BIGSERIAL PRIMARY KEY,
+    uuid VARCHAR NOT NULL UNIQUE,
+    actor_uuid VARCHAR NOT NULL DEFAULT '',
+    action VARCHAR NOT NULL,
+    target_type VARCHAR NOT NULL DEFAULT '',
+    target_id VARCHAR NOT NULL DEFAULT '',
+    ip VARCHAR NOT NULL DEFAULT '',
+    user_agent VARCHAR NOT NULL DEFAULT '',
+    trace_id VARCHAR NOT NULL DEFAULT '',
+    changes JSONB NOT NULL DEFAULT '{}',
+    metadata JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    prev_hash VARCHAR NOT NULL,
+    hash VARCHAR NOT NULL UNIQUE
+);
+
+CREATE INDEX idx_audit_events_actor ON audit_events (actor_uuid, id DESC);
+CREATE INDEX idx_audit_events_target ON audit_events (target_id, id DESC);
+CREATE INDEX idx_audit_events_created_at ON audit_events (created_at);
+
+CREATE FUNCTION audit_events_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_events_no_update BEFORE UPDATE OR DELETE ON audit_events
+    FOR EACH ROW EXECUTE FUNCTION audit_events_append_only();
+CREATE TRIGGER audit_events_no_truncate BEFORE TRUNCATE ON audit_events
+    FOR EACH STATEMENT EXECUTE FUNCTION audit_events_append_only();
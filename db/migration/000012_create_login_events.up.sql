@@ -0,0 +1,22 @@
+-- Every login attempt to an existing account, shown to its owner under
+-- GET /api/users/me/logins. Rows go with the user when it is purged.
+CREATE TABLE login_events (
+    id BIGSERIAL PRIMARY KEY,
+    uuid VARCHAR NOT NULL UNIQUE,
+    user_uuid VARCHAR NOT NULL REFERENCES users (uuid) ON DELETE CASCADE,
+    success BOOLEAN NOT NULL,
+    failure_reason VARCHAR NOT NULL DEFAULT '',
+    ip VARCHAR NOT NULL DEFAULT '',
+    user_agent VARCHAR NOT NULL DEFAULT '',
+    -- browser and operating system, e.g. "Chrome on macOS"
+    device VARCHAR NOT NULL DEFAULT '',
+    country VARCHAR NOT NULL DEFAULT '',
+    region VARCHAR NOT NULL DEFAULT '',
+    city VARCHAR NOT NULL DEFAULT '',
+    -- set when the user was alerted about a device or country not seen before
+    new_device BOOLEAN NOT NULL DEFAULT FALSE,
+    new_country BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_login_events_user ON login_events (user_uuid, id DESC);
@@ -0,0 +1 @@
+DELETE FROM policies WHERE name = 'admins-allow-all';
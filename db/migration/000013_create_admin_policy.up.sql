@@ -0,0 +1,6 @@
+-- Baseline policy for the administrative routes, which are denied by default:
+-- members of the admin role may perform every action. Narrower policies can
+-- be added next to it, or it can be disabled in favour of them.
+INSERT INTO policies (uuid, name, description, action, effect, condition, priority)
+VALUES ('00000000-0000-4000-8000-000000000001', 'admins-allow-all', 'Administrators may perform every action', '*', 'allow', '"admin" in subject.roles', 0)
+ON CONFLICT (name) DO NOTHING;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_webhook_subscriptions_owner;
+
+ALTER TABLE webhook_subscriptions DROP COLUMN IF EXISTS owner_uuid;
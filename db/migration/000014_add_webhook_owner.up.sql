@@ -0,0 +1,6 @@
+-- A subscription belongs to the user who created it, and only they can see
+-- or manage it. Subscriptions created before this migration have no owner
+-- and keep receiving deliveries, but no longer show up under /api/webhooks.
+ALTER TABLE webhook_subscriptions ADD COLUMN owner_uuid VARCHAR REFERENCES users (uuid) ON DELETE CASCADE;
+
+CREATE INDEX idx_webhook_subscriptions_owner ON webhook_subscriptions (owner_uuid, created_at);
@@ -0,0 +1,5 @@
+-- Emails are unique among active users regardless of case, matching the
+-- lower(email) comparisons of the lookups.
+DROP INDEX IF EXISTS idx_users_email_active;
+
+CREATE UNIQUE INDEX idx_users_email_lower_active ON users (lower(email)) WHERE deleted_at IS NULL;
@@ -0,0 +1,3 @@
+-- Tokens issued before tokens_valid_after are rejected, which revokes every
+-- session of a user at once.
+ALTER TABLE users ADD COLUMN tokens_valid_after TIMESTAMP WITH TIME ZONE;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_outbox_parked_at;
+DROP INDEX IF EXISTS idx_outbox_unpublished;
+ALTER TABLE outbox DROP COLUMN IF EXISTS parked_at;
+CREATE INDEX idx_outbox_unpublished ON outbox (id) WHERE published_at IS NULL;
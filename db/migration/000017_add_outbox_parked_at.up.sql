@@ -0,0 +1,7 @@
+-- Events that failed outbox.max_attempts deliveries are parked: the relay
+-- stops retrying them so the rest of their aggregate can flow.
+ALTER TABLE outbox ADD COLUMN parked_at TIMESTAMP WITH TIME ZONE;
+
+DROP INDEX IF EXISTS idx_outbox_unpublished;
+CREATE INDEX idx_outbox_unpublished ON outbox (id) WHERE published_at IS NULL AND parked_at IS NULL;
+CREATE INDEX idx_outbox_parked_at ON outbox (parked_at) WHERE parked_at IS NOT NULL;
@@ -0,0 +1,11 @@
+// Package migration embeds the SQL migrations in this directory so binaries
+// can apply them without the files on disk; db/migrator runs them.
+//
+// Files are named <version>_<name>.up.sql and <version>_<name>.down.sql, as
+// created by `make migrateschema name=<name>`.
+package migration
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS
@@ -0,0 +1,295 @@
+// Package migrator applies the SQL migrations embedded in db/migration from
+// inside the process, replacing the external migrate CLI.
+//
+// The applied version is kept in the same schema_migrations table the migrate
+// CLI used, so existing databases carry on where they were. Each migration
+// runs in its own transaction together with the version update, and the whole
+// run holds a Postgres advisory lock so instances starting at the same time
+// do not apply a migration twice.
+package migrator
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/sirupsen/logrus"
+)
+
+// lockID is the advisory lock key held while migrating.
+const lockID int64 = 4_729_116_342_083_151_507
+
+var (
+	ErrDirty          = errors.New("database is marked dirty by a failed migration; fix it by hand, then set schema_migrations.dirty to false")
+	ErrUnknownVersion = errors.New("unknown migration version")
+	ErrNoDown         = errors.New("migration has no down file")
+)
+
+var fileName = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+// Migration is one numbered schema change.
+type Migration struct {
+	Version uint64
+	Name    string
+	Up      string
+	Down    string
+
+	hasUp, hasDown bool
+}
+
+// Status reports whether a migration has been applied.
+type Status struct {
+	Version uint64 `json:"version"`
+	Name    string `json:"name"`
+	Applied bool   `json:"applied"`
+}
+
+type Migrator struct {
+	db         *sql.DB
+	log        *logrus.Logger
+	migrations []*Migration
+}
+
+// New reads the migrations in the root of fsys, usually migration.FS.
+func New(db *sql.DB, log *logrus.Logger, fsys fs.FS) (*Migrator, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("reading migrations: %w", err)
+	}
+
+	byVersion := map[uint64]*Migration{}
+	for _, entry := range entries {
+		match := fileName.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+		version, err := strconv.ParseUint(match[1], 10, 64)
+		if err != nil || version == 0 {
+			return nil, fmt.Errorf("invalid migration version in %s", entry.Name())
+		}
+		body, err := fs.ReadFile(fsys, path.Join(".", entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", entry.Name(), err)
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("migration version %d is used by both %s and %s", version, m.Name, match[2])
+		}
+		if match[3] == "up" {
+			m.Up, m.hasUp = string(body), true
+		} else {
+			m.Down, m.hasDown = string(body), true
+		}
+	}
+
+	migrations := make([]*Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if !m.hasUp {
+			return nil, fmt.Errorf("migration %d_%s has no up file", m.Version, m.Name)
+		}
+		migrations = append(migrations, m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return &Migrator{db: db, log: log, migrations: migrations}, nil
+}
+
+// Latest returns the highest known version, 0 if there are no migrations.
+func (m *Migrator) Latest() uint64 {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Up applies every migration newer than the current version. A database
+// already past the latest known migration, as during a rolling deploy of an
+// older binary, is left alone.
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.withLock(ctx, func(conn *sql.Conn, current uint64, dirty bool) error {
+		if dirty {
+			return dirtyError(current)
+		}
+		if latest := m.Latest(); current > latest {
+			m.log.Warnf("Database schema version %d is newer than the latest known migration %d", current, latest)
+			return nil
+		}
+		return m.migrate(ctx, conn, current, m.Latest())
+	})
+}
+
+// Down reverts the last steps applied migrations.
+func (m *Migrator) Down(ctx context.Context, steps int) error {
+	if steps < 1 {
+		return fmt.Errorf("steps must be at least 1, got %d", steps)
+	}
+	return m.withLock(ctx, func(conn *sql.Conn, current uint64, dirty bool) error {
+		if dirty {
+			return dirtyError(current)
+		}
+		index, err := m.index(current)
+		if err != nil {
+			return err
+		}
+		target := uint64(0)
+		if index-steps >= 0 {
+			target = m.migrations[index-steps].Version
+		}
+		return m.migrate(ctx, conn, current, target)
+	})
+}
+
+// Goto migrates up or down to version; 0 reverts every migration.
+func (m *Migrator) Goto(ctx context.Context, version uint64) error {
+	if _, err := m.index(version); err != nil {
+		return err
+	}
+	return m.withLock(ctx, func(conn *sql.Conn, current uint64, dirty bool) error {
+		if dirty {
+			return dirtyError(current)
+		}
+		return m.migrate(ctx, conn, current, version)
+	})
+}
+
+// Status returns the current version, whether a failed migration left it
+// dirty, and every known migration.
+func (m *Migrator) Status(ctx context.Context) (current uint64, dirty bool, statuses []Status, err error) {
+	err = m.withLock(ctx, func(_ *sql.Conn, version uint64, isDirty bool) error {
+		current, dirty = version, isDirty
+		return nil
+	})
+	if err != nil {
+		return 0, false, nil, err
+	}
+
+	statuses = make([]Status, len(m.migrations))
+	for i, migration := range m.migrations {
+		statuses[i] = Status{Version: migration.Version, Name: migration.Name, Applied: migration.Version <= current}
+	}
+	return current, dirty, statuses, nil
+}
+
+func dirtyError(version uint64) error {
+	return fmt.Errorf("%w (version %d)", ErrDirty, version)
+}
+
+// index returns the position of version in m.migrations, -1 for version 0.
+func (m *Migrator) index(version uint64) (int, error) {
+	if version == 0 {
+		return -1, nil
+	}
+	i := sort.Search(len(m.migrations), func(i int) bool { return m.migrations[i].Version >= version })
+	if i == len(m.migrations) || m.migrations[i].Version != version {
+		return 0, fmt.Errorf("%w: %d", ErrUnknownVersion, version)
+	}
+	return i, nil
+}
+
+// withLock runs fn on a single connection holding the advisory lock, with
+// the version read from schema_migrations (created if missing).
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn, current uint64, dirty bool) error) (err error) {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// Blocks until any other instance has finished migrating
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, lockID); err != nil {
+		return fmt.Errorf("acquiring migration lock: %w", err)
+	}
+	defer func() {
+		// Unlock even if ctx was cancelled; closing the session would too
+		if _, unlockErr := conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, lockID); unlockErr != nil && err == nil {
+			err = fmt.Errorf("releasing migration lock: %w", unlockErr)
+		}
+	}()
+
+	if _, err := conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (version bigint NOT NULL PRIMARY KEY, dirty boolean NOT NULL)`); err != nil {
+		return fmt.Errorf("creating schema_migrations: %w", err)
+	}
+	var (
+		current int64
+		dirty   bool
+	)
+	err = conn.QueryRowContext(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&current, &dirty)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("reading schema version: %w", err)
+	}
+	return fn(conn, uint64(current), dirty)
+}
+
+// migrate applies the migrations between current and target, one transaction
+// per migration so a failure leaves the database at the last good version.
+func (m *Migrator) migrate(ctx context.Context, conn *sql.Conn, current, target uint64) error {
+	if current == target {
+		m.log.Infof("Database schema is at version %d, nothing to migrate", current)
+		return nil
+	}
+
+	if target > current {
+		for _, migration := range m.migrations {
+			if migration.Version <= current || migration.Version > target {
+				continue
+			}
+			if err := m.apply(ctx, conn, migration, migration.Up, migration.Version, "up"); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	from, err := m.index(current)
+	if err != nil {
+		return fmt.Errorf("database is at version %d: %w", current, err)
+	}
+	for i := from; i >= 0 && m.migrations[i].Version > target; i-- {
+		migration := m.migrations[i]
+		if !migration.hasDown {
+			return fmt.Errorf("%w: %d_%s", ErrNoDown, migration.Version, migration.Name)
+		}
+		previous := uint64(0)
+		if i > 0 {
+			previous = m.migrations[i-1].Version
+		}
+		if err := m.apply(ctx, conn, migration, migration.Down, previous, "down"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *Migrator) apply(ctx context.Context, conn *sql.Conn, migration *Migration, body string, version uint64, direction string) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, body); err != nil {
+		return fmt.Errorf("migration %d_%s %s failed: %w", migration.Version, migration.Name, direction, err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM schema_migrations`); err != nil {
+		return err
+	}
+	if version > 0 {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO schema_migrations (version, dirty) VALUES ($1, false)`, int64(version)); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	m.log.Infof("Applied migration %d_%s %s", migration.Version, migration.Name, direction)
+	return nil
+}
@@ -0,0 +1,262 @@
+package migrator
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"regexp"
+	"testing"
+	"testing/fstest"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+
+	"go-starter-template/db/migration"
+)
+
+var testMigrations = fstest.MapFS{
+	"000001_create_a.up.sql":   {Data: []byte("CREATE TABLE a (id int);")},
+	"000001_create_a.down.sql": {Data: []byte("DROP TABLE a;")},
+	"000002_create_b.up.sql":   {Data: []byte("CREATE TABLE b (id int);")},
+	"000002_create_b.down.sql": {Data: []byte("DROP TABLE b;")},
+	"000003_create_c.up.sql":   {Data: []byte("CREATE TABLE c (id int);")},
+	"000003_create_c.down.sql": {Data: []byte("DROP TABLE c;")},
+	"README.md":                {Data: []byte("not a migration")},
+}
+
+func silentLogger() *logrus.Logger {
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+	return log
+}
+
+func setupMigrator(t *testing.T) (*Migrator, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	m, err := New(db, silentLogger(), testMigrations)
+	require.NoError(t, err)
+	return m, mock
+}
+
+// expectLock expects the lock and version read; version 0 means no row.
+func expectLock(mock sqlmock.Sqlmock, version int64, dirty bool) {
+	mock.ExpectExec(regexp.QuoteMeta(`SELECT pg_advisory_lock($1)`)).WithArgs(lockID).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(`CREATE TABLE IF NOT EXISTS schema_migrations`)).WillReturnResult(sqlmock.NewResult(0, 0))
+	rows := sqlmock.NewRows([]string{"version", "dirty"})
+	if version > 0 {
+		rows.AddRow(version, dirty)
+	}
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT version, dirty FROM schema_migrations LIMIT 1`)).WillReturnRows(rows)
+}
+
+func expectUnlock(mock sqlmock.Sqlmock) {
+	mock.ExpectExec(regexp.QuoteMeta(`SELECT pg_advisory_unlock($1)`)).WithArgs(lockID).WillReturnResult(sqlmock.NewResult(0, 0))
+}
+
+// expectApply expects one migration transaction leaving the schema at version.
+func expectApply(mock sqlmock.Sqlmock, body string, version int64) {
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(body)).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM schema_migrations`)).WillReturnResult(sqlmock.NewResult(0, 1))
+	if version > 0 {
+		mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO schema_migrations (version, dirty) VALUES ($1, false)`)).
+			WithArgs(version).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+	mock.ExpectCommit()
+}
+
+func TestNew(t *testing.T) {
+	m, _ := setupMigrator(t)
+	require.Len(t, m.migrations, 3)
+	require.Equal(t, "create_a", m.migrations[0].Name)
+	require.Equal(t, uint64(3), m.Latest())
+
+	_, err := New(nil, nil, fstest.MapFS{"000001_a.down.sql": {}})
+	require.ErrorContains(t, err, "no up file")
+	_, err = New(nil, nil, fstest.MapFS{"000001_a.up.sql": {}, "000001_b.up.sql": {}})
+	require.ErrorContains(t, err, "used by both")
+	_, err = New(nil, nil, fstest.MapFS{"000000_a.up.sql": {}})
+	require.ErrorContains(t, err, "invalid migration version")
+
+	// The embedded migrations parse and can all be reverted
+	embedded, err := New(nil, nil, migration.FS)
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, embedded.Latest(), uint64(8))
+	for _, migration := range embedded.migrations {
+		require.True(t, migration.hasDown, "%d_%s has no down file", migration.Version, migration.Name)
+	}
+}
+
+func TestMigrator_Up(t *testing.T) {
+	t.Run("FromScratch", func(t *testing.T) {
+		m, mock := setupMigrator(t)
+		expectLock(mock, 0, false)
+		expectApply(mock, "CREATE TABLE a (id int);", 1)
+		expectApply(mock, "CREATE TABLE b (id int);", 2)
+		expectApply(mock, "CREATE TABLE c (id int);", 3)
+		expectUnlock(mock)
+
+		require.NoError(t, m.Up(context.Background()))
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("OnlyPending", func(t *testing.T) {
+		m, mock := setupMigrator(t)
+		expectLock(mock, 2, false)
+		expectApply(mock, "CREATE TABLE c (id int);", 3)
+		expectUnlock(mock)
+
+		require.NoError(t, m.Up(context.Background()))
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("UpToDate", func(t *testing.T) {
+		m, mock := setupMigrator(t)
+		expectLock(mock, 3, false)
+		expectUnlock(mock)
+
+		require.NoError(t, m.Up(context.Background()))
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("DatabaseAhead", func(t *testing.T) {
+		// A newer binary already migrated further; nothing is reverted
+		m, mock := setupMigrator(t)
+		expectLock(mock, 9, false)
+		expectUnlock(mock)
+
+		require.NoError(t, m.Up(context.Background()))
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("FailureRollsBack", func(t *testing.T) {
+		m, mock := setupMigrator(t)
+		expectLock(mock, 1, false)
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta("CREATE TABLE b (id int);")).WillReturnError(errors.New("syntax error"))
+		mock.ExpectRollback()
+		expectUnlock(mock)
+
+		err := m.Up(context.Background())
+		require.ErrorContains(t, err, "migration 2_create_b up failed")
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Dirty", func(t *testing.T) {
+		m, mock := setupMigrator(t)
+		expectLock(mock, 2, true)
+		expectUnlock(mock)
+
+		require.ErrorIs(t, m.Up(context.Background()), ErrDirty)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("LockError", func(t *testing.T) {
+		m, mock := setupMigrator(t)
+		mock.ExpectExec(regexp.QuoteMeta(`SELECT pg_advisory_lock($1)`)).WillReturnError(sql.ErrConnDone)
+
+		require.ErrorIs(t, m.Up(context.Background()), sql.ErrConnDone)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestMigrator_Down(t *testing.T) {
+	t.Run("Steps", func(t *testing.T) {
+		m, mock := setupMigrator(t)
+		expectLock(mock, 3, false)
+		expectApply(mock, "DROP TABLE c;", 2)
+		expectApply(mock, "DROP TABLE b;", 1)
+		expectUnlock(mock)
+
+		require.NoError(t, m.Down(context.Background(), 2))
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("PastFirstStopsAtZero", func(t *testing.T) {
+		m, mock := setupMigrator(t)
+		expectLock(mock, 1, false)
+		expectApply(mock, "DROP TABLE a;", 0)
+		expectUnlock(mock)
+
+		require.NoError(t, m.Down(context.Background(), 5))
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("UnknownCurrentVersion", func(t *testing.T) {
+		m, mock := setupMigrator(t)
+		expectLock(mock, 9, false)
+		expectUnlock(mock)
+
+		require.ErrorIs(t, m.Down(context.Background(), 1), ErrUnknownVersion)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("InvalidSteps", func(t *testing.T) {
+		m, _ := setupMigrator(t)
+		require.Error(t, m.Down(context.Background(), 0))
+	})
+}
+
+func TestMigrator_Goto(t *testing.T) {
+	t.Run("Up", func(t *testing.T) {
+		m, mock := setupMigrator(t)
+		expectLock(mock, 0, false)
+		expectApply(mock, "CREATE TABLE a (id int);", 1)
+		expectApply(mock, "CREATE TABLE b (id int);", 2)
+		expectUnlock(mock)
+
+		require.NoError(t, m.Goto(context.Background(), 2))
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Down", func(t *testing.T) {
+		m, mock := setupMigrator(t)
+		expectLock(mock, 3, false)
+		expectApply(mock, "DROP TABLE c;", 2)
+		expectUnlock(mock)
+
+		require.NoError(t, m.Goto(context.Background(), 2))
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("UnknownTarget", func(t *testing.T) {
+		m, mock := setupMigrator(t)
+		require.ErrorIs(t, m.Goto(context.Background(), 7), ErrUnknownVersion)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("MissingDownFile", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+		m, err := New(db, silentLogger(), fstest.MapFS{"000001_a.up.sql": {Data: []byte("SELECT 1")}})
+		require.NoError(t, err)
+		expectLock(mock, 1, false)
+		expectUnlock(mock)
+
+		require.ErrorIs(t, m.Goto(context.Background(), 0), ErrNoDown)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestMigrator_Status(t *testing.T) {
+	m, mock := setupMigrator(t)
+	expectLock(mock, 2, true)
+	expectUnlock(mock)
+
+	current, dirty, statuses, err := m.Status(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), current)
+	require.True(t, dirty)
+	require.Equal(t, []Status{
+		{Version: 1, Name: "create_a", Applied: true},
+		{Version: 2, Name: "create_b", Applied: true},
+		{Version: 3, Name: "create_c", Applied: false},
+	}, statuses)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,228 @@
+package seeder
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// generateEpoch anchors generated timestamps, so a seed value produces the
+// same rows whenever it runs.
+var generateEpoch = time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+var (
+	firstNames = []string{
+		"Aaliyah", "Aiden", "Amara", "Andre", "Aria", "Bruno", "Camila", "Chen", "Chloe", "Dmitri",
+		"Elena", "Emeka", "Fatima", "Felix", "Grace", "Hana", "Hugo", "Ines", "Isaac", "Jamal",
+		"Julia", "Kenji", "Lars", "Layla", "Leo", "Lucia", "Maya", "Mateo", "Nadia", "Noah",
+		"Olga", "Omar", "Priya", "Rafael", "Sara", "Sofia", "Tariq", "Uma", "Yusuf", "Zoe",
+	}
+	lastNames = []string{
+		"Adeyemi", "Andersen", "Bauer", "Costa", "Dubois", "Edwards", "Fischer", "Garcia", "Haddad", "Ivanova",
+		"Jensen", "Kim", "Kowalski", "Lee", "Lopez", "Martin", "Meyer", "Moreau", "Nakamura", "Novak",
+		"Okafor", "Olsen", "Patel", "Popescu", "Rossi", "Santos", "Schmidt", "Silva", "Singh", "Smith",
+		"Suzuki", "Tanaka", "Torres", "Van Dijk", "Wang", "Weber", "Williams", "Yilmaz", "Zhang", "Zimmermann",
+	}
+	emailDomains = []string{"example.com", "example.net", "example.org"}
+)
+
+var userCopyColumns = []string{
+	"uuid", "name", "email", "password", "status", "status_reason", "suspended_until",
+	"phone", "created_at", "updated_at", "deleted_at", "version",
+}
+
+// GenerateOptions configures fake users for load testing.
+type GenerateOptions struct {
+	Users int
+	// Seed makes runs reproducible: the same seed, grants and options yield
+	// the same rows
+	Seed int64
+	// Password is shared by every generated user and hashed once, so load
+	// tests can log in as any of them
+	Password string
+	// Span is how far before generateEpoch creation times reach, default two years
+	Span time.Duration
+	// BatchSize is the number of users per COPY, default 10000
+	BatchSize int
+}
+
+// Grantable lists the role and permission uuids generated users are given,
+// sorted by name so a seed picks the same grants on every database.
+type Grantable struct {
+	Roles       []string
+	Permissions []string
+}
+
+// LoadGrantable reads the roles and permissions to hand out; apply a seed
+// profile first so there are some.
+func LoadGrantable(ctx context.Context, db *sql.DB) (Grantable, error) {
+	var grantable Grantable
+	for _, target := range []struct {
+		table string
+		uuids *[]string
+	}{{"roles", &grantable.Roles}, {"permissions", &grantable.Permissions}} {
+		rows, err := db.QueryContext(ctx, `SELECT uuid FROM `+target.table+` ORDER BY name`)
+		if err != nil {
+			return grantable, err
+		}
+		for rows.Next() {
+			var id string
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return grantable, err
+			}
+			*target.uuids = append(*target.uuids, id)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return grantable, err
+		}
+	}
+	return grantable, nil
+}
+
+// Copier is the part of pgx.Conn and pgx.Tx that Generator writes through.
+type Copier interface {
+	CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error)
+}
+
+// FakeUser is one generated user with its grants.
+type FakeUser struct {
+	Row         []any
+	Roles       []string
+	Permissions []string
+}
+
+// Generator produces realistic, reproducible users: varied names, unique
+// emails, a mix of statuses, some soft-deleted, timestamps spread over Span,
+// and random roles and direct permissions.
+type Generator struct {
+	opts      GenerateOptions
+	grantable Grantable
+	rng       *rand.Rand
+	hash      string
+	count     int
+}
+
+func NewGenerator(opts GenerateOptions, grantable Grantable, cost int) (*Generator, error) {
+	if opts.Users < 1 {
+		return nil, fmt.Errorf("users must be at least 1, got %d", opts.Users)
+	}
+	if opts.Password == "" {
+		return nil, fmt.Errorf("a password is required")
+	}
+	if opts.Span <= 0 {
+		opts.Span = 2 * 365 * 24 * time.Hour
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 10_000
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(opts.Password), cost)
+	if err != nil {
+		return nil, err
+	}
+	return &Generator{opts: opts, grantable: grantable, rng: rand.New(rand.NewSource(opts.Seed)), hash: string(hash)}, nil
+}
+
+// Next returns the next user. The i-th user depends only on the seed and the
+// users before it, never on batch size.
+func (g *Generator) Next() (FakeUser, error) {
+	g.count++
+	id, err := uuid.NewRandomFromReader(g.rng)
+	if err != nil {
+		return FakeUser{}, err
+	}
+	first := firstNames[g.rng.Intn(len(firstNames))]
+	last := lastNames[g.rng.Intn(len(lastNames))]
+	// The counter keeps emails unique however often names repeat
+	local := strings.ToLower(strings.ReplaceAll(first+"."+last, " ", ""))
+	email := fmt.Sprintf("%s.%d@%s", local, g.count, emailDomains[g.rng.Intn(len(emailDomains))])
+
+	createdAt := generateEpoch.Add(-time.Duration(g.rng.Int63n(int64(g.opts.Span))))
+	updatedAt := createdAt.Add(time.Duration(g.rng.Int63n(int64(generateEpoch.Sub(createdAt)) + 1)))
+
+	status, reason := "active", ""
+	var suspendedUntil any
+	switch n := g.rng.Intn(100); {
+	case n < 4:
+		status, reason = "suspended", "generated for load testing"
+		if g.rng.Intn(2) == 0 {
+			suspendedUntil = generateEpoch.Add(time.Duration(g.rng.Intn(30)+1) * 24 * time.Hour)
+		}
+	case n < 10:
+		status = "pending"
+	}
+
+	var phone any
+	if g.rng.Intn(10) < 6 {
+		phone = fmt.Sprintf("+1555%07d", g.rng.Intn(10_000_000))
+	}
+	var deletedAt any
+	if g.rng.Intn(100) < 2 {
+		deletedAt = updatedAt
+	}
+
+	user := FakeUser{Row: []any{
+		id.String(), first + " " + last, email, g.hash, status, reason, suspendedUntil,
+		phone, createdAt, updatedAt, deletedAt, int64(1),
+	}}
+	// Mostly one role, some two, a few none
+	if roles := g.grantable.Roles; len(roles) > 0 {
+		switch n := g.rng.Intn(10); {
+		case n < 7:
+			user.Roles = []string{roles[g.rng.Intn(len(roles))]}
+		case n < 9 && len(roles) > 1:
+			picked := g.rng.Perm(len(roles))[:2]
+			user.Roles = []string{roles[picked[0]], roles[picked[1]]}
+		}
+	}
+	if permissions := g.grantable.Permissions; len(permissions) > 0 && g.rng.Intn(10) == 0 {
+		user.Permissions = []string{permissions[g.rng.Intn(len(permissions))]}
+	}
+	return user, nil
+}
+
+// CopyTo generates every user and writes them, with their grants, using
+// COPY in batches so memory stays flat however many users are asked for.
+// Run it inside a transaction to get all or nothing.
+func (g *Generator) CopyTo(ctx context.Context, c Copier) (int64, error) {
+	var total int64
+	for remaining := g.opts.Users - g.count; remaining > 0; remaining = g.opts.Users - g.count {
+		size := min(remaining, g.opts.BatchSize)
+		users := make([][]any, 0, size)
+		var userRoles, userPermissions [][]any
+		for range size {
+			user, err := g.Next()
+			if err != nil {
+				return total, err
+			}
+			users = append(users, user.Row)
+			for _, role := range user.Roles {
+				userRoles = append(userRoles, []any{user.Row[0], role})
+			}
+			for _, permission := range user.Permissions {
+				userPermissions = append(userPermissions, []any{user.Row[0], permission})
+			}
+		}
+
+		n, err := c.CopyFrom(ctx, pgx.Identifier{"users"}, userCopyColumns, pgx.CopyFromRows(users))
+		if err != nil {
+			return total, fmt.Errorf("copying users: %w", err)
+		}
+		total += n
+		if _, err := c.CopyFrom(ctx, pgx.Identifier{"user_roles"}, []string{"user_uuid", "role_uuid"}, pgx.CopyFromRows(userRoles)); err != nil {
+			return total, fmt.Errorf("copying user roles: %w", err)
+		}
+		if _, err := c.CopyFrom(ctx, pgx.Identifier{"user_permissions"}, []string{"user_uuid", "permission_uuid"}, pgx.CopyFromRows(userPermissions)); err != nil {
+			return total, fmt.Errorf("copying user permissions: %w", err)
+		}
+	}
+	return total, nil
+}
@@ -0,0 +1,145 @@
+package seeder
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// fakeCopier records COPY calls instead of talking to Postgres.
+type fakeCopier struct {
+	rows  map[string][][]any
+	calls []string
+	err   error
+}
+
+func (c *fakeCopier) CopyFrom(_ context.Context, table pgx.Identifier, _ []string, src pgx.CopyFromSource) (int64, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+	if c.rows == nil {
+		c.rows = map[string][][]any{}
+	}
+	name := table.Sanitize()
+	c.calls = append(c.calls, name)
+	var n int64
+	for src.Next() {
+		values, err := src.Values()
+		if err != nil {
+			return n, err
+		}
+		c.rows[name] = append(c.rows[name], values)
+		n++
+	}
+	return n, src.Err()
+}
+
+var testGrantable = Grantable{Roles: []string{"r-admin", "r-manager", "r-user"}, Permissions: []string{"p-read", "p-write"}}
+
+func newTestGenerator(t *testing.T, opts GenerateOptions) *Generator {
+	t.Helper()
+	if opts.Password == "" {
+		opts.Password = "password"
+	}
+	g, err := NewGenerator(opts, testGrantable, bcrypt.MinCost)
+	require.NoError(t, err)
+	return g
+}
+
+func TestGenerator_CopyTo(t *testing.T) {
+	g := newTestGenerator(t, GenerateOptions{Users: 2500, Seed: 7, BatchSize: 1000})
+	copier := new(fakeCopier)
+
+	n, err := g.CopyTo(context.Background(), copier)
+	require.NoError(t, err)
+	require.Equal(t, int64(2500), n)
+	// Three batches, each copying users before their grants
+	require.Len(t, copier.calls, 9)
+	require.Equal(t, []string{`"users"`, `"user_roles"`, `"user_permissions"`}, copier.calls[:3])
+
+	users := copier.rows[`"users"`]
+	require.Len(t, users, 2500)
+	emails := map[string]bool{}
+	statuses := map[string]int{}
+	for _, row := range users {
+		require.Len(t, row, len(userCopyColumns))
+		email := row[2].(string)
+		require.False(t, emails[email], "duplicate email %s", email)
+		emails[email] = true
+		statuses[row[4].(string)]++
+
+		createdAt, updatedAt := row[8].(time.Time), row[9].(time.Time)
+		require.False(t, createdAt.After(updatedAt))
+		require.False(t, updatedAt.After(generateEpoch))
+	}
+	// A realistic mix rather than all active
+	require.Greater(t, statuses["active"], statuses["pending"])
+	require.NotZero(t, statuses["pending"])
+	require.NotZero(t, statuses["suspended"])
+	require.NotEmpty(t, copier.rows[`"user_roles"`])
+	require.NotEmpty(t, copier.rows[`"user_permissions"`])
+
+	// The password is hashed once and usable for every user
+	require.NoError(t, bcrypt.CompareHashAndPassword([]byte(users[0][3].(string)), []byte("password")))
+	require.Equal(t, users[0][3], users[len(users)-1][3])
+}
+
+func TestGenerator_Deterministic(t *testing.T) {
+	generate := func(seed int64, batch int) [][]any {
+		copier := new(fakeCopier)
+		_, err := newTestGenerator(t, GenerateOptions{Users: 50, Seed: seed, BatchSize: batch}).CopyTo(context.Background(), copier)
+		require.NoError(t, err)
+		// Drop the password hash, which is salted per run
+		rows := copier.rows[`"users"`]
+		for _, row := range rows {
+			row[3] = nil
+		}
+		return append(rows, copier.rows[`"user_roles"`]...)
+	}
+
+	first := generate(42, 10)
+	require.Equal(t, first, generate(42, 10))
+	// Batch size does not change what is generated
+	require.Equal(t, first, generate(42, 7))
+	require.NotEqual(t, first, generate(43, 10))
+}
+
+func TestGenerator_Errors(t *testing.T) {
+	_, err := NewGenerator(GenerateOptions{Users: 0, Password: "x"}, testGrantable, bcrypt.MinCost)
+	require.Error(t, err)
+	_, err = NewGenerator(GenerateOptions{Users: 1}, testGrantable, bcrypt.MinCost)
+	require.Error(t, err)
+
+	// Without grantable rows users are generated without grants
+	g, err := NewGenerator(GenerateOptions{Users: 20, Password: "x"}, Grantable{}, bcrypt.MinCost)
+	require.NoError(t, err)
+	copier := new(fakeCopier)
+	_, err = g.CopyTo(context.Background(), copier)
+	require.NoError(t, err)
+	require.Empty(t, copier.rows[`"user_roles"`])
+
+	g = newTestGenerator(t, GenerateOptions{Users: 5})
+	_, err = g.CopyTo(context.Background(), &fakeCopier{err: errors.New("connection reset")})
+	require.True(t, strings.HasPrefix(err.Error(), "copying users"))
+}
+
+func TestLoadGrantable(t *testing.T) {
+	s, mock, _ := setupSeeder(t)
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT uuid FROM roles ORDER BY name`)).
+		WillReturnRows(sqlmock.NewRows([]string{"uuid"}).AddRow("r1").AddRow("r2"))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT uuid FROM permissions ORDER BY name`)).
+		WillReturnRows(sqlmock.NewRows([]string{"uuid"}).AddRow("p1"))
+
+	grantable, err := LoadGrantable(context.Background(), s.db)
+	require.NoError(t, err)
+	require.Equal(t, Grantable{Roles: []string{"r1", "r2"}, Permissions: []string{"p1"}}, grantable)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -1,107 +1,386 @@
+// Package seeder applies declarative seed files (permissions, roles, their
+// grants and users) to the database.
+//
+// Seeding is idempotent: rows are matched by permission name, role name and
+// user email, missing rows and grants are inserted, and a second run changes
+// nothing. Rows the file does not mention are never touched, unless Fresh
+// asks for the old behaviour of wiping the tables first. Everything happens
+// in one transaction.
 package seeder
 
 import (
-    "database/sql"
-    "log"
-    "time"
+	"bytes"
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"time"
 
-    "github.com/google/uuid"
-    "golang.org/x/crypto/bcrypt"
-    "go-starter-template/internal/model"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/bcrypt"
+	"gopkg.in/yaml.v3"
 )
 
-func Seed(db *sql.DB) {
-    // Cleanup existing records in dependency order
-    for _, table := range []string{"user_roles", "role_permissions", "user_permissions", "users", "roles", "permissions"} {
-        if _, err := db.Exec("DELETE FROM " + table); err != nil {
-            log.Fatalf("Failed to delete from %s: %v", table, err)
-        }
-    }
-
-    // Prepare base data using model structs
-    adminRole := model.Role{UUID: uuid.NewString(), Name: "admin"}
-    userRole := model.Role{UUID: uuid.NewString(), Name: "user"}
-    roles := []model.Role{adminRole, userRole}
-
-    // Insert roles
-    for _, r := range roles {
-        if _, err := db.Exec(`INSERT INTO roles (uuid, name) VALUES ($1, $2)`, r.UUID, r.Name); err != nil {
-            log.Fatalf("Failed to insert role %s: %v", r.Name, err)
-        }
-    }
-
-    // Permissions
-    newPerm := func(name string) model.Permission { return model.Permission{UUID: uuid.NewString(), Name: name} }
-    crudUser := []model.Permission{
-        newPerm("read-user"),
-        newPerm("write-user"),
-        newPerm("delete-user"),
-        newPerm("update-user"),
-    }
-    crudPermissions := []model.Permission{
-        newPerm("read-permission"),
-        newPerm("write-permission"),
-        newPerm("delete-permission"),
-        newPerm("update-permission"),
-    }
-    crudRole := []model.Permission{
-        newPerm("read-role"),
-        newPerm("write-role"),
-        newPerm("delete-role"),
-        newPerm("update-role"),
-    }
-    otherPermission := newPerm("read-other")
-
-    // Insert permissions
-    insertPerm := func(p model.Permission) {
-        if _, err := db.Exec(`INSERT INTO permissions (uuid, name) VALUES ($1, $2)`, p.UUID, p.Name); err != nil {
-            log.Fatalf("Failed to insert permission %s: %v", p.Name, err)
-        }
-    }
-    for _, p := range crudUser { insertPerm(p) }
-    for _, p := range crudPermissions { insertPerm(p) }
-    for _, p := range crudRole { insertPerm(p) }
-    insertPerm(otherPermission)
-
-    // Create a test user
-    hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.DefaultCost)
-    now := time.Now()
-    user := model.User{
-        UUID:      uuid.NewString(),
-        Name:      "Test",
-        Email:     "test@test.com",
-        Password:  string(hashedPassword),
-        CreatedAt: now,
-        UpdatedAt: now,
-    }
-    if _, err := db.Exec(`INSERT INTO users (uuid, name, email, password, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6)`,
-        user.UUID, user.Name, user.Email, user.Password, user.CreatedAt, user.UpdatedAt); err != nil {
-        log.Fatalf("Failed to insert test user: %v", err)
-    }
-
-    // Assign permissions to admin role
-    for _, p := range append(crudPermissions, crudRole...) {
-        if _, err := db.Exec(`INSERT INTO role_permissions (role_uuid, permission_uuid) VALUES ($1, $2)`, adminRole.UUID, p.UUID); err != nil {
-            log.Fatalf("Failed to assign permission %s to admin role: %v", p.Name, err)
-        }
-    }
-
-    // Assign permissions to user role
-    for _, p := range crudUser {
-        if _, err := db.Exec(`INSERT INTO role_permissions (role_uuid, permission_uuid) VALUES ($1, $2)`, userRole.UUID, p.UUID); err != nil {
-            log.Fatalf("Failed to assign permission %s to user role: %v", p.Name, err)
-        }
-    }
-
-    // Assign roles to user
-    for _, r := range []model.Role{adminRole, userRole} {
-        if _, err := db.Exec(`INSERT INTO user_roles (user_uuid, role_uuid) VALUES ($1, $2)`, user.UUID, r.UUID); err != nil {
-            log.Fatalf("Failed to assign role %s to user: %v", r.Name, err)
-        }
-    }
-
-    // Assign extra permission to user
-    if _, err := db.Exec(`INSERT INTO user_permissions (user_uuid, permission_uuid) VALUES ($1, $2)`, user.UUID, otherPermission.UUID); err != nil {
-        log.Fatalf("Failed to assign other permission to user: %v", err)
-    }
+//go:embed seeds/*.yml
+var seeds embed.FS
+
+// Profiles lists the built-in seed files in db/seeder/seeds.
+var Profiles = []string{"dev", "test", "demo"}
+
+var ErrUnknownProfile = errors.New("unknown seed profile")
+
+// wipeTables are emptied by a fresh seed, in foreign key order.
+var wipeTables = []string{"user_roles", "role_permissions", "user_permissions", "users", "roles", "permissions"}
+
+// File is a seed file. JSON files use the same keys.
+type File struct {
+	Permissions []string `yaml:"permissions"`
+	Roles       []Role   `yaml:"roles"`
+	Users       []User   `yaml:"users"`
+}
+
+type Role struct {
+	Name        string   `yaml:"name"`
+	Permissions []string `yaml:"permissions"`
+}
+
+// User is matched by email. Password is only used when the user is created,
+// so re-seeding never resets a password that was changed since.
+type User struct {
+	Name        string   `yaml:"name"`
+	Email       string   `yaml:"email"`
+	Password    string   `yaml:"password"`
+	Roles       []string `yaml:"roles"`
+	Permissions []string `yaml:"permissions"`
+}
+
+// LoadProfile reads one of the built-in Profiles.
+func LoadProfile(name string) (*File, error) {
+	data, err := seeds.ReadFile(path.Join("seeds", name+".yml"))
+	if err != nil {
+		return nil, fmt.Errorf("%w %q (want one of %v)", ErrUnknownProfile, name, Profiles)
+	}
+	return Parse(data)
+}
+
+// LoadFile reads a YAML or JSON seed file from disk.
+func LoadFile(name string) (*File, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// Parse decodes and validates a seed file. JSON is valid YAML, so both
+// formats go through the same decoder; unknown keys are rejected so a typo
+// does not silently seed less than intended.
+func Parse(data []byte) (*File, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	file := new(File)
+	if err := decoder.Decode(file); err != nil {
+		return nil, fmt.Errorf("parsing seed file: %w", err)
+	}
+	if err := file.Validate(); err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+// Validate checks that names are unique and every grant refers to a
+// permission or role declared in the same file.
+func (f *File) Validate() error {
+	permissions := map[string]bool{}
+	for _, name := range f.Permissions {
+		if name == "" || permissions[name] {
+			return fmt.Errorf("permission %q is empty or declared twice", name)
+		}
+		permissions[name] = true
+	}
+	roles := map[string]bool{}
+	for _, role := range f.Roles {
+		if role.Name == "" || roles[role.Name] {
+			return fmt.Errorf("role %q is empty or declared twice", role.Name)
+		}
+		roles[role.Name] = true
+		for _, permission := range role.Permissions {
+			if !permissions[permission] {
+				return fmt.Errorf("role %q grants undeclared permission %q", role.Name, permission)
+			}
+		}
+	}
+	emails := map[string]bool{}
+	for _, user := range f.Users {
+		if user.Email == "" || user.Name == "" || user.Password == "" {
+			return fmt.Errorf("user %q needs a name, email and password", user.Email)
+		}
+		if emails[user.Email] {
+			return fmt.Errorf("user %q is declared twice", user.Email)
+		}
+		emails[user.Email] = true
+		for _, role := range user.Roles {
+			if !roles[role] {
+				return fmt.Errorf("user %q has undeclared role %q", user.Email, role)
+			}
+		}
+		for _, permission := range user.Permissions {
+			if !permissions[permission] {
+				return fmt.Errorf("user %q has undeclared permission %q", user.Email, permission)
+			}
+		}
+	}
+	return nil
+}
+
+type Options struct {
+	// DryRun computes the plan without writing anything
+	DryRun bool
+	// Fresh deletes every user, role and permission before seeding
+	Fresh bool
+}
+
+// Action is one change in a seed plan, e.g. "create role admin".
+type Action struct {
+	Op     string `json:"op"` // wipe, create, update or grant
+	Target string `json:"target"`
+}
+
+func (a Action) String() string {
+	return a.Op + " " + a.Target
+}
+
+type Seeder struct {
+	db  *sql.DB
+	log *logrus.Logger
+	// cost is the bcrypt cost for new users' passwords
+	cost int
+}
+
+func New(db *sql.DB, log *logrus.Logger) *Seeder {
+	return &Seeder{db: db, log: log, cost: bcrypt.DefaultCost}
+}
+
+// Apply brings the database in line with file and returns the changes made,
+// or with DryRun the changes that would be made. An empty plan means the
+// database already matches.
+func (s *Seeder) Apply(ctx context.Context, file *File, opts Options) ([]Action, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	run := &seedRun{ctx: ctx, tx: tx, dryRun: opts.DryRun, fresh: opts.Fresh, cost: s.cost, created: map[string]bool{}}
+	if err := run.apply(file); err != nil {
+		return nil, err
+	}
+	if opts.DryRun {
+		return run.actions, nil
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	s.log.Infof("Seeding applied %d changes", len(run.actions))
+	return run.actions, nil
+}
+
+// seedRun holds the state of one Apply: existing rows are read inside the
+// transaction, and writes are skipped on a dry run.
+type seedRun struct {
+	ctx    context.Context
+	tx     *sql.Tx
+	dryRun bool
+	// fresh means the tables are (or on a dry run would be) empty
+	fresh bool
+	cost  int
+	// created holds the uuids inserted by this run, which have no grants yet
+	created map[string]bool
+	actions []Action
+}
+
+func (r *seedRun) exec(action Action, query string, args ...any) error {
+	r.actions = append(r.actions, action)
+	if r.dryRun {
+		return nil
+	}
+	if _, err := r.tx.ExecContext(r.ctx, query, args...); err != nil {
+		return fmt.Errorf("%s: %w", action, err)
+	}
+	return nil
+}
+
+func (r *seedRun) apply(file *File) error {
+	if r.fresh {
+		for _, table := range wipeTables {
+			if err := r.exec(Action{Op: "wipe", Target: table}, "DELETE FROM "+table); err != nil {
+				return err
+			}
+		}
+	}
+
+	permissions, err := r.ensureNamed("permission", "permissions", file.Permissions)
+	if err != nil {
+		return err
+	}
+	roleNames := make([]string, len(file.Roles))
+	for i, role := range file.Roles {
+		roleNames[i] = role.Name
+	}
+	roles, err := r.ensureNamed("role", "roles", roleNames)
+	if err != nil {
+		return err
+	}
+
+	for _, role := range file.Roles {
+		roleUUID := roles[role.Name]
+		granted, err := r.existing(`SELECT permission_uuid FROM role_permissions WHERE role_uuid = $1`, roleUUID)
+		if err != nil {
+			return err
+		}
+		for _, permission := range role.Permissions {
+			if granted[permissions[permission]] {
+				continue
+			}
+			err := r.exec(Action{Op: "grant", Target: fmt.Sprintf("permission %s to role %s", permission, role.Name)},
+				`INSERT INTO role_permissions (role_uuid, permission_uuid) VALUES ($1, $2) ON CONFLICT DO NOTHING`, roleUUID, permissions[permission])
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, user := range file.Users {
+		if err := r.ensureUser(user, roles, permissions); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ensureNamed creates the named rows of table that do not exist yet and
+// returns the uuid of every name.
+func (r *seedRun) ensureNamed(kind, table string, names []string) (map[string]string, error) {
+	uuids := map[string]string{}
+	if !r.fresh {
+		rows, err := r.tx.QueryContext(r.ctx, `SELECT uuid, name FROM `+table)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var id, name string
+			if err := rows.Scan(&id, &name); err != nil {
+				return nil, err
+			}
+			uuids[name] = id
+		}
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, name := range names {
+		if _, ok := uuids[name]; ok {
+			continue
+		}
+		uuids[name] = uuid.NewString()
+		r.created[uuids[name]] = true
+		err := r.exec(Action{Op: "create", Target: kind + " " + name},
+			`INSERT INTO `+table+` (uuid, name) VALUES ($1, $2) ON CONFLICT (name) DO NOTHING`, uuids[name], name)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return uuids, nil
+}
+
+func (r *seedRun) ensureUser(user User, roles, permissions map[string]string) error {
+	var userUUID, name string
+	if !r.fresh {
+		err := r.tx.QueryRowContext(r.ctx, `SELECT uuid, name FROM users WHERE lower(email) = lower($1) AND deleted_at IS NULL`, user.Email).Scan(&userUUID, &name)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+	}
+
+	switch {
+	case userUUID == "":
+		userUUID = uuid.NewString()
+		r.created[userUUID] = true
+		hashed, err := bcrypt.GenerateFromPassword([]byte(user.Password), r.cost)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		err = r.exec(Action{Op: "create", Target: "user " + user.Email},
+			`INSERT INTO users (uuid, name, email, password, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6)`,
+			userUUID, user.Name, user.Email, string(hashed), now, now)
+		if err != nil {
+			return err
+		}
+	case name != user.Name:
+		err := r.exec(Action{Op: "update", Target: fmt.Sprintf("user %s name to %q", user.Email, user.Name)},
+			`UPDATE users SET name = $1, updated_at = $2, version = version + 1 WHERE uuid = $3`, user.Name, time.Now(), userUUID)
+		if err != nil {
+			return err
+		}
+	}
+
+	granted, err := r.existing(`SELECT role_uuid FROM user_roles WHERE user_uuid = $1`, userUUID)
+	if err != nil {
+		return err
+	}
+	for _, role := range user.Roles {
+		if granted[roles[role]] {
+			continue
+		}
+		err := r.exec(Action{Op: "grant", Target: fmt.Sprintf("role %s to user %s", role, user.Email)},
+			`INSERT INTO user_roles (user_uuid, role_uuid) VALUES ($1, $2) ON CONFLICT DO NOTHING`, userUUID, roles[role])
+		if err != nil {
+			return err
+		}
+	}
+
+	granted, err = r.existing(`SELECT permission_uuid FROM user_permissions WHERE user_uuid = $1`, userUUID)
+	if err != nil {
+		return err
+	}
+	for _, permission := range user.Permissions {
+		if granted[permissions[permission]] {
+			continue
+		}
+		err := r.exec(Action{Op: "grant", Target: fmt.Sprintf("permission %s to user %s", permission, user.Email)},
+			`INSERT INTO user_permissions (user_uuid, permission_uuid) VALUES ($1, $2) ON CONFLICT DO NOTHING`, userUUID, permissions[permission])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// existing returns the set of uuids query selects for key; nothing exists
+// yet when the tables were wiped or key was created by this run.
+func (r *seedRun) existing(query string, key string) (map[string]bool, error) {
+	set := map[string]bool{}
+	if r.fresh || r.created[key] {
+		return set, nil
+	}
+	rows, err := r.tx.QueryContext(r.ctx, query, key)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		set[id] = true
+	}
+	return set, rows.Err()
 }
@@ -0,0 +1,190 @@
+package seeder
+
+import (
+	"context"
+	"errors"
+	"io"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const testSeed = `
+permissions: [read-user, update-user]
+roles:
+  - name: admin
+    permissions: [read-user, update-user]
+users:
+  - name: Alice
+    email: alice@example.com
+    password: secret
+    roles: [admin]
+    permissions: [read-user]
+`
+
+func setupSeeder(t *testing.T) (*Seeder, sqlmock.Sqlmock, *File) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+	s := New(db, log)
+	s.cost = bcrypt.MinCost
+
+	file, err := Parse([]byte(testSeed))
+	require.NoError(t, err)
+	return s, mock, file
+}
+
+func targets(actions []Action) []string {
+	out := make([]string, len(actions))
+	for i, action := range actions {
+		out[i] = action.String()
+	}
+	return out
+}
+
+func TestParse(t *testing.T) {
+	for _, profile := range Profiles {
+		_, err := LoadProfile(profile)
+		require.NoError(t, err, profile)
+	}
+	_, err := LoadProfile("prod")
+	require.ErrorIs(t, err, ErrUnknownProfile)
+
+	// JSON goes through the same decoder
+	file, err := Parse([]byte(`{"permissions": ["read-user"], "roles": [{"name": "viewer", "permissions": ["read-user"]}]}`))
+	require.NoError(t, err)
+	require.Equal(t, "viewer", file.Roles[0].Name)
+
+	cases := map[string]string{
+		"UnknownKey":           "permisions: [read-user]",
+		"DuplicatePermission":  "permissions: [a, a]",
+		"UndeclaredPermission": "roles: [{name: admin, permissions: [missing]}]",
+		"UndeclaredRole":       "users: [{name: A, email: a@example.com, password: x, roles: [missing]}]",
+		"MissingPassword":      "users: [{name: A, email: a@example.com}]",
+		"DuplicateUser":        "users: [{name: A, email: a@example.com, password: x}, {name: B, email: a@example.com, password: x}]",
+	}
+	for name, seed := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := Parse([]byte(seed))
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestSeeder_Apply(t *testing.T) {
+	t.Run("EmptyDatabase", func(t *testing.T) {
+		s, mock, file := setupSeeder(t)
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT uuid, name FROM permissions`)).WillReturnRows(sqlmock.NewRows([]string{"uuid", "name"}))
+		mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO permissions (uuid, name) VALUES ($1, $2) ON CONFLICT (name) DO NOTHING`)).
+			WithArgs(sqlmock.AnyArg(), "read-user").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO permissions`)).WithArgs(sqlmock.AnyArg(), "update-user").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT uuid, name FROM roles`)).WillReturnRows(sqlmock.NewRows([]string{"uuid", "name"}))
+		mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO roles`)).WithArgs(sqlmock.AnyArg(), "admin").WillReturnResult(sqlmock.NewResult(0, 1))
+		// New rows have no grants, so they are not looked up
+		mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO role_permissions`)).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO role_permissions`)).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT uuid, name FROM users WHERE lower(email) = lower($1) AND deleted_at IS NULL`)).
+			WithArgs("alice@example.com").WillReturnRows(sqlmock.NewRows([]string{"uuid", "name"}))
+		mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO users`)).
+			WithArgs(sqlmock.AnyArg(), "Alice", "alice@example.com", sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO user_roles`)).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO user_permissions`)).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		actions, err := s.Apply(context.Background(), file, Options{})
+		require.NoError(t, err)
+		require.Equal(t, []string{
+			"create permission read-user",
+			"create permission update-user",
+			"create role admin",
+			"grant permission read-user to role admin",
+			"grant permission update-user to role admin",
+			"create user alice@example.com",
+			"grant role admin to user alice@example.com",
+			"grant permission read-user to user alice@example.com",
+		}, targets(actions))
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("AlreadySeededIsNoOp", func(t *testing.T) {
+		s, mock, file := setupSeeder(t)
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`FROM permissions`)).
+			WillReturnRows(sqlmock.NewRows([]string{"uuid", "name"}).AddRow("p1", "read-user").AddRow("p2", "update-user"))
+		mock.ExpectQuery(regexp.QuoteMeta(`FROM roles`)).WillReturnRows(sqlmock.NewRows([]string{"uuid", "name"}).AddRow("r1", "admin"))
+		mock.ExpectQuery(regexp.QuoteMeta(`FROM role_permissions WHERE role_uuid = $1`)).WithArgs("r1").
+			WillReturnRows(sqlmock.NewRows([]string{"permission_uuid"}).AddRow("p1").AddRow("p2"))
+		mock.ExpectQuery(regexp.QuoteMeta(`FROM users`)).WithArgs("alice@example.com").
+			WillReturnRows(sqlmock.NewRows([]string{"uuid", "name"}).AddRow("u1", "Alice"))
+		mock.ExpectQuery(regexp.QuoteMeta(`FROM user_roles WHERE user_uuid = $1`)).WithArgs("u1").
+			WillReturnRows(sqlmock.NewRows([]string{"role_uuid"}).AddRow("r1"))
+		mock.ExpectQuery(regexp.QuoteMeta(`FROM user_permissions WHERE user_uuid = $1`)).WithArgs("u1").
+			WillReturnRows(sqlmock.NewRows([]string{"permission_uuid"}).AddRow("p1"))
+		mock.ExpectCommit()
+
+		actions, err := s.Apply(context.Background(), file, Options{})
+		require.NoError(t, err)
+		require.Empty(t, actions)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("DryRunPlansMissingGrantAndRename", func(t *testing.T) {
+		s, mock, file := setupSeeder(t)
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`FROM permissions`)).
+			WillReturnRows(sqlmock.NewRows([]string{"uuid", "name"}).AddRow("p1", "read-user").AddRow("p2", "update-user"))
+		mock.ExpectQuery(regexp.QuoteMeta(`FROM roles`)).WillReturnRows(sqlmock.NewRows([]string{"uuid", "name"}).AddRow("r1", "admin"))
+		mock.ExpectQuery(regexp.QuoteMeta(`FROM role_permissions`)).WithArgs("r1").
+			WillReturnRows(sqlmock.NewRows([]string{"permission_uuid"}).AddRow("p1"))
+		mock.ExpectQuery(regexp.QuoteMeta(`FROM users`)).WithArgs("alice@example.com").
+			WillReturnRows(sqlmock.NewRows([]string{"uuid", "name"}).AddRow("u1", "Alicia"))
+		mock.ExpectQuery(regexp.QuoteMeta(`FROM user_roles`)).WithArgs("u1").
+			WillReturnRows(sqlmock.NewRows([]string{"role_uuid"}).AddRow("r1"))
+		mock.ExpectQuery(regexp.QuoteMeta(`FROM user_permissions`)).WithArgs("u1").
+			WillReturnRows(sqlmock.NewRows([]string{"permission_uuid"}).AddRow("p1"))
+		// Nothing is written and the transaction is rolled back
+		mock.ExpectRollback()
+
+		actions, err := s.Apply(context.Background(), file, Options{DryRun: true})
+		require.NoError(t, err)
+		require.Equal(t, []string{
+			"grant permission update-user to role admin",
+			`update user alice@example.com name to "Alice"`,
+		}, targets(actions))
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("FreshDryRunSkipsReads", func(t *testing.T) {
+		s, mock, file := setupSeeder(t)
+		mock.ExpectBegin()
+		mock.ExpectRollback()
+
+		actions, err := s.Apply(context.Background(), file, Options{DryRun: true, Fresh: true})
+		require.NoError(t, err)
+		require.Len(t, actions, len(wipeTables)+8)
+		require.Equal(t, "wipe user_roles", actions[0].String())
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("ErrorRollsBack", func(t *testing.T) {
+		s, mock, file := setupSeeder(t)
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`FROM permissions`)).WillReturnRows(sqlmock.NewRows([]string{"uuid", "name"}))
+		mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO permissions`)).WillReturnError(errors.New("permission denied"))
+		mock.ExpectRollback()
+
+		_, err := s.Apply(context.Background(), file, Options{})
+		require.ErrorContains(t, err, "create permission read-user: permission denied")
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -1,16 +1,30 @@
 package app
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"go-starter-template/db/migration"
+	"go-starter-template/db/migrator"
+	"go-starter-template/internal/config/database"
 	"go-starter-template/internal/config/env"
 	"go-starter-template/internal/config/validation"
+	"go-starter-template/internal/constant"
 	"go-starter-template/internal/controller"
+	"go-starter-template/internal/event"
+	"go-starter-template/internal/job"
 	"go-starter-template/internal/middleware"
+	"go-starter-template/internal/notify"
+	"go-starter-template/internal/queue"
 	"go-starter-template/internal/repository"
 	"go-starter-template/internal/route"
+	"go-starter-template/internal/scheduler"
 	"go-starter-template/internal/service"
-	"log"
+	"go-starter-template/internal/utils/geoip"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/redis/go-redis/v9"
@@ -19,49 +33,253 @@ import (
 
 type BootstrapConfig struct {
 	db         *sql.DB
+	replicas   *database.Replicas
 	web        *fiber.App
 	log        *logrus.Logger
 	config     *env.Config
 	validation *validation.Validation
 	redis      *redis.Client
+	scheduler  *scheduler.Scheduler
+	outboxJob  *job.OutboxRelayJob
+	webhookJob *job.WebhookDeliveryJob
+	// notifier sends notifications in the background when jobs are disabled
+	notifier *notify.Async
 }
 
-func NewApp(log *logrus.Logger, config *env.Config, db *sql.DB, web *fiber.App, validation *validation.Validation, redis *redis.Client) *BootstrapConfig {
-	return &BootstrapConfig{db, web, log, config, validation, redis}
+func NewApp(log *logrus.Logger, config *env.Config, db *sql.DB, replicas *database.Replicas, web *fiber.App, validation *validation.Validation, redis *redis.Client) *BootstrapConfig {
+	// Migrate before any route can reach the schema
+	if config.Database.MigrateOnStartup {
+		if err := migrateOnStartup(log, db); err != nil {
+			log.Fatalf("Failed to migrate database: %v", err)
+		}
+	}
+	return &BootstrapConfig{db: db, replicas: replicas, web: web, log: log, config: config, validation: validation, redis: redis}
+}
+
+func migrateOnStartup(log *logrus.Logger, db *sql.DB) error {
+	m, err := migrator.New(db, log, migration.FS)
+	if err != nil {
+		return err
+	}
+	return m.Up(context.Background())
 }
 
 func (app *BootstrapConfig) Bootstrap() {
+	location, err := app.config.GetSchedulerLocation()
+	if err != nil {
+		app.log.Fatalf("Invalid scheduler.timezone: %v", err)
+	}
+	app.scheduler = scheduler.New(app.redis, app.log, scheduler.Options{
+		Prefix:    app.config.GetSchedulerPrefix(),
+		Location:  location,
+		LockTTL:   app.config.GetSchedulerLockTTL(),
+		Tolerance: app.config.GetSchedulerTolerance(),
+	})
+
     // setup repositories
     userRepository := repository.NewUserRepository(app.db)
+    policyRepository := repository.NewPolicyRepository(app.db)
+    outboxRepository := repository.NewOutboxRepository(app.db)
+    webhookRepository := repository.NewWebhookRepository(app.db)
+    auditRepository := repository.NewAuditRepository(app.db)
+    loginEventRepository := repository.NewLoginEventRepository(app.db)
     blacklistRepository := repository.NewRedisTokenBlacklist(app.redis)
     uow := repository.NewUnitOfWork(app.db)
+    if app.replicas != nil {
+        userRepository.UseReplicas(app.replicas)
+    }
+    if enabled, err := userRepository.DetectTextSearch(context.Background()); err != nil {
+        app.log.WithError(err).Warn("Could not detect full-text search support, falling back to ILIKE")
+    } else if !enabled {
+        app.log.Info("pg_trgm or users.search_vector missing, user search falls back to ILIKE")
+    }
 
 	// setup use service
 	jwtService := service.NewJwtService(app.log, app.config)
+	jwtService.UseKeyStore(repository.NewSigningKeyRepository(app.redis))
 	blacklistService := service.NewBlacklistService(app.log, jwtService, blacklistRepository)
-    authService := service.NewAuthService(jwtService, userRepository, blacklistService, app.log, uow)
+	auditService := service.NewAuditService(auditRepository, app.log, uow)
+	loginHistoryService := service.NewLoginHistoryService(loginEventRepository, app.newGeoIP(), app.log, uow)
+    authService := service.NewAuthService(jwtService, userRepository, outboxRepository, auditService, loginHistoryService, blacklistService, app.log, uow)
 	redisService := service.NewRedisService(app.redis, app.log)
-	userService := service.NewUserService(userRepository, redisService, app.log)
+	userService := service.NewUserService(userRepository, outboxRepository, auditService, redisService, app.log, uow)
+	notifier := app.newNotifier()
+	userService.UseNotifier(notifier)
+	userService.UseBlacklist(blacklistService)
+	loginHistoryService.UseNotifier(notifier)
+	policyService := service.NewPolicyService(policyRepository, userRepository, app.log)
+	webhookService := service.NewWebhookService(webhookRepository, repository.NewWebhookQueue(app.redis), app.log, app.config, uow)
+	// Webhook deliveries are recorded in the relay's transaction, so they go
+	// first: if they fail, nothing has left the database yet
+	var outboxSinks event.Fanout
+	if app.config.Webhooks.Enabled {
+		outboxSinks = append(outboxSinks, webhookService)
+	}
+	if sink := app.newOutboxSink(); sink != nil {
+		outboxSinks = append(outboxSinks, sink)
+	}
+	outboxService := service.NewOutboxService(outboxRepository, outboxSinks, app.log, uow, app.config.GetOutboxMaxAttempts())
 
 	// setup controller
 	welcomeController := controller.NewWelcomeController()
 	authController := controller.NewAuthController(authService, app.log, app.validation, app.config)
-	userController := controller.NewUserController(userService, app.log)
+	userController := controller.NewUserController(userService, app.log, app.validation, app.config)
+	authzController := controller.NewAuthzController(policyService, app.log, app.validation)
+	webhookController := controller.NewWebhookController(webhookService, app.log, app.validation)
+	schedulerController := controller.NewSchedulerController(app.scheduler, app.log)
+	auditController := controller.NewAuditController(auditService, app.log)
+	loginHistoryController := controller.NewLoginHistoryController(loginHistoryService, app.log)
 
 	// setup middleware
-	authMiddleware := middleware.AuthMiddleware(jwtService, blacklistService, app.log)
+	authMiddleware := middleware.AuthMiddleware(jwtService, blacklistService, authService, app.log)
+	authorize := middleware.Authorize(policyService)
+
+	// setup background jobs
+	purgeJob := job.NewUserPurgeJob(userService, app.log, app.config.GetSoftDeleteRetention())
+	app.schedule("purge_deleted_users", "@hourly", constant.MissedRunOnce, purgeJob.Run)
+	// Blacklist entries must outlive the longest token, or the 24 hours Add
+	// falls back to
+	blacklistMaxAge := max(app.config.GetAccessTokenExpiration(), app.config.GetRefreshTokenExpiration(), 24*time.Hour)
+	pruneJob := job.NewBlacklistPruneJob(blacklistService, app.log, blacklistMaxAge)
+	app.schedule("prune_token_blacklist", "@daily", constant.MissedRunSkip, pruneJob.Run)
+	rotationJob := job.NewKeyRotationJob(jwtService, app.log)
+	app.schedule("rotate_signing_keys", "@weekly", constant.MissedRunOnce, rotationJob.Run)
+	outboxInterval := app.config.GetOutboxInterval()
+	if len(outboxSinks) == 0 {
+		outboxInterval = 0
+	}
+	app.outboxJob = job.NewOutboxRelayJob(outboxService, app.log, outboxInterval, app.config.GetOutboxBatchSize(), app.config.GetOutboxRetention())
+	var webhookInterval time.Duration
+	if app.config.Webhooks.Enabled {
+		webhookInterval = app.config.GetWebhookPollInterval()
+	}
+	app.webhookJob = job.NewWebhookDeliveryJob(webhookService, app.log, webhookInterval, app.config.GetWebhookBatchSize())
 
 	// setup route
+	app.web.Use(middleware.AuditRequest())
+	app.web.Use(middleware.Locale())
+	if app.replicas != nil {
+		app.web.Use(middleware.ReadYourWrites(app.config.GetReplicaSticky()))
+	}
 	routeConfig := route.NewRouteConfig(app.web)
 	routeConfig.WelcomeRoutes(welcomeController)
 	routeConfig.RegisterAuthRoutes(authController)
-	routeConfig.RegisterUserRoutes(userController, authMiddleware)
+	routeConfig.RegisterUserRoutes(userController, loginHistoryController, authMiddleware, authorize)
+	routeConfig.RegisterAuthzRoutes(authzController, authMiddleware)
+	routeConfig.RegisterWebhookRoutes(webhookController, authMiddleware, authorize)
+	routeConfig.RegisterAuditRoutes(auditController, authMiddleware, authorize)
+	routeConfig.RegisterAdminRoutes(schedulerController, authMiddleware, authorize)
+}
+
+// schedule registers a task with the scheduler, letting scheduler.tasks.<name>
+// override its schedule and missed-run policy or disable it.
+func (app *BootstrapConfig) schedule(name, spec string, missed constant.MissedRunPolicy, run func(ctx context.Context) error) {
+	override := app.config.GetScheduledTask(name)
+	if override.Disabled {
+		app.log.Infof("Scheduled task %s is disabled", name)
+		return
+	}
+	if override.Schedule != "" {
+		spec = override.Schedule
+	}
+	if override.Missed != "" {
+		missed = constant.MissedRunPolicy(override.Missed)
+	}
+	if err := app.scheduler.Register(name, spec, missed, run); err != nil {
+		app.log.Fatalf("Failed to schedule %s: %v", name, err)
+	}
 }
 
+// newGeoIP loads the database at geoip.path, or returns nil when none is
+// configured, which leaves login locations unknown.
+func (app *BootstrapConfig) newGeoIP() *geoip.DB {
+	if app.config.GeoIP.Path == "" {
+		return nil
+	}
+	db, err := geoip.Open(app.config.GeoIP.Path)
+	if err != nil {
+		app.log.Fatalf("Failed to load GeoIP database: %v", err)
+	}
+	app.log.Infof("Loaded %d GeoIP ranges from %s", db.Len(), app.config.GeoIP.Path)
+	return db
+}
+
+// newNotifier returns the notifier configured under notify. Messages go
+// through the job queue when jobs are enabled, otherwise through app.notifier,
+// so requests never wait for delivery.
+func (app *BootstrapConfig) newNotifier() notify.Notifier {
+	dispatcher, err := notify.New(app.config)
+	if err != nil {
+		app.log.Fatalf("Failed to set up notifications: %v", err)
+	}
+	// Built even for the job queue, so a bad notify section fails here too
+	if app.config.Jobs.Enabled {
+		return notify.NewQueued(queue.NewClient(app.redis, app.config.GetJobPrefix(), app.config.GetJobMaxAttempts()))
+	}
+	app.notifier = notify.NewAsync(dispatcher, app.log, app.config.GetNotifyAsyncWorkers(), app.config.GetNotifyAsyncQueueSize())
+	return app.notifier
+}
+
+// newOutboxSink returns the sink configured by outbox.sink, or nil for none,
+// which leaves events in the outbox unpublished.
+func (app *BootstrapConfig) newOutboxSink() event.Sink {
+	switch sink := app.config.GetOutboxSink(); sink {
+	case "redis":
+		return event.NewRedisStreamSink(app.redis, app.config.GetOutboxStreamPrefix(), app.config.Outbox.Stream.MaxLen)
+	case "webhook":
+		if app.config.Outbox.Webhook.URL == "" {
+			app.log.Fatal("outbox.webhook.url is required for the webhook sink")
+		}
+		return event.NewWebhookSink(app.config.Outbox.Webhook.URL, app.config.GetOutboxWebhookTimeout())
+	case "none":
+		return nil
+	default:
+		app.log.Fatalf("unknown outbox sink %q", sink)
+		return nil
+	}
+}
+
+// Run serves requests until SIGINT or SIGTERM, then lets in-flight requests
+// finish within web.shutdown_timeout and stops the background components,
+// which drain their own work, before returning.
 func (app *BootstrapConfig) Run() {
 	app.Bootstrap()
-	err := app.web.Listen(fmt.Sprintf(":%d", app.config.Web.Port))
-	if err != nil {
-		log.Fatalf("Failed to start server: %v", err)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if app.notifier != nil {
+		app.notifier.Start()
+		defer app.notifier.Stop()
+	}
+	app.scheduler.Start(context.Background())
+	defer app.scheduler.Stop()
+	app.outboxJob.Start(context.Background())
+	defer app.outboxJob.Stop()
+	app.webhookJob.Start(context.Background())
+	defer app.webhookJob.Stop()
+	app.replicas.Start(context.Background())
+	defer app.replicas.Stop()
+
+	listenErr := make(chan error, 1)
+	go func() {
+		listenErr <- app.web.Listen(fmt.Sprintf(":%d", app.config.Web.Port))
+	}()
+	select {
+	case err := <-listenErr:
+		// Not log.Fatal, which would skip stopping the components
+		if err != nil {
+			app.log.WithError(err).Error("Failed to start server")
+		}
+		return
+	case <-ctx.Done():
+	}
+
+	app.log.Info("shutting down server")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), app.config.GetWebShutdownTimeout())
+	defer cancel()
+	if err := app.web.ShutdownWithContext(shutdownCtx); err != nil {
+		app.log.WithError(err).Warn("in-flight requests were interrupted")
 	}
+	<-listenErr
 }
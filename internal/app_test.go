@@ -5,15 +5,21 @@ import (
     "io"
     "net/http"
     "net/http/httptest"
+    "os"
+    "regexp"
+    "syscall"
     "testing"
     "time"
 
 	sqlmock "github.com/DATA-DOG/go-sqlmock"
 	miniredis "github.com/alicebob/miniredis/v2"
+	"github.com/gofiber/fiber/v2"
 	"github.com/redis/go-redis/v9"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/require"
 
+	"go-starter-template/db/migration"
+	"go-starter-template/db/migrator"
 	"go-starter-template/internal/config/env"
 	"go-starter-template/internal/config/validation"
 	webcfg "go-starter-template/internal/config/web"
@@ -50,7 +56,7 @@ func TestApp_Bootstrap(t *testing.T) {
 	fib := webcfg.NewFiber(cfg)
 
 	validator := validation.NewValidation()
-	boot := NewApp(logger, cfg, db, fib, validator, rdb)
+	boot := NewApp(logger, cfg, db, nil, fib, validator, rdb)
 	boot.Bootstrap()
 
 	type testcase struct {
@@ -93,6 +99,12 @@ func TestApp_Bootstrap(t *testing.T) {
 			path:         "/api/users/",
 			expectStatus: http.StatusUnauthorized,
 		},
+		{
+			name:         "AuthzCheck_UnauthorizedWithoutToken",
+			method:       http.MethodPost,
+			path:         "/api/authz/check",
+			expectStatus: http.StatusUnauthorized,
+		},
 		{
 			name:         "AuthLogout_UnauthorizedWithoutHeader",
 			method:       http.MethodPost,
@@ -150,7 +162,7 @@ func TestApp_Run_StartAndShutdown(t *testing.T) {
 
     fib := webcfg.NewFiber(cfg)
     validator := validation.NewValidation()
-    boot := NewApp(logger, cfg, db, fib, validator, rdb)
+    boot := NewApp(logger, cfg, db, nil, fib, validator, rdb)
 
     done := make(chan struct{})
     go func() {
@@ -171,3 +183,76 @@ func TestApp_Run_StartAndShutdown(t *testing.T) {
         t.Fatal("Run did not return after Shutdown")
     }
 }
+
+// Run shuts the server down and returns on SIGTERM instead of exiting.
+func TestApp_Run_ShutdownOnSignal(t *testing.T) {
+	db, _, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	cfg := &env.Config{}
+	cfg.App.Name = "TestApp"
+	cfg.Web.Cors.AllowOrigins = "http://example.com"
+	cfg.Web.Port = 0
+	cfg.Web.ShutdownTimeout = 1
+
+	fib := webcfg.NewFiber(cfg)
+	boot := NewApp(logger, cfg, db, nil, fib, validation.NewValidation(), rdb)
+	// Run subscribes to the signals before it starts listening
+	listening := make(chan struct{})
+	fib.Hooks().OnListen(func(fiber.ListenData) error {
+		close(listening)
+		return nil
+	})
+
+	done := make(chan struct{})
+	go func() {
+		boot.Run()
+		close(done)
+	}()
+
+	select {
+	case <-listening:
+	case <-time.After(2 * time.Second):
+		t.Fatal("server did not start")
+	}
+	require.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGTERM))
+
+	select {
+	case <-done:
+	case <-time.After(3 * time.Second):
+		t.Fatal("Run did not return after SIGTERM")
+	}
+}
+
+// NewApp applies pending migrations when configured to, before routes exist.
+func TestApp_NewApp_MigrateOnStartup(t *testing.T) {
+    db, mock, err := sqlmock.New()
+    require.NoError(t, err)
+    defer db.Close()
+
+    logger := logrus.New()
+    logger.SetOutput(io.Discard)
+
+    cfg := &env.Config{}
+    cfg.Database.MigrateOnStartup = true
+
+    embedded, err := migrator.New(nil, nil, migration.FS)
+    require.NoError(t, err)
+
+    // Already at the latest version: the runner locks, reads the version and stops
+    mock.ExpectExec(regexp.QuoteMeta(`SELECT pg_advisory_lock($1)`)).WillReturnResult(sqlmock.NewResult(0, 0))
+    mock.ExpectExec(regexp.QuoteMeta(`CREATE TABLE IF NOT EXISTS schema_migrations`)).WillReturnResult(sqlmock.NewResult(0, 0))
+    mock.ExpectQuery(regexp.QuoteMeta(`SELECT version, dirty FROM schema_migrations`)).
+        WillReturnRows(sqlmock.NewRows([]string{"version", "dirty"}).AddRow(int64(embedded.Latest()), false))
+    mock.ExpectExec(regexp.QuoteMeta(`SELECT pg_advisory_unlock($1)`)).WillReturnResult(sqlmock.NewResult(0, 0))
+
+    NewApp(logger, cfg, db, nil, nil, nil, nil)
+    require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,140 @@
+// Package audit carries who made a request through its context and computes
+// the diffs and chained hashes stored in the audit log.
+package audit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"reflect"
+	"time"
+
+	"go-starter-template/internal/model"
+
+	"github.com/goccy/go-json"
+)
+
+const (
+	ActionLogin          = "auth.login"
+	ActionLoginFailed    = "auth.login_failed"
+	ActionTokenRefreshed = "auth.token_refreshed"
+	ActionLogout         = "auth.logout"
+	ActionUserPurged     = "user.purged"
+)
+
+// TargetUser is the target type of actions on a user.
+const TargetUser = "user"
+
+// GenesisHash is the previous hash of the first event in the log.
+const GenesisHash = "0000000000000000000000000000000000000000000000000000000000000000"
+
+// Request describes the client behind a request.
+type Request struct {
+	ActorUUID string
+	IP        string
+	UserAgent string
+}
+
+type contextKey struct{}
+
+// WithRequest stores the client's address and user agent in ctx.
+func WithRequest(ctx context.Context, ip, userAgent string) context.Context {
+	request := FromContext(ctx)
+	request.IP, request.UserAgent = ip, userAgent
+	return context.WithValue(ctx, contextKey{}, request)
+}
+
+// WithActor stores the authenticated user in ctx.
+func WithActor(ctx context.Context, actorUUID string) context.Context {
+	request := FromContext(ctx)
+	request.ActorUUID = actorUUID
+	return context.WithValue(ctx, contextKey{}, request)
+}
+
+// FromContext returns what WithRequest and WithActor stored, empty outside
+// a request.
+func FromContext(ctx context.Context) Request {
+	request, _ := ctx.Value(contextKey{}).(Request)
+	return request
+}
+
+// Change is the value of a field before and after an action.
+type Change struct {
+	Before any `json:"before"`
+	After  any `json:"after"`
+}
+
+// Diff compares the JSON encodings of before and after field by field and
+// returns the fields that differ. Either side may be nil, e.g. on creation.
+func Diff(before, after any) (map[string]Change, error) {
+	beforeFields, err := fields(before)
+	if err != nil {
+		return nil, err
+	}
+	afterFields, err := fields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := map[string]Change{}
+	for name, value := range afterFields {
+		if previous, ok := beforeFields[name]; !ok || !reflect.DeepEqual(previous, value) {
+			changes[name] = Change{Before: previous, After: value}
+		}
+	}
+	for name, value := range beforeFields {
+		if _, ok := afterFields[name]; !ok {
+			changes[name] = Change{Before: value}
+		}
+	}
+	return changes, nil
+}
+
+func fields(v any) (map[string]any, error) {
+	if v == nil || reflect.ValueOf(v).Kind() == reflect.Pointer && reflect.ValueOf(v).IsNil() {
+		return nil, nil
+	}
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]any
+	return fields, json.Unmarshal(raw, &fields)
+}
+
+// Hash returns the hash of e chained to prevHash. It covers every column but
+// id, which the database assigns, so CreatedAt must already be set as stored:
+// in UTC and truncated to microseconds. Changes and Metadata are hashed in a
+// canonical encoding since JSONB does not keep the bytes it was given.
+func Hash(prevHash string, e *model.AuditEvent) string {
+	// Encoding the fields as a JSON array keeps their boundaries unambiguous
+	content, _ := json.Marshal([]string{
+		prevHash,
+		e.UUID,
+		e.ActorUUID,
+		e.Action,
+		e.TargetType,
+		e.TargetID,
+		e.IP,
+		e.UserAgent,
+		e.TraceID,
+		canonical(e.Changes),
+		canonical(e.Metadata),
+		e.CreatedAt.UTC().Format(time.RFC3339Nano),
+	})
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// canonical re-encodes a JSON document with sorted keys and no whitespace.
+func canonical(raw []byte) string {
+	var v any
+	if len(raw) == 0 || json.Unmarshal(raw, &v) != nil {
+		return string(raw)
+	}
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		return string(raw)
+	}
+	return string(encoded)
+}
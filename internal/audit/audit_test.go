@@ -0,0 +1,103 @@
+package audit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"go-starter-template/internal/model"
+)
+
+func TestContext(t *testing.T) {
+	require.Equal(t, Request{}, FromContext(context.Background()))
+
+	ctx := WithRequest(context.Background(), "10.0.0.1", "curl/8.0")
+	ctx = WithActor(ctx, "u1")
+	require.Equal(t, Request{ActorUUID: "u1", IP: "10.0.0.1", UserAgent: "curl/8.0"}, FromContext(ctx))
+}
+
+func TestDiff(t *testing.T) {
+	type user struct {
+		Name  string  `json:"name"`
+		Email string  `json:"email"`
+		Phone *string `json:"phone"`
+	}
+	phone := "+4912345"
+
+	cases := []struct {
+		name   string
+		before any
+		after  any
+		expect map[string]Change
+	}{
+		{
+			name:   "Changed",
+			before: user{Name: "Alice", Email: "a@example.com"},
+			after:  user{Name: "Alice", Email: "alice@example.com", Phone: &phone},
+			expect: map[string]Change{
+				"email": {Before: "a@example.com", After: "alice@example.com"},
+				"phone": {Before: nil, After: phone},
+			},
+		},
+		{
+			name:   "Created",
+			before: (*user)(nil),
+			after:  &user{Name: "Alice"},
+			expect: map[string]Change{
+				"name":  {After: "Alice"},
+				"email": {After: ""},
+				"phone": {},
+			},
+		},
+		{
+			name:   "Deleted",
+			before: user{Name: "Alice"},
+			expect: map[string]Change{
+				"name":  {Before: "Alice"},
+				"email": {Before: ""},
+				"phone": {},
+			},
+		},
+		{
+			name:   "Unchanged",
+			before: user{Name: "Alice"},
+			after:  user{Name: "Alice"},
+			expect: map[string]Change{},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			changes, err := Diff(tc.before, tc.after)
+			require.NoError(t, err)
+			require.Equal(t, tc.expect, changes)
+		})
+	}
+}
+
+func TestHash(t *testing.T) {
+	e := &model.AuditEvent{
+		UUID:      "e1",
+		ActorUUID: "u1",
+		Action:    ActionLogin,
+		Changes:   []byte(`{"name":{"before":"a","after":"b"}}`),
+		Metadata:  []byte(`{}`),
+		CreatedAt: time.Date(2025, 1, 15, 10, 0, 0, 123456000, time.UTC),
+	}
+	hash := Hash(GenesisHash, e)
+	require.Len(t, hash, 64)
+
+	// JSONB hands the document back re-encoded; the hash must not change
+	stored := *e
+	stored.Changes = []byte(`{"name": {"after": "b", "before": "a"}}`)
+	stored.CreatedAt = e.CreatedAt.In(time.FixedZone("CET", 3600))
+	require.Equal(t, hash, Hash(GenesisHash, &stored))
+
+	// Any other change to the content or the chain does
+	tampered := *e
+	tampered.ActorUUID = "u2"
+	require.NotEqual(t, hash, Hash(GenesisHash, &tampered))
+	require.NotEqual(t, hash, Hash(hash, e))
+}
@@ -26,13 +26,7 @@ func NewDatabase(log *logrus.Logger, config *env.Config) *sql.DB {
         log.Fatalf("failed to open sql database: %v", err)
     }
 
-	idleConnection := config.Database.Pool.Idle
-	maxConnection := config.Database.Pool.Max
-	maxLifeTimeConnection := config.Database.Pool.Lifetime
-
-	sqlDB.SetMaxIdleConns(idleConnection)
-	sqlDB.SetMaxOpenConns(maxConnection)
-	sqlDB.SetConnMaxLifetime(time.Duration(maxLifeTimeConnection) * time.Second)
+	configurePool(sqlDB, config)
 
 	if err := sqlDB.Ping(); err != nil {
 		log.Fatalf("failed to ping sql database: %v", err)
@@ -41,3 +35,15 @@ func NewDatabase(log *logrus.Logger, config *env.Config) *sql.DB {
 	log.Info("SQL database connection established successfully")
 	return sqlDB
 }
+
+// configurePool applies the database.pool settings, shared by the primary and
+// the read replicas.
+func configurePool(sqlDB *sql.DB, config *env.Config) {
+	idleConnection := config.Database.Pool.Idle
+	maxConnection := config.Database.Pool.Max
+	maxLifeTimeConnection := config.Database.Pool.Lifetime
+
+	sqlDB.SetMaxIdleConns(idleConnection)
+	sqlDB.SetMaxOpenConns(maxConnection)
+	sqlDB.SetConnMaxLifetime(time.Duration(maxLifeTimeConnection) * time.Second)
+}
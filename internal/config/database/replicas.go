@@ -0,0 +1,134 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go-starter-template/internal/config/env"
+
+	"github.com/sirupsen/logrus"
+	"github.com/uptrace/opentelemetry-go-extra/otelsql"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+)
+
+// pingTimeout bounds a single replica health check.
+const pingTimeout = 2 * time.Second
+
+// Replicas holds connection pools to read replicas and tracks which of them
+// are healthy, so reads can be spread across them and fall back to the
+// primary when none is.
+type Replicas struct {
+	log      *logrus.Logger
+	replicas []*replica
+	next     atomic.Uint64
+	interval time.Duration
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+}
+
+type replica struct {
+	// name identifies the replica in logs without leaking credentials
+	name    string
+	db      *sql.DB
+	healthy atomic.Bool
+}
+
+// NewReplicas opens a pool for every config.Database.Replicas.DSNs entry with
+// the primary's pool settings and returns nil if none are configured. Unlike
+// the primary an unreachable replica is not fatal: it is left out of rotation
+// until a health check succeeds.
+func NewReplicas(log *logrus.Logger, config *env.Config) *Replicas {
+	dsns := config.Database.Replicas.DSNs
+	if len(dsns) == 0 {
+		return nil
+	}
+
+	r := &Replicas{log: log, interval: config.GetReplicaHealthInterval()}
+	for i, dsn := range dsns {
+		db, err := sqlOpen("pgx", dsn, otelsql.WithAttributes(semconv.DBSystemPostgreSQL))
+		if err != nil {
+			log.Fatalf("failed to open read replica %d: %v", i, err)
+		}
+		configurePool(db, config)
+		r.replicas = append(r.replicas, &replica{name: fmt.Sprintf("replica-%d", i), db: db})
+	}
+	r.Check(context.Background())
+	log.WithField("replicas", len(r.replicas)).Info("SQL read replicas configured")
+	return r
+}
+
+// Reader returns the next healthy replica round-robin, or nil when there are
+// no replicas or none is healthy so the caller uses the primary instead.
+func (r *Replicas) Reader() *sql.DB {
+	if r == nil {
+		return nil
+	}
+	n := uint64(len(r.replicas))
+	start := r.next.Add(1)
+	for i := range n {
+		if candidate := r.replicas[(start+i)%n]; candidate.healthy.Load() {
+			return candidate.db
+		}
+	}
+	return nil
+}
+
+// Check pings every replica once and updates its health, logging changes.
+func (r *Replicas) Check(ctx context.Context) {
+	for _, replica := range r.replicas {
+		pingCtx, cancel := context.WithTimeout(ctx, pingTimeout)
+		err := replica.db.PingContext(pingCtx)
+		cancel()
+
+		healthy := err == nil
+		if replica.healthy.Swap(healthy) == healthy {
+			continue
+		}
+		if healthy {
+			r.log.WithField("replica", replica.name).Info("read replica is healthy, routing reads to it")
+		} else {
+			r.log.WithField("replica", replica.name).WithError(err).Warn("read replica is unhealthy, routing its reads elsewhere")
+		}
+	}
+}
+
+// Start runs Check every health interval in the background until Stop is
+// called or ctx is cancelled.
+func (r *Replicas) Start(ctx context.Context) {
+	if r == nil {
+		return
+	}
+	ctx, r.cancel = context.WithCancel(ctx)
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				r.Check(ctx)
+			}
+		}
+	}()
+}
+
+// Stop ends the health checks and closes the replica pools.
+func (r *Replicas) Stop() {
+	if r == nil {
+		return
+	}
+	if r.cancel != nil {
+		r.cancel()
+	}
+	r.wg.Wait()
+	for _, replica := range r.replicas {
+		_ = replica.db.Close()
+	}
+}
@@ -0,0 +1,90 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"github.com/uptrace/opentelemetry-go-extra/otelsql"
+)
+
+func setupReplicas(t *testing.T, n int) (*Replicas, []*sql.DB, []sqlmock.Sqlmock) {
+	t.Helper()
+	dbs := map[string]*sql.DB{}
+	var (
+		ordered []*sql.DB
+		mocks   []sqlmock.Sqlmock
+	)
+	cfg := testConfig()
+	for i := range n {
+		db, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+		require.NoError(t, err)
+		dsn := "replica-dsn-" + string(rune('a'+i))
+		dbs[dsn] = db
+		ordered = append(ordered, db)
+		mocks = append(mocks, mock)
+		cfg.Database.Replicas.DSNs = append(cfg.Database.Replicas.DSNs, dsn)
+		// The first check, run by NewReplicas, finds every replica healthy
+		mock.ExpectPing()
+	}
+
+	orig := sqlOpen
+	sqlOpen = func(driverName, dsn string, opts ...otelsql.Option) (*sql.DB, error) {
+		return dbs[dsn], nil
+	}
+	t.Cleanup(func() { sqlOpen = orig })
+
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+	replicas := NewReplicas(log, cfg)
+	t.Cleanup(replicas.Stop)
+	return replicas, ordered, mocks
+}
+
+func TestNewReplicas_NoneConfigured(t *testing.T) {
+	replicas := NewReplicas(logrus.New(), testConfig())
+	require.Nil(t, replicas)
+	// A nil set is safe to use and never offers a reader
+	require.Nil(t, replicas.Reader())
+	replicas.Start(context.Background())
+	replicas.Stop()
+}
+
+func TestReplicas_Reader(t *testing.T) {
+	replicas, dbs, mocks := setupReplicas(t, 2)
+
+	// Healthy replicas are used in turn
+	seen := map[*sql.DB]int{}
+	for range 4 {
+		seen[replicas.Reader()]++
+	}
+	require.Equal(t, map[*sql.DB]int{dbs[0]: 2, dbs[1]: 2}, seen)
+
+	// An unhealthy replica is skipped until it recovers
+	mocks[0].ExpectPing().WillReturnError(errors.New("connection refused"))
+	mocks[1].ExpectPing()
+	replicas.Check(context.Background())
+	for range 3 {
+		require.Same(t, dbs[1], replicas.Reader())
+	}
+
+	// With no healthy replica the caller falls back to the primary
+	mocks[0].ExpectPing().WillReturnError(errors.New("connection refused"))
+	mocks[1].ExpectPing().WillReturnError(errors.New("connection refused"))
+	replicas.Check(context.Background())
+	require.Nil(t, replicas.Reader())
+
+	mocks[0].ExpectPing()
+	mocks[1].ExpectPing().WillReturnError(errors.New("connection refused"))
+	replicas.Check(context.Background())
+	require.Same(t, dbs[0], replicas.Reader())
+
+	for _, mock := range mocks {
+		require.NoError(t, mock.ExpectationsWereMet())
+	}
+}
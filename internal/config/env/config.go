@@ -17,6 +17,8 @@ type Config struct {
 		Cors    struct {
 			AllowOrigins string `mapstructure:"allow_origins"`
 		} `mapstructure:"cors"`
+		// ShutdownTimeout is how long in-flight requests may finish on shutdown
+		ShutdownTimeout time.Duration `mapstructure:"shutdown_timeout"`
 	} `mapstructure:"web"`
 	JWT struct {
 		Secret                 string        `mapstructure:"secret"`
@@ -50,7 +52,129 @@ type Config struct {
 		Log struct {
 			Level int `mapstructure:"level"`
 		} `mapstructure:"log"`
+		// MigrateOnStartup applies pending embedded migrations before the app serves
+		MigrateOnStartup bool `mapstructure:"migrate_on_startup"`
+
+		Replicas struct {
+			// DSNs of read replicas; reads are spread across the healthy ones
+			DSNs           []string      `mapstructure:"dsns"`
+			HealthInterval time.Duration `mapstructure:"health_interval"`
+			// Sticky is how long a client's reads stay on the primary after a write
+			Sticky time.Duration `mapstructure:"sticky"`
+		} `mapstructure:"replicas"`
 	} `mapstructure:"database"`
+	User struct {
+		SoftDelete struct {
+			Retention time.Duration `mapstructure:"retention"`
+		} `mapstructure:"soft_delete"`
+		Bulk struct {
+			MaxBatchSize int `mapstructure:"max_batch_size"`
+		} `mapstructure:"bulk"`
+	} `mapstructure:"user"`
+	GeoIP struct {
+		// Path of an offline DB-IP lite CSV database, country or city
+		// edition; empty leaves login locations unknown
+		Path string `mapstructure:"path"`
+	} `mapstructure:"geoip"`
+	Notify struct {
+		// DefaultLocale renders messages whose locale has no translation
+		DefaultLocale string `mapstructure:"default_locale"`
+		// Transport of each channel: smtp (email only), console, webhook or none
+		Email struct {
+			Transport string `mapstructure:"transport"`
+		} `mapstructure:"email"`
+		SMS struct {
+			Transport string `mapstructure:"transport"`
+		} `mapstructure:"sms"`
+		SMTP struct {
+			Host     string        `mapstructure:"host"`
+			Port     int           `mapstructure:"port"`
+			Username string        `mapstructure:"username"`
+			Password string        `mapstructure:"password"`
+			From     string        `mapstructure:"from"`
+			Timeout  time.Duration `mapstructure:"timeout"`
+		} `mapstructure:"smtp"`
+		Console struct {
+			// Path of a file messages are appended to; empty writes to stdout
+			Path string `mapstructure:"path"`
+		} `mapstructure:"console"`
+		Webhook struct {
+			URL     string        `mapstructure:"url"`
+			Secret  string        `mapstructure:"secret"`
+			Timeout time.Duration `mapstructure:"timeout"`
+		} `mapstructure:"webhook"`
+		// Async delivers in the app's background when jobs are disabled
+		Async struct {
+			Workers   int `mapstructure:"workers"`
+			QueueSize int `mapstructure:"queue_size"`
+		} `mapstructure:"async"`
+	} `mapstructure:"notify"`
+	Outbox struct {
+		// Sink is where relayed events go: redis, webhook or none
+		Sink      string        `mapstructure:"sink"`
+		Interval  time.Duration `mapstructure:"interval"`
+		BatchSize int           `mapstructure:"batch_size"`
+		Retention time.Duration `mapstructure:"retention"`
+		// MaxAttempts is how many failed deliveries park an event
+		MaxAttempts int `mapstructure:"max_attempts"`
+		Stream      struct {
+			Prefix string `mapstructure:"prefix"`
+			MaxLen int64  `mapstructure:"max_len"`
+		} `mapstructure:"stream"`
+		Webhook struct {
+			URL     string        `mapstructure:"url"`
+			Timeout time.Duration `mapstructure:"timeout"`
+		} `mapstructure:"webhook"`
+	} `mapstructure:"outbox"`
+	Webhooks struct {
+		// Enabled feeds outbox events to webhook subscriptions and runs the
+		// delivery worker
+		Enabled      bool          `mapstructure:"enabled"`
+		PollInterval time.Duration `mapstructure:"poll_interval"`
+		BatchSize    int           `mapstructure:"batch_size"`
+		Timeout      time.Duration `mapstructure:"timeout"`
+		MaxAttempts  int           `mapstructure:"max_attempts"`
+		// Backoff is the first retry delay, doubled after every failed attempt
+		Backoff    time.Duration `mapstructure:"backoff"`
+		MaxBackoff time.Duration `mapstructure:"max_backoff"`
+		// DisableAfter consecutive failed attempts disable a subscription
+		DisableAfter int `mapstructure:"disable_after"`
+		// AllowPrivateNetworks lets subscriptions target loopback, private and
+		// link-local addresses, for local development only
+		AllowPrivateNetworks bool `mapstructure:"allow_private_networks"`
+	} `mapstructure:"webhooks"`
+	Jobs struct {
+		// Enabled hands async work such as emails to the job queue, which
+		// cmd/worker processes
+		Enabled      bool          `mapstructure:"enabled"`
+		Prefix       string        `mapstructure:"prefix"`
+		Concurrency  int           `mapstructure:"concurrency"`
+		PollInterval time.Duration `mapstructure:"poll_interval"`
+		// Lease is how long a job may run before another worker takes it over
+		Lease       time.Duration `mapstructure:"lease"`
+		MaxAttempts int           `mapstructure:"max_attempts"`
+		// Backoff is the first retry delay, doubled after every failed attempt
+		Backoff    time.Duration `mapstructure:"backoff"`
+		MaxBackoff time.Duration `mapstructure:"max_backoff"`
+		// ShutdownTimeout is how long running jobs may finish on shutdown
+		ShutdownTimeout time.Duration `mapstructure:"shutdown_timeout"`
+		// Dead bounds the dead-letter queue by age and length
+		Dead struct {
+			Retention time.Duration `mapstructure:"retention"`
+			MaxLen    int64         `mapstructure:"max_len"`
+		} `mapstructure:"dead"`
+	} `mapstructure:"jobs"`
+	Scheduler struct {
+		Prefix string `mapstructure:"prefix"`
+		// Timezone the cron expressions are evaluated in, e.g. Europe/Berlin
+		Timezone string `mapstructure:"timezone"`
+		// LockTTL is how long leadership outlives a leader that stopped
+		// renewing it
+		LockTTL time.Duration `mapstructure:"lock_ttl"`
+		// Tolerance is how late a run may start before it counts as missed
+		Tolerance time.Duration            `mapstructure:"tolerance"`
+		Tasks     map[string]ScheduledTask `mapstructure:"tasks"`
+	} `mapstructure:"scheduler"`
 	Monitoring struct {
 		Otel struct {
 			Host string `mapstructure:"host"`
@@ -58,6 +182,16 @@ type Config struct {
 	} `mapstructure:"monitoring"`
 }
 
+// ScheduledTask overrides the defaults of a task registered with the
+// scheduler.
+type ScheduledTask struct {
+	// Schedule is a cron expression, empty keeps the task's default
+	Schedule string `mapstructure:"schedule"`
+	// Missed is the missed run policy, skip or run_once
+	Missed   string `mapstructure:"missed"`
+	Disabled bool   `mapstructure:"disabled"`
+}
+
 func NewConfig() *Config {
 	vp := viper.New()
 
@@ -81,6 +215,15 @@ func NewConfig() *Config {
 	return config
 }
 
+// GetWebShutdownTimeout returns how long in-flight requests may finish when
+// the server stops, defaulting to 30 seconds.
+func (c *Config) GetWebShutdownTimeout() time.Duration {
+	if c.Web.ShutdownTimeout <= 0 {
+		return 30 * time.Second
+	}
+	return c.Web.ShutdownTimeout * time.Second
+}
+
 func (c *Config) GetAccessSecret() string {
 	return c.JWT.Secret
 }
@@ -100,3 +243,347 @@ func (c *Config) GetRefreshTokenExpiration() time.Duration {
 func (c *Config) GetCsrfTokenExpiration() time.Duration {
 	return c.JWT.CsrfTokenExpiration * time.Second
 }
+
+func (c *Config) GetSoftDeleteRetention() time.Duration {
+	return c.User.SoftDelete.Retention * time.Second
+}
+
+// GetBulkMaxBatchSize returns the maximum number of operations accepted by
+// one bulk request, defaulting to 500.
+func (c *Config) GetBulkMaxBatchSize() int {
+	if c.User.Bulk.MaxBatchSize <= 0 {
+		return 500
+	}
+	return c.User.Bulk.MaxBatchSize
+}
+
+// GetReplicaHealthInterval returns how often read replicas are pinged,
+// defaulting to 5 seconds.
+func (c *Config) GetReplicaHealthInterval() time.Duration {
+	if c.Database.Replicas.HealthInterval <= 0 {
+		return 5 * time.Second
+	}
+	return c.Database.Replicas.HealthInterval * time.Second
+}
+
+// GetReplicaSticky returns how long reads stay on the primary after a client
+// writes; zero disables stickiness beyond the write request itself.
+func (c *Config) GetReplicaSticky() time.Duration {
+	return c.Database.Replicas.Sticky * time.Second
+}
+
+// GetNotifyDefaultLocale returns the fallback locale of notifications,
+// defaulting to "en".
+func (c *Config) GetNotifyDefaultLocale() string {
+	if c.Notify.DefaultLocale == "" {
+		return "en"
+	}
+	return c.Notify.DefaultLocale
+}
+
+// GetNotifyEmailTransport returns how emails are sent, defaulting to console.
+func (c *Config) GetNotifyEmailTransport() string {
+	if c.Notify.Email.Transport == "" {
+		return "console"
+	}
+	return c.Notify.Email.Transport
+}
+
+// GetNotifySMSTransport returns how text messages are sent, defaulting to
+// none.
+func (c *Config) GetNotifySMSTransport() string {
+	if c.Notify.SMS.Transport == "" {
+		return "none"
+	}
+	return c.Notify.SMS.Transport
+}
+
+// GetNotifySMTPPort returns the port of the SMTP server, defaulting to 587.
+func (c *Config) GetNotifySMTPPort() int {
+	if c.Notify.SMTP.Port <= 0 {
+		return 587
+	}
+	return c.Notify.SMTP.Port
+}
+
+// GetNotifySMTPTimeout returns how long sending one email may take,
+// defaulting to 10 seconds.
+func (c *Config) GetNotifySMTPTimeout() time.Duration {
+	if c.Notify.SMTP.Timeout <= 0 {
+		return 10 * time.Second
+	}
+	return c.Notify.SMTP.Timeout * time.Second
+}
+
+// GetNotifyWebhookTimeout returns the timeout of a notification webhook,
+// defaulting to 10 seconds.
+func (c *Config) GetNotifyWebhookTimeout() time.Duration {
+	if c.Notify.Webhook.Timeout <= 0 {
+		return 10 * time.Second
+	}
+	return c.Notify.Webhook.Timeout * time.Second
+}
+
+// GetNotifyAsyncWorkers returns how many notifications the app sends at
+// once, defaulting to 2.
+func (c *Config) GetNotifyAsyncWorkers() int {
+	if c.Notify.Async.Workers <= 0 {
+		return 2
+	}
+	return c.Notify.Async.Workers
+}
+
+// GetNotifyAsyncQueueSize returns how many notifications may wait for a
+// worker, defaulting to 1000.
+func (c *Config) GetNotifyAsyncQueueSize() int {
+	if c.Notify.Async.QueueSize <= 0 {
+		return 1000
+	}
+	return c.Notify.Async.QueueSize
+}
+
+// GetOutboxSink returns where the outbox relay publishes, defaulting to redis.
+func (c *Config) GetOutboxSink() string {
+	if c.Outbox.Sink == "" {
+		return "redis"
+	}
+	return c.Outbox.Sink
+}
+
+// GetOutboxInterval returns how often the outbox relay runs; zero disables it.
+func (c *Config) GetOutboxInterval() time.Duration {
+	return c.Outbox.Interval * time.Second
+}
+
+// GetOutboxBatchSize returns how many events the relay publishes per
+// transaction, defaulting to 100.
+func (c *Config) GetOutboxBatchSize() int {
+	if c.Outbox.BatchSize <= 0 {
+		return 100
+	}
+	return c.Outbox.BatchSize
+}
+
+// GetOutboxMaxAttempts returns how many failed deliveries park an event,
+// defaulting to 10.
+func (c *Config) GetOutboxMaxAttempts() int {
+	if c.Outbox.MaxAttempts <= 0 {
+		return 10
+	}
+	return c.Outbox.MaxAttempts
+}
+
+// GetOutboxRetention returns how long published events are kept; zero keeps
+// them forever.
+func (c *Config) GetOutboxRetention() time.Duration {
+	return c.Outbox.Retention * time.Second
+}
+
+// GetOutboxStreamPrefix returns the prefix of the Redis stream names,
+// defaulting to "events:".
+func (c *Config) GetOutboxStreamPrefix() string {
+	if c.Outbox.Stream.Prefix == "" {
+		return "events:"
+	}
+	return c.Outbox.Stream.Prefix
+}
+
+// GetOutboxWebhookTimeout returns the timeout of a webhook delivery,
+// defaulting to 10 seconds.
+func (c *Config) GetOutboxWebhookTimeout() time.Duration {
+	if c.Outbox.Webhook.Timeout <= 0 {
+		return 10 * time.Second
+	}
+	return c.Outbox.Webhook.Timeout * time.Second
+}
+
+// GetWebhookPollInterval returns how often the delivery worker looks for due
+// webhook attempts, defaulting to 1 second.
+func (c *Config) GetWebhookPollInterval() time.Duration {
+	if c.Webhooks.PollInterval <= 0 {
+		return time.Second
+	}
+	return c.Webhooks.PollInterval * time.Second
+}
+
+// GetWebhookBatchSize returns how many due attempts the worker claims at
+// once, defaulting to 50.
+func (c *Config) GetWebhookBatchSize() int {
+	if c.Webhooks.BatchSize <= 0 {
+		return 50
+	}
+	return c.Webhooks.BatchSize
+}
+
+// GetWebhookTimeout returns the timeout of one delivery attempt, defaulting
+// to 10 seconds.
+func (c *Config) GetWebhookTimeout() time.Duration {
+	if c.Webhooks.Timeout <= 0 {
+		return 10 * time.Second
+	}
+	return c.Webhooks.Timeout * time.Second
+}
+
+// GetWebhookMaxAttempts returns how often a delivery is attempted before it
+// is marked failed, defaulting to 8.
+func (c *Config) GetWebhookMaxAttempts() int {
+	if c.Webhooks.MaxAttempts <= 0 {
+		return 8
+	}
+	return c.Webhooks.MaxAttempts
+}
+
+// GetWebhookBackoff returns the delay before the first retry, defaulting to
+// 30 seconds.
+func (c *Config) GetWebhookBackoff() time.Duration {
+	if c.Webhooks.Backoff <= 0 {
+		return 30 * time.Second
+	}
+	return c.Webhooks.Backoff * time.Second
+}
+
+// GetWebhookMaxBackoff caps the retry delay, defaulting to 1 hour.
+func (c *Config) GetWebhookMaxBackoff() time.Duration {
+	if c.Webhooks.MaxBackoff <= 0 {
+		return time.Hour
+	}
+	return c.Webhooks.MaxBackoff * time.Second
+}
+
+// GetWebhookDisableAfter returns after how many consecutive failed attempts a
+// subscription is disabled, defaulting to 50.
+func (c *Config) GetWebhookDisableAfter() int {
+	if c.Webhooks.DisableAfter <= 0 {
+		return 50
+	}
+	return c.Webhooks.DisableAfter
+}
+
+// GetJobPrefix returns the prefix of the job queue's Redis keys, defaulting
+// to "jobs:".
+func (c *Config) GetJobPrefix() string {
+	if c.Jobs.Prefix == "" {
+		return "jobs:"
+	}
+	return c.Jobs.Prefix
+}
+
+// GetJobConcurrency returns how many jobs a worker runs at once, defaulting
+// to 10.
+func (c *Config) GetJobConcurrency() int {
+	if c.Jobs.Concurrency <= 0 {
+		return 10
+	}
+	return c.Jobs.Concurrency
+}
+
+// GetJobPollInterval returns how long an idle worker waits before looking
+// for due jobs again, defaulting to 1 second.
+func (c *Config) GetJobPollInterval() time.Duration {
+	if c.Jobs.PollInterval <= 0 {
+		return time.Second
+	}
+	return c.Jobs.PollInterval * time.Second
+}
+
+// GetJobLease returns how long a job may run, defaulting to 5 minutes.
+func (c *Config) GetJobLease() time.Duration {
+	if c.Jobs.Lease <= 0 {
+		return 5 * time.Minute
+	}
+	return c.Jobs.Lease * time.Second
+}
+
+// GetJobMaxAttempts returns how many times a job runs before it is
+// dead-lettered, defaulting to 5.
+func (c *Config) GetJobMaxAttempts() int {
+	if c.Jobs.MaxAttempts <= 0 {
+		return 5
+	}
+	return c.Jobs.MaxAttempts
+}
+
+// GetJobBackoff returns the delay before a job's first retry, defaulting to
+// 10 seconds.
+func (c *Config) GetJobBackoff() time.Duration {
+	if c.Jobs.Backoff <= 0 {
+		return 10 * time.Second
+	}
+	return c.Jobs.Backoff * time.Second
+}
+
+// GetJobMaxBackoff caps the retry delay, defaulting to 1 hour.
+func (c *Config) GetJobMaxBackoff() time.Duration {
+	if c.Jobs.MaxBackoff <= 0 {
+		return time.Hour
+	}
+	return c.Jobs.MaxBackoff * time.Second
+}
+
+// GetJobShutdownTimeout returns how long running jobs may finish when the
+// worker stops, defaulting to 30 seconds.
+func (c *Config) GetJobShutdownTimeout() time.Duration {
+	if c.Jobs.ShutdownTimeout <= 0 {
+		return 30 * time.Second
+	}
+	return c.Jobs.ShutdownTimeout * time.Second
+}
+
+// GetJobDeadRetention returns how long dead-lettered jobs are kept,
+// defaulting to 7 days.
+func (c *Config) GetJobDeadRetention() time.Duration {
+	if c.Jobs.Dead.Retention <= 0 {
+		return 7 * 24 * time.Hour
+	}
+	return c.Jobs.Dead.Retention * time.Second
+}
+
+// GetJobDeadMaxLen returns how many dead-lettered jobs are kept at most,
+// defaulting to 10000.
+func (c *Config) GetJobDeadMaxLen() int64 {
+	if c.Jobs.Dead.MaxLen <= 0 {
+		return 10000
+	}
+	return c.Jobs.Dead.MaxLen
+}
+
+// GetSchedulerPrefix returns the prefix of the scheduler's Redis keys,
+// defaulting to "scheduler:".
+func (c *Config) GetSchedulerPrefix() string {
+	if c.Scheduler.Prefix == "" {
+		return "scheduler:"
+	}
+	return c.Scheduler.Prefix
+}
+
+// GetSchedulerLocation returns the time zone of the cron expressions,
+// defaulting to UTC.
+func (c *Config) GetSchedulerLocation() (*time.Location, error) {
+	if c.Scheduler.Timezone == "" {
+		return time.UTC, nil
+	}
+	return time.LoadLocation(c.Scheduler.Timezone)
+}
+
+// GetSchedulerLockTTL returns how long the scheduler leader lock lives
+// without renewal, defaulting to 15 seconds.
+func (c *Config) GetSchedulerLockTTL() time.Duration {
+	if c.Scheduler.LockTTL <= 0 {
+		return 15 * time.Second
+	}
+	return c.Scheduler.LockTTL * time.Second
+}
+
+// GetSchedulerTolerance returns how late a scheduled run may start before it
+// counts as missed, defaulting to 1 minute.
+func (c *Config) GetSchedulerTolerance() time.Duration {
+	if c.Scheduler.Tolerance <= 0 {
+		return time.Minute
+	}
+	return c.Scheduler.Tolerance * time.Second
+}
+
+// GetScheduledTask returns the overrides of the named task.
+func (c *Config) GetScheduledTask(name string) ScheduledTask {
+	return c.Scheduler.Tasks[name]
+}
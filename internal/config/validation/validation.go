@@ -43,10 +43,33 @@ func (v *Validation) ParseAndValidate(c *fiber.Ctx, data interface{}) error {
 }
 
 func (v *Validation) Validate(data interface{}) error {
+	return v.translate(data, v.validator.Struct(data))
+}
+
+// ValidatePartial validates only the struct fields whose JSON names are given,
+// e.g. the members present in a merge patch document. Unknown names are ignored.
+func (v *Validation) ValidatePartial(data interface{}, jsonFields ...string) error {
+	rt := reflect.TypeOf(data).Elem()
+	fields := make([]string, 0, len(jsonFields))
+	for i := 0; i < rt.NumField(); i++ {
+		name := strings.Split(rt.Field(i).Tag.Get("json"), ",")[0]
+		for _, jsonField := range jsonFields {
+			if name == jsonField {
+				fields = append(fields, rt.Field(i).Name)
+			}
+		}
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+	return v.translate(data, v.validator.StructPartial(data, fields...))
+}
+
+// translate converts validator errors into a ValidationError keyed by JSON field name.
+func (v *Validation) translate(data interface{}, err error) error {
 	errors := make(map[string][]string)
 
-	// Validate the struct
-	if err := v.validator.Struct(data); err != nil {
+	if err != nil {
 		validationErrors, ok := err.(validator.ValidationErrors)
 		if !ok {
 			return fmt.Errorf("unexpected validation error: %v", err)
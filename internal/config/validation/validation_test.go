@@ -270,4 +270,35 @@ func TestParseAndValidate(t *testing.T) {
             require.Equal(t, tc.expectStatus, resp.StatusCode)
         })
     }
-}
\ No newline at end of file
+}
+func TestValidatePartial(t *testing.T) {
+    v := NewValidation()
+
+    cases := []struct {
+        name      string
+        input     *testReq
+        fields    []string
+        expectErr []string
+    }{
+        {name: "NoFields", input: &testReq{}, fields: nil},
+        {name: "OnlyGivenFieldsValidated", input: &testReq{Name: "Alice"}, fields: []string{"name"}},
+        {name: "UnknownFieldIgnored", input: &testReq{}, fields: []string{"nickname"}},
+        {name: "GivenFieldInvalid", input: &testReq{Name: "Alice", Email: "bad"}, fields: []string{"name", "email"}, expectErr: []string{"email"}},
+    }
+
+    for _, tc := range cases {
+        t.Run(tc.name, func(t *testing.T) {
+            err := v.ValidatePartial(tc.input, tc.fields...)
+            if len(tc.expectErr) == 0 {
+                require.NoError(t, err)
+                return
+            }
+            vErr, ok := err.(*ValidationError)
+            require.True(t, ok)
+            require.Len(t, vErr.Errors, len(tc.expectErr))
+            for _, field := range tc.expectErr {
+                require.Contains(t, vErr.Errors, field)
+            }
+        })
+    }
+}
@@ -7,3 +7,103 @@ const (
 	TokenTypeRefresh TokenType = "refresh"
 	TokenTypeCsrf    TokenType = "csrf"
 )
+
+type PolicyEffect string
+
+const (
+	PolicyEffectAllow PolicyEffect = "allow"
+	PolicyEffectDeny  PolicyEffect = "deny"
+)
+
+// PolicyActionWildcard matches every action when used as a policy action.
+const PolicyActionWildcard = "*"
+
+// Actions authorized against the policies table. The part before the colon
+// is the resource type.
+const (
+	// ActionAuthzCheckOthers allows dry-running policies for another subject
+	ActionAuthzCheckOthers = "authz:check_others"
+	// ActionUserListDeleted lists soft-deleted users
+	ActionUserListDeleted = "user:list_deleted"
+	// ActionUserRestore restores a soft-deleted user
+	ActionUserRestore = "user:restore"
+	// ActionUserSuspend suspends a user
+	ActionUserSuspend = "user:suspend"
+	// ActionUserReactivate lifts a suspension
+	ActionUserReactivate = "user:reactivate"
+	// ActionUserBulk runs batched writes on any users
+	ActionUserBulk = "user:bulk"
+	// ActionUserExport downloads the user directory
+	ActionUserExport = "user:export"
+	// ActionUserImport creates users from a file
+	ActionUserImport = "user:import"
+	// ActionUserReadPermissions explains the effective permissions of another user
+	ActionUserReadPermissions = "user:read_permissions"
+	// ActionAuditRead reads and verifies the audit log
+	ActionAuditRead = "audit:read"
+	// ActionSchedulerRead shows the scheduler status
+	ActionSchedulerRead = "scheduler:read"
+	// ActionWebhookManage manages the caller's own webhook subscriptions
+	ActionWebhookManage = "webhook:manage"
+)
+
+type PermissionSource string
+
+const (
+	PermissionSourceDirect PermissionSource = "direct"
+	PermissionSourceRole   PermissionSource = "role"
+)
+
+type UserStatus string
+
+const (
+	UserStatusActive    UserStatus = "active"
+	UserStatusSuspended UserStatus = "suspended"
+	UserStatusPending   UserStatus = "pending"
+)
+
+type BulkOperation string
+
+const (
+	BulkOperationCreate BulkOperation = "create"
+	BulkOperationUpdate BulkOperation = "update"
+	BulkOperationDelete BulkOperation = "delete"
+)
+
+type BulkMode string
+
+const (
+	BulkModeAtomic     BulkMode = "atomic"
+	BulkModeBestEffort BulkMode = "best_effort"
+)
+
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryPending   WebhookDeliveryStatus = "pending"
+	WebhookDeliverySucceeded WebhookDeliveryStatus = "succeeded"
+	WebhookDeliveryFailed    WebhookDeliveryStatus = "failed"
+)
+
+// WebhookEventWildcard subscribes a webhook to every event type.
+const WebhookEventWildcard = "*"
+
+// MissedRunPolicy decides what the scheduler does with runs that were due
+// while no instance was running it.
+type MissedRunPolicy string
+
+const (
+	// MissedRunSkip drops missed runs and waits for the next one on time
+	MissedRunSkip MissedRunPolicy = "skip"
+	// MissedRunOnce runs once as soon as possible for all missed runs
+	MissedRunOnce MissedRunPolicy = "run_once"
+)
+
+type ScheduledRunStatus string
+
+const (
+	ScheduledRunRunning   ScheduledRunStatus = "running"
+	ScheduledRunSucceeded ScheduledRunStatus = "succeeded"
+	ScheduledRunFailed    ScheduledRunStatus = "failed"
+	ScheduledRunSkipped   ScheduledRunStatus = "skipped"
+)
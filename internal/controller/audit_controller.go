@@ -0,0 +1,60 @@
+package controller
+
+import (
+	"go-starter-template/internal/dto"
+	"go-starter-template/internal/service"
+	"go-starter-template/internal/utils/errcode"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type AuditController struct {
+	auditService *service.AuditService
+	logger       *logrus.Logger
+	tracer       trace.Tracer
+}
+
+func NewAuditController(auditService *service.AuditService, logger *logrus.Logger) *AuditController {
+	return &AuditController{auditService, logger, otel.Tracer("AuditController")}
+}
+
+// List returns audit events filtered by actor, target, action and time,
+// newest first.
+func (c *AuditController) List(ctx *fiber.Ctx) error {
+	spanCtx, span := c.tracer.Start(ctx.UserContext(), "AuditController.List")
+	defer span.End()
+
+	logger := c.logger.WithContext(spanCtx)
+
+	req := new(dto.ListAuditEventsRequest)
+	if err := ctx.QueryParser(req); err != nil {
+		logger.WithError(err).Error("failed to parse request query")
+		return errcode.ErrBadRequest
+	}
+	req.SetDefault()
+
+	events, paging, err := c.auditService.List(spanCtx, req)
+	if err != nil {
+		logger.WithError(err).Error("failed to list audit events")
+		return err
+	}
+
+	return ctx.JSON(dto.WebResponse[[]*dto.AuditEventResponse]{Data: events, Paging: paging})
+}
+
+// Verify recomputes the hash chain of the audit log.
+func (c *AuditController) Verify(ctx *fiber.Ctx) error {
+	spanCtx, span := c.tracer.Start(ctx.UserContext(), "AuditController.Verify")
+	defer span.End()
+
+	result, err := c.auditService.Verify(spanCtx)
+	if err != nil {
+		c.logger.WithContext(spanCtx).WithError(err).Error("failed to verify audit log")
+		return err
+	}
+
+	return ctx.JSON(dto.WebResponse[*dto.AuditVerificationResponse]{Data: result})
+}
@@ -0,0 +1,101 @@
+package controller
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+
+	"go-starter-template/internal/audit"
+	"go-starter-template/internal/dto"
+	"go-starter-template/internal/model"
+	"go-starter-template/internal/repository"
+	"go-starter-template/internal/service"
+	"go-starter-template/internal/utils/errcode"
+)
+
+var auditColumns = []string{"id", "uuid", "actor_uuid", "action", "target_type", "target_id", "ip", "user_agent", "trace_id", "changes", "metadata", "created_at", "prev_hash", "hash"}
+
+// setupAuditController constructs a real AuditController backed by sqlmock
+func setupAuditController(t *testing.T) (*fiber.App, sqlmock.Sqlmock) {
+	t.Helper()
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	svc := service.NewAuditService(repository.NewAuditRepository(db), logger, repository.NewUnitOfWork(db))
+	ctrl := NewAuditController(svc, logger)
+
+	app := fiber.New(fiber.Config{ErrorHandler: func(c *fiber.Ctx, err error) error {
+		if code, ok := errcode.GetHTTPStatus(err); ok {
+			return c.Status(code).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}})
+	app.Get("/audit", ctrl.List)
+	app.Get("/audit/verify", ctrl.Verify)
+	return app, mock
+}
+
+func TestAuditController_List(t *testing.T) {
+	t.Run("Filters", func(t *testing.T) {
+		app, mock := setupAuditController(t)
+		now := time.Unix(1736935200, 0)
+		mock.ExpectQuery(regexp.QuoteMeta("FROM audit_events WHERE actor_uuid = $1 AND target_type = $2 AND created_at <= $3 ORDER BY id DESC LIMIT $4")).
+			WithArgs("u1", audit.TargetUser, now, 50).
+			WillReturnRows(sqlmock.NewRows(auditColumns).
+				AddRow(3, "e3", "u1", "user.updated", audit.TargetUser, "u2", "10.0.0.1", "curl/8", "", []byte(`{"name":{"before":"A","after":"B"}}`), []byte(`{}`), now, "", ""))
+
+		resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/audit?actor=u1&target_type=user&to=1736935200", nil))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var body dto.WebResponse[[]*dto.AuditEventResponse]
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		require.Len(t, body.Data, 1)
+		require.Equal(t, "user.updated", body.Data[0].Action)
+		require.Equal(t, "10.0.0.1", body.Data[0].IP)
+		require.JSONEq(t, `{"name":{"before":"A","after":"B"}}`, string(body.Data[0].Changes))
+		require.Empty(t, body.Paging.Next)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("InvalidCursor", func(t *testing.T) {
+		app, _ := setupAuditController(t)
+		resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/audit?cursor=abc", nil))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+}
+
+func TestAuditController_Verify(t *testing.T) {
+	app, mock := setupAuditController(t)
+	e := &model.AuditEvent{ID: 1, UUID: "e1", Action: audit.ActionLogin, Changes: []byte(`{}`), Metadata: []byte(`{}`), CreatedAt: time.Unix(1736935200, 0).UTC(), PrevHash: audit.GenesisHash}
+	e.Hash = audit.Hash(audit.GenesisHash, e)
+	mock.ExpectQuery(regexp.QuoteMeta("FROM audit_events ORDER BY id")).
+		WillReturnRows(sqlmock.NewRows(auditColumns).
+			AddRow(e.ID, e.UUID, "", e.Action, "", "", "", "", "", e.Changes, e.Metadata, e.CreatedAt, e.PrevHash, e.Hash).
+			AddRow(2, "e2", "", audit.ActionLogout, "", "", "", "", "", []byte(`{}`), []byte(`{}`), e.CreatedAt, e.Hash, "forged"))
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/audit/verify", nil))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var body dto.WebResponse[*dto.AuditVerificationResponse]
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	require.False(t, body.Data.Valid)
+	require.Equal(t, int64(2), body.Data.BrokenAt)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
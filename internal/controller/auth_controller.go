@@ -113,7 +113,7 @@ func (c *AuthController) RefreshToken(ctx *fiber.Ctx) error {
 	accessToken, newRefreshToken, err := c.authService.RefreshToken(spanCtx, refreshToken)
 	if err != nil {
 		logger.WithError(err).Warn("Invalid refresh token attempt")
-		c.clearRefreshTokenCookie(ctx)
+		clearRefreshTokenCookie(ctx)
 		return err
 	}
 
@@ -160,7 +160,7 @@ func (c *AuthController) Logout(ctx *fiber.Ctx) error {
 	}
 
 	_, clearCookieSpan := c.tracer.Start(spanCtx, "ClearCookie")
-	c.clearRefreshTokenCookie(ctx)
+	clearRefreshTokenCookie(ctx)
 	clearCookieSpan.End()
 
 	return ctx.JSON(dto.WebResponse[string]{Data: "Logout successfully"})
@@ -176,7 +176,7 @@ func (c *AuthController) setRefreshTokenCookie(ctx *fiber.Ctx, refreshToken stri
 }
 
 // Helper to clear refresh token cookie
-func (c *AuthController) clearRefreshTokenCookie(ctx *fiber.Ctx) {
+func clearRefreshTokenCookie(ctx *fiber.Ctx) {
 	cookie := baseRefreshTokenCookie
 	cookie.Value = ""
 	cookie.Expires = time.Now().Add(-1 * time.Hour)
@@ -38,6 +38,32 @@ func (n *noopBlacklistRepo) IsBlacklisted(token string, tokenType constant.Token
 	return false, nil
 }
 
+func (n *noopBlacklistRepo) Prune(context.Context, time.Duration) (int64, error) {
+	return 0, nil
+}
+
+// expectAuditTx expects an audit event recorded in a transaction of its own
+func expectAuditTx(mock sqlmock.Sqlmock) {
+	mock.ExpectBegin()
+	expectAudit(mock)
+	mock.ExpectCommit()
+}
+
+// expectLoginTx expects a login recorded in the login history and the audit
+// log; a successful one is first compared with the user's earlier logins
+func expectLoginTx(mock sqlmock.Sqlmock, success bool) {
+	mock.ExpectBegin()
+	if success {
+		mock.ExpectQuery(regexp.QuoteMeta("FROM login_events")).
+			WillReturnRows(sqlmock.NewRows([]string{"any", "device", "country"}).AddRow(false, false, false))
+	}
+	mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO login_events")).
+		WithArgs(sqlmock.AnyArg(), "user-123", success, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), false, false, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	expectAudit(mock)
+	mock.ExpectCommit()
+}
+
 // setupControllerWithMock prepares an AuthController with sqlmock for tests.
 func setupControllerWithMock(t *testing.T) (*AuthController, *fiber.App, sqlmock.Sqlmock) {
 	t.Helper()
@@ -61,7 +87,7 @@ func setupControllerWithMock(t *testing.T) (*AuthController, *fiber.App, sqlmock
 	blacklistService := service.NewBlacklistService(logger, jwtService, blRepo)
 	userRepo := repository.NewUserRepository(db)
 	uow := repository.NewUnitOfWork(db)
-	authService := service.NewAuthService(jwtService, userRepo, blacklistService, logger, uow)
+	authService := service.NewAuthService(jwtService, userRepo, repository.NewOutboxRepository(db), service.NewAuditService(repository.NewAuditRepository(db), logger, uow), service.NewLoginHistoryService(repository.NewLoginEventRepository(db), nil, logger, uow), blacklistService, logger, uow)
 
 	validator := validation.NewValidation()
 	ctrl := NewAuthController(authService, logger, validator, cfg)
@@ -106,11 +132,12 @@ func TestAuthController_Login(t *testing.T) {
 			setupMock: func(mock sqlmock.Sqlmock) {
 				hashed, _ := bcrypt.GenerateFromPassword([]byte("secret123"), bcrypt.DefaultCost)
 				now := time.Now()
-				query := `SELECT uuid, name, email, password, created_at, updated_at FROM users WHERE email = $1 LIMIT 1`
+				query := `SELECT uuid, name, email, password, created_at, updated_at, status, status_reason, suspended_until, phone, version FROM users WHERE lower(email) = lower($1) AND deleted_at IS NULL LIMIT 1`
 				mock.ExpectQuery(regexp.QuoteMeta(query)).
 					WithArgs("john@example.com").
-					WillReturnRows(sqlmock.NewRows([]string{"uuid", "name", "email", "password", "created_at", "updated_at"}).
-						AddRow("user-123", "John Doe", "john@example.com", string(hashed), now, now))
+					WillReturnRows(sqlmock.NewRows([]string{"uuid", "name", "email", "password", "created_at", "updated_at", "status", "status_reason", "suspended_until", "phone", "version"}).
+						AddRow("user-123", "John Doe", "john@example.com", string(hashed), now, now, "active", "", nil, nil, 1))
+				expectLoginTx(mock, true)
 			},
 			body:         `{"email":"john@example.com","password":"secret123"}`,
 			expectStatus: http.StatusOK,
@@ -136,7 +163,7 @@ func TestAuthController_Login(t *testing.T) {
 		{
 			name: "InvalidEmail",
 			setupMock: func(mock sqlmock.Sqlmock) {
-				query := `SELECT uuid, name, email, password, created_at, updated_at FROM users WHERE email = $1 LIMIT 1`
+				query := `SELECT uuid, name, email, password, created_at, updated_at, status, status_reason, suspended_until, phone, version FROM users WHERE lower(email) = lower($1) AND deleted_at IS NULL LIMIT 1`
 				mock.ExpectQuery(regexp.QuoteMeta(query)).
 					WithArgs("missing@example.com").
 					WillReturnError(sql.ErrNoRows)
@@ -149,11 +176,12 @@ func TestAuthController_Login(t *testing.T) {
 			setupMock: func(mock sqlmock.Sqlmock) {
 				hashed, _ := bcrypt.GenerateFromPassword([]byte("otherpass"), bcrypt.DefaultCost)
 				now := time.Now()
-				query := `SELECT uuid, name, email, password, created_at, updated_at FROM users WHERE email = $1 LIMIT 1`
+				query := `SELECT uuid, name, email, password, created_at, updated_at, status, status_reason, suspended_until, phone, version FROM users WHERE lower(email) = lower($1) AND deleted_at IS NULL LIMIT 1`
 				mock.ExpectQuery(regexp.QuoteMeta(query)).
 					WithArgs("john@example.com").
-					WillReturnRows(sqlmock.NewRows([]string{"uuid", "name", "email", "password", "created_at", "updated_at"}).
-						AddRow("user-123", "John Doe", "john@example.com", string(hashed), now, now))
+					WillReturnRows(sqlmock.NewRows([]string{"uuid", "name", "email", "password", "created_at", "updated_at", "status", "status_reason", "suspended_until", "phone", "version"}).
+						AddRow("user-123", "John Doe", "john@example.com", string(hashed), now, now, "active", "", nil, nil, 1))
+				expectLoginTx(mock, false)
 			},
 			body:         `{"email":"john@example.com","password":"secret123"}`,
 			expectStatus: http.StatusUnauthorized,
@@ -185,6 +213,7 @@ func TestAuthController_RefreshToken(t *testing.T) {
 	type testcase struct {
 		name         string
 		buildRequest func(*testing.T, *AuthController) *http.Request
+		setupDB      func(sqlmock.Sqlmock)
 		expectStatus int
 		assert       func(*testing.T, *http.Response)
 	}
@@ -229,6 +258,12 @@ func TestAuthController_RefreshToken(t *testing.T) {
 				req.AddCookie(&http.Cookie{Name: refreshTokenCookieName, Value: token})
 				return req
 			},
+			setupDB: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(`SELECT uuid, status, status_reason, suspended_until, tokens_valid_after FROM users WHERE uuid = $1`)).
+					WithArgs("user-123").
+					WillReturnRows(sqlmock.NewRows([]string{"uuid", "status", "status_reason", "suspended_until", "tokens_valid_after"}).AddRow("user-123", "active", "", nil, nil))
+				expectAuditTx(mock)
+			},
 			expectStatus: http.StatusOK,
 			assert: func(t *testing.T, resp *http.Response) {
 				var setCookie *http.Cookie
@@ -252,11 +287,14 @@ func TestAuthController_RefreshToken(t *testing.T) {
 		},
 	}
 
-	ctrl, app, _ := setupControllerWithMock(t)
+	ctrl, app, mock := setupControllerWithMock(t)
 	app.Post("/refresh", ctrl.RefreshToken)
 
 	for _, tc := range cases {
 		t.Run(tc.name, func(t *testing.T) {
+			if tc.setupDB != nil {
+				tc.setupDB(mock)
+			}
 			req := tc.buildRequest(t, ctrl)
 			resp, err := app.Test(req, -1)
 			require.NoError(t, err)
@@ -265,6 +303,7 @@ func TestAuthController_RefreshToken(t *testing.T) {
 			if tc.assert != nil {
 				tc.assert(t, resp)
 			}
+			require.NoError(t, mock.ExpectationsWereMet())
 		})
 	}
 }
@@ -279,7 +318,7 @@ func TestAuthController_Register(t *testing.T) {
 		assert       func(*testing.T, *http.Response)
 	}
 
-	countQuery := `SELECT COUNT(*) FROM users WHERE email = $1`
+	countQuery := `SELECT COUNT(*) FROM users WHERE lower(email) = lower($1) AND deleted_at IS NULL`
 	insertQuery := `
         INSERT INTO users (uuid, name, email, password, created_at, updated_at)
         VALUES ($1, $2, $3, $4, NOW(), NOW())
@@ -301,6 +340,8 @@ func TestAuthController_Register(t *testing.T) {
 				mock.ExpectExec(regexp.QuoteMeta(insertQuery)).
 					WithArgs(sqlmock.AnyArg(), "New User", "new@example.com", sqlmock.AnyArg()).
 					WillReturnResult(sqlmock.NewResult(1, 1))
+				expectOutbox(mock)
+				expectAudit(mock)
 				mock.ExpectCommit()
 			},
 			body:         `{"name":"New User","email":"new@example.com","password":"newpass123"}`,
@@ -363,10 +404,24 @@ func TestAuthController_Logout(t *testing.T) {
 		return ctrl, app
 	}
 
+	// Helper to setup a controller expecting the logout to be audited
+	auditedSetup := func(t *testing.T) (*AuthController, *fiber.App) {
+		ctrl, app, mock := setupControllerWithMock(t)
+		expectAuditTx(mock)
+		t.Cleanup(func() { require.NoError(t, mock.ExpectationsWereMet()) })
+		app.Post("/logout", ctrl.Logout)
+		return ctrl, app
+	}
+
 	// Helper to setup controller with failing blacklist repo
 	failingSetup := func(t *testing.T) (*AuthController, *fiber.App) {
-		db, _, err := sqlmock.New()
+		db, mock, err := sqlmock.New()
 		require.NoError(t, err)
+		// The tokens are revoked after the audit event commits
+		mock.ExpectBegin()
+		expectAudit(mock)
+		mock.ExpectCommit()
+		t.Cleanup(func() { require.NoError(t, mock.ExpectationsWereMet()) })
 
 		logger := logrus.New()
 		logger.SetOutput(io.Discard)
@@ -383,7 +438,7 @@ func TestAuthController_Logout(t *testing.T) {
 		blacklistService := service.NewBlacklistService(logger, jwtService, blRepo)
 		userRepo := repository.NewUserRepository(db)
 		uow := repository.NewUnitOfWork(db)
-		authService := service.NewAuthService(jwtService, userRepo, blacklistService, logger, uow)
+		authService := service.NewAuthService(jwtService, userRepo, repository.NewOutboxRepository(db), service.NewAuditService(repository.NewAuditRepository(db), logger, uow), service.NewLoginHistoryService(repository.NewLoginEventRepository(db), nil, logger, uow), blacklistService, logger, uow)
 
 		validator := validation.NewValidation()
 		ctrl := NewAuthController(authService, logger, validator, cfg)
@@ -482,7 +537,7 @@ func TestAuthController_Logout(t *testing.T) {
 		},
 		{
 			name:  "Success",
-			setup: defaultSetup,
+			setup: auditedSetup,
 			buildRequest: func(t *testing.T, ctrl *AuthController) *http.Request {
 				logger := logrus.New()
 				logger.SetOutput(io.Discard)
@@ -544,3 +599,7 @@ func (f *failingBlacklistRepo) Add(token string, tokenType constant.TokenType, d
 func (f *failingBlacklistRepo) IsBlacklisted(token string, tokenType constant.TokenType) (bool, error) {
 	return false, nil
 }
+
+func (f *failingBlacklistRepo) Prune(context.Context, time.Duration) (int64, error) {
+	return 0, nil
+}
@@ -0,0 +1,51 @@
+package controller
+
+import (
+	"go-starter-template/internal/config/validation"
+	"go-starter-template/internal/dto"
+	"go-starter-template/internal/middleware"
+	"go-starter-template/internal/service"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type AuthzController struct {
+	policyService *service.PolicyService
+	logger        *logrus.Logger
+	validation    *validation.Validation
+	tracer        trace.Tracer
+}
+
+func NewAuthzController(policyService *service.PolicyService, logger *logrus.Logger, validator *validation.Validation) *AuthzController {
+	return &AuthzController{policyService, logger, validator, otel.Tracer("AuthzController")}
+}
+
+// Check is a dry-run endpoint that evaluates the policies for the given
+// action and returns the decision with a per-policy trace.
+func (c *AuthzController) Check(ctx *fiber.Ctx) error {
+	spanCtx, span := c.tracer.Start(ctx.UserContext(), "AuthzController.Check")
+	defer span.End()
+
+	logger := c.logger.WithContext(spanCtx)
+
+	_, parseSpan := c.tracer.Start(spanCtx, "ParseAndValidate")
+	req := new(dto.AuthzCheckRequest)
+	if err := c.validation.ParseAndValidate(ctx, req); err != nil {
+		parseSpan.End()
+		logger.WithError(err).Error("Failed to parse and validate authz check request")
+		return err
+	}
+	parseSpan.End()
+
+	auth := middleware.GetUser(ctx)
+	decision, err := c.policyService.Check(spanCtx, auth.UUID, req, middleware.RequestAttributes(ctx))
+	if err != nil {
+		logger.WithError(err).Error("Failed to evaluate authorization check")
+		return err
+	}
+
+	return ctx.JSON(dto.WebResponse[*dto.AuthzDecisionResponse]{Data: decision})
+}
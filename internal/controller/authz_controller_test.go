@@ -0,0 +1,143 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+
+	"go-starter-template/internal/config/validation"
+	"go-starter-template/internal/dto"
+	"go-starter-template/internal/repository"
+	"go-starter-template/internal/service"
+	"go-starter-template/internal/utils/errcode"
+)
+
+// setupAuthzController constructs a real AuthzController wired with sqlmock
+func setupAuthzController(t *testing.T) (*fiber.App, sqlmock.Sqlmock) {
+	t.Helper()
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	policySvc := service.NewPolicyService(repository.NewPolicyRepository(db), repository.NewUserRepository(db), logger)
+	ctrl := NewAuthzController(policySvc, logger, validation.NewValidation())
+
+	app := fiber.New(fiber.Config{ErrorHandler: func(c *fiber.Ctx, err error) error {
+		if _, ok := err.(*validation.ValidationError); ok {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		if code, ok := errcode.GetHTTPStatus(err); ok {
+			return c.Status(code).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}})
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("auth", &service.Claims{UUID: "caller"})
+		return c.Next()
+	})
+	app.Post("/check", ctrl.Check)
+	return app, mock
+}
+
+func TestAuthzController_Check(t *testing.T) {
+	type testcase struct {
+		name         string
+		body         string
+		setupDB      func(sqlmock.Sqlmock)
+		expectStatus int
+		assert       func(*testing.T, *http.Response)
+	}
+
+	now := time.Now()
+	expectCaller := func(mock sqlmock.Sqlmock) {
+		mock.ExpectQuery(regexp.QuoteMeta(`FROM users WHERE uuid = $1`)).
+			WithArgs("caller").
+			WillReturnRows(sqlmock.NewRows([]string{"uuid", "name", "email", "password", "created_at", "updated_at", "status", "status_reason", "suspended_until", "phone", "version"}).
+				AddRow("caller", "Caller", "caller@example.com", "hash", now, now, "active", "", nil, nil, 1))
+		mock.ExpectQuery(regexp.QuoteMeta(`INNER JOIN roles r`)).
+			WithArgs("caller").
+			WillReturnRows(sqlmock.NewRows([]string{"user_uuid", "uuid", "name"}).AddRow("caller", "r1", "admin"))
+		mock.ExpectQuery(regexp.QuoteMeta(`FROM user_permissions up`)).
+			WithArgs("caller").
+			WillReturnRows(sqlmock.NewRows([]string{"uuid", "name"}))
+		mock.ExpectQuery(regexp.QuoteMeta(`INNER JOIN role_permissions rp`)).
+			WithArgs("caller").
+			WillReturnRows(sqlmock.NewRows([]string{"role_uuid", "uuid", "name"}))
+	}
+
+	cases := []testcase{
+		{
+			name:         "InvalidJSON",
+			body:         "{invalid}",
+			expectStatus: http.StatusBadRequest,
+		},
+		{
+			name:         "ValidationError",
+			body:         `{"resource":{"type":"user"}}`,
+			expectStatus: http.StatusBadRequest,
+		},
+		{
+			name: "Allowed",
+			body: `{"action":"user:read","context":{"channel":"api"}}`,
+			setupDB: func(mock sqlmock.Sqlmock) {
+				expectCaller(mock)
+				mock.ExpectQuery(regexp.QuoteMeta(`FROM policies`)).
+					WithArgs("user:read", "*").
+					WillReturnRows(sqlmock.NewRows([]string{"uuid", "name", "description", "action", "effect", "condition", "priority", "enabled", "created_at", "updated_at"}).
+						AddRow("p1", "admins-read", "", "user:read", "allow", `"admin" in subject.roles && request.method == "POST" && request.channel == "api"`, 0, true, now, now))
+			},
+			expectStatus: http.StatusOK,
+			assert: func(t *testing.T, resp *http.Response) {
+				var out dto.WebResponse[*dto.AuthzDecisionResponse]
+				require.NoError(t, json.NewDecoder(resp.Body).Decode(&out))
+				require.True(t, out.Data.Allowed)
+				require.Equal(t, "user:read", out.Data.Action)
+				require.Len(t, out.Data.Policies, 1)
+			},
+		},
+		{
+			name: "PolicyLoadError",
+			body: `{"action":"user:read"}`,
+			setupDB: func(mock sqlmock.Sqlmock) {
+				expectCaller(mock)
+				mock.ExpectQuery(regexp.QuoteMeta(`FROM policies`)).
+					WithArgs("user:read", "*").
+					WillReturnError(sqlmock.ErrCancelled)
+			},
+			expectStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			app, mock := setupAuthzController(t)
+			if tc.setupDB != nil {
+				tc.setupDB(mock)
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/check", bytes.NewBufferString(tc.body))
+			req.Header.Set("Content-Type", "application/json")
+			resp, err := app.Test(req, -1)
+			require.NoError(t, err)
+			require.Equal(t, tc.expectStatus, resp.StatusCode)
+			if tc.assert != nil {
+				tc.assert(t, resp)
+			}
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package controller
+
+import (
+	"errors"
+	"go-starter-template/internal/config/validation"
+	"go-starter-template/internal/constant"
+	"go-starter-template/internal/dto"
+	"go-starter-template/internal/utils/errcode"
+	"io"
+
+	"github.com/goccy/go-json"
+)
+
+// decodeBulkOperations reads operations one at a time, from a JSON array or,
+// for NDJSON, one object per line. Decoding stops as soon as the batch grows
+// past max, so an oversized request is rejected without decoding all of it.
+func decodeBulkOperations(body io.Reader, ndjson bool, max int) ([]*dto.BulkUserOperation, error) {
+	decoder := json.NewDecoder(body)
+	if !ndjson {
+		if token, err := decoder.Token(); err != nil || token != json.Delim('[') {
+			return nil, errcode.ErrBadRequest
+		}
+	}
+
+	var ops []*dto.BulkUserOperation
+	for ndjson || decoder.More() {
+		op := new(dto.BulkUserOperation)
+		if err := decoder.Decode(op); err != nil {
+			if ndjson && errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, errcode.ErrBadRequest
+		}
+		if len(ops) == max {
+			return nil, errcode.ErrBulkTooLarge
+		}
+		ops = append(ops, op)
+	}
+	if !ndjson {
+		if token, err := decoder.Token(); err != nil || token != json.Delim(']') {
+			return nil, errcode.ErrBadRequest
+		}
+	}
+	if len(ops) == 0 {
+		return nil, errcode.ErrBadRequest
+	}
+	return ops, nil
+}
+
+// prepareBulkOperation decodes and validates op's data for its kind, leaving
+// any problem on op.Err or op.Errors so it is reported per item.
+func prepareBulkOperation(v *validation.Validation, op *dto.BulkUserOperation) {
+	var target interface{}
+	switch op.Op {
+	case constant.BulkOperationCreate:
+		op.Create = new(dto.CreateUserRequest)
+		target = op.Create
+	case constant.BulkOperationUpdate:
+		op.Update = new(dto.UpdateUserRequest)
+		target = op.Update
+	case constant.BulkOperationDelete:
+	default:
+		op.Err = errcode.ErrInvalidBulkOperation
+		return
+	}
+
+	if op.Op != constant.BulkOperationCreate && op.UUID == "" {
+		op.Errors = map[string][]string{"uuid": {"uuid is required"}}
+		return
+	}
+	if target == nil {
+		return
+	}
+	data := op.Data
+	if len(data) == 0 {
+		data = []byte("{}")
+	}
+	if err := json.Unmarshal(data, target); err != nil {
+		op.Err = errcode.ErrBadRequest
+		return
+	}
+	if err := v.Validate(target); err != nil {
+		var validationErr *validation.ValidationError
+		if errors.As(err, &validationErr) {
+			op.Errors = validationErr.Errors
+		} else {
+			op.Err = err
+		}
+	}
+}
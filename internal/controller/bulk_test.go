@@ -0,0 +1,67 @@
+package controller
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"go-starter-template/internal/config/validation"
+	"go-starter-template/internal/constant"
+	"go-starter-template/internal/dto"
+	"go-starter-template/internal/utils/errcode"
+)
+
+func TestDecodeBulkOperations(t *testing.T) {
+	cases := []struct {
+		name      string
+		body      string
+		ndjson    bool
+		max       int
+		expectLen int
+		expectErr error
+	}{
+		{name: "JSONArray", body: `[{"op":"create","data":{"name":"Alice"}}, {"op":"delete","uuid":"u1"}]`, max: 10, expectLen: 2},
+		{name: "NDJSON", body: "{\"op\":\"delete\",\"uuid\":\"u1\"}\n{\"op\":\"delete\",\"uuid\":\"u2\"}\n", ndjson: true, max: 10, expectLen: 2},
+		{name: "TooLarge", body: `[{"op":"delete"},{"op":"delete"},{"op":"delete"}]`, max: 2, expectErr: errcode.ErrBulkTooLarge},
+		{name: "NotAnArray", body: `{"op":"delete"}`, max: 10, expectErr: errcode.ErrBadRequest},
+		{name: "Truncated", body: `[{"op":"delete"}`, max: 10, expectErr: errcode.ErrBadRequest},
+		{name: "Empty", body: `[]`, max: 10, expectErr: errcode.ErrBadRequest},
+		{name: "MalformedLine", body: "{\"op\":\"delete\"}\nnot json\n", ndjson: true, max: 10, expectErr: errcode.ErrBadRequest},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ops, err := decodeBulkOperations(strings.NewReader(tc.body), tc.ndjson, tc.max)
+			require.Equal(t, tc.expectErr, err)
+			require.Len(t, ops, tc.expectLen)
+		})
+	}
+}
+
+func TestPrepareBulkOperation(t *testing.T) {
+	v := validation.NewValidation()
+
+	create := &dto.BulkUserOperation{Op: constant.BulkOperationCreate, Data: []byte(`{"name":"Alice","email":"alice@example.com","password":"secret123"}`)}
+	prepareBulkOperation(v, create)
+	require.NoError(t, create.Err)
+	require.Nil(t, create.Errors)
+	require.Equal(t, "Alice", create.Create.Name)
+
+	invalid := &dto.BulkUserOperation{Op: constant.BulkOperationCreate, Data: []byte(`{"name":"Al","email":"nope"}`)}
+	prepareBulkOperation(v, invalid)
+	require.Contains(t, invalid.Errors, "email")
+	require.Contains(t, invalid.Errors, "password")
+
+	missingUUID := &dto.BulkUserOperation{Op: constant.BulkOperationDelete}
+	prepareBulkOperation(v, missingUUID)
+	require.Contains(t, missingUUID.Errors, "uuid")
+
+	unknown := &dto.BulkUserOperation{Op: "rename"}
+	prepareBulkOperation(v, unknown)
+	require.Equal(t, errcode.ErrInvalidBulkOperation, unknown.Err)
+
+	malformed := &dto.BulkUserOperation{Op: constant.BulkOperationUpdate, UUID: "u1", Data: []byte(`"name"`)}
+	prepareBulkOperation(v, malformed)
+	require.Equal(t, errcode.ErrBadRequest, malformed.Err)
+}
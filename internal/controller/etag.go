@@ -0,0 +1,38 @@
+package controller
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+
+	"go-starter-template/internal/utils/errcode"
+)
+
+// setETag exposes a resource version as a strong ETag, e.g. "3".
+func setETag(ctx *fiber.Ctx, version int64) {
+	ctx.Set(fiber.HeaderETag, strconv.Quote(strconv.FormatInt(version, 10)))
+}
+
+// ifMatchVersion reads the version a client expects from the If-Match header.
+// "*" yields 0, which matches any version. Weak or unparseable tags can never
+// match a strong ETag and therefore fail the precondition.
+func ifMatchVersion(ctx *fiber.Ctx) (int64, error) {
+	header := strings.TrimSpace(ctx.Get(fiber.HeaderIfMatch))
+	if header == "" {
+		return 0, errcode.ErrPreconditionRequired
+	}
+	if header == "*" {
+		return 0, nil
+	}
+
+	tag, err := strconv.Unquote(header)
+	if err != nil || !strings.HasPrefix(header, `"`) {
+		return 0, errcode.ErrPreconditionFailed
+	}
+	version, err := strconv.ParseInt(tag, 10, 64)
+	if err != nil || version <= 0 {
+		return 0, errcode.ErrPreconditionFailed
+	}
+	return version, nil
+}
@@ -0,0 +1,47 @@
+package controller
+
+import (
+	"go-starter-template/internal/dto"
+	"go-starter-template/internal/middleware"
+	"go-starter-template/internal/service"
+	"go-starter-template/internal/utils/errcode"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type LoginHistoryController struct {
+	loginHistoryService *service.LoginHistoryService
+	logger              *logrus.Logger
+	tracer              trace.Tracer
+}
+
+func NewLoginHistoryController(loginHistoryService *service.LoginHistoryService, logger *logrus.Logger) *LoginHistoryController {
+	return &LoginHistoryController{loginHistoryService, logger, otel.Tracer("LoginHistoryController")}
+}
+
+// Me returns the current user's logins, newest first.
+func (c *LoginHistoryController) Me(ctx *fiber.Ctx) error {
+	spanCtx, span := c.tracer.Start(ctx.UserContext(), "LoginHistoryController.Me")
+	defer span.End()
+
+	logger := c.logger.WithContext(spanCtx)
+	auth := middleware.GetUser(ctx)
+
+	req := new(dto.ListLoginsRequest)
+	if err := ctx.QueryParser(req); err != nil {
+		logger.WithError(err).Error("failed to parse request query")
+		return errcode.ErrBadRequest
+	}
+	req.SetDefault()
+
+	logins, paging, err := c.loginHistoryService.List(spanCtx, auth.UUID, req)
+	if err != nil {
+		logger.WithError(err).Error("failed to list logins")
+		return err
+	}
+
+	return ctx.JSON(dto.WebResponse[[]*dto.LoginEventResponse]{Data: logins, Paging: paging})
+}
@@ -0,0 +1,79 @@
+package controller
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+
+	"go-starter-template/internal/dto"
+	"go-starter-template/internal/repository"
+	"go-starter-template/internal/service"
+	"go-starter-template/internal/utils/errcode"
+)
+
+// setupLoginHistoryController constructs a real LoginHistoryController backed
+// by sqlmock, authenticated as user-123
+func setupLoginHistoryController(t *testing.T) (*fiber.App, sqlmock.Sqlmock) {
+	t.Helper()
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	svc := service.NewLoginHistoryService(repository.NewLoginEventRepository(db), nil, logger, repository.NewUnitOfWork(db))
+	ctrl := NewLoginHistoryController(svc, logger)
+
+	app := fiber.New(fiber.Config{ErrorHandler: func(c *fiber.Ctx, err error) error {
+		if code, ok := errcode.GetHTTPStatus(err); ok {
+			return c.Status(code).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}})
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("auth", &service.Claims{UUID: "user-123"})
+		return c.Next()
+	})
+	app.Get("/me/logins", ctrl.Me)
+	return app, mock
+}
+
+func TestLoginHistoryController_Me(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		app, mock := setupLoginHistoryController(t)
+		mock.ExpectQuery(regexp.QuoteMeta("FROM login_events")).
+			WithArgs("user-123", int64(0), 20).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "uuid", "user_uuid", "success", "failure_reason", "ip", "user_agent", "device", "country", "region", "city", "new_device", "new_country", "created_at"}).
+				AddRow(1, "l1", "user-123", true, "", "8.8.8.8", "curl/8", "curl", "US", "California", "Mountain View", false, true, time.Unix(1736935200, 0)))
+
+		resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/me/logins", nil))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var body dto.WebResponse[[]*dto.LoginEventResponse]
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		require.Len(t, body.Data, 1)
+		require.Equal(t, "Mountain View", body.Data[0].City)
+		require.True(t, body.Data[0].NewCountry)
+		require.Equal(t, int64(1736935200), body.Data[0].CreatedAt)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("InvalidCursor", func(t *testing.T) {
+		app, _ := setupLoginHistoryController(t)
+		resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/me/logins?cursor=abc", nil))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+}
@@ -0,0 +1,37 @@
+package controller
+
+import (
+	"go-starter-template/internal/dto"
+	"go-starter-template/internal/dto/converter"
+	"go-starter-template/internal/scheduler"
+	"go-starter-template/internal/utils/errcode"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type SchedulerController struct {
+	scheduler *scheduler.Scheduler
+	logger    *logrus.Logger
+	tracer    trace.Tracer
+}
+
+func NewSchedulerController(scheduler *scheduler.Scheduler, logger *logrus.Logger) *SchedulerController {
+	return &SchedulerController{scheduler, logger, otel.Tracer("SchedulerController")}
+}
+
+// Status shows the scheduler leader and the last and next run of every task.
+func (c *SchedulerController) Status(ctx *fiber.Ctx) error {
+	spanCtx, span := c.tracer.Start(ctx.UserContext(), "SchedulerController.Status")
+	defer span.End()
+
+	status, err := c.scheduler.Status(spanCtx)
+	if err != nil {
+		c.logger.WithContext(spanCtx).WithError(err).Error("failed to get scheduler status")
+		return errcode.ErrRedisGet
+	}
+
+	return ctx.JSON(dto.WebResponse[*dto.SchedulerResponse]{Data: converter.SchedulerToResponse(status)})
+}
@@ -0,0 +1,83 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	miniredis "github.com/alicebob/miniredis/v2"
+	"github.com/gofiber/fiber/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+
+	"go-starter-template/internal/constant"
+	"go-starter-template/internal/dto"
+	"go-starter-template/internal/scheduler"
+	"go-starter-template/internal/utils/errcode"
+)
+
+// setupSchedulerController constructs a real SchedulerController backed by miniredis
+func setupSchedulerController(t *testing.T) (*fiber.App, *miniredis.Miniredis) {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	s := scheduler.New(redis.NewClient(&redis.Options{Addr: mr.Addr()}), logger, scheduler.Options{})
+	require.NoError(t, s.Register("purge_deleted_users", "@hourly", constant.MissedRunOnce, func(context.Context) error { return nil }))
+	ctrl := NewSchedulerController(s, logger)
+
+	app := fiber.New(fiber.Config{ErrorHandler: func(c *fiber.Ctx, err error) error {
+		if code, ok := errcode.GetHTTPStatus(err); ok {
+			return c.Status(code).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}})
+	app.Get("/admin/scheduler", ctrl.Status)
+	return app, mr
+}
+
+func TestSchedulerController_Status(t *testing.T) {
+	app, mr := setupSchedulerController(t)
+	mr.Set("scheduler:leader", "other-instance")
+	mr.HSet("scheduler:task:purge_deleted_users",
+		"last_run_at", "1736935200000",
+		"last_status", "failed",
+		"last_error", "disk full",
+		"last_duration_ms", "1500",
+		"last_instance", "other-instance",
+	)
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/admin/scheduler", nil))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var body dto.WebResponse[*dto.SchedulerResponse]
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	require.Equal(t, "other-instance", body.Data.Leader)
+	require.NotEmpty(t, body.Data.Instance)
+	require.Len(t, body.Data.Tasks, 1)
+	task := body.Data.Tasks[0]
+	require.Equal(t, "purge_deleted_users", task.Name)
+	require.Equal(t, "@hourly", task.Schedule)
+	require.Equal(t, "run_once", task.MissedRunPolicy)
+	require.Equal(t, int64(1736935200), task.LastRunAt)
+	require.Equal(t, "failed", task.LastStatus)
+	require.Equal(t, "disk full", task.LastError)
+	require.Equal(t, int64(1500), task.LastDurationMs)
+	require.NotZero(t, task.NextRunAt)
+}
+
+func TestSchedulerController_Status_RedisDown(t *testing.T) {
+	app, mr := setupSchedulerController(t)
+	mr.Close()
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/admin/scheduler", nil))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+}
@@ -1,10 +1,19 @@
 package controller
 
 import (
+	"bufio"
+	"bytes"
+	"errors"
+	"go-starter-template/internal/config/env"
+	"go-starter-template/internal/config/validation"
+	"go-starter-template/internal/constant"
 	"go-starter-template/internal/dto"
 	"go-starter-template/internal/middleware"
 	"go-starter-template/internal/service"
 	"go-starter-template/internal/utils/errcode"
+	"go-starter-template/internal/utils/userio"
+	"io"
+	"strings"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/sirupsen/logrus"
@@ -15,11 +24,13 @@ import (
 type UserController struct {
 	userService *service.UserService
 	logger      *logrus.Logger
+	validation  *validation.Validation
+	config      *env.Config
 	tracer      trace.Tracer
 }
 
-func NewUserController(userService *service.UserService, logger *logrus.Logger) *UserController {
-	return &UserController{userService, logger, otel.Tracer("UserController")}
+func NewUserController(userService *service.UserService, logger *logrus.Logger, validator *validation.Validation, config *env.Config) *UserController {
+	return &UserController{userService, logger, validator, config, otel.Tracer("UserController")}
 }
 
 func (c *UserController) Me(ctx *fiber.Ctx) error {
@@ -37,6 +48,77 @@ func (c *UserController) Me(ctx *fiber.Ctx) error {
 	return ctx.Type("json").SendString(user)
 }
 
+// UpdateMe updates the current user's name and/or requests an email change.
+func (c *UserController) UpdateMe(ctx *fiber.Ctx) error {
+	spanCtx, span := c.tracer.Start(ctx.UserContext(), "UserController.UpdateMe")
+	defer span.End()
+
+	logger := c.logger.WithContext(spanCtx)
+	auth := middleware.GetUser(ctx)
+
+	req := new(dto.UpdateMeRequest)
+	if err := c.validation.ParseAndValidate(ctx, req); err != nil {
+		logger.WithError(err).Warn("invalid update me request")
+		return err
+	}
+
+	user, err := c.userService.UpdateMe(spanCtx, auth.UUID, req)
+	if err != nil {
+		logger.WithError(err).Error("failed to update current user")
+		return err
+	}
+
+	return ctx.JSON(dto.WebResponse[*dto.UserResponse]{Data: user})
+}
+
+// VerifyEmail confirms a pending email change of the current user.
+func (c *UserController) VerifyEmail(ctx *fiber.Ctx) error {
+	spanCtx, span := c.tracer.Start(ctx.UserContext(), "UserController.VerifyEmail")
+	defer span.End()
+
+	logger := c.logger.WithContext(spanCtx)
+	auth := middleware.GetUser(ctx)
+
+	req := new(dto.VerifyEmailRequest)
+	if err := c.validation.ParseAndValidate(ctx, req); err != nil {
+		logger.WithError(err).Warn("invalid verify email request")
+		return err
+	}
+
+	user, err := c.userService.VerifyEmail(spanCtx, auth.UUID, req.Token)
+	if err != nil {
+		logger.WithError(err).Error("failed to verify email")
+		return err
+	}
+
+	return ctx.JSON(dto.WebResponse[*dto.UserResponse]{Data: user})
+}
+
+// DeleteMe deletes the current user's account after password confirmation.
+func (c *UserController) DeleteMe(ctx *fiber.Ctx) error {
+	spanCtx, span := c.tracer.Start(ctx.UserContext(), "UserController.DeleteMe")
+	defer span.End()
+
+	logger := c.logger.WithContext(spanCtx)
+	auth := middleware.GetUser(ctx)
+
+	req := new(dto.DeleteMeRequest)
+	if err := c.validation.ParseAndValidate(ctx, req); err != nil {
+		logger.WithError(err).Warn("invalid delete me request")
+		return err
+	}
+
+	accessToken := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(ctx.Get("Authorization")), "Bearer"))
+	refreshToken := ctx.Cookies(refreshTokenCookieName)
+	if err := c.userService.DeleteMe(spanCtx, auth.UUID, req.Password, accessToken, refreshToken); err != nil {
+		logger.WithError(err).Error("failed to delete current user")
+		return err
+	}
+
+	clearRefreshTokenCookie(ctx)
+	return ctx.SendStatus(fiber.StatusNoContent)
+}
+
 func (c *UserController) List(ctx *fiber.Ctx) error {
 	spanCtx, span := c.tracer.Start(ctx.UserContext(), "UserController.List")
 	defer span.End()
@@ -53,22 +135,16 @@ func (c *UserController) List(ctx *fiber.Ctx) error {
 	req.SetDefault()
 	parseSpan.End()
 
-	users, totalCount, err := c.userService.Search(spanCtx, req)
+	users, paging, err := c.userService.Search(spanCtx, req)
 	if err != nil {
 		logger.WithError(err).Error("error searching user")
 		return err
 	}
 
-	totalPage := (totalCount + int64(req.Size) - 1) / int64(req.Size)
-
 	return ctx.JSON(dto.WebResponse[[]*dto.UserResponse]{
-		Data: users,
-		Paging: &dto.PageMetadata{
-			Page:      req.Page,
-			Size:      req.Size,
-			TotalItem: totalCount,
-			TotalPage: totalPage,
-		}})
+		Data:   users,
+		Paging: paging,
+	})
 }
 
 func (c *UserController) Create(ctx *fiber.Ctx) error {
@@ -94,6 +170,164 @@ func (c *UserController) Create(ctx *fiber.Ctx) error {
 	return ctx.JSON(dto.WebResponse[*dto.UserResponse]{Data: user})
 }
 
+// Show returns a single user with its current version as ETag, to be echoed
+// in If-Match when updating or deleting it.
+func (c *UserController) Show(ctx *fiber.Ctx) error {
+	spanCtx, span := c.tracer.Start(ctx.UserContext(), "UserController.Show")
+	defer span.End()
+
+	uuid := ctx.Params("uuid")
+	if uuid == "" {
+		return errcode.ErrBadRequest
+	}
+
+	user, err := c.userService.FindUser(spanCtx, uuid)
+	if err != nil {
+		c.logger.WithContext(spanCtx).WithError(err).Warn("failed to find user")
+		return err
+	}
+
+	setETag(ctx, user.Version)
+	return ctx.JSON(dto.WebResponse[*dto.UserResponse]{Data: user})
+}
+
+// Export streams every user matching the search filters as CSV (default) or
+// NDJSON (?format=ndjson). Rows are written as they are read from the
+// database; errors after the first byte can only be logged.
+func (c *UserController) Export(ctx *fiber.Ctx) error {
+	spanCtx, span := c.tracer.Start(ctx.UserContext(), "UserController.Export")
+	defer span.End()
+
+	logger := c.logger.WithContext(spanCtx)
+
+	req := new(dto.SearchUserRequest)
+	if err := ctx.QueryParser(req); err != nil {
+		logger.WithError(err).Error("failed to parse request query")
+		return errcode.ErrBadRequest
+	}
+	format := userio.FormatFor(ctx.Query("format"), "")
+	if format != userio.FormatCSV && format != userio.FormatNDJSON {
+		return errcode.ErrInvalidFileFormat
+	}
+
+	export, err := c.userService.ExportUsers(spanCtx, req)
+	if err != nil {
+		logger.WithError(err).Error("failed to export users")
+		return err
+	}
+
+	ctx.Set(fiber.HeaderContentType, userio.ContentType(format))
+	ctx.Set(fiber.HeaderContentDisposition, `attachment; filename="users.`+format+`"`)
+	ctx.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer export.Close()
+		writer, _ := userio.NewWriter(w, format)
+		for {
+			user, err := export.Next()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err == nil {
+				err = writer.Write(user)
+			}
+			if err != nil {
+				logger.WithError(err).Error("export aborted")
+				return
+			}
+		}
+		if err := writer.Flush(); err != nil {
+			logger.WithError(err).Error("failed to flush export")
+		}
+	})
+	return nil
+}
+
+// Import creates users from a CSV (default) or NDJSON upload, chosen by
+// ?format or the Content-Type. Each row is validated and reported on its own;
+// ?dry_run=true only checks the rows, and ?mode works as for Bulk.
+func (c *UserController) Import(ctx *fiber.Ctx) error {
+	spanCtx, span := c.tracer.Start(ctx.UserContext(), "UserController.Import")
+	defer span.End()
+
+	logger := c.logger.WithContext(spanCtx)
+
+	mode := constant.BulkMode(ctx.Query("mode", string(constant.BulkModeAtomic)))
+	if mode != constant.BulkModeAtomic && mode != constant.BulkModeBestEffort {
+		return errcode.ErrInvalidBulkMode
+	}
+
+	var body io.Reader = ctx.Request().BodyStream()
+	if body == nil {
+		body = bytes.NewReader(ctx.Body())
+	}
+	ops, err := userio.ReadUsers(body, userio.FormatFor(ctx.Query("format"), ctx.Get(fiber.HeaderContentType)), c.config.GetBulkMaxBatchSize(), c.validation)
+	switch {
+	case errors.Is(err, userio.ErrUnsupportedFormat):
+		return errcode.ErrInvalidFileFormat
+	case errors.Is(err, userio.ErrTooManyRows):
+		return errcode.ErrBulkTooLarge
+	case err != nil:
+		logger.WithError(err).Warn("failed to read import")
+		return errcode.ErrBadRequest
+	}
+	if len(ops) == 0 {
+		return errcode.ErrBadRequest
+	}
+
+	result, err := c.userService.ImportUsers(spanCtx, ops, mode == constant.BulkModeAtomic, ctx.QueryBool("dry_run"))
+	if err != nil {
+		logger.WithError(err).Error("failed to import users")
+		return err
+	}
+
+	status := fiber.StatusOK
+	if result.Failed > 0 {
+		status = fiber.StatusMultiStatus
+	}
+	return ctx.Status(status).JSON(dto.WebResponse[*dto.BulkUserResponse]{Data: result})
+}
+
+// Bulk applies a batch of create/update/delete operations. The body is a JSON
+// array of operations, or NDJSON with Content-Type application/x-ndjson;
+// ?mode=best_effort keeps going past failed items instead of rolling back.
+func (c *UserController) Bulk(ctx *fiber.Ctx) error {
+	spanCtx, span := c.tracer.Start(ctx.UserContext(), "UserController.Bulk")
+	defer span.End()
+
+	logger := c.logger.WithContext(spanCtx)
+
+	mode := constant.BulkMode(ctx.Query("mode", string(constant.BulkModeAtomic)))
+	if mode != constant.BulkModeAtomic && mode != constant.BulkModeBestEffort {
+		return errcode.ErrInvalidBulkMode
+	}
+
+	// Read straight from the connection when request body streaming is on
+	var body io.Reader = ctx.Request().BodyStream()
+	if body == nil {
+		body = bytes.NewReader(ctx.Body())
+	}
+	ndjson := strings.HasPrefix(ctx.Get(fiber.HeaderContentType), "application/x-ndjson")
+	ops, err := decodeBulkOperations(body, ndjson, c.config.GetBulkMaxBatchSize())
+	if err != nil {
+		logger.WithError(err).Warn("failed to parse bulk request")
+		return err
+	}
+	for _, op := range ops {
+		prepareBulkOperation(c.validation, op)
+	}
+
+	result, err := c.userService.BulkUsers(spanCtx, ops, mode == constant.BulkModeAtomic)
+	if err != nil {
+		logger.WithError(err).Error("failed to apply bulk operations")
+		return err
+	}
+
+	status := fiber.StatusOK
+	if result.Failed > 0 {
+		status = fiber.StatusMultiStatus
+	}
+	return ctx.Status(status).JSON(dto.WebResponse[*dto.BulkUserResponse]{Data: result})
+}
+
 func (c *UserController) Update(ctx *fiber.Ctx) error {
 	spanCtx, span := c.tracer.Start(ctx.UserContext(), "UserController.Update")
 	defer span.End()
@@ -106,6 +340,11 @@ func (c *UserController) Update(ctx *fiber.Ctx) error {
 		return errcode.ErrBadRequest
 	}
 
+	version, err := ifMatchVersion(ctx)
+	if err != nil {
+		return err
+	}
+
 	// Parse request
 	req := new(dto.UpdateUserRequest)
 	if err := ctx.BodyParser(req); err != nil {
@@ -114,17 +353,56 @@ func (c *UserController) Update(ctx *fiber.Ctx) error {
 	}
 
 	// Update user
-	user, err := c.userService.UpdateUser(spanCtx, uuid, req)
+	user, err := c.userService.UpdateUser(spanCtx, uuid, version, req)
 	if err != nil {
 		logger.WithError(err).Error("failed to update user")
 		return err
 	}
 
+	setETag(ctx, user.Version)
 	return ctx.JSON(dto.WebResponse[*dto.UserResponse]{
 		Data: user,
 	})
 }
 
+// Patch partially updates a user from a JSON Merge Patch (RFC 7396) body,
+// sent as application/merge-patch+json or application/json.
+func (c *UserController) Patch(ctx *fiber.Ctx) error {
+	spanCtx, span := c.tracer.Start(ctx.UserContext(), "UserController.Patch")
+	defer span.End()
+
+	logger := c.logger.WithContext(spanCtx)
+
+	uuid := ctx.Params("uuid")
+	if uuid == "" {
+		return errcode.ErrBadRequest
+	}
+
+	version, err := ifMatchVersion(ctx)
+	if err != nil {
+		return err
+	}
+
+	req := new(dto.PatchUserRequest)
+	if err := ctx.BodyParser(req); err != nil {
+		logger.WithError(err).Warn("failed to parse merge patch")
+		return errcode.ErrBadRequest
+	}
+	if err := c.validation.ValidatePartial(req, req.Fields()...); err != nil {
+		logger.WithError(err).Warn("invalid merge patch")
+		return err
+	}
+
+	user, err := c.userService.PatchUser(spanCtx, uuid, version, req)
+	if err != nil {
+		logger.WithError(err).Error("failed to patch user")
+		return err
+	}
+
+	setETag(ctx, user.Version)
+	return ctx.JSON(dto.WebResponse[*dto.UserResponse]{Data: user})
+}
+
 func (c *UserController) Delete(ctx *fiber.Ctx) error {
 	spanCtx, span := c.tracer.Start(ctx.UserContext(), "UserController.Delete")
 	defer span.End()
@@ -137,11 +415,156 @@ func (c *UserController) Delete(ctx *fiber.Ctx) error {
 		return errcode.ErrBadRequest
 	}
 
+	version, err := ifMatchVersion(ctx)
+	if err != nil {
+		return err
+	}
+
 	// Delete user
-	if err := c.userService.DeleteUser(spanCtx, uuid); err != nil {
+	if err := c.userService.DeleteUser(spanCtx, uuid, version); err != nil {
 		logger.WithError(err).Error("failed to delete user")
 		return err
 	}
 
 	return ctx.SendStatus(fiber.StatusNoContent)
 }
+
+// ListDeleted lists soft-deleted users.
+func (c *UserController) ListDeleted(ctx *fiber.Ctx) error {
+	spanCtx, span := c.tracer.Start(ctx.UserContext(), "UserController.ListDeleted")
+	defer span.End()
+
+	logger := c.logger.WithContext(spanCtx)
+
+	req := new(dto.SearchUserRequest)
+	if err := ctx.QueryParser(req); err != nil {
+		logger.WithError(err).Error("failed to parse request query")
+		return errcode.ErrBadRequest
+	}
+	req.SetDefault()
+	req.OnlyDeleted = true
+
+	users, paging, err := c.userService.Search(spanCtx, req)
+	if err != nil {
+		logger.WithError(err).Error("error searching deleted user")
+		return err
+	}
+
+	return ctx.JSON(dto.WebResponse[[]*dto.UserResponse]{
+		Data:   users,
+		Paging: paging,
+	})
+}
+
+// Restore restores a soft-deleted user.
+func (c *UserController) Restore(ctx *fiber.Ctx) error {
+	spanCtx, span := c.tracer.Start(ctx.UserContext(), "UserController.Restore")
+	defer span.End()
+
+	logger := c.logger.WithContext(spanCtx)
+
+	// Get UUID from path
+	uuid := ctx.Params("uuid")
+	if uuid == "" {
+		return errcode.ErrBadRequest
+	}
+
+	user, err := c.userService.RestoreUser(spanCtx, uuid)
+	if err != nil {
+		logger.WithError(err).Error("failed to restore user")
+		return err
+	}
+
+	return ctx.JSON(dto.WebResponse[*dto.UserResponse]{Data: user})
+}
+
+// Suspend blocks a user from authenticating; their existing tokens stop working immediately.
+func (c *UserController) Suspend(ctx *fiber.Ctx) error {
+	spanCtx, span := c.tracer.Start(ctx.UserContext(), "UserController.Suspend")
+	defer span.End()
+
+	logger := c.logger.WithContext(spanCtx)
+
+	// Get UUID from path
+	uuid := ctx.Params("uuid")
+	if uuid == "" {
+		return errcode.ErrBadRequest
+	}
+
+	// Suspending yourself would lock you out of reactivating
+	if auth := middleware.GetUser(ctx); auth.UUID == uuid {
+		logger.Warn("attempt to suspend own account")
+		return errcode.ErrBadRequest
+	}
+
+	// Parse request; an empty body suspends indefinitely without a reason
+	req := new(dto.SuspendUserRequest)
+	if len(ctx.Body()) > 0 {
+		if err := ctx.BodyParser(req); err != nil {
+			logger.WithError(err).Error("failed to parse request body")
+			return errcode.ErrBadRequest
+		}
+	}
+
+	user, err := c.userService.SuspendUser(spanCtx, uuid, req)
+	if err != nil {
+		logger.WithError(err).Error("failed to suspend user")
+		return err
+	}
+
+	return ctx.JSON(dto.WebResponse[*dto.UserResponse]{Data: user})
+}
+
+// Reactivate lifts a user's suspension.
+func (c *UserController) Reactivate(ctx *fiber.Ctx) error {
+	spanCtx, span := c.tracer.Start(ctx.UserContext(), "UserController.Reactivate")
+	defer span.End()
+
+	logger := c.logger.WithContext(spanCtx)
+
+	// Get UUID from path
+	uuid := ctx.Params("uuid")
+	if uuid == "" {
+		return errcode.ErrBadRequest
+	}
+
+	user, err := c.userService.ReactivateUser(spanCtx, uuid)
+	if err != nil {
+		logger.WithError(err).Error("failed to reactivate user")
+		return err
+	}
+
+	return ctx.JSON(dto.WebResponse[*dto.UserResponse]{Data: user})
+}
+
+// EffectivePermissions lists a user's effective permissions with provenance, or
+// answers a single permission check when the "check" query parameter is set.
+func (c *UserController) EffectivePermissions(ctx *fiber.Ctx) error {
+	spanCtx, span := c.tracer.Start(ctx.UserContext(), "UserController.EffectivePermissions")
+	defer span.End()
+
+	logger := c.logger.WithContext(spanCtx)
+
+	// Get UUID from path
+	uuid := ctx.Params("uuid")
+	if uuid == "" {
+		return errcode.ErrBadRequest
+	}
+
+	if permission := ctx.Query("check"); permission != "" {
+		result, err := c.userService.CheckPermission(spanCtx, uuid, permission)
+		if err != nil {
+			logger.WithError(err).Error("failed to check permission")
+			return err
+		}
+		return ctx.JSON(dto.WebResponse[*dto.PermissionCheckResponse]{Data: result})
+	}
+
+	permissions, err := c.userService.GetEffectivePermissions(spanCtx, uuid)
+	if err != nil {
+		logger.WithError(err).Error("failed to get effective permissions")
+		return err
+	}
+
+	return ctx.JSON(dto.WebResponse[*dto.EffectivePermissionsResponse]{Data: permissions})
+}
@@ -8,6 +8,7 @@ import (
     "net/http"
     "net/http/httptest"
     "regexp"
+    "strings"
     "testing"
     "time"
 
@@ -17,7 +18,10 @@ import (
     "github.com/redis/go-redis/v9"
     "github.com/sirupsen/logrus"
     "github.com/stretchr/testify/require"
+    "golang.org/x/crypto/bcrypt"
 
+    "go-starter-template/internal/config/env"
+    "go-starter-template/internal/config/validation"
     "go-starter-template/internal/dto"
     "go-starter-template/internal/repository"
     "go-starter-template/internal/service"
@@ -42,10 +46,15 @@ func setupUserController(t *testing.T) (*UserController, *fiber.App, sqlmock.Sql
 
     userRepo := repository.NewUserRepository(db)
     redisSvc := service.NewRedisService(rdb, logger)
-    userSvc := service.NewUserService(userRepo, redisSvc, logger)
-    ctrl := NewUserController(userSvc, logger)
+    uow := repository.NewUnitOfWork(db)
+    auditSvc := service.NewAuditService(repository.NewAuditRepository(db), logger, uow)
+    userSvc := service.NewUserService(userRepo, repository.NewOutboxRepository(db), auditSvc, redisSvc, logger, uow)
+    ctrl := NewUserController(userSvc, logger, validation.NewValidation(), &env.Config{})
 
     app := fiber.New(fiber.Config{ErrorHandler: func(c *fiber.Ctx, err error) error {
+        if _, ok := err.(*validation.ValidationError); ok {
+            return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+        }
         if code, ok := errcode.GetHTTPStatus(err); ok {
             return c.Status(code).JSON(fiber.Map{"error": err.Error()})
         }
@@ -54,6 +63,19 @@ func setupUserController(t *testing.T) (*UserController, *fiber.App, sqlmock.Sql
     return ctrl, app, mock, mr
 }
 
+// expectOutbox expects the user event recorded alongside a write
+func expectOutbox(mock sqlmock.Sqlmock) {
+    mock.ExpectExec(regexp.QuoteMeta("INSERT INTO outbox")).
+        WillReturnResult(sqlmock.NewResult(1, 1))
+}
+
+// expectAudit expects the audit event appended alongside a write
+func expectAudit(mock sqlmock.Sqlmock) {
+    mock.ExpectExec(regexp.QuoteMeta("SELECT pg_advisory_xact_lock($1)")).WillReturnResult(sqlmock.NewResult(0, 0))
+    mock.ExpectQuery(regexp.QuoteMeta("SELECT hash FROM audit_events")).WillReturnRows(sqlmock.NewRows([]string{"hash"}))
+    mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO audit_events")).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+}
+
 // Table-driven tests for Me endpoint
 func TestUserController_Me(t *testing.T) {
     type testcase struct {
@@ -73,37 +95,37 @@ func TestUserController_Me(t *testing.T) {
         }
     }
 
-    userRow := sqlmock.NewRows([]string{"uuid", "name", "email", "password", "created_at", "updated_at"}).
-        AddRow("user-123", "Alice", "alice@example.com", "hash", time.Now(), time.Now())
+    userRow := sqlmock.NewRows([]string{"uuid", "name", "email", "password", "created_at", "updated_at", "status", "status_reason", "suspended_until", "phone", "version"}).
+        AddRow("user-123", "Alice", "alice@example.com", "hash", time.Now(), time.Now(), "active", "", nil, nil, 1)
 
     cases := []testcase{
         {
             name: "Success_DBAndCache",
             setupDB: func(mock sqlmock.Sqlmock) {
                 // FindByUUID
-                mock.ExpectQuery(regexp.QuoteMeta(`SELECT uuid, name, email, password, created_at, updated_at FROM users WHERE uuid = $1 LIMIT 1`)).
+                mock.ExpectQuery(regexp.QuoteMeta(`SELECT uuid, name, email, password, created_at, updated_at, status, status_reason, suspended_until, phone, version FROM users WHERE uuid = $1 AND deleted_at IS NULL LIMIT 1`)).
                     WithArgs("user-123").
                     WillReturnRows(userRow)
                 // roles (empty)
-                mock.ExpectQuery(regexp.QuoteMeta(`SELECT r.uuid, r.name
-        FROM roles r
-        INNER JOIN user_roles ur ON ur.role_uuid = r.uuid
-        WHERE ur.user_uuid = $1`)).
+                mock.ExpectQuery(regexp.QuoteMeta(`SELECT ur.user_uuid, r.uuid, r.name
+        FROM user_roles ur
+        INNER JOIN roles r ON r.uuid = ur.role_uuid
+        WHERE ur.user_uuid IN ($1)`)).
                     WithArgs("user-123").
                     WillReturnRows(sqlmock.NewRows([]string{"uuid", "name"}))
                 // direct permissions (empty)
-                mock.ExpectQuery(regexp.QuoteMeta(`SELECT p.uuid, p.name
-        FROM permissions p
-        INNER JOIN user_permissions up ON up.permission_uuid = p.uuid
-        WHERE up.user_uuid = $1`)).
+                mock.ExpectQuery(regexp.QuoteMeta(`SELECT up.user_uuid, p.uuid, p.name
+        FROM user_permissions up
+        INNER JOIN permissions p ON p.uuid = up.permission_uuid
+        WHERE up.user_uuid IN ($1)`)).
                     WithArgs("user-123").
                     WillReturnRows(sqlmock.NewRows([]string{"uuid", "name"}))
                 // role permissions (empty)
-                mock.ExpectQuery(regexp.QuoteMeta(`SELECT rp.role_uuid, p.uuid, p.name
+                mock.ExpectQuery(regexp.QuoteMeta(`SELECT DISTINCT rp.role_uuid, p.uuid, p.name
         FROM permissions p
         INNER JOIN role_permissions rp ON rp.permission_uuid = p.uuid
         INNER JOIN user_roles ur ON ur.role_uuid = rp.role_uuid
-        WHERE ur.user_uuid = $1`)).
+        WHERE ur.user_uuid IN ($1)`)).
                     WithArgs("user-123").
                     WillReturnRows(sqlmock.NewRows([]string{"role_uuid", "uuid", "name"}))
             },
@@ -122,7 +144,7 @@ func TestUserController_Me(t *testing.T) {
             name: "NotFound",
             setupDB: func(mock sqlmock.Sqlmock) {
                 // FindByUUID returns error
-                mock.ExpectQuery(regexp.QuoteMeta(`SELECT uuid, name, email, password, created_at, updated_at FROM users WHERE uuid = $1 LIMIT 1`)).
+                mock.ExpectQuery(regexp.QuoteMeta(`SELECT uuid, name, email, password, created_at, updated_at, status, status_reason, suspended_until, phone, version FROM users WHERE uuid = $1 AND deleted_at IS NULL LIMIT 1`)).
                     WithArgs("missing").
                     WillReturnError(fmt.Errorf("no rows"))
             },
@@ -175,15 +197,15 @@ func TestUserController_List(t *testing.T) {
             query: "?page=2&size=2&name=A",
             setupDB: func(mock sqlmock.Sqlmock) {
                 // Count
-                mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM users WHERE name ILIKE $1")).
+                mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM users WHERE deleted_at IS NULL AND name ILIKE $1")).
                     WithArgs("%A%").
                     WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(5))
                 // Data
-                mock.ExpectQuery(regexp.QuoteMeta("SELECT uuid, name, email, created_at, updated_at FROM users WHERE name ILIKE $1 ORDER BY created_at DESC OFFSET $2 LIMIT $3")).
+                mock.ExpectQuery(regexp.QuoteMeta("SELECT uuid, name, email, phone, status, status_reason, suspended_until, version, created_at, updated_at, deleted_at FROM users WHERE deleted_at IS NULL AND name ILIKE $1 ORDER BY created_at DESC, uuid ASC OFFSET $2 LIMIT $3")).
                     WithArgs("%A%", 2, 2).
-                    WillReturnRows(sqlmock.NewRows([]string{"uuid", "name", "email", "created_at", "updated_at"}).
-                        AddRow("u1", "A", "a@example.com", time.Now(), time.Now()).
-                        AddRow("u2", "B", "b@example.com", time.Now(), time.Now()))
+                    WillReturnRows(sqlmock.NewRows([]string{"uuid", "name", "email", "phone", "status", "status_reason", "suspended_until", "version", "created_at", "updated_at", "deleted_at"}).
+                        AddRow("u1", "A", "a@example.com", nil, "active", "", nil, 1, time.Now(), time.Now(), nil).
+                        AddRow("u2", "B", "b@example.com", nil, "active", "", nil, 1, time.Now(), time.Now(), nil))
             },
             expectStatus: http.StatusOK,
             assert: func(t *testing.T, resp *http.Response) {
@@ -193,21 +215,21 @@ func TestUserController_List(t *testing.T) {
                 require.NotNil(t, out.Paging)
                 require.Equal(t, 2, out.Paging.Page)
                 require.Equal(t, 2, out.Paging.Size)
-                require.Equal(t, int64(5), out.Paging.TotalItem)
-                require.Equal(t, int64(3), out.Paging.TotalPage)
+                require.Equal(t, int64(5), *out.Paging.TotalItem)
+                require.Equal(t, int64(3), *out.Paging.TotalPage)
             },
         },
         {
             name:  "DefaultPaging",
             query: "",
             setupDB: func(mock sqlmock.Sqlmock) {
-                mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM users ")).
+                mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM users WHERE deleted_at IS NULL")).
                     WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
-                mock.ExpectQuery(regexp.QuoteMeta("SELECT uuid, name, email, created_at, updated_at FROM users  ORDER BY created_at DESC OFFSET $1 LIMIT $2")).
+                mock.ExpectQuery(regexp.QuoteMeta("SELECT uuid, name, email, phone, status, status_reason, suspended_until, version, created_at, updated_at, deleted_at FROM users WHERE deleted_at IS NULL ORDER BY created_at DESC, uuid ASC OFFSET $1 LIMIT $2")).
                     WithArgs(0, 10).
-                    WillReturnRows(sqlmock.NewRows([]string{"uuid", "name", "email", "created_at", "updated_at"}).
-                        AddRow("u1", "A", "a@example.com", time.Now(), time.Now()).
-                        AddRow("u2", "B", "b@example.com", time.Now(), time.Now()))
+                    WillReturnRows(sqlmock.NewRows([]string{"uuid", "name", "email", "phone", "status", "status_reason", "suspended_until", "version", "created_at", "updated_at", "deleted_at"}).
+                        AddRow("u1", "A", "a@example.com", nil, "active", "", nil, 1, time.Now(), time.Now(), nil).
+                        AddRow("u2", "B", "b@example.com", nil, "active", "", nil, 1, time.Now(), time.Now(), nil))
             },
             expectStatus: http.StatusOK,
         },
@@ -215,7 +237,7 @@ func TestUserController_List(t *testing.T) {
             name:  "SearchError",
             query: "?page=1&size=10",
             setupDB: func(mock sqlmock.Sqlmock) {
-                mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM users ")).
+                mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM users WHERE deleted_at IS NULL")).
                     WillReturnError(fmt.Errorf("db error"))
             },
             expectStatus: http.StatusNotFound,
@@ -225,6 +247,49 @@ func TestUserController_List(t *testing.T) {
                 require.Equal(t, "failed to retrieve users", out.Error)
             },
         },
+        {
+            name:  "CursorMode_SortAndSkipCount",
+            query: "?limit=1&sort=name&skip_count=true",
+            setupDB: func(mock sqlmock.Sqlmock) {
+                mock.ExpectQuery(regexp.QuoteMeta("SELECT uuid, name, email, phone, status, status_reason, suspended_until, version, created_at, updated_at, deleted_at FROM users WHERE deleted_at IS NULL ORDER BY name ASC, uuid ASC LIMIT $1")).
+                    WithArgs(2).
+                    WillReturnRows(sqlmock.NewRows([]string{"uuid", "name", "email", "phone", "status", "status_reason", "suspended_until", "version", "created_at", "updated_at", "deleted_at"}).
+                        AddRow("u1", "A", "a@example.com", nil, "active", "", nil, 1, time.Now(), time.Now(), nil).
+                        AddRow("u2", "B", "b@example.com", nil, "active", "", nil, 1, time.Now(), time.Now(), nil))
+            },
+            expectStatus: http.StatusOK,
+            assert: func(t *testing.T, resp *http.Response) {
+                var out dto.WebResponse[[]*dto.UserResponse]
+                require.NoError(t, json.NewDecoder(resp.Body).Decode(&out))
+                require.Len(t, out.Data, 1)
+                require.NotEmpty(t, out.Paging.Next)
+                require.Empty(t, out.Paging.Prev)
+                require.Nil(t, out.Paging.TotalItem)
+            },
+        },
+        {
+            name:  "Filters_MatchAny",
+            query: "?role=admin&status=active&match=any&skip_count=true",
+            setupDB: func(mock sqlmock.Sqlmock) {
+                mock.ExpectQuery(regexp.QuoteMeta("WHERE deleted_at IS NULL AND (EXISTS (SELECT 1 FROM user_roles ur INNER JOIN roles r ON r.uuid = ur.role_uuid WHERE ur.user_uuid = users.uuid AND r.name IN ($1)) OR status IN ($2)) ORDER BY")).
+                    WithArgs("admin", "active", 0, 10).
+                    WillReturnRows(sqlmock.NewRows([]string{"uuid", "name", "email", "phone", "status", "status_reason", "suspended_until", "version", "created_at", "updated_at", "deleted_at"}).
+                        AddRow("u1", "A", "a@example.com", nil, "active", "", nil, 1, time.Now(), time.Now(), nil))
+            },
+            expectStatus: http.StatusOK,
+        },
+        {
+            name:         "InvalidFilter",
+            query:        "?status=banned",
+            setupDB:      func(sqlmock.Sqlmock) {},
+            expectStatus: http.StatusBadRequest,
+        },
+        {
+            name:         "InvalidSort",
+            query:        "?sort=password",
+            setupDB:      func(sqlmock.Sqlmock) {},
+            expectStatus: http.StatusBadRequest,
+        },
         {
             name:  "BadRequest_QueryParse",
             query: "?page=abc&size=10",
@@ -260,6 +325,277 @@ func TestUserController_List(t *testing.T) {
     }
 }
 
+func TestUserController_Bulk(t *testing.T) {
+    expectCreate := func(mock sqlmock.Sqlmock, email string) {
+        mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM users WHERE lower(email) = lower($1) AND deleted_at IS NULL")).
+            WithArgs(email).
+            WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+        mock.ExpectExec(regexp.QuoteMeta("INSERT INTO users")).
+            WillReturnResult(sqlmock.NewResult(1, 1))
+        expectOutbox(mock)
+        expectAudit(mock)
+    }
+
+    type testcase struct {
+        name         string
+        query        string
+        contentType  string
+        body         string
+        setupDB      func(sqlmock.Sqlmock)
+        expectStatus int
+        assert       func(*testing.T, *dto.BulkUserResponse)
+    }
+
+    cases := []testcase{
+        {
+            name: "Atomic_Success",
+            body: `[{"op":"create","data":{"name":"Alice","email":"alice@example.com","password":"secret123"}}]`,
+            setupDB: func(mock sqlmock.Sqlmock) {
+                mock.ExpectBegin()
+                expectCreate(mock, "alice@example.com")
+                mock.ExpectCommit()
+            },
+            expectStatus: http.StatusOK,
+            assert: func(t *testing.T, out *dto.BulkUserResponse) {
+                require.Equal(t, "atomic", string(out.Mode))
+                require.Equal(t, 1, out.Succeeded)
+                require.Equal(t, http.StatusCreated, out.Results[0].Status)
+                require.Equal(t, "alice@example.com", out.Results[0].Data.Email)
+            },
+        },
+        {
+            name:        "BestEffort_NDJSONWithInvalidItem",
+            query:       "?mode=best_effort",
+            contentType: "application/x-ndjson",
+            body: `{"op":"create","data":{"name":"Bob","email":"bob@example.com","password":"secret123"}}
+{"op":"create","data":{"name":"Carol","email":"not-an-email","password":"secret123"}}
+`,
+            setupDB: func(mock sqlmock.Sqlmock) {
+                mock.ExpectBegin()
+                expectCreate(mock, "bob@example.com")
+                mock.ExpectCommit()
+            },
+            expectStatus: http.StatusMultiStatus,
+            assert: func(t *testing.T, out *dto.BulkUserResponse) {
+                require.Equal(t, 1, out.Succeeded)
+                require.Equal(t, 1, out.Failed)
+                require.Equal(t, http.StatusBadRequest, out.Results[1].Status)
+                require.Contains(t, out.Results[1].Errors, "email")
+            },
+        },
+        {
+            name:         "InvalidMode",
+            query:        "?mode=sometimes",
+            body:         `[{"op":"delete","uuid":"u1"}]`,
+            setupDB:      func(sqlmock.Sqlmock) {},
+            expectStatus: http.StatusBadRequest,
+        },
+        {
+            name:         "MalformedBody",
+            body:         `{"op":"delete"}`,
+            setupDB:      func(sqlmock.Sqlmock) {},
+            expectStatus: http.StatusBadRequest,
+        },
+    }
+
+    for _, tc := range cases {
+        t.Run(tc.name, func(t *testing.T) {
+            ctrl, app, mock, mr := setupUserController(t)
+            defer mr.Close()
+            app.Post("/users/bulk", ctrl.Bulk)
+            tc.setupDB(mock)
+
+            req := httptest.NewRequest(http.MethodPost, "/users/bulk"+tc.query, bytes.NewBufferString(tc.body))
+            contentType := tc.contentType
+            if contentType == "" {
+                contentType = "application/json"
+            }
+            req.Header.Set("Content-Type", contentType)
+            resp, err := app.Test(req, -1)
+            require.NoError(t, err)
+            require.Equal(t, tc.expectStatus, resp.StatusCode)
+            if tc.assert != nil {
+                var out dto.WebResponse[*dto.BulkUserResponse]
+                require.NoError(t, json.NewDecoder(resp.Body).Decode(&out))
+                tc.assert(t, out.Data)
+            }
+            require.NoError(t, mock.ExpectationsWereMet())
+        })
+    }
+}
+
+func TestUserController_Export(t *testing.T) {
+    columns := []string{"uuid", "name", "email", "phone", "status", "created_at", "updated_at", "deleted_at"}
+    created := time.Unix(1700000000, 0)
+
+    type testcase struct {
+        name         string
+        query        string
+        setupDB      func(sqlmock.Sqlmock)
+        expectStatus int
+        expectType   string
+        expectBody   string
+    }
+
+    cases := []testcase{
+        {
+            name:  "CSV_WithFilters",
+            query: "?status=active&sort=name",
+            setupDB: func(mock sqlmock.Sqlmock) {
+                mock.ExpectQuery(regexp.QuoteMeta("FROM users WHERE deleted_at IS NULL AND status IN ($1) ORDER BY name ASC, uuid ASC")).
+                    WithArgs("active").
+                    WillReturnRows(sqlmock.NewRows(columns).AddRow("u1", "Alice", "a@example.com", nil, "active", created, created, nil))
+            },
+            expectStatus: http.StatusOK,
+            expectType:   "text/csv",
+            expectBody: "uuid,name,email,phone,status,created_at,updated_at,deleted_at\n" +
+                "u1,Alice,a@example.com,,active,2023-11-14T22:13:20Z,2023-11-14T22:13:20Z,\n",
+        },
+        {
+            name:  "NDJSON",
+            query: "?format=ndjson",
+            setupDB: func(mock sqlmock.Sqlmock) {
+                mock.ExpectQuery(regexp.QuoteMeta("FROM users WHERE deleted_at IS NULL ORDER BY created_at DESC, uuid ASC")).
+                    WillReturnRows(sqlmock.NewRows(columns).
+                        AddRow("u1", "Alice", "a@example.com", nil, "active", created, created, nil).
+                        AddRow("u2", "Bob", "b@example.com", nil, "active", created, created, nil))
+            },
+            expectStatus: http.StatusOK,
+            expectType:   "application/x-ndjson",
+        },
+        {
+            name:         "UnknownFormat",
+            query:        "?format=xlsx",
+            setupDB:      func(sqlmock.Sqlmock) {},
+            expectStatus: http.StatusBadRequest,
+        },
+        {
+            name:         "InvalidFilter",
+            query:        "?status=banned",
+            setupDB:      func(sqlmock.Sqlmock) {},
+            expectStatus: http.StatusBadRequest,
+        },
+    }
+
+    for _, tc := range cases {
+        t.Run(tc.name, func(t *testing.T) {
+            ctrl, app, mock, mr := setupUserController(t)
+            defer mr.Close()
+            app.Get("/users/export", ctrl.Export)
+            tc.setupDB(mock)
+
+            resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/users/export"+tc.query, nil), -1)
+            require.NoError(t, err)
+            require.Equal(t, tc.expectStatus, resp.StatusCode)
+            body, err := io.ReadAll(resp.Body)
+            require.NoError(t, err)
+            if tc.expectType != "" {
+                require.Equal(t, tc.expectType, resp.Header.Get("Content-Type"))
+                require.Contains(t, resp.Header.Get("Content-Disposition"), "attachment")
+            }
+            if tc.expectBody != "" {
+                require.Equal(t, tc.expectBody, string(body))
+            }
+            if tc.expectType == "application/x-ndjson" {
+                require.Equal(t, 2, strings.Count(string(body), "\n"))
+            }
+            require.NoError(t, mock.ExpectationsWereMet())
+        })
+    }
+}
+
+func TestUserController_Import(t *testing.T) {
+    type testcase struct {
+        name         string
+        query        string
+        contentType  string
+        body         string
+        setupDB      func(sqlmock.Sqlmock)
+        expectStatus int
+        assert       func(*testing.T, *dto.BulkUserResponse)
+    }
+
+    cases := []testcase{
+        {
+            name:        "CSV_DryRun",
+            query:       "?dry_run=true",
+            contentType: "text/csv",
+            body:        "name,email,password\nAlice,alice@example.com,secret123\nBob,bob,secret123\n",
+            setupDB: func(mock sqlmock.Sqlmock) {
+                mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM users WHERE lower(email) = lower($1)")).
+                    WithArgs("alice@example.com").
+                    WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+            },
+            expectStatus: http.StatusMultiStatus,
+            assert: func(t *testing.T, out *dto.BulkUserResponse) {
+                require.True(t, out.DryRun)
+                require.Equal(t, http.StatusFailedDependency, out.Results[0].Status)
+                require.Equal(t, 3, out.Results[1].Line)
+                require.Contains(t, out.Results[1].Errors, "email")
+            },
+        },
+        {
+            name:        "NDJSON_BestEffort",
+            query:       "?mode=best_effort",
+            contentType: "application/x-ndjson",
+            body:        `{"name":"Alice","email":"alice@example.com","password":"secret123"}` + "\n",
+            setupDB: func(mock sqlmock.Sqlmock) {
+                mock.ExpectBegin()
+                mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM users WHERE lower(email) = lower($1)")).
+                    WithArgs("alice@example.com").
+                    WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+                mock.ExpectExec(regexp.QuoteMeta("INSERT INTO users")).
+                    WillReturnResult(sqlmock.NewResult(1, 1))
+                expectOutbox(mock)
+                expectAudit(mock)
+                mock.ExpectCommit()
+            },
+            expectStatus: http.StatusOK,
+            assert: func(t *testing.T, out *dto.BulkUserResponse) {
+                require.False(t, out.DryRun)
+                require.Equal(t, 1, out.Succeeded)
+                require.Equal(t, http.StatusCreated, out.Results[0].Status)
+                require.Equal(t, 1, out.Results[0].Line)
+            },
+        },
+        {
+            name:         "MissingColumn",
+            contentType:  "text/csv",
+            body:         "name,email\nAlice,alice@example.com\n",
+            setupDB:      func(sqlmock.Sqlmock) {},
+            expectStatus: http.StatusBadRequest,
+        },
+        {
+            name:         "HeaderOnly",
+            contentType:  "text/csv",
+            body:         "name,email,password\n",
+            setupDB:      func(sqlmock.Sqlmock) {},
+            expectStatus: http.StatusBadRequest,
+        },
+    }
+
+    for _, tc := range cases {
+        t.Run(tc.name, func(t *testing.T) {
+            ctrl, app, mock, mr := setupUserController(t)
+            defer mr.Close()
+            app.Post("/users/import", ctrl.Import)
+            tc.setupDB(mock)
+
+            req := httptest.NewRequest(http.MethodPost, "/users/import"+tc.query, strings.NewReader(tc.body))
+            req.Header.Set("Content-Type", tc.contentType)
+            resp, err := app.Test(req, -1)
+            require.NoError(t, err)
+            require.Equal(t, tc.expectStatus, resp.StatusCode)
+            if tc.assert != nil {
+                var out dto.WebResponse[*dto.BulkUserResponse]
+                require.NoError(t, json.NewDecoder(resp.Body).Decode(&out))
+                tc.assert(t, out.Data)
+            }
+            require.NoError(t, mock.ExpectationsWereMet())
+        })
+    }
+}
+
 // Table-driven tests for Create endpoint
 func TestUserController_Create(t *testing.T) {
     type testcase struct {
@@ -275,12 +611,16 @@ func TestUserController_Create(t *testing.T) {
             name: "Success",
             body: `{"name":"Alice","email":"alice@example.com","password":"secret123"}`,
             setupDB: func(mock sqlmock.Sqlmock) {
-                mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM users WHERE email = $1")).
+                mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM users WHERE lower(email) = lower($1) AND deleted_at IS NULL")).
                     WithArgs("alice@example.com").
                     WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+                mock.ExpectBegin()
                 mock.ExpectExec(regexp.QuoteMeta("INSERT INTO users (uuid, name, email, password, created_at, updated_at) VALUES ($1, $2, $3, $4, NOW(), NOW())")).
                     WithArgs(sqlmock.AnyArg(), "Alice", "alice@example.com", sqlmock.AnyArg()).
                     WillReturnResult(sqlmock.NewResult(1, 1))
+                expectOutbox(mock)
+                expectAudit(mock)
+                mock.ExpectCommit()
             },
             expectStatus: http.StatusOK,
             assert: func(t *testing.T, resp *http.Response) {
@@ -299,7 +639,7 @@ func TestUserController_Create(t *testing.T) {
             name: "EmailExists",
             body: `{"name":"Alice","email":"alice@example.com","password":"secret123"}`,
             setupDB: func(mock sqlmock.Sqlmock) {
-                mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM users WHERE email = $1")).
+                mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM users WHERE lower(email) = lower($1) AND deleted_at IS NULL")).
                     WithArgs("alice@example.com").
                     WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
             },
@@ -314,7 +654,7 @@ func TestUserController_Create(t *testing.T) {
             name: "InternalError_CountQuery",
             body: `{"name":"Alice","email":"alice@example.com","password":"secret123"}`,
             setupDB: func(mock sqlmock.Sqlmock) {
-                mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM users WHERE email = $1")).
+                mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM users WHERE lower(email) = lower($1) AND deleted_at IS NULL")).
                     WithArgs("alice@example.com").
                     WillReturnError(fmt.Errorf("db error"))
             },
@@ -329,12 +669,14 @@ func TestUserController_Create(t *testing.T) {
             name: "InternalError_Insert",
             body: `{"name":"Alice","email":"alice@example.com","password":"secret123"}`,
             setupDB: func(mock sqlmock.Sqlmock) {
-                mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM users WHERE email = $1")).
+                mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM users WHERE lower(email) = lower($1) AND deleted_at IS NULL")).
                     WithArgs("alice@example.com").
                     WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+                mock.ExpectBegin()
                 mock.ExpectExec(regexp.QuoteMeta("INSERT INTO users (uuid, name, email, password, created_at, updated_at) VALUES ($1, $2, $3, $4, NOW(), NOW())")).
                     WithArgs(sqlmock.AnyArg(), "Alice", "alice@example.com", sqlmock.AnyArg()).
                     WillReturnError(fmt.Errorf("insert error"))
+                mock.ExpectRollback()
             },
             expectStatus: http.StatusInternalServerError,
         },
@@ -373,8 +715,8 @@ func TestUserController_Update(t *testing.T) {
     }
 
     newUserRow := func(uuid, name, email string) *sqlmock.Rows {
-        return sqlmock.NewRows([]string{"uuid", "name", "email", "password", "created_at", "updated_at"}).
-            AddRow(uuid, name, email, "hash", time.Now(), time.Now())
+        return sqlmock.NewRows([]string{"uuid", "name", "email", "password", "created_at", "updated_at", "status", "status_reason", "suspended_until", "phone", "version"}).
+            AddRow(uuid, name, email, "hash", time.Now(), time.Now(), "active", "", nil, nil, 1)
     }
 
     cases := []testcase{
@@ -390,7 +732,7 @@ func TestUserController_Update(t *testing.T) {
             uuid: "missing",
             body: `{"name":"Alice","email":"alice@example.com"}`,
             setupDB: func(mock sqlmock.Sqlmock) {
-                mock.ExpectQuery(regexp.QuoteMeta(`SELECT uuid, name, email, password, created_at, updated_at FROM users WHERE uuid = $1 LIMIT 1`)).
+                mock.ExpectQuery(regexp.QuoteMeta(`SELECT uuid, name, email, password, created_at, updated_at, status, status_reason, suspended_until, phone, version FROM users WHERE uuid = $1 AND deleted_at IS NULL LIMIT 1`)).
                     WithArgs("missing").
                     WillReturnError(fmt.Errorf("no rows"))
             },
@@ -406,34 +748,38 @@ func TestUserController_Update(t *testing.T) {
             uuid: "u1",
             body: `{"name":"Alice","email":"old@example.com"}`,
             setupDB: func(mock sqlmock.Sqlmock) {
-                mock.ExpectQuery(regexp.QuoteMeta(`SELECT uuid, name, email, password, created_at, updated_at FROM users WHERE uuid = $1 LIMIT 1`)).
+                mock.ExpectQuery(regexp.QuoteMeta(`SELECT uuid, name, email, password, created_at, updated_at, status, status_reason, suspended_until, phone, version FROM users WHERE uuid = $1 AND deleted_at IS NULL LIMIT 1`)).
                     WithArgs("u1").
                     WillReturnRows(newUserRow("u1", "OldName", "old@example.com"))
                 // roles (empty)
-                mock.ExpectQuery(regexp.QuoteMeta(`SELECT r.uuid, r.name
-        FROM roles r
-        INNER JOIN user_roles ur ON ur.role_uuid = r.uuid
-        WHERE ur.user_uuid = $1`)).
+                mock.ExpectQuery(regexp.QuoteMeta(`SELECT ur.user_uuid, r.uuid, r.name
+        FROM user_roles ur
+        INNER JOIN roles r ON r.uuid = ur.role_uuid
+        WHERE ur.user_uuid IN ($1)`)).
                     WithArgs("u1").
                     WillReturnRows(sqlmock.NewRows([]string{"uuid", "name"}))
                 // direct permissions (empty)
-                mock.ExpectQuery(regexp.QuoteMeta(`SELECT p.uuid, p.name
-        FROM permissions p
-        INNER JOIN user_permissions up ON up.permission_uuid = p.uuid
-        WHERE up.user_uuid = $1`)).
+                mock.ExpectQuery(regexp.QuoteMeta(`SELECT up.user_uuid, p.uuid, p.name
+        FROM user_permissions up
+        INNER JOIN permissions p ON p.uuid = up.permission_uuid
+        WHERE up.user_uuid IN ($1)`)).
                     WithArgs("u1").
                     WillReturnRows(sqlmock.NewRows([]string{"uuid", "name"}))
                 // role permissions (empty)
-                mock.ExpectQuery(regexp.QuoteMeta(`SELECT rp.role_uuid, p.uuid, p.name
+                mock.ExpectQuery(regexp.QuoteMeta(`SELECT DISTINCT rp.role_uuid, p.uuid, p.name
         FROM permissions p
         INNER JOIN role_permissions rp ON rp.permission_uuid = p.uuid
         INNER JOIN user_roles ur ON ur.role_uuid = rp.role_uuid
-        WHERE ur.user_uuid = $1`)).
+        WHERE ur.user_uuid IN ($1)`)).
                     WithArgs("u1").
                     WillReturnRows(sqlmock.NewRows([]string{"role_uuid", "uuid", "name"}))
-                mock.ExpectExec(regexp.QuoteMeta("UPDATE users SET name = $1, email = $2, updated_at = NOW() WHERE uuid = $3")).
-                    WithArgs("Alice", "old@example.com", "u1").
+                mock.ExpectBegin()
+                mock.ExpectExec(regexp.QuoteMeta("UPDATE users SET name = $1, email = $2, version = version + 1, updated_at = NOW() WHERE uuid = $3 AND version = $4")).
+                    WithArgs("Alice", "old@example.com", "u1", 1).
                     WillReturnResult(sqlmock.NewResult(1, 1))
+                expectOutbox(mock)
+                expectAudit(mock)
+                mock.ExpectCommit()
             },
             expectStatus: http.StatusOK,
             assert: func(t *testing.T, resp *http.Response) {
@@ -448,30 +794,30 @@ func TestUserController_Update(t *testing.T) {
             uuid: "u1",
             body: `{"name":"Alice","email":"new@example.com"}`,
             setupDB: func(mock sqlmock.Sqlmock) {
-                mock.ExpectQuery(regexp.QuoteMeta(`SELECT uuid, name, email, password, created_at, updated_at FROM users WHERE uuid = $1 LIMIT 1`)).
+                mock.ExpectQuery(regexp.QuoteMeta(`SELECT uuid, name, email, password, created_at, updated_at, status, status_reason, suspended_until, phone, version FROM users WHERE uuid = $1 AND deleted_at IS NULL LIMIT 1`)).
                     WithArgs("u1").
                     WillReturnRows(newUserRow("u1", "OldName", "old@example.com"))
                 // roles/permissions queries
-                mock.ExpectQuery(regexp.QuoteMeta(`SELECT r.uuid, r.name
-        FROM roles r
-        INNER JOIN user_roles ur ON ur.role_uuid = r.uuid
-        WHERE ur.user_uuid = $1`)).
+                mock.ExpectQuery(regexp.QuoteMeta(`SELECT ur.user_uuid, r.uuid, r.name
+        FROM user_roles ur
+        INNER JOIN roles r ON r.uuid = ur.role_uuid
+        WHERE ur.user_uuid IN ($1)`)).
                     WithArgs("u1").
                     WillReturnRows(sqlmock.NewRows([]string{"uuid", "name"}))
-                mock.ExpectQuery(regexp.QuoteMeta(`SELECT p.uuid, p.name
-        FROM permissions p
-        INNER JOIN user_permissions up ON up.permission_uuid = p.uuid
-        WHERE up.user_uuid = $1`)).
+                mock.ExpectQuery(regexp.QuoteMeta(`SELECT up.user_uuid, p.uuid, p.name
+        FROM user_permissions up
+        INNER JOIN permissions p ON p.uuid = up.permission_uuid
+        WHERE up.user_uuid IN ($1)`)).
                     WithArgs("u1").
                     WillReturnRows(sqlmock.NewRows([]string{"uuid", "name"}))
-                mock.ExpectQuery(regexp.QuoteMeta(`SELECT rp.role_uuid, p.uuid, p.name
+                mock.ExpectQuery(regexp.QuoteMeta(`SELECT DISTINCT rp.role_uuid, p.uuid, p.name
         FROM permissions p
         INNER JOIN role_permissions rp ON rp.permission_uuid = p.uuid
         INNER JOIN user_roles ur ON ur.role_uuid = rp.role_uuid
-        WHERE ur.user_uuid = $1`)).
+        WHERE ur.user_uuid IN ($1)`)).
                     WithArgs("u1").
                     WillReturnRows(sqlmock.NewRows([]string{"role_uuid", "uuid", "name"}))
-                mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM users WHERE email = $1")).
+                mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM users WHERE lower(email) = lower($1) AND deleted_at IS NULL")).
                     WithArgs("new@example.com").
                     WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
             },
@@ -494,29 +840,29 @@ func TestUserController_Update(t *testing.T) {
             uuid: "u1",
             body: `{"name":"Alice","email":"new@example.com"}`,
             setupDB: func(mock sqlmock.Sqlmock) {
-                mock.ExpectQuery(regexp.QuoteMeta(`SELECT uuid, name, email, password, created_at, updated_at FROM users WHERE uuid = $1 LIMIT 1`)).
+                mock.ExpectQuery(regexp.QuoteMeta(`SELECT uuid, name, email, password, created_at, updated_at, status, status_reason, suspended_until, phone, version FROM users WHERE uuid = $1 AND deleted_at IS NULL LIMIT 1`)).
                     WithArgs("u1").
                     WillReturnRows(newUserRow("u1", "OldName", "old@example.com"))
-                mock.ExpectQuery(regexp.QuoteMeta(`SELECT r.uuid, r.name
-        FROM roles r
-        INNER JOIN user_roles ur ON ur.role_uuid = r.uuid
-        WHERE ur.user_uuid = $1`)).
+                mock.ExpectQuery(regexp.QuoteMeta(`SELECT ur.user_uuid, r.uuid, r.name
+        FROM user_roles ur
+        INNER JOIN roles r ON r.uuid = ur.role_uuid
+        WHERE ur.user_uuid IN ($1)`)).
                     WithArgs("u1").
                     WillReturnRows(sqlmock.NewRows([]string{"uuid", "name"}))
-                mock.ExpectQuery(regexp.QuoteMeta(`SELECT p.uuid, p.name
-        FROM permissions p
-        INNER JOIN user_permissions up ON up.permission_uuid = p.uuid
-        WHERE up.user_uuid = $1`)).
+                mock.ExpectQuery(regexp.QuoteMeta(`SELECT up.user_uuid, p.uuid, p.name
+        FROM user_permissions up
+        INNER JOIN permissions p ON p.uuid = up.permission_uuid
+        WHERE up.user_uuid IN ($1)`)).
                     WithArgs("u1").
                     WillReturnRows(sqlmock.NewRows([]string{"uuid", "name"}))
-                mock.ExpectQuery(regexp.QuoteMeta(`SELECT rp.role_uuid, p.uuid, p.name
+                mock.ExpectQuery(regexp.QuoteMeta(`SELECT DISTINCT rp.role_uuid, p.uuid, p.name
         FROM permissions p
         INNER JOIN role_permissions rp ON rp.permission_uuid = p.uuid
         INNER JOIN user_roles ur ON ur.role_uuid = rp.role_uuid
-        WHERE ur.user_uuid = $1`)).
+        WHERE ur.user_uuid IN ($1)`)).
                     WithArgs("u1").
                     WillReturnRows(sqlmock.NewRows([]string{"role_uuid", "uuid", "name"}))
-                mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM users WHERE email = $1")).
+                mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM users WHERE lower(email) = lower($1) AND deleted_at IS NULL")).
                     WithArgs("new@example.com").
                     WillReturnError(fmt.Errorf("count error"))
             },
@@ -527,31 +873,33 @@ func TestUserController_Update(t *testing.T) {
             uuid: "u1",
             body: `{"name":"Alice","email":"old@example.com"}`,
             setupDB: func(mock sqlmock.Sqlmock) {
-                mock.ExpectQuery(regexp.QuoteMeta(`SELECT uuid, name, email, password, created_at, updated_at FROM users WHERE uuid = $1 LIMIT 1`)).
+                mock.ExpectQuery(regexp.QuoteMeta(`SELECT uuid, name, email, password, created_at, updated_at, status, status_reason, suspended_until, phone, version FROM users WHERE uuid = $1 AND deleted_at IS NULL LIMIT 1`)).
                     WithArgs("u1").
                     WillReturnRows(newUserRow("u1", "OldName", "old@example.com"))
-                mock.ExpectQuery(regexp.QuoteMeta(`SELECT r.uuid, r.name
-        FROM roles r
-        INNER JOIN user_roles ur ON ur.role_uuid = r.uuid
-        WHERE ur.user_uuid = $1`)).
+                mock.ExpectQuery(regexp.QuoteMeta(`SELECT ur.user_uuid, r.uuid, r.name
+        FROM user_roles ur
+        INNER JOIN roles r ON r.uuid = ur.role_uuid
+        WHERE ur.user_uuid IN ($1)`)).
                     WithArgs("u1").
                     WillReturnRows(sqlmock.NewRows([]string{"uuid", "name"}))
-                mock.ExpectQuery(regexp.QuoteMeta(`SELECT p.uuid, p.name
-        FROM permissions p
-        INNER JOIN user_permissions up ON up.permission_uuid = p.uuid
-        WHERE up.user_uuid = $1`)).
+                mock.ExpectQuery(regexp.QuoteMeta(`SELECT up.user_uuid, p.uuid, p.name
+        FROM user_permissions up
+        INNER JOIN permissions p ON p.uuid = up.permission_uuid
+        WHERE up.user_uuid IN ($1)`)).
                     WithArgs("u1").
                     WillReturnRows(sqlmock.NewRows([]string{"uuid", "name"}))
-                mock.ExpectQuery(regexp.QuoteMeta(`SELECT rp.role_uuid, p.uuid, p.name
+                mock.ExpectQuery(regexp.QuoteMeta(`SELECT DISTINCT rp.role_uuid, p.uuid, p.name
         FROM permissions p
         INNER JOIN role_permissions rp ON rp.permission_uuid = p.uuid
         INNER JOIN user_roles ur ON ur.role_uuid = rp.role_uuid
-        WHERE ur.user_uuid = $1`)).
+        WHERE ur.user_uuid IN ($1)`)).
                     WithArgs("u1").
                     WillReturnRows(sqlmock.NewRows([]string{"role_uuid", "uuid", "name"}))
-                mock.ExpectExec(regexp.QuoteMeta("UPDATE users SET name = $1, email = $2, updated_at = NOW() WHERE uuid = $3")).
-                    WithArgs("Alice", "old@example.com", "u1").
+                mock.ExpectBegin()
+                mock.ExpectExec(regexp.QuoteMeta("UPDATE users SET name = $1, email = $2, version = version + 1, updated_at = NOW() WHERE uuid = $3 AND version = $4")).
+                    WithArgs("Alice", "old@example.com", "u1", 1).
                     WillReturnError(fmt.Errorf("update error"))
+                mock.ExpectRollback()
             },
             expectStatus: http.StatusInternalServerError,
         },
@@ -575,6 +923,7 @@ func TestUserController_Update(t *testing.T) {
             }
             req := httptest.NewRequest(http.MethodPut, path, bytes.NewBufferString(tc.body))
             req.Header.Set("Content-Type", "application/json")
+            req.Header.Set("If-Match", `"1"`)
             resp, err := app.Test(req, -1)
             require.NoError(t, err)
             require.Equal(t, tc.expectStatus, resp.StatusCode)
@@ -601,7 +950,7 @@ func TestUserController_Delete(t *testing.T) {
             name: "NotFound",
             uuid: "missing",
             setupDB: func(mock sqlmock.Sqlmock) {
-                mock.ExpectQuery(regexp.QuoteMeta(`SELECT uuid, name, email, password, created_at, updated_at FROM users WHERE uuid = $1 LIMIT 1`)).
+                mock.ExpectQuery(regexp.QuoteMeta(`SELECT uuid, name, email, password, created_at, updated_at, status, status_reason, suspended_until, phone, version FROM users WHERE uuid = $1 AND deleted_at IS NULL LIMIT 1`)).
                     WithArgs("missing").
                     WillReturnError(fmt.Errorf("no rows"))
             },
@@ -622,34 +971,38 @@ func TestUserController_Delete(t *testing.T) {
             name: "Success",
             uuid: "u1",
             setupDB: func(mock sqlmock.Sqlmock) {
-                userRow := sqlmock.NewRows([]string{"uuid", "name", "email", "password", "created_at", "updated_at"}).
-                    AddRow("u1", "Name", "email@example.com", "hash", time.Now(), time.Now())
-                mock.ExpectQuery(regexp.QuoteMeta(`SELECT uuid, name, email, password, created_at, updated_at FROM users WHERE uuid = $1 LIMIT 1`)).
+                userRow := sqlmock.NewRows([]string{"uuid", "name", "email", "password", "created_at", "updated_at", "status", "status_reason", "suspended_until", "phone", "version"}).
+                    AddRow("u1", "Name", "email@example.com", "hash", time.Now(), time.Now(), "active", "", nil, nil, 1)
+                mock.ExpectQuery(regexp.QuoteMeta(`SELECT uuid, name, email, password, created_at, updated_at, status, status_reason, suspended_until, phone, version FROM users WHERE uuid = $1 AND deleted_at IS NULL LIMIT 1`)).
                     WithArgs("u1").
                     WillReturnRows(userRow)
                 // roles/permissions queries
-                mock.ExpectQuery(regexp.QuoteMeta(`SELECT r.uuid, r.name
-        FROM roles r
-        INNER JOIN user_roles ur ON ur.role_uuid = r.uuid
-        WHERE ur.user_uuid = $1`)).
+                mock.ExpectQuery(regexp.QuoteMeta(`SELECT ur.user_uuid, r.uuid, r.name
+        FROM user_roles ur
+        INNER JOIN roles r ON r.uuid = ur.role_uuid
+        WHERE ur.user_uuid IN ($1)`)).
                     WithArgs("u1").
                     WillReturnRows(sqlmock.NewRows([]string{"uuid", "name"}))
-                mock.ExpectQuery(regexp.QuoteMeta(`SELECT p.uuid, p.name
-        FROM permissions p
-        INNER JOIN user_permissions up ON up.permission_uuid = p.uuid
-        WHERE up.user_uuid = $1`)).
+                mock.ExpectQuery(regexp.QuoteMeta(`SELECT up.user_uuid, p.uuid, p.name
+        FROM user_permissions up
+        INNER JOIN permissions p ON p.uuid = up.permission_uuid
+        WHERE up.user_uuid IN ($1)`)).
                     WithArgs("u1").
                     WillReturnRows(sqlmock.NewRows([]string{"uuid", "name"}))
-                mock.ExpectQuery(regexp.QuoteMeta(`SELECT rp.role_uuid, p.uuid, p.name
+                mock.ExpectQuery(regexp.QuoteMeta(`SELECT DISTINCT rp.role_uuid, p.uuid, p.name
         FROM permissions p
         INNER JOIN role_permissions rp ON rp.permission_uuid = p.uuid
         INNER JOIN user_roles ur ON ur.role_uuid = rp.role_uuid
-        WHERE ur.user_uuid = $1`)).
+        WHERE ur.user_uuid IN ($1)`)).
                     WithArgs("u1").
                     WillReturnRows(sqlmock.NewRows([]string{"role_uuid", "uuid", "name"}))
-                mock.ExpectExec(regexp.QuoteMeta("DELETE FROM users WHERE uuid = $1")).
-                    WithArgs("u1").
+                mock.ExpectBegin()
+                mock.ExpectExec(regexp.QuoteMeta("UPDATE users SET deleted_at = NOW(), tokens_valid_after = NOW(), version = version + 1 WHERE uuid = $1 AND version = $2 AND deleted_at IS NULL")).
+                    WithArgs("u1", int64(1)).
                     WillReturnResult(sqlmock.NewResult(1, 1))
+                expectOutbox(mock)
+                expectAudit(mock)
+                mock.ExpectCommit()
             },
             expectStatus: http.StatusNoContent,
         },
@@ -657,33 +1010,35 @@ func TestUserController_Delete(t *testing.T) {
             name: "InternalError_DeleteExec",
             uuid: "u1",
             setupDB: func(mock sqlmock.Sqlmock) {
-                userRow := sqlmock.NewRows([]string{"uuid", "name", "email", "password", "created_at", "updated_at"}).
-                    AddRow("u1", "Name", "email@example.com", "hash", time.Now(), time.Now())
-                mock.ExpectQuery(regexp.QuoteMeta(`SELECT uuid, name, email, password, created_at, updated_at FROM users WHERE uuid = $1 LIMIT 1`)).
+                userRow := sqlmock.NewRows([]string{"uuid", "name", "email", "password", "created_at", "updated_at", "status", "status_reason", "suspended_until", "phone", "version"}).
+                    AddRow("u1", "Name", "email@example.com", "hash", time.Now(), time.Now(), "active", "", nil, nil, 1)
+                mock.ExpectQuery(regexp.QuoteMeta(`SELECT uuid, name, email, password, created_at, updated_at, status, status_reason, suspended_until, phone, version FROM users WHERE uuid = $1 AND deleted_at IS NULL LIMIT 1`)).
                     WithArgs("u1").
                     WillReturnRows(userRow)
-                mock.ExpectQuery(regexp.QuoteMeta(`SELECT r.uuid, r.name
-        FROM roles r
-        INNER JOIN user_roles ur ON ur.role_uuid = r.uuid
-        WHERE ur.user_uuid = $1`)).
+                mock.ExpectQuery(regexp.QuoteMeta(`SELECT ur.user_uuid, r.uuid, r.name
+        FROM user_roles ur
+        INNER JOIN roles r ON r.uuid = ur.role_uuid
+        WHERE ur.user_uuid IN ($1)`)).
                     WithArgs("u1").
                     WillReturnRows(sqlmock.NewRows([]string{"uuid", "name"}))
-                mock.ExpectQuery(regexp.QuoteMeta(`SELECT p.uuid, p.name
-        FROM permissions p
-        INNER JOIN user_permissions up ON up.permission_uuid = p.uuid
-        WHERE up.user_uuid = $1`)).
+                mock.ExpectQuery(regexp.QuoteMeta(`SELECT up.user_uuid, p.uuid, p.name
+        FROM user_permissions up
+        INNER JOIN permissions p ON p.uuid = up.permission_uuid
+        WHERE up.user_uuid IN ($1)`)).
                     WithArgs("u1").
                     WillReturnRows(sqlmock.NewRows([]string{"uuid", "name"}))
-                mock.ExpectQuery(regexp.QuoteMeta(`SELECT rp.role_uuid, p.uuid, p.name
+                mock.ExpectQuery(regexp.QuoteMeta(`SELECT DISTINCT rp.role_uuid, p.uuid, p.name
         FROM permissions p
         INNER JOIN role_permissions rp ON rp.permission_uuid = p.uuid
         INNER JOIN user_roles ur ON ur.role_uuid = rp.role_uuid
-        WHERE ur.user_uuid = $1`)).
+        WHERE ur.user_uuid IN ($1)`)).
                     WithArgs("u1").
                     WillReturnRows(sqlmock.NewRows([]string{"role_uuid", "uuid", "name"}))
-                mock.ExpectExec(regexp.QuoteMeta("DELETE FROM users WHERE uuid = $1")).
-                    WithArgs("u1").
+                mock.ExpectBegin()
+                mock.ExpectExec(regexp.QuoteMeta("UPDATE users SET deleted_at = NOW(), tokens_valid_after = NOW(), version = version + 1 WHERE uuid = $1 AND version = $2 AND deleted_at IS NULL")).
+                    WithArgs("u1", int64(1)).
                     WillReturnError(fmt.Errorf("delete error"))
+                mock.ExpectRollback()
             },
             expectStatus: http.StatusInternalServerError,
         },
@@ -705,6 +1060,310 @@ func TestUserController_Delete(t *testing.T) {
                 path = "/users"
             }
             req := httptest.NewRequest(http.MethodDelete, path, nil)
+            req.Header.Set("If-Match", `"1"`)
+            resp, err := app.Test(req, -1)
+            require.NoError(t, err)
+            require.Equal(t, tc.expectStatus, resp.StatusCode)
+            if tc.assert != nil {
+                tc.assert(t, resp)
+            }
+            require.NoError(t, mock.ExpectationsWereMet())
+        })
+    }
+}
+
+// Table-driven tests for EffectivePermissions endpoint
+func TestUserController_EffectivePermissions(t *testing.T) {
+	type testcase struct {
+		name         string
+		query        string
+		setupDB      func(sqlmock.Sqlmock)
+		expectStatus int
+		assert       func(*testing.T, *http.Response)
+	}
+
+	expectUser := func(mock sqlmock.Sqlmock) {
+		mock.ExpectQuery(regexp.QuoteMeta(`FROM users WHERE uuid = $1`)).
+			WithArgs("user-123").
+			WillReturnRows(sqlmock.NewRows([]string{"uuid", "name", "email", "password", "created_at", "updated_at", "status", "status_reason", "suspended_until", "phone", "version"}).
+				AddRow("user-123", "Alice", "alice@example.com", "hash", time.Now(), time.Now(), "active", "", nil, nil, 1))
+		mock.ExpectQuery(regexp.QuoteMeta(`INNER JOIN roles r`)).
+			WithArgs("user-123").
+			WillReturnRows(sqlmock.NewRows([]string{"user_uuid", "uuid", "name"}).AddRow("user-123", "r1", "admin"))
+		mock.ExpectQuery(regexp.QuoteMeta(`FROM user_permissions up`)).
+			WithArgs("user-123").
+			WillReturnRows(sqlmock.NewRows([]string{"user_uuid", "uuid", "name"}).AddRow("user-123", "p9", "read-other"))
+		mock.ExpectQuery(regexp.QuoteMeta(`INNER JOIN role_permissions rp`)).
+			WithArgs("user-123").
+			WillReturnRows(sqlmock.NewRows([]string{"role_uuid", "uuid", "name"}).AddRow("r1", "p1", "read-role"))
+	}
+
+	cases := []testcase{
+		{
+			name:         "List",
+			setupDB:      expectUser,
+			expectStatus: http.StatusOK,
+			assert: func(t *testing.T, resp *http.Response) {
+				var out dto.WebResponse[*dto.EffectivePermissionsResponse]
+				require.NoError(t, json.NewDecoder(resp.Body).Decode(&out))
+				require.Len(t, out.Data.Permissions, 2)
+				require.Equal(t, "read-other", out.Data.Permissions[0].Name)
+				require.Equal(t, "direct", out.Data.Permissions[0].Sources[0].Type)
+				require.Equal(t, "admin", out.Data.Permissions[1].Sources[0].Role)
+			},
+		},
+		{
+			name:         "CheckAllowed",
+			query:        "?check=read-role",
+			setupDB:      expectUser,
+			expectStatus: http.StatusOK,
+			assert: func(t *testing.T, resp *http.Response) {
+				var out dto.WebResponse[*dto.PermissionCheckResponse]
+				require.NoError(t, json.NewDecoder(resp.Body).Decode(&out))
+				require.True(t, out.Data.Allowed)
+				require.Equal(t, `granted via role "admin"`, out.Data.Reason)
+			},
+		},
+		{
+			name:         "CheckDenied",
+			query:        "?check=delete-user",
+			setupDB:      expectUser,
+			expectStatus: http.StatusOK,
+			assert: func(t *testing.T, resp *http.Response) {
+				var out dto.WebResponse[*dto.PermissionCheckResponse]
+				require.NoError(t, json.NewDecoder(resp.Body).Decode(&out))
+				require.False(t, out.Data.Allowed)
+				require.Contains(t, out.Data.Reason, "(admin)")
+			},
+		},
+		{
+			name: "NotFound",
+			setupDB: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(`FROM users WHERE uuid = $1`)).
+					WithArgs("user-123").
+					WillReturnError(fmt.Errorf("no rows"))
+			},
+			expectStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl, app, mock, mr := setupUserController(t)
+			defer mr.Close()
+			app.Get("/users/:uuid/permissions/effective", ctrl.EffectivePermissions)
+
+			if tc.setupDB != nil {
+				tc.setupDB(mock)
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/users/user-123/permissions/effective"+tc.query, nil)
+			resp, err := app.Test(req, -1)
+			require.NoError(t, err)
+			require.Equal(t, tc.expectStatus, resp.StatusCode)
+			if tc.assert != nil {
+				tc.assert(t, resp)
+			}
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestUserController_ListDeleted(t *testing.T) {
+    ctrl, app, mock, mr := setupUserController(t)
+    defer mr.Close()
+    app.Get("/users/deleted", ctrl.ListDeleted)
+
+    deletedAt := time.Now()
+    mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM users WHERE deleted_at IS NOT NULL")).
+        WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+    mock.ExpectQuery(regexp.QuoteMeta("SELECT uuid, name, email, phone, status, status_reason, suspended_until, version, created_at, updated_at, deleted_at FROM users WHERE deleted_at IS NOT NULL ORDER BY created_at DESC, uuid ASC OFFSET $1 LIMIT $2")).
+        WithArgs(0, 10).
+        WillReturnRows(sqlmock.NewRows([]string{"uuid", "name", "email", "phone", "status", "status_reason", "suspended_until", "version", "created_at", "updated_at", "deleted_at"}).
+            AddRow("u1", "A", "a@example.com", nil, "active", "", nil, 1, time.Now(), time.Now(), deletedAt))
+
+    req := httptest.NewRequest(http.MethodGet, "/users/deleted", nil)
+    resp, err := app.Test(req, -1)
+    require.NoError(t, err)
+    require.Equal(t, http.StatusOK, resp.StatusCode)
+
+    var out dto.WebResponse[[]*dto.UserResponse]
+    require.NoError(t, json.NewDecoder(resp.Body).Decode(&out))
+    require.Len(t, out.Data, 1)
+    require.Equal(t, deletedAt.Unix(), out.Data[0].DeletedAt)
+    require.Equal(t, int64(1), *out.Paging.TotalItem)
+    require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUserController_Restore(t *testing.T) {
+    findDeleted := `SELECT uuid, name, email, password, version, created_at, updated_at, deleted_at FROM users WHERE uuid = $1 AND deleted_at IS NOT NULL LIMIT 1`
+
+    cases := []struct {
+        name         string
+        setupDB      func(sqlmock.Sqlmock)
+        expectStatus int
+    }{
+        {
+            name: "Success",
+            setupDB: func(mock sqlmock.Sqlmock) {
+                mock.ExpectBegin()
+                mock.ExpectQuery(regexp.QuoteMeta(findDeleted)).
+                    WithArgs("u1").
+                    WillReturnRows(sqlmock.NewRows([]string{"uuid", "name", "email", "password", "version", "created_at", "updated_at", "deleted_at"}).
+                        AddRow("u1", "Name", "email@example.com", "hash", 1, time.Now(), time.Now(), time.Now()))
+                mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM users WHERE lower(email) = lower($1) AND deleted_at IS NULL")).
+                    WithArgs("email@example.com").
+                    WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+                mock.ExpectExec(regexp.QuoteMeta("UPDATE users SET deleted_at = NULL")).
+                    WithArgs("u1").
+                    WillReturnResult(sqlmock.NewResult(0, 1))
+                expectOutbox(mock)
+                expectAudit(mock)
+                mock.ExpectCommit()
+            },
+            expectStatus: http.StatusOK,
+        },
+        {
+            name: "NotFound",
+            setupDB: func(mock sqlmock.Sqlmock) {
+                mock.ExpectBegin()
+                mock.ExpectQuery(regexp.QuoteMeta(findDeleted)).
+                    WithArgs("u1").
+                    WillReturnError(fmt.Errorf("no rows"))
+                mock.ExpectRollback()
+            },
+            expectStatus: http.StatusNotFound,
+        },
+    }
+
+    for _, tc := range cases {
+        t.Run(tc.name, func(t *testing.T) {
+            ctrl, app, mock, mr := setupUserController(t)
+            defer mr.Close()
+            app.Post("/users/:uuid/restore", ctrl.Restore)
+            tc.setupDB(mock)
+
+            req := httptest.NewRequest(http.MethodPost, "/users/u1/restore", nil)
+            resp, err := app.Test(req, -1)
+            require.NoError(t, err)
+            require.Equal(t, tc.expectStatus, resp.StatusCode)
+            require.NoError(t, mock.ExpectationsWereMet())
+        })
+    }
+}
+
+func TestUserController_SuspendReactivate(t *testing.T) {
+    updateStatus := regexp.QuoteMeta(`UPDATE users SET status = $1, status_reason = $2, suspended_until = $3, version = version + 1, updated_at = NOW() WHERE uuid = $4 AND version = $5 AND deleted_at IS NULL`)
+    expectUser := func(mock sqlmock.Sqlmock) {
+        mock.ExpectQuery(regexp.QuoteMeta(`FROM users WHERE uuid = $1`)).
+            WithArgs("u1").
+            WillReturnRows(sqlmock.NewRows([]string{"uuid", "name", "email", "password", "created_at", "updated_at", "status", "status_reason", "suspended_until", "phone", "version"}).
+                AddRow("u1", "Name", "email@example.com", "hash", time.Now(), time.Now(), "active", "", nil, nil, 1))
+        mock.ExpectQuery(regexp.QuoteMeta(`INNER JOIN roles r`)).WithArgs("u1").WillReturnRows(sqlmock.NewRows([]string{"uuid", "name"}))
+        mock.ExpectQuery(regexp.QuoteMeta(`FROM user_permissions up`)).WithArgs("u1").WillReturnRows(sqlmock.NewRows([]string{"uuid", "name"}))
+        mock.ExpectQuery(regexp.QuoteMeta(`INNER JOIN role_permissions rp`)).WithArgs("u1").WillReturnRows(sqlmock.NewRows([]string{"role_uuid", "uuid", "name"}))
+    }
+
+    cases := []struct {
+        name         string
+        path         string
+        body         string
+        setupDB      func(sqlmock.Sqlmock)
+        expectStatus int
+        assert       func(*testing.T, *http.Response)
+    }{
+        {
+            name: "Suspend",
+            path: "/users/u1/suspend",
+            body: `{"reason":"spam"}`,
+            setupDB: func(mock sqlmock.Sqlmock) {
+                expectUser(mock)
+                mock.ExpectBegin()
+                mock.ExpectExec(updateStatus).WithArgs("suspended", "spam", nil, "u1", int64(1)).WillReturnResult(sqlmock.NewResult(0, 1))
+                expectOutbox(mock)
+                expectAudit(mock)
+                mock.ExpectCommit()
+            },
+            expectStatus: http.StatusOK,
+            assert: func(t *testing.T, resp *http.Response) {
+                var out dto.WebResponse[*dto.UserResponse]
+                require.NoError(t, json.NewDecoder(resp.Body).Decode(&out))
+                require.Equal(t, "suspended", out.Data.Status)
+                require.Equal(t, "spam", out.Data.StatusReason)
+            },
+        },
+        {
+            name: "SuspendEmptyBody",
+            path: "/users/u1/suspend",
+            setupDB: func(mock sqlmock.Sqlmock) {
+                expectUser(mock)
+                mock.ExpectBegin()
+                mock.ExpectExec(updateStatus).WithArgs("suspended", "", nil, "u1", int64(1)).WillReturnResult(sqlmock.NewResult(0, 1))
+                expectOutbox(mock)
+                expectAudit(mock)
+                mock.ExpectCommit()
+            },
+            expectStatus: http.StatusOK,
+        },
+        {
+            name:         "SuspendSelf",
+            path:         "/users/admin/suspend",
+            setupDB:      func(sqlmock.Sqlmock) {},
+            expectStatus: http.StatusBadRequest,
+        },
+        {
+            name:         "SuspendInvalidUntil",
+            path:         "/users/u1/suspend",
+            body:         `{"until":1}`,
+            setupDB:      func(sqlmock.Sqlmock) {},
+            expectStatus: http.StatusBadRequest,
+        },
+        {
+            name:         "SuspendInvalidJSON",
+            path:         "/users/u1/suspend",
+            body:         `{invalid}`,
+            setupDB:      func(sqlmock.Sqlmock) {},
+            expectStatus: http.StatusBadRequest,
+        },
+        {
+            name: "Reactivate",
+            path: "/users/u1/reactivate",
+            setupDB: func(mock sqlmock.Sqlmock) {
+                expectUser(mock)
+                mock.ExpectBegin()
+                mock.ExpectExec(updateStatus).WithArgs("active", "", nil, "u1", int64(1)).WillReturnResult(sqlmock.NewResult(0, 1))
+                expectOutbox(mock)
+                expectAudit(mock)
+                mock.ExpectCommit()
+            },
+            expectStatus: http.StatusOK,
+        },
+        {
+            name: "ReactivateNotFound",
+            path: "/users/u1/reactivate",
+            setupDB: func(mock sqlmock.Sqlmock) {
+                mock.ExpectQuery(regexp.QuoteMeta(`FROM users WHERE uuid = $1`)).WithArgs("u1").WillReturnError(fmt.Errorf("no rows"))
+            },
+            expectStatus: http.StatusNotFound,
+        },
+    }
+
+    for _, tc := range cases {
+        t.Run(tc.name, func(t *testing.T) {
+            ctrl, app, mock, mr := setupUserController(t)
+            defer mr.Close()
+            app.Use(func(c *fiber.Ctx) error {
+                c.Locals("auth", &service.Claims{UUID: "admin"})
+                return c.Next()
+            })
+            app.Post("/users/:uuid/suspend", ctrl.Suspend)
+            app.Post("/users/:uuid/reactivate", ctrl.Reactivate)
+            tc.setupDB(mock)
+
+            req := httptest.NewRequest(http.MethodPost, tc.path, bytes.NewBufferString(tc.body))
+            if tc.body != "" {
+                req.Header.Set("Content-Type", "application/json")
+            }
             resp, err := app.Test(req, -1)
             require.NoError(t, err)
             require.Equal(t, tc.expectStatus, resp.StatusCode)
@@ -714,4 +1373,412 @@ func TestUserController_Delete(t *testing.T) {
             require.NoError(t, mock.ExpectationsWereMet())
         })
     }
-}
\ No newline at end of file
+}
+
+func TestUserController_SelfService(t *testing.T) {
+    expectUser := func(mock sqlmock.Sqlmock, password string) {
+        mock.ExpectQuery(regexp.QuoteMeta(`FROM users WHERE uuid = $1`)).
+            WithArgs("u1").
+            WillReturnRows(sqlmock.NewRows([]string{"uuid", "name", "email", "password", "created_at", "updated_at", "status", "status_reason", "suspended_until", "phone", "version"}).
+                AddRow("u1", "Name", "email@example.com", password, time.Now(), time.Now(), "active", "", nil, nil, 1))
+        mock.ExpectQuery(regexp.QuoteMeta(`INNER JOIN roles r`)).WithArgs("u1").WillReturnRows(sqlmock.NewRows([]string{"uuid", "name"}))
+        mock.ExpectQuery(regexp.QuoteMeta(`FROM user_permissions up`)).WithArgs("u1").WillReturnRows(sqlmock.NewRows([]string{"uuid", "name"}))
+        mock.ExpectQuery(regexp.QuoteMeta(`INNER JOIN role_permissions rp`)).WithArgs("u1").WillReturnRows(sqlmock.NewRows([]string{"role_uuid", "uuid", "name"}))
+    }
+    hashed, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+    require.NoError(t, err)
+
+    cases := []struct {
+        name         string
+        method       string
+        path         string
+        body         string
+        setup        func(sqlmock.Sqlmock, *miniredis.Miniredis)
+        expectStatus int
+        assert       func(*testing.T, *http.Response)
+    }{
+        {
+            name:   "UpdateMeName",
+            method: http.MethodPatch,
+            path:   "/users/me",
+            body:   `{"name":"New Name"}`,
+            setup: func(mock sqlmock.Sqlmock, _ *miniredis.Miniredis) {
+                expectUser(mock, "hash")
+                mock.ExpectBegin()
+                mock.ExpectExec(regexp.QuoteMeta(`UPDATE users SET name = $1, email = $2, version = version + 1, updated_at = NOW() WHERE uuid = $3 AND version = $4`)).
+                    WithArgs("New Name", "email@example.com", "u1", 1).
+                    WillReturnResult(sqlmock.NewResult(0, 1))
+                expectOutbox(mock)
+                expectAudit(mock)
+                mock.ExpectCommit()
+            },
+            expectStatus: http.StatusOK,
+            assert: func(t *testing.T, resp *http.Response) {
+                var out dto.WebResponse[*dto.UserResponse]
+                require.NoError(t, json.NewDecoder(resp.Body).Decode(&out))
+                require.Equal(t, "New Name", out.Data.Name)
+            },
+        },
+        {
+            name:   "UpdateMeEmailPending",
+            method: http.MethodPatch,
+            path:   "/users/me",
+            body:   `{"email":"new@example.com"}`,
+            setup: func(mock sqlmock.Sqlmock, _ *miniredis.Miniredis) {
+                expectUser(mock, "hash")
+                mock.ExpectQuery(regexp.QuoteMeta(`SELECT COUNT(*) FROM users WHERE lower(email) = lower($1) AND deleted_at IS NULL`)).
+                    WithArgs("new@example.com").
+                    WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+            },
+            expectStatus: http.StatusOK,
+            assert: func(t *testing.T, resp *http.Response) {
+                var out dto.WebResponse[*dto.UserResponse]
+                require.NoError(t, json.NewDecoder(resp.Body).Decode(&out))
+                require.Equal(t, "email@example.com", out.Data.Email)
+                require.Equal(t, "new@example.com", out.Data.PendingEmail)
+            },
+        },
+        {
+            name:         "UpdateMeInvalidEmail",
+            method:       http.MethodPatch,
+            path:         "/users/me",
+            body:         `{"email":"not-an-email"}`,
+            setup:        func(sqlmock.Sqlmock, *miniredis.Miniredis) {},
+            expectStatus: http.StatusBadRequest,
+        },
+        {
+            name:   "VerifyEmail",
+            method: http.MethodPost,
+            path:   "/users/me/email/verify",
+            body:   `{"token":"tok"}`,
+            setup: func(mock sqlmock.Sqlmock, mr *miniredis.Miniredis) {
+                mr.Set("user:email-verification:tok", `{"uuid":"u1","email":"new@example.com"}`)
+                mock.ExpectBegin()
+                expectUser(mock, "hash")
+                mock.ExpectQuery(regexp.QuoteMeta(`SELECT COUNT(*) FROM users WHERE lower(email) = lower($1) AND deleted_at IS NULL`)).
+                    WithArgs("new@example.com").
+                    WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+                mock.ExpectExec(regexp.QuoteMeta(`UPDATE users SET name = $1, email = $2, version = version + 1, updated_at = NOW() WHERE uuid = $3 AND version = $4`)).
+                    WithArgs("Name", "new@example.com", "u1", 1).
+                    WillReturnResult(sqlmock.NewResult(0, 1))
+                expectOutbox(mock)
+                expectAudit(mock)
+                mock.ExpectCommit()
+            },
+            expectStatus: http.StatusOK,
+            assert: func(t *testing.T, resp *http.Response) {
+                var out dto.WebResponse[*dto.UserResponse]
+                require.NoError(t, json.NewDecoder(resp.Body).Decode(&out))
+                require.Equal(t, "new@example.com", out.Data.Email)
+            },
+        },
+        {
+            name:         "VerifyEmailUnknownToken",
+            method:       http.MethodPost,
+            path:         "/users/me/email/verify",
+            body:         `{"token":"nope"}`,
+            setup:        func(sqlmock.Sqlmock, *miniredis.Miniredis) {},
+            expectStatus: http.StatusBadRequest,
+        },
+        {
+            name:   "DeleteMe",
+            method: http.MethodDelete,
+            path:   "/users/me",
+            body:   `{"password":"secret"}`,
+            setup: func(mock sqlmock.Sqlmock, _ *miniredis.Miniredis) {
+                expectUser(mock, string(hashed))
+                mock.ExpectBegin()
+                mock.ExpectExec(regexp.QuoteMeta("UPDATE users SET deleted_at = NOW(), tokens_valid_after = NOW(), version = version + 1 WHERE uuid = $1 AND version = $2 AND deleted_at IS NULL")).
+                    WithArgs("u1", int64(1)).
+                    WillReturnResult(sqlmock.NewResult(0, 1))
+                expectOutbox(mock)
+                expectAudit(mock)
+                mock.ExpectCommit()
+            },
+            expectStatus: http.StatusNoContent,
+            assert: func(t *testing.T, resp *http.Response) {
+                require.Contains(t, resp.Header.Get("Set-Cookie"), "refresh_token=")
+            },
+        },
+        {
+            name:   "DeleteMeWrongPassword",
+            method: http.MethodDelete,
+            path:   "/users/me",
+            body:   `{"password":"wrong"}`,
+            setup: func(mock sqlmock.Sqlmock, _ *miniredis.Miniredis) {
+                expectUser(mock, string(hashed))
+            },
+            expectStatus: http.StatusForbidden,
+        },
+        {
+            name:         "DeleteMeMissingPassword",
+            method:       http.MethodDelete,
+            path:         "/users/me",
+            body:         `{}`,
+            setup:        func(sqlmock.Sqlmock, *miniredis.Miniredis) {},
+            expectStatus: http.StatusBadRequest,
+        },
+    }
+
+    for _, tc := range cases {
+        t.Run(tc.name, func(t *testing.T) {
+            ctrl, app, mock, mr := setupUserController(t)
+            defer mr.Close()
+            app.Use(func(c *fiber.Ctx) error {
+                c.Locals("auth", &service.Claims{UUID: "u1"})
+                return c.Next()
+            })
+            app.Patch("/users/me", ctrl.UpdateMe)
+            app.Delete("/users/me", ctrl.DeleteMe)
+            app.Post("/users/me/email/verify", ctrl.VerifyEmail)
+            tc.setup(mock, mr)
+
+            req := httptest.NewRequest(tc.method, tc.path, bytes.NewBufferString(tc.body))
+            req.Header.Set("Content-Type", "application/json")
+            resp, err := app.Test(req, -1)
+            require.NoError(t, err)
+            require.Equal(t, tc.expectStatus, resp.StatusCode)
+            if tc.assert != nil {
+                tc.assert(t, resp)
+            }
+            require.NoError(t, mock.ExpectationsWereMet())
+        })
+    }
+}
+
+func TestUserController_Patch(t *testing.T) {
+    expectUser := func(mock sqlmock.Sqlmock) {
+        phone := "+14155550100"
+        mock.ExpectQuery(regexp.QuoteMeta(`FROM users WHERE uuid = $1`)).
+            WithArgs("u1").
+            WillReturnRows(sqlmock.NewRows([]string{"uuid", "name", "email", "password", "created_at", "updated_at", "status", "status_reason", "suspended_until", "phone", "version"}).
+                AddRow("u1", "Name", "email@example.com", "hash", time.Now(), time.Now(), "active", "", nil, phone, 1))
+        mock.ExpectQuery(regexp.QuoteMeta(`INNER JOIN roles r`)).WithArgs("u1").WillReturnRows(sqlmock.NewRows([]string{"uuid", "name"}))
+        mock.ExpectQuery(regexp.QuoteMeta(`FROM user_permissions up`)).WithArgs("u1").WillReturnRows(sqlmock.NewRows([]string{"uuid", "name"}))
+        mock.ExpectQuery(regexp.QuoteMeta(`INNER JOIN role_permissions rp`)).WithArgs("u1").WillReturnRows(sqlmock.NewRows([]string{"role_uuid", "uuid", "name"}))
+    }
+
+    cases := []struct {
+        name         string
+        contentType  string
+        body         string
+        setupDB      func(sqlmock.Sqlmock)
+        expectStatus int
+        assert       func(*testing.T, *http.Response)
+    }{
+        {
+            name:        "MergePatchName",
+            contentType: "application/merge-patch+json",
+            body:        `{"name":"New Name"}`,
+            setupDB: func(mock sqlmock.Sqlmock) {
+                expectUser(mock)
+                mock.ExpectBegin()
+                mock.ExpectExec(regexp.QuoteMeta(`UPDATE users SET name = $1, version = version + 1, updated_at = NOW() WHERE uuid = $2 AND version = $3`)).
+                    WithArgs("New Name", "u1", 1).
+                    WillReturnResult(sqlmock.NewResult(0, 1))
+                expectOutbox(mock)
+                expectAudit(mock)
+                mock.ExpectCommit()
+            },
+            expectStatus: http.StatusOK,
+            assert: func(t *testing.T, resp *http.Response) {
+                var out dto.WebResponse[*dto.UserResponse]
+                require.NoError(t, json.NewDecoder(resp.Body).Decode(&out))
+                require.Equal(t, "New Name", out.Data.Name)
+                require.Equal(t, "email@example.com", out.Data.Email)
+                require.Equal(t, "+14155550100", out.Data.Phone)
+            },
+        },
+        {
+            name:        "NullClearsPhone",
+            contentType: "application/json",
+            body:        `{"phone":null}`,
+            setupDB: func(mock sqlmock.Sqlmock) {
+                expectUser(mock)
+                mock.ExpectBegin()
+                mock.ExpectExec(regexp.QuoteMeta(`UPDATE users SET phone = $1, version = version + 1, updated_at = NOW() WHERE uuid = $2 AND version = $3`)).
+                    WithArgs(nil, "u1", 1).
+                    WillReturnResult(sqlmock.NewResult(0, 1))
+                expectOutbox(mock)
+                expectAudit(mock)
+                mock.ExpectCommit()
+            },
+            expectStatus: http.StatusOK,
+            assert: func(t *testing.T, resp *http.Response) {
+                var out dto.WebResponse[*dto.UserResponse]
+                require.NoError(t, json.NewDecoder(resp.Body).Decode(&out))
+                require.Empty(t, out.Data.Phone)
+            },
+        },
+        {
+            name:         "NullNameRejected",
+            contentType:  "application/merge-patch+json",
+            body:         `{"name":null}`,
+            setupDB:      func(sqlmock.Sqlmock) {},
+            expectStatus: http.StatusBadRequest,
+        },
+        {
+            name:         "InvalidProvidedField",
+            contentType:  "application/merge-patch+json",
+            body:         `{"phone":"12"}`,
+            setupDB:      func(sqlmock.Sqlmock) {},
+            expectStatus: http.StatusBadRequest,
+        },
+        {
+            name:         "NotAnObject",
+            contentType:  "application/merge-patch+json",
+            body:         `["name"]`,
+            setupDB:      func(sqlmock.Sqlmock) {},
+            expectStatus: http.StatusBadRequest,
+        },
+        {
+            name:        "NotFound",
+            contentType: "application/merge-patch+json",
+            body:        `{}`,
+            setupDB: func(mock sqlmock.Sqlmock) {
+                mock.ExpectQuery(regexp.QuoteMeta(`FROM users WHERE uuid = $1`)).WithArgs("u1").WillReturnError(fmt.Errorf("no rows"))
+            },
+            expectStatus: http.StatusNotFound,
+        },
+    }
+
+    for _, tc := range cases {
+        t.Run(tc.name, func(t *testing.T) {
+            ctrl, app, mock, mr := setupUserController(t)
+            defer mr.Close()
+            app.Patch("/users/:uuid", ctrl.Patch)
+            tc.setupDB(mock)
+
+            req := httptest.NewRequest(http.MethodPatch, "/users/u1", bytes.NewBufferString(tc.body))
+            req.Header.Set("Content-Type", tc.contentType)
+            req.Header.Set("If-Match", `"1"`)
+            resp, err := app.Test(req, -1)
+            require.NoError(t, err)
+            require.Equal(t, tc.expectStatus, resp.StatusCode)
+            if tc.assert != nil {
+                tc.assert(t, resp)
+            }
+            require.NoError(t, mock.ExpectationsWereMet())
+        })
+    }
+}
+
+func TestUserController_ConditionalRequests(t *testing.T) {
+    expectUser := func(mock sqlmock.Sqlmock) {
+        mock.ExpectQuery(regexp.QuoteMeta(`FROM users WHERE uuid = $1`)).
+            WithArgs("u1").
+            WillReturnRows(sqlmock.NewRows([]string{"uuid", "name", "email", "password", "created_at", "updated_at", "status", "status_reason", "suspended_until", "phone", "version"}).
+                AddRow("u1", "Name", "email@example.com", "hash", time.Now(), time.Now(), "active", "", nil, nil, 3))
+        mock.ExpectQuery(regexp.QuoteMeta(`INNER JOIN roles r`)).WithArgs("u1").WillReturnRows(sqlmock.NewRows([]string{"uuid", "name"}))
+        mock.ExpectQuery(regexp.QuoteMeta(`FROM user_permissions up`)).WithArgs("u1").WillReturnRows(sqlmock.NewRows([]string{"uuid", "name"}))
+        mock.ExpectQuery(regexp.QuoteMeta(`INNER JOIN role_permissions rp`)).WithArgs("u1").WillReturnRows(sqlmock.NewRows([]string{"role_uuid", "uuid", "name"}))
+    }
+    updateQuery := regexp.QuoteMeta(`UPDATE users SET name = $1, email = $2, version = version + 1, updated_at = NOW() WHERE uuid = $3 AND version = $4`)
+    body := `{"name":"New Name","email":"email@example.com"}`
+
+    cases := []struct {
+        name         string
+        method       string
+        ifMatch      string
+        setupDB      func(sqlmock.Sqlmock)
+        expectStatus int
+        expectETag   string
+    }{
+        {
+            name:         "ShowReturnsETag",
+            method:       http.MethodGet,
+            setupDB:      expectUser,
+            expectStatus: http.StatusOK,
+            expectETag:   `"3"`,
+        },
+        {
+            name:   "PutMatchingETag",
+            method: http.MethodPut,
+            ifMatch: `"3"`,
+            setupDB: func(mock sqlmock.Sqlmock) {
+                expectUser(mock)
+                mock.ExpectBegin()
+                mock.ExpectExec(updateQuery).WithArgs("New Name", "email@example.com", "u1", 3).WillReturnResult(sqlmock.NewResult(0, 1))
+                expectOutbox(mock)
+                expectAudit(mock)
+                mock.ExpectCommit()
+            },
+            expectStatus: http.StatusOK,
+            expectETag:   `"4"`,
+        },
+        {
+            name:    "PutWildcard",
+            method:  http.MethodPut,
+            ifMatch: "*",
+            setupDB: func(mock sqlmock.Sqlmock) {
+                expectUser(mock)
+                mock.ExpectBegin()
+                mock.ExpectExec(updateQuery).WithArgs("New Name", "email@example.com", "u1", 3).WillReturnResult(sqlmock.NewResult(0, 1))
+                expectOutbox(mock)
+                expectAudit(mock)
+                mock.ExpectCommit()
+            },
+            expectStatus: http.StatusOK,
+            expectETag:   `"4"`,
+        },
+        {
+            name:         "PutWithoutIfMatch",
+            method:       http.MethodPut,
+            setupDB:      func(sqlmock.Sqlmock) {},
+            expectStatus: http.StatusPreconditionRequired,
+        },
+        {
+            name:         "PutStaleETag",
+            method:       http.MethodPut,
+            ifMatch:      `"2"`,
+            setupDB:      expectUser,
+            expectStatus: http.StatusPreconditionFailed,
+        },
+        {
+            name:         "PutWeakETag",
+            method:       http.MethodPut,
+            ifMatch:      `W/"3"`,
+            setupDB:      func(sqlmock.Sqlmock) {},
+            expectStatus: http.StatusPreconditionFailed,
+        },
+        {
+            name:         "DeleteWithoutIfMatch",
+            method:       http.MethodDelete,
+            setupDB:      func(sqlmock.Sqlmock) {},
+            expectStatus: http.StatusPreconditionRequired,
+        },
+        {
+            name:         "DeleteStaleETag",
+            method:       http.MethodDelete,
+            ifMatch:      `"1"`,
+            setupDB:      expectUser,
+            expectStatus: http.StatusPreconditionFailed,
+        },
+    }
+
+    for _, tc := range cases {
+        t.Run(tc.name, func(t *testing.T) {
+            ctrl, app, mock, mr := setupUserController(t)
+            defer mr.Close()
+            app.Get("/users/:uuid", ctrl.Show)
+            app.Put("/users/:uuid", ctrl.Update)
+            app.Delete("/users/:uuid", ctrl.Delete)
+            tc.setupDB(mock)
+
+            var reqBody io.Reader
+            if tc.method == http.MethodPut {
+                reqBody = bytes.NewBufferString(body)
+            }
+            req := httptest.NewRequest(tc.method, "/users/u1", reqBody)
+            req.Header.Set("Content-Type", "application/json")
+            if tc.ifMatch != "" {
+                req.Header.Set("If-Match", tc.ifMatch)
+            }
+            resp, err := app.Test(req, -1)
+            require.NoError(t, err)
+            require.Equal(t, tc.expectStatus, resp.StatusCode)
+            require.Equal(t, tc.expectETag, resp.Header.Get("ETag"))
+            require.NoError(t, mock.ExpectationsWereMet())
+        })
+    }
+}
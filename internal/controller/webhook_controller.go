@@ -0,0 +1,171 @@
+package controller
+
+import (
+	"go-starter-template/internal/config/validation"
+	"go-starter-template/internal/dto"
+	"go-starter-template/internal/middleware"
+	"go-starter-template/internal/service"
+	"go-starter-template/internal/utils/errcode"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type WebhookController struct {
+	webhookService *service.WebhookService
+	logger         *logrus.Logger
+	validation     *validation.Validation
+	tracer         trace.Tracer
+}
+
+func NewWebhookController(webhookService *service.WebhookService, logger *logrus.Logger, validator *validation.Validation) *WebhookController {
+	return &WebhookController{webhookService, logger, validator, otel.Tracer("WebhookController")}
+}
+
+// Create subscribes an endpoint to events on behalf of the caller, who owns
+// the subscription. The response holds the signing secret, the only time it
+// is shown.
+func (c *WebhookController) Create(ctx *fiber.Ctx) error {
+	spanCtx, span := c.tracer.Start(ctx.UserContext(), "WebhookController.Create")
+	defer span.End()
+
+	logger := c.logger.WithContext(spanCtx)
+
+	req := new(dto.CreateWebhookRequest)
+	if err := c.validation.ParseAndValidate(ctx, req); err != nil {
+		logger.WithError(err).Error("failed to parse and validate webhook request")
+		return err
+	}
+
+	webhook, err := c.webhookService.CreateSubscription(spanCtx, middleware.GetUser(ctx).UUID, req)
+	if err != nil {
+		logger.WithError(err).Error("failed to create webhook")
+		return err
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(dto.WebResponse[*dto.WebhookResponse]{Data: webhook})
+}
+
+// List returns the caller's webhooks.
+func (c *WebhookController) List(ctx *fiber.Ctx) error {
+	spanCtx, span := c.tracer.Start(ctx.UserContext(), "WebhookController.List")
+	defer span.End()
+
+	webhooks, err := c.webhookService.ListSubscriptions(spanCtx, middleware.GetUser(ctx).UUID)
+	if err != nil {
+		c.logger.WithContext(spanCtx).WithError(err).Error("failed to list webhooks")
+		return err
+	}
+
+	return ctx.JSON(dto.WebResponse[[]*dto.WebhookResponse]{Data: webhooks})
+}
+
+func (c *WebhookController) Show(ctx *fiber.Ctx) error {
+	spanCtx, span := c.tracer.Start(ctx.UserContext(), "WebhookController.Show")
+	defer span.End()
+
+	uuid := ctx.Params("uuid")
+	if uuid == "" {
+		return errcode.ErrBadRequest
+	}
+
+	webhook, err := c.webhookService.GetSubscription(spanCtx, middleware.GetUser(ctx).UUID, uuid)
+	if err != nil {
+		c.logger.WithContext(spanCtx).WithError(err).Error("failed to get webhook")
+		return err
+	}
+
+	return ctx.JSON(dto.WebResponse[*dto.WebhookResponse]{Data: webhook})
+}
+
+func (c *WebhookController) Update(ctx *fiber.Ctx) error {
+	spanCtx, span := c.tracer.Start(ctx.UserContext(), "WebhookController.Update")
+	defer span.End()
+
+	logger := c.logger.WithContext(spanCtx)
+
+	uuid := ctx.Params("uuid")
+	if uuid == "" {
+		return errcode.ErrBadRequest
+	}
+
+	req := new(dto.UpdateWebhookRequest)
+	if err := c.validation.ParseAndValidate(ctx, req); err != nil {
+		logger.WithError(err).Error("failed to parse and validate webhook request")
+		return err
+	}
+
+	webhook, err := c.webhookService.UpdateSubscription(spanCtx, middleware.GetUser(ctx).UUID, uuid, req)
+	if err != nil {
+		logger.WithError(err).Error("failed to update webhook")
+		return err
+	}
+
+	return ctx.JSON(dto.WebResponse[*dto.WebhookResponse]{Data: webhook})
+}
+
+func (c *WebhookController) Delete(ctx *fiber.Ctx) error {
+	spanCtx, span := c.tracer.Start(ctx.UserContext(), "WebhookController.Delete")
+	defer span.End()
+
+	uuid := ctx.Params("uuid")
+	if uuid == "" {
+		return errcode.ErrBadRequest
+	}
+
+	if err := c.webhookService.DeleteSubscription(spanCtx, middleware.GetUser(ctx).UUID, uuid); err != nil {
+		c.logger.WithContext(spanCtx).WithError(err).Error("failed to delete webhook")
+		return err
+	}
+
+	return ctx.SendStatus(fiber.StatusNoContent)
+}
+
+// Deliveries returns the delivery log of a webhook, newest first.
+func (c *WebhookController) Deliveries(ctx *fiber.Ctx) error {
+	spanCtx, span := c.tracer.Start(ctx.UserContext(), "WebhookController.Deliveries")
+	defer span.End()
+
+	logger := c.logger.WithContext(spanCtx)
+
+	uuid := ctx.Params("uuid")
+	if uuid == "" {
+		return errcode.ErrBadRequest
+	}
+
+	req := new(dto.ListWebhookDeliveriesRequest)
+	if err := ctx.QueryParser(req); err != nil {
+		logger.WithError(err).Error("failed to parse request query")
+		return errcode.ErrBadRequest
+	}
+	req.SetDefault()
+
+	deliveries, err := c.webhookService.ListDeliveries(spanCtx, middleware.GetUser(ctx).UUID, uuid, req)
+	if err != nil {
+		logger.WithError(err).Error("failed to list webhook deliveries")
+		return err
+	}
+
+	return ctx.JSON(dto.WebResponse[[]*dto.WebhookDeliveryResponse]{Data: deliveries})
+}
+
+// Replay sends a logged delivery again.
+func (c *WebhookController) Replay(ctx *fiber.Ctx) error {
+	spanCtx, span := c.tracer.Start(ctx.UserContext(), "WebhookController.Replay")
+	defer span.End()
+
+	uuid, deliveryUUID := ctx.Params("uuid"), ctx.Params("delivery")
+	if uuid == "" || deliveryUUID == "" {
+		return errcode.ErrBadRequest
+	}
+
+	delivery, err := c.webhookService.ReplayDelivery(spanCtx, middleware.GetUser(ctx).UUID, uuid, deliveryUUID)
+	if err != nil {
+		c.logger.WithContext(spanCtx).WithError(err).Error("failed to replay webhook delivery")
+		return err
+	}
+
+	return ctx.Status(fiber.StatusAccepted).JSON(dto.WebResponse[*dto.WebhookDeliveryResponse]{Data: delivery})
+}
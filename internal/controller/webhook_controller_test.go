@@ -0,0 +1,212 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	miniredis "github.com/alicebob/miniredis/v2"
+	"github.com/gofiber/fiber/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+
+	"go-starter-template/internal/config/env"
+	"go-starter-template/internal/config/validation"
+	"go-starter-template/internal/dto"
+	"go-starter-template/internal/repository"
+	"go-starter-template/internal/service"
+	"go-starter-template/internal/utils/errcode"
+)
+
+var webhookColumns = []string{"uuid", "url", "description", "events", "secret", "enabled", "consecutive_failures", "disabled_reason", "created_at", "updated_at", "owner_uuid"}
+
+// setupWebhookController constructs a real WebhookController wired with sqlmock and miniredis
+func setupWebhookController(t *testing.T) (*fiber.App, sqlmock.Sqlmock) {
+	t.Helper()
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+	mr := miniredis.RunT(t)
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	// Skip resolving the example hosts
+	cfg := &env.Config{}
+	cfg.Webhooks.AllowPrivateNetworks = true
+	queue := repository.NewWebhookQueue(redis.NewClient(&redis.Options{Addr: mr.Addr()}))
+	webhookSvc := service.NewWebhookService(repository.NewWebhookRepository(db), queue, logger, cfg, repository.NewUnitOfWork(db))
+	ctrl := NewWebhookController(webhookSvc, logger, validation.NewValidation())
+
+	app := fiber.New(fiber.Config{ErrorHandler: func(c *fiber.Ctx, err error) error {
+		if _, ok := err.(*validation.ValidationError); ok {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		if code, ok := errcode.GetHTTPStatus(err); ok {
+			return c.Status(code).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}})
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("auth", &service.Claims{UUID: "u1"})
+		return c.Next()
+	})
+	app.Post("/webhooks", ctrl.Create)
+	app.Get("/webhooks/:uuid", ctrl.Show)
+	app.Get("/webhooks/:uuid/deliveries", ctrl.Deliveries)
+	app.Post("/webhooks/:uuid/deliveries/:delivery/replay", ctrl.Replay)
+	return app, mock
+}
+
+func TestWebhookController(t *testing.T) {
+	type testcase struct {
+		name         string
+		method       string
+		path         string
+		body         string
+		setupDB      func(sqlmock.Sqlmock)
+		expectStatus int
+		assert       func(*testing.T, *http.Response)
+	}
+
+	now := time.Now()
+	expectWebhook := func(mock sqlmock.Sqlmock, owner string, enabled bool) {
+		mock.ExpectQuery(regexp.QuoteMeta(`FROM webhook_subscriptions WHERE uuid = $1`)).
+			WithArgs("w1").
+			WillReturnRows(sqlmock.NewRows(webhookColumns).
+				AddRow("w1", "https://a.example.com", "", []byte(`["*"]`), "whsec_1", enabled, 0, "", now, now, owner))
+	}
+
+	cases := []testcase{
+		{
+			name:         "CreateValidationError",
+			method:       http.MethodPost,
+			path:         "/webhooks",
+			body:         `{"url":"not a url","events":["*"]}`,
+			expectStatus: http.StatusBadRequest,
+		},
+		{
+			name:         "CreateUnknownEvent",
+			method:       http.MethodPost,
+			path:         "/webhooks",
+			body:         `{"url":"https://a.example.com","events":["user.exploded"]}`,
+			expectStatus: http.StatusBadRequest,
+		},
+		{
+			name:   "CreateReturnsSecret",
+			method: http.MethodPost,
+			path:   "/webhooks",
+			body:   `{"url":"https://a.example.com","events":["user.created"]}`,
+			setupDB: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO webhook_subscriptions`)).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+			},
+			expectStatus: http.StatusCreated,
+			assert: func(t *testing.T, resp *http.Response) {
+				var out dto.WebResponse[*dto.WebhookResponse]
+				require.NoError(t, json.NewDecoder(resp.Body).Decode(&out))
+				require.True(t, strings.HasPrefix(out.Data.Secret, "whsec_"))
+				require.Equal(t, []string{"user.created"}, out.Data.Events)
+			},
+		},
+		{
+			name:   "ShowHidesSecret",
+			method: http.MethodGet,
+			path:   "/webhooks/w1",
+			setupDB: func(mock sqlmock.Sqlmock) {
+				expectWebhook(mock, "u1", true)
+			},
+			expectStatus: http.StatusOK,
+			assert: func(t *testing.T, resp *http.Response) {
+				body, err := io.ReadAll(resp.Body)
+				require.NoError(t, err)
+				require.NotContains(t, string(body), "whsec_1")
+			},
+		},
+		{
+			name:   "ShowNotFound",
+			method: http.MethodGet,
+			path:   "/webhooks/w1",
+			setupDB: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(`FROM webhook_subscriptions WHERE uuid = $1`)).
+					WillReturnRows(sqlmock.NewRows(webhookColumns))
+			},
+			expectStatus: http.StatusNotFound,
+		},
+		{
+			name:   "ShowOtherUsersWebhook",
+			method: http.MethodGet,
+			path:   "/webhooks/w1",
+			setupDB: func(mock sqlmock.Sqlmock) {
+				expectWebhook(mock, "u2", true)
+			},
+			expectStatus: http.StatusNotFound,
+		},
+		{
+			name:   "DeliveriesOfOtherUsersWebhook",
+			method: http.MethodGet,
+			path:   "/webhooks/w1/deliveries",
+			setupDB: func(mock sqlmock.Sqlmock) {
+				expectWebhook(mock, "u2", true)
+			},
+			expectStatus: http.StatusNotFound,
+		},
+		{
+			name:   "DeliveriesFilteredByStatus",
+			method: http.MethodGet,
+			path:   "/webhooks/w1/deliveries?status=failed&limit=500",
+			setupDB: func(mock sqlmock.Sqlmock) {
+				expectWebhook(mock, "u1", true)
+				mock.ExpectQuery(regexp.QuoteMeta(`FROM webhook_deliveries`)).
+					WithArgs("w1", "failed", 200).
+					WillReturnRows(sqlmock.NewRows([]string{"uuid", "subscription_uuid", "event_id", "event_type", "payload", "status", "attempts", "response_status", "last_error", "next_attempt_at", "created_at", "delivered_at"}).
+						AddRow("d1", "w1", "e1", "user.created", []byte(`{"id":"e1"}`), "failed", 8, 500, "endpoint responded 500 Internal Server Error", nil, now, nil))
+			},
+			expectStatus: http.StatusOK,
+			assert: func(t *testing.T, resp *http.Response) {
+				var out dto.WebResponse[[]*dto.WebhookDeliveryResponse]
+				require.NoError(t, json.NewDecoder(resp.Body).Decode(&out))
+				require.Len(t, out.Data, 1)
+				require.Equal(t, 8, out.Data[0].Attempts)
+				require.JSONEq(t, `{"id":"e1"}`, string(out.Data[0].Payload))
+			},
+		},
+		{
+			name:   "ReplayDisabledWebhook",
+			method: http.MethodPost,
+			path:   "/webhooks/w1/deliveries/d1/replay",
+			setupDB: func(mock sqlmock.Sqlmock) {
+				expectWebhook(mock, "u1", false)
+			},
+			expectStatus: http.StatusConflict,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			app, mock := setupWebhookController(t)
+			if tc.setupDB != nil {
+				tc.setupDB(mock)
+			}
+
+			req := httptest.NewRequest(tc.method, tc.path, bytes.NewBufferString(tc.body))
+			req.Header.Set("Content-Type", "application/json")
+			resp, err := app.Test(req, -1)
+			require.NoError(t, err)
+			require.Equal(t, tc.expectStatus, resp.StatusCode)
+			if tc.assert != nil {
+				tc.assert(t, resp)
+			}
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,24 @@
+package dto
+
+// ListAuditEventsRequest filters the audit log. Times are inclusive unix
+// timestamps and 0 leaves a bound open; Cursor is the paging.next value of a
+// previous response.
+type ListAuditEventsRequest struct {
+	Actor      string `json:"actor" query:"actor"`
+	TargetType string `json:"target_type" query:"target_type"`
+	Target     string `json:"target" query:"target"`
+	Action     string `json:"action" query:"action"`
+	From       int64  `json:"from" query:"from"`
+	To         int64  `json:"to" query:"to"`
+	Cursor     string `json:"cursor" query:"cursor"`
+	Limit      int    `json:"limit" query:"limit"`
+}
+
+func (r *ListAuditEventsRequest) SetDefault() {
+	if r.Limit <= 0 {
+		r.Limit = 50
+	}
+	if r.Limit > 200 {
+		r.Limit = 200
+	}
+}
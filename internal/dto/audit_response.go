@@ -0,0 +1,28 @@
+package dto
+
+import "github.com/goccy/go-json"
+
+type AuditEventResponse struct {
+	ID         int64  `json:"id"`
+	UUID       string `json:"uuid"`
+	ActorUUID  string `json:"actor_uuid,omitempty"`
+	Action     string `json:"action"`
+	TargetType string `json:"target_type,omitempty"`
+	TargetID   string `json:"target_id,omitempty"`
+	IP         string `json:"ip,omitempty"`
+	UserAgent  string `json:"user_agent,omitempty"`
+	TraceID    string `json:"trace_id,omitempty"`
+	// Changes maps each changed field to its before and after value
+	Changes   json.RawMessage `json:"changes"`
+	Metadata  json.RawMessage `json:"metadata"`
+	CreatedAt int64           `json:"created_at"`
+	PrevHash  string          `json:"prev_hash"`
+	Hash      string          `json:"hash"`
+}
+
+type AuditVerificationResponse struct {
+	Valid   bool  `json:"valid"`
+	Checked int64 `json:"checked"`
+	// BrokenAt is the id of the first event failing verification
+	BrokenAt int64 `json:"broken_at,omitempty"`
+}
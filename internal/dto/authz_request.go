@@ -0,0 +1,24 @@
+package dto
+
+// AuthzCheckRequest describes an authorization question evaluated by the policy engine.
+type AuthzCheckRequest struct {
+	Action   string                 `json:"action" validate:"required,max=100"`
+	Subject  AuthzSubject           `json:"subject"`
+	Resource AuthzResource          `json:"resource"`
+	Context  map[string]interface{} `json:"context,omitempty"`
+}
+
+// AuthzSubject identifies who is acting. UUID defaults to the authenticated user;
+// Attributes are merged over the attributes loaded from the database.
+type AuthzSubject struct {
+	UUID       string                 `json:"uuid,omitempty" validate:"max=100"`
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+}
+
+// AuthzResource identifies what is acted upon. Known types (e.g. "user") are
+// loaded by UUID; Attributes are merged over the loaded attributes.
+type AuthzResource struct {
+	Type       string                 `json:"type" validate:"max=50"`
+	UUID       string                 `json:"uuid,omitempty" validate:"max=100"`
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+}
@@ -0,0 +1,20 @@
+package dto
+
+// AuthzDecisionResponse is the outcome of a policy evaluation, including the
+// per-policy trace so that decisions can be debugged.
+type AuthzDecisionResponse struct {
+	Action   string                     `json:"action"`
+	Allowed  bool                       `json:"allowed"`
+	Effect   string                     `json:"effect"`
+	Reason   string                     `json:"reason"`
+	Policies []PolicyEvaluationResponse `json:"policies"`
+	Input    map[string]interface{}     `json:"input,omitempty"`
+}
+
+type PolicyEvaluationResponse struct {
+	Name      string `json:"name"`
+	Effect    string `json:"effect"`
+	Condition string `json:"condition"`
+	Matched   bool   `json:"matched"`
+	Error     string `json:"error,omitempty"`
+}
@@ -0,0 +1,27 @@
+package converter
+
+import (
+	"go-starter-template/internal/dto"
+	"go-starter-template/internal/model"
+
+	"github.com/goccy/go-json"
+)
+
+func AuditEventToResponse(e *model.AuditEvent) *dto.AuditEventResponse {
+	return &dto.AuditEventResponse{
+		ID:         e.ID,
+		UUID:       e.UUID,
+		ActorUUID:  e.ActorUUID,
+		Action:     e.Action,
+		TargetType: e.TargetType,
+		TargetID:   e.TargetID,
+		IP:         e.IP,
+		UserAgent:  e.UserAgent,
+		TraceID:    e.TraceID,
+		Changes:    json.RawMessage(e.Changes),
+		Metadata:   json.RawMessage(e.Metadata),
+		CreatedAt:  e.CreatedAt.Unix(),
+		PrevHash:   e.PrevHash,
+		Hash:       e.Hash,
+	}
+}
@@ -0,0 +1,23 @@
+package converter
+
+import (
+	"go-starter-template/internal/dto"
+	"go-starter-template/internal/model"
+)
+
+func LoginEventToResponse(e *model.LoginEvent) *dto.LoginEventResponse {
+	return &dto.LoginEventResponse{
+		UUID:          e.UUID,
+		Success:       e.Success,
+		FailureReason: e.FailureReason,
+		IP:            e.IP,
+		UserAgent:     e.UserAgent,
+		Device:        e.Device,
+		Country:       e.Country,
+		Region:        e.Region,
+		City:          e.City,
+		NewDevice:     e.NewDevice,
+		NewCountry:    e.NewCountry,
+		CreatedAt:     e.CreatedAt.Unix(),
+	}
+}
@@ -0,0 +1,37 @@
+package converter
+
+import (
+	"go-starter-template/internal/constant"
+	"go-starter-template/internal/dto"
+	"go-starter-template/internal/model"
+	"sort"
+)
+
+// UserToEffectivePermissions merges direct and role-derived permissions,
+// keeping every source a permission was granted through. Results are sorted by name.
+func UserToEffectivePermissions(user *model.User) []dto.EffectivePermissionResponse {
+	index := make(map[string]int)
+	permissions := make([]dto.EffectivePermissionResponse, 0, len(user.Permissions))
+
+	add := func(name string, source dto.PermissionSourceResponse) {
+		i, ok := index[name]
+		if !ok {
+			i = len(permissions)
+			index[name] = i
+			permissions = append(permissions, dto.EffectivePermissionResponse{Name: name})
+		}
+		permissions[i].Sources = append(permissions[i].Sources, source)
+	}
+
+	for _, perm := range user.Permissions {
+		add(perm.Name, dto.PermissionSourceResponse{Type: string(constant.PermissionSourceDirect)})
+	}
+	for _, role := range user.Roles {
+		for _, perm := range role.Permissions {
+			add(perm.Name, dto.PermissionSourceResponse{Type: string(constant.PermissionSourceRole), Role: role.Name})
+		}
+	}
+
+	sort.Slice(permissions, func(i, j int) bool { return permissions[i].Name < permissions[j].Name })
+	return permissions
+}
@@ -0,0 +1,39 @@
+package converter
+
+import (
+	"go-starter-template/internal/dto"
+	"go-starter-template/internal/scheduler"
+)
+
+func SchedulerToResponse(status *scheduler.Status) *dto.SchedulerResponse {
+	response := &dto.SchedulerResponse{
+		Instance: status.Instance,
+		Leader:   status.Leader,
+		Tasks:    make([]*dto.ScheduledTaskResponse, 0, len(status.Tasks)),
+	}
+	for _, task := range status.Tasks {
+		item := &dto.ScheduledTaskResponse{
+			Name:            task.Name,
+			Schedule:        task.Schedule,
+			MissedRunPolicy: string(task.MissedRunPolicy),
+			LastStatus:      string(task.LastStatus),
+			LastError:       task.LastError,
+			LastDurationMs:  task.LastDuration.Milliseconds(),
+			LastInstance:    task.LastInstance,
+		}
+		if task.LastRunAt != nil {
+			item.LastRunAt = task.LastRunAt.Unix()
+		}
+		if task.LastStartedAt != nil {
+			item.LastStartedAt = task.LastStartedAt.Unix()
+		}
+		if task.LastFinishedAt != nil {
+			item.LastFinishedAt = task.LastFinishedAt.Unix()
+		}
+		if !task.NextRunAt.IsZero() {
+			item.NextRunAt = task.NextRunAt.Unix()
+		}
+		response.Tasks = append(response.Tasks, item)
+	}
+	return response
+}
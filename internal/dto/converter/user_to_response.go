@@ -25,13 +25,26 @@ func UserToResponse(user *model.User) *dto.UserResponse {
 		}
 	}
 
-    return &dto.UserResponse{
-        UUID:        user.UUID,
-        Name:        user.Name,
-        Email:       user.Email,
-        CreatedAt:   user.CreatedAt.Unix(),
-        UpdatedAt:   user.UpdatedAt.Unix(),
-        Roles:       roles,
-        Permissions: directPermissions,
+    response := &dto.UserResponse{
+        UUID:         user.UUID,
+        Name:         user.Name,
+        Email:        user.Email,
+        Status:       user.Status,
+        StatusReason: user.StatusReason,
+        CreatedAt:    user.CreatedAt.Unix(),
+        UpdatedAt:    user.UpdatedAt.Unix(),
+        Version:      user.Version,
+        Roles:        roles,
+        Permissions:  directPermissions,
     }
+    if user.Phone != nil {
+        response.Phone = *user.Phone
+    }
+    if user.SuspendedUntil != nil {
+        response.SuspendedUntil = user.SuspendedUntil.Unix()
+    }
+    if user.DeletedAt != nil {
+        response.DeletedAt = user.DeletedAt.Unix()
+    }
+    return response
 }
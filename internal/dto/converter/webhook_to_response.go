@@ -0,0 +1,44 @@
+package converter
+
+import (
+	"go-starter-template/internal/dto"
+	"go-starter-template/internal/model"
+
+	"github.com/goccy/go-json"
+)
+
+// WebhookToResponse converts a subscription without its secret.
+func WebhookToResponse(subscription *model.WebhookSubscription) *dto.WebhookResponse {
+	return &dto.WebhookResponse{
+		UUID:                subscription.UUID,
+		URL:                 subscription.URL,
+		Description:         subscription.Description,
+		Events:              subscription.Events,
+		Enabled:             subscription.Enabled,
+		ConsecutiveFailures: subscription.ConsecutiveFailures,
+		DisabledReason:      subscription.DisabledReason,
+		CreatedAt:           subscription.CreatedAt.Unix(),
+		UpdatedAt:           subscription.UpdatedAt.Unix(),
+	}
+}
+
+func WebhookDeliveryToResponse(delivery *model.WebhookDelivery) *dto.WebhookDeliveryResponse {
+	response := &dto.WebhookDeliveryResponse{
+		UUID:           delivery.UUID,
+		EventID:        delivery.EventID,
+		EventType:      delivery.EventType,
+		Status:         delivery.Status,
+		Attempts:       delivery.Attempts,
+		ResponseStatus: delivery.ResponseStatus,
+		LastError:      delivery.LastError,
+		Payload:        json.RawMessage(delivery.Payload),
+		CreatedAt:      delivery.CreatedAt.Unix(),
+	}
+	if delivery.NextAttemptAt != nil {
+		response.NextAttemptAt = delivery.NextAttemptAt.Unix()
+	}
+	if delivery.DeliveredAt != nil {
+		response.DeliveredAt = delivery.DeliveredAt.Unix()
+	}
+	return response
+}
@@ -0,0 +1,17 @@
+package dto
+
+// ListLoginsRequest pages through the current user's login history; Cursor is
+// the paging.next value of a previous response.
+type ListLoginsRequest struct {
+	Cursor string `json:"cursor" query:"cursor"`
+	Limit  int    `json:"limit" query:"limit"`
+}
+
+func (r *ListLoginsRequest) SetDefault() {
+	if r.Limit <= 0 {
+		r.Limit = 20
+	}
+	if r.Limit > 100 {
+		r.Limit = 100
+	}
+}
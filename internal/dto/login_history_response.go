@@ -0,0 +1,17 @@
+package dto
+
+type LoginEventResponse struct {
+	UUID    string `json:"uuid"`
+	Success bool   `json:"success"`
+	// FailureReason is why a failed login was rejected, e.g. invalid_password
+	FailureReason string `json:"failure_reason,omitempty"`
+	IP            string `json:"ip,omitempty"`
+	UserAgent     string `json:"user_agent,omitempty"`
+	Device        string `json:"device,omitempty"`
+	Country       string `json:"country,omitempty"`
+	Region        string `json:"region,omitempty"`
+	City          string `json:"city,omitempty"`
+	NewDevice     bool   `json:"new_device"`
+	NewCountry    bool   `json:"new_country"`
+	CreatedAt     int64  `json:"created_at"`
+}
@@ -0,0 +1,28 @@
+package dto
+
+// EffectivePermissionsResponse lists every permission a user holds with its provenance.
+type EffectivePermissionsResponse struct {
+	UserUUID    string                        `json:"user_uuid"`
+	Permissions []EffectivePermissionResponse `json:"permissions"`
+}
+
+type EffectivePermissionResponse struct {
+	Name    string                     `json:"name"`
+	Sources []PermissionSourceResponse `json:"sources"`
+}
+
+// PermissionSourceResponse explains where a permission comes from: granted
+// directly through user_permissions or inherited from a role.
+type PermissionSourceResponse struct {
+	Type string `json:"type"`
+	Role string `json:"role,omitempty"`
+}
+
+// PermissionCheckResponse answers whether a user holds a single permission and why.
+type PermissionCheckResponse struct {
+	UserUUID   string                     `json:"user_uuid"`
+	Permission string                     `json:"permission"`
+	Allowed    bool                       `json:"allowed"`
+	Reason     string                     `json:"reason"`
+	Sources    []PermissionSourceResponse `json:"sources,omitempty"`
+}
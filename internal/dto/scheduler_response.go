@@ -0,0 +1,23 @@
+package dto
+
+type SchedulerResponse struct {
+	// Instance is the instance answering, Leader the one running the tasks
+	Instance string                   `json:"instance"`
+	Leader   string                   `json:"leader"`
+	Tasks    []*ScheduledTaskResponse `json:"tasks"`
+}
+
+type ScheduledTaskResponse struct {
+	Name            string `json:"name"`
+	Schedule        string `json:"schedule"`
+	MissedRunPolicy string `json:"missed_run_policy"`
+	// LastRunAt is when the last run was due, LastStartedAt when it started
+	LastRunAt      int64  `json:"last_run_at,omitempty"`
+	LastStartedAt  int64  `json:"last_started_at,omitempty"`
+	LastFinishedAt int64  `json:"last_finished_at,omitempty"`
+	LastStatus     string `json:"last_status,omitempty"`
+	LastError      string `json:"last_error,omitempty"`
+	LastDurationMs int64  `json:"last_duration_ms,omitempty"`
+	LastInstance   string `json:"last_instance,omitempty"`
+	NextRunAt      int64  `json:"next_run_at,omitempty"`
+}
@@ -0,0 +1,45 @@
+package dto
+
+import (
+	"go-starter-template/internal/constant"
+
+	"github.com/goccy/go-json"
+)
+
+// BulkUserOperation is one item of a POST /api/users/bulk batch. Data holds
+// the CreateUserRequest or UpdateUserRequest body; UUID (and optionally
+// Version, as with If-Match) identify the user to update or delete.
+type BulkUserOperation struct {
+	Op      constant.BulkOperation `json:"op"`
+	UUID    string                 `json:"uuid,omitempty"`
+	Version int64                  `json:"version,omitempty"`
+	Data    json.RawMessage        `json:"data,omitempty"`
+
+	// Line is the source line of an imported row, 0 for bulk requests
+	Line int `json:"-"`
+	// Filled in once the item has been decoded and validated
+	Create *CreateUserRequest  `json:"-"`
+	Update *UpdateUserRequest  `json:"-"`
+	Err    error               `json:"-"`
+	Errors map[string][]string `json:"-"`
+}
+
+// BulkUserResult reports the outcome of one operation, in request order.
+// Status is the HTTP status the operation would have had on its own.
+type BulkUserResult struct {
+	Index  int                    `json:"index"`
+	Line   int                    `json:"line,omitempty"`
+	Op     constant.BulkOperation `json:"op"`
+	Status int                    `json:"status"`
+	Data   *UserResponse          `json:"data,omitempty"`
+	Error  string                 `json:"error,omitempty"`
+	Errors map[string][]string    `json:"errors,omitempty"`
+}
+
+type BulkUserResponse struct {
+	Mode      constant.BulkMode `json:"mode"`
+	DryRun    bool              `json:"dry_run,omitempty"`
+	Succeeded int               `json:"succeeded"`
+	Failed    int               `json:"failed"`
+	Results   []BulkUserResult  `json:"results"`
+}
@@ -0,0 +1,56 @@
+package dto
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/goccy/go-json"
+)
+
+// PatchUserRequest is an RFC 7396 JSON Merge Patch document for a user.
+// Members absent from the document leave the user untouched, while an
+// explicit null clears a nullable field; Has tells the two apart.
+type PatchUserRequest struct {
+	Name  *string `json:"name" validate:"required,min=3,max=100"`
+	Email *string `json:"email" validate:"required,email,max=200"`
+	Phone *string `json:"phone" validate:"omitempty,e164"`
+
+	present map[string]bool
+}
+
+func (r *PatchUserRequest) UnmarshalJSON(data []byte) error {
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(data, &members); err != nil {
+		return err
+	}
+	if members == nil {
+		return errors.New("merge patch must be a JSON object")
+	}
+
+	type plain PatchUserRequest
+	if err := json.Unmarshal(data, (*plain)(r)); err != nil {
+		return err
+	}
+
+	r.present = make(map[string]bool, len(members))
+	for name := range members {
+		r.present[name] = true
+	}
+	return nil
+}
+
+// Has reports whether the patch document contains the given JSON member,
+// including members set to null.
+func (r *PatchUserRequest) Has(field string) bool {
+	return r.present[field]
+}
+
+// Fields returns the JSON members present in the patch document.
+func (r *PatchUserRequest) Fields() []string {
+	fields := make([]string, 0, len(r.present))
+	for name := range r.present {
+		fields = append(fields, name)
+	}
+	sort.Strings(fields)
+	return fields
+}
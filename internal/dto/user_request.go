@@ -1,10 +1,45 @@
 package dto
 
+import "strings"
+
 type SearchUserRequest struct {
 	Name  string `json:"name" validate:"max=100"`
 	Email string `json:"email" validate:"max=200"`
 	Page  int    `json:"page" validate:"min=1"`
 	Size  int    `json:"size" validate:"min=1,max=100"`
+
+	// Q is a free-text query over name and email, ranked by relevance
+	Q string `json:"q" query:"q" validate:"max=200"`
+
+	// Sort lists fields to order by, "-" for descending, e.g. "name,-created_at"
+	Sort string `json:"sort" query:"sort"`
+	// Cursor and Limit select keyset pagination instead of page/size; Cursor
+	// is the opaque paging.next/paging.prev value of a previous response
+	Cursor string `json:"cursor" query:"cursor"`
+	Limit  int    `json:"limit" query:"limit" validate:"min=0,max=100"`
+	// SkipCount leaves out the total count, which is costly on large tables
+	SkipCount bool `json:"skip_count" query:"skip_count"`
+
+	// Roles, Permissions and Status are comma-separated and match any listed
+	// value; permissions count whether granted directly or through a role
+	Roles       string `json:"role" query:"role"`
+	Permissions string `json:"permission" query:"permission"`
+	Status      string `json:"status" query:"status"`
+	// Date ranges are inclusive unix timestamps; 0 leaves a bound open
+	CreatedFrom int64 `json:"created_from" query:"created_from"`
+	CreatedTo   int64 `json:"created_to" query:"created_to"`
+	UpdatedFrom int64 `json:"updated_from" query:"updated_from"`
+	UpdatedTo   int64 `json:"updated_to" query:"updated_to"`
+	// Deleted is "exclude" (default), "include" or "only" soft-deleted users
+	Deleted string `json:"deleted" query:"deleted"`
+	// Match combines the filters with "all" (AND, default) or "any" (OR)
+	Match string `json:"match" query:"match"`
+	// Include loads relations inline: "roles", "permissions" or both; roles
+	// carry the permissions they grant only when both are listed
+	Include string `json:"include" query:"include"`
+
+	// OnlyDeleted lists soft-deleted users instead of active ones (admin endpoint only)
+	OnlyDeleted bool `json:"-" query:"-"`
 }
 
 func (r *SearchUserRequest) SetDefault() {
@@ -14,6 +49,27 @@ func (r *SearchUserRequest) SetDefault() {
 	if r.Size == 0 {
 		r.Size = 10
 	}
+	if r.Cursor != "" && r.Limit == 0 {
+		r.Limit = r.Size
+	}
+	if r.Limit > 100 {
+		r.Limit = 100
+	}
+}
+
+// Includes reports whether relation was requested via Include.
+func (r *SearchUserRequest) Includes(relation string) bool {
+	for _, item := range strings.Split(r.Include, ",") {
+		if strings.TrimSpace(item) == relation {
+			return true
+		}
+	}
+	return false
+}
+
+// CursorMode reports whether keyset pagination was requested.
+func (r *SearchUserRequest) CursorMode() bool {
+	return r.Cursor != "" || r.Limit > 0
 }
 
 type CreateUserRequest struct {
@@ -28,3 +84,24 @@ type UpdateUserRequest struct {
 	Email string   `json:"email" validate:"required,email,max=200"`
 	Roles []string `json:"roles,omitempty" validate:"omitempty"`
 }
+
+type SuspendUserRequest struct {
+	Reason string `json:"reason" validate:"max=255"`
+	// Until is an optional unix timestamp after which the suspension lifts itself
+	Until int64 `json:"until,omitempty" validate:"omitempty,min=0"`
+}
+
+// UpdateMeRequest changes the caller's own profile. Omitted fields are left
+// untouched; a new email only takes effect once verified.
+type UpdateMeRequest struct {
+	Name  *string `json:"name" validate:"omitempty,min=3,max=100"`
+	Email *string `json:"email" validate:"omitempty,email,max=200"`
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+type DeleteMeRequest struct {
+	Password string `json:"password" validate:"required"`
+}
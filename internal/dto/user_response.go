@@ -1,13 +1,22 @@
 package dto
 
 type UserResponse struct {
-	UUID        string         `json:"uuid,omitempty"`
-	Name        string         `json:"name,omitempty"`
-	Email       string         `json:"email,omitempty"`
-	CreatedAt   int64          `json:"created_at,omitempty"`
-	UpdatedAt   int64          `json:"updated_at,omitempty"`
-	Roles       []RoleResponse `json:"roles,omitempty"`
-	Permissions []string       `json:"permissions,omitempty"`
+	UUID           string         `json:"uuid,omitempty"`
+	Name           string         `json:"name,omitempty"`
+	Email          string         `json:"email,omitempty"`
+	PendingEmail   string         `json:"pending_email,omitempty"`
+	Phone          string         `json:"phone,omitempty"`
+	Status         string         `json:"status,omitempty"`
+	StatusReason   string         `json:"status_reason,omitempty"`
+	SuspendedUntil int64          `json:"suspended_until,omitempty"`
+	CreatedAt      int64          `json:"created_at,omitempty"`
+	UpdatedAt      int64          `json:"updated_at,omitempty"`
+	DeletedAt      int64          `json:"deleted_at,omitempty"`
+	Version        int64          `json:"version,omitempty"`
+	Roles          []RoleResponse `json:"roles,omitempty"`
+	Permissions    []string       `json:"permissions,omitempty"`
+	// Highlight holds name/email with free-text query matches wrapped in <mark>
+	Highlight map[string]string `json:"highlight,omitempty"`
 }
 
 type RoleResponse struct {
@@ -6,10 +6,14 @@ type WebResponse[T any] struct {
 	Paging *PageMetadata `json:"paging,omitempty"` // Pagination details (if applicable)
 }
 
-// PageMetadata contains pagination details
+// PageMetadata contains pagination details. Page mode fills Page and
+// TotalPage, cursor mode fills Next and Prev; totals are omitted when the
+// client skipped counting.
 type PageMetadata struct {
-	Page      int   `json:"page"`       // Current page number
-	Size      int   `json:"size"`       // Number of items per page
-	TotalItem int64 `json:"total_item"` // Total number of items
-	TotalPage int64 `json:"total_page"` // Total pages available
+	Page      int    `json:"page,omitempty"`       // Current page number
+	Size      int    `json:"size"`                 // Number of items per page
+	TotalItem *int64 `json:"total_item,omitempty"` // Total number of items
+	TotalPage *int64 `json:"total_page,omitempty"` // Total pages available
+	Next      string `json:"next,omitempty"`       // Cursor of the following page
+	Prev      string `json:"prev,omitempty"`       // Cursor of the preceding page
 }
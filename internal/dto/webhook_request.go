@@ -0,0 +1,35 @@
+package dto
+
+// CreateWebhookRequest subscribes url to the listed event types, or to all
+// of them with "*".
+type CreateWebhookRequest struct {
+	URL         string   `json:"url" validate:"required,url,max=2000"`
+	Description string   `json:"description" validate:"max=255"`
+	Events      []string `json:"events" validate:"required,min=1,dive,required,max=100"`
+}
+
+// UpdateWebhookRequest replaces a subscription's settings. Enabled is kept
+// when omitted; setting it to true re-enables an automatically disabled
+// subscription and resets its failure count.
+type UpdateWebhookRequest struct {
+	URL         string   `json:"url" validate:"required,url,max=2000"`
+	Description string   `json:"description" validate:"max=255"`
+	Events      []string `json:"events" validate:"required,min=1,dive,required,max=100"`
+	Enabled     *bool    `json:"enabled"`
+}
+
+// ListWebhookDeliveriesRequest selects the latest deliveries of a
+// subscription, optionally only those with Status.
+type ListWebhookDeliveriesRequest struct {
+	Status string `json:"status" query:"status"`
+	Limit  int    `json:"limit" query:"limit"`
+}
+
+func (r *ListWebhookDeliveriesRequest) SetDefault() {
+	if r.Limit <= 0 {
+		r.Limit = 50
+	}
+	if r.Limit > 200 {
+		r.Limit = 200
+	}
+}
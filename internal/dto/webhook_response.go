@@ -0,0 +1,31 @@
+package dto
+
+import "github.com/goccy/go-json"
+
+type WebhookResponse struct {
+	UUID                string   `json:"uuid"`
+	URL                 string   `json:"url"`
+	Description         string   `json:"description,omitempty"`
+	Events              []string `json:"events"`
+	Enabled             bool     `json:"enabled"`
+	ConsecutiveFailures int      `json:"consecutive_failures"`
+	DisabledReason      string   `json:"disabled_reason,omitempty"`
+	// Secret signs the deliveries; it is only returned when the webhook is created
+	Secret    string `json:"secret,omitempty"`
+	CreatedAt int64  `json:"created_at"`
+	UpdatedAt int64  `json:"updated_at"`
+}
+
+type WebhookDeliveryResponse struct {
+	UUID           string          `json:"uuid"`
+	EventID        string          `json:"event_id"`
+	EventType      string          `json:"event_type"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	ResponseStatus int             `json:"response_status,omitempty"`
+	LastError      string          `json:"last_error,omitempty"`
+	Payload        json.RawMessage `json:"payload"`
+	NextAttemptAt  int64           `json:"next_attempt_at,omitempty"`
+	CreatedAt      int64           `json:"created_at"`
+	DeliveredAt    int64           `json:"delivered_at,omitempty"`
+}
@@ -0,0 +1,149 @@
+// Package event defines the domain events recorded in the outbox and the
+// sinks the outbox relay publishes them to.
+package event
+
+import (
+	"time"
+
+	"go-starter-template/internal/model"
+
+	"github.com/goccy/go-json"
+)
+
+// AggregateUser is the aggregate type of every user event.
+const AggregateUser = "user"
+
+const (
+	TypeUserRegistered    = "user.registered"
+	TypeUserCreated       = "user.created"
+	TypeUserUpdated       = "user.updated"
+	TypeUserDeleted       = "user.deleted"
+	TypeUserRestored      = "user.restored"
+	TypeUserStatusChanged = "user.status_changed"
+	TypeRoleAssigned      = "user.role_assigned"
+	TypeRoleRevoked       = "user.role_revoked"
+)
+
+// Types lists every event type, e.g. for validating webhook subscriptions.
+var Types = []string{
+	TypeUserRegistered,
+	TypeUserCreated,
+	TypeUserUpdated,
+	TypeUserDeleted,
+	TypeUserRestored,
+	TypeUserStatusChanged,
+	TypeRoleAssigned,
+	TypeRoleRevoked,
+}
+
+// Event is a domain event; its JSON encoding is the payload consumers receive.
+type Event interface {
+	EventType() string
+	AggregateType() string
+	AggregateID() string
+}
+
+// User is the state of a user after the change an event describes, so
+// consumers need not call back for it.
+type User struct {
+	UUID    string  `json:"uuid"`
+	Name    string  `json:"name"`
+	Email   string  `json:"email"`
+	Status  string  `json:"status"`
+	Phone   *string `json:"phone"`
+	Version int64   `json:"version"`
+}
+
+// UserEvent is embedded by every event about a single user.
+type UserEvent struct {
+	User User `json:"user"`
+}
+
+// ForUser snapshots user for an event.
+func ForUser(user *model.User) UserEvent {
+	return UserEvent{User: User{
+		UUID:    user.UUID,
+		Name:    user.Name,
+		Email:   user.Email,
+		Status:  user.Status,
+		Phone:   user.Phone,
+		Version: user.Version,
+	}}
+}
+
+func (e UserEvent) AggregateType() string { return AggregateUser }
+func (e UserEvent) AggregateID() string   { return e.User.UUID }
+
+// UserRegistered is recorded when someone signs up.
+type UserRegistered struct{ UserEvent }
+
+// UserCreated is recorded when an administrator creates a user, one by one,
+// in bulk or by import.
+type UserCreated struct{ UserEvent }
+
+// UserUpdated is recorded when profile fields change; Changed names them.
+type UserUpdated struct {
+	UserEvent
+	Changed []string `json:"changed"`
+}
+
+// UserDeleted is recorded when a user is soft-deleted, by an administrator
+// or by themselves.
+type UserDeleted struct{ UserEvent }
+
+// UserRestored is recorded when a soft-deleted user is brought back.
+type UserRestored struct{ UserEvent }
+
+// UserStatusChanged is recorded when a user is suspended or reactivated.
+type UserStatusChanged struct {
+	UserEvent
+	Reason string     `json:"reason,omitempty"`
+	Until  *time.Time `json:"until,omitempty"`
+}
+
+// RoleAssigned is recorded when a user is granted a role.
+type RoleAssigned struct {
+	UserEvent
+	Role string `json:"role"`
+}
+
+// RoleRevoked is recorded when a role is taken from a user.
+type RoleRevoked struct {
+	UserEvent
+	Role string `json:"role"`
+}
+
+func (UserRegistered) EventType() string    { return TypeUserRegistered }
+func (UserCreated) EventType() string       { return TypeUserCreated }
+func (UserUpdated) EventType() string       { return TypeUserUpdated }
+func (UserDeleted) EventType() string       { return TypeUserDeleted }
+func (UserRestored) EventType() string      { return TypeUserRestored }
+func (UserStatusChanged) EventType() string { return TypeUserStatusChanged }
+func (RoleAssigned) EventType() string      { return TypeRoleAssigned }
+func (RoleRevoked) EventType() string       { return TypeRoleRevoked }
+
+// Envelope is what sinks publish: an event's payload with its identity and
+// position. Delivery is at-least-once, so consumers should drop envelopes
+// whose ID they have seen; Sequence grows with every event of an aggregate.
+type Envelope struct {
+	ID            string          `json:"id"`
+	Type          string          `json:"type"`
+	AggregateType string          `json:"aggregate_type"`
+	AggregateID   string          `json:"aggregate_id"`
+	Sequence      int64           `json:"sequence"`
+	OccurredAt    time.Time       `json:"occurred_at"`
+	Data          json.RawMessage `json:"data"`
+}
+
+// FromOutbox wraps a stored outbox event for publishing.
+func FromOutbox(e *model.OutboxEvent) Envelope {
+	return Envelope{
+		ID:            e.UUID,
+		Type:          e.EventType,
+		AggregateType: e.AggregateType,
+		AggregateID:   e.AggregateID,
+		Sequence:      e.ID,
+		OccurredAt:    e.CreatedAt,
+		Data:          json.RawMessage(e.Payload),
+	}
+}
@@ -0,0 +1,107 @@
+package event
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/redis/go-redis/v9"
+)
+
+// Sink receives published events. Publish must not return before the event
+// is durably accepted, as the outbox marks it published right after.
+type Sink interface {
+	Publish(ctx context.Context, envelope Envelope) error
+}
+
+// Fanout publishes every event to each of sinks in turn and stops at the
+// first error. The relay then retries the event on all of them, so a sink
+// may see an event again after a later sink failed.
+type Fanout []Sink
+
+func (f Fanout) Publish(ctx context.Context, envelope Envelope) error {
+	for _, sink := range f {
+		if err := sink.Publish(ctx, envelope); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// streamClient is the part of redis.Client RedisStreamSink uses.
+type streamClient interface {
+	XAdd(ctx context.Context, a *redis.XAddArgs) *redis.StringCmd
+}
+
+// RedisStreamSink appends events to one Redis stream per aggregate type,
+// such as events:user, so a consumer group reads each aggregate's events in
+// order.
+type RedisStreamSink struct {
+	client streamClient
+	prefix string
+	maxLen int64
+}
+
+// NewRedisStreamSink publishes to streams named prefix plus aggregate type,
+// trimmed to about maxLen entries; 0 keeps every entry.
+func NewRedisStreamSink(client streamClient, prefix string, maxLen int64) *RedisStreamSink {
+	return &RedisStreamSink{client: client, prefix: prefix, maxLen: maxLen}
+}
+
+func (s *RedisStreamSink) Publish(ctx context.Context, envelope Envelope) error {
+	data, err := json.Marshal(envelope)
+	if err != nil {
+		return err
+	}
+	return s.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: s.prefix + envelope.AggregateType,
+		MaxLen: s.maxLen,
+		Approx: s.maxLen > 0,
+		Values: map[string]any{
+			"id":           envelope.ID,
+			"type":         envelope.Type,
+			"aggregate_id": envelope.AggregateID,
+			"envelope":     data,
+		},
+	}).Err()
+}
+
+// WebhookSink POSTs every event as JSON to a single URL and treats any 2xx
+// response as accepted.
+type WebhookSink struct {
+	url    string
+	client *http.Client
+}
+
+func NewWebhookSink(url string, timeout time.Duration) *WebhookSink {
+	return &WebhookSink{url: url, client: &http.Client{Timeout: timeout}}
+}
+
+func (s *WebhookSink) Publish(ctx context.Context, envelope Envelope) error {
+	data, err := json.Marshal(envelope)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Event-ID", envelope.ID)
+	req.Header.Set("X-Event-Type", envelope.Type)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook responded %s", resp.Status)
+	}
+	return nil
+}
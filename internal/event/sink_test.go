@@ -0,0 +1,88 @@
+package event
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	miniredis "github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+
+	"go-starter-template/internal/model"
+)
+
+func testEnvelope() Envelope {
+	payload, _ := json.Marshal(UserUpdated{UserEvent: ForUser(&model.User{UUID: "u1", Name: "Alice", Version: 2}), Changed: []string{"name"}})
+	return FromOutbox(&model.OutboxEvent{
+		ID:            7,
+		UUID:          "evt-1",
+		AggregateType: AggregateUser,
+		AggregateID:   "u1",
+		EventType:     TypeUserUpdated,
+		Payload:       payload,
+		CreatedAt:     time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC),
+	})
+}
+
+func TestRedisStreamSink_Publish(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	sink := NewRedisStreamSink(rdb, "events:", 0)
+
+	require.NoError(t, sink.Publish(context.Background(), testEnvelope()))
+
+	entries, err := rdb.XRange(context.Background(), "events:user", "-", "+").Result()
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, "evt-1", entries[0].Values["id"])
+	require.Equal(t, TypeUserUpdated, entries[0].Values["type"])
+	require.Equal(t, "u1", entries[0].Values["aggregate_id"])
+
+	var envelope Envelope
+	require.NoError(t, json.Unmarshal([]byte(entries[0].Values["envelope"].(string)), &envelope))
+	require.Equal(t, int64(7), envelope.Sequence)
+	require.JSONEq(t, `{"user":{"uuid":"u1","name":"Alice","email":"","status":"","phone":null,"version":2},"changed":["name"]}`, string(envelope.Data))
+}
+
+func TestWebhookSink_Publish(t *testing.T) {
+	cases := []struct {
+		name      string
+		status    int
+		expectErr bool
+	}{
+		{name: "Accepted", status: http.StatusAccepted},
+		{name: "Rejected", status: http.StatusInternalServerError, expectErr: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var received *http.Request
+			var body []byte
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				received = r
+				body, _ = io.ReadAll(r.Body)
+				w.WriteHeader(tc.status)
+			}))
+			defer server.Close()
+
+			err := NewWebhookSink(server.URL, time.Second).Publish(context.Background(), testEnvelope())
+			if tc.expectErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, http.MethodPost, received.Method)
+			require.Equal(t, "evt-1", received.Header.Get("X-Event-ID"))
+			require.Equal(t, TypeUserUpdated, received.Header.Get("X-Event-Type"))
+
+			var envelope Envelope
+			require.NoError(t, json.Unmarshal(body, &envelope))
+			require.Equal(t, "u1", envelope.AggregateID)
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package job
+
+import (
+	"context"
+	"go-starter-template/internal/service"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// BlacklistPruneJob gives token blacklist entries that never expire a TTL of
+// maxAge, so they stop piling up in Redis. The scheduler runs it.
+type BlacklistPruneJob struct {
+	blacklistService *service.BlacklistService
+	log              *logrus.Logger
+	tracer           trace.Tracer
+	maxAge           time.Duration
+}
+
+func NewBlacklistPruneJob(blacklistService *service.BlacklistService, log *logrus.Logger, maxAge time.Duration) *BlacklistPruneJob {
+	return &BlacklistPruneJob{blacklistService: blacklistService, log: log, tracer: otel.Tracer("BlacklistPruneJob"), maxAge: maxAge}
+}
+
+// Run prunes once.
+func (j *BlacklistPruneJob) Run(ctx context.Context) error {
+	spanCtx, span := j.tracer.Start(ctx, "BlacklistPruneJob.Run")
+	defer span.End()
+
+	pruned, err := j.blacklistService.Prune(spanCtx, j.maxAge)
+	if err != nil {
+		return err
+	}
+	j.log.WithContext(spanCtx).WithField("pruned", pruned).Info("blacklist prune job completed")
+	return nil
+}
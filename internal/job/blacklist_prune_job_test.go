@@ -0,0 +1,41 @@
+package job
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	miniredis "github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+
+	"go-starter-template/internal/config/env"
+	"go-starter-template/internal/repository"
+	"go-starter-template/internal/service"
+)
+
+func TestBlacklistPruneJob_Run(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	t.Run("Success", func(t *testing.T) {
+		mr := miniredis.RunT(t)
+		require.NoError(t, mr.Set("blacklist:access:abc", "1"))
+		blacklist := repository.NewRedisTokenBlacklist(redis.NewClient(&redis.Options{Addr: mr.Addr()}))
+		job := NewBlacklistPruneJob(service.NewBlacklistService(logger, service.NewJwtService(logger, &env.Config{}), blacklist), logger, time.Hour)
+
+		require.NoError(t, job.Run(context.Background()))
+		require.Equal(t, time.Hour, mr.TTL("blacklist:access:abc"))
+	})
+
+	t.Run("Failure", func(t *testing.T) {
+		mr := miniredis.RunT(t)
+		blacklist := repository.NewRedisTokenBlacklist(redis.NewClient(&redis.Options{Addr: mr.Addr()}))
+		job := NewBlacklistPruneJob(service.NewBlacklistService(logger, service.NewJwtService(logger, &env.Config{}), blacklist), logger, time.Hour)
+		mr.Close()
+
+		require.Error(t, job.Run(context.Background()))
+	})
+}
@@ -0,0 +1,35 @@
+package job
+
+import (
+	"context"
+	"go-starter-template/internal/service"
+
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// KeyRotationJob replaces the JWT signing keys and deletes the ones no
+// unexpired token was signed with. The scheduler runs it.
+type KeyRotationJob struct {
+	jwtService *service.JwtService
+	log        *logrus.Logger
+	tracer     trace.Tracer
+}
+
+func NewKeyRotationJob(jwtService *service.JwtService, log *logrus.Logger) *KeyRotationJob {
+	return &KeyRotationJob{jwtService: jwtService, log: log, tracer: otel.Tracer("KeyRotationJob")}
+}
+
+// Run rotates once.
+func (j *KeyRotationJob) Run(ctx context.Context) error {
+	spanCtx, span := j.tracer.Start(ctx, "KeyRotationJob.Run")
+	defer span.End()
+
+	retired, err := j.jwtService.RotateSigningKeys(spanCtx)
+	if err != nil {
+		return err
+	}
+	j.log.WithContext(spanCtx).WithField("retired", retired).Info("key rotation job completed")
+	return nil
+}
@@ -0,0 +1,45 @@
+package job
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	miniredis "github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+
+	"go-starter-template/internal/config/env"
+	"go-starter-template/internal/constant"
+	"go-starter-template/internal/repository"
+	"go-starter-template/internal/service"
+)
+
+func TestKeyRotationJob_Run(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	t.Run("Success", func(t *testing.T) {
+		mr := miniredis.RunT(t)
+		keys := repository.NewSigningKeyRepository(redis.NewClient(&redis.Options{Addr: mr.Addr()}))
+		jwtSvc := service.NewJwtService(logger, &env.Config{})
+		jwtSvc.UseKeyStore(keys)
+
+		require.NoError(t, NewKeyRotationJob(jwtSvc, logger).Run(context.Background()))
+		for _, tokenType := range []constant.TokenType{constant.TokenTypeAccess, constant.TokenTypeRefresh} {
+			list, err := keys.List(context.Background(), tokenType)
+			require.NoError(t, err)
+			require.Len(t, list, 1)
+		}
+	})
+
+	t.Run("Failure", func(t *testing.T) {
+		mr := miniredis.RunT(t)
+		jwtSvc := service.NewJwtService(logger, &env.Config{})
+		jwtSvc.UseKeyStore(repository.NewSigningKeyRepository(redis.NewClient(&redis.Options{Addr: mr.Addr()})))
+		mr.Close()
+
+		require.Error(t, NewKeyRotationJob(jwtSvc, logger).Run(context.Background()))
+	})
+}
@@ -0,0 +1,95 @@
+package job
+
+import (
+	"context"
+	"go-starter-template/internal/service"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// OutboxRelayJob periodically publishes the events waiting in the outbox and
+// prunes the ones published longer ago than the retention window.
+type OutboxRelayJob struct {
+	outboxService *service.OutboxService
+	log           *logrus.Logger
+	tracer        trace.Tracer
+	interval      time.Duration
+	batchSize     int
+	retention     time.Duration
+	cancel        context.CancelFunc
+	wg            sync.WaitGroup
+}
+
+func NewOutboxRelayJob(outboxService *service.OutboxService, log *logrus.Logger, interval time.Duration, batchSize int, retention time.Duration) *OutboxRelayJob {
+	return &OutboxRelayJob{outboxService: outboxService, log: log, tracer: otel.Tracer("OutboxRelayJob"), interval: interval, batchSize: batchSize, retention: retention}
+}
+
+// Start runs the job every interval in the background until Stop is called or
+// ctx is cancelled. A non-positive interval disables the job.
+func (j *OutboxRelayJob) Start(ctx context.Context) {
+	if j.interval <= 0 {
+		j.log.Info("outbox relay job disabled")
+		return
+	}
+
+	ctx, j.cancel = context.WithCancel(ctx)
+	j.wg.Add(1)
+	go func() {
+		defer j.wg.Done()
+		ticker := time.NewTicker(j.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				j.Run(ctx)
+			}
+		}
+	}()
+}
+
+// Stop cancels the background loop and waits for a running relay to finish.
+func (j *OutboxRelayJob) Stop() {
+	if j.cancel != nil {
+		j.cancel()
+	}
+	j.wg.Wait()
+}
+
+// Run publishes batches until the outbox is drained or a delivery fails, then
+// prunes published events when a retention is set.
+func (j *OutboxRelayJob) Run(ctx context.Context) {
+	spanCtx, span := j.tracer.Start(ctx, "OutboxRelayJob.Run")
+	defer span.End()
+
+	logger := j.log.WithContext(spanCtx)
+	total := 0
+	for ctx.Err() == nil {
+		published, err := j.outboxService.PublishPending(spanCtx, j.batchSize)
+		if err != nil {
+			logger.WithError(err).Error("outbox relay job failed")
+			return
+		}
+		total += published
+		if published < j.batchSize {
+			break
+		}
+	}
+	if total > 0 {
+		logger.WithField("published", total).Info("outbox relay job published events")
+	}
+
+	if j.retention <= 0 {
+		return
+	}
+	if pruned, err := j.outboxService.PrunePublished(spanCtx, j.retention); err != nil {
+		logger.WithError(err).Error("outbox prune failed")
+	} else if pruned > 0 {
+		logger.WithField("pruned", pruned).Info("outbox relay job pruned published events")
+	}
+}
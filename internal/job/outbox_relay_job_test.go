@@ -0,0 +1,88 @@
+package job
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+
+	"go-starter-template/internal/event"
+	"go-starter-template/internal/repository"
+	"go-starter-template/internal/service"
+)
+
+type recordingSink struct{ published []string }
+
+func (s *recordingSink) Publish(_ context.Context, envelope event.Envelope) error {
+	s.published = append(s.published, envelope.ID)
+	return nil
+}
+
+func setupOutboxRelayJob(t *testing.T, batchSize int, retention time.Duration) (*OutboxRelayJob, *recordingSink, sqlmock.Sqlmock) {
+	t.Helper()
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	sink := &recordingSink{}
+	outboxSvc := service.NewOutboxService(repository.NewOutboxRepository(db), sink, logger, repository.NewUnitOfWork(db), 3)
+	return NewOutboxRelayJob(outboxSvc, logger, time.Minute, batchSize, retention), sink, mock
+}
+
+// expectBatch mocks one relay transaction publishing the events with ids.
+func expectBatch(mock sqlmock.Sqlmock, ids ...int) {
+	rows := sqlmock.NewRows([]string{"id", "uuid", "aggregate_type", "aggregate_id", "event_type", "payload", "created_at", "attempts", "last_error"})
+	for _, id := range ids {
+		rows.AddRow(id, fmt.Sprintf("e%d", id), "user", "u1", "user.updated", []byte(`{}`), time.Now(), 0, "")
+	}
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT pg_try_advisory_xact_lock($1)")).
+		WillReturnRows(sqlmock.NewRows([]string{"locked"}).AddRow(true))
+	mock.ExpectQuery(regexp.QuoteMeta("FROM outbox WHERE published_at IS NULL AND parked_at IS NULL")).WillReturnRows(rows)
+	for _, id := range ids {
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE outbox SET published_at = NOW() WHERE id = $1")).
+			WithArgs(id).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+	mock.ExpectCommit()
+}
+
+func TestOutboxRelayJob_Run(t *testing.T) {
+	t.Run("DrainsFullBatches", func(t *testing.T) {
+		job, sink, mock := setupOutboxRelayJob(t, 2, 0)
+		expectBatch(mock, 1, 2)
+		expectBatch(mock, 3)
+
+		job.Run(context.Background())
+		require.Equal(t, []string{"e1", "e2", "e3"}, sink.published)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("PrunesWithRetention", func(t *testing.T) {
+		job, _, mock := setupOutboxRelayJob(t, 2, time.Hour)
+		expectBatch(mock)
+		mock.ExpectExec(regexp.QuoteMeta("DELETE FROM outbox WHERE published_at < $1")).
+			WillReturnResult(sqlmock.NewResult(0, 5))
+
+		job.Run(context.Background())
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestOutboxRelayJob_StartStop(t *testing.T) {
+	job, _, mock := setupOutboxRelayJob(t, 2, 0)
+	job.interval = 0
+	job.Start(context.Background())
+	job.Stop()
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,37 @@
+package job
+
+import (
+	"context"
+	"go-starter-template/internal/service"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// UserPurgeJob hard-deletes users that have been soft-deleted for longer
+// than the configured retention window. The scheduler runs it.
+type UserPurgeJob struct {
+	userService *service.UserService
+	log         *logrus.Logger
+	tracer      trace.Tracer
+	retention   time.Duration
+}
+
+func NewUserPurgeJob(userService *service.UserService, log *logrus.Logger, retention time.Duration) *UserPurgeJob {
+	return &UserPurgeJob{userService: userService, log: log, tracer: otel.Tracer("UserPurgeJob"), retention: retention}
+}
+
+// Run purges once.
+func (j *UserPurgeJob) Run(ctx context.Context) error {
+	spanCtx, span := j.tracer.Start(ctx, "UserPurgeJob.Run")
+	defer span.End()
+
+	purged, err := j.userService.PurgeDeletedUsers(spanCtx, j.retention)
+	if err != nil {
+		return err
+	}
+	j.log.WithContext(spanCtx).WithField("purged", purged).Info("user purge job completed")
+	return nil
+}
@@ -0,0 +1,65 @@
+package job
+
+import (
+	"context"
+	"io"
+	"regexp"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+
+	"go-starter-template/internal/repository"
+	"go-starter-template/internal/service"
+)
+
+func setupUserPurgeJob(t *testing.T) (*UserPurgeJob, sqlmock.Sqlmock) {
+	t.Helper()
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	uow := repository.NewUnitOfWork(db)
+	auditSvc := service.NewAuditService(repository.NewAuditRepository(db), logger, uow)
+	userSvc := service.NewUserService(repository.NewUserRepository(db), repository.NewOutboxRepository(db), auditSvc, nil, logger, uow)
+	return NewUserPurgeJob(userSvc, logger, time.Hour), mock
+}
+
+func expectPurge(mock sqlmock.Sqlmock, purged int64) {
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM user_roles")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM user_permissions")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM users WHERE deleted_at < $1")).WillReturnResult(sqlmock.NewResult(0, purged))
+	if purged > 0 {
+		mock.ExpectExec(regexp.QuoteMeta("SELECT pg_advisory_xact_lock($1)")).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT hash FROM audit_events")).WillReturnRows(sqlmock.NewRows([]string{"hash"}))
+		mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO audit_events")).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	}
+	mock.ExpectCommit()
+}
+
+func TestUserPurgeJob_Run(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		job, mock := setupUserPurgeJob(t)
+		expectPurge(mock, 3)
+
+		require.NoError(t, job.Run(context.Background()))
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Failure", func(t *testing.T) {
+		job, mock := setupUserPurgeJob(t)
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta("DELETE FROM user_roles")).WillReturnError(sqlmock.ErrCancelled)
+		mock.ExpectRollback()
+
+		require.Error(t, job.Run(context.Background()))
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,84 @@
+package job
+
+import (
+	"context"
+	"go-starter-template/internal/service"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// WebhookDeliveryJob periodically sends the webhook deliveries that are due,
+// first attempts and retries alike.
+type WebhookDeliveryJob struct {
+	webhookService *service.WebhookService
+	log            *logrus.Logger
+	tracer         trace.Tracer
+	interval       time.Duration
+	batchSize      int
+	cancel         context.CancelFunc
+	wg             sync.WaitGroup
+}
+
+func NewWebhookDeliveryJob(webhookService *service.WebhookService, log *logrus.Logger, interval time.Duration, batchSize int) *WebhookDeliveryJob {
+	return &WebhookDeliveryJob{webhookService: webhookService, log: log, tracer: otel.Tracer("WebhookDeliveryJob"), interval: interval, batchSize: batchSize}
+}
+
+// Start runs the job every interval in the background until Stop is called or
+// ctx is cancelled. A non-positive interval disables the job.
+func (j *WebhookDeliveryJob) Start(ctx context.Context) {
+	if j.interval <= 0 {
+		j.log.Info("webhook delivery job disabled")
+		return
+	}
+
+	ctx, j.cancel = context.WithCancel(ctx)
+	j.wg.Add(1)
+	go func() {
+		defer j.wg.Done()
+		ticker := time.NewTicker(j.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				j.Run(ctx)
+			}
+		}
+	}()
+}
+
+// Stop cancels the background loop and waits for running deliveries to finish.
+func (j *WebhookDeliveryJob) Stop() {
+	if j.cancel != nil {
+		j.cancel()
+	}
+	j.wg.Wait()
+}
+
+// Run sends due deliveries in batches until none are left.
+func (j *WebhookDeliveryJob) Run(ctx context.Context) {
+	spanCtx, span := j.tracer.Start(ctx, "WebhookDeliveryJob.Run")
+	defer span.End()
+
+	logger := j.log.WithContext(spanCtx)
+	total := 0
+	for ctx.Err() == nil {
+		attempted, err := j.webhookService.DeliverDue(spanCtx, j.batchSize)
+		total += attempted
+		if err != nil {
+			logger.WithError(err).Error("webhook delivery job failed")
+			break
+		}
+		if attempted < j.batchSize {
+			break
+		}
+	}
+	if total > 0 {
+		logger.WithField("attempted", total).Debug("webhook delivery job completed")
+	}
+}
@@ -0,0 +1,82 @@
+package job
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	miniredis "github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+
+	"go-starter-template/internal/config/env"
+	"go-starter-template/internal/repository"
+	"go-starter-template/internal/service"
+)
+
+func setupWebhookDeliveryJob(t *testing.T, batchSize int) (*WebhookDeliveryJob, *repository.WebhookQueue, sqlmock.Sqlmock) {
+	t.Helper()
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+	mr := miniredis.RunT(t)
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	// The test endpoints listen on loopback
+	cfg := &env.Config{}
+	cfg.Webhooks.AllowPrivateNetworks = true
+	queue := repository.NewWebhookQueue(redis.NewClient(&redis.Options{Addr: mr.Addr()}))
+	webhookSvc := service.NewWebhookService(repository.NewWebhookRepository(db), queue, logger, cfg, repository.NewUnitOfWork(db))
+	return NewWebhookDeliveryJob(webhookSvc, logger, time.Minute, batchSize), queue, mock
+}
+
+func TestWebhookDeliveryJob_Run(t *testing.T) {
+	received := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received++
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	job, queue, mock := setupWebhookDeliveryJob(t, 2)
+	now := time.Now()
+	for i := 1; i <= 3; i++ {
+		id := fmt.Sprintf("d%d", i)
+		require.NoError(t, queue.Schedule(context.Background(), id, now.Add(-time.Duration(4-i)*time.Second)))
+
+		mock.ExpectQuery(regexp.QuoteMeta("FROM webhook_deliveries WHERE uuid = $1")).
+			WithArgs(id).
+			WillReturnRows(sqlmock.NewRows([]string{"uuid", "subscription_uuid", "event_id", "event_type", "payload", "status", "attempts", "response_status", "last_error", "next_attempt_at", "created_at", "delivered_at"}).
+				AddRow(id, "w1", "e"+id, "user.created", []byte(`{}`), "pending", 0, 0, "", now, now, nil))
+		mock.ExpectQuery(regexp.QuoteMeta("FROM webhook_subscriptions WHERE uuid = $1")).
+			WithArgs("w1").
+			WillReturnRows(sqlmock.NewRows([]string{"uuid", "url", "description", "events", "secret", "enabled", "consecutive_failures", "disabled_reason", "created_at", "updated_at", "owner_uuid"}).
+				AddRow("w1", server.URL, "", []byte(`["*"]`), "whsec_1", true, 0, "", now, now, "u1"))
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta("SET consecutive_failures = 0")).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE webhook_deliveries")).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+	}
+
+	job.Run(context.Background())
+	require.Equal(t, 3, received)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestWebhookDeliveryJob_StartStop(t *testing.T) {
+	job, _, mock := setupWebhookDeliveryJob(t, 2)
+	job.interval = 0
+	job.Start(context.Background())
+	job.Stop()
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,16 @@
+package middleware
+
+import (
+	"go-starter-template/internal/audit"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// AuditRequest puts the client's address and user agent in the request
+// context for the audit log. AuthMiddleware adds the authenticated user.
+func AuditRequest() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		c.SetUserContext(audit.WithRequest(c.UserContext(), c.IP(), c.Get(fiber.HeaderUserAgent)))
+		return c.Next()
+	}
+}
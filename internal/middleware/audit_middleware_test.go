@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/require"
+
+	"go-starter-template/internal/audit"
+)
+
+func TestAuditRequest(t *testing.T) {
+	app := fiber.New()
+	app.Use(AuditRequest())
+	app.Get("/", func(c *fiber.Ctx) error {
+		request := audit.FromContext(c.UserContext())
+		c.Set("X-Audit-IP", request.IP)
+		c.Set("X-Audit-User-Agent", request.UserAgent)
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(fiber.HeaderUserAgent, "curl/8")
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.NotEmpty(t, resp.Header.Get("X-Audit-IP"))
+	require.Equal(t, "curl/8", resp.Header.Get("X-Audit-User-Agent"))
+}
@@ -1,6 +1,7 @@
 package middleware
 
 import (
+    "go-starter-template/internal/audit"
     "go-starter-template/internal/constant"
     "go-starter-template/internal/service"
     "go-starter-template/internal/utils/errcode"
@@ -17,7 +18,7 @@ const (
     authKey       = "auth"
 )
 
-func AuthMiddleware(jwtService *service.JwtService, blacklistService *service.BlacklistService, log *logrus.Logger) fiber.Handler {
+func AuthMiddleware(jwtService *service.JwtService, blacklistService *service.BlacklistService, authService *service.AuthService, log *logrus.Logger) fiber.Handler {
 	tracer := otel.Tracer("AuthMiddleware")
 	return func(c *fiber.Ctx) error {
 		spanCtx, span := tracer.Start(c.UserContext(), "AuthMiddleware")
@@ -58,8 +59,15 @@ func AuthMiddleware(jwtService *service.JwtService, blacklistService *service.Bl
 			return errcode.ErrTokenIsExpired
 		}
 
+		// Reject tokens of users suspended or deleted after the token was issued
+		if err := authService.CheckUserStatus(spanCtx, claims); err != nil {
+			logger.WithError(err).Warn("user is not allowed to authenticate")
+			return err
+		}
+
 		// Store claims in locals
 		c.Locals(authKey, claims)
+		c.SetUserContext(audit.WithActor(c.UserContext(), claims.UUID))
 		return c.Next()
 	}
 }
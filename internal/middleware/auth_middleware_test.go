@@ -2,18 +2,22 @@ package middleware
 
 import (
 	"context"
+	"errors"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"regexp"
 	"testing"
 	"time"
 
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
 	"github.com/gofiber/fiber/v2"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/require"
 
 	"go-starter-template/internal/config/env"
 	"go-starter-template/internal/constant"
+	"go-starter-template/internal/repository"
 	"go-starter-template/internal/service"
 	"go-starter-template/internal/utils/errcode"
 )
@@ -56,12 +60,17 @@ func (f *fakeBLRepo) IsBlacklisted(token string, tokenType constant.TokenType) (
 	return false, nil
 }
 
+func (f *fakeBLRepo) Prune(context.Context, time.Duration) (int64, error) {
+	return 0, nil
+}
+
 // TestAuthMiddleware covers error and success paths using table-driven tests
 func TestAuthMiddleware(t *testing.T) {
 	type testcase struct {
 		name         string
 		header       string
 		setupBL      func(*fakeBLRepo)
+		setupDB      func(sqlmock.Sqlmock)
 		expectStatus int
 		assert       func(*testing.T, *http.Response)
 	}
@@ -72,6 +81,17 @@ func TestAuthMiddleware(t *testing.T) {
 	f := &fakeBLRepo{}
 	blSvc := service.NewBlacklistService(logger, jwtSvc, f)
 
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	uow := repository.NewUnitOfWork(db)
+	auditSvc := service.NewAuditService(repository.NewAuditRepository(db), logger, uow)
+	authSvc := service.NewAuthService(jwtSvc, repository.NewUserRepository(db), repository.NewOutboxRepository(db), auditSvc, service.NewLoginHistoryService(repository.NewLoginEventRepository(db), nil, logger, uow), blSvc, logger, uow)
+	statusQuery := regexp.QuoteMeta(`SELECT uuid, status, status_reason, suspended_until, tokens_valid_after FROM users WHERE uuid = $1 AND deleted_at IS NULL LIMIT 1`)
+	statusRow := func(status string, until *time.Time) *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"uuid", "status", "status_reason", "suspended_until", "tokens_valid_after"}).AddRow("u123", status, "", until, nil)
+	}
+
 	// Build Fiber app with error handler mapping errcodes
 	app := fiber.New(fiber.Config{ErrorHandler: func(c *fiber.Ctx, err error) error {
 		if code, ok := errcode.GetHTTPStatus(err); ok {
@@ -81,7 +101,7 @@ func TestAuthMiddleware(t *testing.T) {
 	}})
 
 	// Protected route applying middleware
-	app.Get("/protected", AuthMiddleware(jwtSvc, blSvc, logger), func(c *fiber.Ctx) error {
+	app.Get("/protected", AuthMiddleware(jwtSvc, blSvc, authSvc, logger), func(c *fiber.Ctx) error {
 		// On success, claims should be present
 		claims := c.Locals("auth")
 		if claims == nil {
@@ -164,12 +184,62 @@ func TestAuthMiddleware(t *testing.T) {
 				require.Equal(t, fiber.StatusUnauthorized, resp.StatusCode)
 			},
 		},
+		{
+			name:   "Suspended",
+			header: "Bearer " + validToken,
+			setupDB: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(statusQuery).WithArgs("u123").WillReturnRows(statusRow("suspended", nil))
+			},
+			expectStatus: fiber.StatusForbidden,
+		},
+		{
+			name:   "SuspensionExpired",
+			header: "Bearer " + validToken,
+			setupDB: func(mock sqlmock.Sqlmock) {
+				until := time.Now().Add(-time.Minute)
+				mock.ExpectQuery(statusQuery).WithArgs("u123").WillReturnRows(statusRow("suspended", &until))
+			},
+			expectStatus: fiber.StatusOK,
+		},
+		{
+			name:   "UserDeleted",
+			header: "Bearer " + validToken,
+			setupDB: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(statusQuery).WithArgs("u123").WillReturnError(errors.New("no rows"))
+			},
+			expectStatus: fiber.StatusUnauthorized,
+		},
+		{
+			// A session from before the account was deleted stays revoked
+			// once the account is restored
+			name:   "RevokedBeforeRestore",
+			header: "Bearer " + validToken,
+			setupDB: func(mock sqlmock.Sqlmock) {
+				revokedAt := time.Now()
+				mock.ExpectQuery(statusQuery).WithArgs("u123").
+					WillReturnRows(sqlmock.NewRows([]string{"uuid", "status", "status_reason", "suspended_until", "tokens_valid_after"}).AddRow("u123", "active", "", nil, revokedAt))
+			},
+			expectStatus: fiber.StatusUnauthorized,
+		},
+		{
+			name:   "IssuedAfterRevocation",
+			header: "Bearer " + validToken,
+			setupDB: func(mock sqlmock.Sqlmock) {
+				revokedAt := time.Now().Add(-time.Hour)
+				mock.ExpectQuery(statusQuery).WithArgs("u123").
+					WillReturnRows(sqlmock.NewRows([]string{"uuid", "status", "status_reason", "suspended_until", "tokens_valid_after"}).AddRow("u123", "active", "", nil, revokedAt))
+			},
+			expectStatus: fiber.StatusOK,
+		},
 		{
 			name:   "Success",
 			header: "Bearer " + validToken,
 			setupBL: func(f *fakeBLRepo) {
 				f.isBlacklisted = func(_ string, _ constant.TokenType) (bool, error) { return false, nil }
 			},
+			setupDB: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(statusQuery).WithArgs("u123").WillReturnRows(statusRow("active", nil))
+			},
 			expectStatus: fiber.StatusOK,
 			assert: func(t *testing.T, resp *http.Response) {
 				require.Equal(t, fiber.StatusOK, resp.StatusCode)
@@ -184,6 +254,9 @@ func TestAuthMiddleware(t *testing.T) {
 			if tc.setupBL != nil {
 				tc.setupBL(f)
 			}
+			if tc.setupDB != nil {
+				tc.setupDB(mock)
+			}
 
 			req := httptest.NewRequest(http.MethodGet, "/protected", nil)
 			if tc.header != "" {
@@ -196,6 +269,7 @@ func TestAuthMiddleware(t *testing.T) {
 			if tc.assert != nil {
 				tc.assert(t, resp)
 			}
+			require.NoError(t, mock.ExpectationsWereMet())
 		})
 	}
 }
@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"strings"
+
+	"go-starter-template/internal/dto"
+	"go-starter-template/internal/service"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Authorize returns a factory of handlers that only let a request through if
+// the policies allow the authenticated caller to perform action. The resource
+// type is the part of action before the colon, e.g. "user" for "user:restore".
+// Use after AuthMiddleware.
+func Authorize(policyService *service.PolicyService) func(action string) fiber.Handler {
+	return func(action string) fiber.Handler {
+		resourceType, _, _ := strings.Cut(action, ":")
+		return func(c *fiber.Ctx) error {
+			err := policyService.Authorize(c.UserContext(), GetUser(c).UUID, action, dto.AuthzResource{Type: resourceType}, RequestAttributes(c))
+			if err != nil {
+				return err
+			}
+			return c.Next()
+		}
+	}
+}
+
+// UnlessSelf runs guard only when the path parameter param names someone
+// other than the authenticated caller, so users can always act on themselves.
+// Use after AuthMiddleware.
+func UnlessSelf(param string, guard fiber.Handler) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if c.Params(param) == GetUser(c).UUID {
+			return c.Next()
+		}
+		return guard(c)
+	}
+}
+
+// RequestAttributes returns the attributes of the request policies see under
+// request.
+func RequestAttributes(c *fiber.Ctx) map[string]interface{} {
+	return map[string]interface{}{
+		"ip":     c.IP(),
+		"method": c.Method(),
+		"path":   c.Path(),
+	}
+}
@@ -0,0 +1,117 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/require"
+
+	"go-starter-template/internal/repository"
+	"go-starter-template/internal/service"
+	"go-starter-template/internal/utils/errcode"
+)
+
+// expectCaller mocks the queries loading the caller with role, then the
+// policies of action.
+func expectCaller(mock sqlmock.Sqlmock, role, action string) {
+	now := time.Now()
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM users WHERE uuid = $1`)).WithArgs("caller").
+		WillReturnRows(sqlmock.NewRows([]string{"uuid", "name", "email", "password", "created_at", "updated_at", "status", "status_reason", "suspended_until", "phone", "version"}).
+			AddRow("caller", "Caller", "caller@example.com", "hash", now, now, "active", "", nil, nil, 1))
+	mock.ExpectQuery(regexp.QuoteMeta(`INNER JOIN roles r`)).WithArgs("caller").
+		WillReturnRows(sqlmock.NewRows([]string{"user_uuid", "uuid", "name"}).AddRow("caller", "r1", role))
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM user_permissions up`)).WithArgs("caller").
+		WillReturnRows(sqlmock.NewRows([]string{"uuid", "name"}))
+	mock.ExpectQuery(regexp.QuoteMeta(`INNER JOIN role_permissions rp`)).WithArgs("caller").
+		WillReturnRows(sqlmock.NewRows([]string{"role_uuid", "uuid", "name"}))
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM policies`)).WithArgs(action, "*").
+		WillReturnRows(sqlmock.NewRows([]string{"uuid", "name", "description", "action", "effect", "condition", "priority", "enabled", "created_at", "updated_at"}).
+			AddRow("p1", "admins-allow-all", "", "*", "allow", `"admin" in subject.roles && request.method == "POST"`, 0, true, now, now))
+}
+
+func TestAuthorize(t *testing.T) {
+	cases := []struct {
+		name         string
+		role         string
+		expectStatus int
+	}{
+		{name: "Allowed", role: "admin", expectStatus: http.StatusNoContent},
+		{name: "Forbidden", role: "user", expectStatus: http.StatusForbidden},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherRegexp))
+			require.NoError(t, err)
+			defer db.Close()
+			policyService := service.NewPolicyService(repository.NewPolicyRepository(db), repository.NewUserRepository(db), testLogger())
+			expectCaller(mock, tc.role, "user:restore")
+
+			app := fiber.New(fiber.Config{ErrorHandler: func(c *fiber.Ctx, err error) error {
+				if code, ok := errcode.GetHTTPStatus(err); ok {
+					return c.SendStatus(code)
+				}
+				return c.SendStatus(fiber.StatusInternalServerError)
+			}})
+			app.Post("/", func(c *fiber.Ctx) error {
+				c.Locals(authKey, &service.Claims{UUID: "caller"})
+				return c.Next()
+			}, Authorize(policyService)("user:restore"), func(c *fiber.Ctx) error {
+				return c.SendStatus(http.StatusNoContent)
+			})
+
+			resp, err := app.Test(httptest.NewRequest(http.MethodPost, "/", nil))
+			require.NoError(t, err)
+			require.Equal(t, tc.expectStatus, resp.StatusCode)
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestUnlessSelf(t *testing.T) {
+	cases := []struct {
+		name         string
+		target       string
+		role         string
+		expectStatus int
+	}{
+		{name: "Self", target: "caller", expectStatus: http.StatusNoContent},
+		{name: "OtherAllowed", target: "other", role: "admin", expectStatus: http.StatusNoContent},
+		{name: "OtherForbidden", target: "other", role: "user", expectStatus: http.StatusForbidden},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherRegexp))
+			require.NoError(t, err)
+			defer db.Close()
+			policyService := service.NewPolicyService(repository.NewPolicyRepository(db), repository.NewUserRepository(db), testLogger())
+			if tc.role != "" {
+				expectCaller(mock, tc.role, "user:read_permissions")
+			}
+
+			app := fiber.New(fiber.Config{ErrorHandler: func(c *fiber.Ctx, err error) error {
+				if code, ok := errcode.GetHTTPStatus(err); ok {
+					return c.SendStatus(code)
+				}
+				return c.SendStatus(fiber.StatusInternalServerError)
+			}})
+			app.Post("/users/:uuid", func(c *fiber.Ctx) error {
+				c.Locals(authKey, &service.Claims{UUID: "caller"})
+				return c.Next()
+			}, UnlessSelf("uuid", Authorize(policyService)("user:read_permissions")), func(c *fiber.Ctx) error {
+				return c.SendStatus(http.StatusNoContent)
+			})
+
+			resp, err := app.Test(httptest.NewRequest(http.MethodPost, "/users/"+tc.target, nil))
+			require.NoError(t, err)
+			require.Equal(t, tc.expectStatus, resp.StatusCode)
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
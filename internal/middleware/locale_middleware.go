@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"strconv"
+	"strings"
+
+	"go-starter-template/internal/notify"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Locale puts the client's preferred language from Accept-Language in the
+// request context, so notifications sent while handling the request are
+// rendered in it.
+func Locale() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if locale := preferredLocale(c.Get(fiber.HeaderAcceptLanguage)); locale != "" {
+			c.SetUserContext(notify.WithLocale(c.UserContext(), locale))
+		}
+		return c.Next()
+	}
+}
+
+// preferredLocale returns the tag with the highest quality in an
+// Accept-Language header, the first one on a tie, or "" if there is none.
+func preferredLocale(header string) string {
+	var best string
+	bestQuality := 0.0
+	for _, item := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(item), ";")
+		tag = strings.TrimSpace(tag)
+		if tag == "" || tag == "*" {
+			continue
+		}
+		quality := 1.0
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(q, 64)
+			if err != nil {
+				continue
+			}
+			quality = parsed
+		}
+		if quality > bestQuality {
+			best, bestQuality = tag, quality
+		}
+	}
+	return best
+}
@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/require"
+
+	"go-starter-template/internal/notify"
+)
+
+func TestLocale(t *testing.T) {
+	app := fiber.New()
+	app.Use(Locale())
+	app.Get("/", func(c *fiber.Ctx) error {
+		return c.SendString(notify.LocaleFromContext(c.UserContext()))
+	})
+
+	for header, want := range map[string]string{
+		"":                              "",
+		"de":                            "de",
+		"de-DE,de;q=0.9,en;q=0.8":       "de-DE",
+		"en;q=0.5, pt-BR;q=0.8, fr;q=0": "pt-BR",
+		"*, fr;q=0.7":                   "fr",
+		"es;q=abc":                      "",
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if header != "" {
+			req.Header.Set(fiber.HeaderAcceptLanguage, header)
+		}
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.Equal(t, want, string(body), header)
+	}
+}
@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"time"
+
+	"go-starter-template/internal/repository"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// PrimaryCookie marks a client that wrote recently; its reads go to the
+// primary database until the cookie expires.
+const PrimaryCookie = "db_primary"
+
+// ReadYourWrites routes the reads of every request that may write (any
+// method but GET, HEAD and OPTIONS) to the primary, so checks such as
+// optimistic locking never act on replica lag. After a successful write it
+// sets PrimaryCookie for sticky, keeping the client's following reads on the
+// primary until replicas have caught up; a zero sticky skips the cookie.
+func ReadYourWrites(sticky time.Duration) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		write := c.Method() != fiber.MethodGet && c.Method() != fiber.MethodHead && c.Method() != fiber.MethodOptions
+		if write || c.Cookies(PrimaryCookie) != "" {
+			c.SetUserContext(repository.WithPrimary(c.UserContext()))
+		}
+
+		err := c.Next()
+		if write && sticky > 0 && err == nil && c.Response().StatusCode() < fiber.StatusBadRequest {
+			c.Cookie(&fiber.Cookie{
+				Name:     PrimaryCookie,
+				Value:    "1",
+				Path:     "/",
+				MaxAge:   max(int(sticky.Seconds()), 1),
+				HTTPOnly: true,
+				SameSite: fiber.CookieSameSiteLaxMode,
+			})
+		}
+		return err
+	}
+}
@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/require"
+
+	"go-starter-template/internal/repository"
+)
+
+func TestReadYourWrites(t *testing.T) {
+	newApp := func(sticky time.Duration) *fiber.App {
+		app := fiber.New()
+		app.Use(ReadYourWrites(sticky))
+		handler := func(c *fiber.Ctx) error {
+			if c.Query("fail") != "" {
+				return c.SendStatus(fiber.StatusConflict)
+			}
+			primary := "no"
+			if repository.UsesPrimary(c.UserContext()) {
+				primary = "yes"
+			}
+			c.Set("X-Primary", primary)
+			return c.SendStatus(fiber.StatusOK)
+		}
+		app.Get("/", handler)
+		app.Patch("/", handler)
+		return app
+	}
+
+	type testcase struct {
+		name          string
+		sticky        time.Duration
+		method        string
+		target        string
+		cookie        bool
+		expectPrimary string
+		expectCookie  bool
+	}
+
+	cases := []testcase{
+		{name: "ReadUsesReplica", sticky: 5 * time.Second, method: http.MethodGet, target: "/", expectPrimary: "no"},
+		{name: "WriteUsesPrimaryAndSetsCookie", sticky: 5 * time.Second, method: http.MethodPatch, target: "/", expectPrimary: "yes", expectCookie: true},
+		{name: "ReadAfterWriteUsesPrimary", sticky: 5 * time.Second, method: http.MethodGet, target: "/", cookie: true, expectPrimary: "yes"},
+		{name: "FailedWriteSetsNoCookie", sticky: 5 * time.Second, method: http.MethodPatch, target: "/?fail=1"},
+		{name: "StickyDisabled", method: http.MethodPatch, target: "/", expectPrimary: "yes"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.target, nil)
+			if tc.cookie {
+				req.AddCookie(&http.Cookie{Name: PrimaryCookie, Value: "1"})
+			}
+			resp, err := newApp(tc.sticky).Test(req)
+			require.NoError(t, err)
+			require.Equal(t, tc.expectPrimary, resp.Header.Get("X-Primary"))
+
+			var sticky *http.Cookie
+			for _, cookie := range resp.Cookies() {
+				if cookie.Name == PrimaryCookie {
+					sticky = cookie
+				}
+			}
+			if !tc.expectCookie {
+				require.Nil(t, sticky)
+				return
+			}
+			require.NotNil(t, sticky)
+			require.Equal(t, int(tc.sticky.Seconds()), sticky.MaxAge)
+			require.True(t, sticky.HttpOnly)
+		})
+	}
+}
@@ -0,0 +1,24 @@
+package model
+
+import (
+    "time"
+)
+
+// AuditEvent records who did what to which target. Rows are only ever
+// appended; Hash chains each one to the row before it.
+type AuditEvent struct {
+    ID         int64     `json:"id"`
+    UUID       string    `json:"uuid"`
+    ActorUUID  string    `json:"actor_uuid"`
+    Action     string    `json:"action"`
+    TargetType string    `json:"target_type"`
+    TargetID   string    `json:"target_id"`
+    IP         string    `json:"ip"`
+    UserAgent  string    `json:"user_agent"`
+    TraceID    string    `json:"trace_id"`
+    Changes    []byte    `json:"changes"`
+    Metadata   []byte    `json:"metadata"`
+    CreatedAt  time.Time `json:"created_at"`
+    PrevHash   string    `json:"prev_hash"`
+    Hash       string    `json:"hash"`
+}
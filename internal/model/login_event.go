@@ -0,0 +1,23 @@
+package model
+
+import (
+    "time"
+)
+
+// LoginEvent is one login attempt to an existing account.
+type LoginEvent struct {
+    ID            int64     `json:"id"`
+    UUID          string    `json:"uuid"`
+    UserUUID      string    `json:"user_uuid"`
+    Success       bool      `json:"success"`
+    FailureReason string    `json:"failure_reason"`
+    IP            string    `json:"ip"`
+    UserAgent     string    `json:"user_agent"`
+    Device        string    `json:"device"`
+    Country       string    `json:"country"`
+    Region        string    `json:"region"`
+    City          string    `json:"city"`
+    NewDevice     bool      `json:"new_device"`
+    NewCountry    bool      `json:"new_country"`
+    CreatedAt     time.Time `json:"created_at"`
+}
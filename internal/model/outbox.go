@@ -0,0 +1,20 @@
+package model
+
+import (
+    "time"
+)
+
+// OutboxEvent is a domain event stored in the outbox table until the relay
+// has published it.
+type OutboxEvent struct {
+    ID            int64      `json:"id"`
+    UUID          string     `json:"uuid"`
+    AggregateType string     `json:"aggregate_type"`
+    AggregateID   string     `json:"aggregate_id"`
+    EventType     string     `json:"event_type"`
+    Payload       []byte     `json:"payload"`
+    CreatedAt     time.Time  `json:"created_at"`
+    PublishedAt   *time.Time `json:"published_at"`
+    Attempts      int        `json:"attempts"`
+    LastError     string     `json:"last_error"`
+}
@@ -0,0 +1,18 @@
+package model
+
+import (
+    "time"
+)
+
+type Policy struct {
+    UUID        string    `json:"uuid"`
+    Name        string    `json:"name"`
+    Description string    `json:"description"`
+    Action      string    `json:"action"`
+    Effect      string    `json:"effect"`
+    Condition   string    `json:"condition"`
+    Priority    int       `json:"priority"`
+    Enabled     bool      `json:"enabled"`
+    CreatedAt   time.Time `json:"created_at"`
+    UpdatedAt   time.Time `json:"updated_at"`
+}
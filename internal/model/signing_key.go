@@ -0,0 +1,13 @@
+package model
+
+import (
+    "time"
+)
+
+// SigningKey is a secret signing the JWTs of one token type. Tokens name the
+// key that signed them in their kid header.
+type SigningKey struct {
+    ID        string    `json:"id"`
+    Secret    []byte    `json:"secret"`
+    CreatedAt time.Time `json:"created_at"`
+}
@@ -5,13 +5,21 @@ import (
 )
 
 type User struct {
-    UUID        string       `json:"uuid"`
-    Email       string       `json:"email"`
-    Password    string       `json:"-,omitempty"`
-    Name        string       `json:"name"`
-    Roles       []Role       `json:"roles"`
-    Permissions []Permission `json:"permissions"`
-    CreatedAt   time.Time    `json:"created_at"`
-    UpdatedAt   time.Time    `json:"updated_at"`
-    DeletedAt   *time.Time   `json:"deleted_at"`
+    UUID           string       `json:"uuid"`
+    Email          string       `json:"email"`
+    Password       string       `json:"-,omitempty"`
+    Name           string       `json:"name"`
+    Status         string       `json:"status"`
+    StatusReason   string       `json:"status_reason"`
+    SuspendedUntil *time.Time   `json:"suspended_until"`
+    Phone          *string      `json:"phone"`
+    Version        int64        `json:"version"`
+    Roles          []Role       `json:"roles"`
+    Permissions    []Permission `json:"permissions"`
+    CreatedAt      time.Time    `json:"created_at"`
+    UpdatedAt      time.Time    `json:"updated_at"`
+    DeletedAt      *time.Time   `json:"deleted_at"`
+
+    // TokensValidAfter revokes the tokens issued before it
+    TokensValidAfter *time.Time `json:"-"`
 }
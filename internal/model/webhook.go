@@ -0,0 +1,40 @@
+package model
+
+import (
+    "time"
+)
+
+// WebhookSubscription is a partner endpoint notified of the events it lists.
+type WebhookSubscription struct {
+    UUID        string   `json:"uuid"`
+    // OwnerUUID is the user who created the subscription, empty for those
+    // created before subscriptions had owners
+    OwnerUUID   string   `json:"owner_uuid"`
+    URL         string   `json:"url"`
+    Description string   `json:"description"`
+    Events      []string `json:"events"`
+    // Secret signs every delivery so the receiver can verify it
+    Secret  string `json:"-"`
+    Enabled bool   `json:"enabled"`
+    // ConsecutiveFailures counts failed attempts since the last success
+    ConsecutiveFailures int       `json:"consecutive_failures"`
+    DisabledReason      string    `json:"disabled_reason"`
+    CreatedAt           time.Time `json:"created_at"`
+    UpdatedAt           time.Time `json:"updated_at"`
+}
+
+// WebhookDelivery is one event sent, or to be sent, to a subscription.
+type WebhookDelivery struct {
+    UUID             string     `json:"uuid"`
+    SubscriptionUUID string     `json:"subscription_uuid"`
+    EventID          string     `json:"event_id"`
+    EventType        string     `json:"event_type"`
+    Payload          []byte     `json:"payload"`
+    Status           string     `json:"status"`
+    Attempts         int        `json:"attempts"`
+    ResponseStatus   int        `json:"response_status"`
+    LastError        string     `json:"last_error"`
+    NextAttemptAt    *time.Time `json:"next_attempt_at"`
+    CreatedAt        time.Time  `json:"created_at"`
+    DeliveredAt      *time.Time `json:"delivered_at"`
+}
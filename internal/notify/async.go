@@ -0,0 +1,100 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	// ErrQueueFull is returned when more messages wait than the queue holds.
+	ErrQueueFull = errors.New("notification queue is full")
+	// ErrStopped is returned for messages handed to a stopped Async.
+	ErrStopped = errors.New("notifier is stopped")
+)
+
+// Async hands messages to a pool of goroutines that send them through next,
+// so Notify returns without waiting for a mail server. Messages still queued
+// when the process dies are lost; use Queued where that matters.
+type Async struct {
+	next    Notifier
+	log     *logrus.Logger
+	workers int
+	queue   chan asyncMessage
+
+	mu      sync.RWMutex
+	stopped bool
+	wg      sync.WaitGroup
+}
+
+type asyncMessage struct {
+	ctx context.Context
+	msg Message
+}
+
+// NewAsync returns an Async running workers goroutines, with room for
+// queueSize waiting messages.
+func NewAsync(next Notifier, log *logrus.Logger, workers, queueSize int) *Async {
+	if workers <= 0 {
+		workers = 1
+	}
+	return &Async{next: next, log: log, workers: workers, queue: make(chan asyncMessage, queueSize)}
+}
+
+// Notify queues msg and returns right away. Delivery errors are logged, not
+// returned.
+func (a *Async) Notify(ctx context.Context, msg Message) error {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if a.stopped {
+		return ErrStopped
+	}
+	// The message outlives the request, but keeps its trace and locale
+	item := asyncMessage{ctx: context.WithoutCancel(ctx), msg: withLocale(ctx, msg)}
+	select {
+	case a.queue <- item:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Start runs the workers until Stop is called.
+func (a *Async) Start() {
+	for i := 0; i < a.workers; i++ {
+		a.wg.Add(1)
+		go func() {
+			defer a.wg.Done()
+			for item := range a.queue {
+				a.send(item)
+			}
+		}()
+	}
+	a.log.WithField("workers", a.workers).Info("async notifier started")
+}
+
+// Stop rejects new messages and waits until the queued ones are sent.
+func (a *Async) Stop() {
+	a.mu.Lock()
+	if a.stopped {
+		a.mu.Unlock()
+		return
+	}
+	a.stopped = true
+	close(a.queue)
+	a.mu.Unlock()
+
+	a.wg.Wait()
+	a.log.Info("async notifier stopped")
+}
+
+func (a *Async) send(item asyncMessage) {
+	if err := a.next.Notify(item.ctx, item.msg); err != nil {
+		a.log.WithContext(item.ctx).WithError(err).WithFields(logrus.Fields{
+			"channel":  item.msg.Channel,
+			"template": item.msg.Template,
+		}).Error("Failed to send notification")
+	}
+}
@@ -0,0 +1,82 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+func testLogger() *logrus.Logger {
+	l := logrus.New()
+	l.SetOutput(io.Discard)
+	return l
+}
+
+// blockingNotifier records messages, waiting for release before each one.
+type blockingNotifier struct {
+	mu       sync.Mutex
+	release  chan struct{}
+	messages []Message
+	ctxErrs  []error
+}
+
+func (n *blockingNotifier) Notify(ctx context.Context, msg Message) error {
+	<-n.release
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.messages = append(n.messages, msg)
+	n.ctxErrs = append(n.ctxErrs, ctx.Err())
+	return errors.New("logged, not returned")
+}
+
+func TestAsync(t *testing.T) {
+	t.Run("DeliversInBackground", func(t *testing.T) {
+		next := &blockingNotifier{release: make(chan struct{})}
+		async := NewAsync(next, testLogger(), 1, 10)
+		async.Start()
+
+		// The request ends before the message is sent
+		ctx, cancel := context.WithCancel(WithLocale(context.Background(), "de"))
+		require.NoError(t, async.Notify(ctx, Message{Channel: ChannelEmail, To: "a@example.com"}))
+		cancel()
+		close(next.release)
+		async.Stop()
+
+		require.Len(t, next.messages, 1)
+		require.Equal(t, "de", next.messages[0].Locale)
+		require.NoError(t, next.ctxErrs[0])
+	})
+
+	t.Run("QueueFull", func(t *testing.T) {
+		next := &blockingNotifier{release: make(chan struct{})}
+		async := NewAsync(next, testLogger(), 1, 1)
+
+		require.NoError(t, async.Notify(context.Background(), Message{To: "1"}))
+		require.ErrorIs(t, async.Notify(context.Background(), Message{To: "2"}), ErrQueueFull)
+
+		async.Start()
+		close(next.release)
+		async.Stop()
+		require.Len(t, next.messages, 1)
+	})
+
+	t.Run("StopDrainsQueue", func(t *testing.T) {
+		next := &blockingNotifier{release: make(chan struct{})}
+		close(next.release)
+		async := NewAsync(next, testLogger(), 2, 10)
+		for _, to := range []string{"1", "2", "3"} {
+			require.NoError(t, async.Notify(context.Background(), Message{To: to}))
+		}
+		async.Start()
+		async.Stop()
+
+		require.Len(t, next.messages, 3)
+		require.ErrorIs(t, async.Notify(context.Background(), Message{}), ErrStopped)
+		async.Stop()
+	})
+}
@@ -0,0 +1,74 @@
+package notify
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"go-starter-template/internal/config/env"
+)
+
+// New returns a Dispatcher rendering the embedded templates and sending
+// through the transports chosen under notify in config.
+func New(config *env.Config) (*Dispatcher, error) {
+	templates, err := EmbeddedTemplates(config.GetNotifyDefaultLocale())
+	if err != nil {
+		return nil, err
+	}
+
+	transports := map[Channel]Transport{}
+	for channel, name := range map[Channel]string{
+		ChannelEmail: config.GetNotifyEmailTransport(),
+		ChannelSMS:   config.GetNotifySMSTransport(),
+	} {
+		transport, err := newTransport(config, channel, name)
+		if err != nil {
+			return nil, err
+		}
+		if transport != nil {
+			transports[channel] = transport
+		}
+	}
+	return NewDispatcher(templates, transports), nil
+}
+
+// newTransport returns the transport called name for channel, or nil for
+// none.
+func newTransport(config *env.Config, channel Channel, name string) (Transport, error) {
+	switch name {
+	case "smtp":
+		if channel != ChannelEmail {
+			return nil, fmt.Errorf("notify: the smtp transport only sends email, not %s", channel)
+		}
+		if config.Notify.SMTP.Host == "" || config.Notify.SMTP.From == "" {
+			return nil, fmt.Errorf("notify: notify.smtp.host and notify.smtp.from are required for the smtp transport")
+		}
+		return NewSMTPTransport(SMTPOptions{
+			Host:     config.Notify.SMTP.Host,
+			Port:     config.GetNotifySMTPPort(),
+			Username: config.Notify.SMTP.Username,
+			Password: config.Notify.SMTP.Password,
+			From:     config.Notify.SMTP.From,
+			Timeout:  config.GetNotifySMTPTimeout(),
+		}), nil
+	case "console":
+		var w io.Writer = os.Stdout
+		if path := config.Notify.Console.Path; path != "" {
+			file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+			if err != nil {
+				return nil, fmt.Errorf("notify: %w", err)
+			}
+			w = file
+		}
+		return NewConsoleTransport(w), nil
+	case "webhook":
+		if config.Notify.Webhook.URL == "" {
+			return nil, fmt.Errorf("notify: notify.webhook.url is required for the webhook transport")
+		}
+		return NewWebhookTransport(config.Notify.Webhook.URL, config.Notify.Webhook.Secret, config.GetNotifyWebhookTimeout()), nil
+	case "none":
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("notify: unknown %s transport %q", channel, name)
+	}
+}
@@ -0,0 +1,65 @@
+package notify
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"go-starter-template/internal/config/env"
+)
+
+func TestNew(t *testing.T) {
+	t.Run("Defaults", func(t *testing.T) {
+		dispatcher, err := New(&env.Config{})
+		require.NoError(t, err)
+		require.IsType(t, &ConsoleTransport{}, dispatcher.transports[ChannelEmail])
+		require.NotContains(t, dispatcher.transports, ChannelSMS)
+	})
+
+	t.Run("Transports", func(t *testing.T) {
+		config := &env.Config{}
+		config.Notify.Email.Transport = "smtp"
+		config.Notify.SMTP.Host = "localhost"
+		config.Notify.SMTP.From = "noreply@example.com"
+		config.Notify.SMS.Transport = "webhook"
+		config.Notify.Webhook.URL = "http://localhost/sms"
+
+		dispatcher, err := New(config)
+		require.NoError(t, err)
+		require.IsType(t, &SMTPTransport{}, dispatcher.transports[ChannelEmail])
+		require.IsType(t, &WebhookTransport{}, dispatcher.transports[ChannelSMS])
+	})
+
+	t.Run("ConsoleFile", func(t *testing.T) {
+		config := &env.Config{}
+		config.Notify.Console.Path = filepath.Join(t.TempDir(), "mail.log")
+		dispatcher, err := New(config)
+		require.NoError(t, err)
+
+		require.NoError(t, dispatcher.Notify(t.Context(), Message{Channel: ChannelEmail, To: "a@example.com", Template: TemplateEmailVerification, Data: verificationData}))
+		content, err := os.ReadFile(config.Notify.Console.Path)
+		require.NoError(t, err)
+		require.Contains(t, string(content), "abc123")
+	})
+
+	cases := []struct {
+		name      string
+		configure func(*env.Config)
+		expectErr string
+	}{
+		{"SMTPWithoutHost", func(c *env.Config) { c.Notify.Email.Transport = "smtp" }, "notify.smtp.host"},
+		{"SMTPForSMS", func(c *env.Config) { c.Notify.SMS.Transport = "smtp" }, "only sends email"},
+		{"WebhookWithoutURL", func(c *env.Config) { c.Notify.Email.Transport = "webhook" }, "notify.webhook.url"},
+		{"UnknownTransport", func(c *env.Config) { c.Notify.Email.Transport = "pigeon" }, `unknown email transport "pigeon"`},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			config := &env.Config{}
+			tc.configure(config)
+			_, err := New(config)
+			require.ErrorContains(t, err, tc.expectErr)
+		})
+	}
+}
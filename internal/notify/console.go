@@ -0,0 +1,35 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// ConsoleTransport writes messages to w, such as stdout or a file, for
+// development and tests. It prints the text body only.
+type ConsoleTransport struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func NewConsoleTransport(w io.Writer) *ConsoleTransport {
+	return &ConsoleTransport{w: w}
+}
+
+func (t *ConsoleTransport) Send(_ context.Context, envelope *Envelope) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "----- %s to %s (%s, %s) -----\n", envelope.Channel, envelope.To, envelope.Template, envelope.Locale)
+	if envelope.Subject != "" {
+		fmt.Fprintf(&b, "Subject: %s\n\n", envelope.Subject)
+	}
+	b.WriteString(strings.TrimRight(envelope.Text, "\n"))
+	b.WriteString("\n\n")
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	_, err := io.WriteString(t.w, b.String())
+	return err
+}
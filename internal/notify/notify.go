@@ -0,0 +1,158 @@
+// Package notify sends templated messages to users. A Message names a
+// template, a recipient and a channel; the Dispatcher renders it in the
+// recipient's locale from the embedded templates and hands the result to
+// the Transport configured for that channel (SMTP, console/file or a
+// webhook). Async and Queued deliver in the background so callers, such as
+// request handlers, never wait for a mail server.
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Channel is the medium a message is delivered through.
+type Channel string
+
+const (
+	ChannelEmail Channel = "email"
+	ChannelSMS   Channel = "sms"
+)
+
+// Templates available in the embedded templates directory.
+const (
+	TemplateEmailVerification = "email_verification"
+	TemplateNewLogin          = "new_login"
+)
+
+// ErrChannelDisabled is returned for messages on a channel without transport.
+var ErrChannelDisabled = errors.New("notification channel is disabled")
+
+// Message asks for a templated notification to one recipient. It is JSON
+// encoded when delivered through the job queue, so Data should hold plain
+// values.
+type Message struct {
+	Channel  Channel `json:"channel"`
+	To       string  `json:"to"`
+	Template string  `json:"template"`
+	// Locale is a language tag such as "de" or "pt-BR"; empty uses the locale
+	// of the request in ctx, then the default locale
+	Locale string         `json:"locale,omitempty"`
+	Data   map[string]any `json:"data,omitempty"`
+}
+
+// Envelope is a rendered Message as handed to a Transport.
+type Envelope struct {
+	Channel  Channel `json:"channel"`
+	To       string  `json:"to"`
+	Template string  `json:"template"`
+	Locale   string  `json:"locale"`
+	Subject  string  `json:"subject,omitempty"`
+	Text     string  `json:"text"`
+	// HTML is the alternative body of emails whose template has one
+	HTML string `json:"html,omitempty"`
+}
+
+// Notifier sends messages.
+type Notifier interface {
+	Notify(ctx context.Context, msg Message) error
+}
+
+// Transport delivers rendered messages.
+type Transport interface {
+	Send(ctx context.Context, envelope *Envelope) error
+}
+
+type localeKey struct{}
+
+// WithLocale returns ctx carrying the locale of the client's request.
+func WithLocale(ctx context.Context, locale string) context.Context {
+	return context.WithValue(ctx, localeKey{}, locale)
+}
+
+// LocaleFromContext returns the locale set by WithLocale, or "".
+func LocaleFromContext(ctx context.Context) string {
+	locale, _ := ctx.Value(localeKey{}).(string)
+	return locale
+}
+
+// withLocale fills in the locale of msg from ctx. Background delivery loses
+// the request, so it is resolved before a message is handed off.
+func withLocale(ctx context.Context, msg Message) Message {
+	if msg.Locale == "" {
+		msg.Locale = LocaleFromContext(ctx)
+	}
+	return msg
+}
+
+// Dispatcher renders messages and sends them right away through the
+// transport of their channel.
+type Dispatcher struct {
+	templates  *Templates
+	transports map[Channel]Transport
+	tracer     trace.Tracer
+}
+
+// NewDispatcher returns a Dispatcher. Channels missing from transports are
+// disabled.
+func NewDispatcher(templates *Templates, transports map[Channel]Transport) *Dispatcher {
+	return &Dispatcher{templates: templates, transports: transports, tracer: otel.Tracer("Notifier")}
+}
+
+func (d *Dispatcher) Notify(ctx context.Context, msg Message) error {
+	msg = withLocale(ctx, msg)
+	spanCtx, span := d.tracer.Start(ctx, "Notifier.Notify "+msg.Template, trace.WithAttributes(
+		attribute.String("notify.channel", string(msg.Channel)),
+		attribute.String("notify.template", msg.Template),
+		attribute.String("notify.locale", msg.Locale),
+	))
+	defer span.End()
+
+	fail := func(err error, description string) error {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, description)
+		return err
+	}
+
+	transport, ok := d.transports[msg.Channel]
+	if !ok {
+		return fail(fmt.Errorf("%w: %s", ErrChannelDisabled, msg.Channel), "channel disabled")
+	}
+	envelope, err := d.templates.Render(msg)
+	if err != nil {
+		return fail(err, "render failed")
+	}
+	// The locale actually used after falling back
+	span.SetAttributes(attribute.String("notify.rendered_locale", envelope.Locale))
+	if err := transport.Send(spanCtx, envelope); err != nil {
+		return fail(err, "send failed")
+	}
+	return nil
+}
+
+// LogNotifier only logs messages, at debug level so tokens in their data
+// stay out of production logs. It stands in until a real notifier is set.
+type LogNotifier struct {
+	log *logrus.Logger
+}
+
+func NewLogNotifier(log *logrus.Logger) *LogNotifier {
+	return &LogNotifier{log: log}
+}
+
+func (n *LogNotifier) Notify(ctx context.Context, msg Message) error {
+	n.log.WithContext(ctx).WithFields(logrus.Fields{
+		"channel":  msg.Channel,
+		"to":       msg.To,
+		"template": msg.Template,
+		"data":     msg.Data,
+	}).Debug("notification not sent, no notifier configured")
+	return nil
+}
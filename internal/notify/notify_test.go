@@ -0,0 +1,109 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// recordingTransport remembers the envelopes it was asked to send.
+type recordingTransport struct {
+	envelopes []*Envelope
+	err       error
+}
+
+func (t *recordingTransport) Send(_ context.Context, envelope *Envelope) error {
+	t.envelopes = append(t.envelopes, envelope)
+	return t.err
+}
+
+var verificationData = map[string]any{"Name": "Alice", "Token": "abc123", "ExpiresInHours": 24}
+
+func setupDispatcher(t *testing.T) (*Dispatcher, *recordingTransport, *tracetest.SpanRecorder) {
+	t.Helper()
+	templates, err := EmbeddedTemplates("en")
+	require.NoError(t, err)
+	transport := new(recordingTransport)
+	dispatcher := NewDispatcher(templates, map[Channel]Transport{ChannelEmail: transport})
+	recorder := tracetest.NewSpanRecorder()
+	dispatcher.tracer = sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+	return dispatcher, transport, recorder
+}
+
+func TestDispatcher_Notify(t *testing.T) {
+	t.Run("RendersInRequestLocale", func(t *testing.T) {
+		dispatcher, transport, recorder := setupDispatcher(t)
+		ctx := WithLocale(context.Background(), "de-CH")
+
+		err := dispatcher.Notify(ctx, Message{Channel: ChannelEmail, To: "a@example.com", Template: TemplateEmailVerification, Data: verificationData})
+		require.NoError(t, err)
+		require.Len(t, transport.envelopes, 1)
+		require.Equal(t, "a@example.com", transport.envelopes[0].To)
+		require.Equal(t, "de", transport.envelopes[0].Locale)
+
+		spans := recorder.Ended()
+		require.Len(t, spans, 1)
+		require.Equal(t, "Notifier.Notify email_verification", spans[0].Name())
+		require.Contains(t, spans[0].Attributes(), attribute.String("notify.locale", "de-CH"))
+		require.Contains(t, spans[0].Attributes(), attribute.String("notify.rendered_locale", "de"))
+	})
+
+	t.Run("MessageLocaleWins", func(t *testing.T) {
+		dispatcher, transport, _ := setupDispatcher(t)
+		ctx := WithLocale(context.Background(), "de")
+
+		err := dispatcher.Notify(ctx, Message{Channel: ChannelEmail, Template: TemplateEmailVerification, Locale: "en", Data: verificationData})
+		require.NoError(t, err)
+		require.Equal(t, "en", transport.envelopes[0].Locale)
+	})
+
+	t.Run("ChannelDisabled", func(t *testing.T) {
+		dispatcher, transport, recorder := setupDispatcher(t)
+
+		err := dispatcher.Notify(context.Background(), Message{Channel: ChannelSMS, Template: TemplateEmailVerification, Data: verificationData})
+		require.ErrorIs(t, err, ErrChannelDisabled)
+		require.Empty(t, transport.envelopes)
+		require.Equal(t, codes.Error, recorder.Ended()[0].Status().Code)
+	})
+
+	t.Run("SendFails", func(t *testing.T) {
+		dispatcher, transport, recorder := setupDispatcher(t)
+		transport.err = errors.New("connection refused")
+
+		err := dispatcher.Notify(context.Background(), Message{Channel: ChannelEmail, Template: TemplateEmailVerification, Data: verificationData})
+		require.ErrorIs(t, err, transport.err)
+		require.Equal(t, "send failed", recorder.Ended()[0].Status().Description)
+	})
+
+	t.Run("RenderFails", func(t *testing.T) {
+		dispatcher, transport, _ := setupDispatcher(t)
+
+		err := dispatcher.Notify(context.Background(), Message{Channel: ChannelEmail, Template: TemplateEmailVerification})
+		require.Error(t, err)
+		require.Empty(t, transport.envelopes)
+	})
+}
+
+func TestConsoleTransport_Send(t *testing.T) {
+	var buf bytes.Buffer
+	transport := NewConsoleTransport(&buf)
+
+	err := transport.Send(context.Background(), &Envelope{
+		Channel:  ChannelEmail,
+		To:       "a@example.com",
+		Template: TemplateEmailVerification,
+		Locale:   "en",
+		Subject:  "Confirm",
+		Text:     "Code: abc123\n",
+		HTML:     "<p>Code: abc123</p>",
+	})
+	require.NoError(t, err)
+	require.Equal(t, "----- email to a@example.com (email_verification, en) -----\nSubject: Confirm\n\nCode: abc123\n\n", buf.String())
+}
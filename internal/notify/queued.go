@@ -0,0 +1,40 @@
+package notify
+
+import (
+	"context"
+	"errors"
+
+	"go-starter-template/internal/queue"
+)
+
+// JobSend is the job type delivering a Message handed to Queued.
+const JobSend = "notify.send"
+
+// Queued enqueues messages on the job queue, so they survive restarts and
+// failed sends are retried. Something must run RegisterJobs, usually
+// cmd/worker.
+type Queued struct {
+	jobs *queue.Client
+}
+
+func NewQueued(jobs *queue.Client) *Queued {
+	return &Queued{jobs: jobs}
+}
+
+func (q *Queued) Notify(ctx context.Context, msg Message) error {
+	// The worker has no request to take the locale from
+	_, err := q.jobs.Enqueue(ctx, JobSend, withLocale(ctx, msg))
+	return err
+}
+
+// RegisterJobs sends the messages enqueued by Queued through next. Messages
+// on a disabled channel fail for good instead of being retried.
+func RegisterJobs(worker *queue.Worker, next Notifier) {
+	queue.Register(worker, JobSend, func(ctx context.Context, msg Message) error {
+		err := next.Notify(ctx, msg)
+		if errors.Is(err, ErrChannelDisabled) {
+			return queue.Permanent(err)
+		}
+		return err
+	})
+}
@@ -0,0 +1,47 @@
+package notify
+
+import (
+	"context"
+	"testing"
+
+	miniredis "github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+
+	"go-starter-template/internal/queue"
+)
+
+func TestQueued(t *testing.T) {
+	mr := miniredis.RunT(t)
+	jobs := queue.NewClient(redis.NewClient(&redis.Options{Addr: mr.Addr()}), "jobs:", 3)
+	worker := queue.NewWorker(jobs, testLogger(), queue.WorkerOptions{})
+	dispatcher, transport, _ := setupDispatcher(t)
+	RegisterJobs(worker, dispatcher)
+	notifier := NewQueued(jobs)
+
+	t.Run("SentByWorker", func(t *testing.T) {
+		ctx := WithLocale(context.Background(), "de")
+		require.NoError(t, notifier.Notify(ctx, Message{Channel: ChannelEmail, To: "a@example.com", Template: TemplateEmailVerification, Data: verificationData}))
+		require.Empty(t, transport.envelopes)
+
+		worked, err := worker.Work(context.Background())
+		require.NoError(t, err)
+		require.True(t, worked)
+		require.Len(t, transport.envelopes, 1)
+		require.Equal(t, "de", transport.envelopes[0].Locale)
+		require.Contains(t, transport.envelopes[0].Text, "abc123")
+	})
+
+	t.Run("DisabledChannelIsDeadLettered", func(t *testing.T) {
+		id, err := jobs.Enqueue(context.Background(), JobSend, Message{Channel: ChannelSMS, Template: TemplateEmailVerification, Data: verificationData})
+		require.NoError(t, err)
+
+		_, err = worker.Work(context.Background())
+		require.NoError(t, err)
+		dead, err := jobs.Dead(context.Background(), 10)
+		require.NoError(t, err)
+		require.Len(t, dead, 1)
+		require.Equal(t, id, dead[0].ID)
+		require.Equal(t, 1, dead[0].Attempts)
+	})
+}
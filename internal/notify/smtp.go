@@ -0,0 +1,189 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// SMTPOptions configure an SMTPTransport.
+type SMTPOptions struct {
+	Host string
+	Port int
+	// Username and Password enable PLAIN authentication, which net/smtp only
+	// allows over TLS or to localhost
+	Username string
+	Password string
+	From     string
+	Timeout  time.Duration
+}
+
+// SMTPTransport sends emails through an SMTP server, upgrading the
+// connection with STARTTLS whenever the server offers it.
+type SMTPTransport struct {
+	opts   SMTPOptions
+	tracer trace.Tracer
+}
+
+func NewSMTPTransport(opts SMTPOptions) *SMTPTransport {
+	if opts.Timeout <= 0 {
+		opts.Timeout = 10 * time.Second
+	}
+	return &SMTPTransport{opts: opts, tracer: otel.Tracer("Notifier")}
+}
+
+func (t *SMTPTransport) Send(ctx context.Context, envelope *Envelope) error {
+	spanCtx, span := t.tracer.Start(ctx, "SMTP.Send", trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+		attribute.String("server.address", t.opts.Host),
+		attribute.Int("server.port", t.opts.Port),
+	))
+	defer span.End()
+
+	message, err := t.buildMessage(envelope)
+	if err != nil {
+		return err
+	}
+
+	spanCtx, cancel := context.WithTimeout(spanCtx, t.opts.Timeout)
+	defer cancel()
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(spanCtx, "tcp", net.JoinHostPort(t.opts.Host, strconv.Itoa(t.opts.Port)))
+	if err != nil {
+		return err
+	}
+	deadline, _ := spanCtx.Deadline()
+	_ = conn.SetDeadline(deadline)
+
+	client, err := smtp.NewClient(conn, t.opts.Host)
+	if err != nil {
+		_ = conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: t.opts.Host}); err != nil {
+			return err
+		}
+	}
+	if t.opts.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", t.opts.Username, t.opts.Password, t.opts.Host)); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(senderAddress(t.opts.From)); err != nil {
+		return err
+	}
+	if err := client.Rcpt(envelope.To); err != nil {
+		return err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(message); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// buildMessage returns the RFC 5322 message of envelope: plain text, or
+// multipart/alternative when it has an HTML body.
+func (t *SMTPTransport) buildMessage(envelope *Envelope) ([]byte, error) {
+	var buf bytes.Buffer
+	header := func(key, value string) {
+		fmt.Fprintf(&buf, "%s: %s\r\n", key, value)
+	}
+	from := &mail.Address{Address: t.opts.From}
+	if address, err := mail.ParseAddress(t.opts.From); err == nil {
+		from = address
+	}
+	header("From", from.String())
+	header("To", (&mail.Address{Address: envelope.To}).String())
+	header("Subject", mime.QEncoding.Encode("utf-8", envelope.Subject))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("Message-ID", messageID(from.Address))
+	header("MIME-Version", "1.0")
+
+	if envelope.HTML == "" {
+		header("Content-Type", "text/plain; charset=utf-8")
+		header("Content-Transfer-Encoding", "quoted-printable")
+		buf.WriteString("\r\n")
+		if err := writeQuotedPrintable(&buf, envelope.Text); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	body := multipart.NewWriter(&buf)
+	header("Content-Type", `multipart/alternative; boundary="`+body.Boundary()+`"`)
+	buf.WriteString("\r\n")
+	for _, part := range []struct{ contentType, content string }{
+		{"text/plain; charset=utf-8", envelope.Text},
+		{"text/html; charset=utf-8", envelope.HTML},
+	} {
+		w, err := body.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := writeQuotedPrintable(w, part.content); err != nil {
+			return nil, err
+		}
+	}
+	if err := body.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func writeQuotedPrintable(w io.Writer, content string) error {
+	qp := quotedprintable.NewWriter(w)
+	if _, err := qp.Write([]byte(content)); err != nil {
+		return err
+	}
+	return qp.Close()
+}
+
+// senderAddress returns the bare address of from, which may carry a display
+// name such as "Example <noreply@example.com>".
+func senderAddress(from string) string {
+	if address, err := mail.ParseAddress(from); err == nil {
+		return address.Address
+	}
+	return from
+}
+
+// messageID returns a unique Message-ID in the domain of the sender.
+func messageID(sender string) string {
+	domain := "localhost"
+	if at := strings.LastIndex(sender, "@"); at >= 0 {
+		domain = sender[at+1:]
+	}
+	raw := make([]byte, 16)
+	_, _ = rand.Read(raw)
+	return "<" + hex.EncodeToString(raw) + "@" + domain + ">"
+}
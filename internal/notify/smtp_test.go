@@ -0,0 +1,201 @@
+package notify
+
+import (
+	"context"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSMTPServer accepts mail on a local port and records what it was told.
+type fakeSMTPServer struct {
+	listener net.Listener
+	wg       sync.WaitGroup
+
+	mu       sync.Mutex
+	auth     string
+	from     string
+	to       []string
+	messages []string
+	// rejectRcpt makes RCPT TO fail with a permanent error
+	rejectRcpt bool
+}
+
+func newFakeSMTPServer(t *testing.T) *fakeSMTPServer {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := &fakeSMTPServer{listener: listener}
+	s.wg.Add(1)
+	go s.serve()
+	t.Cleanup(func() {
+		_ = listener.Close()
+		s.wg.Wait()
+	})
+	return s
+}
+
+func (s *fakeSMTPServer) port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (s *fakeSMTPServer) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.handle(textproto.NewConn(conn))
+	}
+}
+
+func (s *fakeSMTPServer) handle(conn *textproto.Conn) {
+	defer conn.Close()
+	_ = conn.PrintfLine("220 localhost ESMTP fake")
+	for {
+		line, err := conn.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		s.mu.Lock()
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			_ = conn.PrintfLine("250-localhost")
+			_ = conn.PrintfLine("250 AUTH PLAIN")
+		case "AUTH":
+			_, credentials, _ := strings.Cut(arg, " ")
+			decoded, _ := base64.StdEncoding.DecodeString(credentials)
+			s.auth = string(decoded)
+			_ = conn.PrintfLine("235 2.7.0 Authentication successful")
+		case "MAIL":
+			s.from = arg
+			_ = conn.PrintfLine("250 OK")
+		case "RCPT":
+			if s.rejectRcpt {
+				_ = conn.PrintfLine("550 5.1.1 No such user")
+				break
+			}
+			s.to = append(s.to, arg)
+			_ = conn.PrintfLine("250 OK")
+		case "DATA":
+			_ = conn.PrintfLine("354 End data with <CR><LF>.<CR><LF>")
+			data, err := io.ReadAll(conn.DotReader())
+			if err != nil {
+				s.mu.Unlock()
+				return
+			}
+			s.messages = append(s.messages, string(data))
+			_ = conn.PrintfLine("250 OK queued")
+		case "QUIT":
+			_ = conn.PrintfLine("221 Bye")
+			s.mu.Unlock()
+			return
+		default:
+			_ = conn.PrintfLine("502 Command not implemented")
+		}
+		s.mu.Unlock()
+	}
+}
+
+func TestSMTPTransport_Send(t *testing.T) {
+	envelope := &Envelope{
+		Channel: ChannelEmail,
+		To:      "alice@example.com",
+		Subject: "Bestätigen Sie Ihre Adresse",
+		Text:    "Code: abc123",
+		HTML:    "<p>Code: <b>abc123</b></p>",
+	}
+
+	t.Run("MultipartWithAuth", func(t *testing.T) {
+		server := newFakeSMTPServer(t)
+		transport := NewSMTPTransport(SMTPOptions{
+			Host:     "127.0.0.1",
+			Port:     server.port(),
+			Username: "user",
+			Password: "pass",
+			From:     "Go Starter <noreply@example.com>",
+			Timeout:  time.Second,
+		})
+
+		require.NoError(t, transport.Send(context.Background(), envelope))
+		require.Equal(t, "\x00user\x00pass", server.auth)
+		require.Equal(t, "FROM:<noreply@example.com>", server.from)
+		require.Equal(t, []string{"TO:<alice@example.com>"}, server.to)
+		require.Len(t, server.messages, 1)
+
+		msg, err := mail.ReadMessage(strings.NewReader(server.messages[0]))
+		require.NoError(t, err)
+		require.Equal(t, `"Go Starter" <noreply@example.com>`, msg.Header.Get("From"))
+		require.Equal(t, "<alice@example.com>", msg.Header.Get("To"))
+		subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+		require.NoError(t, err)
+		require.Equal(t, envelope.Subject, subject)
+		require.True(t, strings.HasSuffix(msg.Header.Get("Message-ID"), "@example.com>"))
+
+		mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+		require.NoError(t, err)
+		require.Equal(t, "multipart/alternative", mediaType)
+		parts := multipart.NewReader(msg.Body, params["boundary"])
+		var bodies []string
+		for {
+			// NextPart undoes the quoted-printable encoding
+			part, err := parts.NextPart()
+			if err == io.EOF {
+				break
+			}
+			require.NoError(t, err)
+			body, err := io.ReadAll(part)
+			require.NoError(t, err)
+			bodies = append(bodies, string(body))
+		}
+		require.Equal(t, []string{envelope.Text, envelope.HTML}, bodies)
+	})
+
+	t.Run("PlainText", func(t *testing.T) {
+		server := newFakeSMTPServer(t)
+		transport := NewSMTPTransport(SMTPOptions{Host: "127.0.0.1", Port: server.port(), From: "noreply@example.com"})
+
+		require.NoError(t, transport.Send(context.Background(), &Envelope{To: "bob@example.com", Subject: "Hi", Text: "Hello Bob"}))
+		require.Empty(t, server.auth)
+		msg, err := mail.ReadMessage(strings.NewReader(server.messages[0]))
+		require.NoError(t, err)
+		require.Equal(t, "text/plain; charset=utf-8", msg.Header.Get("Content-Type"))
+		body, err := io.ReadAll(msg.Body)
+		require.NoError(t, err)
+		require.Equal(t, "Hello Bob", strings.TrimSpace(string(body)))
+	})
+
+	t.Run("RecipientRejected", func(t *testing.T) {
+		server := newFakeSMTPServer(t)
+		server.rejectRcpt = true
+		transport := NewSMTPTransport(SMTPOptions{Host: "127.0.0.1", Port: server.port(), From: "noreply@example.com"})
+
+		err := transport.Send(context.Background(), envelope)
+		require.ErrorContains(t, err, "No such user")
+		require.Empty(t, server.messages)
+	})
+
+	t.Run("ServerDown", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		port := listener.Addr().(*net.TCPAddr).Port
+		require.NoError(t, listener.Close())
+
+		transport := NewSMTPTransport(SMTPOptions{Host: "127.0.0.1", Port: port, From: "noreply@example.com", Timeout: time.Second})
+		err = transport.Send(context.Background(), envelope)
+		require.ErrorContains(t, err, strconv.Itoa(port))
+	})
+}
@@ -0,0 +1,207 @@
+package notify
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"maps"
+	"path"
+	"slices"
+	"strings"
+	texttemplate "text/template"
+)
+
+//go:embed templates
+var embedded embed.FS
+
+// Template files are named <locale>/<template>.<part>.tmpl. The text part is
+// required; subject and html are only used for emails. html is parsed with
+// html/template, so data is escaped, the others with text/template.
+const (
+	partSubject = "subject"
+	partText    = "text"
+	partHTML    = "html"
+)
+
+// Templates renders messages in the recipient's locale.
+type Templates struct {
+	defaultLocale string
+	// text and html are keyed by locale, then by <template>.<part>
+	text map[string]map[string]*texttemplate.Template
+	html map[string]map[string]*htmltemplate.Template
+}
+
+// EmbeddedTemplates loads the templates compiled into the binary.
+func EmbeddedTemplates(defaultLocale string) (*Templates, error) {
+	sub, err := fs.Sub(embedded, "templates")
+	if err != nil {
+		return nil, err
+	}
+	return LoadTemplates(sub, defaultLocale)
+}
+
+// LoadTemplates parses every template in fsys. Each template must exist in
+// defaultLocale, which is used when the requested locale lacks it.
+func LoadTemplates(fsys fs.FS, defaultLocale string) (*Templates, error) {
+	t := &Templates{
+		defaultLocale: normalizeLocale(defaultLocale),
+		text:          map[string]map[string]*texttemplate.Template{},
+		html:          map[string]map[string]*htmltemplate.Template{},
+	}
+
+	err := fs.WalkDir(fsys, ".", func(name string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		locale, file := path.Split(name)
+		locale = normalizeLocale(strings.TrimSuffix(locale, "/"))
+		key, ok := strings.CutSuffix(file, ".tmpl")
+		if !ok || locale == "" || strings.Contains(locale, "/") {
+			return fmt.Errorf("notify: unexpected template file %s", name)
+		}
+		content, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+
+		switch path.Ext(key) {
+		case "." + partSubject, "." + partText:
+			tmpl, err := texttemplate.New(name).Option("missingkey=error").Parse(string(content))
+			if err != nil {
+				return fmt.Errorf("notify: %w", err)
+			}
+			if t.text[locale] == nil {
+				t.text[locale] = map[string]*texttemplate.Template{}
+			}
+			t.text[locale][key] = tmpl
+		case "." + partHTML:
+			tmpl, err := htmltemplate.New(name).Option("missingkey=error").Parse(string(content))
+			if err != nil {
+				return fmt.Errorf("notify: %w", err)
+			}
+			if t.html[locale] == nil {
+				t.html[locale] = map[string]*htmltemplate.Template{}
+			}
+			t.html[locale][key] = tmpl
+		default:
+			return fmt.Errorf("notify: unknown template part in %s", name)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Every translation has the parts of the default locale's version
+	for _, locale := range t.locales() {
+		for _, name := range t.names(locale) {
+			parts := t.parts(locale, name)
+			if !strings.Contains(parts, partText) {
+				return nil, fmt.Errorf("notify: %s/%s has no text part", locale, name)
+			}
+			if want := t.parts(t.defaultLocale, name); parts != want {
+				return nil, fmt.Errorf("notify: %s/%s has parts [%s], %s has [%s]", locale, name, parts, t.defaultLocale, want)
+			}
+		}
+	}
+	return t, nil
+}
+
+// Render renders msg in the first of its locale, that locale's language and
+// the default locale that has the template.
+func (t *Templates) Render(msg Message) (*Envelope, error) {
+	locale := t.resolve(msg.Template, msg.Locale)
+	if locale == "" {
+		return nil, fmt.Errorf("notify: unknown template %q", msg.Template)
+	}
+
+	envelope := &Envelope{Channel: msg.Channel, To: msg.To, Template: msg.Template, Locale: locale}
+	var err error
+	if envelope.Text, err = t.execText(locale, msg.Template+"."+partText, msg.Data); err != nil {
+		return nil, err
+	}
+	if msg.Channel != ChannelEmail {
+		return envelope, nil
+	}
+	if envelope.Subject, err = t.execText(locale, msg.Template+"."+partSubject, msg.Data); err != nil {
+		return nil, err
+	}
+	envelope.Subject = strings.TrimSpace(envelope.Subject)
+	if tmpl, ok := t.html[locale][msg.Template+"."+partHTML]; ok {
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, msg.Data); err != nil {
+			return nil, fmt.Errorf("notify: %w", err)
+		}
+		envelope.HTML = buf.String()
+	}
+	return envelope, nil
+}
+
+func (t *Templates) resolve(template, locale string) string {
+	locale = normalizeLocale(locale)
+	language, _, _ := strings.Cut(locale, "-")
+	for _, candidate := range []string{locale, language, t.defaultLocale} {
+		if _, ok := t.text[candidate][template+"."+partText]; ok {
+			return candidate
+		}
+	}
+	return ""
+}
+
+func (t *Templates) locales() []string {
+	locales := map[string]bool{}
+	for locale := range t.text {
+		locales[locale] = true
+	}
+	for locale := range t.html {
+		locales[locale] = true
+	}
+	return slices.Sorted(maps.Keys(locales))
+}
+
+// names returns the templates with at least one part in locale.
+func (t *Templates) names(locale string) []string {
+	names := map[string]bool{}
+	for key := range t.text[locale] {
+		names[strings.TrimSuffix(key, path.Ext(key))] = true
+	}
+	for key := range t.html[locale] {
+		names[strings.TrimSuffix(key, path.Ext(key))] = true
+	}
+	return slices.Sorted(maps.Keys(names))
+}
+
+// parts lists the parts of a template in locale, e.g. "subject text html".
+func (t *Templates) parts(locale, name string) string {
+	var parts []string
+	for _, part := range []string{partSubject, partText} {
+		if _, ok := t.text[locale][name+"."+part]; ok {
+			parts = append(parts, part)
+		}
+	}
+	if _, ok := t.html[locale][name+"."+partHTML]; ok {
+		parts = append(parts, partHTML)
+	}
+	return strings.Join(parts, " ")
+}
+
+// execText renders a text part; a missing part renders as "".
+func (t *Templates) execText(locale, key string, data map[string]any) (string, error) {
+	tmpl, ok := t.text[locale][key]
+	if !ok {
+		return "", nil
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("notify: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// normalizeLocale lowercases a language tag and uses "-" as its separator,
+// so "pt_BR" and "pt-br" find the same templates.
+func normalizeLocale(locale string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"))
+}
@@ -0,0 +1,124 @@
+package notify
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmbeddedTemplates(t *testing.T) {
+	templates, err := EmbeddedTemplates("en")
+	require.NoError(t, err)
+
+	data := map[string]any{"Name": "Alice", "Token": "abc123", "ExpiresInHours": 24}
+	t.Run("DefaultLocale", func(t *testing.T) {
+		envelope, err := templates.Render(Message{Channel: ChannelEmail, To: "a@example.com", Template: TemplateEmailVerification, Data: data})
+		require.NoError(t, err)
+		require.Equal(t, "en", envelope.Locale)
+		require.Equal(t, "Confirm your new email address", envelope.Subject)
+		require.Contains(t, envelope.Text, "abc123")
+		require.Contains(t, envelope.HTML, "abc123")
+	})
+
+	t.Run("FallsBackToLanguage", func(t *testing.T) {
+		envelope, err := templates.Render(Message{Channel: ChannelEmail, Template: TemplateEmailVerification, Locale: "de_AT", Data: data})
+		require.NoError(t, err)
+		require.Equal(t, "de", envelope.Locale)
+		require.Equal(t, "Bestätigen Sie Ihre neue E-Mail-Adresse", envelope.Subject)
+	})
+
+	t.Run("FallsBackToDefaultLocale", func(t *testing.T) {
+		envelope, err := templates.Render(Message{Channel: ChannelEmail, Template: TemplateEmailVerification, Locale: "fr-FR", Data: data})
+		require.NoError(t, err)
+		require.Equal(t, "en", envelope.Locale)
+	})
+
+	t.Run("SMSHasTextOnly", func(t *testing.T) {
+		envelope, err := templates.Render(Message{Channel: ChannelSMS, Template: TemplateEmailVerification, Data: data})
+		require.NoError(t, err)
+		require.Empty(t, envelope.Subject)
+		require.Empty(t, envelope.HTML)
+		require.Contains(t, envelope.Text, "abc123")
+	})
+
+	t.Run("EscapesHTML", func(t *testing.T) {
+		envelope, err := templates.Render(Message{Channel: ChannelEmail, Template: TemplateNewLogin, Data: map[string]any{
+			"Name": "<script>", "NewDevice": true, "Device": "curl", "Location": "", "IP": "192.0.2.1", "Time": "2024-01-01 10:00 UTC",
+		}})
+		require.NoError(t, err)
+		require.Contains(t, envelope.Text, "Hi <script>,")
+		require.Contains(t, envelope.Text, "Location: unknown")
+		require.NotContains(t, envelope.HTML, "<script>")
+		require.Contains(t, envelope.HTML, "&lt;script&gt;")
+	})
+
+	t.Run("MissingData", func(t *testing.T) {
+		_, err := templates.Render(Message{Channel: ChannelEmail, Template: TemplateEmailVerification, Data: map[string]any{"Name": "Alice"}})
+		require.Error(t, err)
+	})
+
+	t.Run("UnknownTemplate", func(t *testing.T) {
+		_, err := templates.Render(Message{Channel: ChannelEmail, Template: "missing"})
+		require.ErrorContains(t, err, "unknown template")
+	})
+}
+
+func TestLoadTemplates_Validation(t *testing.T) {
+	file := func(content string) *fstest.MapFile { return &fstest.MapFile{Data: []byte(content)} }
+
+	cases := []struct {
+		name      string
+		fsys      fstest.MapFS
+		expectErr string
+	}{
+		{
+			name:      "MissingText",
+			fsys:      fstest.MapFS{"en/welcome.subject.tmpl": file("Hi")},
+			expectErr: "has no text part",
+		},
+		{
+			name: "TranslationLacksPart",
+			fsys: fstest.MapFS{
+				"en/welcome.subject.tmpl": file("Hi"),
+				"en/welcome.text.tmpl":    file("Hello"),
+				"de/welcome.text.tmpl":    file("Hallo"),
+			},
+			expectErr: "de/welcome has parts [text], en has [subject text]",
+		},
+		{
+			name:      "UnknownPart",
+			fsys:      fstest.MapFS{"en/welcome.body.tmpl": file("Hi")},
+			expectErr: "unknown template part",
+		},
+		{
+			name:      "OutsideLocale",
+			fsys:      fstest.MapFS{"welcome.text.tmpl": file("Hi")},
+			expectErr: "unexpected template file",
+		},
+		{
+			name:      "InvalidSyntax",
+			fsys:      fstest.MapFS{"en/welcome.text.tmpl": file("{{.Name")},
+			expectErr: "welcome.text.tmpl",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := LoadTemplates(tc.fsys, "en")
+			require.ErrorContains(t, err, tc.expectErr)
+		})
+	}
+
+	t.Run("Valid", func(t *testing.T) {
+		templates, err := LoadTemplates(fstest.MapFS{
+			"en/welcome.text.tmpl":    file("Hello {{.Name}}"),
+			"pt_BR/welcome.text.tmpl": file("Olá {{.Name}}"),
+		}, "en")
+		require.NoError(t, err)
+		envelope, err := templates.Render(Message{Channel: ChannelSMS, Template: "welcome", Locale: "pt-br", Data: map[string]any{"Name": "Ana"}})
+		require.NoError(t, err)
+		require.Equal(t, "pt-br", envelope.Locale)
+		require.Equal(t, "Olá Ana", strings.TrimSpace(envelope.Text))
+	})
+}
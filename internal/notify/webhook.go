@@ -0,0 +1,56 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go-starter-template/internal/utils/signature"
+
+	"github.com/goccy/go-json"
+)
+
+// WebhookTransport POSTs every rendered message as JSON to a single URL,
+// such as an SMS gateway or a mail API adapter, and treats any 2xx response
+// as delivered. With a secret the body is signed like outgoing webhooks.
+type WebhookTransport struct {
+	url    string
+	secret string
+	client *http.Client
+}
+
+func NewWebhookTransport(url, secret string, timeout time.Duration) *WebhookTransport {
+	return &WebhookTransport{url: url, secret: secret, client: &http.Client{Timeout: timeout}}
+}
+
+func (t *WebhookTransport) Send(ctx context.Context, envelope *Envelope) error {
+	data, err := json.Marshal(envelope)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if t.secret != "" {
+		timestamp := time.Now().Unix()
+		req.Header.Set(signature.TimestampHeader, strconv.FormatInt(timestamp, 10))
+		req.Header.Set(signature.SignatureHeader, signature.Sign(t.secret, timestamp, data))
+	}
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("notification webhook responded %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package notify
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/stretchr/testify/require"
+
+	"go-starter-template/internal/utils/signature"
+)
+
+func TestWebhookTransport_Send(t *testing.T) {
+	envelope := &Envelope{Channel: ChannelSMS, To: "+491701234567", Template: TemplateNewLogin, Locale: "de", Text: "Neue Anmeldung"}
+
+	t.Run("Signed", func(t *testing.T) {
+		var received Envelope
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, err := io.ReadAll(r.Body)
+			require.NoError(t, err)
+			require.NoError(t, signature.Verify("secret", r.Header.Get(signature.TimestampHeader), r.Header.Get(signature.SignatureHeader), body, time.Minute, time.Now()))
+			require.NoError(t, json.Unmarshal(body, &received))
+			w.WriteHeader(http.StatusAccepted)
+		}))
+		defer server.Close()
+
+		require.NoError(t, NewWebhookTransport(server.URL, "secret", time.Second).Send(context.Background(), envelope))
+		require.Equal(t, *envelope, received)
+	})
+
+	t.Run("Unsigned", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			require.Empty(t, r.Header.Get(signature.SignatureHeader))
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+
+		require.NoError(t, NewWebhookTransport(server.URL, "", time.Second).Send(context.Background(), envelope))
+	})
+
+	t.Run("ErrorStatus", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadGateway)
+		}))
+		defer server.Close()
+
+		err := NewWebhookTransport(server.URL, "", time.Second).Send(context.Background(), envelope)
+		require.ErrorContains(t, err, "502")
+	})
+}
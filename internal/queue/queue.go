@@ -0,0 +1,376 @@
+// Package queue runs background jobs through Redis. Producers enqueue typed
+// jobs with a Client; a Worker, usually in cmd/worker, claims and runs them
+// with retries, backoff and a dead-letter queue.
+//
+// Every job lives in a hash under <prefix>data and its ID sits in exactly
+// one sorted set: <prefix>scheduled (scored by when to run), <prefix>active
+// (scored by when its lease expires) or <prefix>dead (scored by when it
+// failed for good). Moves between the sets are Lua scripts, so a job is
+// never claimed twice or lost between them. The dead set is trimmed to a
+// maximum age and length whenever a job is added to it.
+package queue
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var ErrJobNotFound = errors.New("job not found")
+
+// Job is a unit of work as stored in Redis.
+type Job struct {
+	ID          string          `json:"id"`
+	Type        string          `json:"type"`
+	Payload     json.RawMessage `json:"payload"`
+	Attempts    int             `json:"attempts"`
+	MaxAttempts int             `json:"max_attempts"`
+	EnqueuedAt  time.Time       `json:"enqueued_at"`
+	LastError   string          `json:"last_error,omitempty"`
+	FailedAt    *time.Time      `json:"failed_at,omitempty"`
+	// Trace carries the enqueueing span so the execution can link to it
+	Trace map[string]string `json:"trace,omitempty"`
+}
+
+// EnqueueOptions tune a single job. Zero values use the client defaults.
+type EnqueueOptions struct {
+	// RunAt delays the job until the given time
+	RunAt       time.Time
+	MaxAttempts int
+}
+
+type Client struct {
+	client      *redis.Client
+	prefix      string
+	maxAttempts int
+	deadMaxAge  time.Duration
+	deadMaxLen  int64
+	tracer      trace.Tracer
+	propagator  propagation.TextMapPropagator
+}
+
+// NewClient returns a client storing jobs under keys starting with prefix.
+// maxAttempts is the default number of runs before a job is dead-lettered.
+// Dead jobs are kept for 7 days, at most 10000 of them; see LimitDead.
+func NewClient(client *redis.Client, prefix string, maxAttempts int) *Client {
+	if maxAttempts <= 0 {
+		maxAttempts = 1
+	}
+	return &Client{
+		client:      client,
+		prefix:      prefix,
+		maxAttempts: maxAttempts,
+		deadMaxAge:  7 * 24 * time.Hour,
+		deadMaxLen:  10000,
+		tracer:      otel.Tracer("Queue"),
+		propagator:  propagation.TraceContext{},
+	}
+}
+
+// LimitDead sets how long dead jobs are kept and how many at most. Older
+// and, beyond maxLen, the oldest dead jobs are deleted for good. A
+// non-positive value leaves that limit unchanged.
+func (c *Client) LimitDead(maxAge time.Duration, maxLen int64) {
+	if maxAge > 0 {
+		c.deadMaxAge = maxAge
+	}
+	if maxLen > 0 {
+		c.deadMaxLen = maxLen
+	}
+}
+
+func (c *Client) scheduledKey() string { return c.prefix + "scheduled" }
+func (c *Client) activeKey() string    { return c.prefix + "active" }
+func (c *Client) deadKey() string      { return c.prefix + "dead" }
+func (c *Client) dataKey() string      { return c.prefix + "data" }
+
+// Enqueue queues a job of jobType to run as soon as a worker is free and
+// returns its ID. payload is stored as JSON.
+func (c *Client) Enqueue(ctx context.Context, jobType string, payload any) (string, error) {
+	return c.EnqueueWith(ctx, jobType, payload, EnqueueOptions{})
+}
+
+// EnqueueWith is Enqueue with per-job options.
+func (c *Client) EnqueueWith(ctx context.Context, jobType string, payload any, opts EnqueueOptions) (string, error) {
+	spanCtx, span := c.tracer.Start(ctx, "Queue.Enqueue "+jobType, trace.WithSpanKind(trace.SpanKindProducer))
+	defer span.End()
+
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+	job := &Job{
+		ID:          uuid.NewString(),
+		Type:        jobType,
+		Payload:     raw,
+		MaxAttempts: opts.MaxAttempts,
+		EnqueuedAt:  time.Now(),
+		Trace:       map[string]string{},
+	}
+	if job.MaxAttempts <= 0 {
+		job.MaxAttempts = c.maxAttempts
+	}
+	runAt := opts.RunAt
+	if runAt.IsZero() {
+		runAt = job.EnqueuedAt
+	}
+	c.propagator.Inject(spanCtx, propagation.MapCarrier(job.Trace))
+	span.SetAttributes(attribute.String("job.id", job.ID), attribute.String("job.type", jobType))
+
+	data, err := json.Marshal(job)
+	if err != nil {
+		return "", err
+	}
+	_, err = c.client.TxPipelined(spanCtx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(spanCtx, c.dataKey(), job.ID, data)
+		pipe.ZAdd(spanCtx, c.scheduledKey(), redis.Z{Score: float64(runAt.UnixMilli()), Member: job.ID})
+		return nil
+	})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "enqueue job failed")
+		return "", err
+	}
+	return job.ID, nil
+}
+
+// claimScript moves the job that has been due the longest from scheduled to
+// active, leased until ARGV[2], and returns its data.
+var claimScript = redis.NewScript(`
+local ids = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, 1)
+if #ids == 0 then
+    return false
+end
+redis.call('ZREM', KEYS[1], ids[1])
+redis.call('ZADD', KEYS[2], ARGV[2], ids[1])
+return {ids[1], redis.call('HGET', KEYS[3], ids[1])}
+`)
+
+// claim leases the next due job for lease, returning nil if none is due.
+func (c *Client) claim(ctx context.Context, now time.Time, lease time.Duration) (*Job, error) {
+	result, err := claimScript.Run(ctx, c.client,
+		[]string{c.scheduledKey(), c.activeKey(), c.dataKey()},
+		now.UnixMilli(), now.Add(lease).UnixMilli(),
+	).Slice()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	job := new(Job)
+	data, _ := result[1].(string)
+	if err := json.Unmarshal([]byte(data), job); err != nil {
+		// Keep the ID so the unreadable job can still be dead-lettered
+		job.ID, _ = result[0].(string)
+		return job, err
+	}
+	return job, nil
+}
+
+// moveScript takes job ARGV[1] out of KEYS[1]. With data in ARGV[2] it stores
+// it and adds the job to KEYS[3] scored ARGV[3]; without, the job is deleted.
+// It does nothing and returns 0 if the job was not in KEYS[1], e.g. because
+// its lease expired and another worker took it over.
+var moveScript = redis.NewScript(`
+if redis.call('ZREM', KEYS[1], ARGV[1]) == 0 then
+    return 0
+end
+if ARGV[2] == '' then
+    redis.call('HDEL', KEYS[2], ARGV[1])
+    return 1
+end
+redis.call('HSET', KEYS[2], ARGV[1], ARGV[2])
+redis.call('ZADD', KEYS[3], ARGV[3], ARGV[1])
+return 1
+`)
+
+func (c *Client) move(ctx context.Context, from, to string, job *Job, score time.Time) (bool, error) {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return false, err
+	}
+	moved, err := moveScript.Run(ctx, c.client, []string{from, c.dataKey(), to}, job.ID, data, score.UnixMilli()).Int()
+	return moved == 1, err
+}
+
+// complete deletes a finished job.
+func (c *Client) complete(ctx context.Context, id string) error {
+	_, err := moveScript.Run(ctx, c.client, []string{c.activeKey(), c.dataKey(), c.scheduledKey()}, id, "", 0).Result()
+	return err
+}
+
+// retry puts a failed job back into the schedule at runAt.
+func (c *Client) retry(ctx context.Context, job *Job, runAt time.Time) error {
+	_, err := c.move(ctx, c.activeKey(), c.scheduledKey(), job, runAt)
+	return err
+}
+
+// bury moves a job that failed for good to the dead-letter queue.
+func (c *Client) bury(ctx context.Context, job *Job, failedAt time.Time) error {
+	job.FailedAt = &failedAt
+	if _, err := c.move(ctx, c.activeKey(), c.deadKey(), job, failedAt); err != nil {
+		return err
+	}
+	return c.trimDead(ctx, failedAt)
+}
+
+// trimDeadScript deletes up to ARGV[3] dead jobs that failed before ARGV[1]
+// or, oldest first, exceed ARGV[2] jobs, and returns how many it deleted.
+var trimDeadScript = redis.NewScript(`
+local ids = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', '(' .. ARGV[1], 'LIMIT', 0, ARGV[3])
+local over = redis.call('ZCARD', KEYS[1]) - #ids - tonumber(ARGV[2])
+if over > 0 then
+    local oldest = redis.call('ZRANGE', KEYS[1], #ids, #ids + math.min(over, tonumber(ARGV[3]) - #ids) - 1)
+    for _, id in ipairs(oldest) do
+        table.insert(ids, id)
+    end
+end
+for _, id in ipairs(ids) do
+    redis.call('ZREM', KEYS[1], id)
+    redis.call('HDEL', KEYS[2], id)
+end
+return #ids
+`)
+
+func (c *Client) trimDead(ctx context.Context, now time.Time) error {
+	return trimDeadScript.Run(ctx, c.client, []string{c.deadKey(), c.dataKey()}, now.Add(-c.deadMaxAge).UnixMilli(), c.deadMaxLen, 1000).Err()
+}
+
+// reclaimScript moves job ARGV[1] from KEYS[1] to KEYS[3] scored ARGV[3],
+// storing ARGV[2] as its data, if its lease still ended before ARGV[4]. It
+// returns 0 if the job was settled or reclaimed and claimed again meanwhile.
+var reclaimScript = redis.NewScript(`
+local lease = redis.call('ZSCORE', KEYS[1], ARGV[1])
+if not lease or tonumber(lease) > tonumber(ARGV[4]) then
+    return 0
+end
+redis.call('ZREM', KEYS[1], ARGV[1])
+redis.call('HSET', KEYS[2], ARGV[1], ARGV[2])
+redis.call('ZADD', KEYS[3], ARGV[3], ARGV[1])
+return 1
+`)
+
+// reclaim takes back up to 100 jobs whose worker died or hung past the lease
+// and returns how many it moved. The interrupted run counts as an attempt,
+// so a job that keeps crashing its worker ends up dead-lettered; the others
+// are requeued, due immediately.
+func (c *Client) reclaim(ctx context.Context, now time.Time) (int, error) {
+	ids, err := c.client.ZRangeByScore(ctx, c.activeKey(), &redis.ZRangeBy{Min: "-inf", Max: strconv.FormatInt(now.UnixMilli(), 10), Count: 100}).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	reclaimed, buried := 0, false
+	for _, id := range ids {
+		data, err := c.client.HGet(ctx, c.dataKey(), id).Result()
+		if errors.Is(err, redis.Nil) {
+			continue
+		}
+		if err != nil {
+			return reclaimed, err
+		}
+
+		to, score := c.scheduledKey(), now
+		job := new(Job)
+		// An undecodable job is requeued as is; claiming it buries it
+		if json.Unmarshal([]byte(data), job) == nil {
+			job.Attempts++
+			if job.Attempts >= job.MaxAttempts {
+				job.LastError, job.FailedAt = "lease expired", &now
+				to = c.deadKey()
+			}
+			raw, err := json.Marshal(job)
+			if err != nil {
+				return reclaimed, err
+			}
+			data = string(raw)
+		}
+
+		moved, err := reclaimScript.Run(ctx, c.client, []string{c.activeKey(), c.dataKey(), to}, id, data, score.UnixMilli(), now.UnixMilli()).Int()
+		if err != nil {
+			return reclaimed, err
+		}
+		reclaimed += moved
+		buried = buried || (moved == 1 && to == c.deadKey())
+	}
+	if buried {
+		return reclaimed, c.trimDead(ctx, now)
+	}
+	return reclaimed, nil
+}
+
+// Dead returns up to limit dead-lettered jobs, most recently failed first.
+func (c *Client) Dead(ctx context.Context, limit int) ([]*Job, error) {
+	ids, err := c.client.ZRevRange(ctx, c.deadKey(), 0, int64(limit)-1).Result()
+	if err != nil || len(ids) == 0 {
+		return nil, err
+	}
+	values, err := c.client.HMGet(ctx, c.dataKey(), ids...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	jobs := make([]*Job, 0, len(values))
+	for _, value := range values {
+		data, ok := value.(string)
+		if !ok {
+			continue
+		}
+		job := new(Job)
+		if err := json.Unmarshal([]byte(data), job); err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
+
+// RetryDead moves a dead-lettered job back to the schedule with a fresh set
+// of attempts, returning ErrJobNotFound if it is not in the dead-letter queue.
+func (c *Client) RetryDead(ctx context.Context, id string) error {
+	data, err := c.client.HGet(ctx, c.dataKey(), id).Result()
+	if errors.Is(err, redis.Nil) {
+		return ErrJobNotFound
+	}
+	if err != nil {
+		return err
+	}
+	job := new(Job)
+	if err := json.Unmarshal([]byte(data), job); err != nil {
+		return err
+	}
+	job.Attempts, job.FailedAt = 0, nil
+
+	moved, err := c.move(ctx, c.deadKey(), c.scheduledKey(), job, time.Now())
+	if err != nil {
+		return err
+	}
+	if !moved {
+		return ErrJobNotFound
+	}
+	return nil
+}
+
+// Stats returns how many jobs are scheduled, running and dead.
+func (c *Client) Stats(ctx context.Context) (map[string]int64, error) {
+	pipe := c.client.Pipeline()
+	scheduled := pipe.ZCard(ctx, c.scheduledKey())
+	active := pipe.ZCard(ctx, c.activeKey())
+	dead := pipe.ZCard(ctx, c.deadKey())
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+	return map[string]int64{"scheduled": scheduled.Val(), "active": active.Val(), "dead": dead.Val()}, nil
+}
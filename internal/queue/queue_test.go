@@ -0,0 +1,277 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	miniredis "github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+type greeting struct {
+	Name string `json:"name"`
+}
+
+func setupWorker(t *testing.T, maxAttempts int) (*Client, *Worker, *miniredis.Miniredis) {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	client := NewClient(redis.NewClient(&redis.Options{Addr: mr.Addr()}), "jobs:", maxAttempts)
+	worker := NewWorker(client, logger, WorkerOptions{PollInterval: 10 * time.Millisecond, Backoff: time.Minute, MaxBackoff: 3 * time.Minute})
+	return client, worker, mr
+}
+
+func requireScheduledIn(t *testing.T, mr *miniredis.Miniredis, id string, in time.Duration) {
+	t.Helper()
+	score, err := mr.ZScore("jobs:scheduled", id)
+	require.NoError(t, err)
+	require.WithinDuration(t, time.Now().Add(in), time.UnixMilli(int64(score)), 5*time.Second)
+}
+
+func TestWorker_Work(t *testing.T) {
+	t.Run("RunsTypedHandler", func(t *testing.T) {
+		client, worker, mr := setupWorker(t, 3)
+		var got []string
+		Register(worker, "greet", func(_ context.Context, payload greeting) error {
+			got = append(got, payload.Name)
+			return nil
+		})
+
+		_, err := client.Enqueue(context.Background(), "greet", greeting{Name: "Alice"})
+		require.NoError(t, err)
+		_, err = client.Enqueue(context.Background(), "greet", greeting{Name: "Bob"})
+		require.NoError(t, err)
+
+		for range 2 {
+			worked, err := worker.Work(context.Background())
+			require.NoError(t, err)
+			require.True(t, worked)
+		}
+		worked, err := worker.Work(context.Background())
+		require.NoError(t, err)
+		require.False(t, worked)
+		require.Equal(t, []string{"Alice", "Bob"}, got)
+		require.False(t, mr.Exists("jobs:data"))
+	})
+
+	t.Run("DelayedJobWaits", func(t *testing.T) {
+		client, worker, mr := setupWorker(t, 3)
+		worker.Handle("greet", func(context.Context, *Job) error { return nil })
+
+		id, err := client.EnqueueWith(context.Background(), "greet", greeting{}, EnqueueOptions{RunAt: time.Now().Add(time.Hour)})
+		require.NoError(t, err)
+
+		worked, err := worker.Work(context.Background())
+		require.NoError(t, err)
+		require.False(t, worked)
+		requireScheduledIn(t, mr, id, time.Hour)
+	})
+
+	t.Run("RetriesWithBackoffThenDeadLetters", func(t *testing.T) {
+		client, worker, mr := setupWorker(t, 2)
+		worker.Handle("flaky", func(context.Context, *Job) error { return errors.New("smtp unavailable") })
+
+		id, err := client.Enqueue(context.Background(), "flaky", nil)
+		require.NoError(t, err)
+
+		worked, err := worker.Work(context.Background())
+		require.NoError(t, err)
+		require.True(t, worked)
+		requireScheduledIn(t, mr, id, time.Minute)
+
+		// Make the retry due now
+		mr.ZAdd("jobs:scheduled", 0, id)
+		worked, err = worker.Work(context.Background())
+		require.NoError(t, err)
+		require.True(t, worked)
+
+		dead, err := client.Dead(context.Background(), 10)
+		require.NoError(t, err)
+		require.Len(t, dead, 1)
+		require.Equal(t, 2, dead[0].Attempts)
+		require.Equal(t, "smtp unavailable", dead[0].LastError)
+		require.NotNil(t, dead[0].FailedAt)
+
+		require.NoError(t, client.RetryDead(context.Background(), id))
+		require.ErrorIs(t, client.RetryDead(context.Background(), id), ErrJobNotFound)
+		requireScheduledIn(t, mr, id, 0)
+		stats, err := client.Stats(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, map[string]int64{"scheduled": 1, "active": 0, "dead": 0}, stats)
+	})
+
+	t.Run("PermanentFailuresSkipRetries", func(t *testing.T) {
+		client, worker, _ := setupWorker(t, 5)
+		Register(worker, "greet", func(context.Context, greeting) error { return nil })
+		worker.Handle("broken", func(context.Context, *Job) error { return Permanent(errors.New("bad input")) })
+
+		_, err := client.Enqueue(context.Background(), "broken", nil)
+		require.NoError(t, err)
+		_, err = client.Enqueue(context.Background(), "unknown", nil)
+		require.NoError(t, err)
+		_, err = client.Enqueue(context.Background(), "greet", "not an object")
+		require.NoError(t, err)
+		for range 3 {
+			_, err := worker.Work(context.Background())
+			require.NoError(t, err)
+		}
+
+		dead, err := client.Dead(context.Background(), 10)
+		require.NoError(t, err)
+		require.Len(t, dead, 3)
+		for _, job := range dead {
+			require.Equal(t, 1, job.Attempts)
+		}
+	})
+
+	t.Run("PanicIsRetried", func(t *testing.T) {
+		client, worker, mr := setupWorker(t, 3)
+		worker.Handle("panics", func(context.Context, *Job) error { panic("boom") })
+
+		id, err := client.Enqueue(context.Background(), "panics", nil)
+		require.NoError(t, err)
+		_, err = worker.Work(context.Background())
+		require.NoError(t, err)
+		requireScheduledIn(t, mr, id, time.Minute)
+	})
+}
+
+func TestWorker_LinksEnqueueSpan(t *testing.T) {
+	client, worker, _ := setupWorker(t, 1)
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	client.tracer = provider.Tracer("test")
+	worker.tracer = provider.Tracer("test")
+	worker.Handle("greet", func(context.Context, *Job) error { return nil })
+
+	_, err := client.Enqueue(context.Background(), "greet", nil)
+	require.NoError(t, err)
+	_, err = worker.Work(context.Background())
+	require.NoError(t, err)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	require.Equal(t, "Queue.Enqueue greet", spans[0].Name())
+	require.Equal(t, "Queue.Process greet", spans[1].Name())
+	require.Len(t, spans[1].Links(), 1)
+	require.Equal(t, spans[0].SpanContext().SpanID(), spans[1].Links()[0].SpanContext.SpanID())
+}
+
+func TestClient_Reclaim(t *testing.T) {
+	client, _, mr := setupWorker(t, 3)
+	id, err := client.Enqueue(context.Background(), "greet", nil)
+	require.NoError(t, err)
+
+	// A worker claimed the job and died before its lease ran out
+	job, err := client.claim(context.Background(), time.Now(), -time.Second)
+	require.NoError(t, err)
+	require.Equal(t, id, job.ID)
+
+	reclaimed, err := client.reclaim(context.Background(), time.Now())
+	require.NoError(t, err)
+	require.Equal(t, 1, reclaimed)
+	requireScheduledIn(t, mr, id, 0)
+
+	// Settling after losing the lease leaves the requeued job alone
+	require.NoError(t, client.complete(context.Background(), id))
+	requireScheduledIn(t, mr, id, 0)
+
+	// The interrupted run counted, so the job dies once it has used them all
+	for range 2 {
+		_, err = client.claim(context.Background(), time.Now(), -time.Second)
+		require.NoError(t, err)
+		_, err = client.reclaim(context.Background(), time.Now())
+		require.NoError(t, err)
+	}
+	dead, err := client.Dead(context.Background(), 10)
+	require.NoError(t, err)
+	require.Len(t, dead, 1)
+	require.Equal(t, 3, dead[0].Attempts)
+	require.Equal(t, "lease expired", dead[0].LastError)
+	require.False(t, mr.Exists("jobs:scheduled"))
+}
+
+func TestClient_TrimDead(t *testing.T) {
+	client, _, mr := setupWorker(t, 1)
+	client.LimitDead(time.Hour, 2)
+
+	bury := func(failedAt time.Time) string {
+		id, err := client.Enqueue(context.Background(), "greet", nil)
+		require.NoError(t, err)
+		job, err := client.claim(context.Background(), time.Now(), time.Minute)
+		require.NoError(t, err)
+		require.NoError(t, client.bury(context.Background(), job, failedAt))
+		return id
+	}
+	expired := bury(time.Now().Add(-2 * time.Hour))
+	oldest := bury(time.Now().Add(-3 * time.Minute))
+	older := bury(time.Now().Add(-2 * time.Minute))
+	newest := bury(time.Now().Add(-time.Minute))
+
+	members, err := mr.ZMembers("jobs:dead")
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{older, newest}, members)
+	for _, id := range []string{expired, oldest} {
+		require.Empty(t, mr.HGet("jobs:data", id), id)
+	}
+}
+
+func TestWorker_Stop(t *testing.T) {
+	t.Run("DrainsRunningJobs", func(t *testing.T) {
+		client, worker, mr := setupWorker(t, 3)
+		started, release := make(chan struct{}), make(chan struct{})
+		worker.Handle("slow", func(context.Context, *Job) error {
+			close(started)
+			<-release
+			return nil
+		})
+		_, err := client.Enqueue(context.Background(), "slow", nil)
+		require.NoError(t, err)
+
+		worker.Start(context.Background())
+		<-started
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			close(release)
+		}()
+		require.NoError(t, worker.Stop(context.Background()))
+		require.False(t, mr.Exists("jobs:data"))
+	})
+
+	t.Run("CancelsJobsAfterDeadline", func(t *testing.T) {
+		client, worker, mr := setupWorker(t, 3)
+		started := make(chan struct{})
+		worker.Handle("stuck", func(ctx context.Context, _ *Job) error {
+			close(started)
+			<-ctx.Done()
+			return ctx.Err()
+		})
+		id, err := client.Enqueue(context.Background(), "stuck", nil)
+		require.NoError(t, err)
+
+		worker.Start(context.Background())
+		<-started
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		require.ErrorIs(t, worker.Stop(ctx), context.DeadlineExceeded)
+		requireScheduledIn(t, mr, id, time.Minute)
+	})
+}
+
+func TestWorker_RetryDelay(t *testing.T) {
+	_, worker, _ := setupWorker(t, 1)
+	require.Equal(t, time.Minute, worker.retryDelay(1))
+	require.Equal(t, 2*time.Minute, worker.retryDelay(2))
+	require.Equal(t, 3*time.Minute, worker.retryDelay(3))
+	require.Equal(t, 3*time.Minute, worker.retryDelay(40))
+}
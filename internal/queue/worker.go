@@ -0,0 +1,272 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// HandlerFunc runs one job. Returning an error retries it, unless the error
+// is wrapped with Permanent.
+type HandlerFunc func(ctx context.Context, job *Job) error
+
+type permanentError struct{ err error }
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marks err as not worth retrying: the job goes straight to the
+// dead-letter queue.
+func Permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+// IsPermanent reports whether err was wrapped with Permanent.
+func IsPermanent(err error) bool {
+	var permanent *permanentError
+	return errors.As(err, &permanent)
+}
+
+// WorkerOptions configure a Worker. Zero values fall back to the defaults
+// noted on each field.
+type WorkerOptions struct {
+	// Concurrency is how many jobs run at once, 1 by default
+	Concurrency int
+	// PollInterval is how long an idle worker waits before looking for due
+	// jobs again, 1s by default
+	PollInterval time.Duration
+	// Lease is how long a job may run before it is cancelled and handed to
+	// another worker, 5m by default
+	Lease time.Duration
+	// Backoff is the first retry delay, doubled after every failed attempt up
+	// to MaxBackoff; 10s and 1h by default
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+}
+
+// Worker claims due jobs and runs them with the handler registered for their
+// type.
+type Worker struct {
+	client   *Client
+	log      *logrus.Logger
+	tracer   trace.Tracer
+	opts     WorkerOptions
+	handlers map[string]HandlerFunc
+	stop     chan struct{}
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+}
+
+func NewWorker(client *Client, log *logrus.Logger, opts WorkerOptions) *Worker {
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 1
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = time.Second
+	}
+	if opts.Lease <= 0 {
+		opts.Lease = 5 * time.Minute
+	}
+	if opts.Backoff <= 0 {
+		opts.Backoff = 10 * time.Second
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = time.Hour
+	}
+	return &Worker{client: client, log: log, tracer: otel.Tracer("Queue"), opts: opts, handlers: map[string]HandlerFunc{}}
+}
+
+// Handle registers handler for jobs of jobType. Register before Start.
+func (w *Worker) Handle(jobType string, handler HandlerFunc) {
+	w.handlers[jobType] = handler
+}
+
+// Register registers a handler that receives the job payload decoded into T.
+// A payload that does not decode fails the job permanently.
+func Register[T any](w *Worker, jobType string, handler func(ctx context.Context, payload T) error) {
+	w.Handle(jobType, func(ctx context.Context, job *Job) error {
+		var payload T
+		if err := json.Unmarshal(job.Payload, &payload); err != nil {
+			return Permanent(fmt.Errorf("decode %s payload: %w", jobType, err))
+		}
+		return handler(ctx, payload)
+	})
+}
+
+// Start runs Concurrency workers in the background until Stop is called.
+func (w *Worker) Start(ctx context.Context) {
+	ctx, w.cancel = context.WithCancel(ctx)
+	w.stop = make(chan struct{})
+
+	for i := 0; i < w.opts.Concurrency; i++ {
+		w.wg.Add(1)
+		go func() {
+			defer w.wg.Done()
+			w.loop(ctx)
+		}()
+	}
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		w.reclaimLoop(ctx)
+	}()
+	w.log.WithField("concurrency", w.opts.Concurrency).Info("job worker started")
+}
+
+// Stop stops claiming jobs and waits for the running ones to finish. If ctx
+// ends first, the running jobs are cancelled and retried later; Stop still
+// waits for their handlers to return and reports ctx's error.
+func (w *Worker) Stop(ctx context.Context) error {
+	if w.cancel == nil {
+		return nil
+	}
+	close(w.stop)
+
+	done := make(chan struct{})
+	go func() {
+		w.wg.Wait()
+		close(done)
+	}()
+
+	var err error
+	select {
+	case <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+		w.cancel()
+		<-done
+	}
+	w.cancel()
+	w.log.Info("job worker stopped")
+	return err
+}
+
+func (w *Worker) loop(ctx context.Context) {
+	for {
+		select {
+		case <-w.stop:
+			return
+		default:
+		}
+
+		worked, err := w.Work(ctx)
+		if err != nil && ctx.Err() == nil {
+			w.log.WithContext(ctx).WithError(err).Error("job worker failed")
+		}
+		if worked && err == nil {
+			continue
+		}
+
+		select {
+		case <-w.stop:
+			return
+		case <-time.After(w.opts.PollInterval):
+		}
+	}
+}
+
+// reclaimLoop requeues the jobs of workers that died mid-run.
+func (w *Worker) reclaimLoop(ctx context.Context) {
+	ticker := time.NewTicker(w.opts.PollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+			reclaimed, err := w.client.reclaim(ctx, time.Now())
+			if err != nil {
+				w.log.WithError(err).Error("failed to reclaim expired jobs")
+			} else if reclaimed > 0 {
+				w.log.WithField("jobs", reclaimed).Warn("reclaimed jobs whose lease expired")
+			}
+		}
+	}
+}
+
+// Work claims and runs one due job, reporting whether there was one.
+func (w *Worker) Work(ctx context.Context) (bool, error) {
+	job, err := w.client.claim(ctx, time.Now(), w.opts.Lease)
+	if job == nil {
+		return false, err
+	}
+	if err != nil {
+		// Undecodable jobs cannot be retried into shape
+		job.LastError = err.Error()
+		return true, w.client.bury(context.WithoutCancel(ctx), job, time.Now())
+	}
+	return true, w.process(ctx, job)
+}
+
+// process runs job in a span linked to the one that enqueued it, then
+// completes, retries or dead-letters it.
+func (w *Worker) process(ctx context.Context, job *Job) error {
+	enqueued := w.client.propagator.Extract(ctx, propagation.MapCarrier(job.Trace))
+	job.Attempts++
+	spanCtx, span := w.tracer.Start(ctx, "Queue.Process "+job.Type,
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithLinks(trace.LinkFromContext(enqueued)),
+		trace.WithAttributes(
+			attribute.String("job.id", job.ID),
+			attribute.String("job.type", job.Type),
+			attribute.Int("job.attempt", job.Attempts),
+		),
+	)
+	defer span.End()
+
+	logger := w.log.WithContext(spanCtx).WithFields(logrus.Fields{"job_id": job.ID, "job_type": job.Type, "attempt": job.Attempts})
+	runErr := w.run(spanCtx, job)
+	// The outcome is recorded even if the worker is being cancelled
+	settleCtx := context.WithoutCancel(spanCtx)
+	if runErr == nil {
+		return w.client.complete(settleCtx, job.ID)
+	}
+
+	span.RecordError(runErr)
+	span.SetStatus(codes.Error, "job failed")
+	job.LastError = runErr.Error()
+	if IsPermanent(runErr) || job.Attempts >= job.MaxAttempts {
+		logger.WithError(runErr).Error("job failed, moved to dead-letter queue")
+		return w.client.bury(settleCtx, job, time.Now())
+	}
+
+	delay := w.retryDelay(job.Attempts)
+	logger.WithError(runErr).WithField("retry_in", delay.String()).Warn("job failed, retrying")
+	return w.client.retry(settleCtx, job, time.Now().Add(delay))
+}
+
+// run calls the job's handler within its lease, turning panics into errors.
+func (w *Worker) run(ctx context.Context, job *Job) (err error) {
+	handler, ok := w.handlers[job.Type]
+	if !ok {
+		return Permanent(fmt.Errorf("no handler for job type %q", job.Type))
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, w.opts.Lease)
+	defer cancel()
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+	return handler(ctx, job)
+}
+
+// retryDelay doubles the backoff after every failed attempt, up to MaxBackoff.
+func (w *Worker) retryDelay(attempts int) time.Duration {
+	delay := w.opts.Backoff
+	for i := 1; i < attempts && delay < w.opts.MaxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, w.opts.MaxBackoff)
+}
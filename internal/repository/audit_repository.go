@@ -0,0 +1,205 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go-starter-template/internal/audit"
+	"go-starter-template/internal/model"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// auditLockID is the advisory lock serializing appends to the audit log, so
+// every event is chained to the one committed before it.
+const auditLockID = 7_245_002
+
+const auditColumns = `id, uuid, actor_uuid, action, target_type, target_id, ip, user_agent, trace_id, changes, metadata, created_at, prev_hash, hash`
+
+// AuditFilter selects audit events. Empty fields and zero times match
+// everything; BeforeID pages backwards from a previous result.
+type AuditFilter struct {
+	ActorUUID  string
+	TargetType string
+	TargetID   string
+	Action     string
+	From       time.Time
+	To         time.Time
+	BeforeID   int64
+	Limit      int
+}
+
+// AuditVerification is the result of checking the hash chain.
+type AuditVerification struct {
+	Checked int64
+	// BrokenAt is the id of the first event that does not match its hash or
+	// its predecessor, 0 if the chain is intact
+	BrokenAt int64
+}
+
+type AuditRepository struct {
+	*Repository
+	tracer trace.Tracer
+}
+
+func NewAuditRepository(db *sql.DB) *AuditRepository {
+	return &AuditRepository{Repository: &Repository{db: db}, tracer: otel.Tracer("AuditRepository")}
+}
+
+// Append chains e to the latest event and stores it in the transaction
+// carried by ctx, setting its UUID, CreatedAt and hashes. The chain lock is
+// held until that transaction ends, so audited transactions commit one at a
+// time from here on; append last. Every audited write in the app (logins,
+// refreshes, logouts, user changes) queues on this one lock, which caps their
+// throughput at one commit round trip each, so keep slow work such as Redis
+// or HTTP calls out of audited transactions.
+func (r *AuditRepository) Append(ctx context.Context, e *model.AuditEvent) error {
+	spanCtx, span := r.tracer.Start(ctx, "AuditRepository.Append")
+	defer span.End()
+
+	if tx, ok := ctx.Value(TxKey).(*sql.Tx); !ok || tx == nil {
+		return ErrNoTransaction
+	}
+	executor := r.getExecutor(spanCtx)
+	if _, err := executor.ExecContext(spanCtx, `SELECT pg_advisory_xact_lock($1)`, auditLockID); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "lock audit log failed")
+		return err
+	}
+
+	prevHash := audit.GenesisHash
+	err := executor.QueryRowContext(spanCtx, `SELECT hash FROM audit_events ORDER BY id DESC LIMIT 1`).Scan(&prevHash)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "read audit chain head failed")
+		return err
+	}
+
+	e.UUID = uuid.NewString()
+	e.CreatedAt = time.Now().UTC().Truncate(time.Microsecond)
+	if len(e.Changes) == 0 {
+		e.Changes = []byte(`{}`)
+	}
+	if len(e.Metadata) == 0 {
+		e.Metadata = []byte(`{}`)
+	}
+	e.PrevHash = prevHash
+	e.Hash = audit.Hash(prevHash, e)
+
+	err = executor.QueryRowContext(spanCtx, `
+        INSERT INTO audit_events (uuid, actor_uuid, action, target_type, target_id, ip, user_agent, trace_id, changes, metadata, created_at, prev_hash, hash)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+        RETURNING id
+    `, e.UUID, e.ActorUUID, e.Action, e.TargetType, e.TargetID, e.IP, e.UserAgent, e.TraceID, e.Changes, e.Metadata, e.CreatedAt, e.PrevHash, e.Hash).Scan(&e.ID)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "append audit event failed")
+		return err
+	}
+	return nil
+}
+
+// Search returns the events matching filter, newest first.
+func (r *AuditRepository) Search(ctx context.Context, filter AuditFilter) ([]*model.AuditEvent, error) {
+	spanCtx, span := r.tracer.Start(ctx, "AuditRepository.Search")
+	defer span.End()
+
+	var (
+		conditions []string
+		args       []any
+	)
+	where := func(condition string, arg any) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+	if filter.ActorUUID != "" {
+		where("actor_uuid = $%d", filter.ActorUUID)
+	}
+	if filter.TargetType != "" {
+		where("target_type = $%d", filter.TargetType)
+	}
+	if filter.TargetID != "" {
+		where("target_id = $%d", filter.TargetID)
+	}
+	if filter.Action != "" {
+		where("action = $%d", filter.Action)
+	}
+	if !filter.From.IsZero() {
+		where("created_at >= $%d", filter.From)
+	}
+	if !filter.To.IsZero() {
+		where("created_at <= $%d", filter.To)
+	}
+	if filter.BeforeID > 0 {
+		where("id < $%d", filter.BeforeID)
+	}
+
+	query := `SELECT ` + auditColumns + ` FROM audit_events`
+	if len(conditions) > 0 {
+		query += ` WHERE ` + strings.Join(conditions, " AND ")
+	}
+	args = append(args, filter.Limit)
+	query += fmt.Sprintf(` ORDER BY id DESC LIMIT $%d`, len(args))
+
+	rows, err := r.getExecutor(spanCtx).QueryContext(spanCtx, query, args...)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "search audit events failed")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []*model.AuditEvent
+	for rows.Next() {
+		e, err := scanAuditEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// Verify walks the whole log in order and recomputes every hash, stopping at
+// the first event that was altered or whose predecessor was removed.
+func (r *AuditRepository) Verify(ctx context.Context) (*AuditVerification, error) {
+	spanCtx, span := r.tracer.Start(ctx, "AuditRepository.Verify")
+	defer span.End()
+
+	rows, err := r.getExecutor(spanCtx).QueryContext(spanCtx, `SELECT `+auditColumns+` FROM audit_events ORDER BY id`)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "read audit log failed")
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := new(AuditVerification)
+	prevHash := audit.GenesisHash
+	for rows.Next() {
+		e, err := scanAuditEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		result.Checked++
+		if e.PrevHash != prevHash || audit.Hash(e.PrevHash, e) != e.Hash {
+			result.BrokenAt = e.ID
+			return result, nil
+		}
+		prevHash = e.Hash
+	}
+	return result, rows.Err()
+}
+
+func scanAuditEvent(rows *sql.Rows) (*model.AuditEvent, error) {
+	e := new(model.AuditEvent)
+	err := rows.Scan(&e.ID, &e.UUID, &e.ActorUUID, &e.Action, &e.TargetType, &e.TargetID, &e.IP, &e.UserAgent, &e.TraceID, &e.Changes, &e.Metadata, &e.CreatedAt, &e.PrevHash, &e.Hash)
+	return e, err
+}
@@ -0,0 +1,170 @@
+package repository
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+
+	"go-starter-template/internal/audit"
+	"go-starter-template/internal/model"
+)
+
+var auditEventColumns = []string{"id", "uuid", "actor_uuid", "action", "target_type", "target_id", "ip", "user_agent", "trace_id", "changes", "metadata", "created_at", "prev_hash", "hash"}
+
+// chainedEvents returns n events correctly chained from the genesis hash.
+func chainedEvents(n int) []*model.AuditEvent {
+	events := make([]*model.AuditEvent, n)
+	prevHash := audit.GenesisHash
+	for i := range events {
+		e := &model.AuditEvent{
+			ID:        int64(i + 1),
+			UUID:      "e" + string(rune('1'+i)),
+			ActorUUID: "u1",
+			Action:    audit.ActionLogin,
+			Changes:   []byte(`{}`),
+			Metadata:  []byte(`{}`),
+			CreatedAt: time.Date(2025, 1, 15, 10, i, 0, 0, time.UTC),
+			PrevHash:  prevHash,
+		}
+		e.Hash = audit.Hash(prevHash, e)
+		prevHash = e.Hash
+		events[i] = e
+	}
+	return events
+}
+
+func auditRows(events ...*model.AuditEvent) *sqlmock.Rows {
+	rows := sqlmock.NewRows(auditEventColumns)
+	for _, e := range events {
+		rows.AddRow(e.ID, e.UUID, e.ActorUUID, e.Action, e.TargetType, e.TargetID, e.IP, e.UserAgent, e.TraceID, e.Changes, e.Metadata, e.CreatedAt, e.PrevHash, e.Hash)
+	}
+	return rows
+}
+
+func TestAuditRepository_Append(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := NewAuditRepository(db)
+	uow := NewUnitOfWork(db)
+	lockQuery := regexp.QuoteMeta(`SELECT pg_advisory_xact_lock($1)`)
+	headQuery := regexp.QuoteMeta(`SELECT hash FROM audit_events ORDER BY id DESC LIMIT 1`)
+	insertQuery := regexp.QuoteMeta(`INSERT INTO audit_events`)
+
+	t.Run("RequiresTransaction", func(t *testing.T) {
+		err := repo.Append(context.Background(), &model.AuditEvent{Action: audit.ActionLogin})
+		require.ErrorIs(t, err, ErrNoTransaction)
+	})
+
+	t.Run("FirstEventStartsFromGenesis", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec(lockQuery).WithArgs(auditLockID).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(headQuery).WillReturnRows(sqlmock.NewRows([]string{"hash"}))
+		mock.ExpectQuery(insertQuery).
+			WithArgs(sqlmock.AnyArg(), "u1", audit.ActionLogin, audit.TargetUser, "u1", "", "", "", []byte(`{}`), []byte(`{}`), sqlmock.AnyArg(), audit.GenesisHash, sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectCommit()
+
+		e := &model.AuditEvent{ActorUUID: "u1", Action: audit.ActionLogin, TargetType: audit.TargetUser, TargetID: "u1"}
+		require.NoError(t, uow.Do(context.Background(), func(ctx context.Context) error {
+			return repo.Append(ctx, e)
+		}))
+		require.Equal(t, int64(1), e.ID)
+		require.NotEmpty(t, e.UUID)
+		require.Equal(t, audit.GenesisHash, e.PrevHash)
+		require.Equal(t, audit.Hash(audit.GenesisHash, e), e.Hash)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("ChainsToLatestEvent", func(t *testing.T) {
+		head := chainedEvents(1)[0]
+		mock.ExpectBegin()
+		mock.ExpectExec(lockQuery).WithArgs(auditLockID).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(headQuery).WillReturnRows(sqlmock.NewRows([]string{"hash"}).AddRow(head.Hash))
+		mock.ExpectQuery(insertQuery).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+		mock.ExpectCommit()
+
+		e := &model.AuditEvent{Action: audit.ActionUserPurged, Metadata: []byte(`{"count":2}`)}
+		require.NoError(t, uow.Do(context.Background(), func(ctx context.Context) error {
+			return repo.Append(ctx, e)
+		}))
+		require.Equal(t, head.Hash, e.PrevHash)
+		require.Equal(t, audit.Hash(head.Hash, e), e.Hash)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestAuditRepository_Search(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := NewAuditRepository(db)
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM audit_events WHERE actor_uuid = $1 AND target_id = $2 AND created_at >= $3 AND id < $4 ORDER BY id DESC LIMIT $5`)).
+		WithArgs("u1", "u2", from, int64(10), 2).
+		WillReturnRows(auditRows(chainedEvents(2)...))
+
+	events, err := repo.Search(context.Background(), AuditFilter{ActorUUID: "u1", TargetID: "u2", From: from, BeforeID: 10, Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	require.Equal(t, audit.ActionLogin, events[0].Action)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAuditRepository_Verify(t *testing.T) {
+	selectQuery := regexp.QuoteMeta(`FROM audit_events ORDER BY id`)
+
+	cases := []struct {
+		name          string
+		events        func() []*model.AuditEvent
+		expectChecked int64
+		expectBroken  int64
+	}{
+		{
+			name:          "Intact",
+			events:        func() []*model.AuditEvent { return chainedEvents(3) },
+			expectChecked: 3,
+		},
+		{
+			name: "Altered",
+			events: func() []*model.AuditEvent {
+				events := chainedEvents(3)
+				events[1].ActorUUID = "someone-else"
+				return events
+			},
+			expectChecked: 2,
+			expectBroken:  2,
+		},
+		{
+			name: "Removed",
+			events: func() []*model.AuditEvent {
+				events := chainedEvents(3)
+				return []*model.AuditEvent{events[0], events[2]}
+			},
+			expectChecked: 2,
+			expectBroken:  3,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			mock.ExpectQuery(selectQuery).WillReturnRows(auditRows(tc.events()...))
+			result, err := NewAuditRepository(db).Verify(context.Background())
+			require.NoError(t, err)
+			require.Equal(t, tc.expectChecked, result.Checked)
+			require.Equal(t, tc.expectBroken, result.BrokenAt)
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidFilter is returned for filter values outside their allowed set.
+var ErrInvalidFilter = errors.New("invalid filter")
+
+// filterBuilder collects SQL predicates together with their positional
+// arguments. Values only ever reach the query as $n placeholders, so callers
+// never concatenate user input into SQL.
+type filterBuilder struct {
+	scope      []string // always ANDed, e.g. the soft-delete condition
+	predicates []string // combined with AND or OR depending on matchAny
+	args       []any
+	matchAny   bool
+}
+
+// Arg registers a value and returns its placeholder.
+func (b *filterBuilder) Arg(value any) string {
+	b.args = append(b.args, value)
+	return fmt.Sprintf("$%d", len(b.args))
+}
+
+// In registers each value and returns a parenthesized placeholder list.
+func (b *filterBuilder) In(values []string) string {
+	placeholders := make([]string, len(values))
+	for i, value := range values {
+		placeholders[i] = b.Arg(value)
+	}
+	return "(" + strings.Join(placeholders, ", ") + ")"
+}
+
+// Scope adds a predicate every row must satisfy regardless of matchAny.
+func (b *filterBuilder) Scope(predicate string) {
+	b.scope = append(b.scope, predicate)
+}
+
+// Where adds a user-facing filter predicate.
+func (b *filterBuilder) Where(predicate string) {
+	b.predicates = append(b.predicates, predicate)
+}
+
+// Conditions returns the predicates to be joined with AND: the scope followed
+// by the filters, which are grouped into one OR term when matchAny is set.
+func (b *filterBuilder) Conditions() []string {
+	conditions := append([]string{}, b.scope...)
+	if b.matchAny && len(b.predicates) > 1 {
+		return append(conditions, "("+strings.Join(b.predicates, " OR ")+")")
+	}
+	return append(conditions, b.predicates...)
+}
+
+// Args returns the collected arguments in placeholder order.
+func (b *filterBuilder) Args() []any {
+	return b.args
+}
+
+// splitList splits a comma-separated query value, dropping empty entries.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilterBuilder(t *testing.T) {
+	cases := []struct {
+		name       string
+		matchAny   bool
+		build      func(b *filterBuilder)
+		conditions []string
+		args       []any
+	}{
+		{
+			name: "MatchAll",
+			build: func(b *filterBuilder) {
+				b.Scope("deleted_at IS NULL")
+				b.Where("name ILIKE " + b.Arg("%a%"))
+				b.Where("status IN " + b.In([]string{"active", "pending"}))
+			},
+			conditions: []string{"deleted_at IS NULL", "name ILIKE $1", "status IN ($2, $3)"},
+			args:       []any{"%a%", "active", "pending"},
+		},
+		{
+			name:     "MatchAnyKeepsScopeSeparate",
+			matchAny: true,
+			build: func(b *filterBuilder) {
+				b.Scope("deleted_at IS NULL")
+				b.Where("name ILIKE " + b.Arg("%a%"))
+				b.Where("email ILIKE " + b.Arg("%b%"))
+			},
+			conditions: []string{"deleted_at IS NULL", "(name ILIKE $1 OR email ILIKE $2)"},
+			args:       []any{"%a%", "%b%"},
+		},
+		{
+			name:     "MatchAnySinglePredicate",
+			matchAny: true,
+			build: func(b *filterBuilder) {
+				b.Where("name ILIKE " + b.Arg("%a%"))
+			},
+			conditions: []string{"name ILIKE $1"},
+			args:       []any{"%a%"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			b := &filterBuilder{matchAny: tc.matchAny}
+			tc.build(b)
+			require.Equal(t, tc.conditions, b.Conditions())
+			require.Equal(t, tc.args, b.Args())
+		})
+	}
+}
+
+func TestSplitList(t *testing.T) {
+	require.Equal(t, []string{"admin", "editor"}, splitList(" admin, ,editor,"))
+	require.Nil(t, splitList(""))
+}
@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/goccy/go-json"
+)
+
+var (
+	// ErrInvalidSort is returned for sort fields outside a repository's whitelist.
+	ErrInvalidSort = errors.New("invalid sort field")
+	// ErrInvalidCursor is returned for cursors that cannot be decoded or were
+	// issued for a different sort order.
+	ErrInvalidCursor = errors.New("invalid cursor")
+)
+
+// sortField is one ORDER BY term.
+type sortField struct {
+	Column string
+	Desc   bool
+}
+
+// parseSort turns "name,-created_at" into sort fields, rejecting columns not in
+// allowed. An empty sort falls back to fallback. tiebreak (a unique column) is
+// always appended so the order is total, which keyset pagination relies on.
+func parseSort(sort, fallback string, allowed map[string]bool, tiebreak string) ([]sortField, error) {
+	if strings.TrimSpace(sort) == "" {
+		sort = fallback
+	}
+
+	var fields []sortField
+	seen := map[string]bool{}
+	for _, term := range strings.Split(sort, ",") {
+		term = strings.TrimSpace(term)
+		field := sortField{Column: strings.TrimPrefix(term, "-"), Desc: strings.HasPrefix(term, "-")}
+		if !allowed[field.Column] || seen[field.Column] {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidSort, term)
+		}
+		seen[field.Column] = true
+		fields = append(fields, field)
+	}
+	return append(fields, sortField{Column: tiebreak}), nil
+}
+
+// sortKey renders fields back into their canonical "name,-created_at" form.
+func sortKey(fields []sortField) string {
+	terms := make([]string, len(fields))
+	for i, field := range fields {
+		terms[i] = field.Column
+		if field.Desc {
+			terms[i] = "-" + field.Column
+		}
+	}
+	return strings.Join(terms, ",")
+}
+
+// orderBy renders an ORDER BY clause, optionally with every direction flipped.
+func orderBy(fields []sortField, reverse bool) string {
+	terms := make([]string, len(fields))
+	for i, field := range fields {
+		direction := "ASC"
+		if field.Desc != reverse {
+			direction = "DESC"
+		}
+		terms[i] = field.Column + " " + direction
+	}
+	return "ORDER BY " + strings.Join(terms, ", ")
+}
+
+// keysetCondition builds the predicate selecting rows strictly after values in
+// the given order (or strictly before when reverse is set), e.g. for
+// name ASC, uuid ASC: (name > $1) OR (name = $1 AND uuid > $2).
+// Placeholders are numbered from len(args)+1; the extended args are returned.
+func keysetCondition(fields []sortField, values []any, reverse bool, args []any) (string, []any) {
+	placeholders := make([]string, len(fields))
+	for i := range fields {
+		args = append(args, values[i])
+		placeholders[i] = fmt.Sprintf("$%d", len(args))
+	}
+
+	alternatives := make([]string, len(fields))
+	for i, field := range fields {
+		terms := make([]string, 0, i+1)
+		for j := 0; j < i; j++ {
+			terms = append(terms, fields[j].Column+" = "+placeholders[j])
+		}
+		op := ">"
+		if field.Desc != reverse {
+			op = "<"
+		}
+		terms = append(terms, field.Column+" "+op+" "+placeholders[i])
+		alternatives[i] = "(" + strings.Join(terms, " AND ") + ")"
+	}
+	return "(" + strings.Join(alternatives, " OR ") + ")", args
+}
+
+// cursor is the decoded form of the opaque pagination token handed to clients.
+type cursor struct {
+	Sort   string `json:"s"`
+	Before bool   `json:"b,omitempty"`
+	Values []any  `json:"v"`
+}
+
+func encodeCursor(c cursor) string {
+	raw, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// decodeCursor parses a token and checks it belongs to the current sort order
+// and carries one value per sort field.
+func decodeCursor(token string, fields []sortField) (cursor, error) {
+	var c cursor
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return c, ErrInvalidCursor
+	}
+	if err := json.Unmarshal(raw, &c); err != nil {
+		return c, ErrInvalidCursor
+	}
+	if c.Sort != sortKey(fields) || len(c.Values) != len(fields) {
+		return c, ErrInvalidCursor
+	}
+	return c, nil
+}
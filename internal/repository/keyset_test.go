@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSort(t *testing.T) {
+	allowed := map[string]bool{"name": true, "created_at": true}
+
+	cases := []struct {
+		name      string
+		sort      string
+		expected  []sortField
+		expectErr bool
+	}{
+		{name: "Fallback", sort: "", expected: []sortField{{Column: "created_at", Desc: true}, {Column: "uuid"}}},
+		{name: "MultipleFields", sort: "name, -created_at", expected: []sortField{{Column: "name"}, {Column: "created_at", Desc: true}, {Column: "uuid"}}},
+		{name: "NotWhitelisted", sort: "password", expectErr: true},
+		{name: "Duplicate", sort: "name,-name", expectErr: true},
+		{name: "EmptyTerm", sort: "name,", expectErr: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			fields, err := parseSort(tc.sort, "-created_at", allowed, "uuid")
+			if tc.expectErr {
+				require.ErrorIs(t, err, ErrInvalidSort)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, fields)
+		})
+	}
+}
+
+func TestKeysetCondition(t *testing.T) {
+	fields := []sortField{{Column: "name"}, {Column: "created_at", Desc: true}, {Column: "uuid"}}
+
+	condition, args := keysetCondition(fields, []any{"bob", "t", "u1"}, false, []any{"%x%"})
+	require.Equal(t, "((name > $2) OR (name = $2 AND created_at < $3) OR (name = $2 AND created_at = $3 AND uuid > $4))", condition)
+	require.Equal(t, []any{"%x%", "bob", "t", "u1"}, args)
+
+	condition, _ = keysetCondition(fields, []any{"bob", "t", "u1"}, true, nil)
+	require.Equal(t, "((name < $1) OR (name = $1 AND created_at > $2) OR (name = $1 AND created_at = $2 AND uuid < $3))", condition)
+	require.Equal(t, "ORDER BY name DESC, created_at ASC, uuid DESC", orderBy(fields, true))
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	fields := []sortField{{Column: "name"}, {Column: "uuid"}}
+	token := encodeCursor(cursor{Sort: sortKey(fields), Before: true, Values: []any{"bob", "u1"}})
+
+	decoded, err := decodeCursor(token, fields)
+	require.NoError(t, err)
+	require.True(t, decoded.Before)
+	require.Equal(t, []any{"bob", "u1"}, decoded.Values)
+
+	// A cursor issued for another sort order is rejected
+	_, err = decodeCursor(token, []sortField{{Column: "created_at", Desc: true}, {Column: "uuid"}})
+	require.ErrorIs(t, err, ErrInvalidCursor)
+
+	_, err = decodeCursor("%%%", fields)
+	require.ErrorIs(t, err, ErrInvalidCursor)
+}
@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"go-starter-template/internal/model"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const loginEventColumns = `id, uuid, user_uuid, success, failure_reason, ip, user_agent, device, country, region, city, new_device, new_country, created_at`
+
+// KnownLogin tells what a user's earlier successful logins have in common
+// with a new one.
+type KnownLogin struct {
+	// Any is false before the first successful login
+	Any     bool
+	Device  bool
+	Country bool
+}
+
+type LoginEventRepository struct {
+	*Repository
+	tracer trace.Tracer
+}
+
+func NewLoginEventRepository(db *sql.DB) *LoginEventRepository {
+	return &LoginEventRepository{Repository: &Repository{db: db}, tracer: otel.Tracer("LoginEventRepository")}
+}
+
+// Create stores e, setting its ID, UUID and CreatedAt.
+func (r *LoginEventRepository) Create(ctx context.Context, e *model.LoginEvent) error {
+	spanCtx, span := r.tracer.Start(ctx, "LoginEventRepository.Create")
+	defer span.End()
+
+	e.UUID = uuid.NewString()
+	e.CreatedAt = time.Now()
+	err := r.getExecutor(spanCtx).QueryRowContext(spanCtx, `
+        INSERT INTO login_events (uuid, user_uuid, success, failure_reason, ip, user_agent, device, country, region, city, new_device, new_country, created_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+        RETURNING id
+    `, e.UUID, e.UserUUID, e.Success, e.FailureReason, e.IP, e.UserAgent, e.Device, e.Country, e.Region, e.City, e.NewDevice, e.NewCountry, e.CreatedAt).Scan(&e.ID)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "create login event failed")
+		return err
+	}
+	return nil
+}
+
+// Known reports whether the user has logged in successfully before, and
+// whether from device and from country.
+func (r *LoginEventRepository) Known(ctx context.Context, userUUID, device, country string) (KnownLogin, error) {
+	spanCtx, span := r.tracer.Start(ctx, "LoginEventRepository.Known")
+	defer span.End()
+
+	var known KnownLogin
+	err := r.getExecutor(spanCtx).QueryRowContext(spanCtx, `
+        SELECT COUNT(*) > 0,
+               COALESCE(BOOL_OR(device = $2), FALSE),
+               COALESCE(BOOL_OR(country = $3), FALSE)
+        FROM login_events
+        WHERE user_uuid = $1 AND success
+    `, userUUID, device, country).Scan(&known.Any, &known.Device, &known.Country)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "read known logins failed")
+		return KnownLogin{}, err
+	}
+	return known, nil
+}
+
+// ListByUser returns up to limit logins of the user, newest first, starting
+// below beforeID when it is positive.
+func (r *LoginEventRepository) ListByUser(ctx context.Context, userUUID string, beforeID int64, limit int) ([]*model.LoginEvent, error) {
+	spanCtx, span := r.tracer.Start(ctx, "LoginEventRepository.ListByUser")
+	defer span.End()
+
+	rows, err := r.getExecutor(spanCtx).QueryContext(spanCtx, `
+        SELECT `+loginEventColumns+`
+        FROM login_events
+        WHERE user_uuid = $1 AND ($2 = 0 OR id < $2)
+        ORDER BY id DESC
+        LIMIT $3
+    `, userUUID, beforeID, limit)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "list login events failed")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []*model.LoginEvent
+	for rows.Next() {
+		e := new(model.LoginEvent)
+		if err := rows.Scan(&e.ID, &e.UUID, &e.UserUUID, &e.Success, &e.FailureReason, &e.IP, &e.UserAgent, &e.Device, &e.Country, &e.Region, &e.City, &e.NewDevice, &e.NewCountry, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
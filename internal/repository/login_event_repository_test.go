@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+
+	"go-starter-template/internal/model"
+)
+
+func TestLoginEventRepository_Create(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO login_events`)).
+		WithArgs(sqlmock.AnyArg(), "u1", true, "", "8.8.8.8", "curl/8", "curl", "US", "", "", true, false, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+
+	e := &model.LoginEvent{UserUUID: "u1", Success: true, IP: "8.8.8.8", UserAgent: "curl/8", Device: "curl", Country: "US", NewDevice: true}
+	require.NoError(t, NewLoginEventRepository(db).Create(context.Background(), e))
+	require.Equal(t, int64(7), e.ID)
+	require.NotEmpty(t, e.UUID)
+	require.False(t, e.CreatedAt.IsZero())
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestLoginEventRepository_Known(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM login_events WHERE user_uuid = $1 AND success`)).
+		WithArgs("u1", "Chrome on macOS", "DE").
+		WillReturnRows(sqlmock.NewRows([]string{"any", "device", "country"}).AddRow(true, false, true))
+
+	known, err := NewLoginEventRepository(db).Known(context.Background(), "u1", "Chrome on macOS", "DE")
+	require.NoError(t, err)
+	require.Equal(t, KnownLogin{Any: true, Country: true}, known)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestLoginEventRepository_ListByUser(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	now := time.Now()
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM login_events WHERE user_uuid = $1 AND ($2 = 0 OR id < $2) ORDER BY id DESC LIMIT $3`)).
+		WithArgs("u1", int64(0), 20).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "uuid", "user_uuid", "success", "failure_reason", "ip", "user_agent", "device", "country", "region", "city", "new_device", "new_country", "created_at"}).
+			AddRow(2, "l2", "u1", false, "suspended", "", "", "", "", "", "", false, false, now).
+			AddRow(1, "l1", "u1", true, "", "8.8.8.8", "curl/8", "curl", "US", "", "", false, false, now))
+
+	logins, err := NewLoginEventRepository(db).ListByUser(context.Background(), "u1", 0, 20)
+	require.NoError(t, err)
+	require.Len(t, logins, 2)
+	require.Equal(t, "suspended", logins[0].FailureReason)
+	require.Equal(t, "US", logins[1].Country)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
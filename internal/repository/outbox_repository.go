@@ -0,0 +1,125 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"go-starter-template/internal/event"
+	"go-starter-template/internal/model"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// outboxLockID is the advisory lock a relay holds while publishing, so only
+// one instance publishes at a time and events leave in order.
+const outboxLockID = 7_245_001
+
+// ErrNoTransaction is returned by Append outside a transaction, where the
+// events could be stored without the change they describe or vice versa.
+var ErrNoTransaction = errors.New("outbox append requires a transaction")
+
+type OutboxRepository struct {
+	*Repository
+	tracer trace.Tracer
+}
+
+func NewOutboxRepository(db *sql.DB) *OutboxRepository {
+	return &OutboxRepository{Repository: &Repository{db: db}, tracer: otel.Tracer("OutboxRepository")}
+}
+
+// Append stores events in the transaction carried by ctx. Append after
+// writing the aggregate's row: the row lock then orders concurrent changes
+// and their events alike.
+func (r *OutboxRepository) Append(ctx context.Context, events ...event.Event) error {
+	spanCtx, span := r.tracer.Start(ctx, "OutboxRepository.Append")
+	defer span.End()
+
+	if tx, ok := ctx.Value(TxKey).(*sql.Tx); !ok || tx == nil {
+		return ErrNoTransaction
+	}
+	for _, e := range events {
+		payload, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		_, err = r.getExecutor(spanCtx).ExecContext(spanCtx, `
+        INSERT INTO outbox (uuid, aggregate_type, aggregate_id, event_type, payload)
+        VALUES ($1, $2, $3, $4, $5)
+    `, uuid.NewString(), e.AggregateType(), e.AggregateID(), e.EventType(), payload)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "append outbox event failed")
+			return err
+		}
+	}
+	return nil
+}
+
+// Claim takes the relay lock and returns up to limit unpublished events that
+// are not parked, oldest first. It returns nothing when another relay holds the lock. Call
+// it inside a transaction; the lock is released when that ends.
+func (r *OutboxRepository) Claim(ctx context.Context, limit int) ([]*model.OutboxEvent, error) {
+	spanCtx, span := r.tracer.Start(ctx, "OutboxRepository.Claim")
+	defer span.End()
+
+	executor := r.getExecutor(spanCtx)
+	var locked bool
+	if err := executor.QueryRowContext(spanCtx, `SELECT pg_try_advisory_xact_lock($1)`, outboxLockID).Scan(&locked); err != nil || !locked {
+		return nil, err
+	}
+
+	rows, err := executor.QueryContext(spanCtx, `
+        SELECT id, uuid, aggregate_type, aggregate_id, event_type, payload, created_at, attempts, last_error
+        FROM outbox WHERE published_at IS NULL AND parked_at IS NULL ORDER BY id LIMIT $1
+    `, limit)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "claim outbox events failed")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []*model.OutboxEvent
+	for rows.Next() {
+		e := new(model.OutboxEvent)
+		if err := rows.Scan(&e.ID, &e.UUID, &e.AggregateType, &e.AggregateID, &e.EventType, &e.Payload, &e.CreatedAt, &e.Attempts, &e.LastError); err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// MarkPublished records that the event with id was delivered.
+func (r *OutboxRepository) MarkPublished(ctx context.Context, id int64) error {
+	_, err := r.getExecutor(ctx).ExecContext(ctx, `UPDATE outbox SET published_at = NOW() WHERE id = $1`, id)
+	return err
+}
+
+// MarkFailed counts a failed delivery of the event with id and keeps its error.
+func (r *OutboxRepository) MarkFailed(ctx context.Context, id int64, reason string) error {
+	_, err := r.getExecutor(ctx).ExecContext(ctx, `UPDATE outbox SET attempts = attempts + 1, last_error = $2 WHERE id = $1`, id, reason)
+	return err
+}
+
+// Park counts a last failed delivery of the event with id, keeps its error
+// and takes the event out of the relay so it no longer holds back its
+// aggregate.
+func (r *OutboxRepository) Park(ctx context.Context, id int64, reason string) error {
+	_, err := r.getExecutor(ctx).ExecContext(ctx, `UPDATE outbox SET attempts = attempts + 1, last_error = $2, parked_at = NOW() WHERE id = $1`, id, reason)
+	return err
+}
+
+// PrunePublished deletes events published before before.
+func (r *OutboxRepository) PrunePublished(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.getExecutor(ctx).ExecContext(ctx, `DELETE FROM outbox WHERE published_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+
+	"go-starter-template/internal/event"
+	"go-starter-template/internal/model"
+)
+
+func TestOutboxRepository_Append(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := NewOutboxRepository(db)
+	uow := NewUnitOfWork(db)
+	user := event.ForUser(&model.User{UUID: "u1", Name: "Alice", Version: 1})
+
+	t.Run("RequiresTransaction", func(t *testing.T) {
+		err := repo.Append(context.Background(), event.UserCreated{UserEvent: user})
+		require.ErrorIs(t, err, ErrNoTransaction)
+	})
+
+	t.Run("InsertsEachEvent", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO outbox (uuid, aggregate_type, aggregate_id, event_type, payload)")).
+			WithArgs(sqlmock.AnyArg(), event.AggregateUser, "u1", event.TypeUserCreated, sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO outbox (uuid, aggregate_type, aggregate_id, event_type, payload)")).
+			WithArgs(sqlmock.AnyArg(), event.AggregateUser, "u1", event.TypeRoleAssigned, []byte(`{"user":{"uuid":"u1","name":"Alice","email":"","status":"","phone":null,"version":1},"role":"admin"}`)).
+			WillReturnResult(sqlmock.NewResult(2, 1))
+		mock.ExpectCommit()
+
+		err := uow.Do(context.Background(), func(ctx context.Context) error {
+			return repo.Append(ctx, event.UserCreated{UserEvent: user}, event.RoleAssigned{UserEvent: user, Role: "admin"})
+		})
+		require.NoError(t, err)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestOutboxRepository_Claim(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := NewOutboxRepository(db)
+	lockQuery := regexp.QuoteMeta(`SELECT pg_try_advisory_xact_lock($1)`)
+	selectQuery := regexp.QuoteMeta(`FROM outbox WHERE published_at IS NULL AND parked_at IS NULL ORDER BY id LIMIT $1`)
+
+	t.Run("LockHeldElsewhere", func(t *testing.T) {
+		mock.ExpectQuery(lockQuery).WithArgs(outboxLockID).
+			WillReturnRows(sqlmock.NewRows([]string{"locked"}).AddRow(false))
+
+		events, err := repo.Claim(context.Background(), 10)
+		require.NoError(t, err)
+		require.Empty(t, events)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("ReturnsOldestFirst", func(t *testing.T) {
+		now := time.Now()
+		mock.ExpectQuery(lockQuery).WithArgs(outboxLockID).
+			WillReturnRows(sqlmock.NewRows([]string{"locked"}).AddRow(true))
+		mock.ExpectQuery(selectQuery).WithArgs(10).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "uuid", "aggregate_type", "aggregate_id", "event_type", "payload", "created_at", "attempts", "last_error"}).
+				AddRow(1, "e1", "user", "u1", "user.created", []byte(`{}`), now, 0, "").
+				AddRow(2, "e2", "user", "u1", "user.updated", []byte(`{}`), now, 2, "timeout"))
+
+		events, err := repo.Claim(context.Background(), 10)
+		require.NoError(t, err)
+		require.Len(t, events, 2)
+		require.Equal(t, "e1", events[0].UUID)
+		require.Equal(t, 2, events[1].Attempts)
+		require.Equal(t, "timeout", events[1].LastError)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
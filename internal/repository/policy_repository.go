@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"go-starter-template/internal/constant"
+	"go-starter-template/internal/model"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type PolicyRepository struct {
+	*Repository
+	tracer trace.Tracer
+}
+
+func NewPolicyRepository(db *sql.DB) *PolicyRepository {
+	return &PolicyRepository{Repository: &Repository{db: db}, tracer: otel.Tracer("PolicyRepository")}
+}
+
+// FindByAction returns the enabled policies that apply to action (including
+// wildcard policies), highest priority first.
+func (r *PolicyRepository) FindByAction(ctx context.Context, action string) ([]model.Policy, error) {
+	spanCtx, span := r.tracer.Start(ctx, "PolicyRepository.FindByAction")
+	defer span.End()
+
+	rows, err := r.getExecutor(spanCtx).QueryContext(spanCtx, `
+        SELECT uuid, name, description, action, effect, condition, priority, enabled, created_at, updated_at
+        FROM policies
+        WHERE enabled = TRUE AND (action = $1 OR action = $2)
+        ORDER BY priority DESC, name ASC
+    `, action, constant.PolicyActionWildcard)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "query policies failed")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var policies []model.Policy
+	for rows.Next() {
+		var p model.Policy
+		if err := rows.Scan(&p.UUID, &p.Name, &p.Description, &p.Action, &p.Effect, &p.Condition, &p.Priority, &p.Enabled, &p.CreatedAt, &p.UpdatedAt); err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "scan policy failed")
+			return nil, err
+		}
+		policies = append(policies, p)
+	}
+	if err := rows.Err(); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "iterate policies failed")
+		return nil, err
+	}
+
+	return policies, nil
+}
@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+
+	"go-starter-template/internal/model"
+)
+
+func TestPolicyRepository_FindByAction(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := NewPolicyRepository(db)
+
+	query := `
+        SELECT uuid, name, description, action, effect, condition, priority, enabled, created_at, updated_at
+        FROM policies
+        WHERE enabled = TRUE AND (action = $1 OR action = $2)
+        ORDER BY priority DESC, name ASC
+    `
+	columns := []string{"uuid", "name", "description", "action", "effect", "condition", "priority", "enabled", "created_at", "updated_at"}
+	now := time.Now()
+
+	cases := []struct {
+		name      string
+		setupMock func()
+		assert    func(t *testing.T, policies []model.Policy, err error)
+	}{
+		{
+			name: "Success",
+			setupMock: func() {
+				mock.ExpectQuery(regexp.QuoteMeta(query)).
+					WithArgs("user:update", "*").
+					WillReturnRows(sqlmock.NewRows(columns).
+						AddRow("p1", "deny-weekend", "", "*", "deny", `request.weekday == "Sunday"`, 100, true, now, now).
+						AddRow("p2", "managers-update", "", "user:update", "allow", `"manager" in subject.roles`, 0, true, now, now))
+			},
+			assert: func(t *testing.T, policies []model.Policy, err error) {
+				require.NoError(t, err)
+				require.Len(t, policies, 2)
+				require.Equal(t, "deny-weekend", policies[0].Name)
+				require.Equal(t, 100, policies[0].Priority)
+				require.Equal(t, "allow", policies[1].Effect)
+			},
+		},
+		{
+			name: "QueryError",
+			setupMock: func() {
+				mock.ExpectQuery(regexp.QuoteMeta(query)).
+					WithArgs("user:update", "*").
+					WillReturnError(errors.New("db error"))
+			},
+			assert: func(t *testing.T, policies []model.Policy, err error) {
+				require.Error(t, err)
+			},
+		},
+		{
+			name: "ScanError",
+			setupMock: func() {
+				mock.ExpectQuery(regexp.QuoteMeta(query)).
+					WithArgs("user:update", "*").
+					// NULL value to force scan error into string field
+					WillReturnRows(sqlmock.NewRows(columns).
+						AddRow(nil, "bad", "", "*", "deny", "true", 0, true, now, now))
+			},
+			assert: func(t *testing.T, policies []model.Policy, err error) {
+				require.Error(t, err)
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			c.setupMock()
+			policies, err := repo.FindByAction(context.Background(), "user:update")
+			c.assert(t, policies, err)
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -9,6 +9,8 @@ type contextKey string
 
 var TxKey contextKey = "tx"
 
+var primaryKey contextKey = "primary"
+
 // SQLExecutor is implemented by both *sql.DB and *sql.Tx
 type SQLExecutor interface {
 	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
@@ -16,15 +18,70 @@ type SQLExecutor interface {
 	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
 }
 
+// ReplicaSet picks a read replica, returning nil when none is available.
+// It is implemented by database.Replicas.
+type ReplicaSet interface {
+	Reader() *sql.DB
+}
+
 type Repository struct {
 	db *sql.DB
+	// replicas serve reads outside transactions when set; see UseReplicas
+	replicas ReplicaSet
+}
+
+// UseReplicas sends the repository's reads to replicas while they are
+// healthy. Writes, transactions and contexts marked with WithPrimary keep
+// using the primary.
+func (r *Repository) UseReplicas(replicas ReplicaSet) {
+	r.replicas = replicas
+}
+
+// WithPrimary marks ctx so reads go to the primary, giving read-your-writes
+// consistency for work that must not observe replica lag.
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey, true)
+}
+
+// UsesPrimary reports whether ctx was marked with WithPrimary.
+func UsesPrimary(ctx context.Context) bool {
+	primary, _ := ctx.Value(primaryKey).(bool)
+	return primary
 }
 
 // getExecutor returns an executor that is either the active *sql.Tx (if present in ctx)
 // or the base *sql.DB. Use this when calling ExecContext/QueryContext methods.
+// With replicas configured, queries outside a transaction go to a healthy
+// replica unless ctx is marked WithPrimary.
 func (r *Repository) getExecutor(ctx context.Context) SQLExecutor {
 	if tx, ok := ctx.Value(TxKey).(*sql.Tx); ok && tx != nil {
 		return tx
 	}
+	if r.replicas == nil || UsesPrimary(ctx) {
+		return r.db
+	}
+	if reader := r.replicas.Reader(); reader != nil {
+		return routedExecutor{primary: r.db, reader: reader}
+	}
 	return r.db
 }
+
+// routedExecutor sends queries to a replica and statements to the primary.
+// Repositories only query with SELECT and write through ExecContext; a write
+// returning rows must run in a transaction or under WithPrimary.
+type routedExecutor struct {
+	primary *sql.DB
+	reader  *sql.DB
+}
+
+func (e routedExecutor) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return e.primary.ExecContext(ctx, query, args...)
+}
+
+func (e routedExecutor) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return e.reader.QueryContext(ctx, query, args...)
+}
+
+func (e routedExecutor) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return e.reader.QueryRowContext(ctx, query, args...)
+}
@@ -79,4 +79,68 @@ func TestRepository_getExecutor(t *testing.T) {
             }
         })
     }
-}
\ No newline at end of file
+}
+
+type fakeReplicaSet struct {
+    db *sql.DB
+}
+
+func (f fakeReplicaSet) Reader() *sql.DB {
+    return f.db
+}
+
+func TestRepository_getExecutor_Replicas(t *testing.T) {
+    primary, primaryMock, err := sqlmock.New()
+    if err != nil {
+        t.Fatalf("failed to create sqlmock: %v", err)
+    }
+    defer primary.Close()
+    replica, replicaMock, err := sqlmock.New()
+    if err != nil {
+        t.Fatalf("failed to create sqlmock: %v", err)
+    }
+    defer replica.Close()
+
+    repo := &Repository{db: primary}
+    repo.UseReplicas(fakeReplicaSet{db: replica})
+
+    // Queries go to the replica, statements to the primary
+    replicaMock.ExpectQuery("SELECT 1").WillReturnRows(sqlmock.NewRows([]string{"n"}).AddRow(1))
+    primaryMock.ExpectExec("UPDATE users").WillReturnResult(sqlmock.NewResult(0, 1))
+    exec := repo.getExecutor(context.Background())
+    var n int
+    if err := exec.QueryRowContext(context.Background(), "SELECT 1").Scan(&n); err != nil {
+        t.Fatalf("unexpected error: %v", err)
+    }
+    if _, err := exec.ExecContext(context.Background(), "UPDATE users SET name = name"); err != nil {
+        t.Fatalf("unexpected error: %v", err)
+    }
+
+    // Read-your-writes pins reads to the primary
+    if got := repo.getExecutor(WithPrimary(context.Background())); got != primary {
+        t.Fatalf("expected primary for WithPrimary context, got %T", got)
+    }
+
+    // Transactions always run on the primary
+    primaryMock.ExpectBegin()
+    tx, err := primary.Begin()
+    if err != nil {
+        t.Fatalf("unexpected error: %v", err)
+    }
+    if got := repo.getExecutor(context.WithValue(context.Background(), TxKey, tx)); got != tx {
+        t.Fatalf("expected tx, got %T", got)
+    }
+
+    // Without a healthy replica reads fall back to the primary
+    repo.UseReplicas(fakeReplicaSet{})
+    if got := repo.getExecutor(context.Background()); got != primary {
+        t.Fatalf("expected primary fallback, got %T", got)
+    }
+
+    if err := primaryMock.ExpectationsWereMet(); err != nil {
+        t.Fatalf("unmet primary expectations: %v", err)
+    }
+    if err := replicaMock.ExpectationsWereMet(); err != nil {
+        t.Fatalf("unmet replica expectations: %v", err)
+    }
+}
@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"go-starter-template/internal/constant"
+	"go-starter-template/internal/model"
+	"sort"
+
+	"github.com/goccy/go-json"
+	"github.com/redis/go-redis/v9"
+)
+
+// SigningKeyRepository keeps the JWT signing keys in Redis, one hash per
+// token type under jwt:keys:<type>, so every app instance signs and verifies
+// with the same keys.
+type SigningKeyRepository struct {
+	client *redis.Client
+}
+
+func NewSigningKeyRepository(client *redis.Client) *SigningKeyRepository {
+	return &SigningKeyRepository{client: client}
+}
+
+func signingKeysKey(tokenType constant.TokenType) string {
+	return "jwt:keys:" + string(tokenType)
+}
+
+// List returns the keys of tokenType, oldest first.
+func (r *SigningKeyRepository) List(ctx context.Context, tokenType constant.TokenType) ([]*model.SigningKey, error) {
+	values, err := r.client.HGetAll(ctx, signingKeysKey(tokenType)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]*model.SigningKey, 0, len(values))
+	for _, value := range values {
+		key := new(model.SigningKey)
+		if err := json.Unmarshal([]byte(value), key); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].CreatedAt.Before(keys[j].CreatedAt) })
+	return keys, nil
+}
+
+// Get returns the key of tokenType with id, or nil if there is none.
+func (r *SigningKeyRepository) Get(ctx context.Context, tokenType constant.TokenType, id string) (*model.SigningKey, error) {
+	value, err := r.client.HGet(ctx, signingKeysKey(tokenType), id).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	key := new(model.SigningKey)
+	return key, json.Unmarshal([]byte(value), key)
+}
+
+// Add stores key for tokenType.
+func (r *SigningKeyRepository) Add(ctx context.Context, tokenType constant.TokenType, key *model.SigningKey) error {
+	value, err := json.Marshal(key)
+	if err != nil {
+		return err
+	}
+	return r.client.HSet(ctx, signingKeysKey(tokenType), key.ID, value).Err()
+}
+
+// Delete removes the keys of tokenType with ids.
+func (r *SigningKeyRepository) Delete(ctx context.Context, tokenType constant.TokenType, ids ...string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return r.client.HDel(ctx, signingKeysKey(tokenType), ids...).Err()
+}
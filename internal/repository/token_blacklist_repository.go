@@ -13,6 +13,7 @@ import (
 type TokenBlacklistRepository interface {
 	Add(token string, tokenType constant.TokenType, duration time.Duration) error
 	IsBlacklisted(token string, tokenType constant.TokenType) (bool, error)
+	Prune(ctx context.Context, maxAge time.Duration) (int64, error)
 }
 
 type TokenBlacklist struct {
@@ -62,3 +63,30 @@ func (r *RedisTokenBlacklist) IsBlacklisted(token string, tokenType constant.Tok
 	}
 	return result == "1", nil
 }
+
+// Prune bounds blacklist entries that never expire, such as keys written by
+// hand or restored from a backup without their TTL, to maxAge and returns how
+// many it changed. maxAge must outlive any token, so each entry still
+// outlives the token it blocks.
+func (r *RedisTokenBlacklist) Prune(ctx context.Context, maxAge time.Duration) (int64, error) {
+	var pruned int64
+	iter := r.client.Scan(ctx, 0, "blacklist:*", 1000).Iterator()
+	for iter.Next(ctx) {
+		ttl, err := r.client.TTL(ctx, iter.Val()).Result()
+		if err != nil {
+			return pruned, err
+		}
+		// -1 means the key has no expiry
+		if ttl != -1 {
+			continue
+		}
+		set, err := r.client.Expire(ctx, iter.Val(), maxAge).Result()
+		if err != nil {
+			return pruned, err
+		}
+		if set {
+			pruned++
+		}
+	}
+	return pruned, iter.Err()
+}
@@ -160,4 +160,21 @@ func TestRedisTokenBlacklist(t *testing.T) {
             c.assert(t, repo)
         })
     }
-}
\ No newline at end of file
+}
+func TestRedisTokenBlacklist_Prune(t *testing.T) {
+    mr := miniredis.RunT(t)
+    client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+    repo := NewRedisTokenBlacklist(client)
+
+    require.NoError(t, repo.Add("kept", constant.TokenTypeAccess, time.Minute))
+    // An entry that lost its TTL, e.g. restored from a backup
+    require.NoError(t, mr.Set("blacklist:refresh:restored", "1"))
+    require.NoError(t, mr.Set("unrelated", "1"))
+
+    pruned, err := repo.Prune(context.Background(), time.Hour)
+    require.NoError(t, err)
+    require.Equal(t, int64(1), pruned)
+    require.Equal(t, time.Hour, mr.TTL("blacklist:refresh:restored"))
+    require.Equal(t, time.Minute, mr.TTL("blacklist:access:kept"))
+    require.Zero(t, mr.TTL("unrelated"))
+}
@@ -0,0 +1,169 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	// defaultMaxRetries is how often a transaction that hit a serialization
+	// failure or deadlock is retried before the error is returned
+	defaultMaxRetries = 3
+	maxBackoff        = 500 * time.Millisecond
+)
+
+// TxOptions configure a transaction started by UnitOfWork.DoWith. They only
+// apply to the outermost transaction; a nested DoWith becomes a savepoint
+// and runs with the options of the transaction it is nested in.
+type TxOptions struct {
+	// Isolation defaults to the database default (READ COMMITTED)
+	Isolation sql.IsolationLevel
+	ReadOnly  bool
+	// MaxRetries overrides how often serialization failures and deadlocks
+	// are retried; 0 keeps the default and a negative value disables retries
+	MaxRetries int
+}
+
+type txStateKey struct{}
+
+// txState is shared by everything running inside one transaction.
+type txState struct {
+	tx         *sql.Tx
+	savepoints int
+	hooks      []func(ctx context.Context)
+}
+
+// DoWith runs fn within a transaction configured by opts. Called inside
+// another transaction it creates a savepoint instead: an error from fn rolls
+// back only fn's work and is returned to the enclosing fn to handle.
+//
+// A transaction failing with a serialization failure or deadlock is retried
+// from the start with backoff, so fn must not have effects outside the
+// transaction; register those with AfterCommit.
+func (u *UnitOfWork) DoWith(ctx context.Context, opts TxOptions, fn func(ctx context.Context) error) error {
+	if state, ok := ctx.Value(txStateKey{}).(*txState); ok {
+		return savepoint(ctx, state, fn)
+	}
+
+	retries := opts.MaxRetries
+	switch {
+	case retries == 0:
+		retries = u.maxRetries
+	case retries < 0:
+		retries = 0
+	}
+	for attempt := 0; ; attempt++ {
+		err := u.run(ctx, opts, fn)
+		if err == nil || attempt >= retries || !IsRetryable(err) {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return errors.Join(err, ctx.Err())
+		case <-time.After(u.backoff(attempt)):
+		}
+	}
+}
+
+func (u *UnitOfWork) run(ctx context.Context, opts TxOptions, fn func(ctx context.Context) error) error {
+	tx, err := u.db.BeginTx(ctx, &sql.TxOptions{Isolation: opts.Isolation, ReadOnly: opts.ReadOnly})
+	if err != nil {
+		return err
+	}
+	state := &txState{tx: tx}
+	txCtx := context.WithValue(context.WithValue(ctx, TxKey, tx), txStateKey{}, state)
+
+	if err := fn(txCtx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	// Hooks get the caller's context, so anything they query runs outside
+	// the finished transaction
+	for _, hook := range state.hooks {
+		hook(ctx)
+	}
+	return nil
+}
+
+func savepoint(ctx context.Context, state *txState, fn func(ctx context.Context) error) error {
+	state.savepoints++
+	name := fmt.Sprintf("sp_%d", state.savepoints)
+	if _, err := state.tx.ExecContext(ctx, "SAVEPOINT "+name); err != nil {
+		return err
+	}
+
+	hooks := len(state.hooks)
+	if err := fn(ctx); err != nil {
+		// Hooks registered by the rolled back work must not fire
+		state.hooks = state.hooks[:hooks]
+		if _, rollbackErr := state.tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+name); rollbackErr != nil {
+			return errors.Join(err, rollbackErr)
+		}
+		return err
+	}
+	_, err := state.tx.ExecContext(ctx, "RELEASE SAVEPOINT "+name)
+	return err
+}
+
+// Join runs fn in the transaction carried by ctx, or starts one as Do does.
+// Unlike a nested Do it sets no savepoint, so an error from fn is meant to
+// roll back the enclosing transaction too. Use it for writes that must be
+// atomic on their own but may also be part of a larger unit of work.
+func (u *UnitOfWork) Join(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txStateKey{}).(*txState); ok {
+		return fn(ctx)
+	}
+	return u.DoWith(ctx, TxOptions{}, fn)
+}
+
+// AfterCommit registers hook to run once the transaction in ctx commits, or
+// runs it right away when ctx carries no transaction. Use it for effects
+// such as cache invalidation or events that must not happen if the
+// transaction rolls back.
+func AfterCommit(ctx context.Context, hook func(ctx context.Context)) {
+	if state, ok := ctx.Value(txStateKey{}).(*txState); ok {
+		state.hooks = append(state.hooks, hook)
+		return
+	}
+	hook(ctx)
+}
+
+// Transact runs fn like UnitOfWork.DoWith and returns its value once the
+// transaction has committed.
+func Transact[T any](ctx context.Context, u *UnitOfWork, opts TxOptions, fn func(ctx context.Context) (T, error)) (T, error) {
+	var result T
+	err := u.DoWith(ctx, opts, func(txCtx context.Context) error {
+		var err error
+		result, err = fn(txCtx)
+		return err
+	})
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return result, nil
+}
+
+// IsRetryable reports whether err is a serialization failure (40001) or a
+// deadlock (40P01), after which rerunning the whole transaction may succeed.
+func IsRetryable(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && (pgErr.Code == "40001" || pgErr.Code == "40P01")
+}
+
+// exponentialBackoff waits 10ms, 20ms, 40ms... capped at maxBackoff, with
+// jitter so retrying transactions do not collide again.
+func exponentialBackoff(attempt int) time.Duration {
+	// Past 2^6 the cap applies anyway; limiting the shift avoids overflow
+	wait := min(10*time.Millisecond<<min(attempt, 6), maxBackoff)
+	return wait/2 + rand.N(wait/2+1)
+}
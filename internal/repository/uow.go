@@ -3,31 +3,26 @@ package repository
 import (
     "context"
     "database/sql"
+    "time"
 )
 
 // UnitOfWork encapsulates transaction boundaries and exposes repositories
 // bound to the active transaction.
 type UnitOfWork struct {
     db *sql.DB
+    // maxRetries and backoff control how serialization failures and
+    // deadlocks are retried; tests shorten the backoff
+    maxRetries int
+    backoff    func(attempt int) time.Duration
 }
 
 func NewUnitOfWork(db *sql.DB) *UnitOfWork {
-    return &UnitOfWork{db: db}
+    return &UnitOfWork{db: db, maxRetries: defaultMaxRetries, backoff: exponentialBackoff}
 }
 
 // Do runs fn within a transaction. It begins the transaction, injects it
 // into the context so repositories pick it up via getExecutor, and commits
-// or rolls back based on fn's result.
+// or rolls back based on fn's result. See DoWith for nesting and retries.
 func (u *UnitOfWork) Do(ctx context.Context, fn func(ctx context.Context) error) error {
-    tx, err := u.db.BeginTx(ctx, &sql.TxOptions{})
-    if err != nil {
-        return err
-    }
-    ctxWithTx := context.WithValue(ctx, TxKey, tx)
-
-    if err := fn(ctxWithTx); err != nil {
-        _ = tx.Rollback()
-        return err
-    }
-    return tx.Commit()
-}
\ No newline at end of file
+    return u.DoWith(ctx, TxOptions{}, fn)
+}
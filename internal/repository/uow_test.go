@@ -2,11 +2,15 @@ package repository
 
 import (
     "context"
+    "database/sql"
     "errors"
+    "fmt"
     "regexp"
     "testing"
+    "time"
 
     "github.com/DATA-DOG/go-sqlmock"
+    "github.com/jackc/pgx/v5/pgconn"
 )
 
 func TestUnitOfWork_Do(t *testing.T) {
@@ -119,4 +123,241 @@ func TestUnitOfWork_Do(t *testing.T) {
             }
         })
     }
-}
\ No newline at end of file
+}
+
+func newTestUnitOfWork(t *testing.T) (*UnitOfWork, sqlmock.Sqlmock) {
+    db, mock, err := sqlmock.New()
+    if err != nil {
+        t.Fatalf("failed to create sqlmock: %v", err)
+    }
+    t.Cleanup(func() { db.Close() })
+
+    uow := NewUnitOfWork(db)
+    uow.backoff = func(int) time.Duration { return 0 }
+    return uow, mock
+}
+
+func TestUnitOfWork_Savepoints(t *testing.T) {
+    uow, mock := newTestUnitOfWork(t)
+    update := regexp.QuoteMeta("UPDATE users SET name = name WHERE id = $1")
+
+    mock.ExpectBegin()
+    mock.ExpectExec(update).WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+    mock.ExpectExec("SAVEPOINT sp_1").WillReturnResult(sqlmock.NewResult(0, 0))
+    mock.ExpectExec(update).WithArgs(2).WillReturnError(errors.New("duplicate"))
+    mock.ExpectExec("ROLLBACK TO SAVEPOINT sp_1").WillReturnResult(sqlmock.NewResult(0, 0))
+    mock.ExpectExec("SAVEPOINT sp_2").WillReturnResult(sqlmock.NewResult(0, 0))
+    mock.ExpectExec(update).WithArgs(3).WillReturnResult(sqlmock.NewResult(0, 1))
+    mock.ExpectExec("RELEASE SAVEPOINT sp_2").WillReturnResult(sqlmock.NewResult(0, 0))
+    mock.ExpectCommit()
+
+    var hooks []string
+    repo := &Repository{}
+    exec := func(ctx context.Context, id int) error {
+        _, err := repo.getExecutor(ctx).ExecContext(ctx, "UPDATE users SET name = name WHERE id = $1", id)
+        return err
+    }
+    err := uow.Do(context.Background(), func(ctx context.Context) error {
+        if err := exec(ctx, 1); err != nil {
+            return err
+        }
+        AfterCommit(ctx, func(context.Context) { hooks = append(hooks, "outer") })
+
+        // A failing nested unit only undoes its own work and hooks
+        nestedErr := uow.Do(ctx, func(ctx context.Context) error {
+            AfterCommit(ctx, func(context.Context) { hooks = append(hooks, "discarded") })
+            return exec(ctx, 2)
+        })
+        if nestedErr == nil || nestedErr.Error() != "duplicate" {
+            t.Fatalf("expected nested error, got %v", nestedErr)
+        }
+
+        return uow.Do(ctx, func(ctx context.Context) error {
+            AfterCommit(ctx, func(context.Context) { hooks = append(hooks, "nested") })
+            if len(hooks) != 0 {
+                t.Fatalf("hooks ran before commit: %v", hooks)
+            }
+            return exec(ctx, 3)
+        })
+    })
+    if err != nil {
+        t.Fatalf("unexpected error: %v", err)
+    }
+    if len(hooks) != 2 || hooks[0] != "outer" || hooks[1] != "nested" {
+        t.Fatalf("unexpected hooks: %v", hooks)
+    }
+    if err := mock.ExpectationsWereMet(); err != nil {
+        t.Fatalf("unmet expectations: %v", err)
+    }
+}
+
+func TestUnitOfWork_Retry(t *testing.T) {
+    serialization := &pgconn.PgError{Code: "40001"}
+    deadlock := &pgconn.PgError{Code: "40P01"}
+
+    type testcase struct {
+        name      string
+        opts      TxOptions
+        setupMock func(mock sqlmock.Sqlmock)
+        errs      []error
+        attempts  int
+        expectErr error
+    }
+
+    cases := []testcase{
+        {
+            name: "SerializationFailure_Retried",
+            setupMock: func(mock sqlmock.Sqlmock) {
+                mock.ExpectBegin()
+                mock.ExpectRollback()
+                mock.ExpectBegin()
+                mock.ExpectRollback()
+                mock.ExpectBegin()
+                mock.ExpectCommit()
+            },
+            errs:     []error{serialization, fmt.Errorf("wrapped: %w", deadlock), nil},
+            attempts: 3,
+        },
+        {
+            name: "CommitFailure_Retried",
+            setupMock: func(mock sqlmock.Sqlmock) {
+                mock.ExpectBegin()
+                mock.ExpectCommit().WillReturnError(serialization)
+                mock.ExpectBegin()
+                mock.ExpectCommit()
+            },
+            errs:     []error{nil, nil},
+            attempts: 2,
+        },
+        {
+            name: "GivesUpAfterMaxRetries",
+            opts: TxOptions{MaxRetries: 1},
+            setupMock: func(mock sqlmock.Sqlmock) {
+                mock.ExpectBegin()
+                mock.ExpectRollback()
+                mock.ExpectBegin()
+                mock.ExpectRollback()
+            },
+            errs:      []error{serialization, serialization},
+            attempts:  2,
+            expectErr: serialization,
+        },
+        {
+            name: "RetriesDisabled",
+            opts: TxOptions{MaxRetries: -1},
+            setupMock: func(mock sqlmock.Sqlmock) {
+                mock.ExpectBegin()
+                mock.ExpectRollback()
+            },
+            errs:      []error{deadlock},
+            attempts:  1,
+            expectErr: deadlock,
+        },
+        {
+            name: "OtherErrors_NotRetried",
+            setupMock: func(mock sqlmock.Sqlmock) {
+                mock.ExpectBegin()
+                mock.ExpectRollback()
+            },
+            errs:      []error{&pgconn.PgError{Code: "23505"}},
+            attempts:  1,
+            expectErr: &pgconn.PgError{Code: "23505"},
+        },
+    }
+
+    for _, tc := range cases {
+        t.Run(tc.name, func(t *testing.T) {
+            uow, mock := newTestUnitOfWork(t)
+            tc.setupMock(mock)
+
+            attempts, hooks := 0, 0
+            err := uow.DoWith(context.Background(), tc.opts, func(ctx context.Context) error {
+                err := tc.errs[attempts]
+                attempts++
+                AfterCommit(ctx, func(context.Context) { hooks++ })
+                return err
+            })
+            if tc.expectErr == nil && err != nil {
+                t.Fatalf("unexpected error: %v", err)
+            }
+            if tc.expectErr != nil && (err == nil || err.Error() != tc.expectErr.Error()) {
+                t.Fatalf("expected error %v, got %v", tc.expectErr, err)
+            }
+            if attempts != tc.attempts {
+                t.Fatalf("expected %d attempts, got %d", tc.attempts, attempts)
+            }
+            // Hooks of failed attempts never run
+            expectHooks := 0
+            if err == nil {
+                expectHooks = 1
+            }
+            if hooks != expectHooks {
+                t.Fatalf("expected %d hooks to run, got %d", expectHooks, hooks)
+            }
+            if err := mock.ExpectationsWereMet(); err != nil {
+                t.Fatalf("unmet expectations: %v", err)
+            }
+        })
+    }
+}
+
+func TestUnitOfWork_RetryStopsOnContextCancel(t *testing.T) {
+    uow, mock := newTestUnitOfWork(t)
+    uow.backoff = func(int) time.Duration { return time.Hour }
+    mock.ExpectBegin()
+    mock.ExpectRollback()
+
+    ctx, cancel := context.WithCancel(context.Background())
+    err := uow.Do(ctx, func(context.Context) error {
+        cancel()
+        return &pgconn.PgError{Code: "40001"}
+    })
+    if !errors.Is(err, context.Canceled) || !IsRetryable(err) {
+        t.Fatalf("expected cancellation joined with the serialization failure, got %v", err)
+    }
+}
+
+func TestTransact(t *testing.T) {
+    uow, mock := newTestUnitOfWork(t)
+    mock.ExpectBegin()
+    mock.ExpectCommit()
+    mock.ExpectBegin()
+    mock.ExpectRollback()
+
+    opts := TxOptions{Isolation: sql.LevelSerializable, ReadOnly: true}
+    got, err := Transact(context.Background(), uow, opts, func(context.Context) (int, error) {
+        return 42, nil
+    })
+    if err != nil || got != 42 {
+        t.Fatalf("expected 42, got %d, %v", got, err)
+    }
+
+    got, err = Transact(context.Background(), uow, opts, func(context.Context) (int, error) {
+        return 7, errors.New("fn failed")
+    })
+    if err == nil || got != 0 {
+        t.Fatalf("expected zero value and error, got %d, %v", got, err)
+    }
+    if err := mock.ExpectationsWereMet(); err != nil {
+        t.Fatalf("unmet expectations: %v", err)
+    }
+}
+
+func TestAfterCommit_WithoutTransaction(t *testing.T) {
+    ran := false
+    AfterCommit(context.Background(), func(context.Context) { ran = true })
+    if !ran {
+        t.Fatalf("expected hook to run immediately outside a transaction")
+    }
+}
+
+func TestExponentialBackoff(t *testing.T) {
+    for attempt, max := range []time.Duration{10 * time.Millisecond, 20 * time.Millisecond, 40 * time.Millisecond} {
+        if wait := exponentialBackoff(attempt); wait < max/2 || wait > max {
+            t.Fatalf("attempt %d: backoff %s outside [%s, %s]", attempt, wait, max/2, max)
+        }
+    }
+    if wait := exponentialBackoff(100); wait > maxBackoff {
+        t.Fatalf("backoff %s exceeds cap %s", wait, maxBackoff)
+    }
+}
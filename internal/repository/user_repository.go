@@ -3,9 +3,12 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"go-starter-template/internal/dto"
 	"go-starter-template/internal/model"
+	"strings"
+	"time"
 
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/codes"
@@ -15,17 +18,36 @@ import (
 type UserRepository struct {
 	*Repository
 	tracer trace.Tracer
+	// textSearch enables full-text and trigram matching for free-text queries;
+	// see DetectTextSearch
+	textSearch bool
 }
 
 func NewUserRepository(db *sql.DB) *UserRepository {
-	return &UserRepository{Repository: &Repository{db}, tracer: otel.Tracer("UserRepository")}
+	return &UserRepository{Repository: &Repository{db: db}, tracer: otel.Tracer("UserRepository")}
+}
+
+// DetectTextSearch checks whether the database has pg_trgm and the users
+// search_vector column, and enables ranked full-text search if so. Otherwise
+// free-text queries keep using ILIKE.
+func (r *UserRepository) DetectTextSearch(ctx context.Context) (bool, error) {
+	spanCtx, span := r.tracer.Start(ctx, "UserRepository.DetectTextSearch")
+	defer span.End()
+	var available bool
+	err := r.getExecutor(spanCtx).QueryRowContext(spanCtx, `SELECT EXISTS (SELECT 1 FROM pg_extension WHERE extname = 'pg_trgm') AND EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'users' AND column_name = 'search_vector')`).Scan(&available)
+	if err != nil {
+		span.RecordError(err)
+		return false, err
+	}
+	r.textSearch = available
+	return available, nil
 }
 
 func (r *UserRepository) CountByEmail(ctx context.Context, email string) (int64, error) {
 	spanCtx, span := r.tracer.Start(ctx, "UserRepository.CountByEmail")
 	defer span.End()
 	var total int64
-	err := r.getExecutor(spanCtx).QueryRowContext(spanCtx, `SELECT COUNT(*) FROM users WHERE email = $1`, email).Scan(&total)
+	err := r.getExecutor(spanCtx).QueryRowContext(spanCtx, `SELECT COUNT(*) FROM users WHERE lower(email) = lower($1) AND deleted_at IS NULL`, email).Scan(&total)
 	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, "count by email failed")
@@ -37,8 +59,8 @@ func (r *UserRepository) CountByEmail(ctx context.Context, email string) (int64,
 func (r *UserRepository) FindByEmail(ctx context.Context, user *model.User, email string) error {
 	spanCtx, span := r.tracer.Start(ctx, "UserRepository.FindByEmail")
 	defer span.End()
-	row := r.getExecutor(spanCtx).QueryRowContext(spanCtx, `SELECT uuid, name, email, password, created_at, updated_at FROM users WHERE email = $1 LIMIT 1`, email)
-	if err := row.Scan(&user.UUID, &user.Name, &user.Email, &user.Password, &user.CreatedAt, &user.UpdatedAt); err != nil {
+	row := r.getExecutor(spanCtx).QueryRowContext(spanCtx, `SELECT uuid, name, email, password, created_at, updated_at, status, status_reason, suspended_until, phone, version FROM users WHERE lower(email) = lower($1) AND deleted_at IS NULL LIMIT 1`, email)
+	if err := row.Scan(&user.UUID, &user.Name, &user.Email, &user.Password, &user.CreatedAt, &user.UpdatedAt, &user.Status, &user.StatusReason, &user.SuspendedUntil, &user.Phone, &user.Version); err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, "find by email failed")
 		return err
@@ -49,153 +71,489 @@ func (r *UserRepository) FindByEmail(ctx context.Context, user *model.User, emai
 func (r *UserRepository) FindByUUID(ctx context.Context, user *model.User, uuid string) error {
 	spanCtx, span := r.tracer.Start(ctx, "UserRepository.FindByUUID")
 	defer span.End()
-	row := r.getExecutor(spanCtx).QueryRowContext(spanCtx, `SELECT uuid, name, email, password, created_at, updated_at FROM users WHERE uuid = $1 LIMIT 1`, uuid)
-	if err := row.Scan(&user.UUID, &user.Name, &user.Email, &user.Password, &user.CreatedAt, &user.UpdatedAt); err != nil {
+	row := r.getExecutor(spanCtx).QueryRowContext(spanCtx, `SELECT uuid, name, email, password, created_at, updated_at, status, status_reason, suspended_until, phone, version FROM users WHERE uuid = $1 AND deleted_at IS NULL LIMIT 1`, uuid)
+	if err := row.Scan(&user.UUID, &user.Name, &user.Email, &user.Password, &user.CreatedAt, &user.UpdatedAt, &user.Status, &user.StatusReason, &user.SuspendedUntil, &user.Phone, &user.Version); err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, "find by uuid failed")
 		return err
 	}
 
-	// Load roles
-	rolesRows, err := r.getExecutor(spanCtx).QueryContext(spanCtx, `	
-        SELECT r.uuid, r.name
-        FROM roles r
-        INNER JOIN user_roles ur ON ur.role_uuid = r.uuid
-        WHERE ur.user_uuid = $1
-    `, uuid)
-	if err != nil {
-		span.RecordError(err)
-		span.SetStatus(codes.Error, "query roles failed")
-		return err
+	return r.LoadRelations(spanCtx, []*model.User{user}, IncludeAll)
+}
+
+// userSortColumns whitelists the columns users can be sorted by.
+var userSortColumns = map[string]bool{"name": true, "email": true, "created_at": true, "updated_at": true}
+
+// userSortValue returns the value of a sort column on a user, used to build cursors.
+func userSortValue(user *model.User, column string) any {
+	switch column {
+	case "name":
+		return user.Name
+	case "email":
+		return user.Email
+	case "created_at":
+		return user.CreatedAt
+	case "updated_at":
+		return user.UpdatedAt
+	default:
+		return user.UUID
 	}
-	defer rolesRows.Close()
-	var roles []model.Role
-	for rolesRows.Next() {
-		var role model.Role
-		if err = rolesRows.Scan(&role.UUID, &role.Name); err != nil {
-			span.RecordError(err)
-			span.SetStatus(codes.Error, "scan role failed")
-			return err
+}
+
+// userStatuses whitelists the values accepted by the status filter.
+var userStatuses = map[string]bool{"active": true, "suspended": true, "pending": true}
+
+// userSearchFilter builds the predicates shared by page and cursor search.
+// Soft-deleted users are excluded unless requested via Deleted or OnlyDeleted;
+// that scope and the free-text query always apply, while the remaining
+// filters are combined with AND, or with OR when Match is "any".
+// The returned rank is a relevance expression for ORDER BY, empty unless a
+// free-text query is matched with full-text search.
+func userSearchFilter(request *dto.SearchUserRequest, textSearch bool) (b *filterBuilder, rank string, err error) {
+	b = &filterBuilder{matchAny: request.Match == "any"}
+	if request.Match != "" && request.Match != "any" && request.Match != "all" {
+		return nil, "", fmt.Errorf("%w: match %q", ErrInvalidFilter, request.Match)
+	}
+
+	deleted := request.Deleted
+	if request.OnlyDeleted {
+		deleted = "only"
+	}
+	switch deleted {
+	case "", "exclude":
+		b.Scope("deleted_at IS NULL")
+	case "only":
+		b.Scope("deleted_at IS NOT NULL")
+	case "include":
+	default:
+		return nil, "", fmt.Errorf("%w: deleted %q", ErrInvalidFilter, deleted)
+	}
+
+	if q := strings.TrimSpace(request.Q); q != "" {
+		query, like := b.Arg(q), b.Arg("%"+q+"%")
+		if textSearch {
+			// Word matches via the tsvector, typos via trigram similarity on
+			// the name and plain substrings via the trigram indexes
+			tsquery := "websearch_to_tsquery('simple', " + query + ")"
+			b.Scope("(search_vector @@ " + tsquery + " OR name % " + query + " OR name ILIKE " + like + " OR email ILIKE " + like + ")")
+			rank = "ts_rank(search_vector, " + tsquery + ") + similarity(name, " + query + ")"
+		} else {
+			b.Scope("(name ILIKE " + like + " OR email ILIKE " + like + ")")
 		}
-		roles = append(roles, role)
 	}
-	user.Roles = roles
 
-	// Load direct permissions
-	permRows, err := r.getExecutor(spanCtx).QueryContext(spanCtx, `
-        SELECT p.uuid, p.name
-        FROM permissions p
-        INNER JOIN user_permissions up ON up.permission_uuid = p.uuid
-        WHERE up.user_uuid = $1
-    `, uuid)
-	if err != nil {
-		span.RecordError(err)
-		span.SetStatus(codes.Error, "query direct permissions failed")
-		return err
+	if request.Name != "" {
+		b.Where("name ILIKE " + b.Arg("%"+request.Name+"%"))
 	}
-	defer permRows.Close()
-    var permissions []model.Permission
-    for permRows.Next() {
-        var perm model.Permission
-        if err = permRows.Scan(&perm.UUID, &perm.Name); err != nil {
-            span.RecordError(err)
-            span.SetStatus(codes.Error, "scan direct permission failed")
-            return err
-        }
-        permissions = append(permissions, perm)
-    }
-    // Assign only direct user permissions to user.Permissions
-    user.Permissions = permissions
-
-    // Load role-based permissions and attach to each role
-    rolePermRows, err := r.getExecutor(spanCtx).QueryContext(spanCtx, `
-        SELECT rp.role_uuid, p.uuid, p.name
-        FROM permissions p
-        INNER JOIN role_permissions rp ON rp.permission_uuid = p.uuid
-        INNER JOIN user_roles ur ON ur.role_uuid = rp.role_uuid
-        WHERE ur.user_uuid = $1
-    `, uuid)
-    if err != nil {
-        span.RecordError(err)
-        span.SetStatus(codes.Error, "query role permissions failed")
-        return err
-    }
-    defer rolePermRows.Close()
-    // Map role UUID to index for quick attachment
-    roleIndex := make(map[string]int, len(user.Roles))
-    for i := range user.Roles {
-        roleIndex[user.Roles[i].UUID] = i
-    }
-    for rolePermRows.Next() {
-        var roleUUID string
-        var perm model.Permission
-        if err := rolePermRows.Scan(&roleUUID, &perm.UUID, &perm.Name); err != nil {
-            span.RecordError(err)
-            span.SetStatus(codes.Error, "scan role permission failed")
-            return err
-        }
-        if idx, ok := roleIndex[roleUUID]; ok {
-            user.Roles[idx].Permissions = append(user.Roles[idx].Permissions, perm)
-        }
-    }
+	if request.Email != "" {
+		b.Where("email ILIKE " + b.Arg("%"+request.Email+"%"))
+	}
+	if roles := splitList(request.Roles); len(roles) > 0 {
+		b.Where("EXISTS (SELECT 1 FROM user_roles ur INNER JOIN roles r ON r.uuid = ur.role_uuid WHERE ur.user_uuid = users.uuid AND r.name IN " + b.In(roles) + ")")
+	}
+	if permissions := splitList(request.Permissions); len(permissions) > 0 {
+		// Granted either directly or through one of the user's roles
+		names := b.In(permissions)
+		b.Where("(EXISTS (SELECT 1 FROM user_permissions up INNER JOIN permissions p ON p.uuid = up.permission_uuid WHERE up.user_uuid = users.uuid AND p.name IN " + names + ")" +
+			" OR EXISTS (SELECT 1 FROM user_roles ur INNER JOIN role_permissions rp ON rp.role_uuid = ur.role_uuid INNER JOIN permissions p ON p.uuid = rp.permission_uuid WHERE ur.user_uuid = users.uuid AND p.name IN " + names + "))")
+	}
+	if statuses := splitList(request.Status); len(statuses) > 0 {
+		for _, status := range statuses {
+			if !userStatuses[status] {
+				return nil, "", fmt.Errorf("%w: status %q", ErrInvalidFilter, status)
+			}
+		}
+		b.Where("status IN " + b.In(statuses))
+	}
+	for _, column := range []struct {
+		name     string
+		from, to int64
+	}{
+		{"created_at", request.CreatedFrom, request.CreatedTo},
+		{"updated_at", request.UpdatedFrom, request.UpdatedTo},
+	} {
+		var bounds []string
+		if column.from > 0 {
+			bounds = append(bounds, column.name+" >= "+b.Arg(time.Unix(column.from, 0)))
+		}
+		if column.to > 0 {
+			bounds = append(bounds, column.name+" <= "+b.Arg(time.Unix(column.to, 0)))
+		}
+		if len(bounds) > 0 {
+			b.Where(strings.Join(bounds, " AND "))
+		}
+	}
+	return b, rank, nil
+}
 
-	return nil
+// whereClause joins conditions with AND, returning "" when there are none.
+func whereClause(conditions []string) string {
+	if len(conditions) == 0 {
+		return ""
+	}
+	return "WHERE " + strings.Join(conditions, " AND ")
 }
 
+// Search returns one page of users using OFFSET/LIMIT. The total is only
+// counted when request.SkipCount is false, otherwise it is 0.
 func (r *UserRepository) Search(ctx context.Context, request *dto.SearchUserRequest) ([]*model.User, int64, error) {
 	spanCtx, span := r.tracer.Start(ctx, "UserRepository.Search")
 	defer span.End()
 	request.SetDefault()
 
-	// Build filters
-	where := ""
-	args := []interface{}{}
-	if request.Name != "" {
-		where += " AND name ILIKE $" + fmt.Sprintf("%d", len(args)+1)
-		args = append(args, "%"+request.Name+"%")
+	fields, err := parseSort(request.Sort, "-created_at", userSortColumns, "uuid")
+	if err != nil {
+		span.RecordError(err)
+		return nil, 0, err
 	}
-	if request.Email != "" {
-		where += " AND email ILIKE $" + fmt.Sprintf("%d", len(args)+1)
-		args = append(args, "%"+request.Email+"%")
+	filter, rank, err := userSearchFilter(request, r.textSearch)
+	if err != nil {
+		span.RecordError(err)
+		return nil, 0, err
 	}
-	if where != "" {
-		where = "WHERE" + where[4:]
+	where, args := whereClause(filter.Conditions()), filter.Args()
+	if rank != "" && strings.TrimSpace(request.Sort) == "" {
+		// Free-text results default to best match first
+		fields = []sortField{{Column: rank, Desc: true}, {Column: "uuid"}}
 	}
 
-	// Count
-	countQuery := "SELECT COUNT(*) FROM users " + where
 	var total int64
-	if err := r.getExecutor(spanCtx).QueryRowContext(spanCtx, countQuery, args...).Scan(&total); err != nil {
-		span.RecordError(err)
-		span.SetStatus(codes.Error, "count search failed")
-		return nil, 0, err
+	if !request.SkipCount {
+		if total, err = r.countUsers(spanCtx, where, args); err != nil {
+			return nil, 0, err
+		}
 	}
 
 	// Data
 	offset := (request.Page - 1) * request.Size
-	dataQuery := "SELECT uuid, name, email, created_at, updated_at FROM users " + where + " ORDER BY created_at DESC OFFSET $" + fmt.Sprintf("%d", len(args)+1) + " LIMIT $" + fmt.Sprintf("%d", len(args)+2)
+	dataQuery := "SELECT " + userListColumns + " FROM users " + where + " " + orderBy(fields, false) + " OFFSET $" + fmt.Sprintf("%d", len(args)+1) + " LIMIT $" + fmt.Sprintf("%d", len(args)+2)
 	args = append(args, offset, request.Size)
 
-	rows, err := r.getExecutor(spanCtx).QueryContext(spanCtx, dataQuery, args...)
+	users, err := r.queryUsers(spanCtx, dataQuery, args)
+	if err != nil {
+		return nil, 0, err
+	}
+	return users, total, nil
+}
+
+// UserCursorPage is one page of a keyset-paginated user search. Next and Prev
+// are opaque cursors, empty when there is nothing in that direction.
+type UserCursorPage struct {
+	Users []*model.User
+	Total int64
+	Next  string
+	Prev  string
+}
+
+// SearchByCursor pages through users with keyset pagination: instead of
+// skipping rows with OFFSET it seeks past the sort key of the cursor row, so
+// deep pages cost the same as the first one.
+func (r *UserRepository) SearchByCursor(ctx context.Context, request *dto.SearchUserRequest) (*UserCursorPage, error) {
+	spanCtx, span := r.tracer.Start(ctx, "UserRepository.SearchByCursor")
+	defer span.End()
+	request.SetDefault()
+
+	fields, err := parseSort(request.Sort, "-created_at", userSortColumns, "uuid")
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	// Relevance is not a column and cannot be keyed on, so cursors keep the
+	// regular sort order even for free-text queries
+	filter, _, err := userSearchFilter(request, r.textSearch)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	conditions, args := filter.Conditions(), filter.Args()
+
+	page := new(UserCursorPage)
+	if !request.SkipCount {
+		if page.Total, err = r.countUsers(spanCtx, whereClause(conditions), args); err != nil {
+			return nil, err
+		}
+	}
+
+	var current cursor
+	if request.Cursor != "" {
+		if current, err = decodeCursor(request.Cursor, fields); err != nil {
+			span.RecordError(err)
+			return nil, err
+		}
+		values := current.Values
+		for i, field := range fields {
+			// Timestamps come back from JSON as strings
+			if field.Column == "created_at" || field.Column == "updated_at" {
+				raw, _ := values[i].(string)
+				if values[i], err = time.Parse(time.RFC3339Nano, raw); err != nil {
+					span.RecordError(err)
+					return nil, ErrInvalidCursor
+				}
+			}
+		}
+		var condition string
+		condition, args = keysetCondition(fields, values, current.Before, args)
+		conditions = append(conditions, condition)
+	}
+
+	// Fetch one extra row to learn whether another page follows
+	dataQuery := "SELECT " + userListColumns + " FROM users " + whereClause(conditions) + " " + orderBy(fields, current.Before) + fmt.Sprintf(" LIMIT $%d", len(args)+1)
+	args = append(args, request.Limit+1)
+
+	users, err := r.queryUsers(spanCtx, dataQuery, args)
+	if err != nil {
+		return nil, err
+	}
+	hasMore := len(users) > request.Limit
+	if hasMore {
+		users = users[:request.Limit]
+	}
+	if current.Before {
+		for i, j := 0, len(users)-1; i < j; i, j = i+1, j-1 {
+			users[i], users[j] = users[j], users[i]
+		}
+	}
+	page.Users = users
+	if len(users) == 0 {
+		return page, nil
+	}
+
+	cursorAt := func(user *model.User, before bool) string {
+		values := make([]any, len(fields))
+		for i, field := range fields {
+			values[i] = userSortValue(user, field.Column)
+		}
+		return encodeCursor(cursor{Sort: sortKey(fields), Before: before, Values: values})
+	}
+	// Going forward there is a previous page whenever we started from a cursor;
+	// going backward there is always a next page (the one we came from).
+	if current.Before || hasMore {
+		page.Next = cursorAt(users[len(users)-1], false)
+	}
+	if (current.Before && hasMore) || (!current.Before && request.Cursor != "") {
+		page.Prev = cursorAt(users[0], true)
+	}
+	return page, nil
+}
+
+// Include selects the relations LoadRelations attaches to users.
+type Include struct {
+	Roles       bool // roles, with the permissions they grant when Permissions is set too
+	Permissions bool // permissions granted directly to the user
+}
+
+// IncludeAll loads every relation, as FindByUUID does.
+var IncludeAll = Include{Roles: true, Permissions: true}
+
+// LoadRelations attaches the included relations to users with at most three
+// queries (roles, direct permissions, role permissions) however many users
+// there are, instead of a round trip per user and relation.
+func (r *UserRepository) LoadRelations(ctx context.Context, users []*model.User, include Include) error {
+	spanCtx, span := r.tracer.Start(ctx, "UserRepository.LoadRelations")
+	defer span.End()
+	if len(users) == 0 || (!include.Roles && !include.Permissions) {
+		return nil
+	}
+
+	byUUID := make(map[string][]*model.User, len(users))
+	uuids := make([]string, 0, len(users))
+	for _, user := range users {
+		if _, ok := byUUID[user.UUID]; !ok {
+			uuids = append(uuids, user.UUID)
+		}
+		byUUID[user.UUID] = append(byUUID[user.UUID], user)
+		if include.Roles {
+			user.Roles = nil
+		}
+		if include.Permissions {
+			user.Permissions = nil
+		}
+	}
+
+	if include.Roles {
+		err := r.queryRelation(spanCtx, `
+        SELECT ur.user_uuid, r.uuid, r.name
+        FROM user_roles ur
+        INNER JOIN roles r ON r.uuid = ur.role_uuid
+        WHERE ur.user_uuid IN `, uuids, func(userUUID string, uuid, name string) {
+			for _, user := range byUUID[userUUID] {
+				user.Roles = append(user.Roles, model.Role{UUID: uuid, Name: name})
+			}
+		})
+		if err != nil {
+			span.SetStatus(codes.Error, "query roles failed")
+			return err
+		}
+	}
+
+	if include.Permissions {
+		err := r.queryRelation(spanCtx, `
+        SELECT up.user_uuid, p.uuid, p.name
+        FROM user_permissions up
+        INNER JOIN permissions p ON p.uuid = up.permission_uuid
+        WHERE up.user_uuid IN `, uuids, func(userUUID string, uuid, name string) {
+			for _, user := range byUUID[userUUID] {
+				user.Permissions = append(user.Permissions, model.Permission{UUID: uuid, Name: name})
+			}
+		})
+		if err != nil {
+			span.SetStatus(codes.Error, "query direct permissions failed")
+			return err
+		}
+	}
+
+	if include.Roles && include.Permissions {
+		// Keyed by user rather than by the roles just loaded, so the query count
+		// does not depend on whether anyone has roles; DISTINCT folds roles
+		// shared by several users
+		rolePermissions := map[string][]model.Permission{}
+		err := r.queryRelation(spanCtx, `
+        SELECT DISTINCT rp.role_uuid, p.uuid, p.name
+        FROM permissions p
+        INNER JOIN role_permissions rp ON rp.permission_uuid = p.uuid
+        INNER JOIN user_roles ur ON ur.role_uuid = rp.role_uuid
+        WHERE ur.user_uuid IN `, uuids, func(roleUUID string, uuid, name string) {
+			rolePermissions[roleUUID] = append(rolePermissions[roleUUID], model.Permission{UUID: uuid, Name: name})
+		})
+		if err != nil {
+			span.SetStatus(codes.Error, "query role permissions failed")
+			return err
+		}
+		for _, user := range users {
+			for i := range user.Roles {
+				user.Roles[i].Permissions = rolePermissions[user.Roles[i].UUID]
+			}
+		}
+	}
+	return nil
+}
+
+// queryRelation runs query with an IN list of keys appended, ordered by name,
+// and calls attach with each (key, uuid, name) row.
+func (r *UserRepository) queryRelation(ctx context.Context, query string, keys []string, attach func(key, uuid, name string)) error {
+	span := trace.SpanFromContext(ctx)
+	b := new(filterBuilder)
+	rows, err := r.getExecutor(ctx).QueryContext(ctx, query+b.In(keys)+" ORDER BY 3", b.Args()...)
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var key, uuid, name string
+		if err := rows.Scan(&key, &uuid, &name); err != nil {
+			span.RecordError(err)
+			return err
+		}
+		attach(key, uuid, name)
+	}
+	return rows.Err()
+}
+
+func (r *UserRepository) countUsers(ctx context.Context, where string, args []any) (int64, error) {
+	span := trace.SpanFromContext(ctx)
+	var total int64
+	if err := r.getExecutor(ctx).QueryRowContext(ctx, "SELECT COUNT(*) FROM users "+where, args...).Scan(&total); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "count search failed")
+		return 0, err
+	}
+	return total, nil
+}
+
+// userListColumns are the columns of the users returned by Search and
+// SearchByCursor: everything but the password, so list entries carry the
+// version a client needs for its next conditional write.
+const userListColumns = "uuid, name, email, phone, status, status_reason, suspended_until, version, created_at, updated_at, deleted_at"
+
+// queryUsers runs a query selecting userListColumns.
+func (r *UserRepository) queryUsers(ctx context.Context, query string, args []any) ([]*model.User, error) {
+	span := trace.SpanFromContext(ctx)
+	rows, err := r.getExecutor(ctx).QueryContext(ctx, query, args...)
 	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, "query search failed")
-		return nil, 0, err
+		return nil, err
 	}
 	defer rows.Close()
 
 	var users []*model.User
 	for rows.Next() {
 		var u model.User
-		if err := rows.Scan(&u.UUID, &u.Name, &u.Email, &u.CreatedAt, &u.UpdatedAt); err != nil {
+		if err := rows.Scan(&u.UUID, &u.Name, &u.Email, &u.Phone, &u.Status, &u.StatusReason, &u.SuspendedUntil, &u.Version, &u.CreatedAt, &u.UpdatedAt, &u.DeletedAt); err != nil {
 			span.RecordError(err)
 			span.SetStatus(codes.Error, "scan user failed")
-			return nil, 0, err
+			return nil, err
 		}
 		users = append(users, &u)
 	}
+	if err := rows.Err(); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "iterate users failed")
+		return nil, err
+	}
+	return users, nil
+}
 
-	return users, total, nil
+// UserStream iterates over users straight off an open result set, so large
+// exports never hold more than one row in memory. It must be closed.
+type UserStream struct {
+	rows *sql.Rows
+	span trace.Span
+}
+
+// Next scans the following user into user, reporting false once the result
+// set is exhausted.
+func (s *UserStream) Next(user *model.User) (bool, error) {
+	if !s.rows.Next() {
+		if err := s.rows.Err(); err != nil {
+			s.span.RecordError(err)
+			return false, err
+		}
+		return false, nil
+	}
+	if err := s.rows.Scan(&user.UUID, &user.Name, &user.Email, &user.Phone, &user.Status, &user.CreatedAt, &user.UpdatedAt, &user.DeletedAt); err != nil {
+		s.span.RecordError(err)
+		return false, err
+	}
+	return true, nil
+}
+
+// Close releases the result set and its connection.
+func (s *UserStream) Close() error {
+	defer s.span.End()
+	return s.rows.Close()
+}
+
+// StreamUsers runs a search with the same filters and sort as Search but
+// without paging, returning a stream over every matching user. Filter and sort
+// errors are reported here, before any row is read.
+func (r *UserRepository) StreamUsers(ctx context.Context, request *dto.SearchUserRequest) (*UserStream, error) {
+	spanCtx, span := r.tracer.Start(ctx, "UserRepository.StreamUsers")
+
+	fields, err := parseSort(request.Sort, "-created_at", userSortColumns, "uuid")
+	if err != nil {
+		span.RecordError(err)
+		span.End()
+		return nil, err
+	}
+	filter, _, err := userSearchFilter(request, r.textSearch)
+	if err != nil {
+		span.RecordError(err)
+		span.End()
+		return nil, err
+	}
+
+	query := "SELECT uuid, name, email, phone, status, created_at, updated_at, deleted_at FROM users " + whereClause(filter.Conditions()) + " " + orderBy(fields, false)
+	rows, err := r.getExecutor(spanCtx).QueryContext(spanCtx, query, filter.Args()...)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "stream users failed")
+		span.End()
+		return nil, err
+	}
+	return &UserStream{rows: rows, span: span}, nil
 }
 
 func (r *UserRepository) Create(ctx context.Context, user *model.User) error {
@@ -212,26 +570,189 @@ func (r *UserRepository) Create(ctx context.Context, user *model.User) error {
 	return err
 }
 
-func (r *UserRepository) Update(ctx context.Context, user *model.User) error {
+// userUpdatableColumns maps the columns Update may write to their value on the model.
+var userUpdatableColumns = map[string]func(user *model.User) any{
+	"name":  func(user *model.User) any { return user.Name },
+	"email": func(user *model.User) any { return user.Email },
+	"phone": func(user *model.User) any { return user.Phone },
+}
+
+// ErrStaleVersion is returned by Update and Delete when the row's version no
+// longer matches the one the user was loaded with, i.e. someone else modified
+// or deleted it in between.
+var ErrStaleVersion = errors.New("user version is stale")
+
+// Update writes the given columns of user, or name and email when none are given.
+// The write only applies if the stored version still equals user.Version, which is
+// incremented on success.
+func (r *UserRepository) Update(ctx context.Context, user *model.User, columns ...string) error {
 	spanCtx, span := r.tracer.Start(ctx, "UserRepository.Update")
 	defer span.End()
-	_, err := r.getExecutor(spanCtx).ExecContext(spanCtx, `	
-        UPDATE users SET name = $1, email = $2, updated_at = NOW() WHERE uuid = $3
-    `, user.Name, user.Email, user.UUID)
+
+	if len(columns) == 0 {
+		columns = []string{"name", "email"}
+	}
+	sets := make([]string, 0, len(columns)+2)
+	args := make([]any, 0, len(columns)+2)
+	for _, column := range columns {
+		value, ok := userUpdatableColumns[column]
+		if !ok {
+			err := fmt.Errorf("column %q is not updatable", column)
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "update user failed")
+			return err
+		}
+		args = append(args, value(user))
+		sets = append(sets, fmt.Sprintf("%s = $%d", column, len(args)))
+	}
+	sets = append(sets, "version = version + 1", "updated_at = NOW()")
+	args = append(args, user.UUID, user.Version)
+
+	query := fmt.Sprintf("UPDATE users SET %s WHERE uuid = $%d AND version = $%d", strings.Join(sets, ", "), len(args)-1, len(args))
+	result, err := r.getExecutor(spanCtx).ExecContext(spanCtx, query, args...)
 	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, "update user failed")
+		return err
 	}
-	return err
+	affected, err := result.RowsAffected()
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "update user failed")
+		return err
+	}
+	if affected == 0 {
+		span.SetStatus(codes.Error, "stale user version")
+		return ErrStaleVersion
+	}
+	user.Version++
+	return nil
 }
 
+// Delete soft-deletes the user by setting deleted_at, and revokes their tokens
+// by setting tokens_valid_after so no session survives a later restore. The
+// row and its role and permission assignments are kept until PurgeDeleted
+// removes them. Like Update,
+// it only applies if the stored version still equals user.Version, and returns
+// ErrStaleVersion otherwise, including when the user was deleted meanwhile.
 func (r *UserRepository) Delete(ctx context.Context, user *model.User) error {
 	spanCtx, span := r.tracer.Start(ctx, "UserRepository.Delete")
 	defer span.End()
-	_, err := r.getExecutor(spanCtx).ExecContext(spanCtx, `DELETE FROM users WHERE uuid = $1`, user.UUID)
+	result, err := r.getExecutor(spanCtx).ExecContext(spanCtx, `UPDATE users SET deleted_at = NOW(), tokens_valid_after = NOW(), version = version + 1 WHERE uuid = $1 AND version = $2 AND deleted_at IS NULL`, user.UUID, user.Version)
 	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, "delete user failed")
+		return err
 	}
-	return err
+	affected, err := result.RowsAffected()
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "delete user failed")
+		return err
+	}
+	if affected == 0 {
+		span.SetStatus(codes.Error, "stale user version")
+		return ErrStaleVersion
+	}
+	user.Version++
+	return nil
+}
+
+// FindStatusByUUID loads only the status and token revocation columns of an
+// active (not deleted) user. It is cheap enough to run on every authenticated
+// request.
+func (r *UserRepository) FindStatusByUUID(ctx context.Context, user *model.User, uuid string) error {
+	spanCtx, span := r.tracer.Start(ctx, "UserRepository.FindStatusByUUID")
+	defer span.End()
+	row := r.getExecutor(spanCtx).QueryRowContext(spanCtx, `SELECT uuid, status, status_reason, suspended_until, tokens_valid_after FROM users WHERE uuid = $1 AND deleted_at IS NULL LIMIT 1`, uuid)
+	if err := row.Scan(&user.UUID, &user.Status, &user.StatusReason, &user.SuspendedUntil, &user.TokensValidAfter); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "find status by uuid failed")
+		return err
+	}
+	return nil
+}
+
+// UpdateStatus persists status, status_reason and suspended_until and bumps
+// the version, so that writes based on the previous one fail. Like Update, it
+// only applies if the stored version still equals user.Version, and returns
+// ErrStaleVersion otherwise, including when the user was deleted meanwhile.
+func (r *UserRepository) UpdateStatus(ctx context.Context, user *model.User) error {
+	spanCtx, span := r.tracer.Start(ctx, "UserRepository.UpdateStatus")
+	defer span.End()
+	result, err := r.getExecutor(spanCtx).ExecContext(spanCtx, `UPDATE users SET status = $1, status_reason = $2, suspended_until = $3, version = version + 1, updated_at = NOW() WHERE uuid = $4 AND version = $5 AND deleted_at IS NULL`, user.Status, user.StatusReason, user.SuspendedUntil, user.UUID, user.Version)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "update user status failed")
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "update user status failed")
+		return err
+	}
+	if affected == 0 {
+		span.SetStatus(codes.Error, "stale user version")
+		return ErrStaleVersion
+	}
+	user.Version++
+	return nil
+}
+
+// FindDeletedByUUID loads a soft-deleted user without roles or permissions.
+func (r *UserRepository) FindDeletedByUUID(ctx context.Context, user *model.User, uuid string) error {
+	spanCtx, span := r.tracer.Start(ctx, "UserRepository.FindDeletedByUUID")
+	defer span.End()
+	row := r.getExecutor(spanCtx).QueryRowContext(spanCtx, `SELECT uuid, name, email, password, version, created_at, updated_at, deleted_at FROM users WHERE uuid = $1 AND deleted_at IS NOT NULL LIMIT 1`, uuid)
+	if err := row.Scan(&user.UUID, &user.Name, &user.Email, &user.Password, &user.Version, &user.CreatedAt, &user.UpdatedAt, &user.DeletedAt); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "find deleted by uuid failed")
+		return err
+	}
+	return nil
+}
+
+// Restore clears deleted_at on a soft-deleted user and bumps the version.
+func (r *UserRepository) Restore(ctx context.Context, user *model.User) error {
+	spanCtx, span := r.tracer.Start(ctx, "UserRepository.Restore")
+	defer span.End()
+	result, err := r.getExecutor(spanCtx).ExecContext(spanCtx, `UPDATE users SET deleted_at = NULL, version = version + 1, updated_at = NOW() WHERE uuid = $1 AND deleted_at IS NOT NULL`, user.UUID)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "restore user failed")
+		return err
+	}
+	if affected, _ := result.RowsAffected(); affected > 0 {
+		user.Version++
+	}
+	return nil
+}
+
+// PurgeDeleted hard-deletes users soft-deleted before the given time together
+// with their role and permission assignments. Run it inside a UnitOfWork so the
+// join rows and users are removed atomically.
+func (r *UserRepository) PurgeDeleted(ctx context.Context, before time.Time) (int64, error) {
+	spanCtx, span := r.tracer.Start(ctx, "UserRepository.PurgeDeleted")
+	defer span.End()
+
+	executor := r.getExecutor(spanCtx)
+	for _, query := range []string{
+		`DELETE FROM user_roles WHERE user_uuid IN (SELECT uuid FROM users WHERE deleted_at < $1)`,
+		`DELETE FROM user_permissions WHERE user_uuid IN (SELECT uuid FROM users WHERE deleted_at < $1)`,
+	} {
+		if _, err := executor.ExecContext(spanCtx, query, before); err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "purge user assignments failed")
+			return 0, err
+		}
+	}
+
+	result, err := executor.ExecContext(spanCtx, `DELETE FROM users WHERE deleted_at < $1`, before)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "purge users failed")
+		return 0, err
+	}
+	return result.RowsAffected()
 }
@@ -3,7 +3,9 @@ package repository
 import (
     "context"
     "database/sql"
+    "database/sql/driver"
     "errors"
+    "fmt"
     "regexp"
     "testing"
     "time"
@@ -29,7 +31,7 @@ func TestUserRepository_CountByEmail(t *testing.T) {
         assert    func(t *testing.T, total int64, err error)
     }
 
-    query := `SELECT COUNT(*) FROM users WHERE email = $1`
+    query := `SELECT COUNT(*) FROM users WHERE lower(email) = lower($1) AND deleted_at IS NULL`
 
     cases := []tc{
         {
@@ -83,7 +85,7 @@ func TestUserRepository_FindByEmail(t *testing.T) {
         assert    func(t *testing.T, u *model.User, err error)
     }
 
-    query := `SELECT uuid, name, email, password, created_at, updated_at FROM users WHERE email = $1 LIMIT 1`
+    query := `SELECT uuid, name, email, password, created_at, updated_at, status, status_reason, suspended_until, phone, version FROM users WHERE lower(email) = lower($1) AND deleted_at IS NULL LIMIT 1`
     now := time.Now()
 
     cases := []tc{
@@ -93,8 +95,8 @@ func TestUserRepository_FindByEmail(t *testing.T) {
             setupMock: func() {
                 mock.ExpectQuery(regexp.QuoteMeta(query)).
                     WithArgs("john@example.com").
-                    WillReturnRows(sqlmock.NewRows([]string{"uuid", "name", "email", "password", "created_at", "updated_at"}).
-                        AddRow("u1", "John", "john@example.com", "pass", now, now))
+                    WillReturnRows(sqlmock.NewRows([]string{"uuid", "name", "email", "password", "created_at", "updated_at", "status", "status_reason", "suspended_until", "phone", "version"}).
+                        AddRow("u1", "John", "john@example.com", "pass", now, now, "active", "", nil, nil, 1))
             },
             assert: func(t *testing.T, u *model.User, err error) {
                 require.NoError(t, err)
@@ -102,6 +104,21 @@ func TestUserRepository_FindByEmail(t *testing.T) {
                 require.Equal(t, "John", u.Name)
             },
         },
+        {
+            name: "DifferentCase",
+            email: "John@Example.COM",
+            setupMock: func() {
+                mock.ExpectQuery(regexp.QuoteMeta(query)).
+                    WithArgs("John@Example.COM").
+                    WillReturnRows(sqlmock.NewRows([]string{"uuid", "name", "email", "password", "created_at", "updated_at", "status", "status_reason", "suspended_until", "phone", "version"}).
+                        AddRow("u1", "John", "john@example.com", "pass", now, now, "active", "", nil, nil, 1))
+            },
+            assert: func(t *testing.T, u *model.User, err error) {
+                require.NoError(t, err)
+                require.Equal(t, "u1", u.UUID)
+                require.Equal(t, "john@example.com", u.Email)
+            },
+        },
         {
             name: "NotFound",
             email: "missing@example.com",
@@ -141,25 +158,25 @@ func TestUserRepository_FindByUUID(t *testing.T) {
         assert    func(t *testing.T, u *model.User, err error)
     }
 
-    userQuery := `SELECT uuid, name, email, password, created_at, updated_at FROM users WHERE uuid = $1 LIMIT 1`
+    userQuery := `SELECT uuid, name, email, password, created_at, updated_at, status, status_reason, suspended_until, phone, version FROM users WHERE uuid = $1 AND deleted_at IS NULL LIMIT 1`
     rolesQuery := `
-        SELECT r.uuid, r.name
-        FROM roles r
-        INNER JOIN user_roles ur ON ur.role_uuid = r.uuid
-        WHERE ur.user_uuid = $1
+        SELECT ur.user_uuid, r.uuid, r.name
+        FROM user_roles ur
+        INNER JOIN roles r ON r.uuid = ur.role_uuid
+        WHERE ur.user_uuid IN ($1)
     `
     directPermQuery := `
-        SELECT p.uuid, p.name
-        FROM permissions p
-        INNER JOIN user_permissions up ON up.permission_uuid = p.uuid
-        WHERE up.user_uuid = $1
+        SELECT up.user_uuid, p.uuid, p.name
+        FROM user_permissions up
+        INNER JOIN permissions p ON p.uuid = up.permission_uuid
+        WHERE up.user_uuid IN ($1)
     `
     rolePermQuery := `
-        SELECT rp.role_uuid, p.uuid, p.name
+        SELECT DISTINCT rp.role_uuid, p.uuid, p.name
         FROM permissions p
         INNER JOIN role_permissions rp ON rp.permission_uuid = p.uuid
         INNER JOIN user_roles ur ON ur.role_uuid = rp.role_uuid
-        WHERE ur.user_uuid = $1
+        WHERE ur.user_uuid IN ($1)
     `
 
     now := time.Now()
@@ -171,16 +188,16 @@ func TestUserRepository_FindByUUID(t *testing.T) {
             setupMock: func() {
                 mock.ExpectQuery(regexp.QuoteMeta(userQuery)).
                     WithArgs("u2").
-                    WillReturnRows(sqlmock.NewRows([]string{"uuid", "name", "email", "password", "created_at", "updated_at"}).
-                        AddRow("u2", "Jane", "jane@example.com", "pass", now, now))
+                    WillReturnRows(sqlmock.NewRows([]string{"uuid", "name", "email", "password", "created_at", "updated_at", "status", "status_reason", "suspended_until", "phone", "version"}).
+                        AddRow("u2", "Jane", "jane@example.com", "pass", now, now, "active", "", nil, nil, 1))
 
                 mock.ExpectQuery(regexp.QuoteMeta(rolesQuery)).
                     WithArgs("u2").
-                    WillReturnRows(sqlmock.NewRows([]string{"uuid", "name"}).AddRow("r1", "Admin").AddRow("r2", "User"))
+                    WillReturnRows(sqlmock.NewRows([]string{"user_uuid", "uuid", "name"}).AddRow("u2", "r1", "Admin").AddRow("u2", "r2", "User"))
 
                 mock.ExpectQuery(regexp.QuoteMeta(directPermQuery)).
                     WithArgs("u2").
-                    WillReturnRows(sqlmock.NewRows([]string{"uuid", "name"}).AddRow("p1", "read"))
+                    WillReturnRows(sqlmock.NewRows([]string{"user_uuid", "uuid", "name"}).AddRow("u2", "p1", "read"))
 
                 mock.ExpectQuery(regexp.QuoteMeta(rolePermQuery)).
                     WithArgs("u2").
@@ -215,8 +232,8 @@ func TestUserRepository_FindByUUID(t *testing.T) {
                 mock.ExpectQuery(regexp.QuoteMeta(userQuery)).
                     WithArgs("uBad").
                     // invalid time values to force scan error on created_at/updated_at
-                    WillReturnRows(sqlmock.NewRows([]string{"uuid", "name", "email", "password", "created_at", "updated_at"}).
-                        AddRow("uBad", "Bad", "bad@example.com", "pass", "bad-time", "bad-time"))
+                    WillReturnRows(sqlmock.NewRows([]string{"uuid", "name", "email", "password", "created_at", "updated_at", "status", "status_reason", "suspended_until", "phone", "version"}).
+                        AddRow("uBad", "Bad", "bad@example.com", "pass", "bad-time", "bad-time", "active", "", nil, nil, 1))
             },
             assert: func(t *testing.T, u *model.User, err error) {
                 require.Error(t, err)
@@ -228,8 +245,8 @@ func TestUserRepository_FindByUUID(t *testing.T) {
             setupMock: func() {
                 mock.ExpectQuery(regexp.QuoteMeta(userQuery)).
                     WithArgs("u3").
-                    WillReturnRows(sqlmock.NewRows([]string{"uuid", "name", "email", "password", "created_at", "updated_at"}).
-                        AddRow("u3", "Jim", "jim@example.com", "pass", now, now))
+                    WillReturnRows(sqlmock.NewRows([]string{"uuid", "name", "email", "password", "created_at", "updated_at", "status", "status_reason", "suspended_until", "phone", "version"}).
+                        AddRow("u3", "Jim", "jim@example.com", "pass", now, now, "active", "", nil, nil, 1))
                 mock.ExpectQuery(regexp.QuoteMeta(rolesQuery)).
                     WithArgs("u3").
                     WillReturnError(errors.New("roles query error"))
@@ -244,15 +261,15 @@ func TestUserRepository_FindByUUID(t *testing.T) {
             setupMock: func() {
                 mock.ExpectQuery(regexp.QuoteMeta(userQuery)).
                     WithArgs("u4").
-                    WillReturnRows(sqlmock.NewRows([]string{"uuid", "name", "email", "password", "created_at", "updated_at"}).
-                        AddRow("u4", "Jill", "jill@example.com", "pass", now, now))
+                    WillReturnRows(sqlmock.NewRows([]string{"uuid", "name", "email", "password", "created_at", "updated_at", "status", "status_reason", "suspended_until", "phone", "version"}).
+                        AddRow("u4", "Jill", "jill@example.com", "pass", now, now, "active", "", nil, nil, 1))
                 mock.ExpectQuery(regexp.QuoteMeta(rolesQuery)).
                     WithArgs("u4").
-                    WillReturnRows(sqlmock.NewRows([]string{"uuid", "name"}).AddRow("r1", "Admin"))
+                    WillReturnRows(sqlmock.NewRows([]string{"user_uuid", "uuid", "name"}).AddRow("u4", "r1", "Admin"))
                 mock.ExpectQuery(regexp.QuoteMeta(directPermQuery)).
                     WithArgs("u4").
                     // NULL value to force scan error when scanning into string fields
-                    WillReturnRows(sqlmock.NewRows([]string{"uuid", "name"}).AddRow(nil, "read"))
+                    WillReturnRows(sqlmock.NewRows([]string{"user_uuid", "uuid", "name"}).AddRow("u4", nil, "read"))
             },
             assert: func(t *testing.T, u *model.User, err error) {
                 require.Error(t, err)
@@ -264,13 +281,13 @@ func TestUserRepository_FindByUUID(t *testing.T) {
             setupMock: func() {
                 mock.ExpectQuery(regexp.QuoteMeta(userQuery)).
                     WithArgs("u2a").
-                    WillReturnRows(sqlmock.NewRows([]string{"uuid", "name", "email", "password", "created_at", "updated_at"}).
-                        AddRow("u2a", "Jane", "jane@example.com", "pass", now, now))
+                    WillReturnRows(sqlmock.NewRows([]string{"uuid", "name", "email", "password", "created_at", "updated_at", "status", "status_reason", "suspended_until", "phone", "version"}).
+                        AddRow("u2a", "Jane", "jane@example.com", "pass", now, now, "active", "", nil, nil, 1))
 
                 mock.ExpectQuery(regexp.QuoteMeta(rolesQuery)).
                     WithArgs("u2a").
                     // NULL value to force scan error when scanning into string fields
-                    WillReturnRows(sqlmock.NewRows([]string{"uuid", "name"}).AddRow(nil, "Admin"))
+                    WillReturnRows(sqlmock.NewRows([]string{"user_uuid", "uuid", "name"}).AddRow("u2a", nil, "Admin"))
             },
             assert: func(t *testing.T, u *model.User, err error) {
                 require.Error(t, err)
@@ -282,11 +299,11 @@ func TestUserRepository_FindByUUID(t *testing.T) {
             setupMock: func() {
                 mock.ExpectQuery(regexp.QuoteMeta(userQuery)).
                     WithArgs("u5").
-                    WillReturnRows(sqlmock.NewRows([]string{"uuid", "name", "email", "password", "created_at", "updated_at"}).
-                        AddRow("u5", "Jack", "jack@example.com", "pass", now, now))
+                    WillReturnRows(sqlmock.NewRows([]string{"uuid", "name", "email", "password", "created_at", "updated_at", "status", "status_reason", "suspended_until", "phone", "version"}).
+                        AddRow("u5", "Jack", "jack@example.com", "pass", now, now, "active", "", nil, nil, 1))
                 mock.ExpectQuery(regexp.QuoteMeta(rolesQuery)).
                     WithArgs("u5").
-                    WillReturnRows(sqlmock.NewRows([]string{"uuid", "name"}).AddRow("r1", "Admin"))
+                    WillReturnRows(sqlmock.NewRows([]string{"user_uuid", "uuid", "name"}).AddRow("u5", "r1", "Admin"))
                 mock.ExpectQuery(regexp.QuoteMeta(directPermQuery)).
                     WithArgs("u5").
                     WillReturnError(errors.New("direct perm query error"))
@@ -301,14 +318,14 @@ func TestUserRepository_FindByUUID(t *testing.T) {
             setupMock: func() {
                 mock.ExpectQuery(regexp.QuoteMeta(userQuery)).
                     WithArgs("u6").
-                    WillReturnRows(sqlmock.NewRows([]string{"uuid", "name", "email", "password", "created_at", "updated_at"}).
-                        AddRow("u6", "Jenny", "jenny@example.com", "pass", now, now))
+                    WillReturnRows(sqlmock.NewRows([]string{"uuid", "name", "email", "password", "created_at", "updated_at", "status", "status_reason", "suspended_until", "phone", "version"}).
+                        AddRow("u6", "Jenny", "jenny@example.com", "pass", now, now, "active", "", nil, nil, 1))
                 mock.ExpectQuery(regexp.QuoteMeta(rolesQuery)).
                     WithArgs("u6").
-                    WillReturnRows(sqlmock.NewRows([]string{"uuid", "name"}).AddRow("r1", "Admin"))
+                    WillReturnRows(sqlmock.NewRows([]string{"user_uuid", "uuid", "name"}).AddRow("u6", "r1", "Admin"))
                 mock.ExpectQuery(regexp.QuoteMeta(directPermQuery)).
                     WithArgs("u6").
-                    WillReturnRows(sqlmock.NewRows([]string{"uuid", "name"}).AddRow("p1", "read"))
+                    WillReturnRows(sqlmock.NewRows([]string{"user_uuid", "uuid", "name"}).AddRow("u6", "p1", "read"))
                 mock.ExpectQuery(regexp.QuoteMeta(rolePermQuery)).
                     WithArgs("u6").
                     WillReturnError(errors.New("role perm query error"))
@@ -323,18 +340,18 @@ func TestUserRepository_FindByUUID(t *testing.T) {
             setupMock: func() {
                 mock.ExpectQuery(regexp.QuoteMeta(userQuery)).
                     WithArgs("u7").
-                    WillReturnRows(sqlmock.NewRows([]string{"uuid", "name", "email", "password", "created_at", "updated_at"}).
-                        AddRow("u7", "Julia", "julia@example.com", "pass", now, now))
+                    WillReturnRows(sqlmock.NewRows([]string{"uuid", "name", "email", "password", "created_at", "updated_at", "status", "status_reason", "suspended_until", "phone", "version"}).
+                        AddRow("u7", "Julia", "julia@example.com", "pass", now, now, "active", "", nil, nil, 1))
                 mock.ExpectQuery(regexp.QuoteMeta(rolesQuery)).
                     WithArgs("u7").
-                    WillReturnRows(sqlmock.NewRows([]string{"uuid", "name"}).AddRow("r1", "Admin"))
+                    WillReturnRows(sqlmock.NewRows([]string{"user_uuid", "uuid", "name"}).AddRow("u7", "r1", "Admin"))
                 mock.ExpectQuery(regexp.QuoteMeta(directPermQuery)).
                     WithArgs("u7").
-                    WillReturnRows(sqlmock.NewRows([]string{"uuid", "name"}).AddRow("p1", "read"))
+                    WillReturnRows(sqlmock.NewRows([]string{"user_uuid", "uuid", "name"}).AddRow("u7", "p1", "read"))
                 mock.ExpectQuery(regexp.QuoteMeta(rolePermQuery)).
                     WithArgs("u7").
                     // NULL values to force scan error into *string fields
-                    WillReturnRows(sqlmock.NewRows([]string{"uuid", "name"}).AddRow(nil, "write"))
+                    WillReturnRows(sqlmock.NewRows([]string{"user_uuid", "uuid", "name"}).AddRow("u7", nil, "write"))
             },
             assert: func(t *testing.T, u *model.User, err error) {
                 require.Error(t, err)
@@ -368,8 +385,8 @@ func TestUserRepository_Search(t *testing.T) {
         assert    func(t *testing.T, users []*model.User, total int64, err error)
     }
 
-    countBase := "SELECT COUNT(*) FROM users "
-    dataBase := "SELECT uuid, name, email, created_at, updated_at FROM users "
+    countBase := "SELECT COUNT(*) FROM users WHERE deleted_at IS NULL"
+    dataBase := "SELECT uuid, name, email, phone, status, status_reason, suspended_until, version, created_at, updated_at, deleted_at FROM users WHERE deleted_at IS NULL"
 
     cases := []tc{
         {
@@ -378,31 +395,35 @@ func TestUserRepository_Search(t *testing.T) {
             setupMock: func() {
                 mock.ExpectQuery(regexp.QuoteMeta(countBase)).
                     WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
-                mock.ExpectQuery(regexp.QuoteMeta(dataBase + " ORDER BY created_at DESC OFFSET $1 LIMIT $2")).
+                mock.ExpectQuery(regexp.QuoteMeta(dataBase + " ORDER BY created_at DESC, uuid ASC OFFSET $1 LIMIT $2")).
                     WithArgs(0, 2).
-                    WillReturnRows(sqlmock.NewRows([]string{"uuid", "name", "email", "created_at", "updated_at"}).
-                        AddRow("u1", "A", "a@example.com", now, now).
-                        AddRow("u2", "B", "b@example.com", now, now))
+                    WillReturnRows(sqlmock.NewRows([]string{"uuid", "name", "email", "phone", "status", "status_reason", "suspended_until", "version", "created_at", "updated_at", "deleted_at"}).
+                        AddRow("u1", "A", "a@example.com", nil, "active", "", nil, 1, now, now, nil).
+                        AddRow("u2", "B", "b@example.com", "+15550100", "suspended", "abuse", nil, 3, now, now, nil))
             },
             assert: func(t *testing.T, users []*model.User, total int64, err error) {
                 require.NoError(t, err)
                 require.Equal(t, int64(2), total)
                 require.Len(t, users, 2)
+                require.Equal(t, "suspended", users[1].Status)
+                require.Equal(t, "abuse", users[1].StatusReason)
+                require.Equal(t, "+15550100", *users[1].Phone)
+                require.Equal(t, int64(3), users[1].Version)
             },
         },
         {
             name: "WithFilters_Success",
             req:  &dto.SearchUserRequest{Name: "Al", Email: "ex", Page: 2, Size: 5},
             setupMock: func() {
-                where := "WHERE name ILIKE $1 AND email ILIKE $2"
+                where := " AND name ILIKE $1 AND email ILIKE $2"
                 mock.ExpectQuery(regexp.QuoteMeta(countBase + where)).
                     WithArgs("%Al%", "%ex%").
                     WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(7))
-                mock.ExpectQuery(regexp.QuoteMeta(dataBase + where + " ORDER BY created_at DESC OFFSET $3 LIMIT $4")).
+                mock.ExpectQuery(regexp.QuoteMeta(dataBase + where + " ORDER BY created_at DESC, uuid ASC OFFSET $3 LIMIT $4")).
                     WithArgs("%Al%", "%ex%", 5, 5).
-                    WillReturnRows(sqlmock.NewRows([]string{"uuid", "name", "email", "created_at", "updated_at"}).
-                        AddRow("u1", "Alison", "alison@example.com", now, now).
-                        AddRow("u2", "Alex", "alex@example.com", now, now))
+                    WillReturnRows(sqlmock.NewRows([]string{"uuid", "name", "email", "phone", "status", "status_reason", "suspended_until", "version", "created_at", "updated_at", "deleted_at"}).
+                        AddRow("u1", "Alison", "alison@example.com", nil, "active", "", nil, 1, now, now, nil).
+                        AddRow("u2", "Alex", "alex@example.com", nil, "active", "", nil, 1, now, now, nil))
             },
             assert: func(t *testing.T, users []*model.User, total int64, err error) {
                 require.NoError(t, err)
@@ -425,11 +446,11 @@ func TestUserRepository_Search(t *testing.T) {
             name: "QueryError",
             req:  &dto.SearchUserRequest{Name: "x", Page: 1, Size: 1},
             setupMock: func() {
-                where := "WHERE name ILIKE $1"
+                where := " AND name ILIKE $1"
                 mock.ExpectQuery(regexp.QuoteMeta(countBase + where)).
                     WithArgs("%x%").
                     WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
-                mock.ExpectQuery(regexp.QuoteMeta(dataBase + where + " ORDER BY created_at DESC OFFSET $2 LIMIT $3")).
+                mock.ExpectQuery(regexp.QuoteMeta(dataBase + where + " ORDER BY created_at DESC, uuid ASC OFFSET $2 LIMIT $3")).
                     WithArgs("%x%", 0, 1).
                     WillReturnError(errors.New("query failed"))
             },
@@ -441,18 +462,35 @@ func TestUserRepository_Search(t *testing.T) {
             name: "ScanError",
             req:  &dto.SearchUserRequest{Email: "x", Page: 1, Size: 1},
             setupMock: func() {
-                where := "WHERE email ILIKE $1"
+                where := " AND email ILIKE $1"
                 mock.ExpectQuery(regexp.QuoteMeta(countBase + where)).
                     WithArgs("%x%").
                     WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
-                mock.ExpectQuery(regexp.QuoteMeta(dataBase + where + " ORDER BY created_at DESC OFFSET $2 LIMIT $3")).
+                mock.ExpectQuery(regexp.QuoteMeta(dataBase + where + " ORDER BY created_at DESC, uuid ASC OFFSET $2 LIMIT $3")).
                     WithArgs("%x%", 0, 1).
-                    WillReturnRows(sqlmock.NewRows([]string{"uuid", "name", "email", "created_at", "updated_at"}).
+                    WillReturnRows(sqlmock.NewRows([]string{"uuid", "name", "email", "phone", "status", "status_reason", "suspended_until", "version", "created_at", "updated_at", "deleted_at"}).
                         // wrong types to force scan error: created_at/updated_at should be time.Time
-                        AddRow("uX", "X", "x@example.com", "bad-time", "bad-time"))
+                        AddRow("uX", "X", "x@example.com", nil, "active", "", nil, 1, "bad-time", "bad-time", nil))
+            },
+            assert: func(t *testing.T, users []*model.User, total int64, err error) {
+                require.Error(t, err)
+            },
+        },
+        {
+            name: "RowError",
+            req:  &dto.SearchUserRequest{Page: 1, Size: 2, SkipCount: true},
+            setupMock: func() {
+                mock.ExpectQuery(regexp.QuoteMeta(dataBase + " ORDER BY created_at DESC, uuid ASC OFFSET $1 LIMIT $2")).
+                    WithArgs(0, 2).
+                    WillReturnRows(sqlmock.NewRows([]string{"uuid", "name", "email", "phone", "status", "status_reason", "suspended_until", "version", "created_at", "updated_at", "deleted_at"}).
+                        AddRow("u1", "A", "a@example.com", nil, "active", "", nil, 1, now, now, nil).
+                        AddRow("u2", "B", "b@example.com", nil, "active", "", nil, 1, now, now, nil).
+                        RowError(1, errors.New("connection reset")))
             },
             assert: func(t *testing.T, users []*model.User, total int64, err error) {
+                // A failure mid-iteration must not pass for a short page
                 require.Error(t, err)
+                require.Nil(t, users)
             },
         },
     }
@@ -468,6 +506,367 @@ func TestUserRepository_Search(t *testing.T) {
     }
 }
 
+func TestUserRepository_SearchByCursor(t *testing.T) {
+    db, mock, err := sqlmock.New()
+    require.NoError(t, err)
+    defer db.Close()
+
+    repo := NewUserRepository(db)
+    now := time.Now()
+    dataBase := "SELECT uuid, name, email, phone, status, status_reason, suspended_until, version, created_at, updated_at, deleted_at FROM users WHERE deleted_at IS NULL"
+    rows := func(uuids ...string) *sqlmock.Rows {
+        r := sqlmock.NewRows([]string{"uuid", "name", "email", "phone", "status", "status_reason", "suspended_until", "version", "created_at", "updated_at", "deleted_at"})
+        for _, uuid := range uuids {
+            r.AddRow(uuid, "N", uuid+"@example.com", nil, "active", "", nil, 1, now, now, nil)
+        }
+        return r
+    }
+
+    // First page: one extra row signals a following page
+    mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM users WHERE deleted_at IS NULL")).
+        WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+    mock.ExpectQuery(regexp.QuoteMeta(dataBase + " ORDER BY created_at DESC, uuid ASC LIMIT $1")).
+        WithArgs(3).
+        WillReturnRows(rows("u1", "u2", "u3"))
+    first, err := repo.SearchByCursor(context.Background(), &dto.SearchUserRequest{Limit: 2})
+    require.NoError(t, err)
+    require.Equal(t, int64(3), first.Total)
+    require.Len(t, first.Users, 2)
+    require.NotEmpty(t, first.Next)
+    require.Empty(t, first.Prev)
+
+    // Next page seeks past the last row of the first page
+    mock.ExpectQuery(regexp.QuoteMeta(dataBase + " AND ((created_at < $1) OR (created_at = $1 AND uuid > $2)) ORDER BY created_at DESC, uuid ASC LIMIT $3")).
+        WithArgs(sqlmock.AnyArg(), "u2", 3).
+        WillReturnRows(rows("u3"))
+    second, err := repo.SearchByCursor(context.Background(), &dto.SearchUserRequest{Cursor: first.Next, Limit: 2, SkipCount: true})
+    require.NoError(t, err)
+    require.Len(t, second.Users, 1)
+    require.Empty(t, second.Next)
+    require.NotEmpty(t, second.Prev)
+
+    // Going back reverses the order in SQL and restores it in the result
+    mock.ExpectQuery(regexp.QuoteMeta(dataBase + " AND ((created_at > $1) OR (created_at = $1 AND uuid < $2)) ORDER BY created_at ASC, uuid DESC LIMIT $3")).
+        WithArgs(sqlmock.AnyArg(), "u3", 3).
+        WillReturnRows(rows("u2", "u1"))
+    back, err := repo.SearchByCursor(context.Background(), &dto.SearchUserRequest{Cursor: second.Prev, Limit: 2, SkipCount: true})
+    require.NoError(t, err)
+    require.Equal(t, "u1", back.Users[0].UUID)
+    require.Equal(t, "u2", back.Users[1].UUID)
+    require.NotEmpty(t, back.Next)
+    require.Empty(t, back.Prev)
+
+    // Cursors are bound to their sort order
+    _, err = repo.SearchByCursor(context.Background(), &dto.SearchUserRequest{Cursor: first.Next, Sort: "name", SkipCount: true})
+    require.ErrorIs(t, err, ErrInvalidCursor)
+
+    require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUserRepository_SearchFilters(t *testing.T) {
+    db, mock, err := sqlmock.New()
+    require.NoError(t, err)
+    defer db.Close()
+
+    repo := NewUserRepository(db)
+    roleExists := "EXISTS (SELECT 1 FROM user_roles ur INNER JOIN roles r ON r.uuid = ur.role_uuid WHERE ur.user_uuid = users.uuid AND r.name IN "
+
+    cases := []struct {
+        name      string
+        req       *dto.SearchUserRequest
+        setupMock func()
+        expectErr error
+    }{
+        {
+            name: "MatchAnyAcrossFilters",
+            req:  &dto.SearchUserRequest{Roles: "admin,editor", Status: "active", CreatedFrom: 1700000000, Match: "any"},
+            setupMock: func() {
+                mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM users WHERE deleted_at IS NULL AND (" + roleExists + "($1, $2)) OR status IN ($3) OR created_at >= $4)")).
+                    WithArgs("admin", "editor", "active", time.Unix(1700000000, 0)).
+                    WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+                mock.ExpectQuery(regexp.QuoteMeta("OR created_at >= $4) ORDER BY created_at DESC, uuid ASC OFFSET $5 LIMIT $6")).
+                    WillReturnRows(sqlmock.NewRows([]string{"uuid", "name", "email", "phone", "status", "status_reason", "suspended_until", "version", "created_at", "updated_at", "deleted_at"}))
+            },
+        },
+        {
+            name: "PermissionDirectOrViaRole",
+            req:  &dto.SearchUserRequest{Permissions: "read-user", UpdatedTo: 1700000000, Deleted: "include", SkipCount: true},
+            setupMock: func() {
+                mock.ExpectQuery(regexp.QuoteMeta("FROM users WHERE (EXISTS (SELECT 1 FROM user_permissions up INNER JOIN permissions p ON p.uuid = up.permission_uuid WHERE up.user_uuid = users.uuid AND p.name IN ($1)) OR EXISTS (SELECT 1 FROM user_roles ur INNER JOIN role_permissions rp ON rp.role_uuid = ur.role_uuid INNER JOIN permissions p ON p.uuid = rp.permission_uuid WHERE ur.user_uuid = users.uuid AND p.name IN ($1))) AND updated_at <= $2 ORDER BY")).
+                    WithArgs("read-user", time.Unix(1700000000, 0), 0, 10).
+                    WillReturnRows(sqlmock.NewRows([]string{"uuid", "name", "email", "phone", "status", "status_reason", "suspended_until", "version", "created_at", "updated_at", "deleted_at"}))
+            },
+        },
+        {
+            name:      "UnknownStatus",
+            req:       &dto.SearchUserRequest{Status: "banned"},
+            setupMock: func() {},
+            expectErr: ErrInvalidFilter,
+        },
+        {
+            name:      "UnknownDeletedScope",
+            req:       &dto.SearchUserRequest{Deleted: "maybe"},
+            setupMock: func() {},
+            expectErr: ErrInvalidFilter,
+        },
+        {
+            name:      "UnknownMatch",
+            req:       &dto.SearchUserRequest{Match: "xor"},
+            setupMock: func() {},
+            expectErr: ErrInvalidFilter,
+        },
+    }
+
+    for _, c := range cases {
+        t.Run(c.name, func(t *testing.T) {
+            c.setupMock()
+            _, _, err := repo.Search(context.Background(), c.req)
+            if c.expectErr != nil {
+                require.ErrorIs(t, err, c.expectErr)
+            } else {
+                require.NoError(t, err)
+            }
+            require.NoError(t, mock.ExpectationsWereMet())
+        })
+    }
+}
+
+func TestUserRepository_FreeTextSearch(t *testing.T) {
+    db, mock, err := sqlmock.New()
+    require.NoError(t, err)
+    defer db.Close()
+
+    repo := NewUserRepository(db)
+    columns := []string{"uuid", "name", "email", "phone", "status", "status_reason", "suspended_until", "version", "created_at", "updated_at", "deleted_at"}
+
+    // Without pg_trgm the query degrades to the plain ILIKE match
+    mock.ExpectQuery(regexp.QuoteMeta("SELECT EXISTS (SELECT 1 FROM pg_extension WHERE extname = 'pg_trgm')")).
+        WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+    enabled, err := repo.DetectTextSearch(context.Background())
+    require.NoError(t, err)
+    require.False(t, enabled)
+
+    mock.ExpectQuery(regexp.QuoteMeta("FROM users WHERE deleted_at IS NULL AND (name ILIKE $2 OR email ILIKE $2) ORDER BY created_at DESC, uuid ASC OFFSET $3 LIMIT $4")).
+        WithArgs("jane", "%jane%", 0, 10).
+        WillReturnRows(sqlmock.NewRows(columns))
+    _, _, err = repo.Search(context.Background(), &dto.SearchUserRequest{Q: " jane ", SkipCount: true})
+    require.NoError(t, err)
+
+    mock.ExpectQuery(regexp.QuoteMeta("SELECT EXISTS")).
+        WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+    enabled, err = repo.DetectTextSearch(context.Background())
+    require.NoError(t, err)
+    require.True(t, enabled)
+
+    match := "(search_vector @@ websearch_to_tsquery('simple', $1) OR name % $1 OR name ILIKE $2 OR email ILIKE $2)"
+    rank := "ts_rank(search_vector, websearch_to_tsquery('simple', $1)) + similarity(name, $1)"
+
+    // Ranked by relevance unless a sort is given; the count shares the args
+    mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM users WHERE deleted_at IS NULL AND " + match)).
+        WithArgs("jane", "%jane%").
+        WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+    mock.ExpectQuery(regexp.QuoteMeta("WHERE deleted_at IS NULL AND " + match + " ORDER BY " + rank + " DESC, uuid ASC OFFSET $3 LIMIT $4")).
+        WithArgs("jane", "%jane%", 0, 10).
+        WillReturnRows(sqlmock.NewRows(columns).AddRow("u1", "Jane", "jane@example.com", nil, "active", "", nil, 1, time.Now(), time.Now(), nil))
+    users, total, err := repo.Search(context.Background(), &dto.SearchUserRequest{Q: "jane"})
+    require.NoError(t, err)
+    require.Len(t, users, 1)
+    require.Equal(t, int64(1), total)
+
+    mock.ExpectQuery(regexp.QuoteMeta(match + " ORDER BY name ASC, uuid ASC OFFSET $3 LIMIT $4")).
+        WithArgs("jane", "%jane%", 0, 10).
+        WillReturnRows(sqlmock.NewRows(columns))
+    _, _, err = repo.Search(context.Background(), &dto.SearchUserRequest{Q: "jane", Sort: "name", SkipCount: true})
+    require.NoError(t, err)
+
+    // Cursors cannot key on relevance and keep the regular order
+    mock.ExpectQuery(regexp.QuoteMeta(match + " ORDER BY created_at DESC, uuid ASC LIMIT $3")).
+        WithArgs("jane", "%jane%", 3).
+        WillReturnRows(sqlmock.NewRows(columns))
+    _, err = repo.SearchByCursor(context.Background(), &dto.SearchUserRequest{Q: "jane", Limit: 2, SkipCount: true})
+    require.NoError(t, err)
+
+    require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUserRepository_StreamUsers(t *testing.T) {
+    db, mock, err := sqlmock.New()
+    require.NoError(t, err)
+    defer db.Close()
+
+    repo := NewUserRepository(db)
+
+    mock.ExpectQuery(regexp.QuoteMeta("SELECT uuid, name, email, phone, status, created_at, updated_at, deleted_at FROM users WHERE deleted_at IS NULL AND status IN ($1) ORDER BY name ASC, uuid ASC")).
+        WithArgs("active").
+        WillReturnRows(sqlmock.NewRows([]string{"uuid", "name", "email", "phone", "status", "created_at", "updated_at", "deleted_at"}).
+            AddRow("u1", "Alice", "a@example.com", nil, "active", time.Now(), time.Now(), nil).
+            AddRow("u2", "Bob", "b@example.com", "+15551234567", "active", time.Now(), time.Now(), nil))
+
+    stream, err := repo.StreamUsers(context.Background(), &dto.SearchUserRequest{Status: "active", Sort: "name", Page: 3, Size: 1})
+    require.NoError(t, err)
+
+    var names []string
+    for {
+        var user model.User
+        ok, err := stream.Next(&user)
+        require.NoError(t, err)
+        if !ok {
+            break
+        }
+        names = append(names, user.Name)
+    }
+    require.NoError(t, stream.Close())
+    require.Equal(t, []string{"Alice", "Bob"}, names)
+
+    // Filter errors surface before any query runs
+    _, err = repo.StreamUsers(context.Background(), &dto.SearchUserRequest{Status: "banned"})
+    require.ErrorIs(t, err, ErrInvalidFilter)
+    _, err = repo.StreamUsers(context.Background(), &dto.SearchUserRequest{Sort: "password"})
+    require.ErrorIs(t, err, ErrInvalidSort)
+
+    require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUserRepository_LoadRelations(t *testing.T) {
+    db, mock, err := sqlmock.New()
+    require.NoError(t, err)
+    defer db.Close()
+
+    repo := NewUserRepository(db)
+
+    t.Run("RolesOnly", func(t *testing.T) {
+        users := []*model.User{{UUID: "u1"}, {UUID: "u2"}}
+        mock.ExpectQuery(regexp.QuoteMeta("FROM user_roles ur INNER JOIN roles r ON r.uuid = ur.role_uuid WHERE ur.user_uuid IN ($1, $2)")).
+            WithArgs("u1", "u2").
+            WillReturnRows(sqlmock.NewRows([]string{"user_uuid", "uuid", "name"}).
+                AddRow("u1", "r1", "admin").
+                AddRow("u1", "r2", "editor").
+                AddRow("u2", "r2", "editor"))
+
+        require.NoError(t, repo.LoadRelations(context.Background(), users, Include{Roles: true}))
+        require.Len(t, users[0].Roles, 2)
+        require.Equal(t, "editor", users[1].Roles[0].Name)
+        require.Nil(t, users[0].Roles[0].Permissions)
+    })
+
+    t.Run("RolesAndPermissions", func(t *testing.T) {
+        // Duplicates are queried once; every query covers all users at once
+        users := []*model.User{{UUID: "u1"}, {UUID: "u2"}, {UUID: "u1"}, {UUID: "u3"}}
+        mock.ExpectQuery(regexp.QuoteMeta("FROM user_roles ur INNER JOIN roles r ON r.uuid = ur.role_uuid WHERE ur.user_uuid IN ($1, $2, $3)")).
+            WithArgs("u1", "u2", "u3").
+            WillReturnRows(sqlmock.NewRows([]string{"user_uuid", "uuid", "name"}).
+                AddRow("u1", "r1", "admin").
+                AddRow("u2", "r1", "admin"))
+        mock.ExpectQuery(regexp.QuoteMeta("FROM user_permissions up INNER JOIN permissions p ON p.uuid = up.permission_uuid WHERE up.user_uuid IN ($1, $2, $3)")).
+            WithArgs("u1", "u2", "u3").
+            WillReturnRows(sqlmock.NewRows([]string{"user_uuid", "uuid", "name"}).
+                AddRow("u3", "p9", "read-other"))
+        mock.ExpectQuery(regexp.QuoteMeta("SELECT DISTINCT rp.role_uuid, p.uuid, p.name")).
+            WithArgs("u1", "u2", "u3").
+            WillReturnRows(sqlmock.NewRows([]string{"role_uuid", "uuid", "name"}).
+                AddRow("r1", "p1", "read-user").
+                AddRow("r1", "p2", "update-user"))
+
+        require.NoError(t, repo.LoadRelations(context.Background(), users, IncludeAll))
+        require.Len(t, users[1].Roles, 1)
+        require.Len(t, users[1].Roles[0].Permissions, 2)
+        require.Len(t, users[0].Roles[0].Permissions, 2)
+        require.Len(t, users[2].Roles[0].Permissions, 2)
+        require.Empty(t, users[1].Permissions)
+        require.Empty(t, users[3].Roles)
+        require.Equal(t, "read-other", users[3].Permissions[0].Name)
+    })
+
+    t.Run("QueryError", func(t *testing.T) {
+        mock.ExpectQuery(regexp.QuoteMeta("FROM user_permissions up")).
+            WithArgs("u1").
+            WillReturnError(errors.New("boom"))
+        require.Error(t, repo.LoadRelations(context.Background(), []*model.User{{UUID: "u1"}}, Include{Permissions: true}))
+    })
+
+    // Nothing to load means no query at all
+    require.NoError(t, repo.LoadRelations(context.Background(), nil, IncludeAll))
+    require.NoError(t, repo.LoadRelations(context.Background(), []*model.User{{UUID: "u1"}}, Include{}))
+    require.NoError(t, mock.ExpectationsWereMet())
+}
+
+// The benchmarks compare loading roles and permissions for a page of users
+// one FindByUUID at a time against a single batched LoadRelations call. The
+// mocked driver has no latency, so the gap mostly reflects query count;
+// against a real database every round trip adds to it.
+const benchPageSize = 50
+
+func benchUsers() ([]*model.User, []driver.Value) {
+    users := make([]*model.User, benchPageSize)
+    args := make([]driver.Value, benchPageSize)
+    for i := range users {
+        uuid := fmt.Sprintf("u%d", i)
+        users[i] = &model.User{UUID: uuid}
+        args[i] = uuid
+    }
+    return users, args
+}
+
+// benchRepository returns a repository on a fresh mock; sqlmock keeps matched
+// expectations around, so reusing one across iterations would skew timings.
+func benchRepository(b *testing.B) (*UserRepository, sqlmock.Sqlmock) {
+    db, mock, err := sqlmock.New()
+    require.NoError(b, err)
+    b.Cleanup(func() { db.Close() })
+    return NewUserRepository(db), mock
+}
+
+func BenchmarkUserRepository_LoadRelations_PerUser(b *testing.B) {
+    users, _ := benchUsers()
+    now := time.Now()
+
+    for i := 0; i < b.N; i++ {
+        b.StopTimer()
+        repo, mock := benchRepository(b)
+        for _, user := range users {
+            mock.ExpectQuery("FROM users WHERE uuid").WithArgs(user.UUID).
+                WillReturnRows(sqlmock.NewRows([]string{"uuid", "name", "email", "password", "created_at", "updated_at", "status", "status_reason", "suspended_until", "phone", "version"}).
+                    AddRow(user.UUID, "Name", "name@example.com", "hash", now, now, "active", "", nil, nil, 1))
+            mock.ExpectQuery("FROM user_roles ur").WithArgs(user.UUID).
+                WillReturnRows(sqlmock.NewRows([]string{"user_uuid", "uuid", "name"}).AddRow(user.UUID, "r1", "admin"))
+            mock.ExpectQuery("FROM user_permissions up").WithArgs(user.UUID).
+                WillReturnRows(sqlmock.NewRows([]string{"user_uuid", "uuid", "name"}))
+            mock.ExpectQuery("SELECT DISTINCT rp.role_uuid").WithArgs(user.UUID).
+                WillReturnRows(sqlmock.NewRows([]string{"role_uuid", "uuid", "name"}).AddRow("r1", "p1", "read-user"))
+        }
+        b.StartTimer()
+
+        for _, user := range users {
+            if err := repo.FindByUUID(context.Background(), new(model.User), user.UUID); err != nil {
+                b.Fatal(err)
+            }
+        }
+    }
+}
+
+func BenchmarkUserRepository_LoadRelations_Batched(b *testing.B) {
+    users, args := benchUsers()
+
+    for i := 0; i < b.N; i++ {
+        b.StopTimer()
+        repo, mock := benchRepository(b)
+        roles := sqlmock.NewRows([]string{"user_uuid", "uuid", "name"})
+        for _, user := range users {
+            roles.AddRow(user.UUID, "r1", "admin")
+        }
+        mock.ExpectQuery("FROM user_roles ur").WithArgs(args...).WillReturnRows(roles)
+        mock.ExpectQuery("FROM user_permissions up").WithArgs(args...).
+            WillReturnRows(sqlmock.NewRows([]string{"user_uuid", "uuid", "name"}))
+        mock.ExpectQuery("SELECT DISTINCT rp.role_uuid").WithArgs(args...).
+            WillReturnRows(sqlmock.NewRows([]string{"role_uuid", "uuid", "name"}).AddRow("r1", "p1", "read-user"))
+        b.StartTimer()
+
+        if err := repo.LoadRelations(context.Background(), users, IncludeAll); err != nil {
+            b.Fatal(err)
+        }
+    }
+}
+
 func TestUserRepository_Create_Update_Delete(t *testing.T) {
     db, mock, err := sqlmock.New()
     require.NoError(t, err)
@@ -480,9 +879,9 @@ func TestUserRepository_Create_Update_Delete(t *testing.T) {
         VALUES ($1, $2, $3, $4, NOW(), NOW())
     `
     updateQuery := `
-        UPDATE users SET name = $1, email = $2, updated_at = NOW() WHERE uuid = $3
+        UPDATE users SET name = $1, email = $2, version = version + 1, updated_at = NOW() WHERE uuid = $3 AND version = $4
     `
-    deleteQuery := `DELETE FROM users WHERE uuid = $1`
+    deleteQuery := `UPDATE users SET deleted_at = NOW(), tokens_valid_after = NOW(), version = version + 1 WHERE uuid = $1 AND version = $2 AND deleted_at IS NULL`
 
     type tc struct {
         name      string
@@ -492,6 +891,8 @@ func TestUserRepository_Create_Update_Delete(t *testing.T) {
     }
 
     u := &model.User{UUID: "u10", Name: "Ten", Email: "ten@example.com", Password: "pass"}
+    // fresh copies keep Update's version bump from leaking into other cases
+    fresh := func() *model.User { c := *u; return &c }
 
     cases := []tc{
         {
@@ -518,40 +919,105 @@ func TestUserRepository_Create_Update_Delete(t *testing.T) {
             name: "UpdateSuccess",
             setupMock: func() {
                 mock.ExpectExec(regexp.QuoteMeta(updateQuery)).
-                    WithArgs("Ten", "ten@example.com", "u10").
+                    WithArgs("Ten", "ten@example.com", "u10", 0).
                     WillReturnResult(sqlmock.NewResult(1, 1))
             },
-            action: func() error { return repo.Update(context.Background(), u) },
+            action: func() error { return repo.Update(context.Background(), fresh()) },
             expectErr: false,
         },
         {
             name: "UpdateError",
             setupMock: func() {
                 mock.ExpectExec(regexp.QuoteMeta(updateQuery)).
-                    WithArgs("Ten", "ten@example.com", "u10").
+                    WithArgs("Ten", "ten@example.com", "u10", 0).
                     WillReturnError(errors.New("update error"))
             },
-            action: func() error { return repo.Update(context.Background(), u) },
+            action: func() error { return repo.Update(context.Background(), fresh()) },
+            expectErr: true,
+        },
+        {
+            name: "UpdateSelectedColumns",
+            setupMock: func() {
+                mock.ExpectExec(regexp.QuoteMeta(`UPDATE users SET phone = $1, name = $2, version = version + 1, updated_at = NOW() WHERE uuid = $3 AND version = $4`)).
+                    WithArgs(nil, "Ten", "u10", 0).
+                    WillReturnResult(sqlmock.NewResult(1, 1))
+            },
+            action: func() error { return repo.Update(context.Background(), fresh(), "phone", "name") },
+            expectErr: false,
+        },
+        {
+            name: "UpdateBumpsVersion",
+            setupMock: func() {
+                mock.ExpectExec(regexp.QuoteMeta(updateQuery)).
+                    WithArgs("Ten", "ten@example.com", "u10", 4).
+                    WillReturnResult(sqlmock.NewResult(0, 1))
+            },
+            action: func() error {
+                user := fresh()
+                user.Version = 4
+                if err := repo.Update(context.Background(), user); err != nil {
+                    return err
+                }
+                if user.Version != 5 {
+                    return fmt.Errorf("expected version 5, got %d", user.Version)
+                }
+                return nil
+            },
+            expectErr: false,
+        },
+        {
+            name: "UpdateStaleVersion",
+            setupMock: func() {
+                mock.ExpectExec(regexp.QuoteMeta(updateQuery)).
+                    WithArgs("Ten", "ten@example.com", "u10", 0).
+                    WillReturnResult(sqlmock.NewResult(0, 0))
+            },
+            action: func() error {
+                if err := repo.Update(context.Background(), fresh()); !errors.Is(err, ErrStaleVersion) {
+                    return fmt.Errorf("expected ErrStaleVersion, got %v", err)
+                }
+                return nil
+            },
+            expectErr: false,
+        },
+        {
+            name: "UpdateUnknownColumn",
+            setupMock: func() {},
+            action: func() error { return repo.Update(context.Background(), fresh(), "password") },
             expectErr: true,
         },
         {
             name: "DeleteSuccess",
             setupMock: func() {
                 mock.ExpectExec(regexp.QuoteMeta(deleteQuery)).
-                    WithArgs("u10").
+                    WithArgs("u10", int64(0)).
                     WillReturnResult(sqlmock.NewResult(1, 1))
             },
-            action: func() error { return repo.Delete(context.Background(), u) },
+            action: func() error { return repo.Delete(context.Background(), fresh()) },
             expectErr: false,
         },
+        {
+            name: "DeleteStaleVersion",
+            setupMock: func() {
+                mock.ExpectExec(regexp.QuoteMeta(deleteQuery)).
+                    WithArgs("u10", int64(0)).
+                    WillReturnResult(sqlmock.NewResult(0, 0))
+            },
+            action: func() error {
+                err := repo.Delete(context.Background(), fresh())
+                require.ErrorIs(t, err, ErrStaleVersion)
+                return err
+            },
+            expectErr: true,
+        },
         {
             name: "DeleteError",
             setupMock: func() {
                 mock.ExpectExec(regexp.QuoteMeta(deleteQuery)).
-                    WithArgs("u10").
+                    WithArgs("u10", int64(0)).
                     WillReturnError(errors.New("delete error"))
             },
-            action: func() error { return repo.Delete(context.Background(), u) },
+            action: func() error { return repo.Delete(context.Background(), fresh()) },
             expectErr: true,
         },
     }
@@ -568,4 +1034,218 @@ func TestUserRepository_Create_Update_Delete(t *testing.T) {
             require.NoError(t, mock.ExpectationsWereMet())
         })
     }
-}
\ No newline at end of file
+}
+func TestUserRepository_SoftDelete(t *testing.T) {
+    db, mock, err := sqlmock.New()
+    require.NoError(t, err)
+    defer db.Close()
+
+    repo := NewUserRepository(db)
+    now := time.Now()
+    before := now.Add(-time.Hour)
+
+    findQuery := `SELECT uuid, name, email, password, version, created_at, updated_at, deleted_at FROM users WHERE uuid = $1 AND deleted_at IS NOT NULL LIMIT 1`
+    restoreQuery := `UPDATE users SET deleted_at = NULL, version = version + 1, updated_at = NOW() WHERE uuid = $1 AND deleted_at IS NOT NULL`
+    purgeRolesQuery := `DELETE FROM user_roles WHERE user_uuid IN (SELECT uuid FROM users WHERE deleted_at < $1)`
+    purgePermsQuery := `DELETE FROM user_permissions WHERE user_uuid IN (SELECT uuid FROM users WHERE deleted_at < $1)`
+    purgeUsersQuery := `DELETE FROM users WHERE deleted_at < $1`
+
+    cases := []struct {
+        name      string
+        setupMock func()
+        action    func(t *testing.T) error
+        expectErr bool
+    }{
+        {
+            name: "FindDeletedSuccess",
+            setupMock: func() {
+                mock.ExpectQuery(regexp.QuoteMeta(findQuery)).
+                    WithArgs("u1").
+                    WillReturnRows(sqlmock.NewRows([]string{"uuid", "name", "email", "password", "version", "created_at", "updated_at", "deleted_at"}).
+                        AddRow("u1", "John", "john@example.com", "pass", 3, now, now, now))
+            },
+            action: func(t *testing.T) error {
+                var u model.User
+                err := repo.FindDeletedByUUID(context.Background(), &u, "u1")
+                require.NotNil(t, u.DeletedAt)
+                require.Equal(t, int64(3), u.Version)
+                return err
+            },
+        },
+        {
+            name: "FindDeletedNotFound",
+            setupMock: func() {
+                mock.ExpectQuery(regexp.QuoteMeta(findQuery)).
+                    WithArgs("u1").
+                    WillReturnError(sql.ErrNoRows)
+            },
+            action: func(t *testing.T) error {
+                var u model.User
+                return repo.FindDeletedByUUID(context.Background(), &u, "u1")
+            },
+            expectErr: true,
+        },
+        {
+            name: "RestoreSuccess",
+            setupMock: func() {
+                mock.ExpectExec(regexp.QuoteMeta(restoreQuery)).
+                    WithArgs("u1").
+                    WillReturnResult(sqlmock.NewResult(0, 1))
+            },
+            action: func(t *testing.T) error {
+                return repo.Restore(context.Background(), &model.User{UUID: "u1"})
+            },
+        },
+        {
+            name: "RestoreError",
+            setupMock: func() {
+                mock.ExpectExec(regexp.QuoteMeta(restoreQuery)).
+                    WithArgs("u1").
+                    WillReturnError(errors.New("restore error"))
+            },
+            action: func(t *testing.T) error {
+                return repo.Restore(context.Background(), &model.User{UUID: "u1"})
+            },
+            expectErr: true,
+        },
+        {
+            name: "PurgeSuccess",
+            setupMock: func() {
+                mock.ExpectExec(regexp.QuoteMeta(purgeRolesQuery)).WithArgs(before).WillReturnResult(sqlmock.NewResult(0, 2))
+                mock.ExpectExec(regexp.QuoteMeta(purgePermsQuery)).WithArgs(before).WillReturnResult(sqlmock.NewResult(0, 0))
+                mock.ExpectExec(regexp.QuoteMeta(purgeUsersQuery)).WithArgs(before).WillReturnResult(sqlmock.NewResult(0, 3))
+            },
+            action: func(t *testing.T) error {
+                purged, err := repo.PurgeDeleted(context.Background(), before)
+                require.Equal(t, int64(3), purged)
+                return err
+            },
+        },
+        {
+            name: "PurgeAssignmentsError",
+            setupMock: func() {
+                mock.ExpectExec(regexp.QuoteMeta(purgeRolesQuery)).WithArgs(before).WillReturnError(errors.New("purge error"))
+            },
+            action: func(t *testing.T) error {
+                _, err := repo.PurgeDeleted(context.Background(), before)
+                return err
+            },
+            expectErr: true,
+        },
+    }
+
+    for _, c := range cases {
+        t.Run(c.name, func(t *testing.T) {
+            c.setupMock()
+            err := c.action(t)
+            if c.expectErr {
+                require.Error(t, err)
+            } else {
+                require.NoError(t, err)
+            }
+            require.NoError(t, mock.ExpectationsWereMet())
+        })
+    }
+}
+
+func TestUserRepository_Status(t *testing.T) {
+    db, mock, err := sqlmock.New()
+    require.NoError(t, err)
+    defer db.Close()
+
+    repo := NewUserRepository(db)
+    until := time.Now().Add(time.Hour)
+
+    findQuery := `SELECT uuid, status, status_reason, suspended_until, tokens_valid_after FROM users WHERE uuid = $1 AND deleted_at IS NULL LIMIT 1`
+    updateQuery := `UPDATE users SET status = $1, status_reason = $2, suspended_until = $3, version = version + 1, updated_at = NOW() WHERE uuid = $4 AND version = $5 AND deleted_at IS NULL`
+
+    cases := []struct {
+        name      string
+        setupMock func()
+        action    func(t *testing.T) error
+        expectErr bool
+    }{
+        {
+            name: "FindStatusSuccess",
+            setupMock: func() {
+                mock.ExpectQuery(regexp.QuoteMeta(findQuery)).
+                    WithArgs("u1").
+                    WillReturnRows(sqlmock.NewRows([]string{"uuid", "status", "status_reason", "suspended_until", "tokens_valid_after"}).
+                        AddRow("u1", "suspended", "abuse", until, nil))
+            },
+            action: func(t *testing.T) error {
+                var u model.User
+                err := repo.FindStatusByUUID(context.Background(), &u, "u1")
+                require.Equal(t, "suspended", u.Status)
+                require.Equal(t, "abuse", u.StatusReason)
+                require.NotNil(t, u.SuspendedUntil)
+                return err
+            },
+        },
+        {
+            name: "FindStatusNotFound",
+            setupMock: func() {
+                mock.ExpectQuery(regexp.QuoteMeta(findQuery)).
+                    WithArgs("u1").
+                    WillReturnError(sql.ErrNoRows)
+            },
+            action: func(t *testing.T) error {
+                var u model.User
+                return repo.FindStatusByUUID(context.Background(), &u, "u1")
+            },
+            expectErr: true,
+        },
+        {
+            name: "UpdateStatusSuccess",
+            setupMock: func() {
+                mock.ExpectExec(regexp.QuoteMeta(updateQuery)).
+                    WithArgs("suspended", "abuse", &until, "u1", int64(0)).
+                    WillReturnResult(sqlmock.NewResult(0, 1))
+            },
+            action: func(t *testing.T) error {
+                return repo.UpdateStatus(context.Background(), &model.User{UUID: "u1", Status: "suspended", StatusReason: "abuse", SuspendedUntil: &until})
+            },
+        },
+        {
+            name: "UpdateStatusStaleVersion",
+            setupMock: func() {
+                mock.ExpectExec(regexp.QuoteMeta(updateQuery)).
+                    WithArgs("suspended", "", nil, "u1", int64(2)).
+                    WillReturnResult(sqlmock.NewResult(0, 0))
+            },
+            action: func(t *testing.T) error {
+                user := &model.User{UUID: "u1", Status: "suspended", Version: 2}
+                err := repo.UpdateStatus(context.Background(), user)
+                require.ErrorIs(t, err, ErrStaleVersion)
+                require.Equal(t, int64(2), user.Version)
+                return err
+            },
+            expectErr: true,
+        },
+        {
+            name: "UpdateStatusError",
+            setupMock: func() {
+                mock.ExpectExec(regexp.QuoteMeta(updateQuery)).
+                    WithArgs("active", "", nil, "u1", int64(0)).
+                    WillReturnError(errors.New("update error"))
+            },
+            action: func(t *testing.T) error {
+                return repo.UpdateStatus(context.Background(), &model.User{UUID: "u1", Status: "active"})
+            },
+            expectErr: true,
+        },
+    }
+
+    for _, c := range cases {
+        t.Run(c.name, func(t *testing.T) {
+            c.setupMock()
+            err := c.action(t)
+            if c.expectErr {
+                require.Error(t, err)
+            } else {
+                require.NoError(t, err)
+            }
+            require.NoError(t, mock.ExpectationsWereMet())
+        })
+    }
+}
@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// webhookQueueKey is the sorted set of delivery UUIDs scored by the Unix
+	// milliseconds of their next attempt.
+	webhookQueueKey = "webhooks:queue"
+	// webhookActiveKey is the sorted set of claimed delivery UUIDs scored by
+	// the Unix milliseconds at which their lease expires.
+	webhookActiveKey = "webhooks:active"
+)
+
+// WebhookQueue schedules webhook delivery attempts in Redis. It only holds
+// when to try; the delivery itself lives in webhook_deliveries.
+type WebhookQueue struct {
+	client *redis.Client
+}
+
+func NewWebhookQueue(client *redis.Client) *WebhookQueue {
+	return &WebhookQueue{client: client}
+}
+
+// Schedule queues an attempt of the delivery at at, moving it if it is
+// already queued and ending its lease if it is claimed.
+func (q *WebhookQueue) Schedule(ctx context.Context, deliveryUUID string, at time.Time) error {
+	_, err := q.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZAdd(ctx, webhookQueueKey, redis.Z{Score: float64(at.UnixMilli()), Member: deliveryUUID})
+		pipe.ZRem(ctx, webhookActiveKey, deliveryUUID)
+		return nil
+	})
+	return err
+}
+
+// claimDueScript first requeues the deliveries whose lease expired before
+// ARGV[1], due immediately, then moves up to ARGV[3] deliveries due by
+// ARGV[1] from the queue to active, leased until ARGV[2], and returns them.
+var claimDueScript = redis.NewScript(`
+local expired = redis.call('ZRANGEBYSCORE', KEYS[2], '-inf', ARGV[1])
+for _, id in ipairs(expired) do
+    redis.call('ZREM', KEYS[2], id)
+    redis.call('ZADD', KEYS[1], ARGV[1], id)
+end
+local ids = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, ARGV[3])
+for _, id in ipairs(ids) do
+    redis.call('ZREM', KEYS[1], id)
+    redis.call('ZADD', KEYS[2], ARGV[2], id)
+end
+return ids
+`)
+
+// ClaimDue leases up to limit deliveries due by now and returns them, each
+// claimed by one worker only. A claimed delivery leaves the queue until
+// Schedule queues its next attempt or Release drops it. If neither happens
+// before the lease runs out, e.g. because the worker died mid-attempt, it is
+// queued again by a later ClaimDue.
+func (q *WebhookQueue) ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]string, error) {
+	return claimDueScript.Run(ctx, q.client,
+		[]string{webhookQueueKey, webhookActiveKey},
+		now.UnixMilli(), now.Add(lease).UnixMilli(), limit,
+	).StringSlice()
+}
+
+// Release ends the lease of a delivery that needs no further attempt.
+func (q *WebhookQueue) Release(ctx context.Context, deliveryUUID string) error {
+	return q.client.ZRem(ctx, webhookActiveKey, deliveryUUID).Err()
+}
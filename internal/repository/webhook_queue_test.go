@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	miniredis "github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookQueue_ClaimDue(t *testing.T) {
+	mr := miniredis.RunT(t)
+	queue := NewWebhookQueue(redis.NewClient(&redis.Options{Addr: mr.Addr()}))
+	ctx := context.Background()
+	now := time.Now()
+
+	require.NoError(t, queue.Schedule(ctx, "later", now.Add(time.Minute)))
+	require.NoError(t, queue.Schedule(ctx, "second", now.Add(-time.Second)))
+	require.NoError(t, queue.Schedule(ctx, "first", now.Add(-time.Minute)))
+
+	claimed, err := queue.ClaimDue(ctx, now, time.Minute, 10)
+	require.NoError(t, err)
+	require.Equal(t, []string{"first", "second"}, claimed)
+
+	claimed, err = queue.ClaimDue(ctx, now, time.Minute, 10)
+	require.NoError(t, err)
+	require.Empty(t, claimed)
+
+	// rescheduling moves the attempt instead of queueing it twice
+	require.NoError(t, queue.Schedule(ctx, "later", now.Add(-time.Second)))
+	claimed, err = queue.ClaimDue(ctx, now, time.Minute, 1)
+	require.NoError(t, err)
+	require.Equal(t, []string{"later"}, claimed)
+}
+
+func TestWebhookQueue_Lease(t *testing.T) {
+	mr := miniredis.RunT(t)
+	queue := NewWebhookQueue(redis.NewClient(&redis.Options{Addr: mr.Addr()}))
+	ctx := context.Background()
+	now := time.Now()
+
+	require.NoError(t, queue.Schedule(ctx, "crashed", now.Add(-time.Second)))
+	require.NoError(t, queue.Schedule(ctx, "retried", now.Add(-time.Second)))
+	require.NoError(t, queue.Schedule(ctx, "delivered", now.Add(-time.Second)))
+	claimed, err := queue.ClaimDue(ctx, now, time.Minute, 10)
+	require.NoError(t, err)
+	require.Len(t, claimed, 3)
+
+	// a retry ends the lease, and so does releasing a finished delivery
+	require.NoError(t, queue.Schedule(ctx, "retried", now.Add(time.Hour)))
+	require.NoError(t, queue.Release(ctx, "delivered"))
+
+	// the delivery whose worker never reported back is claimed again once
+	// its lease has expired
+	claimed, err = queue.ClaimDue(ctx, now.Add(30*time.Second), time.Minute, 10)
+	require.NoError(t, err)
+	require.Empty(t, claimed)
+	claimed, err = queue.ClaimDue(ctx, now.Add(2*time.Minute), time.Minute, 10)
+	require.NoError(t, err)
+	require.Equal(t, []string{"crashed"}, claimed)
+}
@@ -0,0 +1,304 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"go-starter-template/internal/model"
+
+	"github.com/goccy/go-json"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type WebhookRepository struct {
+	*Repository
+	tracer trace.Tracer
+}
+
+func NewWebhookRepository(db *sql.DB) *WebhookRepository {
+	return &WebhookRepository{Repository: &Repository{db: db}, tracer: otel.Tracer("WebhookRepository")}
+}
+
+const webhookSubscriptionColumns = `uuid, url, description, events, secret, enabled, consecutive_failures, disabled_reason, created_at, updated_at, COALESCE(owner_uuid, '')`
+
+const webhookDeliveryColumns = `uuid, subscription_uuid, event_id, event_type, payload, status, attempts, response_status, last_error, next_attempt_at, created_at, delivered_at`
+
+func (r *WebhookRepository) CreateSubscription(ctx context.Context, subscription *model.WebhookSubscription) error {
+	spanCtx, span := r.tracer.Start(ctx, "WebhookRepository.CreateSubscription")
+	defer span.End()
+
+	events, err := json.Marshal(subscription.Events)
+	if err != nil {
+		return err
+	}
+	_, err = r.getExecutor(spanCtx).ExecContext(spanCtx, `
+        INSERT INTO webhook_subscriptions (uuid, url, description, events, secret, owner_uuid, created_at, updated_at)
+        VALUES ($1, $2, $3, $4, $5, $6, NOW(), NOW())
+    `, subscription.UUID, subscription.URL, subscription.Description, events, subscription.Secret, subscription.OwnerUUID)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "create webhook subscription failed")
+	}
+	return err
+}
+
+// FindSubscription loads a subscription, returning sql.ErrNoRows if there is none.
+func (r *WebhookRepository) FindSubscription(ctx context.Context, subscription *model.WebhookSubscription, uuid string) error {
+	spanCtx, span := r.tracer.Start(ctx, "WebhookRepository.FindSubscription")
+	defer span.End()
+
+	row := r.getExecutor(spanCtx).QueryRowContext(spanCtx, `SELECT `+webhookSubscriptionColumns+` FROM webhook_subscriptions WHERE uuid = $1`, uuid)
+	if err := scanWebhookSubscription(row, subscription); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "find webhook subscription failed")
+		return err
+	}
+	return nil
+}
+
+// ListSubscriptions returns all subscriptions, or only the enabled ones,
+// oldest first.
+func (r *WebhookRepository) ListSubscriptions(ctx context.Context, onlyEnabled bool) ([]*model.WebhookSubscription, error) {
+	spanCtx, span := r.tracer.Start(ctx, "WebhookRepository.ListSubscriptions")
+	defer span.End()
+
+	rows, err := r.getExecutor(spanCtx).QueryContext(spanCtx, `
+        SELECT `+webhookSubscriptionColumns+`
+        FROM webhook_subscriptions
+        WHERE enabled OR NOT $1
+        ORDER BY created_at ASC, uuid ASC
+    `, onlyEnabled)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "list webhook subscriptions failed")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var subscriptions []*model.WebhookSubscription
+	for rows.Next() {
+		subscription := new(model.WebhookSubscription)
+		if err := scanWebhookSubscription(rows, subscription); err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "scan webhook subscription failed")
+			return nil, err
+		}
+		subscriptions = append(subscriptions, subscription)
+	}
+	return subscriptions, rows.Err()
+}
+
+// ListSubscriptionsByOwner returns the subscriptions of a user, oldest first.
+func (r *WebhookRepository) ListSubscriptionsByOwner(ctx context.Context, ownerUUID string) ([]*model.WebhookSubscription, error) {
+	spanCtx, span := r.tracer.Start(ctx, "WebhookRepository.ListSubscriptionsByOwner")
+	defer span.End()
+
+	rows, err := r.getExecutor(spanCtx).QueryContext(spanCtx, `
+        SELECT `+webhookSubscriptionColumns+`
+        FROM webhook_subscriptions
+        WHERE owner_uuid = $1
+        ORDER BY created_at ASC, uuid ASC
+    `, ownerUUID)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "list webhook subscriptions failed")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var subscriptions []*model.WebhookSubscription
+	for rows.Next() {
+		subscription := new(model.WebhookSubscription)
+		if err := scanWebhookSubscription(rows, subscription); err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "scan webhook subscription failed")
+			return nil, err
+		}
+		subscriptions = append(subscriptions, subscription)
+	}
+	return subscriptions, rows.Err()
+}
+
+// UpdateSubscription writes every editable column of subscription, returning
+// sql.ErrNoRows if it does not exist.
+func (r *WebhookRepository) UpdateSubscription(ctx context.Context, subscription *model.WebhookSubscription) error {
+	spanCtx, span := r.tracer.Start(ctx, "WebhookRepository.UpdateSubscription")
+	defer span.End()
+
+	events, err := json.Marshal(subscription.Events)
+	if err != nil {
+		return err
+	}
+	result, err := r.getExecutor(spanCtx).ExecContext(spanCtx, `
+        UPDATE webhook_subscriptions
+        SET url = $1, description = $2, events = $3, enabled = $4, consecutive_failures = $5, disabled_reason = $6, updated_at = NOW()
+        WHERE uuid = $7
+    `, subscription.URL, subscription.Description, events, subscription.Enabled, subscription.ConsecutiveFailures, subscription.DisabledReason, subscription.UUID)
+	if err := requireAffected(result, err); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "update webhook subscription failed")
+		return err
+	}
+	return nil
+}
+
+// DeleteSubscription removes a subscription of ownerUUID and its delivery
+// log, returning sql.ErrNoRows if the user has no such subscription.
+func (r *WebhookRepository) DeleteSubscription(ctx context.Context, uuid, ownerUUID string) error {
+	spanCtx, span := r.tracer.Start(ctx, "WebhookRepository.DeleteSubscription")
+	defer span.End()
+
+	result, err := r.getExecutor(spanCtx).ExecContext(spanCtx, `DELETE FROM webhook_subscriptions WHERE uuid = $1 AND owner_uuid = $2`, uuid, ownerUUID)
+	if err := requireAffected(result, err); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "delete webhook subscription failed")
+		return err
+	}
+	return nil
+}
+
+// RecordSuccess resets the consecutive failures of a subscription.
+func (r *WebhookRepository) RecordSuccess(ctx context.Context, uuid string) error {
+	_, err := r.getExecutor(ctx).ExecContext(ctx, `UPDATE webhook_subscriptions SET consecutive_failures = 0 WHERE uuid = $1 AND consecutive_failures > 0`, uuid)
+	return err
+}
+
+// RecordFailure counts a failed attempt against a subscription and disables
+// it with reason once disableAfter attempts in a row have failed. It returns
+// whether the subscription is still enabled. Run it in a transaction: it
+// writes through QueryRow, which outside one may go to a read replica.
+func (r *WebhookRepository) RecordFailure(ctx context.Context, uuid string, disableAfter int, reason string) (bool, error) {
+	spanCtx, span := r.tracer.Start(ctx, "WebhookRepository.RecordFailure")
+	defer span.End()
+
+	var enabled bool
+	err := r.getExecutor(spanCtx).QueryRowContext(spanCtx, `
+        UPDATE webhook_subscriptions
+        SET consecutive_failures = consecutive_failures + 1,
+            enabled = enabled AND consecutive_failures + 1 < $2,
+            disabled_reason = CASE WHEN enabled AND consecutive_failures + 1 >= $2 THEN $3 ELSE disabled_reason END,
+            updated_at = NOW()
+        WHERE uuid = $1
+        RETURNING enabled
+    `, uuid, disableAfter, reason).Scan(&enabled)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "record webhook failure failed")
+	}
+	return enabled, err
+}
+
+// CreateDelivery records a delivery unless the subscription already has one
+// for the same event, and reports whether it was created.
+func (r *WebhookRepository) CreateDelivery(ctx context.Context, delivery *model.WebhookDelivery) (bool, error) {
+	spanCtx, span := r.tracer.Start(ctx, "WebhookRepository.CreateDelivery")
+	defer span.End()
+
+	result, err := r.getExecutor(spanCtx).ExecContext(spanCtx, `
+        INSERT INTO webhook_deliveries (uuid, subscription_uuid, event_id, event_type, payload, status, next_attempt_at, created_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, NOW())
+        ON CONFLICT (subscription_uuid, event_id) DO NOTHING
+    `, delivery.UUID, delivery.SubscriptionUUID, delivery.EventID, delivery.EventType, delivery.Payload, delivery.Status, delivery.NextAttemptAt)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "create webhook delivery failed")
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// FindDelivery loads a delivery, returning sql.ErrNoRows if there is none.
+func (r *WebhookRepository) FindDelivery(ctx context.Context, delivery *model.WebhookDelivery, uuid string) error {
+	spanCtx, span := r.tracer.Start(ctx, "WebhookRepository.FindDelivery")
+	defer span.End()
+
+	row := r.getExecutor(spanCtx).QueryRowContext(spanCtx, `SELECT `+webhookDeliveryColumns+` FROM webhook_deliveries WHERE uuid = $1`, uuid)
+	if err := scanWebhookDelivery(row, delivery); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "find webhook delivery failed")
+		return err
+	}
+	return nil
+}
+
+// ListDeliveries returns the latest deliveries of a subscription, newest
+// first, optionally only those with status.
+func (r *WebhookRepository) ListDeliveries(ctx context.Context, subscriptionUUID, status string, limit int) ([]*model.WebhookDelivery, error) {
+	spanCtx, span := r.tracer.Start(ctx, "WebhookRepository.ListDeliveries")
+	defer span.End()
+
+	rows, err := r.getExecutor(spanCtx).QueryContext(spanCtx, `
+        SELECT `+webhookDeliveryColumns+`
+        FROM webhook_deliveries
+        WHERE subscription_uuid = $1 AND ($2 = '' OR status = $2)
+        ORDER BY created_at DESC, uuid DESC
+        LIMIT $3
+    `, subscriptionUUID, status, limit)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "list webhook deliveries failed")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []*model.WebhookDelivery
+	for rows.Next() {
+		delivery := new(model.WebhookDelivery)
+		if err := scanWebhookDelivery(rows, delivery); err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "scan webhook delivery failed")
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, rows.Err()
+}
+
+// UpdateDelivery writes the outcome columns of delivery.
+func (r *WebhookRepository) UpdateDelivery(ctx context.Context, delivery *model.WebhookDelivery) error {
+	spanCtx, span := r.tracer.Start(ctx, "WebhookRepository.UpdateDelivery")
+	defer span.End()
+
+	result, err := r.getExecutor(spanCtx).ExecContext(spanCtx, `
+        UPDATE webhook_deliveries
+        SET status = $1, attempts = $2, response_status = $3, last_error = $4, next_attempt_at = $5, delivered_at = $6
+        WHERE uuid = $7
+    `, delivery.Status, delivery.Attempts, delivery.ResponseStatus, delivery.LastError, delivery.NextAttemptAt, delivery.DeliveredAt, delivery.UUID)
+	if err := requireAffected(result, err); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "update webhook delivery failed")
+		return err
+	}
+	return nil
+}
+
+// requireAffected turns a statement that matched no row into sql.ErrNoRows.
+func requireAffected(result sql.Result, err error) error {
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err == nil && affected == 0 {
+		err = sql.ErrNoRows
+	}
+	return err
+}
+
+// rowScanner is implemented by *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanWebhookSubscription(row rowScanner, subscription *model.WebhookSubscription) error {
+	var events []byte
+	if err := row.Scan(&subscription.UUID, &subscription.URL, &subscription.Description, &events, &subscription.Secret, &subscription.Enabled, &subscription.ConsecutiveFailures, &subscription.DisabledReason, &subscription.CreatedAt, &subscription.UpdatedAt, &subscription.OwnerUUID); err != nil {
+		return err
+	}
+	return json.Unmarshal(events, &subscription.Events)
+}
+
+func scanWebhookDelivery(row rowScanner, delivery *model.WebhookDelivery) error {
+	return row.Scan(&delivery.UUID, &delivery.SubscriptionUUID, &delivery.EventID, &delivery.EventType, &delivery.Payload, &delivery.Status, &delivery.Attempts, &delivery.ResponseStatus, &delivery.LastError, &delivery.NextAttemptAt, &delivery.CreatedAt, &delivery.DeliveredAt)
+}
@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+
+	"go-starter-template/internal/model"
+)
+
+func TestWebhookRepository_ListSubscriptions(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := NewWebhookRepository(db)
+	now := time.Now()
+	mock.ExpectQuery(regexp.QuoteMeta(`WHERE enabled OR NOT $1`)).
+		WithArgs(true).
+		WillReturnRows(sqlmock.NewRows([]string{"uuid", "url", "description", "events", "secret", "enabled", "consecutive_failures", "disabled_reason", "created_at", "updated_at", "owner_uuid"}).
+			AddRow("w1", "https://a.example.com", "CRM", []byte(`["user.created","user.deleted"]`), "whsec_1", true, 2, "", now, now, "u1"))
+
+	subscriptions, err := repo.ListSubscriptions(context.Background(), true)
+	require.NoError(t, err)
+	require.Len(t, subscriptions, 1)
+	require.Equal(t, []string{"user.created", "user.deleted"}, subscriptions[0].Events)
+	require.Equal(t, 2, subscriptions[0].ConsecutiveFailures)
+	require.Equal(t, "u1", subscriptions[0].OwnerUUID)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestWebhookRepository_UpdateSubscription(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := NewWebhookRepository(db)
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE webhook_subscriptions`)).
+		WithArgs("https://a.example.com", "", []byte(`["*"]`), false, 0, "disabled manually", "w1").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err = repo.UpdateSubscription(context.Background(), &model.WebhookSubscription{
+		UUID:           "w1",
+		URL:            "https://a.example.com",
+		Events:         []string{"*"},
+		DisabledReason: "disabled manually",
+	})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestWebhookRepository_RecordFailure(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := NewWebhookRepository(db)
+	mock.ExpectQuery(regexp.QuoteMeta(`SET consecutive_failures = consecutive_failures + 1`)).
+		WithArgs("w1", 3, "too many failures").
+		WillReturnRows(sqlmock.NewRows([]string{"enabled"}).AddRow(false))
+
+	enabled, err := repo.RecordFailure(context.Background(), "w1", 3, "too many failures")
+	require.NoError(t, err)
+	require.False(t, enabled)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestWebhookRepository_CreateDelivery(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := NewWebhookRepository(db)
+	insert := regexp.QuoteMeta(`ON CONFLICT (subscription_uuid, event_id) DO NOTHING`)
+	delivery := &model.WebhookDelivery{UUID: "d1", SubscriptionUUID: "w1", EventID: "e1", EventType: "user.created", Payload: []byte(`{}`), Status: "pending"}
+
+	mock.ExpectExec(insert).WillReturnResult(sqlmock.NewResult(1, 1))
+	created, err := repo.CreateDelivery(context.Background(), delivery)
+	require.NoError(t, err)
+	require.True(t, created)
+
+	mock.ExpectExec(insert).WillReturnResult(sqlmock.NewResult(0, 0))
+	created, err = repo.CreateDelivery(context.Background(), delivery)
+	require.NoError(t, err)
+	require.False(t, created)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
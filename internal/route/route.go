@@ -1,7 +1,9 @@
 package route
 
 import (
+	"go-starter-template/internal/constant"
 	"go-starter-template/internal/controller"
+	"go-starter-template/internal/middleware"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
@@ -43,15 +45,75 @@ func (r *RouteConfig) RegisterAuthRoutes(authController *controller.AuthControll
 	}
 }
 
-// RegisterUserRoutes defines user-related routes with authentication middleware
-func (r *RouteConfig) RegisterUserRoutes(userController *controller.UserController, authMiddleware fiber.Handler) {
+// RegisterUserRoutes defines user-related routes with authentication middleware.
+// authorize guards the administrative ones with a policy action.
+func (r *RouteConfig) RegisterUserRoutes(userController *controller.UserController, loginHistoryController *controller.LoginHistoryController, authMiddleware fiber.Handler, authorize func(action string) fiber.Handler) {
 	user := r.App.Group("/api/users")
 	{
 		user.Use(authMiddleware)
 		user.Get("/", userController.List)
 		user.Get("/me", userController.Me)
+		user.Patch("/me", userController.UpdateMe)
+		user.Delete("/me", userController.DeleteMe)
+		user.Post("/me/email/verify", userController.VerifyEmail)
+		user.Get("/me/logins", loginHistoryController.Me)
+		user.Get("/deleted", authorize(constant.ActionUserListDeleted), userController.ListDeleted)
 		user.Post("/", userController.Create)
+		user.Post("/bulk", authorize(constant.ActionUserBulk), userController.Bulk)
+		user.Get("/export", authorize(constant.ActionUserExport), userController.Export)
+		user.Post("/import", authorize(constant.ActionUserImport), userController.Import)
+		user.Get("/:uuid", userController.Show)
 		user.Put("/:uuid", userController.Update)
+		user.Patch("/:uuid", userController.Patch)
 		user.Delete("/:uuid", userController.Delete)
+		user.Post("/:uuid/restore", authorize(constant.ActionUserRestore), userController.Restore)
+		user.Post("/:uuid/suspend", authorize(constant.ActionUserSuspend), userController.Suspend)
+		user.Post("/:uuid/reactivate", authorize(constant.ActionUserReactivate), userController.Reactivate)
+		user.Get("/:uuid/permissions/effective", middleware.UnlessSelf("uuid", authorize(constant.ActionUserReadPermissions)), userController.EffectivePermissions)
+	}
+}
+
+// RegisterAuthzRoutes defines authorization debugging routes with authentication middleware
+func (r *RouteConfig) RegisterAuthzRoutes(authzController *controller.AuthzController, authMiddleware fiber.Handler) {
+	authz := r.App.Group("/api/authz")
+	{
+		authz.Use(authMiddleware)
+		authz.Post("/check", authzController.Check)
+	}
+}
+
+// RegisterWebhookRoutes defines webhook subscription routes, restricted to
+// callers allowed to manage webhooks
+func (r *RouteConfig) RegisterWebhookRoutes(webhookController *controller.WebhookController, authMiddleware fiber.Handler, authorize func(action string) fiber.Handler) {
+	webhooks := r.App.Group("/api/webhooks")
+	{
+		webhooks.Use(authMiddleware, authorize(constant.ActionWebhookManage))
+		webhooks.Get("/", webhookController.List)
+		webhooks.Post("/", webhookController.Create)
+		webhooks.Get("/:uuid", webhookController.Show)
+		webhooks.Put("/:uuid", webhookController.Update)
+		webhooks.Delete("/:uuid", webhookController.Delete)
+		webhooks.Get("/:uuid/deliveries", webhookController.Deliveries)
+		webhooks.Post("/:uuid/deliveries/:delivery/replay", webhookController.Replay)
+	}
+}
+
+// RegisterAuditRoutes defines audit log routes, restricted to callers allowed
+// to read the audit log
+func (r *RouteConfig) RegisterAuditRoutes(auditController *controller.AuditController, authMiddleware fiber.Handler, authorize func(action string) fiber.Handler) {
+	audit := r.App.Group("/api/audit")
+	{
+		audit.Use(authMiddleware, authorize(constant.ActionAuditRead))
+		audit.Get("/", auditController.List)
+		audit.Get("/verify", auditController.Verify)
+	}
+}
+
+// RegisterAdminRoutes defines operational routes, each guarded by a policy action
+func (r *RouteConfig) RegisterAdminRoutes(schedulerController *controller.SchedulerController, authMiddleware fiber.Handler, authorize func(action string) fiber.Handler) {
+	admin := r.App.Group("/api/admin")
+	{
+		admin.Use(authMiddleware)
+		admin.Get("/scheduler", authorize(constant.ActionSchedulerRead), schedulerController.Status)
 	}
 }
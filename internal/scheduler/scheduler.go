@@ -0,0 +1,401 @@
+// Package scheduler runs periodic tasks on cron schedules across every app
+// instance. Instances elect a leader through a Redis lock and only the
+// leader starts runs. Each run is also claimed in Redis, so a tick runs once
+// even while leadership changes hands.
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go-starter-template/internal/constant"
+	"go-starter-template/internal/utils/cron"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// maxCatchUp bounds the missed ticks walked to find the latest one.
+const maxCatchUp = 100000
+
+// Options configure a Scheduler. Zero values fall back to the defaults
+// noted on each field.
+type Options struct {
+	// Prefix of the Redis keys, "scheduler:" by default
+	Prefix string
+	// Location the cron expressions are evaluated in, UTC by default
+	Location *time.Location
+	// LockTTL is how long leadership outlives a leader that stopped renewing
+	// it, 15s by default
+	LockTTL time.Duration
+	// Tolerance is how late a run may start before it counts as missed, 1m
+	// by default
+	Tolerance time.Duration
+	// Interval is how often instances campaign and check for due runs, 1s by
+	// default
+	Interval time.Duration
+}
+
+type task struct {
+	name     string
+	spec     string
+	schedule *cron.Schedule
+	missed   constant.MissedRunPolicy
+	run      func(ctx context.Context) error
+	running  atomic.Bool
+}
+
+// TaskStatus is what Status reports about a task. The Last fields are empty
+// until the task has run once.
+type TaskStatus struct {
+	Name            string
+	Schedule        string
+	MissedRunPolicy constant.MissedRunPolicy
+	// LastRunAt is the scheduled time of the last run, LastStartedAt when it
+	// actually started
+	LastRunAt      *time.Time
+	LastStartedAt  *time.Time
+	LastFinishedAt *time.Time
+	LastStatus     constant.ScheduledRunStatus
+	LastError      string
+	LastDuration   time.Duration
+	LastInstance   string
+	NextRunAt      time.Time
+}
+
+// Status is the scheduler state shared by all instances.
+type Status struct {
+	Instance string
+	Leader   string
+	Tasks    []TaskStatus
+}
+
+type Scheduler struct {
+	client   *redis.Client
+	log      *logrus.Logger
+	tracer   trace.Tracer
+	opts     Options
+	instance string
+	tasks    []*task
+	leader   bool
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+	runs     sync.WaitGroup
+}
+
+func New(client *redis.Client, log *logrus.Logger, opts Options) *Scheduler {
+	if opts.Prefix == "" {
+		opts.Prefix = "scheduler:"
+	}
+	if opts.Location == nil {
+		opts.Location = time.UTC
+	}
+	if opts.LockTTL <= 0 {
+		opts.LockTTL = 15 * time.Second
+	}
+	if opts.Tolerance <= 0 {
+		opts.Tolerance = time.Minute
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = time.Second
+	}
+	host, _ := os.Hostname()
+	instance := fmt.Sprintf("%s-%s", host, uuid.NewString()[:8])
+	return &Scheduler{client: client, log: log, tracer: otel.Tracer("Scheduler"), opts: opts, instance: instance}
+}
+
+func (s *Scheduler) leaderKey() string          { return s.opts.Prefix + "leader" }
+func (s *Scheduler) taskKey(name string) string { return s.opts.Prefix + "task:" + name }
+
+// Register adds a task running run on the cron expression spec. Register
+// before Start.
+func (s *Scheduler) Register(name, spec string, missed constant.MissedRunPolicy, run func(ctx context.Context) error) error {
+	schedule, err := cron.Parse(spec)
+	if err != nil {
+		return fmt.Errorf("task %s: %w", name, err)
+	}
+	if missed != constant.MissedRunSkip && missed != constant.MissedRunOnce {
+		return fmt.Errorf("task %s: unknown missed run policy %q", name, missed)
+	}
+	for _, t := range s.tasks {
+		if t.name == name {
+			return fmt.Errorf("task %s is already registered", name)
+		}
+	}
+	s.tasks = append(s.tasks, &task{name: name, spec: spec, schedule: schedule, missed: missed, run: run})
+	return nil
+}
+
+// Start campaigns for leadership and runs due tasks every Interval in the
+// background until Stop is called or ctx is cancelled.
+func (s *Scheduler) Start(ctx context.Context) {
+	if len(s.tasks) == 0 {
+		s.log.Info("scheduler has no tasks")
+		return
+	}
+
+	ctx, s.cancel = context.WithCancel(ctx)
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ticker := time.NewTicker(s.opts.Interval)
+		defer ticker.Stop()
+		s.tick(ctx, time.Now())
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				s.tick(ctx, now)
+			}
+		}
+	}()
+}
+
+// Stop cancels running tasks, waits for them to return and hands leadership
+// over instead of letting the lock expire.
+func (s *Scheduler) Stop() {
+	if s.cancel == nil {
+		return
+	}
+	s.cancel()
+	s.wg.Wait()
+	s.runs.Wait()
+
+	if err := resignScript.Run(context.Background(), s.client, []string{s.leaderKey()}, s.instance).Err(); err != nil {
+		s.log.WithError(err).Warn("scheduler failed to resign leadership")
+	}
+}
+
+// campaignScript takes or renews the leader lock for ARGV[1] and reports
+// whether it holds it.
+var campaignScript = redis.NewScript(`
+local owner = redis.call('GET', KEYS[1])
+if owner == false then
+    redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+    return 1
+end
+if owner == ARGV[1] then
+    redis.call('PEXPIRE', KEYS[1], ARGV[2])
+    return 1
+end
+return 0
+`)
+
+var resignScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+    return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// tick campaigns for leadership and, as leader, starts the runs due at now.
+func (s *Scheduler) tick(ctx context.Context, now time.Time) {
+	leader, err := campaignScript.Run(ctx, s.client, []string{s.leaderKey()}, s.instance, s.opts.LockTTL.Milliseconds()).Bool()
+	if err != nil {
+		if ctx.Err() == nil {
+			s.log.WithError(err).Error("scheduler failed to campaign for leadership")
+		}
+		return
+	}
+	if leader != s.leader {
+		s.leader = leader
+		s.log.WithFields(logrus.Fields{"instance": s.instance, "leader": leader}).Info("scheduler leadership changed")
+	}
+	if !leader {
+		return
+	}
+
+	now = now.In(s.opts.Location)
+	for _, t := range s.tasks {
+		if err := s.dispatch(ctx, t, now); err != nil && ctx.Err() == nil {
+			s.log.WithError(err).WithField("task", t.name).Error("scheduler failed to dispatch task")
+		}
+	}
+}
+
+// claimScript records run ARGV[1] (Unix ms) of a task as started, unless it
+// or a later one already was, and reports whether it did.
+var claimScript = redis.NewScript(`
+local last = tonumber(redis.call('HGET', KEYS[1], 'last_run_at') or '0')
+if last >= tonumber(ARGV[1]) then
+    return 0
+end
+redis.call('HSET', KEYS[1], 'last_run_at', ARGV[1], 'last_started_at', ARGV[2], 'last_status', ARGV[3], 'last_instance', ARGV[4])
+redis.call('HDEL', KEYS[1], 'last_finished_at', 'last_error', 'last_duration_ms')
+return 1
+`)
+
+// dispatch starts the latest run of t due by now, applying the task's missed
+// run policy if it is late or earlier runs were missed.
+func (s *Scheduler) dispatch(ctx context.Context, t *task, now time.Time) error {
+	if t.running.Load() {
+		return nil
+	}
+
+	key := s.taskKey(t.name)
+	lastRunAt, err := s.client.HGet(ctx, key, "last_run_at").Int64()
+	if errors.Is(err, redis.Nil) {
+		// A new task starts counting now instead of catching up on history
+		return s.client.HSetNX(ctx, key, "last_run_at", now.UnixMilli()).Err()
+	}
+	if err != nil {
+		return err
+	}
+
+	due := t.schedule.Next(time.UnixMilli(lastRunAt).In(s.opts.Location))
+	if due.IsZero() || due.After(now) {
+		return nil
+	}
+	runAt := due
+	for i := 0; i < maxCatchUp; i++ {
+		next := t.schedule.Next(runAt)
+		if next.IsZero() || next.After(now) {
+			break
+		}
+		runAt = next
+	}
+
+	late := now.Sub(runAt) > s.opts.Tolerance
+	status := constant.ScheduledRunRunning
+	if late && t.missed == constant.MissedRunSkip {
+		status = constant.ScheduledRunSkipped
+	}
+	claimed, err := claimScript.Run(ctx, s.client, []string{key}, runAt.UnixMilli(), now.UnixMilli(), string(status), s.instance).Bool()
+	if err != nil || !claimed {
+		return err
+	}
+
+	logger := s.log.WithContext(ctx).WithFields(logrus.Fields{"task": t.name, "run_at": runAt.Format(time.RFC3339)})
+	if status == constant.ScheduledRunSkipped {
+		logger.Warn("scheduled task missed its run, waiting for the next one")
+		return s.finish(ctx, t.name, status, fmt.Errorf("missed by %s", now.Sub(runAt).Round(time.Second)), 0)
+	}
+	if late || !runAt.Equal(due) {
+		logger.Info("scheduled task catching up on missed runs")
+	}
+
+	t.running.Store(true)
+	s.runs.Add(1)
+	go func() {
+		defer s.runs.Done()
+		defer t.running.Store(false)
+		s.run(ctx, t, runAt)
+	}()
+	return nil
+}
+
+// run runs t in a span and records the outcome.
+func (s *Scheduler) run(ctx context.Context, t *task, runAt time.Time) {
+	spanCtx, span := s.tracer.Start(ctx, "Scheduler.Run "+t.name, trace.WithAttributes(
+		attribute.String("task.name", t.name),
+		attribute.String("task.run_at", runAt.Format(time.RFC3339)),
+	))
+	defer span.End()
+
+	logger := s.log.WithContext(spanCtx).WithField("task", t.name)
+	started := time.Now()
+	err := safeRun(spanCtx, t.run)
+	duration := time.Since(started)
+
+	status := constant.ScheduledRunSucceeded
+	if err != nil {
+		status = constant.ScheduledRunFailed
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "scheduled task failed")
+		logger.WithError(err).Error("scheduled task failed")
+	} else {
+		logger.WithField("duration", duration.String()).Info("scheduled task completed")
+	}
+
+	// The outcome is recorded even if the scheduler is stopping
+	if err := s.finish(context.WithoutCancel(spanCtx), t.name, status, err, duration); err != nil {
+		logger.WithError(err).Error("failed to record scheduled task outcome")
+	}
+}
+
+func safeRun(ctx context.Context, run func(ctx context.Context) error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("task panicked: %v", r)
+		}
+	}()
+	return run(ctx)
+}
+
+func (s *Scheduler) finish(ctx context.Context, name string, status constant.ScheduledRunStatus, runErr error, duration time.Duration) error {
+	lastError := ""
+	if runErr != nil {
+		lastError = runErr.Error()
+	}
+	return s.client.HSet(ctx, s.taskKey(name),
+		"last_finished_at", time.Now().UnixMilli(),
+		"last_status", string(status),
+		"last_error", lastError,
+		"last_duration_ms", duration.Milliseconds(),
+	).Err()
+}
+
+// Status returns the current leader and the last and next run of every
+// registered task.
+func (s *Scheduler) Status(ctx context.Context) (*Status, error) {
+	leader, err := s.client.Get(ctx, s.leaderKey()).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return nil, err
+	}
+
+	pipe := s.client.Pipeline()
+	states := make([]*redis.MapStringStringCmd, len(s.tasks))
+	for i, t := range s.tasks {
+		states[i] = pipe.HGetAll(ctx, s.taskKey(t.name))
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().In(s.opts.Location)
+	status := &Status{Instance: s.instance, Leader: leader, Tasks: make([]TaskStatus, 0, len(s.tasks))}
+	for i, t := range s.tasks {
+		state := states[i].Val()
+		task := TaskStatus{Name: t.name, Schedule: t.spec, MissedRunPolicy: t.missed, NextRunAt: t.schedule.Next(now)}
+		// Before the first run last_run_at only marks when the task was
+		// first seen
+		if state["last_status"] != "" {
+			task.LastRunAt = parseMillis(state["last_run_at"], s.opts.Location)
+			task.LastStartedAt = parseMillis(state["last_started_at"], s.opts.Location)
+			task.LastFinishedAt = parseMillis(state["last_finished_at"], s.opts.Location)
+			task.LastStatus = constant.ScheduledRunStatus(state["last_status"])
+			task.LastError = state["last_error"]
+			task.LastInstance = state["last_instance"]
+			if ms, err := strconv.ParseInt(state["last_duration_ms"], 10, 64); err == nil {
+				task.LastDuration = time.Duration(ms) * time.Millisecond
+			}
+		}
+		if last := parseMillis(state["last_run_at"], s.opts.Location); last != nil {
+			// An overdue run shows up as a next run in the past
+			task.NextRunAt = t.schedule.Next(*last)
+		}
+		status.Tasks = append(status.Tasks, task)
+	}
+	return status, nil
+}
+
+func parseMillis(value string, loc *time.Location) *time.Time {
+	ms, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return nil
+	}
+	t := time.UnixMilli(ms).In(loc)
+	return &t
+}
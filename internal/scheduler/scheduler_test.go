@@ -0,0 +1,206 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	miniredis "github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+
+	"go-starter-template/internal/constant"
+)
+
+func newScheduler(t *testing.T, mr *miniredis.Miniredis) *Scheduler {
+	t.Helper()
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return New(redis.NewClient(&redis.Options{Addr: mr.Addr()}), logger, Options{})
+}
+
+// at is a Wednesday morning in UTC.
+func at(hour, minute int) time.Time {
+	return time.Date(2025, time.January, 15, hour, minute, 0, 0, time.UTC)
+}
+
+func lastRunAt(t *testing.T, mr *miniredis.Miniredis, name string) time.Time {
+	t.Helper()
+	ms, err := strconv.ParseInt(mr.HGet("scheduler:task:"+name, "last_run_at"), 10, 64)
+	require.NoError(t, err)
+	return time.UnixMilli(ms).UTC()
+}
+
+func TestScheduler_LeaderElection(t *testing.T) {
+	mr := miniredis.RunT(t)
+	first, second := newScheduler(t, mr), newScheduler(t, mr)
+	var runs atomic.Int32
+	for _, s := range []*Scheduler{first, second} {
+		require.NoError(t, s.Register("count", "* * * * *", constant.MissedRunSkip, func(context.Context) error {
+			runs.Add(1)
+			return nil
+		}))
+	}
+
+	first.tick(context.Background(), at(10, 0))
+	second.tick(context.Background(), at(10, 0))
+	require.True(t, first.leader)
+	require.False(t, second.leader)
+
+	// Only the leader runs the tick
+	first.tick(context.Background(), at(10, 1))
+	second.tick(context.Background(), at(10, 1))
+	first.runs.Wait()
+	require.Equal(t, int32(1), runs.Load())
+
+	// The leader's lock expires and the other instance takes over
+	mr.FastForward(16 * time.Second)
+	second.tick(context.Background(), at(10, 2))
+	first.tick(context.Background(), at(10, 2))
+	second.runs.Wait()
+	require.True(t, second.leader)
+	require.False(t, first.leader)
+	require.Equal(t, int32(2), runs.Load())
+}
+
+func TestScheduler_RunsEachTickOnce(t *testing.T) {
+	mr := miniredis.RunT(t)
+	first, second := newScheduler(t, mr), newScheduler(t, mr)
+	var runs atomic.Int32
+	run := func(context.Context) error {
+		runs.Add(1)
+		return nil
+	}
+	require.NoError(t, first.Register("count", "*/5 * * * *", constant.MissedRunSkip, run))
+	require.NoError(t, second.Register("count", "*/5 * * * *", constant.MissedRunSkip, run))
+
+	// A new task starts counting when it is first seen
+	require.NoError(t, first.dispatch(context.Background(), first.tasks[0], at(10, 2)))
+	require.Zero(t, runs.Load())
+
+	// Two instances that both believe they lead dispatch the same tick
+	require.NoError(t, first.dispatch(context.Background(), first.tasks[0], at(10, 5)))
+	require.NoError(t, second.dispatch(context.Background(), second.tasks[0], at(10, 5)))
+	first.runs.Wait()
+	second.runs.Wait()
+	require.Equal(t, int32(1), runs.Load())
+
+	// Nothing is due until the next tick
+	require.NoError(t, first.dispatch(context.Background(), first.tasks[0], at(10, 9)))
+	require.Equal(t, int32(1), runs.Load())
+}
+
+func TestScheduler_MissedRuns(t *testing.T) {
+	cases := []struct {
+		name         string
+		policy       constant.MissedRunPolicy
+		now          time.Time
+		expectRuns   int32
+		expectStatus constant.ScheduledRunStatus
+	}{
+		{
+			name:         "OnTimeAfterMissedTicks",
+			policy:       constant.MissedRunSkip,
+			now:          at(13, 0).Add(30 * time.Second),
+			expectRuns:   1,
+			expectStatus: constant.ScheduledRunSucceeded,
+		},
+		{
+			name:         "SkipLateRun",
+			policy:       constant.MissedRunSkip,
+			now:          at(13, 20),
+			expectStatus: constant.ScheduledRunSkipped,
+		},
+		{
+			name:         "RunOnceForAllMissed",
+			policy:       constant.MissedRunOnce,
+			now:          at(13, 20),
+			expectRuns:   1,
+			expectStatus: constant.ScheduledRunSucceeded,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mr := miniredis.RunT(t)
+			s := newScheduler(t, mr)
+			var runs atomic.Int32
+			require.NoError(t, s.Register("hourly", "@hourly", tc.policy, func(context.Context) error {
+				runs.Add(1)
+				return nil
+			}))
+			// The last run was at 10:00, so 11:00 and 12:00 were missed
+			mr.HSet("scheduler:task:hourly", "last_run_at", strconv.FormatInt(at(10, 0).UnixMilli(), 10), "last_status", "succeeded")
+
+			s.tick(context.Background(), tc.now)
+			s.runs.Wait()
+			require.Equal(t, tc.expectRuns, runs.Load())
+			require.Equal(t, at(13, 0), lastRunAt(t, mr, "hourly"))
+
+			status, err := s.Status(context.Background())
+			require.NoError(t, err)
+			require.Equal(t, tc.expectStatus, status.Tasks[0].LastStatus)
+			require.Equal(t, s.instance, status.Leader)
+		})
+	}
+}
+
+func TestScheduler_Status(t *testing.T) {
+	mr := miniredis.RunT(t)
+	s := newScheduler(t, mr)
+	require.NoError(t, s.Register("fails", "0 3 * * *", constant.MissedRunOnce, func(context.Context) error {
+		return errors.New("disk full")
+	}))
+	require.NoError(t, s.Register("panics", "0 3 * * *", constant.MissedRunOnce, func(context.Context) error {
+		panic("boom")
+	}))
+
+	status, err := s.Status(context.Background())
+	require.NoError(t, err)
+	require.Empty(t, status.Leader)
+	require.Nil(t, status.Tasks[0].LastRunAt)
+	require.False(t, status.Tasks[0].NextRunAt.IsZero())
+
+	s.tick(context.Background(), at(2, 0))
+	s.tick(context.Background(), at(3, 0))
+	s.runs.Wait()
+
+	status, err = s.Status(context.Background())
+	require.NoError(t, err)
+	for i, expectErr := range []string{"disk full", "task panicked: boom"} {
+		task := status.Tasks[i]
+		require.Equal(t, constant.ScheduledRunFailed, task.LastStatus)
+		require.Equal(t, expectErr, task.LastError)
+		require.Equal(t, at(3, 0), task.LastRunAt.UTC())
+		require.Equal(t, s.instance, task.LastInstance)
+		require.NotNil(t, task.LastFinishedAt)
+		require.Equal(t, time.Date(2025, time.January, 16, 3, 0, 0, 0, time.UTC), task.NextRunAt.UTC())
+	}
+}
+
+func TestScheduler_Register(t *testing.T) {
+	s := newScheduler(t, miniredis.RunT(t))
+	noop := func(context.Context) error { return nil }
+
+	require.NoError(t, s.Register("purge", "@daily", constant.MissedRunOnce, noop))
+	require.Error(t, s.Register("purge", "@daily", constant.MissedRunOnce, noop))
+	require.Error(t, s.Register("bad", "61 * * * *", constant.MissedRunOnce, noop))
+	require.Error(t, s.Register("policy", "@daily", "sometimes", noop))
+}
+
+func TestScheduler_StartStop(t *testing.T) {
+	mr := miniredis.RunT(t)
+	s := newScheduler(t, mr)
+	require.NoError(t, s.Register("every", "@every 1s", constant.MissedRunSkip, func(context.Context) error { return nil }))
+
+	s.Start(context.Background())
+	require.Eventually(t, func() bool { return mr.Exists("scheduler:leader") }, time.Second, 10*time.Millisecond)
+	s.Stop()
+	// Leadership is handed over on shutdown
+	require.False(t, mr.Exists("scheduler:leader"))
+}
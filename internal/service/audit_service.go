@@ -0,0 +1,148 @@
+package service
+
+import (
+	"context"
+	"go-starter-template/internal/audit"
+	"go-starter-template/internal/dto"
+	"go-starter-template/internal/dto/converter"
+	"go-starter-template/internal/model"
+	"go-starter-template/internal/repository"
+	"go-starter-template/internal/utils/errcode"
+	"strconv"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// AuditRecord describes an action for the audit log. The actor defaults to
+// the authenticated user of the request in ctx; the client address, user
+// agent and trace ID always come from ctx.
+type AuditRecord struct {
+	Action     string
+	ActorUUID  string
+	TargetType string
+	TargetID   string
+	// Before and After are the target's state around the action, nil when
+	// it did not exist; only the fields that differ are stored
+	Before   any
+	After    any
+	Metadata map[string]any
+}
+
+// AuditService writes and queries the audit log.
+type AuditService struct {
+	auditRepository *repository.AuditRepository
+	log             *logrus.Logger
+	tracer          trace.Tracer
+	uow             *repository.UnitOfWork
+}
+
+func NewAuditService(auditRepository *repository.AuditRepository, log *logrus.Logger, uow *repository.UnitOfWork) *AuditService {
+	return &AuditService{auditRepository: auditRepository, log: log, tracer: otel.Tracer("AuditService"), uow: uow}
+}
+
+// Record appends record to the audit log in the caller's transaction, so it
+// is stored if and only if the action is, or in a transaction of its own.
+func (s *AuditService) Record(ctx context.Context, record AuditRecord) error {
+	spanCtx, span := s.tracer.Start(ctx, "AuditService.Record")
+	defer span.End()
+
+	logger := s.log.WithContext(spanCtx)
+	request := audit.FromContext(spanCtx)
+	e := &model.AuditEvent{
+		ActorUUID:  record.ActorUUID,
+		Action:     record.Action,
+		TargetType: record.TargetType,
+		TargetID:   record.TargetID,
+		IP:         request.IP,
+		UserAgent:  request.UserAgent,
+	}
+	if e.ActorUUID == "" {
+		e.ActorUUID = request.ActorUUID
+	}
+	if sc := trace.SpanContextFromContext(spanCtx); sc.HasTraceID() {
+		e.TraceID = sc.TraceID().String()
+	}
+
+	changes, err := audit.Diff(record.Before, record.After)
+	if err == nil {
+		e.Changes, err = json.Marshal(changes)
+	}
+	if err == nil && record.Metadata != nil {
+		e.Metadata, err = json.Marshal(record.Metadata)
+	}
+	if err != nil {
+		logger.WithError(err).Error("Failed to encode audit event")
+		return errcode.ErrInternalServerError
+	}
+
+	if err := s.uow.Join(spanCtx, func(txCtx context.Context) error {
+		return s.auditRepository.Append(txCtx, e)
+	}); err != nil {
+		logger.WithError(err).WithField("action", record.Action).Error("Failed to record audit event")
+		return errcode.ErrDatabaseError
+	}
+	return nil
+}
+
+// List returns the audit events matching request, newest first.
+func (s *AuditService) List(ctx context.Context, request *dto.ListAuditEventsRequest) ([]*dto.AuditEventResponse, *dto.PageMetadata, error) {
+	spanCtx, span := s.tracer.Start(ctx, "AuditService.List")
+	defer span.End()
+
+	filter := repository.AuditFilter{
+		ActorUUID:  request.Actor,
+		TargetType: request.TargetType,
+		TargetID:   request.Target,
+		Action:     request.Action,
+		Limit:      request.Limit,
+	}
+	if request.From != 0 {
+		filter.From = time.Unix(request.From, 0)
+	}
+	if request.To != 0 {
+		filter.To = time.Unix(request.To, 0)
+	}
+	if request.Cursor != "" {
+		id, err := strconv.ParseInt(request.Cursor, 10, 64)
+		if err != nil || id <= 0 {
+			return nil, nil, errcode.ErrInvalidCursor
+		}
+		filter.BeforeID = id
+	}
+
+	events, err := s.auditRepository.Search(spanCtx, filter)
+	if err != nil {
+		s.log.WithContext(spanCtx).WithError(err).Error("Failed to search audit events")
+		return nil, nil, errcode.ErrDatabaseError
+	}
+
+	responses := make([]*dto.AuditEventResponse, len(events))
+	for i, e := range events {
+		responses[i] = converter.AuditEventToResponse(e)
+	}
+	paging := &dto.PageMetadata{Size: len(responses)}
+	if len(events) == request.Limit {
+		paging.Next = strconv.FormatInt(events[len(events)-1].ID, 10)
+	}
+	return responses, paging, nil
+}
+
+// Verify checks the hash chain of the whole audit log.
+func (s *AuditService) Verify(ctx context.Context) (*dto.AuditVerificationResponse, error) {
+	spanCtx, span := s.tracer.Start(ctx, "AuditService.Verify")
+	defer span.End()
+
+	result, err := s.auditRepository.Verify(spanCtx)
+	if err != nil {
+		s.log.WithContext(spanCtx).WithError(err).Error("Failed to verify audit log")
+		return nil, errcode.ErrDatabaseError
+	}
+	if result.BrokenAt != 0 {
+		s.log.WithContext(spanCtx).WithField("id", result.BrokenAt).Error("Audit log hash chain is broken")
+	}
+	return &dto.AuditVerificationResponse{Valid: result.BrokenAt == 0, Checked: result.Checked, BrokenAt: result.BrokenAt}, nil
+}
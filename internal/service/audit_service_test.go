@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+
+	"go-starter-template/internal/audit"
+	"go-starter-template/internal/dto"
+	"go-starter-template/internal/repository"
+	"go-starter-template/internal/utils/errcode"
+)
+
+func setupAuditService(t *testing.T) (*AuditService, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+	return NewAuditService(repository.NewAuditRepository(db), testLogger(), repository.NewUnitOfWork(db)), mock
+}
+
+func TestAuditService_Record(t *testing.T) {
+	t.Run("TakesRequestFromContext", func(t *testing.T) {
+		svc, mock := setupAuditService(t)
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta("SELECT pg_advisory_xact_lock($1)")).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT hash FROM audit_events")).WillReturnRows(sqlmock.NewRows([]string{"hash"}))
+		mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO audit_events")).
+			WithArgs(sqlmock.AnyArg(), "admin-1", "user.updated", audit.TargetUser, "u1", "10.0.0.1", "curl/8", "",
+				[]byte(`{"name":{"before":"Alice","after":"Alicia"}}`), []byte(`{"source":"test"}`),
+				sqlmock.AnyArg(), audit.GenesisHash, sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectCommit()
+
+		ctx := audit.WithActor(audit.WithRequest(context.Background(), "10.0.0.1", "curl/8"), "admin-1")
+		err := svc.Record(ctx, AuditRecord{
+			Action:     "user.updated",
+			TargetType: audit.TargetUser,
+			TargetID:   "u1",
+			Before:     map[string]string{"name": "Alice", "email": "a@example.com"},
+			After:      map[string]string{"name": "Alicia", "email": "a@example.com"},
+			Metadata:   map[string]any{"source": "test"},
+		})
+		require.NoError(t, err)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("DatabaseError", func(t *testing.T) {
+		svc, mock := setupAuditService(t)
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta("SELECT pg_advisory_xact_lock($1)")).WillReturnError(sqlmock.ErrCancelled)
+		mock.ExpectRollback()
+
+		err := svc.Record(context.Background(), AuditRecord{Action: audit.ActionLogin})
+		require.ErrorIs(t, err, errcode.ErrDatabaseError)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestAuditService_List(t *testing.T) {
+	columns := []string{"id", "uuid", "actor_uuid", "action", "target_type", "target_id", "ip", "user_agent", "trace_id", "changes", "metadata", "created_at", "prev_hash", "hash"}
+	now := time.Now()
+
+	t.Run("NextCursorOnFullPage", func(t *testing.T) {
+		svc, mock := setupAuditService(t)
+		mock.ExpectQuery(regexp.QuoteMeta("FROM audit_events WHERE action = $1 AND id < $2 ORDER BY id DESC LIMIT $3")).
+			WithArgs(audit.ActionLogin, int64(9), 2).
+			WillReturnRows(sqlmock.NewRows(columns).
+				AddRow(8, "e8", "u1", audit.ActionLogin, audit.TargetUser, "u1", "", "", "", []byte(`{}`), []byte(`{}`), now, "", "").
+				AddRow(5, "e5", "u2", audit.ActionLogin, audit.TargetUser, "u2", "", "", "", []byte(`{}`), []byte(`{}`), now, "", ""))
+
+		events, paging, err := svc.List(context.Background(), &dto.ListAuditEventsRequest{Action: audit.ActionLogin, Cursor: "9", Limit: 2})
+		require.NoError(t, err)
+		require.Len(t, events, 2)
+		require.Equal(t, "e8", events[0].UUID)
+		require.Equal(t, "5", paging.Next)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("InvalidCursor", func(t *testing.T) {
+		svc, _ := setupAuditService(t)
+		_, _, err := svc.List(context.Background(), &dto.ListAuditEventsRequest{Cursor: "abc", Limit: 2})
+		require.ErrorIs(t, err, errcode.ErrInvalidCursor)
+	})
+}
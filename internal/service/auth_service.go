@@ -2,8 +2,11 @@ package service
 
 import (
 	"context"
+	"errors"
+	"go-starter-template/internal/audit"
 	"go-starter-template/internal/constant"
 	"go-starter-template/internal/dto"
+	"go-starter-template/internal/event"
 	"go-starter-template/internal/model"
 	"go-starter-template/internal/repository"
 	"go-starter-template/internal/utils/errcode"
@@ -20,6 +23,9 @@ import (
 type AuthService struct {
     jwtService       *JwtService
     userRepository   *repository.UserRepository
+    outboxRepository *repository.OutboxRepository
+    auditService     *AuditService
+    loginHistory     *LoginHistoryService
     logger           *logrus.Logger
     blacklistService *BlacklistService
     tracer           trace.Tracer
@@ -27,8 +33,8 @@ type AuthService struct {
     hashPassword     func(password []byte, cost int) ([]byte, error)
 }
 
-func NewAuthService(jwtService *JwtService, userRepo *repository.UserRepository, blacklistService *BlacklistService, logger *logrus.Logger, uow *repository.UnitOfWork) *AuthService {
-    return &AuthService{jwtService: jwtService, userRepository: userRepo, logger: logger, blacklistService: blacklistService, tracer: otel.Tracer("AuthService"), uow: uow, hashPassword: bcrypt.GenerateFromPassword}
+func NewAuthService(jwtService *JwtService, userRepo *repository.UserRepository, outboxRepo *repository.OutboxRepository, auditService *AuditService, loginHistory *LoginHistoryService, blacklistService *BlacklistService, logger *logrus.Logger, uow *repository.UnitOfWork) *AuthService {
+    return &AuthService{jwtService: jwtService, userRepository: userRepo, logger: logger, blacklistService: blacklistService, tracer: otel.Tracer("AuthService"), uow: uow, outboxRepository: outboxRepo, auditService: auditService, loginHistory: loginHistory, hashPassword: bcrypt.GenerateFromPassword}
 }
 
 // Login authenticates a user and returns JWT tokens.
@@ -49,10 +55,17 @@ func (s *AuthService) Login(ctx context.Context, req *dto.LoginRequest) (accessT
 	if err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
 		passwordSpan.End()
 		logger.WithError(err).Error("Invalid password attempt")
+		s.recordLoginFailure(spanCtx, user, "invalid_password")
 		return "", "", errcode.ErrInvalidEmailOrPassword
 	}
 	passwordSpan.End()
 
+	if err = checkUserStatus(user, time.Now()); err != nil {
+		logger.WithError(err).Warn("Login attempt by inactive user")
+		s.recordLoginFailure(spanCtx, user, loginFailureReason(err))
+		return "", "", err
+	}
+
 	// Generate JWT tokens
 	if accessToken, err = s.jwtService.GenerateAccessToken(spanCtx, user.UUID); err != nil {
 		logger.WithError(err).Error("Error generating access token")
@@ -64,9 +77,48 @@ func (s *AuthService) Login(ctx context.Context, req *dto.LoginRequest) (accessT
 		return "", "", errcode.ErrRefreshTokenGeneration
 	}
 
+	// Tokens are only handed out once the login is on record
+	if err = s.uow.Do(spanCtx, func(txCtx context.Context) error {
+		if err := s.loginHistory.Record(txCtx, user, ""); err != nil {
+			return err
+		}
+		return s.auditService.Record(txCtx, AuditRecord{Action: audit.ActionLogin, ActorUUID: user.UUID, TargetType: audit.TargetUser, TargetID: user.UUID})
+	}); err != nil {
+		return "", "", err
+	}
+
 	return accessToken, refreshToken, nil
 }
 
+// recordLoginFailure records a failed login to an existing account in the
+// user's login history and the audit log. The request is unauthenticated, so
+// it has no actor. Failing to record it does not change the outcome, which
+// is already an error.
+func (s *AuthService) recordLoginFailure(ctx context.Context, user *model.User, reason string) {
+	_ = s.uow.Do(ctx, func(txCtx context.Context) error {
+		if err := s.loginHistory.Record(txCtx, user, reason); err != nil {
+			return err
+		}
+		return s.auditService.Record(txCtx, AuditRecord{
+			Action:     audit.ActionLoginFailed,
+			TargetType: audit.TargetUser,
+			TargetID:   user.UUID,
+			Metadata:   map[string]any{"reason": reason},
+		})
+	})
+}
+
+// loginFailureReason names the status error that rejected a login.
+func loginFailureReason(err error) string {
+	switch {
+	case errors.Is(err, errcode.ErrUserSuspended):
+		return "suspended"
+	case errors.Is(err, errcode.ErrUserPending):
+		return "pending"
+	}
+	return err.Error()
+}
+
 // Register creates a new user with a hashed password.
 func (s *AuthService) Register(ctx context.Context, req *dto.RegisterRequest) (*dto.UserResponse, error) {
 	spanCtx, span := s.tracer.Start(ctx, "AuthService.Register")
@@ -105,6 +157,8 @@ func (s *AuthService) Register(ctx context.Context, req *dto.RegisterRequest) (*
 			Email:     req.Email,
 			Password:  string(hashedPassword),
 			Name:      req.Name,
+			Status:    string(constant.UserStatusActive),
+			Version:   1,
 			CreatedAt: time.Now(),
 			UpdatedAt: time.Now(),
 		}
@@ -113,6 +167,19 @@ func (s *AuthService) Register(ctx context.Context, req *dto.RegisterRequest) (*
 			logger.WithError(err).Error("Error creating user")
 			return errcode.ErrUserCreationFailed
 		}
+		if err := s.outboxRepository.Append(txCtx, event.UserRegistered{UserEvent: event.ForUser(&user)}); err != nil {
+			logger.WithError(err).Error("Error recording registration event")
+			return errcode.ErrUserCreationFailed
+		}
+		if err := s.auditService.Record(txCtx, AuditRecord{
+			Action:     event.TypeUserRegistered,
+			ActorUUID:  user.UUID,
+			TargetType: audit.TargetUser,
+			TargetID:   user.UUID,
+			After:      auditSnapshot(&user),
+		}); err != nil {
+			return errcode.ErrUserCreationFailed
+		}
 
 		// Populate response timestamps from user if set by DB triggers or defaults
 		return nil
@@ -124,6 +191,7 @@ func (s *AuthService) Register(ctx context.Context, req *dto.RegisterRequest) (*
 		UUID:      user.UUID,
 		Name:      user.Name,
 		Email:     user.Email,
+		Status:    user.Status,
 		CreatedAt: user.CreatedAt.Unix(),
 		UpdatedAt: user.UpdatedAt.Unix(),
 	}, nil
@@ -147,6 +215,11 @@ func (s *AuthService) RefreshToken(ctx context.Context, refreshToken string) (ac
 		return "", "", errcode.ErrInvalidToken
 	}
 
+	if err = s.CheckUserStatus(spanCtx, claims); err != nil {
+		logger.WithError(err).Warn("Refresh attempt by inactive user")
+		return "", "", err
+	}
+
 	eg, egCtx := errgroup.WithContext(spanCtx)
 	eg.Go(func() error {
 		if accessToken, err = s.jwtService.GenerateAccessToken(egCtx, claims.UUID); err != nil {
@@ -166,6 +239,14 @@ func (s *AuthService) RefreshToken(ctx context.Context, refreshToken string) (ac
 		return "", "", err
 	}
 
+	// Redis is only called once the audit transaction has committed, so the
+	// audit chain lock is not held across it; the new tokens are only handed
+	// out once the old one is revoked
+	if err := s.uow.Do(spanCtx, func(txCtx context.Context) error {
+		return s.auditService.Record(txCtx, AuditRecord{Action: audit.ActionTokenRefreshed, ActorUUID: claims.UUID, TargetType: audit.TargetUser, TargetID: claims.UUID})
+	}); err != nil {
+		return "", "", err
+	}
 	if err := s.blacklistService.Add(spanCtx, refreshToken, constant.TokenTypeRefresh); err != nil {
 		logger.WithError(err).Error("Failed to blacklist old refresh token")
 		return "", "", err
@@ -174,6 +255,53 @@ func (s *AuthService) RefreshToken(ctx context.Context, refreshToken string) (ac
 	return accessToken, newRefreshToken, nil
 }
 
+// CheckUserStatus returns an error when the user of claims no longer exists,
+// is not allowed to authenticate (suspended or pending), or had their tokens
+// revoked after claims were issued.
+func (s *AuthService) CheckUserStatus(ctx context.Context, claims *Claims) error {
+	spanCtx, span := s.tracer.Start(ctx, "AuthService.CheckUserStatus")
+	defer span.End()
+
+	user := new(model.User)
+	if err := s.userRepository.FindStatusByUUID(spanCtx, user, claims.UUID); err != nil {
+		s.logger.WithContext(spanCtx).WithError(err).Warn("User status not found")
+		return errcode.ErrUnauthorized
+	}
+	if tokenRevoked(claims, user.TokensValidAfter) {
+		s.logger.WithContext(spanCtx).Warn("Token was issued before the user's tokens were revoked")
+		return errcode.ErrUnauthorized
+	}
+	return checkUserStatus(user, time.Now())
+}
+
+// tokenRevoked reports whether claims were issued before validAfter. iat only
+// has second precision, so a token issued within the second of the
+// revocation counts as revoked too.
+func tokenRevoked(claims *Claims, validAfter *time.Time) bool {
+	if validAfter == nil {
+		return false
+	}
+	if claims.IssuedAt == nil {
+		return true
+	}
+	return !claims.IssuedAt.Time.After(validAfter.Truncate(time.Second))
+}
+
+// checkUserStatus reports whether the user may authenticate at now. A
+// suspension with an end date lifts itself once that date has passed.
+func checkUserStatus(user *model.User, now time.Time) error {
+	switch constant.UserStatus(user.Status) {
+	case constant.UserStatusSuspended:
+		if user.SuspendedUntil != nil && !now.Before(*user.SuspendedUntil) {
+			return nil
+		}
+		return errcode.ErrUserSuspended
+	case constant.UserStatusPending:
+		return errcode.ErrUserPending
+	}
+	return nil
+}
+
 // Logout invalidates access and refresh tokens.
 func (s *AuthService) Logout(ctx context.Context, accessToken, refreshToken string) error {
 	spanCtx, span := s.tracer.Start(ctx, "AuthService.Logout")
@@ -181,18 +309,44 @@ func (s *AuthService) Logout(ctx context.Context, accessToken, refreshToken stri
 
 	logger := s.logger.WithContext(spanCtx)
 
-	eg, egCtx := errgroup.WithContext(spanCtx)
-	eg.Go(func() error {
-		return s.blacklistService.Add(egCtx, accessToken, constant.TokenTypeAccess)
-	})
-	eg.Go(func() error {
-		return s.blacklistService.Add(egCtx, refreshToken, constant.TokenTypeRefresh)
-	})
+	revoke := func(ctx context.Context) error {
+		eg, egCtx := errgroup.WithContext(ctx)
+		eg.Go(func() error {
+			return s.blacklistService.Add(egCtx, accessToken, constant.TokenTypeAccess)
+		})
+		eg.Go(func() error {
+			return s.blacklistService.Add(egCtx, refreshToken, constant.TokenTypeRefresh)
+		})
+		if err := eg.Wait(); err != nil {
+			logger.WithError(err).Error("Failed to invalidate tokens")
+			return err
+		}
+		return nil
+	}
 
-	if err := eg.Wait(); err != nil {
-		logger.WithError(err).Error("Failed to invalidate tokens")
+	// Tokens that are expired or forged name nobody worth auditing
+	actor := s.tokenOwner(spanCtx, accessToken, refreshToken)
+	if actor == "" {
+		return revoke(spanCtx)
+	}
+	// The tokens are revoked after the audit transaction commits, so the
+	// audit chain lock is not held across the Redis calls
+	if err := s.uow.Do(spanCtx, func(txCtx context.Context) error {
+		return s.auditService.Record(txCtx, AuditRecord{Action: audit.ActionLogout, ActorUUID: actor, TargetType: audit.TargetUser, TargetID: actor})
+	}); err != nil {
 		return err
 	}
+	return revoke(spanCtx)
+}
 
-	return nil
+// tokenOwner returns the user a valid refresh or access token was issued
+// to, or "" when neither is valid.
+func (s *AuthService) tokenOwner(ctx context.Context, accessToken, refreshToken string) string {
+	if claims, err := s.jwtService.ValidateRefreshToken(ctx, refreshToken); err == nil {
+		return claims.UUID
+	}
+	if claims, err := s.jwtService.ValidateAccessToken(ctx, accessToken); err == nil {
+		return claims.UUID
+	}
+	return ""
 }
@@ -40,13 +40,38 @@ func testLogger() *logrus.Logger {
 }
 
 // helper: setup sqlmock-backed UserRepository and UnitOfWork
-func setupRepoAndUow(t *testing.T) (*repository.UserRepository, *repository.UnitOfWork, sqlmock.Sqlmock, func()) {
+func setupRepoAndUow(t *testing.T) (*repository.UserRepository, *repository.OutboxRepository, *AuditService, *LoginHistoryService, *repository.UnitOfWork, sqlmock.Sqlmock, func()) {
 	db, mock, err := sqlmock.New()
 	require.NoError(t, err)
 	repo := repository.NewUserRepository(db)
 	uow := repository.NewUnitOfWork(db)
 	cleanup := func() { _ = db.Close() }
-	return repo, uow, mock, cleanup
+	loginHistory := NewLoginHistoryService(repository.NewLoginEventRepository(db), nil, testLogger(), uow)
+	return repo, repository.NewOutboxRepository(db), NewAuditService(repository.NewAuditRepository(db), testLogger(), uow), loginHistory, uow, mock, cleanup
+}
+
+// expectLoginTx expects a login attempt of u1 recorded in the login history
+// and the audit log in a transaction of its own; an empty reason means it
+// succeeded.
+func expectLoginTx(mock sqlmock.Sqlmock, reason, action string) {
+	mock.ExpectBegin()
+	if reason == "" {
+		mock.ExpectQuery(regexp.QuoteMeta("FROM login_events WHERE user_uuid = $1 AND success")).
+			WithArgs("u1", "", "").
+			WillReturnRows(sqlmock.NewRows([]string{"any", "device", "country"}).AddRow(true, true, false))
+	}
+	mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO login_events")).
+		WithArgs(sqlmock.AnyArg(), "u1", reason == "", reason, "", "", "", "", "", "", false, false, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	expectAudit(mock, action)
+	mock.ExpectCommit()
+}
+
+// expectAuditTx expects an audit event recorded in a transaction of its own.
+func expectAuditTx(mock sqlmock.Sqlmock, action string) {
+	mock.ExpectBegin()
+	expectAudit(mock, action)
+	mock.ExpectCommit()
 }
 
 // fake blacklist repository implementing interface
@@ -71,6 +96,10 @@ func (f *fakeBLRepo) IsBlacklisted(token string, tokenType constant.TokenType) (
 	return false, nil
 }
 
+func (f *fakeBLRepo) Prune(context.Context, time.Duration) (int64, error) {
+	return 0, nil
+}
+
 // signing method that always fails when signing (used to force refresh token generation errors)
 type failingSignMethod struct{}
 
@@ -100,10 +129,11 @@ func TestAuthService_Login(t *testing.T) {
 			name: "Success",
 			req:  &dto.LoginRequest{Email: "user@example.com", Password: "pass"},
 			setupDB: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery(regexp.QuoteMeta(`SELECT uuid, name, email, password, created_at, updated_at FROM users WHERE email = $1 LIMIT 1`)).
+				mock.ExpectQuery(regexp.QuoteMeta(`SELECT uuid, name, email, password, created_at, updated_at, status, status_reason, suspended_until, phone, version FROM users WHERE lower(email) = lower($1) AND deleted_at IS NULL LIMIT 1`)).
 					WithArgs("user@example.com").
-					WillReturnRows(sqlmock.NewRows([]string{"uuid", "name", "email", "password", "created_at", "updated_at"}).
-						AddRow("u1", "Name", "user@example.com", string(hashed), time.Now(), time.Now()))
+					WillReturnRows(sqlmock.NewRows([]string{"uuid", "name", "email", "password", "created_at", "updated_at", "status", "status_reason", "suspended_until", "phone", "version"}).
+						AddRow("u1", "Name", "user@example.com", string(hashed), time.Now(), time.Now(), "active", "", nil, nil, 1))
+				expectLoginTx(mock, "", "auth.login")
 			},
 			assert: func(t *testing.T, access, refresh string, err error) {
 				require.NoError(t, err)
@@ -115,7 +145,7 @@ func TestAuthService_Login(t *testing.T) {
 			name: "UserNotFound",
 			req:  &dto.LoginRequest{Email: "missing@example.com", Password: "pass"},
 			setupDB: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery(regexp.QuoteMeta(`SELECT uuid, name, email, password, created_at, updated_at FROM users WHERE email = $1 LIMIT 1`)).
+				mock.ExpectQuery(regexp.QuoteMeta(`SELECT uuid, name, email, password, created_at, updated_at, status, status_reason, suspended_until, phone, version FROM users WHERE lower(email) = lower($1) AND deleted_at IS NULL LIMIT 1`)).
 					WithArgs("missing@example.com").
 					WillReturnError(errors.New("no rows"))
 			},
@@ -127,23 +157,70 @@ func TestAuthService_Login(t *testing.T) {
 			name: "InvalidPassword",
 			req:  &dto.LoginRequest{Email: "user@example.com", Password: "wrong"},
 			setupDB: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery(regexp.QuoteMeta(`SELECT uuid, name, email, password, created_at, updated_at FROM users WHERE email = $1 LIMIT 1`)).
+				mock.ExpectQuery(regexp.QuoteMeta(`SELECT uuid, name, email, password, created_at, updated_at, status, status_reason, suspended_until, phone, version FROM users WHERE lower(email) = lower($1) AND deleted_at IS NULL LIMIT 1`)).
 					WithArgs("user@example.com").
-					WillReturnRows(sqlmock.NewRows([]string{"uuid", "name", "email", "password", "created_at", "updated_at"}).
-						AddRow("u1", "Name", "user@example.com", string(hashed), time.Now(), time.Now()))
+					WillReturnRows(sqlmock.NewRows([]string{"uuid", "name", "email", "password", "created_at", "updated_at", "status", "status_reason", "suspended_until", "phone", "version"}).
+						AddRow("u1", "Name", "user@example.com", string(hashed), time.Now(), time.Now(), "active", "", nil, nil, 1))
+				expectLoginTx(mock, "invalid_password", "auth.login_failed")
 			},
 			assert: func(t *testing.T, _, _ string, err error) {
 				require.ErrorIs(t, err, errcode.ErrInvalidEmailOrPassword)
 			},
 		},
+		{
+			name: "Suspended",
+			req:  &dto.LoginRequest{Email: "user@example.com", Password: "pass"},
+			setupDB: func(mock sqlmock.Sqlmock) {
+				until := time.Now().Add(time.Hour)
+				mock.ExpectQuery(regexp.QuoteMeta(`SELECT uuid, name, email, password, created_at, updated_at, status, status_reason, suspended_until, phone, version FROM users WHERE lower(email) = lower($1) AND deleted_at IS NULL LIMIT 1`)).
+					WithArgs("user@example.com").
+					WillReturnRows(sqlmock.NewRows([]string{"uuid", "name", "email", "password", "created_at", "updated_at", "status", "status_reason", "suspended_until", "phone", "version"}).
+						AddRow("u1", "Name", "user@example.com", string(hashed), time.Now(), time.Now(), "suspended", "abuse", until, nil, 1))
+				expectLoginTx(mock, "suspended", "auth.login_failed")
+			},
+			assert: func(t *testing.T, access, _ string, err error) {
+				require.ErrorIs(t, err, errcode.ErrUserSuspended)
+				require.Empty(t, access)
+			},
+		},
+		{
+			name: "SuspensionExpired",
+			req:  &dto.LoginRequest{Email: "user@example.com", Password: "pass"},
+			setupDB: func(mock sqlmock.Sqlmock) {
+				until := time.Now().Add(-time.Hour)
+				mock.ExpectQuery(regexp.QuoteMeta(`SELECT uuid, name, email, password, created_at, updated_at, status, status_reason, suspended_until, phone, version FROM users WHERE lower(email) = lower($1) AND deleted_at IS NULL LIMIT 1`)).
+					WithArgs("user@example.com").
+					WillReturnRows(sqlmock.NewRows([]string{"uuid", "name", "email", "password", "created_at", "updated_at", "status", "status_reason", "suspended_until", "phone", "version"}).
+						AddRow("u1", "Name", "user@example.com", string(hashed), time.Now(), time.Now(), "suspended", "abuse", until, nil, 1))
+				expectLoginTx(mock, "", "auth.login")
+			},
+			assert: func(t *testing.T, access, _ string, err error) {
+				require.NoError(t, err)
+				require.NotEmpty(t, access)
+			},
+		},
+		{
+			name: "Pending",
+			req:  &dto.LoginRequest{Email: "user@example.com", Password: "pass"},
+			setupDB: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(`SELECT uuid, name, email, password, created_at, updated_at, status, status_reason, suspended_until, phone, version FROM users WHERE lower(email) = lower($1) AND deleted_at IS NULL LIMIT 1`)).
+					WithArgs("user@example.com").
+					WillReturnRows(sqlmock.NewRows([]string{"uuid", "name", "email", "password", "created_at", "updated_at", "status", "status_reason", "suspended_until", "phone", "version"}).
+						AddRow("u1", "Name", "user@example.com", string(hashed), time.Now(), time.Now(), "pending", "", nil, nil, 1))
+				expectLoginTx(mock, "pending", "auth.login_failed")
+			},
+			assert: func(t *testing.T, _, _ string, err error) {
+				require.ErrorIs(t, err, errcode.ErrUserPending)
+			},
+		},
 		{
 			name: "AccessTokenSignMethodError",
 			req:  &dto.LoginRequest{Email: "user@example.com", Password: "pass"},
 			setupDB: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery(regexp.QuoteMeta(`SELECT uuid, name, email, password, created_at, updated_at FROM users WHERE email = $1 LIMIT 1`)).
+				mock.ExpectQuery(regexp.QuoteMeta(`SELECT uuid, name, email, password, created_at, updated_at, status, status_reason, suspended_until, phone, version FROM users WHERE lower(email) = lower($1) AND deleted_at IS NULL LIMIT 1`)).
 					WithArgs("user@example.com").
-					WillReturnRows(sqlmock.NewRows([]string{"uuid", "name", "email", "password", "created_at", "updated_at"}).
-						AddRow("u1", "Name", "user@example.com", string(hashed), time.Now(), time.Now()))
+					WillReturnRows(sqlmock.NewRows([]string{"uuid", "name", "email", "password", "created_at", "updated_at", "status", "status_reason", "suspended_until", "phone", "version"}).
+						AddRow("u1", "Name", "user@example.com", string(hashed), time.Now(), time.Now(), "active", "", nil, nil, 1))
 			},
 			before: func(_ *JwtService) {
 				// Force HS256 to use an unavailable hash to make SignedString fail
@@ -168,10 +245,10 @@ func TestAuthService_Login(t *testing.T) {
 			name: "RefreshTokenSignMethodError",
 			req:  &dto.LoginRequest{Email: "user@example.com", Password: "pass"},
 			setupDB: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery(regexp.QuoteMeta(`SELECT uuid, name, email, password, created_at, updated_at FROM users WHERE email = $1 LIMIT 1`)).
+				mock.ExpectQuery(regexp.QuoteMeta(`SELECT uuid, name, email, password, created_at, updated_at, status, status_reason, suspended_until, phone, version FROM users WHERE lower(email) = lower($1) AND deleted_at IS NULL LIMIT 1`)).
 					WithArgs("user@example.com").
-					WillReturnRows(sqlmock.NewRows([]string{"uuid", "name", "email", "password", "created_at", "updated_at"}).
-						AddRow("u1", "Name", "user@example.com", string(hashed), time.Now(), time.Now()))
+					WillReturnRows(sqlmock.NewRows([]string{"uuid", "name", "email", "password", "created_at", "updated_at", "status", "status_reason", "suspended_until", "phone", "version"}).
+						AddRow("u1", "Name", "user@example.com", string(hashed), time.Now(), time.Now(), "active", "", nil, nil, 1))
 			},
 			before: func(js *JwtService) {
 				// Override only the refresh signing method to force a signing error
@@ -192,13 +269,13 @@ func TestAuthService_Login(t *testing.T) {
 
 	for _, tc := range cases {
 		t.Run(tc.name, func(t *testing.T) {
-			repo, uow, mock, cleanup := setupRepoAndUow(t)
+			repo, outbox, auditSvc, loginHistory, uow, mock, cleanup := setupRepoAndUow(t)
 			defer cleanup()
 			cfg := testEnvConfig()
 			log := testLogger()
 			jwtSvc := NewJwtService(log, cfg)
 			blSvc := NewBlacklistService(log, jwtSvc, &fakeBLRepo{})
-			svc := NewAuthService(jwtSvc, repo, blSvc, log, uow)
+			svc := NewAuthService(jwtSvc, repo, outbox, auditSvc, loginHistory, blSvc, log, uow)
 
 			if tc.setupDB != nil {
 				tc.setupDB(mock)
@@ -236,7 +313,7 @@ func TestAuthService_Register(t *testing.T) {
 			name: "DBError_CheckingExisting",
 			setupDB: func(mock sqlmock.Sqlmock) {
 				mock.ExpectBegin()
-				mock.ExpectQuery(regexp.QuoteMeta(`SELECT COUNT(*) FROM users WHERE email = $1`)).
+				mock.ExpectQuery(regexp.QuoteMeta(`SELECT COUNT(*) FROM users WHERE lower(email) = lower($1) AND deleted_at IS NULL`)).
 					WithArgs("new@example.com").
 					WillReturnError(errors.New("db error"))
 				mock.ExpectRollback()
@@ -247,7 +324,7 @@ func TestAuthService_Register(t *testing.T) {
 			name: "AlreadyExists",
 			setupDB: func(mock sqlmock.Sqlmock) {
 				mock.ExpectBegin()
-				mock.ExpectQuery(regexp.QuoteMeta(`SELECT COUNT(*) FROM users WHERE email = $1`)).
+				mock.ExpectQuery(regexp.QuoteMeta(`SELECT COUNT(*) FROM users WHERE lower(email) = lower($1) AND deleted_at IS NULL`)).
 					WithArgs("new@example.com").
 					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
 				mock.ExpectRollback()
@@ -258,7 +335,7 @@ func TestAuthService_Register(t *testing.T) {
 			name: "CreateError",
 			setupDB: func(mock sqlmock.Sqlmock) {
 				mock.ExpectBegin()
-				mock.ExpectQuery(regexp.QuoteMeta(`SELECT COUNT(*) FROM users WHERE email = $1`)).
+				mock.ExpectQuery(regexp.QuoteMeta(`SELECT COUNT(*) FROM users WHERE lower(email) = lower($1) AND deleted_at IS NULL`)).
 					WithArgs("new@example.com").
 					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
 				mock.ExpectExec(regexp.QuoteMeta(`
@@ -273,13 +350,17 @@ func TestAuthService_Register(t *testing.T) {
 			name: "Success",
 			setupDB: func(mock sqlmock.Sqlmock) {
 				mock.ExpectBegin()
-				mock.ExpectQuery(regexp.QuoteMeta(`SELECT COUNT(*) FROM users WHERE email = $1`)).
+				mock.ExpectQuery(regexp.QuoteMeta(`SELECT COUNT(*) FROM users WHERE lower(email) = lower($1) AND deleted_at IS NULL`)).
 					WithArgs("new@example.com").
 					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
 				mock.ExpectExec(regexp.QuoteMeta(`
         INSERT INTO users (uuid, name, email, password, created_at, updated_at)
         VALUES ($1, $2, $3, $4, NOW(), NOW())
     `)).WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO outbox`)).
+					WithArgs(sqlmock.AnyArg(), "user", sqlmock.AnyArg(), "user.registered", sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				expectAudit(mock, "user.registered")
 				mock.ExpectCommit()
 			},
 			assertResp: func(t *testing.T, resp *dto.UserResponse) {
@@ -289,11 +370,24 @@ func TestAuthService_Register(t *testing.T) {
 				require.NotEmpty(t, resp.UUID)
 			},
 		},
+		{
+			name: "OutboxError_RollsBack",
+			setupDB: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(regexp.QuoteMeta(`SELECT COUNT(*) FROM users WHERE lower(email) = lower($1) AND deleted_at IS NULL`)).
+					WithArgs("new@example.com").
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+				mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO users`)).WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO outbox`)).WillReturnError(errors.New("outbox error"))
+				mock.ExpectRollback()
+			},
+			expectErr: errcode.ErrUserCreationFailed,
+		},
 		{
 			name: "PasswordHashError",
 			setupDB: func(mock sqlmock.Sqlmock) {
 				mock.ExpectBegin()
-				mock.ExpectQuery(regexp.QuoteMeta(`SELECT COUNT(*) FROM users WHERE email = $1`)).
+				mock.ExpectQuery(regexp.QuoteMeta(`SELECT COUNT(*) FROM users WHERE lower(email) = lower($1) AND deleted_at IS NULL`)).
 					WithArgs("new@example.com").
 					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
 				// No INSERT expected because hashing fails
@@ -325,13 +419,13 @@ func TestAuthService_Register(t *testing.T) {
 
 	for _, tc := range cases {
 		t.Run(tc.name, func(t *testing.T) {
-			repo, uow, mock, cleanup := setupRepoAndUow(t)
+			repo, outbox, auditSvc, loginHistory, uow, mock, cleanup := setupRepoAndUow(t)
 			defer cleanup()
 			cfg := testEnvConfig()
 			log := testLogger()
 			jwtSvc := NewJwtService(log, cfg)
 			blSvc := NewBlacklistService(log, jwtSvc, &fakeBLRepo{})
-			svc := NewAuthService(jwtSvc, repo, blSvc, log, uow)
+			svc := NewAuthService(jwtSvc, repo, outbox, auditSvc, loginHistory, blSvc, log, uow)
 			if tc.mutateSvc != nil {
 				tc.mutateSvc(svc)
 			}
@@ -364,6 +458,7 @@ func TestAuthService_RefreshToken(t *testing.T) {
 	type testcase struct {
 		name      string
 		setupRepo func(*fakeBLRepo)
+		setupDB   func(sqlmock.Sqlmock)
 		mutateSvc func(*JwtService)
 		after     func(*JwtService)
 		token     string
@@ -377,6 +472,15 @@ func TestAuthService_RefreshToken(t *testing.T) {
 	validRefresh, err := jwtSvc.GenerateRefreshToken(context.Background(), "u1")
 	require.NoError(t, err)
 
+	statusQuery := regexp.QuoteMeta(`SELECT uuid, status, status_reason, suspended_until, tokens_valid_after FROM users WHERE uuid = $1 AND deleted_at IS NULL LIMIT 1`)
+	expectStatus := func(status string) func(sqlmock.Sqlmock) {
+		return func(mock sqlmock.Sqlmock) {
+			mock.ExpectQuery(statusQuery).
+				WithArgs("u1").
+				WillReturnRows(sqlmock.NewRows([]string{"uuid", "status", "status_reason", "suspended_until", "tokens_valid_after"}).AddRow("u1", status, "", nil, nil))
+		}
+	}
+
 	cases := []testcase{
 		{
 			name: "Blacklisted",
@@ -401,6 +505,10 @@ func TestAuthService_RefreshToken(t *testing.T) {
 		{
 			name:  "Success",
 			token: validRefresh,
+			setupDB: func(mock sqlmock.Sqlmock) {
+				expectStatus("active")(mock)
+				expectAuditTx(mock, "auth.token_refreshed")
+			},
 			setupRepo: func(f *fakeBLRepo) {
 				f.isBlacklisted = func(_ string, _ constant.TokenType) (bool, error) { return false, nil }
 				f.add = func(_ string, _ constant.TokenType, d time.Duration) error {
@@ -415,11 +523,52 @@ func TestAuthService_RefreshToken(t *testing.T) {
 			},
 		},
 		{
-			name:  "AccessGenerationError",
+			name:  "Suspended",
 			token: validRefresh,
 			setupRepo: func(f *fakeBLRepo) {
 				f.isBlacklisted = func(_ string, _ constant.TokenType) (bool, error) { return false, nil }
 			},
+			setupDB: expectStatus("suspended"),
+			assert: func(t *testing.T, _, _ string, err error) {
+				require.ErrorIs(t, err, errcode.ErrUserSuspended)
+			},
+		},
+		{
+			name:  "UserDeleted",
+			token: validRefresh,
+			setupRepo: func(f *fakeBLRepo) {
+				f.isBlacklisted = func(_ string, _ constant.TokenType) (bool, error) { return false, nil }
+			},
+			setupDB: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(statusQuery).WithArgs("u1").WillReturnError(errors.New("no rows"))
+			},
+			assert: func(t *testing.T, _, _ string, err error) {
+				require.ErrorIs(t, err, errcode.ErrUnauthorized)
+			},
+		},
+		{
+			// Another session of a user who deleted their account stays
+			// revoked after an admin restores it
+			name:  "RevokedBeforeRestore",
+			token: validRefresh,
+			setupRepo: func(f *fakeBLRepo) {
+				f.isBlacklisted = func(_ string, _ constant.TokenType) (bool, error) { return false, nil }
+			},
+			setupDB: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(statusQuery).WithArgs("u1").
+					WillReturnRows(sqlmock.NewRows([]string{"uuid", "status", "status_reason", "suspended_until", "tokens_valid_after"}).AddRow("u1", "active", "", nil, time.Now()))
+			},
+			assert: func(t *testing.T, _, _ string, err error) {
+				require.ErrorIs(t, err, errcode.ErrUnauthorized)
+			},
+		},
+		{
+			name:    "AccessGenerationError",
+			token:   validRefresh,
+			setupDB: expectStatus("active"),
+			setupRepo: func(f *fakeBLRepo) {
+				f.isBlacklisted = func(_ string, _ constant.TokenType) (bool, error) { return false, nil }
+			},
 			mutateSvc: func(js *JwtService) {
 				js.SetAccessMethod(failingSignMethod{})
 			},
@@ -431,8 +580,9 @@ func TestAuthService_RefreshToken(t *testing.T) {
 			},
 		},
 		{
-			name:  "RefreshGenerationError",
-			token: validRefresh,
+			name:    "RefreshGenerationError",
+			token:   validRefresh,
+			setupDB: expectStatus("active"),
 			setupRepo: func(f *fakeBLRepo) {
 				f.isBlacklisted = func(_ string, _ constant.TokenType) (bool, error) { return false, nil }
 			},
@@ -449,6 +599,10 @@ func TestAuthService_RefreshToken(t *testing.T) {
 		{
 			name:  "BlacklistAddFails",
 			token: validRefresh,
+			setupDB: func(mock sqlmock.Sqlmock) {
+				expectStatus("active")(mock)
+				expectAuditTx(mock, "auth.token_refreshed")
+			},
 			setupRepo: func(f *fakeBLRepo) {
 				f.isBlacklisted = func(_ string, _ constant.TokenType) (bool, error) { return false, nil }
 				f.add = func(_ string, _ constant.TokenType, _ time.Duration) error { return errors.New("redis set") }
@@ -475,8 +629,13 @@ func TestAuthService_RefreshToken(t *testing.T) {
 			if tc.setupRepo != nil {
 				tc.setupRepo(f)
 			}
+			repo, outbox, auditSvc, loginHistory, uow, mock, cleanup := setupRepoAndUow(t)
+			defer cleanup()
+			if tc.setupDB != nil {
+				tc.setupDB(mock)
+			}
 			blSvc := NewBlacklistService(log, jwtSvc, f)
-			svc := NewAuthService(jwtSvc, nil, blSvc, log, nil)
+			svc := NewAuthService(jwtSvc, repo, outbox, auditSvc, loginHistory, blSvc, log, uow)
 			if tc.mutateSvc != nil {
 				tc.mutateSvc(jwtSvc)
 			}
@@ -486,6 +645,7 @@ func TestAuthService_RefreshToken(t *testing.T) {
 			if tc.after != nil {
 				tc.after(jwtSvc)
 			}
+			require.NoError(t, mock.ExpectationsWereMet())
 		})
 	}
 }
@@ -533,7 +693,7 @@ func TestAuthService_Logout(t *testing.T) {
 				tc.setupRepo(f)
 			}
 			blSvc := NewBlacklistService(log, jwtSvc, f)
-			svc := NewAuthService(jwtSvc, nil, blSvc, log, nil)
+			svc := NewAuthService(jwtSvc, nil, nil, nil, nil, blSvc, log, nil)
 
 			err := svc.Logout(context.Background(), "access", "refresh")
 			tc.assert(t, err)
@@ -93,3 +93,17 @@ func (b *BlacklistService) Add(ctx context.Context, token string, tokenType cons
 
 	return nil
 }
+
+// Prune gives blacklist entries that never expire a TTL of maxAge and returns
+// how many it changed.
+func (b *BlacklistService) Prune(ctx context.Context, maxAge time.Duration) (int64, error) {
+	spanCtx, span := b.tracer.Start(ctx, "BlacklistService.Prune")
+	defer span.End()
+
+	pruned, err := b.blacklistRepository.Prune(spanCtx, maxAge)
+	if err != nil {
+		b.log.WithContext(spanCtx).WithError(err).Error("failed to prune token blacklist")
+		return pruned, errcode.ErrRedisSet
+	}
+	return pruned, nil
+}
@@ -56,6 +56,10 @@ func (f *blFakeRepo) IsBlacklisted(token string, tokenType constant.TokenType) (
     return false, nil
 }
 
+func (f *blFakeRepo) Prune(context.Context, time.Duration) (int64, error) {
+    return 0, nil
+}
+
 func TestBlacklistService_IsTokenBlacklisted(t *testing.T) {
     type testcase struct {
         name      string
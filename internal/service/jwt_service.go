@@ -2,12 +2,16 @@ package service
 
 import (
 	"context"
+	"crypto/rand"
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
 	"go-starter-template/internal/config/env"
 	"go-starter-template/internal/constant"
+	"go-starter-template/internal/model"
+	"go-starter-template/internal/repository"
 	"go-starter-template/internal/utils/errcode"
+	"sync"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -31,12 +35,23 @@ type JwtService struct {
 	refreshMethod    jwt.SigningMethod
 	parseOverride    func(ctx context.Context, token string, tokenType constant.TokenType) (*Claims, error)
 	validateOverride func(tokenString string, claims *Claims, secretKey string) (*jwt.Token, error)
+	keys             *repository.SigningKeyRepository
+	// keyCache holds verification secrets by token type and kid; keys never
+	// change once created
+	keyCache sync.Map
 }
 
 func NewJwtService(log *logrus.Logger, config *env.Config) *JwtService {
 	return &JwtService{log: log, config: config, tracer: otel.Tracer("JwtService"), accessMethod: jwt.SigningMethodHS256, refreshMethod: jwt.SigningMethodHS256}
 }
 
+// UseKeyStore signs tokens with the newest key in keys, naming it in the kid
+// header, once RotateSigningKeys has created one. Tokens without a kid are
+// still checked against the configured secrets.
+func (j *JwtService) UseKeyStore(keys *repository.SigningKeyRepository) {
+	j.keys = keys
+}
+
 // SetAccessMethod allows overriding the signing method for access tokens (useful in tests)
 func (j *JwtService) SetAccessMethod(m jwt.SigningMethod) {
 	j.accessMethod = m
@@ -71,8 +86,7 @@ func (j *JwtService) GenerateAccessToken(ctx context.Context, userUUID string) (
 		},
 	}
 
-	token := jwt.NewWithClaims(j.accessMethod, claims)
-	return token.SignedString([]byte(j.config.GetAccessSecret()))
+	return j.sign(ctx, jwt.NewWithClaims(j.accessMethod, claims), constant.TokenTypeAccess, j.config.GetAccessSecret())
 }
 
 // GenerateRefreshToken creates a long-lived JWT refresh token
@@ -90,26 +104,107 @@ func (j *JwtService) GenerateRefreshToken(ctx context.Context, userUUID string)
 		},
 	}
 
-	token := jwt.NewWithClaims(j.refreshMethod, claims)
-	return token.SignedString([]byte(j.config.GetRefreshSecret()))
+	return j.sign(ctx, jwt.NewWithClaims(j.refreshMethod, claims), constant.TokenTypeRefresh, j.config.GetRefreshSecret())
+}
+
+// sign signs token with the newest key of tokenType, or with secret if there
+// is no key store or no key yet.
+func (j *JwtService) sign(ctx context.Context, token *jwt.Token, tokenType constant.TokenType, secret string) (string, error) {
+	if j.keys == nil {
+		return token.SignedString([]byte(secret))
+	}
+	keys, err := j.keys.List(ctx, tokenType)
+	if err != nil {
+		return "", err
+	}
+	if len(keys) == 0 {
+		return token.SignedString([]byte(secret))
+	}
+	current := keys[len(keys)-1]
+	token.Header["kid"] = current.ID
+	return token.SignedString(current.Secret)
+}
+
+// verificationKey returns the secret of the key of tokenType named kid.
+func (j *JwtService) verificationKey(ctx context.Context, tokenType constant.TokenType, kid string) ([]byte, error) {
+	if j.keys == nil {
+		return nil, errcode.ErrInvalidToken
+	}
+	cacheKey := string(tokenType) + ":" + kid
+	if secret, ok := j.keyCache.Load(cacheKey); ok {
+		return secret.([]byte), nil
+	}
+	key, err := j.keys.Get(ctx, tokenType, kid)
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		return nil, errcode.ErrInvalidToken
+	}
+	j.keyCache.Store(cacheKey, key.Secret)
+	return key.Secret, nil
+}
+
+// RotateSigningKeys creates a new signing key for access and refresh tokens
+// and deletes the keys whose tokens have all expired, that is keys replaced
+// longer ago than the token lifetime. It returns how many keys it deleted.
+func (j *JwtService) RotateSigningKeys(ctx context.Context) (int, error) {
+	spanCtx, span := j.tracer.Start(ctx, "JwtService.RotateSigningKeys")
+	defer span.End()
+
+	if j.keys == nil {
+		return 0, nil
+	}
+	lifetimes := map[constant.TokenType]time.Duration{
+		constant.TokenTypeAccess:  j.config.GetAccessTokenExpiration(),
+		constant.TokenTypeRefresh: j.config.GetRefreshTokenExpiration(),
+	}
+	retired := 0
+	for tokenType, lifetime := range lifetimes {
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return retired, err
+		}
+		now := time.Now()
+		if err := j.keys.Add(spanCtx, tokenType, &model.SigningKey{ID: uuid.NewString(), Secret: secret, CreatedAt: now}); err != nil {
+			return retired, err
+		}
+
+		keys, err := j.keys.List(spanCtx, tokenType)
+		if err != nil {
+			return retired, err
+		}
+		var expired []string
+		for i, key := range keys[:len(keys)-1] {
+			// Tokens signed with key were issued before its successor existed
+			if keys[i+1].CreatedAt.Add(lifetime).Before(now) {
+				expired = append(expired, key.ID)
+			}
+		}
+		if err := j.keys.Delete(spanCtx, tokenType, expired...); err != nil {
+			return retired, err
+		}
+		retired += len(expired)
+	}
+	return retired, nil
 }
 
 func (j *JwtService) ValidateAccessToken(ctx context.Context, token string) (*Claims, error) {
 	spanCtx, span := j.tracer.Start(ctx, "JwtService.ValidateAccessToken")
 	defer span.End()
 
-	return j.validateToken(spanCtx, token, j.config.GetAccessSecret())
+	return j.validateToken(spanCtx, token, constant.TokenTypeAccess, j.config.GetAccessSecret())
 }
 
 func (j *JwtService) ValidateRefreshToken(ctx context.Context, token string) (*Claims, error) {
 	spanCtx, span := j.tracer.Start(ctx, "JwtService.ValidateRefreshToken")
 	defer span.End()
 
-	return j.validateToken(spanCtx, token, j.config.GetRefreshSecret())
+	return j.validateToken(spanCtx, token, constant.TokenTypeRefresh, j.config.GetRefreshSecret())
 }
 
 // ValidateToken verifies a JWT token and returns the claims if valid
-func (j *JwtService) validateToken(ctx context.Context, tokenString string, secretKey string) (*Claims, error) {
+func (j *JwtService) validateToken(ctx context.Context, tokenString string, tokenType constant.TokenType, secretKey string) (*Claims, error) {
 	spanCtx, span := j.tracer.Start(ctx, "JwtService.validateToken")
 	defer span.End()
 
@@ -126,6 +221,9 @@ func (j *JwtService) validateToken(ctx context.Context, tokenString string, secr
 				logger.Error("Token method not match")
 				return nil, errcode.ErrUnexpectedSignMethod
 			}
+			if kid, ok := token.Header["kid"].(string); ok {
+				return j.verificationKey(spanCtx, tokenType, kid)
+			}
 			return []byte(secretKey), nil
 		})
 	}
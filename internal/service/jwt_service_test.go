@@ -10,9 +10,13 @@ import (
 
     "go-starter-template/internal/config/env"
     "go-starter-template/internal/constant"
+    "go-starter-template/internal/model"
+    "go-starter-template/internal/repository"
     "go-starter-template/internal/utils/errcode"
 
+    miniredis "github.com/alicebob/miniredis/v2"
     "github.com/golang-jwt/jwt/v5"
+    "github.com/redis/go-redis/v9"
     "github.com/stretchr/testify/require"
 )
 
@@ -350,4 +354,65 @@ func TestJwtService_GenerateTokenHash(t *testing.T) {
             require.Equal(t, c.expect, got)
         })
     }
-}
\ No newline at end of file
+}
+
+func TestJwtService_SigningKeys(t *testing.T) {
+    cfg := testEnvConfig()
+    mr := miniredis.RunT(t)
+    keys := repository.NewSigningKeyRepository(redis.NewClient(&redis.Options{Addr: mr.Addr()}))
+    svc := NewJwtService(testLogger(), cfg)
+    svc.UseKeyStore(keys)
+    ctx := context.Background()
+
+    // Without a key yet, tokens are signed with the configured secret
+    legacy, err := svc.GenerateAccessToken(ctx, "u1")
+    require.NoError(t, err)
+    parsed, _, err := jwt.NewParser().ParseUnverified(legacy, &Claims{})
+    require.NoError(t, err)
+    require.NotContains(t, parsed.Header, "kid")
+
+    // A replaced key outlives its successor's creation by the token lifetime
+    require.NoError(t, keys.Add(ctx, constant.TokenTypeAccess, &model.SigningKey{ID: "old", Secret: []byte("old"), CreatedAt: time.Now().Add(-2 * time.Hour)}))
+    require.NoError(t, keys.Add(ctx, constant.TokenTypeAccess, &model.SigningKey{ID: "previous", Secret: []byte("previous"), CreatedAt: time.Now().Add(-time.Hour)}))
+    previous, err := svc.GenerateAccessToken(ctx, "u1")
+    require.NoError(t, err)
+
+    retired, err := svc.RotateSigningKeys(ctx)
+    require.NoError(t, err)
+    require.Equal(t, 1, retired)
+    access, err := keys.List(ctx, constant.TokenTypeAccess)
+    require.NoError(t, err)
+    require.Len(t, access, 2)
+    require.Equal(t, "previous", access[0].ID)
+    refresh, err := keys.List(ctx, constant.TokenTypeRefresh)
+    require.NoError(t, err)
+    require.Len(t, refresh, 1)
+
+    current, err := svc.GenerateAccessToken(ctx, "u1")
+    require.NoError(t, err)
+    parsed, _, err = jwt.NewParser().ParseUnverified(current, &Claims{})
+    require.NoError(t, err)
+    require.Equal(t, access[1].ID, parsed.Header["kid"])
+
+    for _, token := range []string{legacy, previous, current} {
+        claims, err := svc.ValidateAccessToken(ctx, token)
+        require.NoError(t, err)
+        require.Equal(t, "u1", claims.UUID)
+    }
+
+    // Refresh tokens are signed with their own key
+    refreshToken, err := svc.GenerateRefreshToken(ctx, "u1")
+    require.NoError(t, err)
+    _, err = svc.ValidateRefreshToken(ctx, refreshToken)
+    require.NoError(t, err)
+    _, err = svc.ValidateAccessToken(ctx, refreshToken)
+    require.Error(t, err)
+
+    // A token naming an unknown key is rejected
+    forged := jwt.NewWithClaims(jwt.SigningMethodHS256, Claims{UUID: "u1", RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute))}})
+    forged.Header["kid"] = "unknown"
+    signed, err := forged.SignedString([]byte(cfg.GetAccessSecret()))
+    require.NoError(t, err)
+    _, err = svc.ValidateAccessToken(ctx, signed)
+    require.ErrorIs(t, err, errcode.ErrInvalidToken)
+}
@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"go-starter-template/internal/audit"
+	"go-starter-template/internal/dto"
+	"go-starter-template/internal/dto/converter"
+	"go-starter-template/internal/model"
+	"go-starter-template/internal/notify"
+	"go-starter-template/internal/repository"
+	"go-starter-template/internal/utils/errcode"
+	"go-starter-template/internal/utils/geoip"
+	"go-starter-template/internal/utils/useragent"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// LoginHistoryService records login attempts and shows users their own.
+type LoginHistoryService struct {
+	loginEventRepository *repository.LoginEventRepository
+	// geoIP locates client addresses; nil leaves locations unknown
+	geoIP *geoip.DB
+	// notifier alerts users to logins from a new device or country
+	notifier notify.Notifier
+	log      *logrus.Logger
+	tracer   trace.Tracer
+	uow      *repository.UnitOfWork
+}
+
+func NewLoginHistoryService(loginEventRepository *repository.LoginEventRepository, geoIP *geoip.DB, log *logrus.Logger, uow *repository.UnitOfWork) *LoginHistoryService {
+	return &LoginHistoryService{loginEventRepository: loginEventRepository, geoIP: geoIP, notifier: notify.NewLogNotifier(log), log: log, tracer: otel.Tracer("LoginHistoryService"), uow: uow}
+}
+
+// UseNotifier replaces the default notifier, which only logs.
+func (s *LoginHistoryService) UseNotifier(notifier notify.Notifier) {
+	s.notifier = notifier
+}
+
+// Record stores a login attempt of user in the caller's transaction, or in
+// one of its own; a non-empty failureReason marks it as failed. The client's
+// address and user agent come from ctx. A successful login from a new device
+// or country notifies the user once the transaction has committed.
+func (s *LoginHistoryService) Record(ctx context.Context, user *model.User, failureReason string) error {
+	spanCtx, span := s.tracer.Start(ctx, "LoginHistoryService.Record")
+	defer span.End()
+
+	request := audit.FromContext(spanCtx)
+	location := s.geoIP.Lookup(request.IP)
+	login := &model.LoginEvent{
+		UserUUID:      user.UUID,
+		Success:       failureReason == "",
+		FailureReason: failureReason,
+		IP:            request.IP,
+		UserAgent:     request.UserAgent,
+		Device:        useragent.Device(request.UserAgent),
+		Country:       location.Country,
+		Region:        location.Region,
+		City:          location.City,
+	}
+
+	if err := s.uow.Join(spanCtx, func(txCtx context.Context) error {
+		if login.Success {
+			known, err := s.loginEventRepository.Known(txCtx, user.UUID, login.Device, login.Country)
+			if err != nil {
+				return err
+			}
+			// The first login has nothing to compare with
+			login.NewDevice = known.Any && !known.Device
+			login.NewCountry = known.Any && login.Country != "" && !known.Country
+		}
+		if err := s.loginEventRepository.Create(txCtx, login); err != nil {
+			return err
+		}
+		if login.NewDevice || login.NewCountry {
+			repository.AfterCommit(txCtx, func(ctx context.Context) {
+				if err := s.notifyNewLogin(ctx, user, login); err != nil {
+					s.log.WithContext(ctx).WithError(err).WithField("user_uuid", user.UUID).Error("Failed to notify about new login")
+				}
+			})
+		}
+		return nil
+	}); err != nil {
+		s.log.WithContext(spanCtx).WithError(err).Error("Failed to record login")
+		return errcode.ErrDatabaseError
+	}
+	return nil
+}
+
+// List returns the user's logins matching request, newest first.
+func (s *LoginHistoryService) List(ctx context.Context, userUUID string, request *dto.ListLoginsRequest) ([]*dto.LoginEventResponse, *dto.PageMetadata, error) {
+	spanCtx, span := s.tracer.Start(ctx, "LoginHistoryService.List")
+	defer span.End()
+
+	var beforeID int64
+	if request.Cursor != "" {
+		id, err := strconv.ParseInt(request.Cursor, 10, 64)
+		if err != nil || id <= 0 {
+			return nil, nil, errcode.ErrInvalidCursor
+		}
+		beforeID = id
+	}
+
+	logins, err := s.loginEventRepository.ListByUser(spanCtx, userUUID, beforeID, request.Limit)
+	if err != nil {
+		s.log.WithContext(spanCtx).WithError(err).Error("Failed to list logins")
+		return nil, nil, errcode.ErrDatabaseError
+	}
+
+	responses := make([]*dto.LoginEventResponse, len(logins))
+	for i, login := range logins {
+		responses[i] = converter.LoginEventToResponse(login)
+	}
+	paging := &dto.PageMetadata{Size: len(responses)}
+	if len(logins) == request.Limit {
+		paging.Next = strconv.FormatInt(logins[len(logins)-1].ID, 10)
+	}
+	return responses, paging, nil
+}
+
+// notifyNewLogin emails the user about a login from a new device or country.
+func (s *LoginHistoryService) notifyNewLogin(ctx context.Context, user *model.User, login *model.LoginEvent) error {
+	var location []string
+	for _, part := range []string{login.City, login.Region, login.Country} {
+		if part != "" {
+			location = append(location, part)
+		}
+	}
+	return s.notifier.Notify(ctx, notify.Message{
+		Channel:  notify.ChannelEmail,
+		To:       user.Email,
+		Template: notify.TemplateNewLogin,
+		Data: map[string]any{
+			"Name":      user.Name,
+			"NewDevice": login.NewDevice,
+			"Device":    login.Device,
+			"Location":  strings.Join(location, ", "),
+			"IP":        login.IP,
+			"Time":      login.CreatedAt.UTC().Format("2006-01-02 15:04 MST"),
+		},
+	})
+}
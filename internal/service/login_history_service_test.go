@@ -0,0 +1,179 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+
+	"go-starter-template/internal/audit"
+	"go-starter-template/internal/dto"
+	"go-starter-template/internal/model"
+	"go-starter-template/internal/notify"
+	"go-starter-template/internal/repository"
+	"go-starter-template/internal/utils/errcode"
+	"go-starter-template/internal/utils/geoip"
+)
+
+const chromeOnMac = "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0.0.0 Safari/537.36"
+
+// recordingNotifier remembers the messages it was asked to send.
+type recordingNotifier struct {
+	messages []notify.Message
+	err      error
+}
+
+func (n *recordingNotifier) Notify(_ context.Context, msg notify.Message) error {
+	n.messages = append(n.messages, msg)
+	return n.err
+}
+
+func setupLoginHistoryService(t *testing.T) (*LoginHistoryService, *recordingNotifier, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	geo, err := geoip.Load(strings.NewReader("8.8.8.0,8.8.8.255,NA,US,California,Mountain View,0,0\n"))
+	require.NoError(t, err)
+
+	notifier := new(recordingNotifier)
+	svc := NewLoginHistoryService(repository.NewLoginEventRepository(db), geo, testLogger(), repository.NewUnitOfWork(db))
+	svc.UseNotifier(notifier)
+	return svc, notifier, mock
+}
+
+func TestLoginHistoryService_Record(t *testing.T) {
+	knownQuery := regexp.QuoteMeta("FROM login_events WHERE user_uuid = $1 AND success")
+	insertQuery := regexp.QuoteMeta("INSERT INTO login_events")
+	user := &model.User{UUID: "u1", Name: "Alice", Email: "alice@example.com"}
+	ctx := audit.WithRequest(context.Background(), "8.8.8.8", chromeOnMac)
+
+	type testcase struct {
+		name          string
+		known         [3]bool
+		expectDevice  bool
+		expectCountry bool
+	}
+
+	cases := []testcase{
+		{name: "FirstLogin", known: [3]bool{false, false, false}},
+		{name: "KnownDeviceAndCountry", known: [3]bool{true, true, true}},
+		{name: "NewDevice", known: [3]bool{true, false, true}, expectDevice: true},
+		{name: "NewCountry", known: [3]bool{true, true, false}, expectCountry: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			svc, notifier, mock := setupLoginHistoryService(t)
+			mock.ExpectBegin()
+			mock.ExpectQuery(knownQuery).WithArgs("u1", "Chrome on macOS", "US").
+				WillReturnRows(sqlmock.NewRows([]string{"any", "device", "country"}).AddRow(tc.known[0], tc.known[1], tc.known[2]))
+			mock.ExpectQuery(insertQuery).
+				WithArgs(sqlmock.AnyArg(), "u1", true, "", "8.8.8.8", chromeOnMac, "Chrome on macOS", "US", "California", "Mountain View", tc.expectDevice, tc.expectCountry, sqlmock.AnyArg()).
+				WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+			mock.ExpectCommit()
+
+			require.NoError(t, svc.Record(ctx, user, ""))
+			if tc.expectDevice || tc.expectCountry {
+				require.Len(t, notifier.messages, 1)
+				msg := notifier.messages[0]
+				require.Equal(t, notify.ChannelEmail, msg.Channel)
+				require.Equal(t, "alice@example.com", msg.To)
+				require.Equal(t, notify.TemplateNewLogin, msg.Template)
+				require.Equal(t, tc.expectDevice, msg.Data["NewDevice"])
+				require.Equal(t, "Chrome on macOS", msg.Data["Device"])
+				require.Equal(t, "Mountain View, California, US", msg.Data["Location"])
+			} else {
+				require.Empty(t, notifier.messages)
+			}
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+
+	t.Run("FailedLoginNeverNotifies", func(t *testing.T) {
+		svc, notifier, mock := setupLoginHistoryService(t)
+		mock.ExpectBegin()
+		mock.ExpectQuery(insertQuery).
+			WithArgs(sqlmock.AnyArg(), "u1", false, "invalid_password", "8.8.8.8", chromeOnMac, "Chrome on macOS", "US", "California", "Mountain View", false, false, sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectCommit()
+
+		require.NoError(t, svc.Record(ctx, user, "invalid_password"))
+		require.Empty(t, notifier.messages)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("NoNotificationOnRollback", func(t *testing.T) {
+		svc, notifier, mock := setupLoginHistoryService(t)
+		mock.ExpectBegin()
+		mock.ExpectQuery(knownQuery).WillReturnRows(sqlmock.NewRows([]string{"any", "device", "country"}).AddRow(true, false, false))
+		mock.ExpectQuery(insertQuery).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectRollback()
+
+		err := svc.uow.Do(ctx, func(txCtx context.Context) error {
+			require.NoError(t, svc.Record(txCtx, user, ""))
+			return errors.New("token generation failed")
+		})
+		require.Error(t, err)
+		require.Empty(t, notifier.messages)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("NotifierErrorIsIgnored", func(t *testing.T) {
+		svc, notifier, mock := setupLoginHistoryService(t)
+		notifier.err = errors.New("smtp down")
+		mock.ExpectBegin()
+		mock.ExpectQuery(knownQuery).WillReturnRows(sqlmock.NewRows([]string{"any", "device", "country"}).AddRow(true, false, true))
+		mock.ExpectQuery(insertQuery).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectCommit()
+
+		require.NoError(t, svc.Record(ctx, user, ""))
+		require.Len(t, notifier.messages, 1)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("DatabaseError", func(t *testing.T) {
+		svc, _, mock := setupLoginHistoryService(t)
+		mock.ExpectBegin()
+		mock.ExpectQuery(knownQuery).WillReturnError(sqlmock.ErrCancelled)
+		mock.ExpectRollback()
+
+		require.ErrorIs(t, svc.Record(ctx, user, ""), errcode.ErrDatabaseError)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestLoginHistoryService_List(t *testing.T) {
+	columns := []string{"id", "uuid", "user_uuid", "success", "failure_reason", "ip", "user_agent", "device", "country", "region", "city", "new_device", "new_country", "created_at"}
+	now := time.Now()
+
+	t.Run("NextCursorOnFullPage", func(t *testing.T) {
+		svc, _, mock := setupLoginHistoryService(t)
+		mock.ExpectQuery(regexp.QuoteMeta("FROM login_events")).
+			WithArgs("u1", int64(9), 2).
+			WillReturnRows(sqlmock.NewRows(columns).
+				AddRow(8, "l8", "u1", true, "", "8.8.8.8", chromeOnMac, "Chrome on macOS", "US", "California", "Mountain View", true, false, now).
+				AddRow(4, "l4", "u1", false, "invalid_password", "8.8.8.8", "curl/8", "curl", "US", "", "", false, false, now))
+
+		logins, paging, err := svc.List(context.Background(), "u1", &dto.ListLoginsRequest{Cursor: "9", Limit: 2})
+		require.NoError(t, err)
+		require.Len(t, logins, 2)
+		require.Equal(t, "Chrome on macOS", logins[0].Device)
+		require.True(t, logins[0].NewDevice)
+		require.Equal(t, "invalid_password", logins[1].FailureReason)
+		require.Equal(t, "4", paging.Next)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("InvalidCursor", func(t *testing.T) {
+		svc, _, _ := setupLoginHistoryService(t)
+		_, _, err := svc.List(context.Background(), "u1", &dto.ListLoginsRequest{Cursor: "-1", Limit: 2})
+		require.ErrorIs(t, err, errcode.ErrInvalidCursor)
+	})
+}
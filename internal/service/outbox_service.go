@@ -0,0 +1,99 @@
+package service
+
+import (
+	"context"
+	"go-starter-template/internal/event"
+	"go-starter-template/internal/repository"
+	"go-starter-template/internal/utils/errcode"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// OutboxService relays the events recorded in the outbox to a sink.
+type OutboxService struct {
+	outboxRepository *repository.OutboxRepository
+	sink             event.Sink
+	log              *logrus.Logger
+	tracer           trace.Tracer
+	uow              *repository.UnitOfWork
+	maxAttempts      int
+}
+
+// NewOutboxService creates the relay service. An event failing maxAttempts
+// deliveries is parked; a non-positive maxAttempts retries it forever.
+func NewOutboxService(outboxRepository *repository.OutboxRepository, sink event.Sink, log *logrus.Logger, uow *repository.UnitOfWork, maxAttempts int) *OutboxService {
+	return &OutboxService{outboxRepository: outboxRepository, sink: sink, log: log, tracer: otel.Tracer("OutboxService"), uow: uow, maxAttempts: maxAttempts}
+}
+
+// PublishPending publishes up to limit unpublished events oldest first and
+// returns how many were delivered. Delivery is at least once: an event may
+// be sent again if marking it published fails, so consumers should dedupe by
+// envelope ID. After a failed delivery the aggregate's later events are held
+// back until it succeeds, keeping each aggregate's events in order. An event
+// that has failed maxAttempts times is parked instead, letting the rest of
+// its aggregate through.
+func (s *OutboxService) PublishPending(ctx context.Context, limit int) (int, error) {
+	spanCtx, span := s.tracer.Start(ctx, "OutboxService.PublishPending")
+	defer span.End()
+
+	logger := s.log.WithContext(spanCtx)
+	var published int
+	// Serialization failures are not retried: events already handed to the
+	// sink would be sent again
+	if err := s.uow.DoWith(spanCtx, repository.TxOptions{MaxRetries: -1}, func(txCtx context.Context) error {
+		events, err := s.outboxRepository.Claim(txCtx, limit)
+		if err != nil {
+			return err
+		}
+
+		blocked := make(map[string]bool)
+		for _, e := range events {
+			aggregate := e.AggregateType + ":" + e.AggregateID
+			if blocked[aggregate] {
+				continue
+			}
+			if err := s.sink.Publish(txCtx, event.FromOutbox(e)); err != nil {
+				fields := logrus.Fields{"event_id": e.UUID, "event_type": e.EventType, "attempts": e.Attempts + 1}
+				if s.maxAttempts > 0 && e.Attempts+1 >= s.maxAttempts {
+					logger.WithError(err).WithFields(fields).Error("Parked outbox event after too many failed deliveries")
+					if err := s.outboxRepository.Park(txCtx, e.ID, err.Error()); err != nil {
+						return err
+					}
+					continue
+				}
+				blocked[aggregate] = true
+				logger.WithError(err).WithFields(fields).Warn("Failed to publish outbox event")
+				if err := s.outboxRepository.MarkFailed(txCtx, e.ID, err.Error()); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := s.outboxRepository.MarkPublished(txCtx, e.ID); err != nil {
+				return err
+			}
+			published++
+		}
+		return nil
+	}); err != nil {
+		logger.WithError(err).Error("Failed to relay outbox events")
+		return 0, errcode.ErrDatabaseError
+	}
+
+	return published, nil
+}
+
+// PrunePublished deletes events published more than retention ago.
+func (s *OutboxService) PrunePublished(ctx context.Context, retention time.Duration) (int64, error) {
+	spanCtx, span := s.tracer.Start(ctx, "OutboxService.PrunePublished")
+	defer span.End()
+
+	pruned, err := s.outboxRepository.PrunePublished(spanCtx, time.Now().Add(-retention))
+	if err != nil {
+		s.log.WithContext(spanCtx).WithError(err).Error("Failed to prune published outbox events")
+		return 0, errcode.ErrDatabaseError
+	}
+	return pruned, nil
+}
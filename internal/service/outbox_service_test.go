@@ -0,0 +1,165 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+
+	"go-starter-template/internal/event"
+	"go-starter-template/internal/repository"
+	"go-starter-template/internal/utils/errcode"
+)
+
+// fakeSink records published envelopes and fails those listed in fail.
+type fakeSink struct {
+	published []string
+	fail      map[string]bool
+}
+
+func (s *fakeSink) Publish(_ context.Context, envelope event.Envelope) error {
+	if s.fail[envelope.ID] {
+		return errors.New("sink unavailable")
+	}
+	s.published = append(s.published, envelope.ID)
+	return nil
+}
+
+var outboxColumns = []string{"id", "uuid", "aggregate_type", "aggregate_id", "event_type", "payload", "created_at", "attempts", "last_error"}
+
+func setupOutboxService(t *testing.T, sink event.Sink) (*OutboxService, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherRegexp))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+	return NewOutboxService(repository.NewOutboxRepository(db), sink, silentLogger(), repository.NewUnitOfWork(db), 3), mock
+}
+
+func expectClaim(m sqlmock.Sqlmock, locked bool, rows *sqlmock.Rows) {
+	m.ExpectQuery(regexp.QuoteMeta("SELECT pg_try_advisory_xact_lock($1)")).
+		WillReturnRows(sqlmock.NewRows([]string{"locked"}).AddRow(locked))
+	if locked {
+		m.ExpectQuery(regexp.QuoteMeta("FROM outbox WHERE published_at IS NULL AND parked_at IS NULL ORDER BY id LIMIT $1")).
+			WithArgs(10).
+			WillReturnRows(rows)
+	}
+}
+
+func TestOutboxService_PublishPending(t *testing.T) {
+	now := time.Now()
+	markPublished := regexp.QuoteMeta("UPDATE outbox SET published_at = NOW() WHERE id = $1")
+	markFailed := regexp.QuoteMeta("UPDATE outbox SET attempts = attempts + 1, last_error = $2 WHERE id = $1")
+	park := regexp.QuoteMeta("UPDATE outbox SET attempts = attempts + 1, last_error = $2, parked_at = NOW() WHERE id = $1")
+
+	type testcase struct {
+		name            string
+		fail            map[string]bool
+		setupDB         func(sqlmock.Sqlmock)
+		expectPublished int
+		expectSent      []string
+		expectErr       error
+	}
+
+	cases := []testcase{
+		{
+			name: "InOrder",
+			setupDB: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				expectClaim(m, true, sqlmock.NewRows(outboxColumns).
+					AddRow(1, "e1", "user", "u1", "user.created", []byte(`{}`), now, 0, "").
+					AddRow(2, "e2", "user", "u2", "user.created", []byte(`{}`), now, 0, ""))
+				m.ExpectExec(markPublished).WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+				m.ExpectExec(markPublished).WithArgs(2).WillReturnResult(sqlmock.NewResult(0, 1))
+				m.ExpectCommit()
+			},
+			expectPublished: 2,
+			expectSent:      []string{"e1", "e2"},
+		},
+		{
+			name: "FailureHoldsBackAggregate",
+			fail: map[string]bool{"e1": true},
+			setupDB: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				expectClaim(m, true, sqlmock.NewRows(outboxColumns).
+					AddRow(1, "e1", "user", "u1", "user.created", []byte(`{}`), now, 0, "").
+					AddRow(2, "e2", "user", "u2", "user.created", []byte(`{}`), now, 0, "").
+					AddRow(3, "e3", "user", "u1", "user.updated", []byte(`{}`), now, 0, ""))
+				m.ExpectExec(markFailed).WithArgs(1, "sink unavailable").WillReturnResult(sqlmock.NewResult(0, 1))
+				m.ExpectExec(markPublished).WithArgs(2).WillReturnResult(sqlmock.NewResult(0, 1))
+				m.ExpectCommit()
+			},
+			expectPublished: 1,
+			expectSent:      []string{"e2"},
+		},
+		{
+			// The service allows 3 attempts: e1's third failure parks it and
+			// the aggregate's next event goes out
+			name: "ParksAfterMaxAttempts",
+			fail: map[string]bool{"e1": true},
+			setupDB: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				expectClaim(m, true, sqlmock.NewRows(outboxColumns).
+					AddRow(1, "e1", "user", "u1", "user.created", []byte(`{}`), now, 2, "sink unavailable").
+					AddRow(3, "e3", "user", "u1", "user.updated", []byte(`{}`), now, 0, ""))
+				m.ExpectExec(park).WithArgs(1, "sink unavailable").WillReturnResult(sqlmock.NewResult(0, 1))
+				m.ExpectExec(markPublished).WithArgs(3).WillReturnResult(sqlmock.NewResult(0, 1))
+				m.ExpectCommit()
+			},
+			expectPublished: 1,
+			expectSent:      []string{"e3"},
+		},
+		{
+			name: "LockHeldElsewhere",
+			setupDB: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				expectClaim(m, false, nil)
+				m.ExpectCommit()
+			},
+		},
+		{
+			name: "MarkError",
+			setupDB: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				expectClaim(m, true, sqlmock.NewRows(outboxColumns).
+					AddRow(1, "e1", "user", "u1", "user.created", []byte(`{}`), now, 0, ""))
+				m.ExpectExec(markPublished).WithArgs(1).WillReturnError(errors.New("db down"))
+				m.ExpectRollback()
+			},
+			expectSent: []string{"e1"},
+			expectErr:  errcode.ErrDatabaseError,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			sink := &fakeSink{fail: tc.fail}
+			svc, mock := setupOutboxService(t, sink)
+			tc.setupDB(mock)
+
+			published, err := svc.PublishPending(context.Background(), 10)
+			if tc.expectErr != nil {
+				require.ErrorIs(t, err, tc.expectErr)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, tc.expectPublished, published)
+			require.Equal(t, tc.expectSent, sink.published)
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestOutboxService_PrunePublished(t *testing.T) {
+	svc, mock := setupOutboxService(t, &fakeSink{})
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM outbox WHERE published_at < $1")).
+		WithArgs(sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 4))
+
+	pruned, err := svc.PrunePublished(context.Background(), time.Hour)
+	require.NoError(t, err)
+	require.Equal(t, int64(4), pruned)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
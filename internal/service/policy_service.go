@@ -0,0 +1,238 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"go-starter-template/internal/constant"
+	"go-starter-template/internal/dto"
+	"go-starter-template/internal/model"
+	"go-starter-template/internal/repository"
+	"go-starter-template/internal/utils/errcode"
+	"go-starter-template/internal/utils/expr"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// resourceTypeUser is the resource type whose attributes are loaded from the users table.
+const resourceTypeUser = "user"
+
+type PolicyService struct {
+	policyRepository *repository.PolicyRepository
+	userRepository   *repository.UserRepository
+	log              *logrus.Logger
+	tracer           trace.Tracer
+	programs         sync.Map
+	now              func() time.Time
+}
+
+func NewPolicyService(policyRepository *repository.PolicyRepository, userRepository *repository.UserRepository, log *logrus.Logger) *PolicyService {
+	return &PolicyService{policyRepository: policyRepository, userRepository: userRepository, log: log, tracer: otel.Tracer("PolicyService"), now: time.Now}
+}
+
+// Authorize evaluates the policies for action and returns errcode.ErrForbidden
+// when the subject is not allowed to perform it on resource.
+func (s *PolicyService) Authorize(ctx context.Context, subjectUUID, action string, resource dto.AuthzResource, requestAttributes map[string]interface{}) error {
+	spanCtx, span := s.tracer.Start(ctx, "PolicyService.Authorize")
+	defer span.End()
+
+	decision, err := s.Check(spanCtx, subjectUUID, &dto.AuthzCheckRequest{Action: action, Resource: resource}, requestAttributes)
+	if err != nil {
+		return err
+	}
+	if !decision.Allowed {
+		s.log.WithContext(spanCtx).WithField("action", action).WithField("reason", decision.Reason).Warn("authorization denied")
+		return errcode.ErrForbidden
+	}
+	return nil
+}
+
+// Check evaluates every policy applicable to req.Action and returns the
+// decision together with a per-policy trace. The matching policy with the
+// highest priority decides, a deny winning over an allow of the same priority,
+// and the default decision is deny. Evaluating it for
+// another subject than the caller requires constant.ActionAuthzCheckOthers,
+// as the trace exposes the subject's attributes.
+func (s *PolicyService) Check(ctx context.Context, subjectUUID string, req *dto.AuthzCheckRequest, requestAttributes map[string]interface{}) (*dto.AuthzDecisionResponse, error) {
+	spanCtx, span := s.tracer.Start(ctx, "PolicyService.Check")
+	defer span.End()
+	span.SetAttributes(attribute.String("authz.action", req.Action))
+	// A revoked policy or role must stop granting access right away, so
+	// decisions never read from a lagging replica
+	spanCtx = repository.WithPrimary(spanCtx)
+
+	logger := s.log.WithContext(spanCtx)
+
+	if req.Subject.UUID != "" && req.Subject.UUID != subjectUUID {
+		if err := s.Authorize(spanCtx, subjectUUID, constant.ActionAuthzCheckOthers, dto.AuthzResource{Type: resourceTypeUser}, requestAttributes); err != nil {
+			return nil, err
+		}
+		subjectUUID = req.Subject.UUID
+	}
+
+	input, err := s.buildInput(spanCtx, subjectUUID, req, requestAttributes)
+	if err != nil {
+		return nil, err
+	}
+
+	policies, err := s.policyRepository.FindByAction(spanCtx, req.Action)
+	if err != nil {
+		logger.WithError(err).Error("failed to load policies")
+		return nil, errcode.ErrPolicyLoadFailed
+	}
+
+	decision := &dto.AuthzDecisionResponse{
+		Action:   req.Action,
+		Effect:   string(constant.PolicyEffectDeny),
+		Reason:   "no policy matched; denied by default",
+		Policies: make([]dto.PolicyEvaluationResponse, 0, len(policies)),
+		Input:    input,
+	}
+
+	// policies come highest priority first
+	var decidedBy *model.Policy
+	for i, policy := range policies {
+		evaluation := dto.PolicyEvaluationResponse{Name: policy.Name, Effect: policy.Effect, Condition: policy.Condition}
+
+		matched, err := s.evaluate(policy, input)
+		if err != nil {
+			logger.WithError(err).WithField("policy", policy.Name).Warn("failed to evaluate policy")
+			evaluation.Error = err.Error()
+			// Fail closed: a broken deny policy still denies, a broken allow policy never allows
+			matched = policy.Effect == string(constant.PolicyEffectDeny)
+		}
+		evaluation.Matched = matched
+		decision.Policies = append(decision.Policies, evaluation)
+
+		if !matched {
+			continue
+		}
+		if decidedBy == nil || (policy.Priority == decidedBy.Priority &&
+			policy.Effect == string(constant.PolicyEffectDeny) && decidedBy.Effect != string(constant.PolicyEffectDeny)) {
+			decidedBy = &policies[i]
+		}
+	}
+
+	if decidedBy != nil {
+		switch constant.PolicyEffect(decidedBy.Effect) {
+		case constant.PolicyEffectAllow:
+			decision.Allowed = true
+			decision.Effect = string(constant.PolicyEffectAllow)
+			decision.Reason = fmt.Sprintf("allowed by policy %q", decidedBy.Name)
+		default:
+			decision.Reason = fmt.Sprintf("denied by policy %q", decidedBy.Name)
+		}
+	}
+	span.SetAttributes(attribute.Bool("authz.allowed", decision.Allowed))
+
+	return decision, nil
+}
+
+// evaluate compiles (with caching) and runs the policy condition against input.
+func (s *PolicyService) evaluate(policy model.Policy, input map[string]interface{}) (bool, error) {
+	if cached, ok := s.programs.Load(policy.Condition); ok {
+		return cached.(*expr.Program).EvalBool(input)
+	}
+	program, err := expr.Compile(policy.Condition)
+	if err != nil {
+		return false, err
+	}
+	s.programs.Store(policy.Condition, program)
+	return program.EvalBool(input)
+}
+
+// buildInput assembles the subject, resource and request attributes exposed to
+// policy conditions. Attributes supplied by the caller only fill in keys the
+// server did not derive itself, so they cannot fake a role or an address.
+func (s *PolicyService) buildInput(ctx context.Context, subjectUUID string, req *dto.AuthzCheckRequest, requestAttributes map[string]interface{}) (map[string]interface{}, error) {
+	logger := s.log.WithContext(ctx)
+
+	subject := map[string]interface{}{"uuid": subjectUUID}
+	if subjectUUID != "" {
+		user := new(model.User)
+		if err := s.userRepository.FindByUUID(ctx, user, subjectUUID); err != nil {
+			logger.WithError(err).Warn("failed to load authorization subject")
+			return nil, errcode.ErrUserNotFound
+		}
+		subject = userAttributes(user)
+	}
+	defaultAttributes(subject, req.Subject.Attributes)
+
+	resource := map[string]interface{}{"type": req.Resource.Type, "uuid": req.Resource.UUID}
+	if req.Resource.Type == resourceTypeUser && req.Resource.UUID != "" {
+		user := new(model.User)
+		if err := s.userRepository.FindByUUID(ctx, user, req.Resource.UUID); err != nil {
+			logger.WithError(err).Warn("failed to load authorization resource")
+			return nil, errcode.ErrAuthzResourceNotFound
+		}
+		mergeAttributes(resource, userAttributes(user))
+	}
+	defaultAttributes(resource, req.Resource.Attributes)
+
+	now := s.now()
+	request := map[string]interface{}{
+		"action":  req.Action,
+		"time":    now.Format(time.RFC3339),
+		"hour":    now.Hour(),
+		"weekday": now.Weekday().String(),
+	}
+	mergeAttributes(request, requestAttributes)
+	defaultAttributes(request, req.Context)
+
+	return map[string]interface{}{
+		"subject":  subject,
+		"resource": resource,
+		"request":  request,
+	}, nil
+}
+
+// userAttributes flattens a user into policy attributes. Permissions include
+// both direct and role-derived permissions.
+func userAttributes(user *model.User) map[string]interface{} {
+	roles := make([]string, 0, len(user.Roles))
+	seen := make(map[string]struct{})
+	permissions := make([]string, 0, len(user.Permissions))
+	addPermission := func(name string) {
+		if _, ok := seen[name]; !ok {
+			seen[name] = struct{}{}
+			permissions = append(permissions, name)
+		}
+	}
+	for _, perm := range user.Permissions {
+		addPermission(perm.Name)
+	}
+	for _, role := range user.Roles {
+		roles = append(roles, role.Name)
+		for _, perm := range role.Permissions {
+			addPermission(perm.Name)
+		}
+	}
+
+	return map[string]interface{}{
+		"uuid":        user.UUID,
+		"name":        user.Name,
+		"email":       user.Email,
+		"roles":       roles,
+		"permissions": permissions,
+		"created_at":  user.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+func mergeAttributes(dst, src map[string]interface{}) {
+	for k, v := range src {
+		dst[k] = v
+	}
+}
+
+// defaultAttributes copies the keys of src that dst does not have yet.
+func defaultAttributes(dst, src map[string]interface{}) {
+	for k, v := range src {
+		if _, ok := dst[k]; !ok {
+			dst[k] = v
+		}
+	}
+}
@@ -0,0 +1,343 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+
+	"go-starter-template/internal/dto"
+	"go-starter-template/internal/repository"
+	"go-starter-template/internal/utils/errcode"
+)
+
+var policyColumns = []string{"uuid", "name", "description", "action", "effect", "condition", "priority", "enabled", "created_at", "updated_at"}
+
+const policyQuery = `FROM policies`
+
+// expectUserWithRole mocks the four FindByUUID queries for a user holding a single role.
+func expectUserWithRole(mock sqlmock.Sqlmock, uuid, role string) {
+	now := time.Now()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT uuid, name, email, password, created_at, updated_at, status, status_reason, suspended_until, phone, version FROM users WHERE uuid = $1`)).
+		WithArgs(uuid).
+		WillReturnRows(sqlmock.NewRows([]string{"uuid", "name", "email", "password", "created_at", "updated_at", "status", "status_reason", "suspended_until", "phone", "version"}).
+			AddRow(uuid, "Name "+uuid, uuid+"@example.com", "hash", now, now, "active", "", nil, nil, 1))
+	mock.ExpectQuery(regexp.QuoteMeta(`INNER JOIN roles r`)).
+		WithArgs(uuid).
+		WillReturnRows(sqlmock.NewRows([]string{"user_uuid", "uuid", "name"}).AddRow(uuid, "r-"+role, role))
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM user_permissions up`)).
+		WithArgs(uuid).
+		WillReturnRows(sqlmock.NewRows([]string{"uuid", "name"}))
+	mock.ExpectQuery(regexp.QuoteMeta(`INNER JOIN role_permissions rp`)).
+		WithArgs(uuid).
+		WillReturnRows(sqlmock.NewRows([]string{"role_uuid", "uuid", "name"}).AddRow("r-"+role, "p1", "update-user"))
+}
+
+func setupPolicyService(t *testing.T) (*PolicyService, sqlmock.Sqlmock, func()) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherRegexp))
+	require.NoError(t, err)
+	svc := NewPolicyService(repository.NewPolicyRepository(db), repository.NewUserRepository(db), silentLogger())
+	// Monday 10:00 UTC
+	svc.now = func() time.Time { return time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC) }
+	return svc, mock, func() { _ = db.Close() }
+}
+
+func TestPolicyService_Check(t *testing.T) {
+	now := time.Now()
+
+	type testcase struct {
+		name      string
+		req       *dto.AuthzCheckRequest
+		setupDB   func(sqlmock.Sqlmock)
+		expectErr error
+		assert    func(t *testing.T, decision *dto.AuthzDecisionResponse)
+	}
+
+	cases := []testcase{
+		{
+			name: "AllowedByMatchingPolicy",
+			req: &dto.AuthzCheckRequest{
+				Action:   "user:update",
+				Subject:  dto.AuthzSubject{Attributes: map[string]interface{}{"department": "sales"}},
+				Resource: dto.AuthzResource{Type: "user", UUID: "target", Attributes: map[string]interface{}{"department": "sales"}},
+			},
+			setupDB: func(mock sqlmock.Sqlmock) {
+				expectUserWithRole(mock, "caller", "manager")
+				expectUserWithRole(mock, "target", "user")
+				mock.ExpectQuery(policyQuery).
+					WithArgs("user:update", "*").
+					WillReturnRows(sqlmock.NewRows(policyColumns).
+						AddRow("p1", "managers-same-department", "", "user:update", "allow",
+							`"manager" in subject.roles && subject.department == resource.department && request.hour >= 9 && request.hour < 17`,
+							0, true, now, now))
+			},
+			assert: func(t *testing.T, decision *dto.AuthzDecisionResponse) {
+				require.True(t, decision.Allowed)
+				require.Equal(t, "allow", decision.Effect)
+				require.Contains(t, decision.Reason, "managers-same-department")
+				require.Len(t, decision.Policies, 1)
+				require.True(t, decision.Policies[0].Matched)
+			},
+		},
+		{
+			name: "HigherPriorityDenyBeatsLowerAllow",
+			req:  &dto.AuthzCheckRequest{Action: "user:update", Context: map[string]interface{}{"channel": "batch"}},
+			setupDB: func(mock sqlmock.Sqlmock) {
+				expectUserWithRole(mock, "caller", "manager")
+				mock.ExpectQuery(policyQuery).
+					WithArgs("user:update", "*").
+					WillReturnRows(sqlmock.NewRows(policyColumns).
+						AddRow("p1", "no-weekends", "", "*", "deny", `request.channel == "batch"`, 10, true, now, now).
+						AddRow("p2", "managers", "", "user:update", "allow", `"manager" in subject.roles`, 0, true, now, now))
+			},
+			assert: func(t *testing.T, decision *dto.AuthzDecisionResponse) {
+				require.False(t, decision.Allowed)
+				require.Equal(t, "deny", decision.Effect)
+				require.Contains(t, decision.Reason, "no-weekends")
+				require.Len(t, decision.Policies, 2)
+			},
+		},
+		{
+			name: "HigherPriorityAllowBeatsLowerDeny",
+			req:  &dto.AuthzCheckRequest{Action: "user:update", Context: map[string]interface{}{"channel": "batch"}},
+			setupDB: func(mock sqlmock.Sqlmock) {
+				expectUserWithRole(mock, "caller", "manager")
+				mock.ExpectQuery(policyQuery).
+					WithArgs("user:update", "*").
+					WillReturnRows(sqlmock.NewRows(policyColumns).
+						AddRow("p2", "managers", "", "user:update", "allow", `"manager" in subject.roles`, 20, true, now, now).
+						AddRow("p1", "no-batch", "", "*", "deny", `request.channel == "batch"`, 10, true, now, now))
+			},
+			assert: func(t *testing.T, decision *dto.AuthzDecisionResponse) {
+				require.True(t, decision.Allowed)
+				require.Equal(t, "allow", decision.Effect)
+				require.Contains(t, decision.Reason, "managers")
+				require.True(t, decision.Policies[1].Matched)
+			},
+		},
+		{
+			name: "DenyWinsAtEqualPriority",
+			req:  &dto.AuthzCheckRequest{Action: "user:update", Context: map[string]interface{}{"channel": "batch"}},
+			setupDB: func(mock sqlmock.Sqlmock) {
+				expectUserWithRole(mock, "caller", "manager")
+				mock.ExpectQuery(policyQuery).
+					WithArgs("user:update", "*").
+					WillReturnRows(sqlmock.NewRows(policyColumns).
+						AddRow("p2", "managers", "", "user:update", "allow", `"manager" in subject.roles`, 10, true, now, now).
+						AddRow("p1", "no-batch", "", "*", "deny", `request.channel == "batch"`, 10, true, now, now))
+			},
+			assert: func(t *testing.T, decision *dto.AuthzDecisionResponse) {
+				require.False(t, decision.Allowed)
+				require.Contains(t, decision.Reason, "no-batch")
+			},
+		},
+		{
+			name: "DefaultDenyWhenNothingMatches",
+			req:  &dto.AuthzCheckRequest{Action: "user:delete"},
+			setupDB: func(mock sqlmock.Sqlmock) {
+				expectUserWithRole(mock, "caller", "user")
+				mock.ExpectQuery(policyQuery).
+					WithArgs("user:delete", "*").
+					WillReturnRows(sqlmock.NewRows(policyColumns).
+						AddRow("p1", "admins", "", "user:delete", "allow", `"admin" in subject.roles`, 0, true, now, now))
+			},
+			assert: func(t *testing.T, decision *dto.AuthzDecisionResponse) {
+				require.False(t, decision.Allowed)
+				require.Contains(t, decision.Reason, "denied by default")
+			},
+		},
+		{
+			name: "BrokenPoliciesFailClosed",
+			req:  &dto.AuthzCheckRequest{Action: "user:update"},
+			setupDB: func(mock sqlmock.Sqlmock) {
+				expectUserWithRole(mock, "caller", "manager")
+				mock.ExpectQuery(policyQuery).
+					WithArgs("user:update", "*").
+					WillReturnRows(sqlmock.NewRows(policyColumns).
+						AddRow("p1", "broken-allow", "", "user:update", "allow", `subject.roles >`, 0, true, now, now).
+						AddRow("p2", "broken-deny", "", "user:update", "deny", `subject.name > 1`, 0, true, now, now))
+			},
+			assert: func(t *testing.T, decision *dto.AuthzDecisionResponse) {
+				require.False(t, decision.Allowed)
+				require.Contains(t, decision.Reason, "broken-deny")
+				require.False(t, decision.Policies[0].Matched)
+				require.NotEmpty(t, decision.Policies[0].Error)
+				require.True(t, decision.Policies[1].Matched)
+				require.NotEmpty(t, decision.Policies[1].Error)
+			},
+		},
+		{
+			name: "CallerAttributesCannotOverrideServerAttributes",
+			req: &dto.AuthzCheckRequest{
+				Action:  "user:update",
+				Subject: dto.AuthzSubject{Attributes: map[string]interface{}{"roles": []interface{}{"admin"}}},
+				Context: map[string]interface{}{"ip": "10.0.0.1", "weekday": "Sunday"},
+			},
+			setupDB: func(mock sqlmock.Sqlmock) {
+				expectUserWithRole(mock, "caller", "user")
+				mock.ExpectQuery(policyQuery).
+					WithArgs("user:update", "*").
+					WillReturnRows(sqlmock.NewRows(policyColumns).
+						AddRow("p1", "admins", "", "*", "allow", `"admin" in subject.roles || request.ip == "10.0.0.1" || request.weekday == "Sunday"`, 0, true, now, now))
+			},
+			assert: func(t *testing.T, decision *dto.AuthzDecisionResponse) {
+				require.False(t, decision.Allowed)
+				request := decision.Input["request"].(map[string]interface{})
+				require.Equal(t, "127.0.0.1", request["ip"])
+				require.Equal(t, "Monday", request["weekday"])
+			},
+		},
+		{
+			name: "SubjectOverrideRequiresPermission",
+			req:  &dto.AuthzCheckRequest{Action: "user:update", Subject: dto.AuthzSubject{UUID: "target"}},
+			setupDB: func(mock sqlmock.Sqlmock) {
+				expectUserWithRole(mock, "caller", "user")
+				mock.ExpectQuery(policyQuery).
+					WithArgs("authz:check_others", "*").
+					WillReturnRows(sqlmock.NewRows(policyColumns).
+						AddRow("p1", "admins-allow-all", "", "*", "allow", `"admin" in subject.roles`, 0, true, now, now))
+			},
+			expectErr: errcode.ErrForbidden,
+		},
+		{
+			name: "SubjectOverride",
+			req:  &dto.AuthzCheckRequest{Action: "user:update", Subject: dto.AuthzSubject{UUID: "target"}},
+			setupDB: func(mock sqlmock.Sqlmock) {
+				expectUserWithRole(mock, "caller", "admin")
+				mock.ExpectQuery(policyQuery).
+					WithArgs("authz:check_others", "*").
+					WillReturnRows(sqlmock.NewRows(policyColumns).
+						AddRow("p1", "admins-allow-all", "", "*", "allow", `"admin" in subject.roles`, 0, true, now, now))
+				expectUserWithRole(mock, "target", "user")
+				mock.ExpectQuery(policyQuery).
+					WithArgs("user:update", "*").
+					WillReturnRows(sqlmock.NewRows(policyColumns))
+			},
+			assert: func(t *testing.T, decision *dto.AuthzDecisionResponse) {
+				subject := decision.Input["subject"].(map[string]interface{})
+				require.Equal(t, "target", subject["uuid"])
+			},
+		},
+		{
+			name: "SubjectOverrideNotFound",
+			req:  &dto.AuthzCheckRequest{Action: "user:update", Subject: dto.AuthzSubject{UUID: "ghost"}},
+			setupDB: func(mock sqlmock.Sqlmock) {
+				expectUserWithRole(mock, "caller", "admin")
+				mock.ExpectQuery(policyQuery).
+					WithArgs("authz:check_others", "*").
+					WillReturnRows(sqlmock.NewRows(policyColumns).
+						AddRow("p1", "admins-allow-all", "", "*", "allow", `"admin" in subject.roles`, 0, true, now, now))
+				mock.ExpectQuery(regexp.QuoteMeta(`FROM users WHERE uuid = $1`)).
+					WithArgs("ghost").
+					WillReturnError(errors.New("no rows"))
+			},
+			expectErr: errcode.ErrUserNotFound,
+		},
+		{
+			name: "ResourceNotFound",
+			req:  &dto.AuthzCheckRequest{Action: "user:update", Resource: dto.AuthzResource{Type: "user", UUID: "missing"}},
+			setupDB: func(mock sqlmock.Sqlmock) {
+				expectUserWithRole(mock, "caller", "manager")
+				mock.ExpectQuery(regexp.QuoteMeta(`FROM users WHERE uuid = $1`)).
+					WithArgs("missing").
+					WillReturnError(errors.New("no rows"))
+			},
+			expectErr: errcode.ErrAuthzResourceNotFound,
+		},
+		{
+			name: "PolicyLoadError",
+			req:  &dto.AuthzCheckRequest{Action: "user:update"},
+			setupDB: func(mock sqlmock.Sqlmock) {
+				expectUserWithRole(mock, "caller", "manager")
+				mock.ExpectQuery(policyQuery).
+					WithArgs("user:update", "*").
+					WillReturnError(errors.New("db down"))
+			},
+			expectErr: errcode.ErrPolicyLoadFailed,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			svc, mock, cleanup := setupPolicyService(t)
+			defer cleanup()
+			tc.setupDB(mock)
+
+			decision, err := svc.Check(context.Background(), "caller", tc.req, map[string]interface{}{"ip": "127.0.0.1"})
+			if tc.expectErr != nil {
+				require.ErrorIs(t, err, tc.expectErr)
+			} else {
+				require.NoError(t, err)
+				tc.assert(t, decision)
+			}
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestPolicyService_Authorize(t *testing.T) {
+	now := time.Now()
+
+	cases := []struct {
+		name      string
+		role      string
+		expectErr error
+	}{
+		{name: "Allowed", role: "admin"},
+		{name: "Forbidden", role: "user", expectErr: errcode.ErrForbidden},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			svc, mock, cleanup := setupPolicyService(t)
+			defer cleanup()
+			expectUserWithRole(mock, "caller", tc.role)
+			mock.ExpectQuery(policyQuery).
+				WithArgs("user:delete", "*").
+				WillReturnRows(sqlmock.NewRows(policyColumns).
+					AddRow("p1", "admins", "", "user:delete", "allow", `"admin" in subject.roles`, 0, true, now, now))
+
+			err := svc.Authorize(context.Background(), "caller", "user:delete", dto.AuthzResource{Type: "user"}, nil)
+			if tc.expectErr != nil {
+				require.ErrorIs(t, err, tc.expectErr)
+			} else {
+				require.NoError(t, err)
+			}
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+type policyReplicas struct{ db *sql.DB }
+
+func (r policyReplicas) Reader() *sql.DB { return r.db }
+
+// TestPolicyService_CheckReadsPrimary ensures decisions ignore replicas, so a
+// revoked policy or role stops granting access without waiting for replication.
+func TestPolicyService_CheckReadsPrimary(t *testing.T) {
+	svc, mock, cleanup := setupPolicyService(t)
+	defer cleanup()
+	replica, replicaMock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer replica.Close()
+	svc.userRepository.UseReplicas(policyReplicas{replica})
+	svc.policyRepository.UseReplicas(policyReplicas{replica})
+
+	now := time.Now()
+	expectUserWithRole(mock, "caller", "admin")
+	mock.ExpectQuery(policyQuery).
+		WithArgs("user:update", "*").
+		WillReturnRows(sqlmock.NewRows(policyColumns).
+			AddRow("p1", "admins-allow-all", "", "*", "allow", `"admin" in subject.roles`, 0, true, now, now))
+
+	decision, err := svc.Check(context.Background(), "caller", &dto.AuthzCheckRequest{Action: "user:update"}, nil)
+	require.NoError(t, err)
+	require.True(t, decision.Allowed)
+	require.NoError(t, mock.ExpectationsWereMet())
+	require.NoError(t, replicaMock.ExpectationsWereMet())
+}
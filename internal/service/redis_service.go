@@ -22,6 +22,7 @@ type RedisService struct {
 type redisClient interface {
     Get(ctx context.Context, key string) *redis.StringCmd
     Set(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.StatusCmd
+    Del(ctx context.Context, keys ...string) *redis.IntCmd
 }
 
 func NewRedisService(client redisClient, logger *logrus.Logger) *RedisService {
@@ -71,3 +72,16 @@ func (r *RedisService) Set(ctx context.Context, key string, data interface{}, tt
 
 	return string(json), nil
 }
+
+// Delete removes keys from Redis, e.g. to invalidate cached responses.
+func (r *RedisService) Delete(ctx context.Context, keys ...string) error {
+	spanCtx, span := r.tracer.Start(ctx, "RedisService.Delete")
+	defer span.End()
+
+	if err := r.client.Del(spanCtx, keys...).Err(); err != nil {
+		r.logger.WithContext(spanCtx).WithError(err).Error("Failed to delete data from redis")
+		return err
+	}
+
+	return nil
+}
@@ -16,12 +16,14 @@ import (
 type fakeRedisClient struct {
     getFunc func(ctx context.Context, key string) *redis.StringCmd
     setFunc func(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.StatusCmd
+    delFunc func(ctx context.Context, keys ...string) *redis.IntCmd
 }
 
 func (f *fakeRedisClient) Get(ctx context.Context, key string) *redis.StringCmd { return f.getFunc(ctx, key) }
 func (f *fakeRedisClient) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.StatusCmd {
     return f.setFunc(ctx, key, value, expiration)
 }
+func (f *fakeRedisClient) Del(ctx context.Context, keys ...string) *redis.IntCmd { return f.delFunc(ctx, keys...) }
 
 func silentLogger() *logrus.Logger {
     l := logrus.New()
@@ -166,4 +168,39 @@ func TestRedisService_Set(t *testing.T) {
             c.assert(t, val, err)
         })
     }
-}
\ No newline at end of file
+}
+func TestRedisService_Delete(t *testing.T) {
+    logger := silentLogger()
+
+    cases := []struct {
+        name      string
+        err       error
+        expectErr bool
+    }{
+        {name: "Success"},
+        {name: "RedisError", err: errors.New("redis down"), expectErr: true},
+    }
+
+    for _, c := range cases {
+        t.Run(c.name, func(t *testing.T) {
+            var deleted []string
+            client := &fakeRedisClient{delFunc: func(ctx context.Context, keys ...string) *redis.IntCmd {
+                deleted = keys
+                cmd := redis.NewIntCmd(ctx)
+                if c.err != nil {
+                    cmd.SetErr(c.err)
+                } else {
+                    cmd.SetVal(int64(len(keys)))
+                }
+                return cmd
+            }}
+            err := NewRedisService(client, logger).Delete(context.Background(), "user:me:u1")
+            if c.expectErr {
+                require.Error(t, err)
+            } else {
+                require.NoError(t, err)
+            }
+            require.Equal(t, []string{"user:me:u1"}, deleted)
+        })
+    }
+}
@@ -2,14 +2,26 @@ package service
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"go-starter-template/internal/audit"
+	"go-starter-template/internal/constant"
 	"go-starter-template/internal/dto"
 	"go-starter-template/internal/dto/converter"
+	"go-starter-template/internal/event"
 	"go-starter-template/internal/model"
+	"go-starter-template/internal/notify"
 	"go-starter-template/internal/repository"
 	"go-starter-template/internal/utils/errcode"
+	"go-starter-template/internal/utils/highlight"
+	"io"
+	"strings"
 	"time"
 
+	"github.com/goccy/go-json"
+	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
 	"go.opentelemetry.io/otel"
@@ -19,14 +31,50 @@ import (
 
 type UserService struct {
     userRepository *repository.UserRepository
+    // outboxRepository records the events of every change in its transaction
+    outboxRepository *repository.OutboxRepository
+    // auditService records who changed what in the same transaction
+    auditService   *AuditService
     redisService   *RedisService
     log            *logrus.Logger
     tracer         trace.Tracer
+    uow            *repository.UnitOfWork
     hashPassword   func(password []byte, cost int) ([]byte, error)
+    // notifier sends the token confirming a new email address
+    notifier notify.Notifier
+    // blacklistService revokes the tokens of a session whose account is deleted
+    blacklistService *BlacklistService
 }
 
-func NewUserService(userRepository *repository.UserRepository, redisService *RedisService, logrus *logrus.Logger) *UserService {
-    return &UserService{userRepository: userRepository, redisService: redisService, log: logrus, tracer: otel.Tracer("UserService"), hashPassword: bcrypt.GenerateFromPassword}
+// emailVerificationTTL is how long a pending email change can be confirmed.
+const emailVerificationTTL = 24 * time.Hour
+
+// pendingEmailChange is stored in Redis under emailVerificationKey(token).
+type pendingEmailChange struct {
+	UUID  string `json:"uuid"`
+	Email string `json:"email"`
+}
+
+func NewUserService(userRepository *repository.UserRepository, outboxRepository *repository.OutboxRepository, auditService *AuditService, redisService *RedisService, logrus *logrus.Logger, uow *repository.UnitOfWork) *UserService {
+    return &UserService{userRepository: userRepository, outboxRepository: outboxRepository, auditService: auditService, redisService: redisService, log: logrus, tracer: otel.Tracer("UserService"), uow: uow, hashPassword: bcrypt.GenerateFromPassword, notifier: notify.NewLogNotifier(logrus)}
+}
+
+// UseNotifier replaces the default notifier, which only logs.
+func (s *UserService) UseNotifier(notifier notify.Notifier) {
+    s.notifier = notifier
+}
+
+// UseBlacklist lets DeleteMe revoke the tokens of the deleting session.
+func (s *UserService) UseBlacklist(blacklistService *BlacklistService) {
+    s.blacklistService = blacklistService
+}
+
+func userCacheKey(uuid string) string {
+    return fmt.Sprintf("user:me:%s", uuid)
+}
+
+func emailVerificationKey(token string) string {
+    return fmt.Sprintf("user:email-verification:%s", token)
 }
 
 // GetUser retrieves a user by UUID.
@@ -35,7 +83,7 @@ func (s *UserService) GetUser(ctx context.Context, uuid string) (result string,
 	defer span.End()
 
 	logger := s.log.WithContext(spanCtx)
-	cacheKey := fmt.Sprintf("user:me:%s", uuid)
+	cacheKey := userCacheKey(uuid)
 
 	cachedResponse, found := s.redisService.Get(spanCtx, cacheKey)
 	if found {
@@ -43,8 +91,10 @@ func (s *UserService) GetUser(ctx context.Context, uuid string) (result string,
 		return cachedResponse, nil
 	}
 
+	// Fill the cache from the primary: a lagging replica would cache a stale
+	// profile for the whole TTL right after an update invalidated it
 	user := new(model.User)
-	if err = s.userRepository.FindByUUID(spanCtx, user, uuid); err != nil {
+	if err = s.userRepository.FindByUUID(repository.WithPrimary(spanCtx), user, uuid); err != nil {
 		logger.WithError(err).Warn("failed to find user by UUID")
 		return "", errcode.ErrUserNotFound
 	}
@@ -59,26 +109,80 @@ func (s *UserService) GetUser(ctx context.Context, uuid string) (result string,
 	return
 }
 
-// Search retrieves users based on search criteria.
-func (s *UserService) Search(ctx context.Context, request *dto.SearchUserRequest) ([]*dto.UserResponse, int64, error) {
+// Search retrieves users based on search criteria, using keyset pagination
+// when the request carries a cursor or limit and OFFSET pages otherwise.
+func (s *UserService) Search(ctx context.Context, request *dto.SearchUserRequest) ([]*dto.UserResponse, *dto.PageMetadata, error) {
 	spanCtx, span := s.tracer.Start(ctx, "UserService.Search")
 	defer span.End()
 
-	users, total, err := s.userRepository.Search(spanCtx, request)
-
-	if err != nil {
+	var (
+		users  []*model.User
+		total  int64
+		paging = new(dto.PageMetadata)
+		err    error
+	)
+	if request.CursorMode() {
+		var page *repository.UserCursorPage
+		if page, err = s.userRepository.SearchByCursor(spanCtx, request); err == nil {
+			users, total = page.Users, page.Total
+			paging.Size, paging.Next, paging.Prev = request.Limit, page.Next, page.Prev
+		}
+	} else {
+		if users, total, err = s.userRepository.Search(spanCtx, request); err == nil {
+			paging.Page, paging.Size = request.Page, request.Size
+			if !request.SkipCount {
+				totalPage := (total + int64(request.Size) - 1) / int64(request.Size)
+				paging.TotalPage = &totalPage
+			}
+		}
+	}
+	if err == nil && (request.Includes("roles") || request.Includes("permissions")) {
+		err = s.userRepository.LoadRelations(spanCtx, users, repository.Include{Roles: request.Includes("roles"), Permissions: request.Includes("permissions")})
+	}
+	switch {
+	case errors.Is(err, repository.ErrInvalidSort):
+		return nil, nil, errcode.ErrInvalidSort
+	case errors.Is(err, repository.ErrInvalidFilter):
+		return nil, nil, errcode.ErrInvalidFilter
+	case errors.Is(err, repository.ErrInvalidCursor):
+		return nil, nil, errcode.ErrInvalidCursor
+	case err != nil:
 		s.log.WithContext(spanCtx).WithError(err).Error("Error retrieving users")
-		return nil, 0, errcode.ErrUserSearchFailed
+		return nil, nil, errcode.ErrUserSearchFailed
+	}
+	if !request.SkipCount {
+		paging.TotalItem = &total
 	}
 
 	_, convertSpan := s.tracer.Start(spanCtx, "ConvertUsersToDTO")
 	responses := make([]*dto.UserResponse, len(users))
+	terms := highlight.Terms(request.Q)
 	for i, user := range users {
 		responses[i] = converter.UserToResponse(user)
+		responses[i].Highlight = highlightUser(user, terms)
 	}
 	convertSpan.End()
 
-	return responses, total, nil
+	return responses, paging, nil
+}
+
+// highlightUser marks the query terms in a search result's name and email,
+// returning nil when there is nothing to highlight.
+func highlightUser(user *model.User, terms []string) map[string]string {
+	if len(terms) == 0 {
+		return nil
+	}
+	marked := map[string]string{}
+	if name := highlight.Mark(user.Name, terms); name != "" {
+		marked["name"] = name
+	}
+	if email := highlight.Mark(user.Email, terms); email != "" {
+		marked["email"] = email
+	}
+	if len(marked) == 0 {
+		return nil
+	}
+	return marked
 }
 
 // CreateUser creates a new user.
@@ -107,18 +211,28 @@ func (s *UserService) CreateUser(ctx context.Context, request *dto.CreateUserReq
 		return nil, errcode.ErrPasswordEncryption
 	}
 
-	// Create user entity
+	// Create user entity, status and version as the column defaults set them
 	user := &model.User{
 		UUID:     uuid.New().String(),
 		Name:     request.Name,
 		Email:    request.Email,
 		Password: string(hashedPassword),
+		Status:   string(constant.UserStatusActive),
+		Version:  1,
 	}
 
 	// Create user
-	if err := s.userRepository.Create(spanCtx, user); err != nil {
-		logger.WithError(err).Error("Failed to create user")
-		return nil, errcode.ErrInternalServerError
+	if err := s.inTx(spanCtx, func(txCtx context.Context) error {
+		if err := s.userRepository.Create(txCtx, user); err != nil {
+			logger.WithError(err).Error("Failed to create user")
+			return errcode.ErrInternalServerError
+		}
+		if err := s.publish(txCtx, event.UserCreated{UserEvent: event.ForUser(user)}); err != nil {
+			return err
+		}
+		return s.recordAudit(txCtx, event.TypeUserCreated, nil, user, nil)
+	}); err != nil {
+		return nil, err
 	}
 
 	// Convert to response
@@ -126,8 +240,9 @@ func (s *UserService) CreateUser(ctx context.Context, request *dto.CreateUserReq
 	return response, nil
 }
 
-// UpdateUser updates an existing user.
-func (s *UserService) UpdateUser(ctx context.Context, uuid string, request *dto.UpdateUserRequest) (*dto.UserResponse, error) {
+// UpdateUser updates an existing user. version is the one the client last saw
+// (from If-Match); 0 skips the check.
+func (s *UserService) UpdateUser(ctx context.Context, uuid string, version int64, request *dto.UpdateUserRequest) (*dto.UserResponse, error) {
 	spanCtx, span := s.tracer.Start(ctx, "UserService.UpdateUser")
 	defer span.End()
 
@@ -138,6 +253,9 @@ func (s *UserService) UpdateUser(ctx context.Context, uuid string, request *dto.
 		logger.WithError(err).Warn("Failed to find user by UUID")
 		return nil, errcode.ErrUserNotFound
 	}
+	if err := checkVersion(user, version); err != nil {
+		return nil, err
+	}
 
 	// Check if email already exists (if email is changed)
 	if user.Email != request.Email {
@@ -153,22 +271,137 @@ func (s *UserService) UpdateUser(ctx context.Context, uuid string, request *dto.
 	}
 
 	// Update user fields
+	before := *user
+	var changed []string
+	if user.Name != request.Name {
+		changed = append(changed, "name")
+	}
+	if user.Email != request.Email {
+		changed = append(changed, "email")
+	}
 	user.Name = request.Name
 	user.Email = request.Email
 
 	// Update user
-	if err := s.userRepository.Update(spanCtx, user); err != nil {
-		logger.WithError(err).Error("Failed to update user")
-		return nil, errcode.ErrInternalServerError
+	if err := s.updateUserWithEvent(spanCtx, &before, user, changed); err != nil {
+		return nil, err
 	}
+	s.invalidateUserCache(spanCtx, uuid)
 
 	// Convert to response
 	response := converter.UserToResponse(user)
 	return response, nil
 }
 
-// DeleteUser deletes a user by UUID.
-func (s *UserService) DeleteUser(ctx context.Context, uuid string) error {
+// PatchUser applies a JSON Merge Patch to a user. Only members present in the
+// patch are written; a null phone clears it while name and email are never
+// cleared (validation rejects null for them). version works as in UpdateUser.
+func (s *UserService) PatchUser(ctx context.Context, uuid string, version int64, request *dto.PatchUserRequest) (*dto.UserResponse, error) {
+	spanCtx, span := s.tracer.Start(ctx, "UserService.PatchUser")
+	defer span.End()
+
+	logger := s.log.WithContext(spanCtx)
+	user := new(model.User)
+	if err := s.userRepository.FindByUUID(spanCtx, user, uuid); err != nil {
+		logger.WithError(err).Warn("Failed to find user by UUID")
+		return nil, errcode.ErrUserNotFound
+	}
+	if err := checkVersion(user, version); err != nil {
+		return nil, err
+	}
+
+	before := *user
+	var columns []string
+	if request.Name != nil && *request.Name != user.Name {
+		user.Name = *request.Name
+		columns = append(columns, "name")
+	}
+	if request.Email != nil && *request.Email != user.Email {
+		count, err := s.userRepository.CountByEmail(spanCtx, *request.Email)
+		if err != nil {
+			logger.WithError(err).Error("Failed to check email existence")
+			return nil, errcode.ErrInternalServerError
+		}
+		if count > 0 {
+			return nil, errcode.ErrUserAlreadyExists
+		}
+		user.Email = *request.Email
+		columns = append(columns, "email")
+	}
+	if request.Has("phone") {
+		user.Phone = request.Phone
+		columns = append(columns, "phone")
+	}
+
+	// An empty patch (or one matching the current state) is a no-op
+	if len(columns) == 0 {
+		return converter.UserToResponse(user), nil
+	}
+
+	if err := s.updateUserWithEvent(spanCtx, &before, user, columns, columns...); err != nil {
+		return nil, err
+	}
+	s.invalidateUserCache(spanCtx, uuid)
+
+	return converter.UserToResponse(user), nil
+}
+
+// FindUser returns a user straight from the database, bypassing the profile
+// cache so the version (and thus the ETag) is current.
+func (s *UserService) FindUser(ctx context.Context, uuid string) (*dto.UserResponse, error) {
+	spanCtx, span := s.tracer.Start(ctx, "UserService.FindUser")
+	defer span.End()
+
+	user := new(model.User)
+	if err := s.userRepository.FindByUUID(spanCtx, user, uuid); err != nil {
+		s.log.WithContext(spanCtx).WithError(err).Warn("Failed to find user by UUID")
+		return nil, errcode.ErrUserNotFound
+	}
+	return converter.UserToResponse(user), nil
+}
+
+// checkVersion fails with ErrPreconditionFailed when the client's version is
+// not the stored one. Version 0 (If-Match: *) matches any version.
+func checkVersion(user *model.User, version int64) error {
+	if version != 0 && user.Version != version {
+		return errcode.ErrPreconditionFailed
+	}
+	return nil
+}
+
+// updateUser persists user, reporting a concurrent modification between load
+// and write as ErrPreconditionFailed.
+func (s *UserService) updateUser(ctx context.Context, user *model.User, columns ...string) error {
+	err := s.userRepository.Update(ctx, user, columns...)
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, repository.ErrStaleVersion):
+		s.log.WithContext(ctx).WithField("uuid", user.UUID).Warn("User was modified concurrently")
+		return errcode.ErrPreconditionFailed
+	default:
+		s.log.WithContext(ctx).WithError(err).Error("Failed to update user")
+		return errcode.ErrInternalServerError
+	}
+}
+
+// updateUserWithEvent persists user like updateUser and records a
+// UserUpdated event naming the changed fields in the same transaction, and
+// audits the change from before.
+func (s *UserService) updateUserWithEvent(ctx context.Context, before, user *model.User, changed []string, columns ...string) error {
+	return s.inTx(ctx, func(txCtx context.Context) error {
+		if err := s.updateUser(txCtx, user, columns...); err != nil {
+			return err
+		}
+		if err := s.publish(txCtx, event.UserUpdated{UserEvent: event.ForUser(user), Changed: changed}); err != nil {
+			return err
+		}
+		return s.recordAudit(txCtx, event.TypeUserUpdated, before, user, nil)
+	})
+}
+
+// DeleteUser soft-deletes a user by UUID. version works as in UpdateUser.
+func (s *UserService) DeleteUser(ctx context.Context, uuid string, version int64) error {
 	spanCtx, span := s.tracer.Start(ctx, "UserService.DeleteUser")
 	defer span.End()
 
@@ -179,12 +412,697 @@ func (s *UserService) DeleteUser(ctx context.Context, uuid string) error {
 		logger.WithError(err).Warn("Failed to find user by UUID")
 		return errcode.ErrUserNotFound
 	}
+	if err := checkVersion(user, version); err != nil {
+		return err
+	}
 
 	// Delete user
-	if err := s.userRepository.Delete(spanCtx, user); err != nil {
-		logger.WithError(err).Error("Failed to delete user")
+	if err := s.deleteUser(spanCtx, user); err != nil {
+		return err
+	}
+	s.invalidateUserCache(spanCtx, uuid)
+
+	return nil
+}
+
+// deleteUser soft-deletes user and records a UserDeleted event with it.
+func (s *UserService) deleteUser(ctx context.Context, user *model.User) error {
+	return s.inTx(ctx, func(txCtx context.Context) error {
+		if err := s.userRepository.Delete(txCtx, user); err != nil {
+			if errors.Is(err, repository.ErrStaleVersion) {
+				s.log.WithContext(txCtx).WithField("uuid", user.UUID).Warn("User was modified concurrently")
+				return errcode.ErrPreconditionFailed
+			}
+			s.log.WithContext(txCtx).WithError(err).Error("Failed to delete user")
+			return errcode.ErrInternalServerError
+		}
+		if err := s.publish(txCtx, event.UserDeleted{UserEvent: event.ForUser(user)}); err != nil {
+			return err
+		}
+		return s.recordAudit(txCtx, event.TypeUserDeleted, user, nil, nil)
+	})
+}
+
+// BulkUsers applies a batch of create/update/delete operations in order and
+// reports each outcome. When atomic, all operations share one transaction and
+// a single failure rolls back the whole batch; otherwise each runs in its own
+// transaction so failures only affect their item. Operations that failed
+// decoding or validation (Err or Errors set) are never run, and in atomic
+// mode they abort the batch before it touches the database.
+func (s *UserService) BulkUsers(ctx context.Context, ops []*dto.BulkUserOperation, atomic bool) (*dto.BulkUserResponse, error) {
+	spanCtx, span := s.tracer.Start(ctx, "UserService.BulkUsers")
+	defer span.End()
+
+	response := &dto.BulkUserResponse{Mode: constant.BulkModeBestEffort, Results: make([]dto.BulkUserResult, len(ops))}
+	invalid := false
+	for i, op := range ops {
+		response.Results[i] = dto.BulkUserResult{Index: i, Line: op.Line, Op: op.Op}
+		switch {
+		case op.Errors != nil:
+			response.Results[i].Status, response.Results[i].Error, response.Results[i].Errors = fiber.StatusBadRequest, "Validation failed", op.Errors
+			invalid = true
+		case op.Err != nil:
+			setBulkError(&response.Results[i], op.Err)
+			invalid = true
+		}
+	}
+
+	if atomic {
+		response.Mode = constant.BulkModeAtomic
+		failed := -1
+		err := errcode.ErrBulkAborted
+		if !invalid {
+			err = s.uow.Do(spanCtx, func(txCtx context.Context) error {
+				for i, op := range ops {
+					if err := s.runBulkOperation(txCtx, op, &response.Results[i]); err != nil {
+						failed = i
+						return err
+					}
+				}
+				return nil
+			})
+		}
+		if err != nil {
+			for i := range ops {
+				if i != failed && ops[i].Err == nil && ops[i].Errors == nil {
+					response.Results[i] = dto.BulkUserResult{Index: i, Line: ops[i].Line, Op: ops[i].Op}
+					setBulkError(&response.Results[i], errcode.ErrBulkAborted)
+				}
+			}
+			if !invalid && failed < 0 {
+				s.log.WithContext(spanCtx).WithError(err).Error("Bulk transaction failed")
+				return nil, errcode.ErrDatabaseTransaction
+			}
+		}
+	} else {
+		for i, op := range ops {
+			if op.Err != nil || op.Errors != nil {
+				continue
+			}
+			err := s.uow.Do(spanCtx, func(txCtx context.Context) error {
+				return s.runBulkOperation(txCtx, op, &response.Results[i])
+			})
+			if err != nil && response.Results[i].Error == "" {
+				// The operation succeeded but its transaction did not
+				s.log.WithContext(spanCtx).WithError(err).Error("Bulk operation transaction failed")
+				response.Results[i].Data = nil
+				setBulkError(&response.Results[i], errcode.ErrDatabaseTransaction)
+			}
+		}
+	}
+
+	countBulkResults(response)
+	return response, nil
+}
+
+// countBulkResults tallies the succeeded and failed results of response.
+func countBulkResults(response *dto.BulkUserResponse) {
+	response.Succeeded, response.Failed = 0, 0
+	for _, result := range response.Results {
+		if result.Status < fiber.StatusMultipleChoices {
+			response.Succeeded++
+		} else {
+			response.Failed++
+		}
+	}
+}
+
+// ImportUsers creates users from imported rows (create operations) the same
+// way BulkUsers does. A dry run only validates the rows and checks their
+// emails against existing users and earlier rows, reporting what a real
+// import would do without writing anything.
+func (s *UserService) ImportUsers(ctx context.Context, ops []*dto.BulkUserOperation, atomic, dryRun bool) (*dto.BulkUserResponse, error) {
+	spanCtx, span := s.tracer.Start(ctx, "UserService.ImportUsers")
+	defer span.End()
+
+	if !dryRun {
+		return s.BulkUsers(spanCtx, ops, atomic)
+	}
+
+	response := &dto.BulkUserResponse{Mode: constant.BulkModeBestEffort, DryRun: true, Results: make([]dto.BulkUserResult, len(ops))}
+	if atomic {
+		response.Mode = constant.BulkModeAtomic
+	}
+	seen := map[string]bool{}
+	for i, op := range ops {
+		result := &response.Results[i]
+		*result = dto.BulkUserResult{Index: i, Line: op.Line, Op: op.Op}
+		switch {
+		case op.Errors != nil:
+			result.Status, result.Error, result.Errors = fiber.StatusBadRequest, "Validation failed", op.Errors
+			continue
+		case op.Err != nil:
+			setBulkError(result, op.Err)
+			continue
+		case op.Op != constant.BulkOperationCreate:
+			setBulkError(result, errcode.ErrInvalidBulkOperation)
+			continue
+		}
+
+		email := strings.ToLower(op.Create.Email)
+		count, err := s.userRepository.CountByEmail(spanCtx, op.Create.Email)
+		switch {
+		case err != nil:
+			s.log.WithContext(spanCtx).WithError(err).Error("Failed to check email existence")
+			setBulkError(result, errcode.ErrInternalServerError)
+		case count > 0 || seen[email]:
+			setBulkError(result, errcode.ErrUserAlreadyExists)
+		default:
+			result.Status = fiber.StatusCreated
+		}
+		seen[email] = true
+	}
+
+	if atomic {
+		failed := false
+		for _, result := range response.Results {
+			failed = failed || result.Status >= fiber.StatusMultipleChoices
+		}
+		for i := range response.Results {
+			if failed && response.Results[i].Status < fiber.StatusMultipleChoices {
+				setBulkError(&response.Results[i], errcode.ErrBulkAborted)
+			}
+		}
+	}
+	countBulkResults(response)
+	return response, nil
+}
+
+// UserExport streams users for an export as responses. It must be closed.
+type UserExport struct {
+	stream *repository.UserStream
+}
+
+// Next returns the following user, or io.EOF once all have been read.
+func (e *UserExport) Next() (*dto.UserResponse, error) {
+	user := new(model.User)
+	ok, err := e.stream.Next(user)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, io.EOF
+	}
+	return converter.UserToResponse(user), nil
+}
+
+func (e *UserExport) Close() error {
+	return e.stream.Close()
+}
+
+// ExportUsers opens a stream over every user matching request's filters and
+// sort. Paging parameters are ignored.
+func (s *UserService) ExportUsers(ctx context.Context, request *dto.SearchUserRequest) (*UserExport, error) {
+	spanCtx, span := s.tracer.Start(ctx, "UserService.ExportUsers")
+	defer span.End()
+
+	stream, err := s.userRepository.StreamUsers(spanCtx, request)
+	switch {
+	case errors.Is(err, repository.ErrInvalidSort):
+		return nil, errcode.ErrInvalidSort
+	case errors.Is(err, repository.ErrInvalidFilter):
+		return nil, errcode.ErrInvalidFilter
+	case err != nil:
+		s.log.WithContext(spanCtx).WithError(err).Error("Error exporting users")
+		return nil, errcode.ErrUserSearchFailed
+	}
+	return &UserExport{stream: stream}, nil
+}
+
+// runBulkOperation executes one operation through the regular single-user
+// methods, recording the outcome in result.
+func (s *UserService) runBulkOperation(ctx context.Context, op *dto.BulkUserOperation, result *dto.BulkUserResult) error {
+	var (
+		user   *dto.UserResponse
+		status = fiber.StatusOK
+		err    error
+	)
+	switch op.Op {
+	case constant.BulkOperationCreate:
+		user, err = s.CreateUser(ctx, op.Create)
+		status = fiber.StatusCreated
+	case constant.BulkOperationUpdate:
+		user, err = s.UpdateUser(ctx, op.UUID, op.Version, op.Update)
+	case constant.BulkOperationDelete:
+		err = s.DeleteUser(ctx, op.UUID, op.Version)
+	default:
+		err = errcode.ErrInvalidBulkOperation
+	}
+	if err != nil {
+		setBulkError(result, err)
+		return err
+	}
+	result.Status, result.Data = status, user
+	return nil
+}
+
+// setBulkError records err on result with the status it maps to.
+func setBulkError(result *dto.BulkUserResult, err error) {
+	status, ok := errcode.GetHTTPStatus(err)
+	if !ok {
+		status = fiber.StatusInternalServerError
+	}
+	result.Status, result.Error = status, err.Error()
+}
+
+// RestoreUser restores a soft-deleted user, unless its email has been taken by another user since.
+func (s *UserService) RestoreUser(ctx context.Context, uuid string) (*dto.UserResponse, error) {
+	spanCtx, span := s.tracer.Start(ctx, "UserService.RestoreUser")
+	defer span.End()
+
+	logger := s.log.WithContext(spanCtx)
+	user := new(model.User)
+	if err := s.uow.Do(spanCtx, func(txCtx context.Context) error {
+		if err := s.userRepository.FindDeletedByUUID(txCtx, user, uuid); err != nil {
+			logger.WithError(err).Warn("Failed to find deleted user by UUID")
+			return errcode.ErrUserNotFound
+		}
+
+		count, err := s.userRepository.CountByEmail(txCtx, user.Email)
+		if err != nil {
+			logger.WithError(err).Error("Failed to check email existence")
+			return errcode.ErrInternalServerError
+		}
+		if count > 0 {
+			logger.Warn("Attempt to restore a user whose email is taken")
+			return errcode.ErrUserAlreadyExists
+		}
+
+		if err := s.userRepository.Restore(txCtx, user); err != nil {
+			logger.WithError(err).Error("Failed to restore user")
+			return errcode.ErrInternalServerError
+		}
+		user.DeletedAt = nil
+		if err := s.publish(txCtx, event.UserRestored{UserEvent: event.ForUser(user)}); err != nil {
+			return err
+		}
+		return s.recordAudit(txCtx, event.TypeUserRestored, nil, user, nil)
+	}); err != nil {
+		return nil, err
+	}
+
+	return converter.UserToResponse(user), nil
+}
+
+// PurgeDeletedUsers hard-deletes users that were soft-deleted longer than retention ago.
+func (s *UserService) PurgeDeletedUsers(ctx context.Context, retention time.Duration) (int64, error) {
+	spanCtx, span := s.tracer.Start(ctx, "UserService.PurgeDeletedUsers")
+	defer span.End()
+
+	var purged int64
+	if err := s.uow.Do(spanCtx, func(txCtx context.Context) error {
+		var err error
+		if purged, err = s.userRepository.PurgeDeleted(txCtx, time.Now().Add(-retention)); err != nil || purged == 0 {
+			return err
+		}
+		return s.auditService.Record(txCtx, AuditRecord{
+			Action:   audit.ActionUserPurged,
+			Metadata: map[string]any{"count": purged, "retention_seconds": int64(retention.Seconds())},
+		})
+	}); err != nil {
+		s.log.WithContext(spanCtx).WithError(err).Error("Failed to purge deleted users")
+		return 0, errcode.ErrDatabaseError
+	}
+
+	return purged, nil
+}
+
+// UpdateMe applies the caller's own profile changes. A name change is saved
+// immediately; an email change is only recorded as pending and a verification
+// token is sent to the new address, see VerifyEmail.
+func (s *UserService) UpdateMe(ctx context.Context, uuid string, request *dto.UpdateMeRequest) (*dto.UserResponse, error) {
+	spanCtx, span := s.tracer.Start(ctx, "UserService.UpdateMe")
+	defer span.End()
+
+	logger := s.log.WithContext(spanCtx)
+	user := new(model.User)
+	if err := s.userRepository.FindByUUID(spanCtx, user, uuid); err != nil {
+		logger.WithError(err).Warn("Failed to find user by UUID")
+		return nil, errcode.ErrUserNotFound
+	}
+
+	var pendingEmail string
+	if request.Email != nil && *request.Email != user.Email {
+		count, err := s.userRepository.CountByEmail(spanCtx, *request.Email)
+		if err != nil {
+			logger.WithError(err).Error("Failed to check email existence")
+			return nil, errcode.ErrInternalServerError
+		}
+		if count > 0 {
+			return nil, errcode.ErrUserAlreadyExists
+		}
+		pendingEmail = *request.Email
+	}
+
+	if request.Name != nil && *request.Name != user.Name {
+		before := *user
+		user.Name = *request.Name
+		if err := s.updateUserWithEvent(spanCtx, &before, user, []string{"name"}); err != nil {
+			return nil, err
+		}
+		s.invalidateUserCache(spanCtx, uuid)
+	}
+
+	if pendingEmail != "" {
+		if err := s.requestEmailVerification(spanCtx, user, pendingEmail); err != nil {
+			return nil, err
+		}
+	}
+
+	response := converter.UserToResponse(user)
+	response.PendingEmail = pendingEmail
+	return response, nil
+}
+
+func (s *UserService) requestEmailVerification(ctx context.Context, user *model.User, email string) error {
+	logger := s.log.WithContext(ctx)
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		logger.WithError(err).Error("Failed to generate email verification token")
 		return errcode.ErrInternalServerError
 	}
+	token := hex.EncodeToString(raw)
+
+	if _, err := s.redisService.Set(ctx, emailVerificationKey(token), pendingEmailChange{UUID: user.UUID, Email: email}, emailVerificationTTL); err != nil {
+		return errcode.ErrRedisSet
+	}
 
+	if err := s.notifier.Notify(ctx, notify.Message{
+		Channel:  notify.ChannelEmail,
+		To:       email,
+		Template: notify.TemplateEmailVerification,
+		Data: map[string]any{
+			"Name":           user.Name,
+			"Token":          token,
+			"ExpiresInHours": int(emailVerificationTTL / time.Hour),
+		},
+	}); err != nil {
+		logger.WithError(err).Error("Failed to send email verification")
+		return errcode.ErrInternalServerError
+	}
 	return nil
 }
+
+// VerifyEmail confirms a pending email change made by the same user.
+func (s *UserService) VerifyEmail(ctx context.Context, uuid, token string) (*dto.UserResponse, error) {
+	spanCtx, span := s.tracer.Start(ctx, "UserService.VerifyEmail")
+	defer span.End()
+
+	logger := s.log.WithContext(spanCtx)
+
+	cached, found := s.redisService.Get(spanCtx, emailVerificationKey(token))
+	if !found {
+		return nil, errcode.ErrInvalidEmailToken
+	}
+	var pending pendingEmailChange
+	if err := json.Unmarshal([]byte(cached), &pending); err != nil || pending.UUID != uuid {
+		logger.Warn("Email verification token does not belong to user")
+		return nil, errcode.ErrInvalidEmailToken
+	}
+
+	user := new(model.User)
+	if err := s.uow.Do(spanCtx, func(txCtx context.Context) error {
+		if err := s.userRepository.FindByUUID(txCtx, user, uuid); err != nil {
+			logger.WithError(err).Warn("Failed to find user by UUID")
+			return errcode.ErrUserNotFound
+		}
+
+		// The address may have been taken since the change was requested
+		count, err := s.userRepository.CountByEmail(txCtx, pending.Email)
+		if err != nil {
+			logger.WithError(err).Error("Failed to check email existence")
+			return errcode.ErrInternalServerError
+		}
+		if count > 0 {
+			return errcode.ErrUserAlreadyExists
+		}
+
+		before := *user
+		user.Email = pending.Email
+		return s.updateUserWithEvent(txCtx, &before, user, []string{"email"})
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := s.redisService.Delete(spanCtx, emailVerificationKey(token)); err != nil {
+		logger.WithError(err).Warn("Failed to delete used email verification token")
+	}
+	s.invalidateUserCache(spanCtx, uuid)
+
+	return converter.UserToResponse(user), nil
+}
+
+// DeleteMe soft-deletes the caller's own account after confirming their
+// password. The deletion revokes every token issued so far, so no session
+// comes back should the account be restored; the tokens of the deleting
+// session are also blacklisted once the deletion commits.
+func (s *UserService) DeleteMe(ctx context.Context, uuid, password, accessToken, refreshToken string) error {
+	spanCtx, span := s.tracer.Start(ctx, "UserService.DeleteMe")
+	defer span.End()
+
+	logger := s.log.WithContext(spanCtx)
+	user := new(model.User)
+	if err := s.userRepository.FindByUUID(spanCtx, user, uuid); err != nil {
+		logger.WithError(err).Warn("Failed to find user by UUID")
+		return errcode.ErrUserNotFound
+	}
+
+	_, passwordSpan := s.tracer.Start(spanCtx, "CompareHashPassword")
+	err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password))
+	passwordSpan.End()
+	if err != nil {
+		logger.Warn("Account deletion with wrong password")
+		return errcode.ErrPasswordMismatch
+	}
+
+	if err := s.inTx(spanCtx, func(txCtx context.Context) error {
+		if err := s.deleteUser(txCtx, user); err != nil {
+			return err
+		}
+		repository.AfterCommit(txCtx, func(ctx context.Context) {
+			s.revokeTokens(ctx, uuid, accessToken, refreshToken)
+		})
+		return nil
+	}); err != nil {
+		return err
+	}
+	s.invalidateUserCache(spanCtx, uuid)
+
+	return nil
+}
+
+// revokeTokens blacklists the given session tokens of a user, skipping empty
+// ones. Failures are only logged: the deletion has already committed and the
+// status check rejects the tokens regardless.
+func (s *UserService) revokeTokens(ctx context.Context, uuid, accessToken, refreshToken string) {
+	if s.blacklistService == nil {
+		return
+	}
+	for _, token := range []struct {
+		value     string
+		tokenType constant.TokenType
+	}{
+		{accessToken, constant.TokenTypeAccess},
+		{refreshToken, constant.TokenTypeRefresh},
+	} {
+		if token.value == "" {
+			continue
+		}
+		if err := s.blacklistService.Add(ctx, token.value, token.tokenType); err != nil {
+			s.log.WithContext(ctx).WithError(err).WithField("uuid", uuid).Warn("Failed to revoke token of deleted user")
+		}
+	}
+}
+
+// SuspendUser blocks a user from authenticating until reactivated or, when
+// request.Until is set, until that time has passed.
+func (s *UserService) SuspendUser(ctx context.Context, uuid string, request *dto.SuspendUserRequest) (*dto.UserResponse, error) {
+	spanCtx, span := s.tracer.Start(ctx, "UserService.SuspendUser")
+	defer span.End()
+
+	var until *time.Time
+	if request.Until != 0 {
+		t := time.Unix(request.Until, 0)
+		if !t.After(time.Now()) {
+			s.log.WithContext(spanCtx).Warn("Suspension end is not in the future")
+			return nil, errcode.ErrInvalidSuspension
+		}
+		until = &t
+	}
+
+	return s.updateStatus(spanCtx, uuid, constant.UserStatusSuspended, request.Reason, until)
+}
+
+// ReactivateUser lifts a suspension (or activates a pending user).
+func (s *UserService) ReactivateUser(ctx context.Context, uuid string) (*dto.UserResponse, error) {
+	spanCtx, span := s.tracer.Start(ctx, "UserService.ReactivateUser")
+	defer span.End()
+
+	return s.updateStatus(spanCtx, uuid, constant.UserStatusActive, "", nil)
+}
+
+func (s *UserService) updateStatus(ctx context.Context, uuid string, status constant.UserStatus, reason string, until *time.Time) (*dto.UserResponse, error) {
+	logger := s.log.WithContext(ctx)
+
+	user := new(model.User)
+	if err := s.userRepository.FindByUUID(ctx, user, uuid); err != nil {
+		logger.WithError(err).Warn("Failed to find user by UUID")
+		return nil, errcode.ErrUserNotFound
+	}
+
+	before := *user
+	user.Status = string(status)
+	user.StatusReason = reason
+	user.SuspendedUntil = until
+	if err := s.inTx(ctx, func(txCtx context.Context) error {
+		if err := s.userRepository.UpdateStatus(txCtx, user); err != nil {
+			if errors.Is(err, repository.ErrStaleVersion) {
+				logger.WithField("uuid", user.UUID).Warn("User was modified or deleted concurrently")
+				return errcode.ErrPreconditionFailed
+			}
+			logger.WithError(err).Error("Failed to update user status")
+			return errcode.ErrInternalServerError
+		}
+		if err := s.publish(txCtx, event.UserStatusChanged{UserEvent: event.ForUser(user), Reason: reason, Until: until}); err != nil {
+			return err
+		}
+		metadata := map[string]any{}
+		if reason != "" {
+			metadata["reason"] = reason
+		}
+		if until != nil {
+			metadata["until"] = until.Unix()
+		}
+		return s.recordAudit(txCtx, event.TypeUserStatusChanged, &before, user, metadata)
+	}); err != nil {
+		return nil, err
+	}
+
+	// The cached profile carries the status, drop it so /me reflects the change
+	s.invalidateUserCache(ctx, uuid)
+
+	return converter.UserToResponse(user), nil
+}
+
+// inTx runs fn in the caller's transaction or a new one, so a change and its
+// events are stored together. Errors fn did not map, such as a failed
+// commit, become ErrDatabaseTransaction.
+func (s *UserService) inTx(ctx context.Context, fn func(txCtx context.Context) error) error {
+	err := s.uow.Join(ctx, fn)
+	if err == nil {
+		return nil
+	}
+	if _, mapped := errcode.GetHTTPStatus(err); mapped {
+		return err
+	}
+	s.log.WithContext(ctx).WithError(err).Error("User transaction failed")
+	return errcode.ErrDatabaseTransaction
+}
+
+// publish records events in the outbox within the transaction in ctx.
+func (s *UserService) publish(ctx context.Context, events ...event.Event) error {
+	if err := s.outboxRepository.Append(ctx, events...); err != nil {
+		s.log.WithContext(ctx).WithError(err).Error("Failed to record user events")
+		return errcode.ErrDatabaseError
+	}
+	return nil
+}
+
+// recordAudit audits action on a user within the transaction in ctx. before
+// is nil for a new user and after nil for a deleted one.
+func (s *UserService) recordAudit(ctx context.Context, action string, before, after *model.User, metadata map[string]any) error {
+	target := after
+	if target == nil {
+		target = before
+	}
+	return s.auditService.Record(ctx, AuditRecord{
+		Action:     action,
+		TargetType: audit.TargetUser,
+		TargetID:   target.UUID,
+		Before:     auditSnapshot(before),
+		After:      auditSnapshot(after),
+		Metadata:   metadata,
+	})
+}
+
+// auditSnapshot returns the fields of user the audit log diffs, the same
+// its events carry, so credentials never reach the log.
+func auditSnapshot(user *model.User) *event.User {
+	if user == nil {
+		return nil
+	}
+	snapshot := event.ForUser(user).User
+	return &snapshot
+}
+
+// invalidateUserCache drops the cached GetUser response; failures only delay
+// freshness by the cache TTL, so they are logged rather than returned. Inside
+// a transaction it waits for the commit, so a concurrent read cannot cache
+// the old row again and a rollback leaves the cache alone.
+func (s *UserService) invalidateUserCache(ctx context.Context, uuid string) {
+	repository.AfterCommit(ctx, func(ctx context.Context) {
+		if err := s.redisService.Delete(ctx, userCacheKey(uuid)); err != nil {
+			s.log.WithContext(ctx).WithError(err).Warn("failed to invalidate cached user profile")
+		}
+	})
+}
+
+// GetEffectivePermissions lists every permission held by a user together with its provenance.
+func (s *UserService) GetEffectivePermissions(ctx context.Context, uuid string) (*dto.EffectivePermissionsResponse, error) {
+	spanCtx, span := s.tracer.Start(ctx, "UserService.GetEffectivePermissions")
+	defer span.End()
+
+	user := new(model.User)
+	if err := s.userRepository.FindByUUID(spanCtx, user, uuid); err != nil {
+		s.log.WithContext(spanCtx).WithError(err).Warn("Failed to find user by UUID")
+		return nil, errcode.ErrUserNotFound
+	}
+
+	return &dto.EffectivePermissionsResponse{
+		UserUUID:    user.UUID,
+		Permissions: converter.UserToEffectivePermissions(user),
+	}, nil
+}
+
+// CheckPermission reports whether a user holds permission and explains the decision.
+func (s *UserService) CheckPermission(ctx context.Context, uuid string, permission string) (*dto.PermissionCheckResponse, error) {
+	spanCtx, span := s.tracer.Start(ctx, "UserService.CheckPermission")
+	defer span.End()
+
+	user := new(model.User)
+	if err := s.userRepository.FindByUUID(spanCtx, user, uuid); err != nil {
+		s.log.WithContext(spanCtx).WithError(err).Warn("Failed to find user by UUID")
+		return nil, errcode.ErrUserNotFound
+	}
+
+	response := &dto.PermissionCheckResponse{UserUUID: user.UUID, Permission: permission}
+	for _, perm := range converter.UserToEffectivePermissions(user) {
+		if perm.Name != permission {
+			continue
+		}
+		reasons := make([]string, len(perm.Sources))
+		for i, source := range perm.Sources {
+			if source.Type == string(constant.PermissionSourceDirect) {
+				reasons[i] = "granted directly via user_permissions"
+			} else {
+				reasons[i] = fmt.Sprintf("granted via role %q", source.Role)
+			}
+		}
+		response.Allowed = true
+		response.Sources = perm.Sources
+		response.Reason = strings.Join(reasons, "; ")
+		return response, nil
+	}
+
+	if len(user.Roles) == 0 {
+		response.Reason = fmt.Sprintf("permission %q is not granted directly and the user has no roles", permission)
+		return response, nil
+	}
+	roles := make([]string, len(user.Roles))
+	for i, role := range user.Roles {
+		roles[i] = role.Name
+	}
+	response.Reason = fmt.Sprintf("permission %q is not granted directly or by any of the user's roles (%s)", permission, strings.Join(roles, ", "))
+	return response, nil
+}
@@ -2,33 +2,61 @@ package service
 
 import (
 	"context"
+	"database/sql"
+	"encoding/json"
 	"errors"
+	"io"
+	"net/http"
 	"regexp"
 	"testing"
 	"time"
 
 	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/alicebob/miniredis/v2"
 	"github.com/redis/go-redis/v9"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
 
+	"go-starter-template/internal/constant"
 	"go-starter-template/internal/dto"
+	"go-starter-template/internal/event"
+	"go-starter-template/internal/notify"
+	"go-starter-template/internal/queue"
 	"go-starter-template/internal/repository"
 	"go-starter-template/internal/utils/errcode"
 )
 
 // setupRepoAndUow replicates the helper in auth_service_test.go to produce a sqlmock-backed repository.
-func setupRepo(t *testing.T) (*repository.UserRepository, sqlmock.Sqlmock, func()) {
+func setupRepo(t *testing.T) (*repository.UserRepository, *repository.OutboxRepository, *AuditService, *repository.UnitOfWork, sqlmock.Sqlmock, func()) {
 	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherRegexp))
 	require.NoError(t, err)
 	repo := repository.NewUserRepository(db)
+	uow := repository.NewUnitOfWork(db)
 	cleanup := func() { _ = db.Close() }
-	return repo, mock, cleanup
+	return repo, repository.NewOutboxRepository(db), NewAuditService(repository.NewAuditRepository(db), testLogger(), uow), uow, mock, cleanup
+}
+
+// expectEvent expects the outbox insert of an eventType event about a user.
+func expectEvent(m sqlmock.Sqlmock, eventType string) {
+	m.ExpectExec(regexp.QuoteMeta("INSERT INTO outbox")).
+		WithArgs(sqlmock.AnyArg(), event.AggregateUser, sqlmock.AnyArg(), eventType, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+}
+
+// expectAudit expects an audit event appended to the hash chain.
+func expectAudit(m sqlmock.Sqlmock, action string) {
+	m.ExpectExec(regexp.QuoteMeta("SELECT pg_advisory_xact_lock($1)")).WillReturnResult(sqlmock.NewResult(0, 0))
+	m.ExpectQuery(regexp.QuoteMeta("SELECT hash FROM audit_events")).WillReturnRows(sqlmock.NewRows([]string{"hash"}))
+	m.ExpectQuery(regexp.QuoteMeta("INSERT INTO audit_events")).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), action, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
 }
 
 // fakeRedisClient satisfies redisClient for testing cache behavior in GetUser.
 type userTestRedisClient struct {
 	getFunc func(ctx context.Context, key string) *redis.StringCmd
 	setFunc func(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.StatusCmd
+	delFunc func(ctx context.Context, keys ...string) *redis.IntCmd
 }
 
 func (f *userTestRedisClient) Get(ctx context.Context, key string) *redis.StringCmd {
@@ -37,6 +65,14 @@ func (f *userTestRedisClient) Get(ctx context.Context, key string) *redis.String
 func (f *userTestRedisClient) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.StatusCmd {
 	return f.setFunc(ctx, key, value, expiration)
 }
+func (f *userTestRedisClient) Del(ctx context.Context, keys ...string) *redis.IntCmd {
+	if f.delFunc != nil {
+		return f.delFunc(ctx, keys...)
+	}
+	cmd := redis.NewIntCmd(ctx)
+	cmd.SetVal(int64(len(keys)))
+	return cmd
+}
 
 func TestUserService_GetUser(t *testing.T) {
 	logger := silentLogger()
@@ -76,7 +112,7 @@ func TestUserService_GetUser(t *testing.T) {
 			name: "CacheMiss_DBNotFound",
 			uuid: "missing",
 			setupDB: func(mock sqlmock.Sqlmock) {
-				mock.ExpectQuery(regexp.QuoteMeta(`SELECT uuid, name, email, password, created_at, updated_at FROM users WHERE uuid = $1 LIMIT 1`)).
+				mock.ExpectQuery(regexp.QuoteMeta(`SELECT uuid, name, email, password, created_at, updated_at, status, status_reason, suspended_until, phone, version FROM users WHERE uuid = $1 AND deleted_at IS NULL LIMIT 1`)).
 					WithArgs("missing").
 					WillReturnError(errors.New("no rows"))
 			},
@@ -101,28 +137,28 @@ func TestUserService_GetUser(t *testing.T) {
 			name: "CacheMiss_DBFound_StoresCache",
 			uuid: "user-123",
 			setupDB: func(m sqlmock.Sqlmock) {
-				userRow := sqlmock.NewRows([]string{"uuid", "name", "email", "password", "created_at", "updated_at"}).
-					AddRow("user-123", "Alice", "alice@example.com", "hash", time.Now(), time.Now())
-				m.ExpectQuery(regexp.QuoteMeta(`SELECT uuid, name, email, password, created_at, updated_at FROM users WHERE uuid = $1 LIMIT 1`)).
+				userRow := sqlmock.NewRows([]string{"uuid", "name", "email", "password", "created_at", "updated_at", "status", "status_reason", "suspended_until", "phone", "version"}).
+					AddRow("user-123", "Alice", "alice@example.com", "hash", time.Now(), time.Now(), "active", "", nil, nil, 1)
+				m.ExpectQuery(regexp.QuoteMeta(`SELECT uuid, name, email, password, created_at, updated_at, status, status_reason, suspended_until, phone, version FROM users WHERE uuid = $1 AND deleted_at IS NULL LIMIT 1`)).
 					WithArgs("user-123").
 					WillReturnRows(userRow)
-				m.ExpectQuery(regexp.QuoteMeta(`SELECT r.uuid, r.name
-        FROM roles r
-        INNER JOIN user_roles ur ON ur.role_uuid = r.uuid
-        WHERE ur.user_uuid = $1`)).
+				m.ExpectQuery(regexp.QuoteMeta(`SELECT ur.user_uuid, r.uuid, r.name
+        FROM user_roles ur
+        INNER JOIN roles r ON r.uuid = ur.role_uuid
+        WHERE ur.user_uuid IN ($1)`)).
 					WithArgs("user-123").
 					WillReturnRows(sqlmock.NewRows([]string{"uuid", "name"}))
-				m.ExpectQuery(regexp.QuoteMeta(`SELECT p.uuid, p.name
-        FROM permissions p
-        INNER JOIN user_permissions up ON up.permission_uuid = p.uuid
-        WHERE up.user_uuid = $1`)).
+				m.ExpectQuery(regexp.QuoteMeta(`SELECT up.user_uuid, p.uuid, p.name
+        FROM user_permissions up
+        INNER JOIN permissions p ON p.uuid = up.permission_uuid
+        WHERE up.user_uuid IN ($1)`)).
 					WithArgs("user-123").
 					WillReturnRows(sqlmock.NewRows([]string{"uuid", "name"}))
-                m.ExpectQuery(regexp.QuoteMeta(`SELECT rp.role_uuid, p.uuid, p.name
+                m.ExpectQuery(regexp.QuoteMeta(`SELECT DISTINCT rp.role_uuid, p.uuid, p.name
         FROM permissions p
         INNER JOIN role_permissions rp ON rp.permission_uuid = p.uuid
         INNER JOIN user_roles ur ON ur.role_uuid = rp.role_uuid
-        WHERE ur.user_uuid = $1`)).
+        WHERE ur.user_uuid IN ($1)`)).
                     WithArgs("user-123").
                     WillReturnRows(sqlmock.NewRows([]string{"role_uuid", "uuid", "name"}))
 			},
@@ -149,28 +185,28 @@ func TestUserService_GetUser(t *testing.T) {
 			name: "CacheMiss_DBFound_SetError",
 			uuid: "user-123",
 			setupDB: func(m sqlmock.Sqlmock) {
-				userRow := sqlmock.NewRows([]string{"uuid", "name", "email", "password", "created_at", "updated_at"}).
-					AddRow("user-123", "Alice", "alice@example.com", "hash", time.Now(), time.Now())
-				m.ExpectQuery(regexp.QuoteMeta(`SELECT uuid, name, email, password, created_at, updated_at FROM users WHERE uuid = $1 LIMIT 1`)).
+				userRow := sqlmock.NewRows([]string{"uuid", "name", "email", "password", "created_at", "updated_at", "status", "status_reason", "suspended_until", "phone", "version"}).
+					AddRow("user-123", "Alice", "alice@example.com", "hash", time.Now(), time.Now(), "active", "", nil, nil, 1)
+				m.ExpectQuery(regexp.QuoteMeta(`SELECT uuid, name, email, password, created_at, updated_at, status, status_reason, suspended_until, phone, version FROM users WHERE uuid = $1 AND deleted_at IS NULL LIMIT 1`)).
 					WithArgs("user-123").
 					WillReturnRows(userRow)
-				m.ExpectQuery(regexp.QuoteMeta(`SELECT r.uuid, r.name
-        FROM roles r
-        INNER JOIN user_roles ur ON ur.role_uuid = r.uuid
-        WHERE ur.user_uuid = $1`)).
+				m.ExpectQuery(regexp.QuoteMeta(`SELECT ur.user_uuid, r.uuid, r.name
+        FROM user_roles ur
+        INNER JOIN roles r ON r.uuid = ur.role_uuid
+        WHERE ur.user_uuid IN ($1)`)).
 					WithArgs("user-123").
 					WillReturnRows(sqlmock.NewRows([]string{"uuid", "name"}))
-				m.ExpectQuery(regexp.QuoteMeta(`SELECT p.uuid, p.name
-        FROM permissions p
-        INNER JOIN user_permissions up ON up.permission_uuid = p.uuid
-        WHERE up.user_uuid = $1`)).
+				m.ExpectQuery(regexp.QuoteMeta(`SELECT up.user_uuid, p.uuid, p.name
+        FROM user_permissions up
+        INNER JOIN permissions p ON p.uuid = up.permission_uuid
+        WHERE up.user_uuid IN ($1)`)).
 					WithArgs("user-123").
 					WillReturnRows(sqlmock.NewRows([]string{"uuid", "name"}))
-                m.ExpectQuery(regexp.QuoteMeta(`SELECT rp.role_uuid, p.uuid, p.name
+                m.ExpectQuery(regexp.QuoteMeta(`SELECT DISTINCT rp.role_uuid, p.uuid, p.name
         FROM permissions p
         INNER JOIN role_permissions rp ON rp.permission_uuid = p.uuid
         INNER JOIN user_roles ur ON ur.role_uuid = rp.role_uuid
-        WHERE ur.user_uuid = $1`)).
+        WHERE ur.user_uuid IN ($1)`)).
                     WithArgs("user-123").
                     WillReturnRows(sqlmock.NewRows([]string{"role_uuid", "uuid", "name"}))
 			},
@@ -197,14 +233,14 @@ func TestUserService_GetUser(t *testing.T) {
 
 	for _, tc := range cases {
 		t.Run(tc.name, func(t *testing.T) {
-			repo, mock, cleanup := setupRepo(t)
+			repo, outbox, auditSvc, uow, mock, cleanup := setupRepo(t)
 			defer cleanup()
 			require.NotNil(t, repo)
 			if tc.setupDB != nil {
 				tc.setupDB(mock)
 			}
 			redisSvc := NewRedisService(tc.setupRds(), logger)
-			svc := NewUserService(repo, redisSvc, logger)
+			svc := NewUserService(repo, outbox, auditSvc, redisSvc, logger, uow)
 			resp, err := svc.GetUser(context.Background(), tc.uuid)
 			if e := mock.ExpectationsWereMet(); e != nil {
 				t.Logf("sqlmock expectations error: %v", e)
@@ -225,23 +261,23 @@ func TestUserService_GetUser(t *testing.T) {
 
 func TestUserService_Search(t *testing.T) {
 	logger := silentLogger()
-	repo, mock, cleanup := setupRepo(t)
+	repo, outbox, auditSvc, uow, mock, cleanup := setupRepo(t)
 	defer cleanup()
 
-	svc := NewUserService(repo, NewRedisService(&userTestRedisClient{}, logger), logger)
+	svc := NewUserService(repo, outbox, auditSvc, NewRedisService(&userTestRedisClient{}, logger), logger, uow)
 
 	type testcase struct {
 		name      string
 		request   *dto.SearchUserRequest
 		setupDB   func(sqlmock.Sqlmock)
 		expectErr error
-		assert    func(t *testing.T, users []*dto.UserResponse, total int64)
+		assert    func(t *testing.T, users []*dto.UserResponse, paging *dto.PageMetadata)
 	}
 
 	mkRows := func(n int) *sqlmock.Rows {
-		rows := sqlmock.NewRows([]string{"uuid", "name", "email", "created_at", "updated_at"})
+		rows := sqlmock.NewRows([]string{"uuid", "name", "email", "phone", "status", "status_reason", "suspended_until", "version", "created_at", "updated_at", "deleted_at"})
 		for i := 0; i < n; i++ {
-			rows.AddRow("u"+string(rune('1'+i)), "N"+string(rune('1'+i)), "e"+string(rune('1'+i))+"@ex.com", time.Now(), time.Now())
+			rows.AddRow("u"+string(rune('1'+i)), "N"+string(rune('1'+i)), "e"+string(rune('1'+i))+"@ex.com", nil, "active", "", nil, 1, time.Now(), time.Now(), nil)
 		}
 		return rows
 	}
@@ -251,39 +287,146 @@ func TestUserService_Search(t *testing.T) {
 			name:    "DefaultPaging_Success",
 			request: &dto.SearchUserRequest{},
 			setupDB: func(m sqlmock.Sqlmock) {
-				m.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM users ")).
+				m.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM users WHERE deleted_at IS NULL")).
 					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
-				m.ExpectQuery(regexp.QuoteMeta("SELECT uuid, name, email, created_at, updated_at FROM users  ORDER BY created_at DESC OFFSET $1 LIMIT $2")).
+				m.ExpectQuery(regexp.QuoteMeta("SELECT uuid, name, email, phone, status, status_reason, suspended_until, version, created_at, updated_at, deleted_at FROM users WHERE deleted_at IS NULL ORDER BY created_at DESC, uuid ASC OFFSET $1 LIMIT $2")).
 					WithArgs(0, 10).
 					WillReturnRows(mkRows(2))
 			},
 			expectErr: nil,
-			assert: func(t *testing.T, users []*dto.UserResponse, total int64) {
+			assert: func(t *testing.T, users []*dto.UserResponse, paging *dto.PageMetadata) {
 				require.Len(t, users, 2)
-				require.Equal(t, int64(2), total)
+				require.Equal(t, int64(2), *paging.TotalItem)
+				require.Equal(t, int64(1), *paging.TotalPage)
 			},
 		},
 		{
 			name:    "FilterByNameAndEmail_Success",
 			request: &dto.SearchUserRequest{Name: "Al", Email: "ex"},
 			setupDB: func(m sqlmock.Sqlmock) {
-				m.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM users WHERE name ILIKE $1 AND email ILIKE $2")).
+				m.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM users WHERE deleted_at IS NULL AND name ILIKE $1 AND email ILIKE $2")).
 					WithArgs("%Al%", "%ex%").
 					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
-				m.ExpectQuery(regexp.QuoteMeta("SELECT uuid, name, email, created_at, updated_at FROM users WHERE name ILIKE $1 AND email ILIKE $2 ORDER BY created_at DESC OFFSET $3 LIMIT $4")).
+				m.ExpectQuery(regexp.QuoteMeta("SELECT uuid, name, email, phone, status, status_reason, suspended_until, version, created_at, updated_at, deleted_at FROM users WHERE deleted_at IS NULL AND name ILIKE $1 AND email ILIKE $2 ORDER BY created_at DESC, uuid ASC OFFSET $3 LIMIT $4")).
 					WithArgs("%Al%", "%ex%", 0, 10).
 					WillReturnRows(mkRows(1))
 			},
-			assert: func(t *testing.T, users []*dto.UserResponse, total int64) {
+			assert: func(t *testing.T, users []*dto.UserResponse, paging *dto.PageMetadata) {
+				require.Len(t, users, 1)
+				require.Equal(t, int64(1), *paging.TotalItem)
+			},
+		},
+		{
+			name:    "SortAndSkipCount",
+			request: &dto.SearchUserRequest{Sort: "name,-updated_at", SkipCount: true},
+			setupDB: func(m sqlmock.Sqlmock) {
+				m.ExpectQuery(regexp.QuoteMeta("SELECT uuid, name, email, phone, status, status_reason, suspended_until, version, created_at, updated_at, deleted_at FROM users WHERE deleted_at IS NULL ORDER BY name ASC, updated_at DESC, uuid ASC OFFSET $1 LIMIT $2")).
+					WithArgs(0, 10).
+					WillReturnRows(mkRows(1))
+			},
+			assert: func(t *testing.T, users []*dto.UserResponse, paging *dto.PageMetadata) {
 				require.Len(t, users, 1)
-				require.Equal(t, int64(1), total)
+				require.Nil(t, paging.TotalItem)
+				require.Nil(t, paging.TotalPage)
+				require.Equal(t, 1, paging.Page)
 			},
 		},
+		{
+			name:    "CursorMode_FirstPage",
+			request: &dto.SearchUserRequest{Limit: 2, SkipCount: true},
+			setupDB: func(m sqlmock.Sqlmock) {
+				m.ExpectQuery(regexp.QuoteMeta("SELECT uuid, name, email, phone, status, status_reason, suspended_until, version, created_at, updated_at, deleted_at FROM users WHERE deleted_at IS NULL ORDER BY created_at DESC, uuid ASC LIMIT $1")).
+					WithArgs(3).
+					WillReturnRows(mkRows(3))
+			},
+			assert: func(t *testing.T, users []*dto.UserResponse, paging *dto.PageMetadata) {
+				require.Len(t, users, 2)
+				require.Equal(t, 2, paging.Size)
+				require.NotEmpty(t, paging.Next)
+				require.Empty(t, paging.Prev)
+				require.Zero(t, paging.Page)
+			},
+		},
+		{
+			name:    "IncludeRoles",
+			request: &dto.SearchUserRequest{Status: "active", Include: "roles", SkipCount: true},
+			setupDB: func(m sqlmock.Sqlmock) {
+				m.ExpectQuery(regexp.QuoteMeta("FROM users WHERE deleted_at IS NULL AND status IN ($1) ORDER BY created_at DESC, uuid ASC OFFSET $2 LIMIT $3")).
+					WithArgs("active", 0, 10).
+					WillReturnRows(mkRows(1))
+				m.ExpectQuery(regexp.QuoteMeta("WHERE ur.user_uuid IN ($1)")).
+					WithArgs("u1").
+					WillReturnRows(sqlmock.NewRows([]string{"user_uuid", "uuid", "name"}).AddRow("u1", "r1", "admin"))
+			},
+			assert: func(t *testing.T, users []*dto.UserResponse, paging *dto.PageMetadata) {
+				require.Len(t, users, 1)
+				require.Len(t, users[0].Roles, 1)
+				require.Equal(t, "admin", users[0].Roles[0].Name)
+			},
+		},
+		{
+			name:    "IncludeRolesAndPermissions",
+			request: &dto.SearchUserRequest{Include: "roles,permissions", SkipCount: true},
+			setupDB: func(m sqlmock.Sqlmock) {
+				m.ExpectQuery(regexp.QuoteMeta("FROM users WHERE deleted_at IS NULL ORDER BY created_at DESC, uuid ASC OFFSET $1 LIMIT $2")).
+					WithArgs(0, 10).
+					WillReturnRows(mkRows(2))
+				// One query per relation for the whole page, not per user
+				m.ExpectQuery(regexp.QuoteMeta("FROM user_roles ur INNER JOIN roles r ON r.uuid = ur.role_uuid WHERE ur.user_uuid IN ($1, $2)")).
+					WithArgs("u1", "u2").
+					WillReturnRows(sqlmock.NewRows([]string{"user_uuid", "uuid", "name"}).AddRow("u1", "r1", "admin").AddRow("u2", "r1", "admin"))
+				m.ExpectQuery(regexp.QuoteMeta("FROM user_permissions up INNER JOIN permissions p ON p.uuid = up.permission_uuid WHERE up.user_uuid IN ($1, $2)")).
+					WithArgs("u1", "u2").
+					WillReturnRows(sqlmock.NewRows([]string{"user_uuid", "uuid", "name"}).AddRow("u2", "p9", "read-other"))
+				m.ExpectQuery(regexp.QuoteMeta("SELECT DISTINCT rp.role_uuid, p.uuid, p.name")).
+					WithArgs("u1", "u2").
+					WillReturnRows(sqlmock.NewRows([]string{"role_uuid", "uuid", "name"}).AddRow("r1", "p1", "read-user"))
+			},
+			assert: func(t *testing.T, users []*dto.UserResponse, paging *dto.PageMetadata) {
+				require.Len(t, users, 2)
+				require.Equal(t, []string{"read-user"}, users[0].Roles[0].Permissions)
+				require.Empty(t, users[0].Permissions)
+				require.Equal(t, []string{"read-other"}, users[1].Permissions)
+			},
+		},
+		{
+			name:    "FreeText_Highlight",
+			request: &dto.SearchUserRequest{Q: "n1", SkipCount: true},
+			setupDB: func(m sqlmock.Sqlmock) {
+				m.ExpectQuery(regexp.QuoteMeta("WHERE deleted_at IS NULL AND (name ILIKE $2 OR email ILIKE $2)")).
+					WithArgs("n1", "%n1%", 0, 10).
+					WillReturnRows(mkRows(2))
+			},
+			assert: func(t *testing.T, users []*dto.UserResponse, paging *dto.PageMetadata) {
+				require.Len(t, users, 2)
+				require.Equal(t, map[string]string{"name": "<mark>N1</mark>"}, users[0].Highlight)
+				require.Nil(t, users[1].Highlight)
+			},
+		},
+		{
+			name:      "InvalidFilter",
+			request:   &dto.SearchUserRequest{Status: "banned"},
+			expectErr: errcode.ErrInvalidFilter,
+		},
+		{
+			name:      "InvalidSort",
+			request:   &dto.SearchUserRequest{Sort: "password"},
+			expectErr: errcode.ErrInvalidSort,
+		},
+		{
+			name:      "InvalidCursor",
+			request:   &dto.SearchUserRequest{Cursor: "not-a-cursor"},
+			setupDB: func(m sqlmock.Sqlmock) {
+				m.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM users WHERE deleted_at IS NULL")).
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+			},
+			expectErr: errcode.ErrInvalidCursor,
+		},
 		{
 			name:    "SearchError_CountQuery",
 			request: &dto.SearchUserRequest{Page: 1, Size: 10},
 			setupDB: func(m sqlmock.Sqlmock) {
-				m.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM users ")).
+				m.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM users WHERE deleted_at IS NULL")).
 					WillReturnError(errors.New("db error"))
 			},
 			expectErr: errcode.ErrUserSearchFailed,
@@ -295,7 +438,7 @@ func TestUserService_Search(t *testing.T) {
 			if tc.setupDB != nil {
 				tc.setupDB(mock)
 			}
-			users, total, err := svc.Search(context.Background(), tc.request)
+			users, paging, err := svc.Search(context.Background(), tc.request)
 			if tc.expectErr != nil {
 				require.Error(t, err)
 				require.Equal(t, tc.expectErr, err)
@@ -303,7 +446,7 @@ func TestUserService_Search(t *testing.T) {
 				require.NoError(t, err)
 			}
 			if tc.assert != nil {
-				tc.assert(t, users, total)
+				tc.assert(t, users, paging)
 			}
 			require.NoError(t, mock.ExpectationsWereMet())
 		})
@@ -312,9 +455,9 @@ func TestUserService_Search(t *testing.T) {
 
 func TestUserService_UpdateUser(t *testing.T) {
 	logger := silentLogger()
-	repo, mock, cleanup := setupRepo(t)
+	repo, outbox, auditSvc, uow, mock, cleanup := setupRepo(t)
 	defer cleanup()
-	svc := NewUserService(repo, NewRedisService(&userTestRedisClient{}, logger), logger)
+	svc := NewUserService(repo, outbox, auditSvc, NewRedisService(&userTestRedisClient{}, logger), logger, uow)
 
 	type testcase struct {
 		name      string
@@ -326,8 +469,8 @@ func TestUserService_UpdateUser(t *testing.T) {
 	}
 
 	newUserRow := func(uuid, name, email string) *sqlmock.Rows {
-		return sqlmock.NewRows([]string{"uuid", "name", "email", "password", "created_at", "updated_at"}).
-			AddRow(uuid, name, email, "hash", time.Now(), time.Now())
+		return sqlmock.NewRows([]string{"uuid", "name", "email", "password", "created_at", "updated_at", "status", "status_reason", "suspended_until", "phone", "version"}).
+			AddRow(uuid, name, email, "hash", time.Now(), time.Now(), "active", "", nil, nil, 1)
 	}
 
 	cases := []testcase{
@@ -336,7 +479,7 @@ func TestUserService_UpdateUser(t *testing.T) {
 			uuid: "missing",
 			req:  &dto.UpdateUserRequest{Name: "Alice", Email: "alice@example.com"},
 			setupDB: func(m sqlmock.Sqlmock) {
-				m.ExpectQuery(regexp.QuoteMeta(`SELECT uuid, name, email, password, created_at, updated_at FROM users WHERE uuid = $1 LIMIT 1`)).
+				m.ExpectQuery(regexp.QuoteMeta(`SELECT uuid, name, email, password, created_at, updated_at, status, status_reason, suspended_until, phone, version FROM users WHERE uuid = $1 AND deleted_at IS NULL LIMIT 1`)).
 					WithArgs("missing").
 					WillReturnError(errors.New("no rows"))
 			},
@@ -347,31 +490,35 @@ func TestUserService_UpdateUser(t *testing.T) {
 			uuid: "u1",
 			req:  &dto.UpdateUserRequest{Name: "Alice", Email: "old@example.com"},
 			setupDB: func(m sqlmock.Sqlmock) {
-				m.ExpectQuery(regexp.QuoteMeta(`SELECT uuid, name, email, password, created_at, updated_at FROM users WHERE uuid = $1 LIMIT 1`)).
+				m.ExpectQuery(regexp.QuoteMeta(`SELECT uuid, name, email, password, created_at, updated_at, status, status_reason, suspended_until, phone, version FROM users WHERE uuid = $1 AND deleted_at IS NULL LIMIT 1`)).
 					WithArgs("u1").
 					WillReturnRows(newUserRow("u1", "Old", "old@example.com"))
-				m.ExpectQuery(regexp.QuoteMeta(`SELECT r.uuid, r.name
-        FROM roles r
-        INNER JOIN user_roles ur ON ur.role_uuid = r.uuid
-        WHERE ur.user_uuid = $1`)).
+				m.ExpectQuery(regexp.QuoteMeta(`SELECT ur.user_uuid, r.uuid, r.name
+        FROM user_roles ur
+        INNER JOIN roles r ON r.uuid = ur.role_uuid
+        WHERE ur.user_uuid IN ($1)`)).
 					WithArgs("u1").
 					WillReturnRows(sqlmock.NewRows([]string{"uuid", "name"}))
-				m.ExpectQuery(regexp.QuoteMeta(`SELECT p.uuid, p.name
-        FROM permissions p
-        INNER JOIN user_permissions up ON up.permission_uuid = p.uuid
-        WHERE up.user_uuid = $1`)).
+				m.ExpectQuery(regexp.QuoteMeta(`SELECT up.user_uuid, p.uuid, p.name
+        FROM user_permissions up
+        INNER JOIN permissions p ON p.uuid = up.permission_uuid
+        WHERE up.user_uuid IN ($1)`)).
 					WithArgs("u1").
 					WillReturnRows(sqlmock.NewRows([]string{"uuid", "name"}))
-                m.ExpectQuery(regexp.QuoteMeta(`SELECT rp.role_uuid, p.uuid, p.name
+                m.ExpectQuery(regexp.QuoteMeta(`SELECT DISTINCT rp.role_uuid, p.uuid, p.name
         FROM permissions p
         INNER JOIN role_permissions rp ON rp.permission_uuid = p.uuid
         INNER JOIN user_roles ur ON ur.role_uuid = rp.role_uuid
-        WHERE ur.user_uuid = $1`)).
+        WHERE ur.user_uuid IN ($1)`)).
                     WithArgs("u1").
                     WillReturnRows(sqlmock.NewRows([]string{"role_uuid", "uuid", "name"}))
-				m.ExpectExec(regexp.QuoteMeta("UPDATE users SET name = $1, email = $2, updated_at = NOW() WHERE uuid = $3")).
-					WithArgs("Alice", "old@example.com", "u1").
+				m.ExpectBegin()
+				m.ExpectExec(regexp.QuoteMeta("UPDATE users SET name = $1, email = $2, version = version + 1, updated_at = NOW() WHERE uuid = $3 AND version = $4")).
+					WithArgs("Alice", "old@example.com", "u1", 1).
 					WillReturnResult(sqlmock.NewResult(1, 1))
+				expectEvent(m, event.TypeUserUpdated)
+				expectAudit(m, event.TypeUserUpdated)
+				m.ExpectCommit()
 			},
 			assert: func(t *testing.T, resp *dto.UserResponse) {
 				require.NotNil(t, resp)
@@ -384,29 +531,29 @@ func TestUserService_UpdateUser(t *testing.T) {
 			uuid: "u1",
 			req:  &dto.UpdateUserRequest{Name: "Alice", Email: "new@example.com"},
 			setupDB: func(m sqlmock.Sqlmock) {
-				m.ExpectQuery(regexp.QuoteMeta(`SELECT uuid, name, email, password, created_at, updated_at FROM users WHERE uuid = $1 LIMIT 1`)).
+				m.ExpectQuery(regexp.QuoteMeta(`SELECT uuid, name, email, password, created_at, updated_at, status, status_reason, suspended_until, phone, version FROM users WHERE uuid = $1 AND deleted_at IS NULL LIMIT 1`)).
 					WithArgs("u1").
 					WillReturnRows(newUserRow("u1", "Old", "old@example.com"))
-				m.ExpectQuery(regexp.QuoteMeta(`SELECT r.uuid, r.name
-        FROM roles r
-        INNER JOIN user_roles ur ON ur.role_uuid = r.uuid
-        WHERE ur.user_uuid = $1`)).
+				m.ExpectQuery(regexp.QuoteMeta(`SELECT ur.user_uuid, r.uuid, r.name
+        FROM user_roles ur
+        INNER JOIN roles r ON r.uuid = ur.role_uuid
+        WHERE ur.user_uuid IN ($1)`)).
 					WithArgs("u1").
 					WillReturnRows(sqlmock.NewRows([]string{"uuid", "name"}))
-				m.ExpectQuery(regexp.QuoteMeta(`SELECT p.uuid, p.name
-        FROM permissions p
-        INNER JOIN user_permissions up ON up.permission_uuid = p.uuid
-        WHERE up.user_uuid = $1`)).
+				m.ExpectQuery(regexp.QuoteMeta(`SELECT up.user_uuid, p.uuid, p.name
+        FROM user_permissions up
+        INNER JOIN permissions p ON p.uuid = up.permission_uuid
+        WHERE up.user_uuid IN ($1)`)).
 					WithArgs("u1").
 					WillReturnRows(sqlmock.NewRows([]string{"uuid", "name"}))
-                m.ExpectQuery(regexp.QuoteMeta(`SELECT rp.role_uuid, p.uuid, p.name
+                m.ExpectQuery(regexp.QuoteMeta(`SELECT DISTINCT rp.role_uuid, p.uuid, p.name
         FROM permissions p
         INNER JOIN role_permissions rp ON rp.permission_uuid = p.uuid
         INNER JOIN user_roles ur ON ur.role_uuid = rp.role_uuid
-        WHERE ur.user_uuid = $1`)).
+        WHERE ur.user_uuid IN ($1)`)).
                     WithArgs("u1").
                     WillReturnRows(sqlmock.NewRows([]string{"role_uuid", "uuid", "name"}))
-				m.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM users WHERE email = $1")).
+				m.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM users WHERE lower(email) = lower($1) AND deleted_at IS NULL")).
 					WithArgs("new@example.com").
 					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
 			},
@@ -417,29 +564,29 @@ func TestUserService_UpdateUser(t *testing.T) {
 			uuid: "u1",
 			req:  &dto.UpdateUserRequest{Name: "Alice", Email: "new@example.com"},
 			setupDB: func(m sqlmock.Sqlmock) {
-				m.ExpectQuery(regexp.QuoteMeta(`SELECT uuid, name, email, password, created_at, updated_at FROM users WHERE uuid = $1 LIMIT 1`)).
+				m.ExpectQuery(regexp.QuoteMeta(`SELECT uuid, name, email, password, created_at, updated_at, status, status_reason, suspended_until, phone, version FROM users WHERE uuid = $1 AND deleted_at IS NULL LIMIT 1`)).
 					WithArgs("u1").
 					WillReturnRows(newUserRow("u1", "Old", "old@example.com"))
-				m.ExpectQuery(regexp.QuoteMeta(`SELECT r.uuid, r.name
-        FROM roles r
-        INNER JOIN user_roles ur ON ur.role_uuid = r.uuid
-        WHERE ur.user_uuid = $1`)).
+				m.ExpectQuery(regexp.QuoteMeta(`SELECT ur.user_uuid, r.uuid, r.name
+        FROM user_roles ur
+        INNER JOIN roles r ON r.uuid = ur.role_uuid
+        WHERE ur.user_uuid IN ($1)`)).
 					WithArgs("u1").
 					WillReturnRows(sqlmock.NewRows([]string{"uuid", "name"}))
-				m.ExpectQuery(regexp.QuoteMeta(`SELECT p.uuid, p.name
-        FROM permissions p
-        INNER JOIN user_permissions up ON up.permission_uuid = p.uuid
-        WHERE up.user_uuid = $1`)).
+				m.ExpectQuery(regexp.QuoteMeta(`SELECT up.user_uuid, p.uuid, p.name
+        FROM user_permissions up
+        INNER JOIN permissions p ON p.uuid = up.permission_uuid
+        WHERE up.user_uuid IN ($1)`)).
 					WithArgs("u1").
 					WillReturnRows(sqlmock.NewRows([]string{"uuid", "name"}))
-                m.ExpectQuery(regexp.QuoteMeta(`SELECT rp.role_uuid, p.uuid, p.name
+                m.ExpectQuery(regexp.QuoteMeta(`SELECT DISTINCT rp.role_uuid, p.uuid, p.name
         FROM permissions p
         INNER JOIN role_permissions rp ON rp.permission_uuid = p.uuid
         INNER JOIN user_roles ur ON ur.role_uuid = rp.role_uuid
-        WHERE ur.user_uuid = $1`)).
+        WHERE ur.user_uuid IN ($1)`)).
                     WithArgs("u1").
                     WillReturnRows(sqlmock.NewRows([]string{"role_uuid", "uuid", "name"}))
-				m.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM users WHERE email = $1")).
+				m.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM users WHERE lower(email) = lower($1) AND deleted_at IS NULL")).
 					WithArgs("new@example.com").
 					WillReturnError(errors.New("count error"))
 			},
@@ -450,31 +597,33 @@ func TestUserService_UpdateUser(t *testing.T) {
 			uuid: "u1",
 			req:  &dto.UpdateUserRequest{Name: "Alice", Email: "old@example.com"},
 			setupDB: func(m sqlmock.Sqlmock) {
-				m.ExpectQuery(regexp.QuoteMeta(`SELECT uuid, name, email, password, created_at, updated_at FROM users WHERE uuid = $1 LIMIT 1`)).
+				m.ExpectQuery(regexp.QuoteMeta(`SELECT uuid, name, email, password, created_at, updated_at, status, status_reason, suspended_until, phone, version FROM users WHERE uuid = $1 AND deleted_at IS NULL LIMIT 1`)).
 					WithArgs("u1").
 					WillReturnRows(newUserRow("u1", "Old", "old@example.com"))
-				m.ExpectQuery(regexp.QuoteMeta(`SELECT r.uuid, r.name
-        FROM roles r
-        INNER JOIN user_roles ur ON ur.role_uuid = r.uuid
-        WHERE ur.user_uuid = $1`)).
+				m.ExpectQuery(regexp.QuoteMeta(`SELECT ur.user_uuid, r.uuid, r.name
+        FROM user_roles ur
+        INNER JOIN roles r ON r.uuid = ur.role_uuid
+        WHERE ur.user_uuid IN ($1)`)).
 					WithArgs("u1").
 					WillReturnRows(sqlmock.NewRows([]string{"uuid", "name"}))
-				m.ExpectQuery(regexp.QuoteMeta(`SELECT p.uuid, p.name
-        FROM permissions p
-        INNER JOIN user_permissions up ON up.permission_uuid = p.uuid
-        WHERE up.user_uuid = $1`)).
+				m.ExpectQuery(regexp.QuoteMeta(`SELECT up.user_uuid, p.uuid, p.name
+        FROM user_permissions up
+        INNER JOIN permissions p ON p.uuid = up.permission_uuid
+        WHERE up.user_uuid IN ($1)`)).
 					WithArgs("u1").
 					WillReturnRows(sqlmock.NewRows([]string{"uuid", "name"}))
-                m.ExpectQuery(regexp.QuoteMeta(`SELECT rp.role_uuid, p.uuid, p.name
+                m.ExpectQuery(regexp.QuoteMeta(`SELECT DISTINCT rp.role_uuid, p.uuid, p.name
         FROM permissions p
         INNER JOIN role_permissions rp ON rp.permission_uuid = p.uuid
         INNER JOIN user_roles ur ON ur.role_uuid = rp.role_uuid
-        WHERE ur.user_uuid = $1`)).
+        WHERE ur.user_uuid IN ($1)`)).
                     WithArgs("u1").
                     WillReturnRows(sqlmock.NewRows([]string{"role_uuid", "uuid", "name"}))
-				m.ExpectExec(regexp.QuoteMeta("UPDATE users SET name = $1, email = $2, updated_at = NOW() WHERE uuid = $3")).
-					WithArgs("Alice", "old@example.com", "u1").
+				m.ExpectBegin()
+				m.ExpectExec(regexp.QuoteMeta("UPDATE users SET name = $1, email = $2, version = version + 1, updated_at = NOW() WHERE uuid = $3 AND version = $4")).
+					WithArgs("Alice", "old@example.com", "u1", 1).
 					WillReturnError(errors.New("update error"))
+				m.ExpectRollback()
 			},
 			expectErr: errcode.ErrInternalServerError,
 		},
@@ -485,7 +634,7 @@ func TestUserService_UpdateUser(t *testing.T) {
 			if tc.setupDB != nil {
 				tc.setupDB(mock)
 			}
-			resp, err := svc.UpdateUser(context.Background(), tc.uuid, tc.req)
+			resp, err := svc.UpdateUser(context.Background(), tc.uuid, 0, tc.req)
 			if tc.expectErr != nil {
 				require.Error(t, err)
 				require.Equal(t, tc.expectErr, err)
@@ -502,9 +651,9 @@ func TestUserService_UpdateUser(t *testing.T) {
 
 func TestUserService_DeleteUser(t *testing.T) {
 	logger := silentLogger()
-	repo, mock, cleanup := setupRepo(t)
+	repo, outbox, auditSvc, uow, mock, cleanup := setupRepo(t)
 	defer cleanup()
-	svc := NewUserService(repo, NewRedisService(&userTestRedisClient{}, logger), logger)
+	svc := NewUserService(repo, outbox, auditSvc, NewRedisService(&userTestRedisClient{}, logger), logger, uow)
 
 	type testcase struct {
 		name      string
@@ -518,7 +667,7 @@ func TestUserService_DeleteUser(t *testing.T) {
 			name: "NotFound",
 			uuid: "missing",
 			setupDB: func(m sqlmock.Sqlmock) {
-				m.ExpectQuery(regexp.QuoteMeta(`SELECT uuid, name, email, password, created_at, updated_at FROM users WHERE uuid = $1 LIMIT 1`)).
+				m.ExpectQuery(regexp.QuoteMeta(`SELECT uuid, name, email, password, created_at, updated_at, status, status_reason, suspended_until, phone, version FROM users WHERE uuid = $1 AND deleted_at IS NULL LIMIT 1`)).
 					WithArgs("missing").
 					WillReturnError(errors.New("no rows"))
 			},
@@ -528,69 +677,111 @@ func TestUserService_DeleteUser(t *testing.T) {
 			name: "DeleteSuccess",
 			uuid: "u1",
 			setupDB: func(m sqlmock.Sqlmock) {
-				userRow := sqlmock.NewRows([]string{"uuid", "name", "email", "password", "created_at", "updated_at"}).
-					AddRow("u1", "Name", "e@example.com", "hash", time.Now(), time.Now())
-				m.ExpectQuery(regexp.QuoteMeta(`SELECT uuid, name, email, password, created_at, updated_at FROM users WHERE uuid = $1 LIMIT 1`)).
+				userRow := sqlmock.NewRows([]string{"uuid", "name", "email", "password", "created_at", "updated_at", "status", "status_reason", "suspended_until", "phone", "version"}).
+					AddRow("u1", "Name", "e@example.com", "hash", time.Now(), time.Now(), "active", "", nil, nil, 1)
+				m.ExpectQuery(regexp.QuoteMeta(`SELECT uuid, name, email, password, created_at, updated_at, status, status_reason, suspended_until, phone, version FROM users WHERE uuid = $1 AND deleted_at IS NULL LIMIT 1`)).
 					WithArgs("u1").
 					WillReturnRows(userRow)
-				m.ExpectQuery(regexp.QuoteMeta(`SELECT r.uuid, r.name
-        FROM roles r
-        INNER JOIN user_roles ur ON ur.role_uuid = r.uuid
-        WHERE ur.user_uuid = $1`)).
+				m.ExpectQuery(regexp.QuoteMeta(`SELECT ur.user_uuid, r.uuid, r.name
+        FROM user_roles ur
+        INNER JOIN roles r ON r.uuid = ur.role_uuid
+        WHERE ur.user_uuid IN ($1)`)).
 					WithArgs("u1").
 					WillReturnRows(sqlmock.NewRows([]string{"uuid", "name"}))
-				m.ExpectQuery(regexp.QuoteMeta(`SELECT p.uuid, p.name
-        FROM permissions p
-        INNER JOIN user_permissions up ON up.permission_uuid = p.uuid
-        WHERE up.user_uuid = $1`)).
+				m.ExpectQuery(regexp.QuoteMeta(`SELECT up.user_uuid, p.uuid, p.name
+        FROM user_permissions up
+        INNER JOIN permissions p ON p.uuid = up.permission_uuid
+        WHERE up.user_uuid IN ($1)`)).
 					WithArgs("u1").
 					WillReturnRows(sqlmock.NewRows([]string{"uuid", "name"}))
-                m.ExpectQuery(regexp.QuoteMeta(`SELECT rp.role_uuid, p.uuid, p.name
+                m.ExpectQuery(regexp.QuoteMeta(`SELECT DISTINCT rp.role_uuid, p.uuid, p.name
         FROM permissions p
         INNER JOIN role_permissions rp ON rp.permission_uuid = p.uuid
         INNER JOIN user_roles ur ON ur.role_uuid = rp.role_uuid
-        WHERE ur.user_uuid = $1`)).
+        WHERE ur.user_uuid IN ($1)`)).
                     WithArgs("u1").
                     WillReturnRows(sqlmock.NewRows([]string{"role_uuid", "uuid", "name"}))
-				m.ExpectExec(regexp.QuoteMeta("DELETE FROM users WHERE uuid = $1")).
-					WithArgs("u1").
+				m.ExpectBegin()
+				m.ExpectExec(regexp.QuoteMeta("UPDATE users SET deleted_at = NOW(), tokens_valid_after = NOW(), version = version + 1 WHERE uuid = $1 AND version = $2 AND deleted_at IS NULL")).
+					WithArgs("u1", int64(1)).
 					WillReturnResult(sqlmock.NewResult(1, 1))
+				expectEvent(m, event.TypeUserDeleted)
+				expectAudit(m, event.TypeUserDeleted)
+				m.ExpectCommit()
 			},
 		},
 		{
 			name: "DeleteExecError",
 			uuid: "u1",
 			setupDB: func(m sqlmock.Sqlmock) {
-				userRow := sqlmock.NewRows([]string{"uuid", "name", "email", "password", "created_at", "updated_at"}).
-					AddRow("u1", "Name", "e@example.com", "hash", time.Now(), time.Now())
-				m.ExpectQuery(regexp.QuoteMeta(`SELECT uuid, name, email, password, created_at, updated_at FROM users WHERE uuid = $1 LIMIT 1`)).
+				userRow := sqlmock.NewRows([]string{"uuid", "name", "email", "password", "created_at", "updated_at", "status", "status_reason", "suspended_until", "phone", "version"}).
+					AddRow("u1", "Name", "e@example.com", "hash", time.Now(), time.Now(), "active", "", nil, nil, 1)
+				m.ExpectQuery(regexp.QuoteMeta(`SELECT uuid, name, email, password, created_at, updated_at, status, status_reason, suspended_until, phone, version FROM users WHERE uuid = $1 AND deleted_at IS NULL LIMIT 1`)).
 					WithArgs("u1").
 					WillReturnRows(userRow)
-				m.ExpectQuery(regexp.QuoteMeta(`SELECT r.uuid, r.name
-        FROM roles r
-        INNER JOIN user_roles ur ON ur.role_uuid = r.uuid
-        WHERE ur.user_uuid = $1`)).
+				m.ExpectQuery(regexp.QuoteMeta(`SELECT ur.user_uuid, r.uuid, r.name
+        FROM user_roles ur
+        INNER JOIN roles r ON r.uuid = ur.role_uuid
+        WHERE ur.user_uuid IN ($1)`)).
 					WithArgs("u1").
 					WillReturnRows(sqlmock.NewRows([]string{"uuid", "name"}))
-				m.ExpectQuery(regexp.QuoteMeta(`SELECT p.uuid, p.name
-        FROM permissions p
-        INNER JOIN user_permissions up ON up.permission_uuid = p.uuid
-        WHERE up.user_uuid = $1`)).
+				m.ExpectQuery(regexp.QuoteMeta(`SELECT up.user_uuid, p.uuid, p.name
+        FROM user_permissions up
+        INNER JOIN permissions p ON p.uuid = up.permission_uuid
+        WHERE up.user_uuid IN ($1)`)).
 					WithArgs("u1").
 					WillReturnRows(sqlmock.NewRows([]string{"uuid", "name"}))
-                m.ExpectQuery(regexp.QuoteMeta(`SELECT rp.role_uuid, p.uuid, p.name
+                m.ExpectQuery(regexp.QuoteMeta(`SELECT DISTINCT rp.role_uuid, p.uuid, p.name
         FROM permissions p
         INNER JOIN role_permissions rp ON rp.permission_uuid = p.uuid
         INNER JOIN user_roles ur ON ur.role_uuid = rp.role_uuid
-        WHERE ur.user_uuid = $1`)).
+        WHERE ur.user_uuid IN ($1)`)).
                     WithArgs("u1").
                     WillReturnRows(sqlmock.NewRows([]string{"role_uuid", "uuid", "name"}))
-				m.ExpectExec(regexp.QuoteMeta("DELETE FROM users WHERE uuid = $1")).
-					WithArgs("u1").
+				m.ExpectBegin()
+				m.ExpectExec(regexp.QuoteMeta("UPDATE users SET deleted_at = NOW(), tokens_valid_after = NOW(), version = version + 1 WHERE uuid = $1 AND version = $2 AND deleted_at IS NULL")).
+					WithArgs("u1", int64(1)).
 					WillReturnError(errors.New("delete error"))
+				m.ExpectRollback()
 			},
 			expectErr: errcode.ErrInternalServerError,
 		},
+		{
+			name: "DeleteConcurrentlyModified",
+			uuid: "u1",
+			setupDB: func(m sqlmock.Sqlmock) {
+				userRow := sqlmock.NewRows([]string{"uuid", "name", "email", "password", "created_at", "updated_at", "status", "status_reason", "suspended_until", "phone", "version"}).
+					AddRow("u1", "Name", "e@example.com", "hash", time.Now(), time.Now(), "active", "", nil, nil, 1)
+				m.ExpectQuery(regexp.QuoteMeta(`SELECT uuid, name, email, password, created_at, updated_at, status, status_reason, suspended_until, phone, version FROM users WHERE uuid = $1 AND deleted_at IS NULL LIMIT 1`)).
+					WithArgs("u1").
+					WillReturnRows(userRow)
+				m.ExpectQuery(regexp.QuoteMeta(`SELECT ur.user_uuid, r.uuid, r.name
+        FROM user_roles ur
+        INNER JOIN roles r ON r.uuid = ur.role_uuid
+        WHERE ur.user_uuid IN ($1)`)).
+					WithArgs("u1").
+					WillReturnRows(sqlmock.NewRows([]string{"uuid", "name"}))
+				m.ExpectQuery(regexp.QuoteMeta(`SELECT up.user_uuid, p.uuid, p.name
+        FROM user_permissions up
+        INNER JOIN permissions p ON p.uuid = up.permission_uuid
+        WHERE up.user_uuid IN ($1)`)).
+					WithArgs("u1").
+					WillReturnRows(sqlmock.NewRows([]string{"uuid", "name"}))
+                m.ExpectQuery(regexp.QuoteMeta(`SELECT DISTINCT rp.role_uuid, p.uuid, p.name
+        FROM permissions p
+        INNER JOIN role_permissions rp ON rp.permission_uuid = p.uuid
+        INNER JOIN user_roles ur ON ur.role_uuid = rp.role_uuid
+        WHERE ur.user_uuid IN ($1)`)).
+                    WithArgs("u1").
+                    WillReturnRows(sqlmock.NewRows([]string{"role_uuid", "uuid", "name"}))
+				m.ExpectBegin()
+				m.ExpectExec(regexp.QuoteMeta("UPDATE users SET deleted_at = NOW(), tokens_valid_after = NOW(), version = version + 1 WHERE uuid = $1 AND version = $2 AND deleted_at IS NULL")).
+					WithArgs("u1", int64(1)).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				m.ExpectRollback()
+			},
+			expectErr: errcode.ErrPreconditionFailed,
+		},
 	}
 
 	for _, tc := range cases {
@@ -598,7 +789,7 @@ func TestUserService_DeleteUser(t *testing.T) {
 			if tc.setupDB != nil {
 				tc.setupDB(mock)
 			}
-			err := svc.DeleteUser(context.Background(), tc.uuid)
+			err := svc.DeleteUser(context.Background(), tc.uuid, 0)
 			if tc.expectErr != nil {
 				require.Error(t, err)
 				require.Equal(t, tc.expectErr, err)
@@ -611,7 +802,7 @@ func TestUserService_DeleteUser(t *testing.T) {
 }
 func TestUserService_CreateUser(t *testing.T) {
 	logger := silentLogger()
-	repo, mock, cleanup := setupRepo(t)
+	repo, outbox, auditSvc, uow, mock, cleanup := setupRepo(t)
 	defer cleanup()
 
 	type testcase struct {
@@ -628,7 +819,7 @@ func TestUserService_CreateUser(t *testing.T) {
 			name: "CountError",
 			req:  &dto.CreateUserRequest{Name: "Alice", Email: "alice@example.com", Password: "pass"},
 			setupDB: func(m sqlmock.Sqlmock) {
-				m.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM users WHERE email = $1")).
+				m.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM users WHERE lower(email) = lower($1) AND deleted_at IS NULL")).
 					WithArgs("alice@example.com").
 					WillReturnError(errors.New("db error"))
 			},
@@ -638,7 +829,7 @@ func TestUserService_CreateUser(t *testing.T) {
 			name: "EmailExists_Conflict",
 			req:  &dto.CreateUserRequest{Name: "Alice", Email: "alice@example.com", Password: "pass"},
 			setupDB: func(m sqlmock.Sqlmock) {
-				m.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM users WHERE email = $1")).
+				m.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM users WHERE lower(email) = lower($1) AND deleted_at IS NULL")).
 					WithArgs("alice@example.com").
 					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
 			},
@@ -648,7 +839,7 @@ func TestUserService_CreateUser(t *testing.T) {
 			name: "HashError",
 			req:  &dto.CreateUserRequest{Name: "Alice", Email: "alice@example.com", Password: "pass"},
 			setupDB: func(m sqlmock.Sqlmock) {
-				m.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM users WHERE email = $1")).
+				m.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM users WHERE lower(email) = lower($1) AND deleted_at IS NULL")).
 					WithArgs("alice@example.com").
 					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
 			},
@@ -661,15 +852,17 @@ func TestUserService_CreateUser(t *testing.T) {
 			name: "CreateExecError",
 			req:  &dto.CreateUserRequest{Name: "Alice", Email: "alice@example.com", Password: "pass"},
 			setupDB: func(m sqlmock.Sqlmock) {
-				m.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM users WHERE email = $1")).
+				m.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM users WHERE lower(email) = lower($1) AND deleted_at IS NULL")).
 					WithArgs("alice@example.com").
 					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+				m.ExpectBegin()
 				m.ExpectExec(regexp.QuoteMeta(`
         INSERT INTO users (uuid, name, email, password, created_at, updated_at)
         VALUES ($1, $2, $3, $4, NOW(), NOW())
     `)).
 					WithArgs(sqlmock.AnyArg(), "Alice", "alice@example.com", sqlmock.AnyArg()).
 					WillReturnError(errors.New("create error"))
+				m.ExpectRollback()
 			},
 			expectErr: errcode.ErrInternalServerError,
 		},
@@ -677,15 +870,19 @@ func TestUserService_CreateUser(t *testing.T) {
 			name: "CreateSuccess",
 			req:  &dto.CreateUserRequest{Name: "Alice", Email: "alice@example.com", Password: "pass"},
 			setupDB: func(m sqlmock.Sqlmock) {
-				m.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM users WHERE email = $1")).
+				m.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM users WHERE lower(email) = lower($1) AND deleted_at IS NULL")).
 					WithArgs("alice@example.com").
 					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+				m.ExpectBegin()
 				m.ExpectExec(regexp.QuoteMeta(`
         INSERT INTO users (uuid, name, email, password, created_at, updated_at)
         VALUES ($1, $2, $3, $4, NOW(), NOW())
     `)).
 					WithArgs(sqlmock.AnyArg(), "Alice", "alice@example.com", sqlmock.AnyArg()).
 					WillReturnResult(sqlmock.NewResult(1, 1))
+				expectEvent(m, event.TypeUserCreated)
+				expectAudit(m, event.TypeUserCreated)
+				m.ExpectCommit()
 			},
 			assert: func(t *testing.T, resp *dto.UserResponse) {
 				require.NotNil(t, resp)
@@ -693,6 +890,21 @@ func TestUserService_CreateUser(t *testing.T) {
 				require.Equal(t, "alice@example.com", resp.Email)
 			},
 		},
+		{
+			name: "CommitError",
+			req:  &dto.CreateUserRequest{Name: "Alice", Email: "alice@example.com", Password: "pass"},
+			setupDB: func(m sqlmock.Sqlmock) {
+				m.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM users WHERE lower(email) = lower($1) AND deleted_at IS NULL")).
+					WithArgs("alice@example.com").
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+				m.ExpectBegin()
+				m.ExpectExec(regexp.QuoteMeta("INSERT INTO users")).WillReturnResult(sqlmock.NewResult(1, 1))
+				expectEvent(m, event.TypeUserCreated)
+				expectAudit(m, event.TypeUserCreated)
+				m.ExpectCommit().WillReturnError(errors.New("commit error"))
+			},
+			expectErr: errcode.ErrDatabaseTransaction,
+		},
 	}
 
 	for _, tc := range cases {
@@ -701,7 +913,7 @@ func TestUserService_CreateUser(t *testing.T) {
 				tc.setupDB(mock)
 			}
 			// fresh service per test to avoid state leakage from mutateSvc
-			svc := NewUserService(repo, NewRedisService(&userTestRedisClient{}, logger), logger)
+			svc := NewUserService(repo, outbox, auditSvc, NewRedisService(&userTestRedisClient{}, logger), logger, uow)
 			if tc.mutateSvc != nil {
 				tc.mutateSvc(svc)
 			}
@@ -719,3 +931,1053 @@ func TestUserService_CreateUser(t *testing.T) {
 		})
 	}
 }
+
+// expectUserPermissions mocks FindByUUID for a user with a direct "read-other"
+// permission and two roles that both grant "read-user".
+func expectUserPermissions(m sqlmock.Sqlmock, uuid string) {
+	m.ExpectQuery(regexp.QuoteMeta(`FROM users WHERE uuid = $1`)).
+		WithArgs(uuid).
+		WillReturnRows(sqlmock.NewRows([]string{"uuid", "name", "email", "password", "created_at", "updated_at", "status", "status_reason", "suspended_until", "phone", "version"}).
+			AddRow(uuid, "Alice", "alice@example.com", "hash", time.Now(), time.Now(), "active", "", nil, nil, 1))
+	m.ExpectQuery(regexp.QuoteMeta(`INNER JOIN roles r`)).
+		WithArgs(uuid).
+		WillReturnRows(sqlmock.NewRows([]string{"user_uuid", "uuid", "name"}).AddRow(uuid, "r1", "admin").AddRow(uuid, "r2", "user"))
+	m.ExpectQuery(regexp.QuoteMeta(`FROM user_permissions up`)).
+		WithArgs(uuid).
+		WillReturnRows(sqlmock.NewRows([]string{"user_uuid", "uuid", "name"}).AddRow(uuid, "p9", "read-other"))
+	m.ExpectQuery(regexp.QuoteMeta(`INNER JOIN role_permissions rp`)).
+		WithArgs(uuid).
+		WillReturnRows(sqlmock.NewRows([]string{"role_uuid", "uuid", "name"}).
+			AddRow("r1", "p1", "read-user").
+			AddRow("r1", "p2", "delete-user").
+			AddRow("r2", "p1", "read-user"))
+}
+
+func TestUserService_GetEffectivePermissions(t *testing.T) {
+	logger := silentLogger()
+	repo, outbox, auditSvc, uow, mock, cleanup := setupRepo(t)
+	defer cleanup()
+	svc := NewUserService(repo, outbox, auditSvc, NewRedisService(&userTestRedisClient{}, logger), logger, uow)
+
+	t.Run("Success", func(t *testing.T) {
+		expectUserPermissions(mock, "user-1")
+		resp, err := svc.GetEffectivePermissions(context.Background(), "user-1")
+		require.NoError(t, err)
+		require.Equal(t, "user-1", resp.UserUUID)
+		require.Len(t, resp.Permissions, 3)
+		// sorted by name
+		require.Equal(t, "delete-user", resp.Permissions[0].Name)
+		require.Equal(t, "read-other", resp.Permissions[1].Name)
+		require.Equal(t, []dto.PermissionSourceResponse{{Type: "direct"}}, resp.Permissions[1].Sources)
+		require.Equal(t, "read-user", resp.Permissions[2].Name)
+		require.Equal(t, []dto.PermissionSourceResponse{{Type: "role", Role: "admin"}, {Type: "role", Role: "user"}}, resp.Permissions[2].Sources)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta(`FROM users WHERE uuid = $1`)).
+			WithArgs("missing").
+			WillReturnError(errors.New("no rows"))
+		_, err := svc.GetEffectivePermissions(context.Background(), "missing")
+		require.ErrorIs(t, err, errcode.ErrUserNotFound)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestUserService_CheckPermission(t *testing.T) {
+	logger := silentLogger()
+	repo, outbox, auditSvc, uow, mock, cleanup := setupRepo(t)
+	defer cleanup()
+	svc := NewUserService(repo, outbox, auditSvc, NewRedisService(&userTestRedisClient{}, logger), logger, uow)
+
+	cases := []struct {
+		name       string
+		permission string
+		allowed    bool
+		reason     string
+	}{
+		{name: "AllowedViaRoles", permission: "read-user", allowed: true, reason: `granted via role "admin"; granted via role "user"`},
+		{name: "AllowedDirect", permission: "read-other", allowed: true, reason: "granted directly via user_permissions"},
+		{name: "Denied", permission: "write-role", allowed: false, reason: `permission "write-role" is not granted directly or by any of the user's roles (admin, user)`},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			expectUserPermissions(mock, "user-1")
+			resp, err := svc.CheckPermission(context.Background(), "user-1", tc.permission)
+			require.NoError(t, err)
+			require.Equal(t, tc.allowed, resp.Allowed)
+			require.Equal(t, tc.reason, resp.Reason)
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+
+	t.Run("NotFound", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta(`FROM users WHERE uuid = $1`)).
+			WithArgs("missing").
+			WillReturnError(errors.New("no rows"))
+		_, err := svc.CheckPermission(context.Background(), "missing", "read-user")
+		require.ErrorIs(t, err, errcode.ErrUserNotFound)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestUserService_BulkUsers(t *testing.T) {
+	logger := silentLogger()
+	repo, outbox, auditSvc, uow, mock, cleanup := setupRepo(t)
+	defer cleanup()
+	var invalidated []string
+	redisClient := &userTestRedisClient{delFunc: func(ctx context.Context, keys ...string) *redis.IntCmd {
+		invalidated = append(invalidated, keys...)
+		return redis.NewIntCmd(ctx)
+	}}
+	svc := NewUserService(repo, outbox, auditSvc, NewRedisService(redisClient, logger), logger, uow)
+	svc.hashPassword = func(password []byte, _ int) ([]byte, error) { return password, nil }
+
+	expectCreate := func(m sqlmock.Sqlmock, email string, existing int) {
+		m.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM users WHERE lower(email) = lower($1) AND deleted_at IS NULL")).
+			WithArgs(email).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(existing))
+		if existing == 0 {
+			m.ExpectExec(regexp.QuoteMeta("INSERT INTO users")).
+				WillReturnResult(sqlmock.NewResult(1, 1))
+			expectEvent(m, event.TypeUserCreated)
+			expectAudit(m, event.TypeUserCreated)
+		}
+	}
+	expectDelete := func(m sqlmock.Sqlmock, uuid string) {
+		m.ExpectQuery(regexp.QuoteMeta("FROM users WHERE uuid = $1 AND deleted_at IS NULL LIMIT 1")).
+			WithArgs(uuid).
+			WillReturnRows(sqlmock.NewRows([]string{"uuid", "name", "email", "password", "created_at", "updated_at", "status", "status_reason", "suspended_until", "phone", "version"}).
+				AddRow(uuid, "Name", "e@example.com", "hash", time.Now(), time.Now(), "active", "", nil, nil, 1))
+		m.ExpectQuery(regexp.QuoteMeta("INNER JOIN roles r")).WillReturnRows(sqlmock.NewRows([]string{"uuid", "name"}))
+		m.ExpectQuery(regexp.QuoteMeta("INNER JOIN permissions p")).WillReturnRows(sqlmock.NewRows([]string{"uuid", "name"}))
+		m.ExpectQuery(regexp.QuoteMeta("INNER JOIN role_permissions rp")).WillReturnRows(sqlmock.NewRows([]string{"role_uuid", "uuid", "name"}))
+		m.ExpectExec(regexp.QuoteMeta("UPDATE users SET deleted_at = NOW()")).
+			WithArgs(uuid, int64(1)).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		expectEvent(m, event.TypeUserDeleted)
+		expectAudit(m, event.TypeUserDeleted)
+	}
+	ops := func() []*dto.BulkUserOperation {
+		return []*dto.BulkUserOperation{
+			{Op: constant.BulkOperationCreate, Create: &dto.CreateUserRequest{Name: "Alice", Email: "alice@example.com", Password: "secret123"}},
+			{Op: constant.BulkOperationDelete, UUID: "u1"},
+		}
+	}
+
+	type testcase struct {
+		name      string
+		ops       []*dto.BulkUserOperation
+		atomic    bool
+		setupDB   func(sqlmock.Sqlmock)
+		expectErr error
+		statuses  []int
+		// invalidated lists the cache keys dropped, only after a commit
+		invalidated []string
+	}
+
+	cases := []testcase{
+		{
+			name:   "Atomic_AllSucceed",
+			ops:    ops(),
+			atomic: true,
+			setupDB: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				expectCreate(m, "alice@example.com", 0)
+				expectDelete(m, "u1")
+				m.ExpectCommit()
+			},
+			statuses:    []int{201, 200},
+			invalidated: []string{"user:me:u1"},
+		},
+		{
+			name: "Atomic_RollbackKeepsCache",
+			ops: []*dto.BulkUserOperation{
+				{Op: constant.BulkOperationDelete, UUID: "u1"},
+				{Op: constant.BulkOperationCreate, Create: &dto.CreateUserRequest{Name: "Alice", Email: "alice@example.com", Password: "secret123"}},
+			},
+			atomic: true,
+			setupDB: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				expectDelete(m, "u1")
+				expectCreate(m, "alice@example.com", 1)
+				m.ExpectRollback()
+			},
+			statuses: []int{424, 409},
+		},
+		{
+			name:   "Atomic_FailureRollsBackBatch",
+			ops:    ops(),
+			atomic: true,
+			setupDB: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				expectCreate(m, "alice@example.com", 0)
+				m.ExpectQuery(regexp.QuoteMeta("FROM users WHERE uuid = $1")).
+					WithArgs("u1").
+					WillReturnError(sql.ErrNoRows)
+				m.ExpectRollback()
+			},
+			statuses: []int{424, 404},
+		},
+		{
+			name: "Atomic_InvalidItemSkipsDatabase",
+			ops: append(ops(), &dto.BulkUserOperation{
+				Op:     constant.BulkOperationUpdate,
+				Errors: map[string][]string{"uuid": {"uuid is required"}},
+			}),
+			atomic:   true,
+			setupDB:  func(sqlmock.Sqlmock) {},
+			statuses: []int{424, 424, 400},
+		},
+		{
+			name:   "Atomic_CommitFails",
+			ops:    ops()[:1],
+			atomic: true,
+			setupDB: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				expectCreate(m, "alice@example.com", 0)
+				m.ExpectCommit().WillReturnError(errors.New("commit failed"))
+			},
+			expectErr: errcode.ErrDatabaseTransaction,
+		},
+		{
+			name: "BestEffort_ReportsPerItem",
+			ops: append(ops(), &dto.BulkUserOperation{
+				Op:  "rename",
+				Err: errcode.ErrInvalidBulkOperation,
+			}),
+			setupDB: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				expectCreate(m, "alice@example.com", 1)
+				m.ExpectRollback()
+				m.ExpectBegin()
+				expectDelete(m, "u1")
+				m.ExpectCommit()
+			},
+			statuses:    []int{409, 200, 400},
+			invalidated: []string{"user:me:u1"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			invalidated = nil
+			tc.setupDB(mock)
+			res, err := svc.BulkUsers(context.Background(), tc.ops, tc.atomic)
+			if tc.expectErr != nil {
+				require.Equal(t, tc.expectErr, err)
+			} else {
+				require.NoError(t, err)
+				statuses := make([]int, len(res.Results))
+				failed := 0
+				for i, result := range res.Results {
+					require.Equal(t, i, result.Index)
+					statuses[i] = result.Status
+					if result.Status >= 300 {
+						failed++
+					}
+				}
+				require.Equal(t, tc.statuses, statuses)
+				require.Equal(t, failed, res.Failed)
+				require.Equal(t, len(tc.ops)-failed, res.Succeeded)
+			}
+			require.Equal(t, tc.invalidated, invalidated)
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestUserService_ImportUsers_DryRun(t *testing.T) {
+	logger := silentLogger()
+	repo, outbox, auditSvc, uow, mock, cleanup := setupRepo(t)
+	defer cleanup()
+	svc := NewUserService(repo, outbox, auditSvc, NewRedisService(&userTestRedisClient{}, logger), logger, uow)
+
+	newOps := func() []*dto.BulkUserOperation {
+		return []*dto.BulkUserOperation{
+			{Op: constant.BulkOperationCreate, Line: 2, Create: &dto.CreateUserRequest{Email: "new@example.com"}},
+			{Op: constant.BulkOperationCreate, Line: 3, Create: &dto.CreateUserRequest{Email: "taken@example.com"}},
+			{Op: constant.BulkOperationCreate, Line: 4, Create: &dto.CreateUserRequest{Email: "NEW@example.com"}},
+			{Op: constant.BulkOperationCreate, Line: 5, Errors: map[string][]string{"email": {"email is required"}}},
+		}
+	}
+	expectCounts := func() {
+		for _, c := range []struct {
+			email string
+			count int
+		}{{"new@example.com", 0}, {"taken@example.com", 1}, {"NEW@example.com", 0}} {
+			mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM users WHERE lower(email) = lower($1)")).
+				WithArgs(c.email).
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(c.count))
+		}
+	}
+
+	// Nothing is written: only email lookups, no transaction
+	expectCounts()
+	res, err := svc.ImportUsers(context.Background(), newOps(), false, true)
+	require.NoError(t, err)
+	require.True(t, res.DryRun)
+	require.Equal(t, 1, res.Succeeded)
+	require.Equal(t, 3, res.Failed)
+	require.Equal(t, http.StatusCreated, res.Results[0].Status)
+	require.Equal(t, http.StatusConflict, res.Results[1].Status)
+	require.Equal(t, http.StatusConflict, res.Results[2].Status, "duplicate of an earlier row")
+	require.Equal(t, http.StatusBadRequest, res.Results[3].Status)
+	require.Equal(t, 5, res.Results[3].Line)
+
+	// In atomic mode the would-be successes report they would be rolled back
+	expectCounts()
+	res, err = svc.ImportUsers(context.Background(), newOps(), true, true)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusFailedDependency, res.Results[0].Status)
+	require.Zero(t, res.Succeeded)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUserService_ExportUsers(t *testing.T) {
+	logger := silentLogger()
+	repo, outbox, auditSvc, uow, mock, cleanup := setupRepo(t)
+	defer cleanup()
+	svc := NewUserService(repo, outbox, auditSvc, NewRedisService(&userTestRedisClient{}, logger), logger, uow)
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT uuid, name, email, phone, status, created_at, updated_at, deleted_at FROM users WHERE deleted_at IS NULL ORDER BY created_at DESC, uuid ASC")).
+		WillReturnRows(sqlmock.NewRows([]string{"uuid", "name", "email", "phone", "status", "created_at", "updated_at", "deleted_at"}).
+			AddRow("u1", "Alice", "a@example.com", nil, "active", time.Now(), time.Now(), nil))
+
+	export, err := svc.ExportUsers(context.Background(), &dto.SearchUserRequest{})
+	require.NoError(t, err)
+	user, err := export.Next()
+	require.NoError(t, err)
+	require.Equal(t, "Alice", user.Name)
+	_, err = export.Next()
+	require.ErrorIs(t, err, io.EOF)
+	require.NoError(t, export.Close())
+
+	_, err = svc.ExportUsers(context.Background(), &dto.SearchUserRequest{Match: "xor"})
+	require.Equal(t, errcode.ErrInvalidFilter, err)
+
+	mock.ExpectQuery(regexp.QuoteMeta("FROM users")).WillReturnError(errors.New("db down"))
+	_, err = svc.ExportUsers(context.Background(), &dto.SearchUserRequest{})
+	require.Equal(t, errcode.ErrUserSearchFailed, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUserService_RestoreUser(t *testing.T) {
+	logger := silentLogger()
+	repo, outbox, auditSvc, uow, mock, cleanup := setupRepo(t)
+	defer cleanup()
+	svc := NewUserService(repo, outbox, auditSvc, NewRedisService(&userTestRedisClient{}, logger), logger, uow)
+
+	findDeleted := `SELECT uuid, name, email, password, version, created_at, updated_at, deleted_at FROM users WHERE uuid = $1 AND deleted_at IS NOT NULL LIMIT 1`
+	deletedRow := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"uuid", "name", "email", "password", "version", "created_at", "updated_at", "deleted_at"}).
+			AddRow("u1", "Name", "e@example.com", "hash", 1, time.Now(), time.Now(), time.Now())
+	}
+
+	cases := []struct {
+		name      string
+		setupDB   func(sqlmock.Sqlmock)
+		expectErr error
+	}{
+		{
+			name: "Success",
+			setupDB: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				m.ExpectQuery(regexp.QuoteMeta(findDeleted)).WithArgs("u1").WillReturnRows(deletedRow())
+				m.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM users WHERE lower(email) = lower($1) AND deleted_at IS NULL")).
+					WithArgs("e@example.com").
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+				m.ExpectExec(regexp.QuoteMeta("UPDATE users SET deleted_at = NULL, version = version + 1, updated_at = NOW() WHERE uuid = $1 AND deleted_at IS NOT NULL")).
+					WithArgs("u1").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				expectEvent(m, event.TypeUserRestored)
+				expectAudit(m, event.TypeUserRestored)
+				m.ExpectCommit()
+			},
+		},
+		{
+			name: "NotFound",
+			setupDB: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				m.ExpectQuery(regexp.QuoteMeta(findDeleted)).WithArgs("u1").WillReturnError(errors.New("no rows"))
+				m.ExpectRollback()
+			},
+			expectErr: errcode.ErrUserNotFound,
+		},
+		{
+			name: "EmailTaken",
+			setupDB: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				m.ExpectQuery(regexp.QuoteMeta(findDeleted)).WithArgs("u1").WillReturnRows(deletedRow())
+				m.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM users WHERE lower(email) = lower($1) AND deleted_at IS NULL")).
+					WithArgs("e@example.com").
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+				m.ExpectRollback()
+			},
+			expectErr: errcode.ErrUserAlreadyExists,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.setupDB(mock)
+			resp, err := svc.RestoreUser(context.Background(), "u1")
+			if tc.expectErr != nil {
+				require.ErrorIs(t, err, tc.expectErr)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, "u1", resp.UUID)
+				require.Zero(t, resp.DeletedAt)
+			}
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestUserService_PurgeDeletedUsers(t *testing.T) {
+	logger := silentLogger()
+	repo, outbox, auditSvc, uow, mock, cleanup := setupRepo(t)
+	defer cleanup()
+	svc := NewUserService(repo, outbox, auditSvc, NewRedisService(&userTestRedisClient{}, logger), logger, uow)
+
+	t.Run("Success", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta("DELETE FROM user_roles")).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta("DELETE FROM user_permissions")).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(regexp.QuoteMeta("DELETE FROM users WHERE deleted_at < $1")).WillReturnResult(sqlmock.NewResult(0, 2))
+		expectAudit(mock, "user.purged")
+		mock.ExpectCommit()
+
+		purged, err := svc.PurgeDeletedUsers(context.Background(), time.Hour)
+		require.NoError(t, err)
+		require.Equal(t, int64(2), purged)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("DatabaseError", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta("DELETE FROM user_roles")).WillReturnError(errors.New("db down"))
+		mock.ExpectRollback()
+
+		_, err := svc.PurgeDeletedUsers(context.Background(), time.Hour)
+		require.ErrorIs(t, err, errcode.ErrDatabaseError)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestUserService_SuspendAndReactivate(t *testing.T) {
+	logger := silentLogger()
+	repo, outbox, auditSvc, uow, mock, cleanup := setupRepo(t)
+	defer cleanup()
+
+	var invalidated []string
+	redisClient := &userTestRedisClient{delFunc: func(ctx context.Context, keys ...string) *redis.IntCmd {
+		invalidated = append(invalidated, keys...)
+		return redis.NewIntCmd(ctx)
+	}}
+	svc := NewUserService(repo, outbox, auditSvc, NewRedisService(redisClient, logger), logger, uow)
+
+	updateStatus := regexp.QuoteMeta(`UPDATE users SET status = $1, status_reason = $2, suspended_until = $3, version = version + 1, updated_at = NOW() WHERE uuid = $4 AND version = $5 AND deleted_at IS NULL`)
+	until := time.Now().Add(24 * time.Hour).Unix()
+
+	cases := []struct {
+		name      string
+		action    func() (*dto.UserResponse, error)
+		setupDB   func(sqlmock.Sqlmock)
+		expectErr error
+		assert    func(t *testing.T, resp *dto.UserResponse)
+	}{
+		{
+			name: "SuspendUntil",
+			action: func() (*dto.UserResponse, error) {
+				return svc.SuspendUser(context.Background(), "user-1", &dto.SuspendUserRequest{Reason: "chargeback", Until: until})
+			},
+			setupDB: func(m sqlmock.Sqlmock) {
+				expectUserPermissions(m, "user-1")
+				m.ExpectBegin()
+				m.ExpectExec(updateStatus).
+					WithArgs("suspended", "chargeback", sqlmock.AnyArg(), "user-1", int64(1)).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				expectEvent(m, event.TypeUserStatusChanged)
+				expectAudit(m, event.TypeUserStatusChanged)
+				m.ExpectCommit()
+			},
+			assert: func(t *testing.T, resp *dto.UserResponse) {
+				require.Equal(t, "suspended", resp.Status)
+				require.Equal(t, "chargeback", resp.StatusReason)
+				require.Equal(t, until, resp.SuspendedUntil)
+			},
+		},
+		{
+			name: "SuspendUntilInPast",
+			action: func() (*dto.UserResponse, error) {
+				return svc.SuspendUser(context.Background(), "user-1", &dto.SuspendUserRequest{Until: time.Now().Add(-time.Hour).Unix()})
+			},
+			setupDB:   func(sqlmock.Sqlmock) {},
+			expectErr: errcode.ErrInvalidSuspension,
+		},
+		{
+			name: "SuspendNotFound",
+			action: func() (*dto.UserResponse, error) {
+				return svc.SuspendUser(context.Background(), "missing", &dto.SuspendUserRequest{})
+			},
+			setupDB: func(m sqlmock.Sqlmock) {
+				m.ExpectQuery(regexp.QuoteMeta(`FROM users WHERE uuid = $1`)).
+					WithArgs("missing").
+					WillReturnError(errors.New("no rows"))
+			},
+			expectErr: errcode.ErrUserNotFound,
+		},
+		{
+			name: "SuspendUpdateError",
+			action: func() (*dto.UserResponse, error) {
+				return svc.SuspendUser(context.Background(), "user-1", &dto.SuspendUserRequest{})
+			},
+			setupDB: func(m sqlmock.Sqlmock) {
+				expectUserPermissions(m, "user-1")
+				m.ExpectBegin()
+				m.ExpectExec(updateStatus).
+					WithArgs("suspended", "", nil, "user-1", int64(1)).
+					WillReturnError(errors.New("db down"))
+				m.ExpectRollback()
+			},
+			expectErr: errcode.ErrInternalServerError,
+		},
+		{
+			name: "SuspendDeletedMeanwhile",
+			action: func() (*dto.UserResponse, error) {
+				return svc.SuspendUser(context.Background(), "user-1", &dto.SuspendUserRequest{})
+			},
+			setupDB: func(m sqlmock.Sqlmock) {
+				expectUserPermissions(m, "user-1")
+				m.ExpectBegin()
+				m.ExpectExec(updateStatus).
+					WithArgs("suspended", "", nil, "user-1", int64(1)).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				m.ExpectRollback()
+			},
+			expectErr: errcode.ErrPreconditionFailed,
+		},
+		{
+			name: "Reactivate",
+			action: func() (*dto.UserResponse, error) {
+				return svc.ReactivateUser(context.Background(), "user-1")
+			},
+			setupDB: func(m sqlmock.Sqlmock) {
+				expectUserPermissions(m, "user-1")
+				m.ExpectBegin()
+				m.ExpectExec(updateStatus).
+					WithArgs("active", "", nil, "user-1", int64(1)).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				expectEvent(m, event.TypeUserStatusChanged)
+				expectAudit(m, event.TypeUserStatusChanged)
+				m.ExpectCommit()
+			},
+			assert: func(t *testing.T, resp *dto.UserResponse) {
+				require.Equal(t, "active", resp.Status)
+				require.Empty(t, resp.StatusReason)
+				require.Zero(t, resp.SuspendedUntil)
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			invalidated = nil
+			tc.setupDB(mock)
+			resp, err := tc.action()
+			if tc.expectErr != nil {
+				require.ErrorIs(t, err, tc.expectErr)
+				require.Empty(t, invalidated)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, []string{"user:me:user-1"}, invalidated)
+				tc.assert(t, resp)
+			}
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func setupUserServiceWithRedis(t *testing.T) (*UserService, sqlmock.Sqlmock, *miniredis.Miniredis, *[]string) {
+	t.Helper()
+	logger := silentLogger()
+	repo, outbox, auditSvc, uow, mock, cleanup := setupRepo(t)
+	t.Cleanup(cleanup)
+	mr := miniredis.RunT(t)
+	svc := NewUserService(repo, outbox, auditSvc, NewRedisService(redis.NewClient(&redis.Options{Addr: mr.Addr()}), logger), logger, uow)
+
+	tokens := []string{}
+	svc.UseNotifier(notifyFunc(func(_ context.Context, msg notify.Message) error {
+		tokens = append(tokens, msg.Data["Token"].(string))
+		return nil
+	}))
+	return svc, mock, mr, &tokens
+}
+
+// notifyFunc adapts a function to notify.Notifier.
+type notifyFunc func(ctx context.Context, msg notify.Message) error
+
+func (f notifyFunc) Notify(ctx context.Context, msg notify.Message) error {
+	return f(ctx, msg)
+}
+
+func TestUserService_UpdateMe(t *testing.T) {
+	name := "Alice Cooper"
+	email := "new@example.com"
+	updateQuery := regexp.QuoteMeta(`UPDATE users SET name = $1, email = $2, version = version + 1, updated_at = NOW() WHERE uuid = $3 AND version = $4`)
+	countQuery := regexp.QuoteMeta(`SELECT COUNT(*) FROM users WHERE lower(email) = lower($1) AND deleted_at IS NULL`)
+
+	cases := []struct {
+		name      string
+		req       *dto.UpdateMeRequest
+		setupDB   func(sqlmock.Sqlmock)
+		expectErr error
+		assert    func(t *testing.T, resp *dto.UserResponse, mr *miniredis.Miniredis, tokens []string)
+	}{
+		{
+			name: "NameOnly",
+			req:  &dto.UpdateMeRequest{Name: &name},
+			setupDB: func(m sqlmock.Sqlmock) {
+				expectUserPermissions(m, "user-1")
+				m.ExpectBegin()
+				m.ExpectExec(updateQuery).WithArgs(name, "alice@example.com", "user-1", 1).WillReturnResult(sqlmock.NewResult(0, 1))
+				expectEvent(m, event.TypeUserUpdated)
+				expectAudit(m, event.TypeUserUpdated)
+				m.ExpectCommit()
+			},
+			assert: func(t *testing.T, resp *dto.UserResponse, mr *miniredis.Miniredis, tokens []string) {
+				require.Equal(t, name, resp.Name)
+				require.Empty(t, resp.PendingEmail)
+				require.Empty(t, tokens)
+				require.False(t, mr.Exists("user:me:user-1"))
+			},
+		},
+		{
+			name: "EmailChangeIsPending",
+			req:  &dto.UpdateMeRequest{Email: &email},
+			setupDB: func(m sqlmock.Sqlmock) {
+				expectUserPermissions(m, "user-1")
+				m.ExpectQuery(countQuery).WithArgs(email).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+			},
+			assert: func(t *testing.T, resp *dto.UserResponse, mr *miniredis.Miniredis, tokens []string) {
+				require.Equal(t, "alice@example.com", resp.Email)
+				require.Equal(t, email, resp.PendingEmail)
+				require.Len(t, tokens, 1)
+				require.True(t, mr.Exists("user:email-verification:"+tokens[0]))
+			},
+		},
+		{
+			name: "EmailTaken",
+			req:  &dto.UpdateMeRequest{Email: &email},
+			setupDB: func(m sqlmock.Sqlmock) {
+				expectUserPermissions(m, "user-1")
+				m.ExpectQuery(countQuery).WithArgs(email).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+			},
+			expectErr: errcode.ErrUserAlreadyExists,
+		},
+		{
+			name: "NotFound",
+			req:  &dto.UpdateMeRequest{Name: &name},
+			setupDB: func(m sqlmock.Sqlmock) {
+				m.ExpectQuery(regexp.QuoteMeta(`FROM users WHERE uuid = $1`)).WithArgs("user-1").WillReturnError(errors.New("no rows"))
+			},
+			expectErr: errcode.ErrUserNotFound,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			svc, mock, mr, tokens := setupUserServiceWithRedis(t)
+			mr.Set("user:me:user-1", "{}")
+			tc.setupDB(mock)
+
+			resp, err := svc.UpdateMe(context.Background(), "user-1", tc.req)
+			if tc.expectErr != nil {
+				require.ErrorIs(t, err, tc.expectErr)
+			} else {
+				require.NoError(t, err)
+				tc.assert(t, resp, mr, *tokens)
+			}
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestUserService_UpdateMe_QueuesEmailVerification(t *testing.T) {
+	svc, mock, mr, _ := setupUserServiceWithRedis(t)
+	jobs := queue.NewClient(redis.NewClient(&redis.Options{Addr: mr.Addr()}), "jobs:", 3)
+	svc.UseNotifier(notify.NewQueued(jobs))
+	worker := queue.NewWorker(jobs, silentLogger(), queue.WorkerOptions{})
+	var sent []notify.Message
+	notify.RegisterJobs(worker, notifyFunc(func(_ context.Context, msg notify.Message) error {
+		sent = append(sent, msg)
+		return nil
+	}))
+
+	email := "new@example.com"
+	expectUserPermissions(mock, "user-1")
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT COUNT(*) FROM users WHERE lower(email) = lower($1)`)).WithArgs(email).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+
+	_, err := svc.UpdateMe(notify.WithLocale(context.Background(), "de-DE"), "user-1", &dto.UpdateMeRequest{Email: &email})
+	require.NoError(t, err)
+	require.Empty(t, sent)
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	// The token only goes out once a worker runs the job, in the locale of
+	// the request
+	worked, err := worker.Work(context.Background())
+	require.NoError(t, err)
+	require.True(t, worked)
+	require.Len(t, sent, 1)
+	require.Equal(t, email, sent[0].To)
+	require.Equal(t, notify.TemplateEmailVerification, sent[0].Template)
+	require.Equal(t, "de-DE", sent[0].Locale)
+	require.True(t, mr.Exists("user:email-verification:"+sent[0].Data["Token"].(string)))
+}
+
+func TestUserService_VerifyEmail(t *testing.T) {
+	countQuery := regexp.QuoteMeta(`SELECT COUNT(*) FROM users WHERE lower(email) = lower($1) AND deleted_at IS NULL`)
+	updateQuery := regexp.QuoteMeta(`UPDATE users SET name = $1, email = $2, version = version + 1, updated_at = NOW() WHERE uuid = $3 AND version = $4`)
+
+	t.Run("Success", func(t *testing.T) {
+		svc, mock, mr, _ := setupUserServiceWithRedis(t)
+		mr.Set("user:email-verification:tok", `{"uuid":"user-1","email":"new@example.com"}`)
+		mr.Set("user:me:user-1", "{}")
+
+		mock.ExpectBegin()
+		expectUserPermissions(mock, "user-1")
+		mock.ExpectQuery(countQuery).WithArgs("new@example.com").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+		mock.ExpectExec(updateQuery).WithArgs("Alice", "new@example.com", "user-1", 1).WillReturnResult(sqlmock.NewResult(0, 1))
+		expectEvent(mock, event.TypeUserUpdated)
+		expectAudit(mock, event.TypeUserUpdated)
+		mock.ExpectCommit()
+
+		resp, err := svc.VerifyEmail(context.Background(), "user-1", "tok")
+		require.NoError(t, err)
+		require.Equal(t, "new@example.com", resp.Email)
+		require.False(t, mr.Exists("user:email-verification:tok"))
+		require.False(t, mr.Exists("user:me:user-1"))
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("UnknownToken", func(t *testing.T) {
+		svc, mock, _, _ := setupUserServiceWithRedis(t)
+		_, err := svc.VerifyEmail(context.Background(), "user-1", "missing")
+		require.ErrorIs(t, err, errcode.ErrInvalidEmailToken)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("TokenOfAnotherUser", func(t *testing.T) {
+		svc, mock, mr, _ := setupUserServiceWithRedis(t)
+		mr.Set("user:email-verification:tok", `{"uuid":"user-2","email":"new@example.com"}`)
+		_, err := svc.VerifyEmail(context.Background(), "user-1", "tok")
+		require.ErrorIs(t, err, errcode.ErrInvalidEmailToken)
+		require.True(t, mr.Exists("user:email-verification:tok"))
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("EmailTakenMeanwhile", func(t *testing.T) {
+		svc, mock, mr, _ := setupUserServiceWithRedis(t)
+		mr.Set("user:email-verification:tok", `{"uuid":"user-1","email":"new@example.com"}`)
+
+		mock.ExpectBegin()
+		expectUserPermissions(mock, "user-1")
+		mock.ExpectQuery(countQuery).WithArgs("new@example.com").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+		mock.ExpectRollback()
+
+		_, err := svc.VerifyEmail(context.Background(), "user-1", "tok")
+		require.ErrorIs(t, err, errcode.ErrUserAlreadyExists)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestUserService_DeleteMe(t *testing.T) {
+	hashed, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	expectUser := func(m sqlmock.Sqlmock) {
+		m.ExpectQuery(regexp.QuoteMeta(`FROM users WHERE uuid = $1`)).
+			WithArgs("user-1").
+			WillReturnRows(sqlmock.NewRows([]string{"uuid", "name", "email", "password", "created_at", "updated_at", "status", "status_reason", "suspended_until", "phone", "version"}).
+				AddRow("user-1", "Alice", "alice@example.com", string(hashed), time.Now(), time.Now(), "active", "", nil, nil, 1))
+		m.ExpectQuery(regexp.QuoteMeta(`INNER JOIN roles r`)).WithArgs("user-1").WillReturnRows(sqlmock.NewRows([]string{"uuid", "name"}))
+		m.ExpectQuery(regexp.QuoteMeta(`FROM user_permissions up`)).WithArgs("user-1").WillReturnRows(sqlmock.NewRows([]string{"uuid", "name"}))
+		m.ExpectQuery(regexp.QuoteMeta(`INNER JOIN role_permissions rp`)).WithArgs("user-1").WillReturnRows(sqlmock.NewRows([]string{"role_uuid", "uuid", "name"}))
+	}
+
+	cases := []struct {
+		name      string
+		password  string
+		setupDB   func(sqlmock.Sqlmock)
+		expectErr error
+		revoked   []constant.TokenType
+	}{
+		{
+			name:     "Success",
+			password: "secret",
+			setupDB: func(m sqlmock.Sqlmock) {
+				expectUser(m)
+				m.ExpectBegin()
+				m.ExpectExec(regexp.QuoteMeta("UPDATE users SET deleted_at = NOW(), tokens_valid_after = NOW(), version = version + 1 WHERE uuid = $1 AND version = $2 AND deleted_at IS NULL")).
+					WithArgs("user-1", int64(1)).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				expectEvent(m, event.TypeUserDeleted)
+				expectAudit(m, event.TypeUserDeleted)
+				m.ExpectCommit()
+			},
+			revoked: []constant.TokenType{constant.TokenTypeAccess, constant.TokenTypeRefresh},
+		},
+		{
+			name:      "WrongPassword",
+			password:  "nope",
+			setupDB:   expectUser,
+			expectErr: errcode.ErrPasswordMismatch,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			svc, mock, mr, _ := setupUserServiceWithRedis(t)
+			mr.Set("user:me:user-1", "{}")
+			tc.setupDB(mock)
+			var revoked []constant.TokenType
+			svc.UseBlacklist(NewBlacklistService(testLogger(), NewJwtService(testLogger(), testEnvConfig()), &fakeBLRepo{
+				add: func(_ string, tokenType constant.TokenType, _ time.Duration) error {
+					revoked = append(revoked, tokenType)
+					return nil
+				},
+			}))
+
+			err := svc.DeleteMe(context.Background(), "user-1", tc.password, "access-token", "refresh-token")
+			if tc.expectErr != nil {
+				require.ErrorIs(t, err, tc.expectErr)
+				require.True(t, mr.Exists("user:me:user-1"))
+			} else {
+				require.NoError(t, err)
+				require.False(t, mr.Exists("user:me:user-1"))
+			}
+			require.Equal(t, tc.revoked, revoked)
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestUserService_PatchUser(t *testing.T) {
+	countQuery := regexp.QuoteMeta(`SELECT COUNT(*) FROM users WHERE lower(email) = lower($1) AND deleted_at IS NULL`)
+
+	cases := []struct {
+		name      string
+		patch     string
+		setupDB   func(sqlmock.Sqlmock)
+		expectErr error
+		assert    func(t *testing.T, resp *dto.UserResponse)
+	}{
+		{
+			name:  "NameOnly",
+			patch: `{"name":"Alicia"}`,
+			setupDB: func(m sqlmock.Sqlmock) {
+				expectUserPermissions(m, "user-1")
+				m.ExpectBegin()
+				m.ExpectExec(regexp.QuoteMeta(`UPDATE users SET name = $1, version = version + 1, updated_at = NOW() WHERE uuid = $2 AND version = $3`)).
+					WithArgs("Alicia", "user-1", 1).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				expectEvent(m, event.TypeUserUpdated)
+				expectAudit(m, event.TypeUserUpdated)
+				m.ExpectCommit()
+			},
+			assert: func(t *testing.T, resp *dto.UserResponse) {
+				require.Equal(t, "Alicia", resp.Name)
+				require.Equal(t, "alice@example.com", resp.Email)
+			},
+		},
+		{
+			name:  "EmailAndPhone",
+			patch: `{"email":"new@example.com","phone":"+14155550100"}`,
+			setupDB: func(m sqlmock.Sqlmock) {
+				expectUserPermissions(m, "user-1")
+				m.ExpectQuery(countQuery).WithArgs("new@example.com").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+				m.ExpectBegin()
+				m.ExpectExec(regexp.QuoteMeta(`UPDATE users SET email = $1, phone = $2, version = version + 1, updated_at = NOW() WHERE uuid = $3 AND version = $4`)).
+					WithArgs("new@example.com", "+14155550100", "user-1", 1).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				expectEvent(m, event.TypeUserUpdated)
+				expectAudit(m, event.TypeUserUpdated)
+				m.ExpectCommit()
+			},
+			assert: func(t *testing.T, resp *dto.UserResponse) {
+				require.Equal(t, "new@example.com", resp.Email)
+				require.Equal(t, "+14155550100", resp.Phone)
+			},
+		},
+		{
+			name:  "NullClearsPhone",
+			patch: `{"phone":null}`,
+			setupDB: func(m sqlmock.Sqlmock) {
+				expectUserPermissions(m, "user-1")
+				m.ExpectBegin()
+				m.ExpectExec(regexp.QuoteMeta(`UPDATE users SET phone = $1, version = version + 1, updated_at = NOW() WHERE uuid = $2 AND version = $3`)).
+					WithArgs(nil, "user-1", 1).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				expectEvent(m, event.TypeUserUpdated)
+				expectAudit(m, event.TypeUserUpdated)
+				m.ExpectCommit()
+			},
+			assert: func(t *testing.T, resp *dto.UserResponse) {
+				require.Empty(t, resp.Phone)
+			},
+		},
+		{
+			name:  "UnchangedIsNoop",
+			patch: `{"name":"Alice"}`,
+			setupDB: func(m sqlmock.Sqlmock) {
+				expectUserPermissions(m, "user-1")
+			},
+			assert: func(t *testing.T, resp *dto.UserResponse) {
+				require.Equal(t, "Alice", resp.Name)
+			},
+		},
+		{
+			name:  "EmailTaken",
+			patch: `{"email":"taken@example.com"}`,
+			setupDB: func(m sqlmock.Sqlmock) {
+				expectUserPermissions(m, "user-1")
+				m.ExpectQuery(countQuery).WithArgs("taken@example.com").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+			},
+			expectErr: errcode.ErrUserAlreadyExists,
+		},
+		{
+			name:  "NotFound",
+			patch: `{"name":"Alicia"}`,
+			setupDB: func(m sqlmock.Sqlmock) {
+				m.ExpectQuery(regexp.QuoteMeta(`FROM users WHERE uuid = $1`)).WithArgs("user-1").WillReturnError(errors.New("no rows"))
+			},
+			expectErr: errcode.ErrUserNotFound,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			svc, mock, _, _ := setupUserServiceWithRedis(t)
+			tc.setupDB(mock)
+
+			req := new(dto.PatchUserRequest)
+			require.NoError(t, json.Unmarshal([]byte(tc.patch), req))
+
+			resp, err := svc.PatchUser(context.Background(), "user-1", 0, req)
+			if tc.expectErr != nil {
+				require.ErrorIs(t, err, tc.expectErr)
+			} else {
+				require.NoError(t, err)
+				tc.assert(t, resp)
+			}
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestUserService_Versioning(t *testing.T) {
+	updateQuery := regexp.QuoteMeta(`UPDATE users SET name = $1, email = $2, version = version + 1, updated_at = NOW() WHERE uuid = $3 AND version = $4`)
+	request := &dto.UpdateUserRequest{Name: "Alicia", Email: "alice@example.com"}
+
+	cases := []struct {
+		name      string
+		version   int64
+		action    func(svc *UserService, version int64) (*dto.UserResponse, error)
+		setupDB   func(sqlmock.Sqlmock)
+		expectErr error
+		expectVer int64
+	}{
+		{
+			name:    "UpdateMatchingVersion",
+			version: 1,
+			action: func(svc *UserService, version int64) (*dto.UserResponse, error) {
+				return svc.UpdateUser(context.Background(), "user-1", version, request)
+			},
+			setupDB: func(m sqlmock.Sqlmock) {
+				expectUserPermissions(m, "user-1")
+				m.ExpectBegin()
+				m.ExpectExec(updateQuery).WithArgs("Alicia", "alice@example.com", "user-1", 1).WillReturnResult(sqlmock.NewResult(0, 1))
+				expectEvent(m, event.TypeUserUpdated)
+				expectAudit(m, event.TypeUserUpdated)
+				m.ExpectCommit()
+			},
+			expectVer: 2,
+		},
+		{
+			name:    "UpdateOutdatedVersion",
+			version: 3,
+			action: func(svc *UserService, version int64) (*dto.UserResponse, error) {
+				return svc.UpdateUser(context.Background(), "user-1", version, request)
+			},
+			setupDB:   func(m sqlmock.Sqlmock) { expectUserPermissions(m, "user-1") },
+			expectErr: errcode.ErrPreconditionFailed,
+		},
+		{
+			name:    "UpdateLostRace",
+			version: 1,
+			action: func(svc *UserService, version int64) (*dto.UserResponse, error) {
+				return svc.UpdateUser(context.Background(), "user-1", version, request)
+			},
+			setupDB: func(m sqlmock.Sqlmock) {
+				expectUserPermissions(m, "user-1")
+				m.ExpectBegin()
+				m.ExpectExec(updateQuery).WithArgs("Alicia", "alice@example.com", "user-1", 1).WillReturnResult(sqlmock.NewResult(0, 0))
+				m.ExpectRollback()
+			},
+			expectErr: errcode.ErrPreconditionFailed,
+		},
+		{
+			name:    "PatchOutdatedVersion",
+			version: 2,
+			action: func(svc *UserService, version int64) (*dto.UserResponse, error) {
+				req := new(dto.PatchUserRequest)
+				require.NoError(t, json.Unmarshal([]byte(`{"phone":null}`), req))
+				return svc.PatchUser(context.Background(), "user-1", version, req)
+			},
+			setupDB:   func(m sqlmock.Sqlmock) { expectUserPermissions(m, "user-1") },
+			expectErr: errcode.ErrPreconditionFailed,
+		},
+		{
+			name:    "DeleteOutdatedVersion",
+			version: 2,
+			action: func(svc *UserService, version int64) (*dto.UserResponse, error) {
+				return nil, svc.DeleteUser(context.Background(), "user-1", version)
+			},
+			setupDB:   func(m sqlmock.Sqlmock) { expectUserPermissions(m, "user-1") },
+			expectErr: errcode.ErrPreconditionFailed,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			svc, mock, _, _ := setupUserServiceWithRedis(t)
+			tc.setupDB(mock)
+
+			resp, err := tc.action(svc, tc.version)
+			if tc.expectErr != nil {
+				require.ErrorIs(t, err, tc.expectErr)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, tc.expectVer, resp.Version)
+			}
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestUserService_FindUser(t *testing.T) {
+	svc, mock, mr, _ := setupUserServiceWithRedis(t)
+	mr.Set("user:me:user-1", `{"data":{"version":0}}`)
+	expectUserPermissions(mock, "user-1")
+
+	resp, err := svc.FindUser(context.Background(), "user-1")
+	require.NoError(t, err)
+	require.Equal(t, int64(1), resp.Version)
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM users WHERE uuid = $1`)).WithArgs("missing").WillReturnError(errors.New("no rows"))
+	_, err = svc.FindUser(context.Background(), "missing")
+	require.ErrorIs(t, err, errcode.ErrUserNotFound)
+}
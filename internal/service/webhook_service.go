@@ -0,0 +1,528 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"go-starter-template/internal/config/env"
+	"go-starter-template/internal/constant"
+	"go-starter-template/internal/dto"
+	"go-starter-template/internal/dto/converter"
+	"go-starter-template/internal/event"
+	"go-starter-template/internal/model"
+	"go-starter-template/internal/repository"
+	"go-starter-template/internal/utils/errcode"
+	"go-starter-template/internal/utils/netguard"
+	"go-starter-template/internal/utils/signature"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// WebhookService manages webhook subscriptions and delivers events to them.
+// As an event.Sink it records a delivery per matching subscription, which
+// the delivery worker then sends with retries.
+//
+// Unless webhooks.allow_private_networks is set, endpoints must be public:
+// their host is checked when a subscription is saved, and the delivery client
+// refuses to connect to other addresses whatever the host resolves to then.
+type WebhookService struct {
+	webhookRepository *repository.WebhookRepository
+	queue             *repository.WebhookQueue
+	log               *logrus.Logger
+	tracer            trace.Tracer
+	uow               *repository.UnitOfWork
+	client            *http.Client
+	// checkHost rejects hosts that do not resolve to public addresses; nil
+	// when private networks are allowed
+	checkHost    func(ctx context.Context, host string) error
+	timeout      time.Duration
+	maxAttempts  int
+	backoff      time.Duration
+	maxBackoff   time.Duration
+	disableAfter int
+}
+
+func NewWebhookService(webhookRepository *repository.WebhookRepository, queue *repository.WebhookQueue, log *logrus.Logger, config *env.Config, uow *repository.UnitOfWork) *WebhookService {
+	s := &WebhookService{
+		webhookRepository: webhookRepository,
+		queue:             queue,
+		log:               log,
+		tracer:            otel.Tracer("WebhookService"),
+		uow:               uow,
+		client:            &http.Client{Timeout: config.GetWebhookTimeout()},
+		timeout:           config.GetWebhookTimeout(),
+		maxAttempts:       config.GetWebhookMaxAttempts(),
+		backoff:           config.GetWebhookBackoff(),
+		maxBackoff:        config.GetWebhookMaxBackoff(),
+		disableAfter:      config.GetWebhookDisableAfter(),
+	}
+	if !config.Webhooks.AllowPrivateNetworks {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		// Connect to endpoints directly, since through a proxy the dialed
+		// address would be the proxy's
+		transport.Proxy = nil
+		transport.DialContext = (&net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second, Control: netguard.Control}).DialContext
+		s.client.Transport = transport
+		s.checkHost = func(ctx context.Context, host string) error {
+			return netguard.CheckHost(ctx, net.DefaultResolver, host)
+		}
+	}
+	return s
+}
+
+// CreateSubscription registers a webhook owned by ownerUUID and returns it
+// with its signing secret, which is not shown again.
+func (s *WebhookService) CreateSubscription(ctx context.Context, ownerUUID string, request *dto.CreateWebhookRequest) (*dto.WebhookResponse, error) {
+	spanCtx, span := s.tracer.Start(ctx, "WebhookService.CreateSubscription")
+	defer span.End()
+
+	if err := s.validateWebhook(spanCtx, request.URL, request.Events); err != nil {
+		return nil, err
+	}
+	secret, err := newWebhookSecret()
+	if err != nil {
+		s.log.WithContext(spanCtx).WithError(err).Error("Failed to generate webhook secret")
+		return nil, errcode.ErrInternalServerError
+	}
+
+	now := time.Now()
+	subscription := &model.WebhookSubscription{
+		UUID:        uuid.NewString(),
+		OwnerUUID:   ownerUUID,
+		URL:         request.URL,
+		Description: request.Description,
+		Events:      request.Events,
+		Secret:      secret,
+		Enabled:     true,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if err := s.webhookRepository.CreateSubscription(spanCtx, subscription); err != nil {
+		s.log.WithContext(spanCtx).WithError(err).Error("Failed to create webhook subscription")
+		return nil, errcode.ErrDatabaseError
+	}
+
+	response := converter.WebhookToResponse(subscription)
+	response.Secret = secret
+	return response, nil
+}
+
+// ListSubscriptions returns the subscriptions owned by ownerUUID.
+func (s *WebhookService) ListSubscriptions(ctx context.Context, ownerUUID string) ([]*dto.WebhookResponse, error) {
+	spanCtx, span := s.tracer.Start(ctx, "WebhookService.ListSubscriptions")
+	defer span.End()
+
+	subscriptions, err := s.webhookRepository.ListSubscriptionsByOwner(spanCtx, ownerUUID)
+	if err != nil {
+		s.log.WithContext(spanCtx).WithError(err).Error("Failed to list webhook subscriptions")
+		return nil, errcode.ErrDatabaseError
+	}
+
+	responses := make([]*dto.WebhookResponse, len(subscriptions))
+	for i, subscription := range subscriptions {
+		responses[i] = converter.WebhookToResponse(subscription)
+	}
+	return responses, nil
+}
+
+func (s *WebhookService) GetSubscription(ctx context.Context, ownerUUID, uuid string) (*dto.WebhookResponse, error) {
+	spanCtx, span := s.tracer.Start(ctx, "WebhookService.GetSubscription")
+	defer span.End()
+
+	subscription, err := s.findSubscription(spanCtx, ownerUUID, uuid)
+	if err != nil {
+		return nil, err
+	}
+	return converter.WebhookToResponse(subscription), nil
+}
+
+// UpdateSubscription replaces a subscription's settings. Re-enabling it
+// clears its failure count; deliveries that failed meanwhile can be replayed.
+func (s *WebhookService) UpdateSubscription(ctx context.Context, ownerUUID, uuid string, request *dto.UpdateWebhookRequest) (*dto.WebhookResponse, error) {
+	spanCtx, span := s.tracer.Start(ctx, "WebhookService.UpdateSubscription")
+	defer span.End()
+
+	if err := s.validateWebhook(spanCtx, request.URL, request.Events); err != nil {
+		return nil, err
+	}
+	subscription, err := s.findSubscription(spanCtx, ownerUUID, uuid)
+	if err != nil {
+		return nil, err
+	}
+
+	subscription.URL = request.URL
+	subscription.Description = request.Description
+	subscription.Events = request.Events
+	if request.Enabled != nil && *request.Enabled != subscription.Enabled {
+		subscription.Enabled = *request.Enabled
+		subscription.ConsecutiveFailures = 0
+		subscription.DisabledReason = ""
+		if !subscription.Enabled {
+			subscription.DisabledReason = "disabled manually"
+		}
+	}
+	if err := s.webhookRepository.UpdateSubscription(spanCtx, subscription); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errcode.ErrWebhookNotFound
+		}
+		s.log.WithContext(spanCtx).WithError(err).Error("Failed to update webhook subscription")
+		return nil, errcode.ErrDatabaseError
+	}
+	subscription.UpdatedAt = time.Now()
+
+	return converter.WebhookToResponse(subscription), nil
+}
+
+// DeleteSubscription removes a subscription together with its delivery log.
+func (s *WebhookService) DeleteSubscription(ctx context.Context, ownerUUID, uuid string) error {
+	spanCtx, span := s.tracer.Start(ctx, "WebhookService.DeleteSubscription")
+	defer span.End()
+
+	if err := s.webhookRepository.DeleteSubscription(spanCtx, uuid, ownerUUID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return errcode.ErrWebhookNotFound
+		}
+		s.log.WithContext(spanCtx).WithError(err).Error("Failed to delete webhook subscription")
+		return errcode.ErrDatabaseError
+	}
+	return nil
+}
+
+// ListDeliveries returns the delivery log of a subscription, newest first.
+func (s *WebhookService) ListDeliveries(ctx context.Context, ownerUUID, uuid string, request *dto.ListWebhookDeliveriesRequest) ([]*dto.WebhookDeliveryResponse, error) {
+	spanCtx, span := s.tracer.Start(ctx, "WebhookService.ListDeliveries")
+	defer span.End()
+
+	if _, err := s.findSubscription(spanCtx, ownerUUID, uuid); err != nil {
+		return nil, err
+	}
+	deliveries, err := s.webhookRepository.ListDeliveries(spanCtx, uuid, request.Status, request.Limit)
+	if err != nil {
+		s.log.WithContext(spanCtx).WithError(err).Error("Failed to list webhook deliveries")
+		return nil, errcode.ErrDatabaseError
+	}
+
+	responses := make([]*dto.WebhookDeliveryResponse, len(deliveries))
+	for i, delivery := range deliveries {
+		responses[i] = converter.WebhookDeliveryToResponse(delivery)
+	}
+	return responses, nil
+}
+
+// ReplayDelivery sends a delivery again right away with a fresh set of
+// attempts, whatever its outcome so far.
+func (s *WebhookService) ReplayDelivery(ctx context.Context, ownerUUID, subscriptionUUID, deliveryUUID string) (*dto.WebhookDeliveryResponse, error) {
+	spanCtx, span := s.tracer.Start(ctx, "WebhookService.ReplayDelivery")
+	defer span.End()
+
+	logger := s.log.WithContext(spanCtx)
+	subscription, err := s.findSubscription(spanCtx, ownerUUID, subscriptionUUID)
+	if err != nil {
+		return nil, err
+	}
+	if !subscription.Enabled {
+		return nil, errcode.ErrWebhookDisabled
+	}
+
+	delivery := new(model.WebhookDelivery)
+	if err := s.webhookRepository.FindDelivery(spanCtx, delivery, deliveryUUID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errcode.ErrWebhookDeliveryNotFound
+		}
+		logger.WithError(err).Error("Failed to find webhook delivery")
+		return nil, errcode.ErrDatabaseError
+	}
+	if delivery.SubscriptionUUID != subscriptionUUID {
+		return nil, errcode.ErrWebhookDeliveryNotFound
+	}
+
+	now := time.Now()
+	delivery.Status = string(constant.WebhookDeliveryPending)
+	delivery.Attempts = 0
+	delivery.ResponseStatus = 0
+	delivery.LastError = ""
+	delivery.NextAttemptAt = &now
+	delivery.DeliveredAt = nil
+	if err := s.webhookRepository.UpdateDelivery(spanCtx, delivery); err != nil {
+		logger.WithError(err).Error("Failed to reset webhook delivery")
+		return nil, errcode.ErrDatabaseError
+	}
+	if err := s.queue.Schedule(spanCtx, delivery.UUID, now); err != nil {
+		logger.WithError(err).Error("Failed to queue webhook delivery")
+		return nil, errcode.ErrRedisSet
+	}
+
+	return converter.WebhookDeliveryToResponse(delivery), nil
+}
+
+// Publish records a delivery of envelope for every enabled subscription to
+// its type and queues them once the transaction in ctx commits. It is called
+// by the outbox relay, which may publish an event twice; the second time
+// records nothing.
+func (s *WebhookService) Publish(ctx context.Context, envelope event.Envelope) error {
+	spanCtx, span := s.tracer.Start(ctx, "WebhookService.Publish")
+	defer span.End()
+
+	subscriptions, err := s.webhookRepository.ListSubscriptions(spanCtx, true)
+	if err != nil {
+		return err
+	}
+	var payload []byte
+	for _, subscription := range subscriptions {
+		if !subscribes(subscription, envelope.Type) {
+			continue
+		}
+		if payload == nil {
+			if payload, err = json.Marshal(envelope); err != nil {
+				return err
+			}
+		}
+
+		now := time.Now()
+		delivery := &model.WebhookDelivery{
+			UUID:             uuid.NewString(),
+			SubscriptionUUID: subscription.UUID,
+			EventID:          envelope.ID,
+			EventType:        envelope.Type,
+			Payload:          payload,
+			Status:           string(constant.WebhookDeliveryPending),
+			NextAttemptAt:    &now,
+		}
+		created, err := s.webhookRepository.CreateDelivery(spanCtx, delivery)
+		if err != nil {
+			return err
+		}
+		if created {
+			s.scheduleAfterCommit(spanCtx, delivery.UUID, now)
+		}
+	}
+	return nil
+}
+
+// DeliverDue attempts up to limit deliveries whose time has come and returns
+// how many it attempted. The claimed deliveries are leased for long enough to
+// attempt them one after the other; one whose outcome could not be recorded
+// keeps its lease and is attempted again once that expires.
+func (s *WebhookService) DeliverDue(ctx context.Context, limit int) (int, error) {
+	spanCtx, span := s.tracer.Start(ctx, "WebhookService.DeliverDue")
+	defer span.End()
+
+	logger := s.log.WithContext(spanCtx)
+	lease := time.Duration(limit)*s.timeout + time.Minute
+	uuids, err := s.queue.ClaimDue(spanCtx, time.Now(), lease, limit)
+	if err != nil {
+		logger.WithError(err).Error("Failed to claim due webhook deliveries")
+		return 0, errcode.ErrRedisGet
+	}
+	for _, uuid := range uuids {
+		if err := s.deliver(spanCtx, uuid); err != nil {
+			logger.WithError(err).WithField("delivery", uuid).Error("Failed to record webhook delivery attempt")
+			continue
+		}
+		// Does nothing if a retry was queued, which already ended the lease
+		if err := s.queue.Release(spanCtx, uuid); err != nil {
+			logger.WithError(err).WithField("delivery", uuid).Warn("Failed to release webhook delivery")
+		}
+	}
+	return len(uuids), nil
+}
+
+// deliver makes one attempt of a pending delivery and records its outcome:
+// success, a retry with exponential backoff, or failure once the attempts
+// are used up. Every failed attempt counts against the subscription, which
+// is disabled after disableAfter of them in a row.
+func (s *WebhookService) deliver(ctx context.Context, uuid string) error {
+	logger := s.log.WithContext(ctx).WithField("delivery", uuid)
+
+	delivery := new(model.WebhookDelivery)
+	if err := s.webhookRepository.FindDelivery(ctx, delivery, uuid); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			// Deleted along with its subscription
+			return nil
+		}
+		return err
+	}
+	if delivery.Status != string(constant.WebhookDeliveryPending) {
+		return nil
+	}
+	subscription := new(model.WebhookSubscription)
+	if err := s.webhookRepository.FindSubscription(ctx, subscription, delivery.SubscriptionUUID); err != nil {
+		return err
+	}
+	if !subscription.Enabled {
+		delivery.Status = string(constant.WebhookDeliveryFailed)
+		delivery.LastError = "webhook is disabled"
+		delivery.NextAttemptAt = nil
+		return s.webhookRepository.UpdateDelivery(ctx, delivery)
+	}
+
+	status, sendErr := s.send(ctx, subscription, delivery)
+	now := time.Now()
+	delivery.Attempts++
+	delivery.ResponseStatus = status
+
+	return s.uow.Do(ctx, func(txCtx context.Context) error {
+		if sendErr == nil {
+			delivery.Status = string(constant.WebhookDeliverySucceeded)
+			delivery.LastError = ""
+			delivery.NextAttemptAt = nil
+			delivery.DeliveredAt = &now
+			if err := s.webhookRepository.RecordSuccess(txCtx, subscription.UUID); err != nil {
+				return err
+			}
+			return s.webhookRepository.UpdateDelivery(txCtx, delivery)
+		}
+
+		delivery.LastError = deliveryErrorClass(status, sendErr)
+		reason := fmt.Sprintf("disabled after %d consecutive failed attempts", s.disableAfter)
+		enabled, err := s.webhookRepository.RecordFailure(txCtx, subscription.UUID, s.disableAfter, reason)
+		if err != nil {
+			return err
+		}
+		if !enabled {
+			logger.WithField("webhook", subscription.UUID).Warn("Webhook disabled after repeated delivery failures")
+		}
+
+		if enabled && delivery.Attempts < s.maxAttempts {
+			next := now.Add(s.retryDelay(delivery.Attempts))
+			delivery.NextAttemptAt = &next
+			s.scheduleAfterCommit(txCtx, delivery.UUID, next)
+		} else {
+			delivery.Status = string(constant.WebhookDeliveryFailed)
+			delivery.NextAttemptAt = nil
+		}
+		logger.WithError(sendErr).WithField("attempts", delivery.Attempts).Warn("Webhook delivery attempt failed")
+		return s.webhookRepository.UpdateDelivery(txCtx, delivery)
+	})
+}
+
+// send POSTs the delivery's payload signed with the subscription's secret
+// and returns the response status, if any.
+func (s *WebhookService) send(ctx context.Context, subscription *model.WebhookSubscription, delivery *model.WebhookDelivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Webhook-ID", delivery.UUID)
+	req.Header.Set("X-Webhook-Event", delivery.EventType)
+	req.Header.Set(signature.TimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(signature.SignatureHeader, signature.Sign(subscription.Secret, timestamp, delivery.Payload))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("endpoint responded %d %s", resp.StatusCode, http.StatusText(resp.StatusCode))
+	}
+	return resp.StatusCode, nil
+}
+
+// retryDelay doubles the backoff after every failed attempt, up to maxBackoff.
+func (s *WebhookService) retryDelay(attempts int) time.Duration {
+	delay := s.backoff
+	for i := 1; i < attempts && delay < s.maxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, s.maxBackoff)
+}
+
+// scheduleAfterCommit queues a delivery attempt once the transaction in ctx
+// commits. A delivery whose scheduling fails stays pending and can be
+// replayed.
+func (s *WebhookService) scheduleAfterCommit(ctx context.Context, uuid string, at time.Time) {
+	repository.AfterCommit(ctx, func(ctx context.Context) {
+		if err := s.queue.Schedule(ctx, uuid, at); err != nil {
+			s.log.WithContext(ctx).WithError(err).WithField("delivery", uuid).Error("Failed to queue webhook delivery")
+		}
+	})
+}
+
+// findSubscription loads a subscription of ownerUUID. Those of other users
+// are not found, so callers cannot tell them from missing ones.
+func (s *WebhookService) findSubscription(ctx context.Context, ownerUUID, uuid string) (*model.WebhookSubscription, error) {
+	subscription := new(model.WebhookSubscription)
+	if err := s.webhookRepository.FindSubscription(ctx, subscription, uuid); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errcode.ErrWebhookNotFound
+		}
+		s.log.WithContext(ctx).WithError(err).Error("Failed to find webhook subscription")
+		return nil, errcode.ErrDatabaseError
+	}
+	if subscription.OwnerUUID == "" || subscription.OwnerUUID != ownerUUID {
+		return nil, errcode.ErrWebhookNotFound
+	}
+	return subscription, nil
+}
+
+// subscribes reports whether subscription wants events of eventType.
+func subscribes(subscription *model.WebhookSubscription, eventType string) bool {
+	return slices.Contains(subscription.Events, eventType) || slices.Contains(subscription.Events, constant.WebhookEventWildcard)
+}
+
+func (s *WebhookService) validateWebhook(ctx context.Context, rawURL string, events []string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errcode.ErrInvalidWebhookURL
+	}
+	for _, eventType := range events {
+		if eventType != constant.WebhookEventWildcard && !slices.Contains(event.Types, eventType) {
+			return errcode.ErrInvalidWebhookEvent
+		}
+	}
+	if s.checkHost != nil {
+		if err := s.checkHost(ctx, u.Hostname()); err != nil {
+			s.log.WithContext(ctx).WithError(err).WithField("host", u.Hostname()).Warn("Rejected webhook endpoint")
+			return errcode.ErrWebhookURLNotAllowed
+		}
+	}
+	return nil
+}
+
+// deliveryErrorClass describes a failed attempt for the delivery log, which
+// the subscriber can read. It names the kind of failure only: the error text
+// of a refused or failed connection would tell them about the network the
+// request was sent from.
+func deliveryErrorClass(status int, err error) string {
+	var dnsErr *net.DNSError
+	var netErr net.Error
+	switch {
+	case status != 0:
+		return fmt.Sprintf("endpoint responded %d %s", status, http.StatusText(status))
+	case errors.Is(err, netguard.ErrForbiddenAddress):
+		return "endpoint address is not allowed"
+	case errors.As(err, &dnsErr):
+		return "endpoint host could not be resolved"
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return "endpoint timed out"
+	default:
+		return "endpoint could not be reached"
+	}
+}
+
+func newWebhookSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(secret), nil
+}
@@ -0,0 +1,417 @@
+package service
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	miniredis "github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+
+	"go-starter-template/internal/config/env"
+	"go-starter-template/internal/dto"
+	"go-starter-template/internal/event"
+	"go-starter-template/internal/repository"
+	"go-starter-template/internal/utils/errcode"
+	"go-starter-template/internal/utils/netguard"
+	"go-starter-template/internal/utils/signature"
+)
+
+var (
+	webhookSubscriptionRows = []string{"uuid", "url", "description", "events", "secret", "enabled", "consecutive_failures", "disabled_reason", "created_at", "updated_at", "owner_uuid"}
+	webhookDeliveryRows     = []string{"uuid", "subscription_uuid", "event_id", "event_type", "payload", "status", "attempts", "response_status", "last_error", "next_attempt_at", "created_at", "delivered_at"}
+)
+
+const (
+	webhookSecret         = "whsec_test"
+	webhookOwner          = "owner-1"
+	findWebhookQuery      = `FROM webhook_subscriptions WHERE uuid = $1`
+	findDeliveryQuery     = `FROM webhook_deliveries WHERE uuid = $1`
+	updateDeliveryQuery   = `UPDATE webhook_deliveries`
+	recordFailureQuery    = `SET consecutive_failures = consecutive_failures + 1`
+	recordSuccessQuery    = `UPDATE webhook_subscriptions SET consecutive_failures = 0`
+	webhookQueueKeyInTest = "webhooks:queue"
+)
+
+func setupWebhookService(t *testing.T) (*WebhookService, sqlmock.Sqlmock, *miniredis.Miniredis) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherRegexp))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+
+	cfg := &env.Config{}
+	cfg.Webhooks.MaxAttempts = 3
+	cfg.Webhooks.Backoff = 30
+	cfg.Webhooks.MaxBackoff = 90
+	cfg.Webhooks.DisableAfter = 5
+	svc := NewWebhookService(repository.NewWebhookRepository(db), repository.NewWebhookQueue(rdb), silentLogger(), cfg, repository.NewUnitOfWork(db))
+	// Host names resolve to a public address without DNS; IP literals are checked as they are
+	svc.checkHost = func(ctx context.Context, host string) error {
+		if net.ParseIP(host) == nil {
+			host = "93.184.216.34"
+		}
+		return netguard.CheckHost(ctx, net.DefaultResolver, host)
+	}
+	return svc, mock, mr
+}
+
+func expectWebhook(m sqlmock.Sqlmock, uuid, url string, enabled bool) {
+	m.ExpectQuery(regexp.QuoteMeta(findWebhookQuery)).
+		WithArgs(uuid).
+		WillReturnRows(sqlmock.NewRows(webhookSubscriptionRows).
+			AddRow(uuid, url, "", []byte(`["user.created"]`), webhookSecret, enabled, 0, "", time.Now(), time.Now(), webhookOwner))
+}
+
+func expectDelivery(m sqlmock.Sqlmock, uuid, subscriptionUUID, status string, attempts int) {
+	m.ExpectQuery(regexp.QuoteMeta(findDeliveryQuery)).
+		WithArgs(uuid).
+		WillReturnRows(sqlmock.NewRows(webhookDeliveryRows).
+			AddRow(uuid, subscriptionUUID, "evt-1", event.TypeUserCreated, []byte(`{"id":"evt-1"}`), status, attempts, 0, "", time.Now(), time.Now(), nil))
+}
+
+func TestWebhookService_CreateSubscription(t *testing.T) {
+	cases := []struct {
+		name      string
+		req       *dto.CreateWebhookRequest
+		setupDB   func(sqlmock.Sqlmock)
+		expectErr error
+	}{
+		{
+			name: "Success",
+			req:  &dto.CreateWebhookRequest{URL: "https://partner.example.com/hooks", Events: []string{event.TypeUserCreated, event.TypeUserDeleted}},
+			setupDB: func(m sqlmock.Sqlmock) {
+				m.ExpectExec(regexp.QuoteMeta(`INSERT INTO webhook_subscriptions`)).
+					WithArgs(sqlmock.AnyArg(), "https://partner.example.com/hooks", "", []byte(`["user.created","user.deleted"]`), sqlmock.AnyArg(), webhookOwner).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+			},
+		},
+		{
+			name:      "UnsupportedScheme",
+			req:       &dto.CreateWebhookRequest{URL: "ftp://partner.example.com", Events: []string{"*"}},
+			expectErr: errcode.ErrInvalidWebhookURL,
+		},
+		{
+			name:      "UnknownEvent",
+			req:       &dto.CreateWebhookRequest{URL: "https://partner.example.com", Events: []string{"user.exploded"}},
+			expectErr: errcode.ErrInvalidWebhookEvent,
+		},
+		{
+			name:      "MetadataAddress",
+			req:       &dto.CreateWebhookRequest{URL: "http://169.254.169.254/latest/meta-data", Events: []string{"*"}},
+			expectErr: errcode.ErrWebhookURLNotAllowed,
+		},
+		{
+			name:      "Loopback",
+			req:       &dto.CreateWebhookRequest{URL: "http://[::1]:8080/hooks", Events: []string{"*"}},
+			expectErr: errcode.ErrWebhookURLNotAllowed,
+		},
+		{
+			name:      "PrivateNetwork",
+			req:       &dto.CreateWebhookRequest{URL: "https://10.0.0.5/hooks", Events: []string{"*"}},
+			expectErr: errcode.ErrWebhookURLNotAllowed,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			svc, mock, _ := setupWebhookService(t)
+			if tc.setupDB != nil {
+				tc.setupDB(mock)
+			}
+
+			webhook, err := svc.CreateSubscription(context.Background(), webhookOwner, tc.req)
+			if tc.expectErr != nil {
+				require.ErrorIs(t, err, tc.expectErr)
+			} else {
+				require.NoError(t, err)
+				require.True(t, strings.HasPrefix(webhook.Secret, "whsec_"))
+				require.True(t, webhook.Enabled)
+			}
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestWebhookService_Publish(t *testing.T) {
+	svc, mock, mr := setupWebhookService(t)
+	now := time.Now()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM webhook_subscriptions`)).
+		WithArgs(true).
+		WillReturnRows(sqlmock.NewRows(webhookSubscriptionRows).
+			AddRow("w1", "https://a.example.com", "", []byte(`["user.created"]`), webhookSecret, true, 0, "", now, now, webhookOwner).
+			AddRow("w2", "https://b.example.com", "", []byte(`["*"]`), webhookSecret, true, 0, "", now, now, webhookOwner).
+			AddRow("w3", "https://c.example.com", "", []byte(`["user.deleted"]`), webhookSecret, true, 0, "", now, now, ""))
+	insert := regexp.QuoteMeta(`INSERT INTO webhook_deliveries`)
+	mock.ExpectExec(insert).
+		WithArgs(sqlmock.AnyArg(), "w1", "evt-1", event.TypeUserCreated, sqlmock.AnyArg(), "pending", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	// w2 already has this event from an earlier relay run
+	mock.ExpectExec(insert).
+		WithArgs(sqlmock.AnyArg(), "w2", "evt-1", event.TypeUserCreated, sqlmock.AnyArg(), "pending", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err := svc.Publish(context.Background(), event.Envelope{ID: "evt-1", Type: event.TypeUserCreated, AggregateType: event.AggregateUser, AggregateID: "u1"})
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	queued, err := mr.ZMembers(webhookQueueKeyInTest)
+	require.NoError(t, err)
+	require.Len(t, queued, 1)
+}
+
+func TestWebhookService_DeliverDue(t *testing.T) {
+	type testcase struct {
+		name          string
+		status        int
+		enabled       bool
+		attempts      int
+		setupDB       func(m sqlmock.Sqlmock, url string)
+		expectCalls   int
+		expectQueued  bool
+		expectBackoff time.Duration
+	}
+
+	cases := []testcase{
+		{
+			name:    "Success",
+			status:  http.StatusNoContent,
+			enabled: true,
+			setupDB: func(m sqlmock.Sqlmock, url string) {
+				m.ExpectBegin()
+				m.ExpectExec(regexp.QuoteMeta(recordSuccessQuery)).WithArgs("w1").WillReturnResult(sqlmock.NewResult(0, 1))
+				m.ExpectExec(regexp.QuoteMeta(updateDeliveryQuery)).
+					WithArgs("succeeded", 1, http.StatusNoContent, "", nil, sqlmock.AnyArg(), "d1").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				m.ExpectCommit()
+			},
+			expectCalls: 1,
+		},
+		{
+			name:     "FailureIsRetriedWithBackoff",
+			status:   http.StatusInternalServerError,
+			enabled:  true,
+			attempts: 1,
+			setupDB: func(m sqlmock.Sqlmock, url string) {
+				m.ExpectBegin()
+				m.ExpectQuery(regexp.QuoteMeta(recordFailureQuery)).
+					WithArgs("w1", 5, "disabled after 5 consecutive failed attempts").
+					WillReturnRows(sqlmock.NewRows([]string{"enabled"}).AddRow(true))
+				m.ExpectExec(regexp.QuoteMeta(updateDeliveryQuery)).
+					WithArgs("pending", 2, http.StatusInternalServerError, "endpoint responded 500 Internal Server Error", sqlmock.AnyArg(), nil, "d1").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				m.ExpectCommit()
+			},
+			expectCalls:   1,
+			expectQueued:  true,
+			expectBackoff: time.Minute,
+		},
+		{
+			name:     "AttemptsExhausted",
+			status:   http.StatusBadGateway,
+			enabled:  true,
+			attempts: 2,
+			setupDB: func(m sqlmock.Sqlmock, url string) {
+				m.ExpectBegin()
+				m.ExpectQuery(regexp.QuoteMeta(recordFailureQuery)).
+					WillReturnRows(sqlmock.NewRows([]string{"enabled"}).AddRow(true))
+				m.ExpectExec(regexp.QuoteMeta(updateDeliveryQuery)).
+					WithArgs("failed", 3, http.StatusBadGateway, "endpoint responded 502 Bad Gateway", nil, nil, "d1").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				m.ExpectCommit()
+			},
+			expectCalls: 1,
+		},
+		{
+			name:    "FailureDisablesWebhook",
+			status:  http.StatusGone,
+			enabled: true,
+			setupDB: func(m sqlmock.Sqlmock, url string) {
+				m.ExpectBegin()
+				m.ExpectQuery(regexp.QuoteMeta(recordFailureQuery)).
+					WillReturnRows(sqlmock.NewRows([]string{"enabled"}).AddRow(false))
+				m.ExpectExec(regexp.QuoteMeta(updateDeliveryQuery)).
+					WithArgs("failed", 1, http.StatusGone, "endpoint responded 410 Gone", nil, nil, "d1").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				m.ExpectCommit()
+			},
+			expectCalls: 1,
+		},
+		{
+			name:    "WebhookDisabled",
+			enabled: false,
+			setupDB: func(m sqlmock.Sqlmock, url string) {
+				m.ExpectExec(regexp.QuoteMeta(updateDeliveryQuery)).
+					WithArgs("failed", 0, 0, "webhook is disabled", nil, nil, "d1").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			calls := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				body, _ := io.ReadAll(r.Body)
+				require.NoError(t, signature.Verify(webhookSecret, r.Header.Get(signature.TimestampHeader), r.Header.Get(signature.SignatureHeader), body, time.Minute, time.Now()))
+				require.Equal(t, "d1", r.Header.Get("X-Webhook-ID"))
+				require.Equal(t, event.TypeUserCreated, r.Header.Get("X-Webhook-Event"))
+				require.JSONEq(t, `{"id":"evt-1"}`, string(body))
+				w.WriteHeader(tc.status)
+			}))
+			defer server.Close()
+
+			svc, mock, mr := setupWebhookService(t)
+			// The test endpoint listens on loopback
+			svc.client = server.Client()
+			_, err := mr.ZAdd(webhookQueueKeyInTest, float64(time.Now().Add(-time.Second).UnixMilli()), "d1")
+			require.NoError(t, err)
+			expectDelivery(mock, "d1", "w1", "pending", tc.attempts)
+			expectWebhook(mock, "w1", server.URL, tc.enabled)
+			tc.setupDB(mock, server.URL)
+
+			attempted, err := svc.DeliverDue(context.Background(), 10)
+			require.NoError(t, err)
+			require.Equal(t, 1, attempted)
+			require.Equal(t, tc.expectCalls, calls)
+			require.NoError(t, mock.ExpectationsWereMet())
+			// every attempt ended its lease, by a retry or by releasing it
+			require.False(t, mr.Exists("webhooks:active"))
+
+			score, err := mr.ZScore(webhookQueueKeyInTest, "d1")
+			if !tc.expectQueued {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.WithinDuration(t, time.Now().Add(tc.expectBackoff), time.UnixMilli(int64(score)), 5*time.Second)
+		})
+	}
+
+	t.Run("NothingDue", func(t *testing.T) {
+		svc, mock, mr := setupWebhookService(t)
+		_, err := mr.ZAdd(webhookQueueKeyInTest, float64(time.Now().Add(time.Hour).UnixMilli()), "d1")
+		require.NoError(t, err)
+
+		attempted, err := svc.DeliverDue(context.Background(), 10)
+		require.NoError(t, err)
+		require.Zero(t, attempted)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("PrivateAddressRefused", func(t *testing.T) {
+		calls := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { calls++ }))
+		defer server.Close()
+
+		svc, mock, mr := setupWebhookService(t)
+		_, err := mr.ZAdd(webhookQueueKeyInTest, 0, "d1")
+		require.NoError(t, err)
+		expectDelivery(mock, "d1", "w1", "pending", 0)
+		expectWebhook(mock, "w1", server.URL, true)
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(recordFailureQuery)).
+			WillReturnRows(sqlmock.NewRows([]string{"enabled"}).AddRow(true))
+		mock.ExpectExec(regexp.QuoteMeta(updateDeliveryQuery)).
+			WithArgs("pending", 1, 0, "endpoint address is not allowed", sqlmock.AnyArg(), nil, "d1").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		attempted, err := svc.DeliverDue(context.Background(), 10)
+		require.NoError(t, err)
+		require.Equal(t, 1, attempted)
+		require.Zero(t, calls)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("AlreadyDelivered", func(t *testing.T) {
+		svc, mock, mr := setupWebhookService(t)
+		_, err := mr.ZAdd(webhookQueueKeyInTest, 0, "d1")
+		require.NoError(t, err)
+		expectDelivery(mock, "d1", "w1", "succeeded", 1)
+
+		attempted, err := svc.DeliverDue(context.Background(), 10)
+		require.NoError(t, err)
+		require.Equal(t, 1, attempted)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestWebhookService_ReplayDelivery(t *testing.T) {
+	cases := []struct {
+		name      string
+		setupDB   func(sqlmock.Sqlmock)
+		expectErr error
+	}{
+		{
+			name: "Success",
+			setupDB: func(m sqlmock.Sqlmock) {
+				expectWebhook(m, "w1", "https://a.example.com", true)
+				expectDelivery(m, "d1", "w1", "failed", 3)
+				m.ExpectExec(regexp.QuoteMeta(updateDeliveryQuery)).
+					WithArgs("pending", 0, 0, "", sqlmock.AnyArg(), nil, "d1").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{
+			name:      "WebhookDisabled",
+			setupDB:   func(m sqlmock.Sqlmock) { expectWebhook(m, "w1", "https://a.example.com", false) },
+			expectErr: errcode.ErrWebhookDisabled,
+		},
+		{
+			name: "WebhookOfAnotherUser",
+			setupDB: func(m sqlmock.Sqlmock) {
+				m.ExpectQuery(regexp.QuoteMeta(findWebhookQuery)).
+					WithArgs("w1").
+					WillReturnRows(sqlmock.NewRows(webhookSubscriptionRows).
+						AddRow("w1", "https://a.example.com", "", []byte(`["*"]`), webhookSecret, true, 0, "", time.Now(), time.Now(), "owner-2"))
+			},
+			expectErr: errcode.ErrWebhookNotFound,
+		},
+		{
+			name: "DeliveryOfAnotherWebhook",
+			setupDB: func(m sqlmock.Sqlmock) {
+				expectWebhook(m, "w1", "https://a.example.com", true)
+				expectDelivery(m, "d1", "w2", "failed", 3)
+			},
+			expectErr: errcode.ErrWebhookDeliveryNotFound,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			svc, mock, mr := setupWebhookService(t)
+			tc.setupDB(mock)
+
+			delivery, err := svc.ReplayDelivery(context.Background(), webhookOwner, "w1", "d1")
+			require.NoError(t, mock.ExpectationsWereMet())
+			if tc.expectErr != nil {
+				require.ErrorIs(t, err, tc.expectErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, "pending", delivery.Status)
+			require.Zero(t, delivery.Attempts)
+			_, err = mr.ZScore(webhookQueueKeyInTest, "d1")
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestWebhookService_RetryDelay(t *testing.T) {
+	svc, _, _ := setupWebhookService(t)
+	require.Equal(t, 30*time.Second, svc.retryDelay(1))
+	require.Equal(t, time.Minute, svc.retryDelay(2))
+	require.Equal(t, 90*time.Second, svc.retryDelay(3))
+	require.Equal(t, 90*time.Second, svc.retryDelay(30))
+}
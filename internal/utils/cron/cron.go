@@ -0,0 +1,196 @@
+// Package cron parses standard five-field cron expressions
+//
+//	minute hour day-of-month month day-of-week
+//
+// with *, lists (1,15), ranges (1-5), steps (*/10, 8-18/2) and month and
+// weekday names (JAN, MON). Sunday is 0 or 7. The descriptors @yearly,
+// @annually, @monthly, @weekly, @daily, @midnight and @hourly are accepted,
+// as is "@every <duration>", which fires at multiples of the duration.
+//
+// As in Vixie cron, when both day fields are restricted a day matches if
+// either does.
+package cron
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrSyntax = errors.New("invalid cron expression")
+
+// Schedule is a parsed cron expression.
+type Schedule struct {
+	minute, hour, dom, month, dow uint64
+	// domAny and dowAny record a day field starting with *, which then does
+	// not restrict the other one
+	domAny, dowAny bool
+	every          time.Duration
+}
+
+var descriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+var monthNames = map[string]int{
+	"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+	"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+}
+
+var dayNames = map[string]int{"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6}
+
+// Parse parses spec into a Schedule.
+func Parse(spec string) (*Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if rest, ok := strings.CutPrefix(spec, "@every "); ok {
+		every, err := time.ParseDuration(strings.TrimSpace(rest))
+		if err != nil || every < time.Second {
+			return nil, fmt.Errorf("%w: %q needs a duration of at least 1s", ErrSyntax, spec)
+		}
+		return &Schedule{every: every}, nil
+	}
+	if expanded, ok := descriptors[strings.ToLower(spec)]; ok {
+		spec = expanded
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("%w: %q has %d fields, want 5", ErrSyntax, spec, len(fields))
+	}
+	s := &Schedule{
+		domAny: strings.HasPrefix(fields[2], "*"),
+		dowAny: strings.HasPrefix(fields[4], "*"),
+	}
+	var err error
+	if s.minute, err = parseField(fields[0], 0, 59, nil); err != nil {
+		return nil, err
+	}
+	if s.hour, err = parseField(fields[1], 0, 23, nil); err != nil {
+		return nil, err
+	}
+	if s.dom, err = parseField(fields[2], 1, 31, nil); err != nil {
+		return nil, err
+	}
+	if s.month, err = parseField(fields[3], 1, 12, monthNames); err != nil {
+		return nil, err
+	}
+	if s.dow, err = parseField(fields[4], 0, 7, dayNames); err != nil {
+		return nil, err
+	}
+	// 7 is another name for Sunday
+	if s.dow&(1<<7) != 0 {
+		s.dow = s.dow&^(1<<7) | 1
+	}
+	return s, nil
+}
+
+// parseField returns the values field selects between lo and hi as a bit set.
+func parseField(field string, lo, hi int, names map[string]int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepPart)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("%w: bad step in %q", ErrSyntax, part)
+			}
+			step = n
+		}
+
+		var from, to int
+		switch {
+		case rangePart == "*":
+			from, to = lo, hi
+		case strings.Contains(rangePart, "-"):
+			first, last, _ := strings.Cut(rangePart, "-")
+			var err error
+			if from, err = parseValue(first, names); err != nil {
+				return 0, err
+			}
+			if to, err = parseValue(last, names); err != nil {
+				return 0, err
+			}
+		default:
+			var err error
+			if from, err = parseValue(rangePart, names); err != nil {
+				return 0, err
+			}
+			to = from
+			// 5/15 means from 5 on, every 15
+			if hasStep {
+				to = hi
+			}
+		}
+		if from < lo || to > hi || from > to {
+			return 0, fmt.Errorf("%w: %q is outside %d-%d", ErrSyntax, part, lo, hi)
+		}
+		for v := from; v <= to; v += step {
+			bits |= 1 << v
+		}
+	}
+	return bits, nil
+}
+
+func parseValue(value string, names map[string]int) (int, error) {
+	if n, ok := names[strings.ToLower(value)]; ok {
+		return n, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("%w: bad value %q", ErrSyntax, value)
+	}
+	return n, nil
+}
+
+// Next returns the first time the schedule fires strictly after after, in
+// after's location, or the zero time if it never fires within five years
+// (e.g. "0 0 30 2 *").
+func (s *Schedule) Next(after time.Time) time.Time {
+	if s.every > 0 {
+		return after.Truncate(s.every).Add(s.every)
+	}
+
+	loc := after.Location()
+	t := after.Truncate(time.Minute).Add(time.Minute)
+	limit := after.Year() + 5
+	for t.Year() <= limit {
+		if s.month&(1<<int(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+			continue
+		}
+		// Hours and minutes advance in absolute time so that repeated or
+		// skipped local hours around DST changes cannot send t backwards
+		if s.hour&(1<<t.Hour()) == 0 {
+			t = t.Add(time.Duration(60-t.Minute()) * time.Minute)
+			continue
+		}
+		if s.minute&(1<<t.Minute()) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+func (s *Schedule) dayMatches(t time.Time) bool {
+	dom := s.dom&(1<<t.Day()) != 0
+	dow := s.dow&(1<<int(t.Weekday())) != 0
+	if s.domAny || s.dowAny {
+		return dom && dow
+	}
+	return dom || dow
+}
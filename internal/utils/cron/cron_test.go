@@ -0,0 +1,89 @@
+package cron
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchedule_Next(t *testing.T) {
+	// Wednesday
+	base := time.Date(2025, time.January, 15, 10, 7, 30, 0, time.UTC)
+
+	cases := []struct {
+		spec   string
+		after  time.Time
+		expect time.Time
+	}{
+		{"* * * * *", base, time.Date(2025, 1, 15, 10, 8, 0, 0, time.UTC)},
+		{"*/15 * * * *", base, time.Date(2025, 1, 15, 10, 15, 0, 0, time.UTC)},
+		{"0 * * * *", base, time.Date(2025, 1, 15, 11, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2025, 1, 15, 11, 0, 0, 0, time.UTC), time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC)},
+		{"30 2 * * *", base, time.Date(2025, 1, 16, 2, 30, 0, 0, time.UTC)},
+		{"0 9-17/4 * * MON-FRI", base, time.Date(2025, 1, 15, 13, 0, 0, 0, time.UTC)},
+		{"0 0 * * sun", base, time.Date(2025, 1, 19, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", base, time.Date(2025, 1, 19, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1 */3 *", base, time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)},
+		{"@yearly", base, time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", base, time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		// Both day fields restricted: the 20th or any Monday
+		{"0 0 20 * 1", base, time.Date(2025, 1, 20, 0, 0, 0, 0, time.UTC)},
+		{"0 0 31 * 1", base, time.Date(2025, 1, 20, 0, 0, 0, 0, time.UTC)},
+		{"0 0 30 2 *", base, time.Time{}},
+		{"@every 10m", base, time.Date(2025, 1, 15, 10, 10, 0, 0, time.UTC)},
+		{"@every 90s", time.Date(2025, 1, 15, 10, 10, 0, 0, time.UTC), time.Date(2025, 1, 15, 10, 10, 30, 0, time.UTC)},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.spec, func(t *testing.T) {
+			schedule, err := Parse(tc.spec)
+			require.NoError(t, err)
+			require.True(t, tc.expect.Equal(schedule.Next(tc.after)), "got %s", schedule.Next(tc.after))
+		})
+	}
+}
+
+func TestSchedule_NextAcrossDST(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("time zone data unavailable")
+	}
+
+	// 02:30 does not exist on 2025-03-30, so the next run is the day after
+	schedule, err := Parse("30 2 * * *")
+	require.NoError(t, err)
+	next := schedule.Next(time.Date(2025, 3, 29, 3, 0, 0, 0, berlin))
+	require.Equal(t, time.Date(2025, 3, 31, 2, 30, 0, 0, berlin), next)
+
+	// Hourly runs keep advancing through the repeated hour on 2025-10-26
+	schedule, err = Parse("0 * * * *")
+	require.NoError(t, err)
+	start := time.Date(2025, 10, 26, 1, 30, 0, 0, berlin)
+	first := schedule.Next(start)
+	second := schedule.Next(first)
+	third := schedule.Next(second)
+	require.Equal(t, time.Hour, second.Sub(first))
+	require.Equal(t, time.Hour, third.Sub(second))
+}
+
+func TestParse_Errors(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"* * * FOO *",
+		"@every 10",
+		"@every 500ms",
+		"@fortnightly",
+	} {
+		_, err := Parse(spec)
+		require.ErrorIs(t, err, ErrSyntax, spec)
+	}
+}
@@ -19,6 +19,11 @@ var (
 	ErrTokenIsExpired         = errors.New("token is expired")
 	ErrUnexpectedSignMethod   = errors.New("unexpected signing method")
 
+	// Authorization Errors
+	ErrForbidden             = errors.New("forbidden")
+	ErrPolicyLoadFailed      = errors.New("failed to load policies")
+	ErrAuthzResourceNotFound = errors.New("authorization resource not found")
+
 	// Access Urls Errors
 	ErrCsrfTokenHeader      = errors.New("csrf token is required")
 	ErrCsrfTokenInvalidPath = errors.New("csrf token is invalid for this url")
@@ -33,8 +38,35 @@ var (
 	ErrMarshal = errors.New("failed to marshal")
 
 	// User Errors
-	ErrUserNotFound     = errors.New("user not found")
-	ErrUserSearchFailed = errors.New("failed to retrieve users")
+	ErrUserNotFound      = errors.New("user not found")
+	ErrUserSearchFailed  = errors.New("failed to retrieve users")
+	ErrUserSuspended     = errors.New("user is suspended")
+	ErrUserPending       = errors.New("user is pending activation")
+	ErrInvalidSuspension = errors.New("suspension end must be in the future")
+	ErrPasswordMismatch  = errors.New("password is incorrect")
+	ErrInvalidEmailToken = errors.New("email verification token is invalid or expired")
+	ErrInvalidSort       = errors.New("sort field is not supported")
+	ErrInvalidCursor     = errors.New("cursor is invalid for this query")
+	ErrInvalidFilter     = errors.New("filter value is not supported")
+
+	// Bulk Errors
+	ErrBulkTooLarge         = errors.New("bulk request exceeds the maximum batch size")
+	ErrInvalidBulkMode      = errors.New("bulk mode must be atomic or best_effort")
+	ErrInvalidBulkOperation = errors.New("bulk operation must be create, update or delete")
+	ErrBulkAborted          = errors.New("not applied because another operation in the batch failed")
+	ErrInvalidFileFormat    = errors.New("format must be csv or ndjson")
+
+	// Webhook Errors
+	ErrWebhookNotFound         = errors.New("webhook not found")
+	ErrWebhookDeliveryNotFound = errors.New("webhook delivery not found")
+	ErrInvalidWebhookURL       = errors.New("webhook url must be http or https")
+	ErrWebhookURLNotAllowed    = errors.New("webhook url must resolve to a public address")
+	ErrInvalidWebhookEvent     = errors.New("webhook event type is not supported")
+	ErrWebhookDisabled         = errors.New("webhook is disabled")
+
+	// Concurrency Errors
+	ErrPreconditionFailed   = errors.New("resource has been modified since it was fetched")
+	ErrPreconditionRequired = errors.New("If-Match header is required")
 
 	// Registration Errors
 	ErrUserAlreadyExists   = errors.New("user already exists")
@@ -68,8 +100,23 @@ var errorStatusMap = map[error]int{
 	ErrBearerHeader:           fiber.StatusUnauthorized,
 	ErrUnauthorized:           fiber.StatusUnauthorized,
 
+	// 403 Forbidden Errors
+	ErrForbidden:        fiber.StatusForbidden,
+	ErrUserSuspended:    fiber.StatusForbidden,
+	ErrUserPending:      fiber.StatusForbidden,
+	ErrPasswordMismatch: fiber.StatusForbidden,
+
 	// 409 Conflict Errors
 	ErrUserAlreadyExists: fiber.StatusConflict,
+	ErrWebhookDisabled:   fiber.StatusConflict,
+
+	// 413/424 Bulk Errors
+	ErrBulkTooLarge: fiber.StatusRequestEntityTooLarge,
+	ErrBulkAborted:  fiber.StatusFailedDependency,
+
+	// 412/428 Precondition Errors
+	ErrPreconditionFailed:   fiber.StatusPreconditionFailed,
+	ErrPreconditionRequired: fiber.StatusPreconditionRequired,
 
 	// 500 Internal Server Errors
 	ErrDatabaseError:          fiber.StatusInternalServerError,
@@ -84,11 +131,26 @@ var errorStatusMap = map[error]int{
 	ErrRedisGet:               fiber.StatusInternalServerError,
 	ErrUnexpectedSignMethod:   fiber.StatusInternalServerError,
 	ErrInternalServerError:    fiber.StatusInternalServerError,
+	ErrPolicyLoadFailed:       fiber.StatusInternalServerError,
 
 	// 404 Not Found Errors
-	ErrUserNotFound:     fiber.StatusNotFound,
-	ErrUserSearchFailed: fiber.StatusNotFound,
-	ErrBadRequest:       fiber.StatusBadRequest,
+	ErrUserNotFound:            fiber.StatusNotFound,
+	ErrUserSearchFailed:        fiber.StatusNotFound,
+	ErrAuthzResourceNotFound:   fiber.StatusNotFound,
+	ErrWebhookNotFound:         fiber.StatusNotFound,
+	ErrWebhookDeliveryNotFound: fiber.StatusNotFound,
+	ErrBadRequest:              fiber.StatusBadRequest,
+	ErrInvalidSuspension:       fiber.StatusBadRequest,
+	ErrInvalidEmailToken:       fiber.StatusBadRequest,
+	ErrInvalidSort:             fiber.StatusBadRequest,
+	ErrInvalidCursor:           fiber.StatusBadRequest,
+	ErrInvalidFilter:           fiber.StatusBadRequest,
+	ErrInvalidBulkMode:         fiber.StatusBadRequest,
+	ErrInvalidBulkOperation:    fiber.StatusBadRequest,
+	ErrInvalidFileFormat:       fiber.StatusBadRequest,
+	ErrInvalidWebhookURL:       fiber.StatusBadRequest,
+	ErrWebhookURLNotAllowed:    fiber.StatusBadRequest,
+	ErrInvalidWebhookEvent:     fiber.StatusBadRequest,
 }
 
 // GetHTTPStatus retrieves the HTTP status code for a given error.
@@ -0,0 +1,849 @@
+// Package expr implements a small, side-effect free expression language used
+// to describe authorization conditions, e.g.
+//
+//	subject.department == resource.department && "manager" in subject.roles
+//
+// The grammar is intentionally tiny: literals (numbers, strings, booleans,
+// null and lists), dotted attribute access, the boolean operators && || !,
+// comparisons (== != < <= > >=), membership (in), arithmetic (+ - * / %)
+// and a handful of built-in functions (see builtins).
+package expr
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrSyntax = errors.New("syntax error")
+	ErrType   = errors.New("type error")
+)
+
+// Program is a compiled expression ready to be evaluated many times.
+type Program struct {
+	source string
+	root   node
+}
+
+// Compile parses src into a Program.
+func Compile(src string) (*Program, error) {
+	p := &parser{lex: newLexer(src)}
+	p.next()
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.err != nil {
+		return nil, p.err
+	}
+	if p.tok.kind != tokEOF {
+		return nil, p.errorf("unexpected %q", p.tok.text)
+	}
+	return &Program{source: src, root: root}, nil
+}
+
+// Source returns the original expression text.
+func (p *Program) Source() string {
+	return p.source
+}
+
+// Eval evaluates the program against vars. Missing attributes evaluate to nil.
+func (p *Program) Eval(vars map[string]any) (any, error) {
+	return p.root.eval(normalize(vars).(map[string]any))
+}
+
+// EvalBool evaluates the program and requires a boolean result.
+func (p *Program) EvalBool(vars map[string]any) (bool, error) {
+	v, err := p.Eval(vars)
+	if err != nil {
+		return false, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("%w: expression must evaluate to bool, got %s", ErrType, typeName(v))
+	}
+	return b, nil
+}
+
+// ---- lexer ----
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokNumber
+	tokString
+	tokOp
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	num  float64
+	pos  int
+}
+
+type lexer struct {
+	src string
+	pos int
+}
+
+func newLexer(src string) *lexer {
+	return &lexer{src: src}
+}
+
+var twoCharOps = []string{"==", "!=", "<=", ">=", "&&", "||"}
+
+func (l *lexer) next() (token, error) {
+	for l.pos < len(l.src) && strings.ContainsRune(" \t\r\n", rune(l.src[l.pos])) {
+		l.pos++
+	}
+	if l.pos >= len(l.src) {
+		return token{kind: tokEOF, pos: l.pos}, nil
+	}
+
+	start := l.pos
+	c := l.src[l.pos]
+	switch {
+	case isIdentStart(c):
+		for l.pos < len(l.src) && isIdentPart(l.src[l.pos]) {
+			l.pos++
+		}
+		return token{kind: tokIdent, text: l.src[start:l.pos], pos: start}, nil
+	case c >= '0' && c <= '9':
+		for l.pos < len(l.src) && (l.src[l.pos] >= '0' && l.src[l.pos] <= '9' || l.src[l.pos] == '.') {
+			l.pos++
+		}
+		f, err := strconv.ParseFloat(l.src[start:l.pos], 64)
+		if err != nil {
+			return token{}, fmt.Errorf("%w at %d: invalid number %q", ErrSyntax, start, l.src[start:l.pos])
+		}
+		return token{kind: tokNumber, text: l.src[start:l.pos], num: f, pos: start}, nil
+	case c == '"' || c == '\'':
+		l.pos++
+		var sb strings.Builder
+		for l.pos < len(l.src) && l.src[l.pos] != c {
+			if l.src[l.pos] == '\\' && l.pos+1 < len(l.src) {
+				l.pos++
+			}
+			sb.WriteByte(l.src[l.pos])
+			l.pos++
+		}
+		if l.pos >= len(l.src) {
+			return token{}, fmt.Errorf("%w at %d: unterminated string", ErrSyntax, start)
+		}
+		l.pos++
+		return token{kind: tokString, text: sb.String(), pos: start}, nil
+	}
+
+	for _, op := range twoCharOps {
+		if strings.HasPrefix(l.src[l.pos:], op) {
+			l.pos += 2
+			return token{kind: tokOp, text: op, pos: start}, nil
+		}
+	}
+	if strings.ContainsRune("<>!+-*/%().,[]", rune(c)) {
+		l.pos++
+		return token{kind: tokOp, text: string(c), pos: start}, nil
+	}
+	return token{}, fmt.Errorf("%w at %d: unexpected character %q", ErrSyntax, start, c)
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isIdentPart(c byte) bool {
+	return isIdentStart(c) || c >= '0' && c <= '9'
+}
+
+// ---- parser ----
+
+type parser struct {
+	lex *lexer
+	tok token
+	err error
+}
+
+func (p *parser) next() {
+	if p.err != nil {
+		return
+	}
+	p.tok, p.err = p.lex.next()
+	if p.err != nil {
+		p.tok = token{kind: tokEOF}
+	}
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	if p.err != nil {
+		return p.err
+	}
+	return fmt.Errorf("%w at %d: %s", ErrSyntax, p.tok.pos, fmt.Sprintf(format, args...))
+}
+
+func (p *parser) isOp(ops ...string) bool {
+	if p.tok.kind != tokOp {
+		return false
+	}
+	for _, op := range ops {
+		if p.tok.text == op {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *parser) expect(op string) error {
+	if !p.isOp(op) {
+		return p.errorf("expected %q", op)
+	}
+	p.next()
+	return nil
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("||") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalNode{op: "||", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseCompare()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("&&") {
+		p.next()
+		right, err := p.parseCompare()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalNode{op: "&&", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseCompare() (node, error) {
+	left, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		var op string
+		switch {
+		case p.isOp("==", "!=", "<", "<=", ">", ">="):
+			op = p.tok.text
+		case p.tok.kind == tokIdent && p.tok.text == "in":
+			op = "in"
+		default:
+			return left, nil
+		}
+		p.next()
+		right, err := p.parseAdditive()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: op, left: left, right: right}
+	}
+}
+
+func (p *parser) parseAdditive() (node, error) {
+	left, err := p.parseMultiplicative()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("+", "-") {
+		op := p.tok.text
+		p.next()
+		right, err := p.parseMultiplicative()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: op, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseMultiplicative() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("*", "/", "%") {
+		op := p.tok.text
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: op, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if p.isOp("!", "-") {
+		op := p.tok.text
+		p.next()
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &unaryNode{op: op, operand: operand}, nil
+	}
+	return p.parsePostfix()
+}
+
+func (p *parser) parsePostfix() (node, error) {
+	n, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch {
+		case p.isOp("."):
+			p.next()
+			if p.tok.kind != tokIdent {
+				return nil, p.errorf("expected attribute name after '.'")
+			}
+			n = &memberNode{target: n, name: p.tok.text}
+			p.next()
+		case p.isOp("["):
+			p.next()
+			index, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			n = &indexNode{target: n, index: index}
+		default:
+			return n, nil
+		}
+	}
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	tok := p.tok
+	switch tok.kind {
+	case tokNumber:
+		p.next()
+		return &literalNode{value: tok.num}, nil
+	case tokString:
+		p.next()
+		return &literalNode{value: tok.text}, nil
+	case tokIdent:
+		p.next()
+		switch tok.text {
+		case "true":
+			return &literalNode{value: true}, nil
+		case "false":
+			return &literalNode{value: false}, nil
+		case "null":
+			return &literalNode{value: nil}, nil
+		}
+		if p.isOp("(") {
+			return p.parseCall(tok)
+		}
+		return &identNode{name: tok.text}, nil
+	case tokOp:
+		switch tok.text {
+		case "(":
+			p.next()
+			n, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			return n, nil
+		case "[":
+			p.next()
+			list := &listNode{}
+			for !p.isOp("]") {
+				item, err := p.parseOr()
+				if err != nil {
+					return nil, err
+				}
+				list.items = append(list.items, item)
+				if !p.isOp(",") {
+					break
+				}
+				p.next()
+			}
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			return list, nil
+		}
+	}
+	if tok.kind == tokEOF {
+		return nil, p.errorf("unexpected end of expression")
+	}
+	return nil, p.errorf("unexpected %q", tok.text)
+}
+
+func (p *parser) parseCall(name token) (node, error) {
+	fn, ok := builtins[name.text]
+	if !ok {
+		return nil, fmt.Errorf("%w at %d: unknown function %q", ErrSyntax, name.pos, name.text)
+	}
+	p.next() // consume "("
+	call := &callNode{name: name.text, fn: fn}
+	for !p.isOp(")") {
+		arg, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		call.args = append(call.args, arg)
+		if !p.isOp(",") {
+			break
+		}
+		p.next()
+	}
+	if err := p.expect(")"); err != nil {
+		return nil, err
+	}
+	return call, nil
+}
+
+// ---- AST & evaluation ----
+
+type node interface {
+	eval(vars map[string]any) (any, error)
+}
+
+type literalNode struct{ value any }
+
+func (n *literalNode) eval(map[string]any) (any, error) { return n.value, nil }
+
+type identNode struct{ name string }
+
+func (n *identNode) eval(vars map[string]any) (any, error) { return vars[n.name], nil }
+
+type memberNode struct {
+	target node
+	name   string
+}
+
+func (n *memberNode) eval(vars map[string]any) (any, error) {
+	target, err := n.target.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	switch t := target.(type) {
+	case nil:
+		return nil, nil
+	case map[string]any:
+		return t[n.name], nil
+	}
+	return nil, fmt.Errorf("%w: cannot access %q on %s", ErrType, n.name, typeName(target))
+}
+
+type indexNode struct {
+	target node
+	index  node
+}
+
+func (n *indexNode) eval(vars map[string]any) (any, error) {
+	target, err := n.target.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	index, err := n.index.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	switch t := target.(type) {
+	case nil:
+		return nil, nil
+	case map[string]any:
+		key, ok := index.(string)
+		if !ok {
+			return nil, fmt.Errorf("%w: map key must be string, got %s", ErrType, typeName(index))
+		}
+		return t[key], nil
+	case []any:
+		f, ok := index.(float64)
+		if !ok || f != math.Trunc(f) {
+			return nil, fmt.Errorf("%w: list index must be integer, got %s", ErrType, typeName(index))
+		}
+		if i := int(f); i >= 0 && i < len(t) {
+			return t[i], nil
+		}
+		return nil, nil
+	}
+	return nil, fmt.Errorf("%w: cannot index %s", ErrType, typeName(target))
+}
+
+type listNode struct{ items []node }
+
+func (n *listNode) eval(vars map[string]any) (any, error) {
+	out := make([]any, len(n.items))
+	for i, item := range n.items {
+		v, err := item.eval(vars)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = v
+	}
+	return out, nil
+}
+
+type unaryNode struct {
+	op      string
+	operand node
+}
+
+func (n *unaryNode) eval(vars map[string]any) (any, error) {
+	v, err := n.operand.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	switch n.op {
+	case "!":
+		b, ok := v.(bool)
+		if !ok {
+			return nil, fmt.Errorf("%w: operator ! requires bool, got %s", ErrType, typeName(v))
+		}
+		return !b, nil
+	default:
+		f, ok := v.(float64)
+		if !ok {
+			return nil, fmt.Errorf("%w: operator - requires number, got %s", ErrType, typeName(v))
+		}
+		return -f, nil
+	}
+}
+
+type logicalNode struct {
+	op          string
+	left, right node
+}
+
+func (n *logicalNode) eval(vars map[string]any) (any, error) {
+	left, err := n.left.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	lb, ok := left.(bool)
+	if !ok {
+		return nil, fmt.Errorf("%w: operator %s requires bool, got %s", ErrType, n.op, typeName(left))
+	}
+	// Short-circuit evaluation
+	if n.op == "&&" && !lb || n.op == "||" && lb {
+		return lb, nil
+	}
+	right, err := n.right.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	rb, ok := right.(bool)
+	if !ok {
+		return nil, fmt.Errorf("%w: operator %s requires bool, got %s", ErrType, n.op, typeName(right))
+	}
+	return rb, nil
+}
+
+type binaryNode struct {
+	op          string
+	left, right node
+}
+
+func (n *binaryNode) eval(vars map[string]any) (any, error) {
+	left, err := n.left.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	right, err := n.right.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "==":
+		return equal(left, right), nil
+	case "!=":
+		return !equal(left, right), nil
+	case "in":
+		return contains(right, left)
+	case "<", "<=", ">", ">=":
+		cmp, err := compare(left, right)
+		if err != nil {
+			return nil, err
+		}
+		switch n.op {
+		case "<":
+			return cmp < 0, nil
+		case "<=":
+			return cmp <= 0, nil
+		case ">":
+			return cmp > 0, nil
+		default:
+			return cmp >= 0, nil
+		}
+	case "+":
+		if ls, ok := left.(string); ok {
+			if rs, ok := right.(string); ok {
+				return ls + rs, nil
+			}
+		}
+	}
+
+	lf, lok := left.(float64)
+	rf, rok := right.(float64)
+	if !lok || !rok {
+		return nil, fmt.Errorf("%w: operator %s not supported for %s and %s", ErrType, n.op, typeName(left), typeName(right))
+	}
+	switch n.op {
+	case "+":
+		return lf + rf, nil
+	case "-":
+		return lf - rf, nil
+	case "*":
+		return lf * rf, nil
+	case "/":
+		if rf == 0 {
+			return nil, fmt.Errorf("%w: division by zero", ErrType)
+		}
+		return lf / rf, nil
+	default:
+		if rf == 0 {
+			return nil, fmt.Errorf("%w: division by zero", ErrType)
+		}
+		return math.Mod(lf, rf), nil
+	}
+}
+
+type callNode struct {
+	name string
+	fn   builtin
+	args []node
+}
+
+func (n *callNode) eval(vars map[string]any) (any, error) {
+	args := make([]any, len(n.args))
+	for i, arg := range n.args {
+		v, err := arg.eval(vars)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = v
+	}
+	if len(args) != n.fn.arity {
+		return nil, fmt.Errorf("%w: %s expects %d argument(s), got %d", ErrType, n.name, n.fn.arity, len(args))
+	}
+	return n.fn.call(args)
+}
+
+// ---- built-in functions ----
+
+type builtin struct {
+	arity int
+	call  func(args []any) (any, error)
+}
+
+var builtins = map[string]builtin{
+	"size": {1, func(args []any) (any, error) {
+		switch v := args[0].(type) {
+		case nil:
+			return float64(0), nil
+		case string:
+			return float64(len(v)), nil
+		case []any:
+			return float64(len(v)), nil
+		case map[string]any:
+			return float64(len(v)), nil
+		}
+		return nil, fmt.Errorf("%w: size not supported for %s", ErrType, typeName(args[0]))
+	}},
+	"contains": {2, func(args []any) (any, error) {
+		if s, ok := args[0].(string); ok {
+			sub, ok := args[1].(string)
+			return ok && strings.Contains(s, sub), nil
+		}
+		return contains(args[0], args[1])
+	}},
+	"startsWith": {2, stringPredicate(strings.HasPrefix)},
+	"endsWith":   {2, stringPredicate(strings.HasSuffix)},
+	"lower": {1, func(args []any) (any, error) {
+		s, ok := args[0].(string)
+		if !ok {
+			return nil, fmt.Errorf("%w: lower requires string, got %s", ErrType, typeName(args[0]))
+		}
+		return strings.ToLower(s), nil
+	}},
+}
+
+func stringPredicate(fn func(s, affix string) bool) func(args []any) (any, error) {
+	return func(args []any) (any, error) {
+		s, sok := args[0].(string)
+		affix, aok := args[1].(string)
+		if !sok || !aok {
+			return false, nil
+		}
+		return fn(s, affix), nil
+	}
+}
+
+// ---- value helpers ----
+
+func equal(a, b any) bool {
+	switch av := a.(type) {
+	case []any:
+		bv, ok := b.([]any)
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for i := range av {
+			if !equal(av[i], bv[i]) {
+				return false
+			}
+		}
+		return true
+	case map[string]any:
+		return reflect.DeepEqual(a, b)
+	}
+	return a == b
+}
+
+func contains(collection, item any) (any, error) {
+	switch c := collection.(type) {
+	case nil:
+		return false, nil
+	case []any:
+		for _, v := range c {
+			if equal(v, item) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case map[string]any:
+		key, ok := item.(string)
+		if !ok {
+			return false, nil
+		}
+		_, exists := c[key]
+		return exists, nil
+	case string:
+		s, ok := item.(string)
+		return ok && strings.Contains(c, s), nil
+	}
+	return nil, fmt.Errorf("%w: operator in not supported for %s", ErrType, typeName(collection))
+}
+
+func compare(a, b any) (int, error) {
+	switch av := a.(type) {
+	case float64:
+		if bv, ok := b.(float64); ok {
+			switch {
+			case av < bv:
+				return -1, nil
+			case av > bv:
+				return 1, nil
+			}
+			return 0, nil
+		}
+	case string:
+		if bv, ok := b.(string); ok {
+			return strings.Compare(av, bv), nil
+		}
+	}
+	return 0, fmt.Errorf("%w: cannot compare %s with %s", ErrType, typeName(a), typeName(b))
+}
+
+// normalize converts arbitrary Go values into the small set of types the
+// evaluator understands: nil, bool, float64, string, []any and map[string]any.
+func normalize(v any) any {
+	switch t := v.(type) {
+	case nil, bool, float64, string:
+		return t
+	case time.Time:
+		return t.Format(time.RFC3339)
+	case map[string]any:
+		out := make(map[string]any, len(t))
+		for k, item := range t {
+			out[k] = normalize(item)
+		}
+		return out
+	case []any:
+		out := make([]any, len(t))
+		for i, item := range t {
+			out[i] = normalize(item)
+		}
+		return out
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint())
+	case reflect.Float32:
+		return rv.Float()
+	case reflect.String:
+		return rv.String()
+	case reflect.Bool:
+		return rv.Bool()
+	case reflect.Slice, reflect.Array:
+		out := make([]any, rv.Len())
+		for i := range out {
+			out[i] = normalize(rv.Index(i).Interface())
+		}
+		return out
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			break
+		}
+		out := make(map[string]any, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			out[iter.Key().String()] = normalize(iter.Value().Interface())
+		}
+		return out
+	case reflect.Pointer:
+		if rv.IsNil() {
+			return nil
+		}
+		return normalize(rv.Elem().Interface())
+	}
+	return fmt.Sprint(v)
+}
+
+func typeName(v any) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "bool"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "list"
+	case map[string]any:
+		return "map"
+	}
+	return fmt.Sprintf("%T", v)
+}
@@ -0,0 +1,95 @@
+package expr
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompile_Errors(t *testing.T) {
+	cases := []struct {
+		name string
+		src  string
+	}{
+		{name: "Empty", src: ""},
+		{name: "DanglingOperator", src: "a &&"},
+		{name: "UnterminatedString", src: `a == "x`},
+		{name: "UnexpectedCharacter", src: "a # b"},
+		{name: "UnknownFunction", src: "nope(a)"},
+		{name: "UnclosedParen", src: "(a == 1"},
+		{name: "TrailingTokens", src: "a b"},
+		{name: "InvalidNumber", src: "1.2.3"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Compile(tc.src)
+			require.Error(t, err)
+			require.True(t, errors.Is(err, ErrSyntax))
+		})
+	}
+}
+
+func TestProgram_EvalBool(t *testing.T) {
+	vars := map[string]any{
+		"subject": map[string]any{
+			"uuid":       "u1",
+			"roles":      []string{"manager", "user"},
+			"department": "sales",
+			"level":      3,
+		},
+		"resource": map[string]any{
+			"uuid":       "u2",
+			"department": "sales",
+			"tags":       map[string]string{"vip": "yes"},
+		},
+		"request": map[string]any{
+			"hour":    10,
+			"weekday": "Monday",
+		},
+	}
+
+	cases := []struct {
+		name   string
+		src    string
+		expect bool
+		errIs  error
+	}{
+		{name: "Equality", src: `subject.department == resource.department`, expect: true},
+		{name: "Inequality", src: `subject.uuid != resource.uuid`, expect: true},
+		{name: "Membership", src: `"manager" in subject.roles`, expect: true},
+		{name: "MembershipMissing", src: `"admin" in subject.roles`, expect: false},
+		{name: "ListLiteral", src: `request.weekday in ["Saturday", "Sunday"]`, expect: false},
+		{name: "NumericRange", src: `request.hour >= 9 && request.hour < 17`, expect: true},
+		{name: "Arithmetic", src: `subject.level * 2 + 1 == 7`, expect: true},
+		{name: "Precedence", src: `false && true || true`, expect: true},
+		{name: "Negation", src: `!(subject.level > 5)`, expect: true},
+		{name: "MissingAttributeIsNull", src: `subject.missing == null`, expect: true},
+		{name: "NestedMissingIsNull", src: `subject.missing.deeper == null`, expect: true},
+		{name: "MapKeyMembership", src: `"vip" in resource.tags`, expect: true},
+		{name: "IndexAccess", src: `subject.roles[0] == "manager" && resource.tags["vip"] == "yes"`, expect: true},
+		{name: "Builtins", src: `size(subject.roles) == 2 && startsWith(lower("ABC"), "ab") && endsWith("abc", "c") && contains("abc", "b")`, expect: true},
+		{name: "ShortCircuitSkipsTypeError", src: `false && subject.department > 1`, expect: false},
+		{name: "CompareTypeError", src: `subject.department > 1`, errIs: ErrType},
+		{name: "NonBoolResult", src: `subject.level`, errIs: ErrType},
+		{name: "LogicalRequiresBool", src: `subject.level && true`, errIs: ErrType},
+		{name: "DivisionByZero", src: `subject.level / 0 == 1`, errIs: ErrType},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			program, err := Compile(tc.src)
+			require.NoError(t, err)
+			require.Equal(t, tc.src, program.Source())
+
+			got, err := program.EvalBool(vars)
+			if tc.errIs != nil {
+				require.ErrorIs(t, err, tc.errIs)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expect, got)
+		})
+	}
+}
@@ -0,0 +1,139 @@
+// Package geoip resolves IP addresses to a coarse location using an offline
+// range database in the CSV layout of the free DB-IP "lite" downloads. Both
+// editions are understood, one range per line:
+//
+//	country: ip_start,ip_end,country
+//	city:    ip_start,ip_end,continent,country,region,city,latitude,longitude
+//
+// IPv4 and IPv6 ranges may be mixed in one file.
+package geoip
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"sort"
+)
+
+// unknownCountry is the code DB-IP uses for ranges without a country.
+const unknownCountry = "ZZ"
+
+// Location is where an address is registered. Country is an ISO 3166-1
+// alpha-2 code; fields the database does not know are empty.
+type Location struct {
+	Country string
+	Region  string
+	City    string
+}
+
+// DB is an in-memory range database. A nil *DB knows no addresses, so
+// callers need no special case when GeoIP is not configured.
+type DB struct {
+	ranges []ipRange
+}
+
+type ipRange struct {
+	start    netip.Addr
+	end      netip.Addr
+	location Location
+}
+
+// Open loads the database at path.
+func Open(path string) (*DB, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Load(f)
+}
+
+// Load reads a database from r.
+func Load(r io.Reader) (*DB, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	db := new(DB)
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("geoip: %w", err)
+		}
+		rng, err := parseRange(record)
+		if err != nil {
+			return nil, fmt.Errorf("geoip: line %d: %w", line, err)
+		}
+		db.ranges = append(db.ranges, rng)
+	}
+
+	sort.Slice(db.ranges, func(i, j int) bool {
+		return db.ranges[i].start.Less(db.ranges[j].start)
+	})
+	return db, nil
+}
+
+func parseRange(record []string) (ipRange, error) {
+	if len(record) != 3 && len(record) < 6 {
+		return ipRange{}, fmt.Errorf("expected 3 fields or at least 6, got %d", len(record))
+	}
+	start, err := netip.ParseAddr(record[0])
+	if err != nil {
+		return ipRange{}, err
+	}
+	end, err := netip.ParseAddr(record[1])
+	if err != nil {
+		return ipRange{}, err
+	}
+	start, end = start.Unmap(), end.Unmap()
+	if start.Is4() != end.Is4() || end.Less(start) {
+		return ipRange{}, fmt.Errorf("invalid range %s-%s", start, end)
+	}
+
+	var location Location
+	if len(record) == 3 {
+		location.Country = record[2]
+	} else {
+		location = Location{Country: record[3], Region: record[4], City: record[5]}
+	}
+	if location.Country == unknownCountry {
+		location.Country = ""
+	}
+	return ipRange{start: start, end: end, location: location}, nil
+}
+
+// Lookup returns the location of ip, or the zero Location when ip is not a
+// valid address or not in the database.
+func (db *DB) Lookup(ip string) Location {
+	if db == nil {
+		return Location{}
+	}
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return Location{}
+	}
+	addr = addr.Unmap()
+
+	// The range starting closest before addr is the only one that can hold
+	// it. IPv4 sorts before IPv6, so a range of the other family never does.
+	i := sort.Search(len(db.ranges), func(i int) bool {
+		return addr.Less(db.ranges[i].start)
+	}) - 1
+	if i < 0 || db.ranges[i].end.Less(addr) {
+		return Location{}
+	}
+	return db.ranges[i].location
+}
+
+// Len returns the number of ranges in the database.
+func (db *DB) Len() int {
+	if db == nil {
+		return 0
+	}
+	return len(db.ranges)
+}
@@ -0,0 +1,87 @@
+package geoip
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const cityCSV = `1.0.0.0,1.0.0.255,OC,AU,Queensland,"South Brisbane",-27.4748,153.017
+8.8.8.0,8.8.8.255,NA,US,California,"Mountain View",37.422,-122.085
+10.0.0.0,10.255.255.255,ZZ,ZZ,,,0,0
+2001:4860::,2001:4860:ffff:ffff:ffff:ffff:ffff:ffff,NA,US,California,"Mountain View",37.422,-122.085
+`
+
+func TestLookup(t *testing.T) {
+	db, err := Load(strings.NewReader(cityCSV))
+	require.NoError(t, err)
+	require.Equal(t, 4, db.Len())
+
+	cases := []struct {
+		name   string
+		ip     string
+		expect Location
+	}{
+		{name: "RangeStart", ip: "1.0.0.0", expect: Location{Country: "AU", Region: "Queensland", City: "South Brisbane"}},
+		{name: "RangeEnd", ip: "8.8.8.255", expect: Location{Country: "US", Region: "California", City: "Mountain View"}},
+		{name: "IPv4MappedIPv6", ip: "::ffff:8.8.8.8", expect: Location{Country: "US", Region: "California", City: "Mountain View"}},
+		{name: "IPv6", ip: "2001:4860:4860::8888", expect: Location{Country: "US", Region: "California", City: "Mountain View"}},
+		{name: "UnknownCountry", ip: "10.1.2.3"},
+		{name: "BetweenRanges", ip: "8.8.9.0"},
+		{name: "BeforeFirstRange", ip: "0.0.0.1"},
+		{name: "IPv6AfterIPv4Ranges", ip: "::1"},
+		{name: "Invalid", ip: "not-an-ip"},
+		{name: "Empty", ip: ""},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expect, db.Lookup(tc.ip))
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	t.Run("CountryEdition", func(t *testing.T) {
+		db, err := Load(strings.NewReader("9.9.9.0,9.9.9.255,CH\n1.1.1.0,1.1.1.255,AU\n"))
+		require.NoError(t, err)
+		require.Equal(t, Location{Country: "AU"}, db.Lookup("1.1.1.1"))
+		require.Equal(t, Location{Country: "CH"}, db.Lookup("9.9.9.9"))
+	})
+
+	t.Run("InvalidRange", func(t *testing.T) {
+		_, err := Load(strings.NewReader("1.1.1.0,1.1.1.255,AU\n2.0.0.9,2.0.0.1,FR\n"))
+		require.ErrorContains(t, err, "line 2")
+	})
+
+	t.Run("MixedFamilies", func(t *testing.T) {
+		_, err := Load(strings.NewReader("1.0.0.0,::1,AU\n"))
+		require.Error(t, err)
+	})
+
+	t.Run("WrongFieldCount", func(t *testing.T) {
+		_, err := Load(strings.NewReader("1.0.0.0,1.0.0.255,OC,AU\n"))
+		require.Error(t, err)
+	})
+}
+
+func TestOpen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dbip-city-lite.csv")
+	require.NoError(t, os.WriteFile(path, []byte(cityCSV), 0o600))
+
+	db, err := Open(path)
+	require.NoError(t, err)
+	require.Equal(t, "AU", db.Lookup("1.0.0.1").Country)
+
+	_, err = Open(filepath.Join(t.TempDir(), "missing.csv"))
+	require.Error(t, err)
+}
+
+func TestNilDB(t *testing.T) {
+	var db *DB
+	require.Equal(t, Location{}, db.Lookup("8.8.8.8"))
+	require.Zero(t, db.Len())
+}
@@ -0,0 +1,63 @@
+// Package highlight marks the parts of a text that match a free-text query,
+// e.g. for search results:
+//
+//	Mark("Jane Doe", Terms("jan")) == "<mark>Jan</mark>e Doe"
+package highlight
+
+import (
+	"html"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	openTag  = "<mark>"
+	closeTag = "</mark>"
+)
+
+// Terms extracts the words to highlight from a web-search style query:
+// quotes are dropped, and negated terms ("-foo") and the OR keyword are
+// skipped because they never contribute a match.
+func Terms(query string) []string {
+	var terms []string
+	for _, word := range strings.Fields(strings.ReplaceAll(query, `"`, " ")) {
+		if strings.HasPrefix(word, "-") || strings.EqualFold(word, "or") {
+			continue
+		}
+		terms = append(terms, word)
+	}
+	return terms
+}
+
+// Mark returns text with every case-insensitive occurrence of a term wrapped
+// in <mark> tags, or "" when nothing matches. The text itself is HTML-escaped
+// so the result is safe to render.
+func Mark(text string, terms []string) string {
+	if text == "" || len(terms) == 0 {
+		return ""
+	}
+
+	// Longest first so "jane" wins over "jan" at the same position
+	sorted := append([]string{}, terms...)
+	sort.Slice(sorted, func(i, j int) bool { return len(sorted[i]) > len(sorted[j]) })
+	quoted := make([]string, len(sorted))
+	for i, term := range sorted {
+		quoted[i] = regexp.QuoteMeta(term)
+	}
+	pattern := regexp.MustCompile("(?i)" + strings.Join(quoted, "|"))
+
+	matches := pattern.FindAllStringIndex(text, -1)
+	if len(matches) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		b.WriteString(html.EscapeString(text[last:m[0]]))
+		b.WriteString(openTag + html.EscapeString(text[m[0]:m[1]]) + closeTag)
+		last = m[1]
+	}
+	b.WriteString(html.EscapeString(text[last:]))
+	return b.String()
+}
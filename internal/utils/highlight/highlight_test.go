@@ -0,0 +1,35 @@
+package highlight
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTerms(t *testing.T) {
+	require.Equal(t, []string{"jane", "doe", "smith"}, Terms(`"jane doe" or smith -bob`))
+	require.Nil(t, Terms("  "))
+}
+
+func TestMark(t *testing.T) {
+	cases := []struct {
+		name  string
+		text  string
+		terms []string
+		want  string
+	}{
+		{name: "CaseInsensitive", text: "Jane Doe", terms: []string{"jane"}, want: "<mark>Jane</mark> Doe"},
+		{name: "EveryOccurrence", text: "ana banana", terms: []string{"ana"}, want: "<mark>ana</mark> b<mark>ana</mark>na"},
+		{name: "LongestTermFirst", text: "Janet", terms: []string{"jan", "janet"}, want: "<mark>Janet</mark>"},
+		{name: "EscapesHTML", text: "<b>Jo</b>", terms: []string{"jo"}, want: "&lt;b&gt;<mark>Jo</mark>&lt;/b&gt;"},
+		{name: "RegexpMetaCharacters", text: "a.b@example.com", terms: []string{"a.b"}, want: "<mark>a.b</mark>@example.com"},
+		{name: "NoMatch", text: "Jane", terms: []string{"bob"}, want: ""},
+		{name: "NoTerms", text: "Jane", want: ""},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.want, Mark(tc.text, tc.terms))
+		})
+	}
+}
@@ -0,0 +1,97 @@
+// Package netguard keeps outgoing requests to user-supplied URLs, such as
+// webhook deliveries, away from the server's own network: loopback, private
+// (RFC 1918 and IPv6 unique local), link-local, including the cloud metadata
+// address 169.254.169.254, multicast and unspecified addresses, as well as
+// "this network" 0.0.0.0/8, carrier-grade NAT 100.64.0.0/10, the benchmarking
+// range 198.18.0.0/15 and the IPv6 prefixes that embed an IPv4 address
+// (IPv4-mapped and -compatible, NAT64), which could reach any of the above.
+//
+// CheckHost rejects a URL up front. Control enforces the same rule on the
+// address actually dialed, after DNS resolution, so a host that resolves to a
+// public address when checked and to a private one when used is still
+// refused.
+package netguard
+
+import (
+	"context"
+	"errors"
+	"net"
+	"syscall"
+)
+
+var ErrForbiddenAddress = errors.New("address is not publicly routable")
+
+// reserved lists the ranges the net.IP predicates do not cover.
+var reserved = mustParseCIDRs(
+	"0.0.0.0/8",      // "this network", reaches the local host on Linux
+	"100.64.0.0/10",  // carrier-grade NAT
+	"198.18.0.0/15",  // benchmarking
+	"::/96",          // IPv4-compatible
+	"64:ff9b::/96",   // NAT64
+	"64:ff9b:1::/48", // local-use NAT64
+)
+
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	nets := make([]*net.IPNet, len(cidrs))
+	for i, cidr := range cidrs {
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		nets[i] = n
+	}
+	return nets
+}
+
+// IsPublic reports whether ip may be dialed. IPv4-mapped addresses are
+// judged by the IPv4 address they carry.
+func IsPublic(ip net.IP) bool {
+	if v4 := ip.To4(); v4 != nil {
+		ip = v4
+	}
+	if len(ip) == 0 || ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() {
+		return false
+	}
+	for _, n := range reserved {
+		if n.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// CheckHost resolves host, a name or an IP literal, and returns
+// ErrForbiddenAddress if any of its addresses is not public.
+func CheckHost(ctx context.Context, resolver *net.Resolver, host string) error {
+	if ip := net.ParseIP(host); ip != nil {
+		if !IsPublic(ip) {
+			return ErrForbiddenAddress
+		}
+		return nil
+	}
+	addrs, err := resolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return err
+	}
+	for _, addr := range addrs {
+		if !IsPublic(addr.IP) {
+			return ErrForbiddenAddress
+		}
+	}
+	return nil
+}
+
+// Control is a net.Dialer Control function refusing connections to addresses
+// that are not public.
+func Control(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !IsPublic(ip) {
+		return ErrForbiddenAddress
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package netguard
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsPublic(t *testing.T) {
+	cases := map[string]bool{
+		"93.184.216.34":        true,
+		"2606:4700::1111":      true,
+		"127.0.0.1":            false,
+		"::1":                  false,
+		"10.1.2.3":             false,
+		"172.16.0.1":           false,
+		"192.168.1.1":          false,
+		"169.254.169.254":      false,
+		"fe80::1":              false,
+		"fd00::1":              false,
+		"0.0.0.0":              false,
+		"::":                   false,
+		"::ffff:10.0.0.1":      false,
+		"224.0.0.1":            false,
+		"0.1.2.3":              false,
+		"100.64.0.1":           false,
+		"100.127.255.254":      false,
+		"100.128.0.1":          true,
+		"198.18.0.1":           false,
+		"198.19.255.254":       false,
+		"198.20.0.1":           true,
+		"64:ff9b::a00:1":       false,
+		"64:ff9b::7f00:1":      false,
+		"64:ff9b:1::1":         false,
+		"::ffff:127.0.0.1":     false,
+		"::ffff:100.64.0.1":    false,
+		"::ffff:198.18.0.1":    false,
+		"::ffff:0.0.0.1":       false,
+		"::ffff:93.184.216.34": true,
+		"::127.0.0.1":          false,
+		"::a9fe:a9fe":          false,
+	}
+	for addr, public := range cases {
+		require.Equal(t, public, IsPublic(net.ParseIP(addr)), addr)
+	}
+	require.False(t, IsPublic(nil))
+}
+
+func TestCheckHost(t *testing.T) {
+	require.NoError(t, CheckHost(context.Background(), net.DefaultResolver, "93.184.216.34"))
+	require.ErrorIs(t, CheckHost(context.Background(), net.DefaultResolver, "169.254.169.254"), ErrForbiddenAddress)
+	require.ErrorIs(t, CheckHost(context.Background(), net.DefaultResolver, "::1"), ErrForbiddenAddress)
+	require.ErrorIs(t, CheckHost(context.Background(), net.DefaultResolver, "localhost"), ErrForbiddenAddress)
+}
+
+func TestControl(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	transport := &http.Transport{DialContext: (&net.Dialer{Control: Control}).DialContext}
+	client := &http.Client{Transport: transport}
+	_, err := client.Get(server.URL)
+	require.ErrorIs(t, err, ErrForbiddenAddress)
+
+	require.NoError(t, Control("tcp4", "93.184.216.34:443", nil))
+	require.ErrorIs(t, Control("tcp6", "[::1]:443", nil), ErrForbiddenAddress)
+}
@@ -0,0 +1,50 @@
+package signature
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSign(t *testing.T) {
+	// echo -n '1704103200.{"id":"e1"}' | openssl dgst -sha256 -hmac whsec_test
+	require.Equal(t, "sha256=cf961d57d2d0845640c74427776185ad1ac02c0d71066e094dcd48f75c864037", Sign("whsec_test", 1704103200, []byte(`{"id":"e1"}`)))
+}
+
+func TestVerify(t *testing.T) {
+	now := time.Unix(1704103200, 0)
+	body := []byte(`{"id":"e1"}`)
+	signed := Sign("whsec_test", now.Unix(), body)
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+
+	cases := []struct {
+		name      string
+		secret    string
+		timestamp string
+		signature string
+		body      []byte
+		now       time.Time
+		expectErr error
+	}{
+		{name: "Valid", secret: "whsec_test", timestamp: timestamp, signature: signed, body: body, now: now.Add(time.Minute)},
+		{name: "WrongSecret", secret: "other", timestamp: timestamp, signature: signed, body: body, now: now, expectErr: ErrInvalidSignature},
+		{name: "TamperedBody", secret: "whsec_test", timestamp: timestamp, signature: signed, body: []byte(`{"id":"e2"}`), now: now, expectErr: ErrInvalidSignature},
+		{name: "MissingPrefix", secret: "whsec_test", timestamp: timestamp, signature: signed[len(prefix):], body: body, now: now, expectErr: ErrInvalidSignature},
+		{name: "BadTimestamp", secret: "whsec_test", timestamp: "soon", signature: signed, body: body, now: now, expectErr: ErrInvalidSignature},
+		{name: "Expired", secret: "whsec_test", timestamp: timestamp, signature: signed, body: body, now: now.Add(10 * time.Minute), expectErr: ErrExpiredTimestamp},
+		{name: "FromTheFuture", secret: "whsec_test", timestamp: timestamp, signature: signed, body: body, now: now.Add(-10 * time.Minute), expectErr: ErrExpiredTimestamp},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := Verify(tc.secret, tc.timestamp, tc.signature, tc.body, 5*time.Minute, tc.now)
+			if tc.expectErr != nil {
+				require.ErrorIs(t, err, tc.expectErr)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+// Package useragent reduces a User-Agent header to the kind of device that
+// sent it, such as "Chrome on macOS". Versions are dropped on purpose: a
+// browser update is not a new device.
+package useragent
+
+import "strings"
+
+type marker struct {
+	tokens []string
+	name   string
+}
+
+// Order matters: iOS and Android user agents also claim to be macOS and
+// Linux, and most browsers also claim to be Chrome or Safari.
+var (
+	systems = []marker{
+		{[]string{"iPhone", "iPad", "iPod"}, "iOS"},
+		{[]string{"Android"}, "Android"},
+		{[]string{"CrOS"}, "ChromeOS"},
+		{[]string{"Windows"}, "Windows"},
+		{[]string{"Macintosh", "Mac OS X"}, "macOS"},
+		{[]string{"Linux"}, "Linux"},
+	}
+	browsers = []marker{
+		{[]string{"Edg/", "EdgA/", "EdgiOS/", "Edge/"}, "Edge"},
+		{[]string{"OPR/", "Opera"}, "Opera"},
+		{[]string{"SamsungBrowser/"}, "Samsung Internet"},
+		{[]string{"Firefox/", "FxiOS/"}, "Firefox"},
+		{[]string{"Chrome/", "CriOS/", "Chromium/"}, "Chrome"},
+		{[]string{"Safari/"}, "Safari"},
+	}
+)
+
+// Device describes the browser and operating system of ua. Clients that are
+// not browsers, such as curl, are named by their first product token. It
+// returns "" for an empty ua.
+func Device(ua string) string {
+	ua = strings.TrimSpace(ua)
+	if ua == "" {
+		return ""
+	}
+
+	system := match(systems, ua)
+	browser := match(browsers, ua)
+	if browser == "" && !strings.HasPrefix(ua, "Mozilla/") {
+		browser, _, _ = strings.Cut(ua, "/")
+		browser, _, _ = strings.Cut(browser, " ")
+	}
+
+	switch {
+	case browser != "" && system != "":
+		return browser + " on " + system
+	case browser != "":
+		return browser
+	case system != "":
+		return system
+	}
+	return "Unknown"
+}
+
+func match(markers []marker, ua string) string {
+	for _, m := range markers {
+		for _, token := range m.tokens {
+			if strings.Contains(ua, token) {
+				return m.name
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,33 @@
+package useragent
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDevice(t *testing.T) {
+	cases := []struct {
+		name   string
+		ua     string
+		expect string
+	}{
+		{name: "ChromeMac", ua: "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0.0.0 Safari/537.36", expect: "Chrome on macOS"},
+		{name: "EdgeWindows", ua: "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0.0.0 Safari/537.36 Edg/126.0.2592.87", expect: "Edge on Windows"},
+		{name: "FirefoxLinux", ua: "Mozilla/5.0 (X11; Linux x86_64; rv:127.0) Gecko/20100101 Firefox/127.0", expect: "Firefox on Linux"},
+		{name: "SafariIPhone", ua: "Mozilla/5.0 (iPhone; CPU iPhone OS 17_5 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.5 Mobile/15E148 Safari/604.1", expect: "Safari on iOS"},
+		{name: "ChromeAndroid", ua: "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0.0.0 Mobile Safari/537.36", expect: "Chrome on Android"},
+		{name: "SamsungAndroid", ua: "Mozilla/5.0 (Linux; Android 14; SM-S918B) AppleWebKit/537.36 (KHTML, like Gecko) SamsungBrowser/25.0 Chrome/121.0.0.0 Mobile Safari/537.36", expect: "Samsung Internet on Android"},
+		{name: "VersionIgnored", ua: "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/127.0.0.0 Safari/537.36", expect: "Chrome on macOS"},
+		{name: "Curl", ua: "curl/8.4.0", expect: "curl"},
+		{name: "ProductWithComment", ua: "okhttp/4.12.0 (Android)", expect: "okhttp on Android"},
+		{name: "BareMozilla", ua: "Mozilla/5.0", expect: "Unknown"},
+		{name: "Empty", ua: "  ", expect: ""},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expect, Device(tc.ua))
+		})
+	}
+}
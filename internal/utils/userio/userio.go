@@ -0,0 +1,248 @@
+// Package userio reads and writes users as CSV or NDJSON for the import and
+// export endpoints and the import CLI. Both directions work row by row, so
+// neither side has to hold a whole file in memory.
+package userio
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"go-starter-template/internal/config/validation"
+	"go-starter-template/internal/constant"
+	"go-starter-template/internal/dto"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-json"
+)
+
+const (
+	FormatCSV    = "csv"
+	FormatNDJSON = "ndjson"
+)
+
+var (
+	ErrUnsupportedFormat = errors.New("format must be csv or ndjson")
+	ErrMalformed         = errors.New("import file is malformed")
+	ErrTooManyRows       = errors.New("import file has too many rows")
+)
+
+// maxLineSize bounds a single NDJSON line.
+const maxLineSize = 1 << 20
+
+// importColumns are the CSV columns read on import; headers are matched
+// case-insensitively and may come in any order. Other columns are ignored.
+var importColumns = []string{"name", "email", "password"}
+
+// ContentType returns the MIME type of format.
+func ContentType(format string) string {
+	if format == FormatNDJSON {
+		return "application/x-ndjson"
+	}
+	return "text/csv"
+}
+
+// ReadUsers decodes import rows into create operations and validates each
+// with v. Problems with a single row are left on its operation (Err or
+// Errors) so they can be reported per row; only an unreadable file or more
+// than max rows (0 for no limit) fails the whole import.
+func ReadUsers(r io.Reader, format string, max int, v *validation.Validation) ([]*dto.BulkUserOperation, error) {
+	var (
+		ops []*dto.BulkUserOperation
+		err error
+	)
+	switch format {
+	case FormatCSV:
+		ops, err = readCSV(r, max)
+	case FormatNDJSON:
+		ops, err = readNDJSON(r, max)
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, op := range ops {
+		if op.Err != nil || op.Errors != nil {
+			continue
+		}
+		if err := v.Validate(op.Create); err != nil {
+			var validationErr *validation.ValidationError
+			if errors.As(err, &validationErr) {
+				op.Errors = validationErr.Errors
+			} else {
+				op.Err = err
+			}
+		}
+	}
+	return ops, nil
+}
+
+func readCSV(r io.Reader, max int) ([]*dto.BulkUserOperation, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1 // short rows are reported by validation
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: reading header: %v", ErrMalformed, err)
+	}
+	index := map[string]int{}
+	for i, column := range header {
+		index[strings.ToLower(strings.TrimSpace(column))] = i
+	}
+	for _, column := range importColumns {
+		if _, ok := index[column]; !ok {
+			return nil, fmt.Errorf("%w: missing %q column", ErrMalformed, column)
+		}
+	}
+
+	var ops []*dto.BulkUserOperation
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrMalformed, err)
+		}
+		if max > 0 && len(ops) == max {
+			return nil, ErrTooManyRows
+		}
+		line, _ := reader.FieldPos(0)
+		field := func(column string) string {
+			if i := index[column]; i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		ops = append(ops, &dto.BulkUserOperation{
+			Op:     constant.BulkOperationCreate,
+			Line:   line,
+			Create: &dto.CreateUserRequest{Name: field("name"), Email: field("email"), Password: field("password")},
+		})
+	}
+	return ops, nil
+}
+
+func readNDJSON(r io.Reader, max int) ([]*dto.BulkUserOperation, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+
+	var ops []*dto.BulkUserOperation
+	for line := 1; scanner.Scan(); line++ {
+		raw := strings.TrimSpace(scanner.Text())
+		if raw == "" {
+			continue
+		}
+		if max > 0 && len(ops) == max {
+			return nil, ErrTooManyRows
+		}
+		op := &dto.BulkUserOperation{Op: constant.BulkOperationCreate, Line: line, Create: new(dto.CreateUserRequest)}
+		if err := json.Unmarshal([]byte(raw), op.Create); err != nil {
+			op.Errors = map[string][]string{"row": {"row must be a JSON object with string fields"}}
+		}
+		ops = append(ops, op)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrMalformed, err)
+	}
+	return ops, nil
+}
+
+// Writer encodes exported users one at a time. Flush must be called once all
+// users have been written.
+type Writer interface {
+	Write(user *dto.UserResponse) error
+	Flush() error
+}
+
+// NewWriter returns a Writer encoding users to w in format.
+func NewWriter(w io.Writer, format string) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return &csvWriter{writer: csv.NewWriter(w)}, nil
+	case FormatNDJSON:
+		return &ndjsonWriter{encoder: json.NewEncoder(w)}, nil
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+}
+
+// exportColumns is the CSV header written on export.
+var exportColumns = []string{"uuid", "name", "email", "phone", "status", "created_at", "updated_at", "deleted_at"}
+
+type csvWriter struct {
+	writer        *csv.Writer
+	headerWritten bool
+}
+
+func (w *csvWriter) Write(user *dto.UserResponse) error {
+	if !w.headerWritten {
+		if err := w.writer.Write(exportColumns); err != nil {
+			return err
+		}
+		w.headerWritten = true
+	}
+	return w.writer.Write([]string{
+		user.UUID, escapeFormula(user.Name), escapeFormula(user.Email), escapeFormula(user.Phone), user.Status,
+		formatTime(user.CreatedAt), formatTime(user.UpdatedAt), formatTime(user.DeletedAt),
+	})
+}
+
+// escapeFormula prefixes user-controlled cells that a spreadsheet would run
+// as a formula with a quote, so opening an export cannot execute them.
+func escapeFormula(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+func (w *csvWriter) Flush() error {
+	if !w.headerWritten {
+		// An empty export still gets its header
+		if err := w.writer.Write(exportColumns); err != nil {
+			return err
+		}
+		w.headerWritten = true
+	}
+	w.writer.Flush()
+	return w.writer.Error()
+}
+
+// formatTime renders a unix timestamp as RFC 3339, which spreadsheets parse,
+// or "" when unset.
+func formatTime(unix int64) string {
+	if unix == 0 {
+		return ""
+	}
+	return time.Unix(unix, 0).UTC().Format(time.RFC3339)
+}
+
+type ndjsonWriter struct {
+	encoder *json.Encoder
+}
+
+func (w *ndjsonWriter) Write(user *dto.UserResponse) error {
+	return w.encoder.Encode(user)
+}
+
+func (w *ndjsonWriter) Flush() error {
+	return nil
+}
+
+// FormatFor picks the format named in the query, falling back to NDJSON for
+// an NDJSON content type and CSV otherwise.
+func FormatFor(query, contentType string) string {
+	if query != "" {
+		return strings.ToLower(query)
+	}
+	if strings.HasPrefix(contentType, "application/x-ndjson") {
+		return FormatNDJSON
+	}
+	return FormatCSV
+}
@@ -0,0 +1,105 @@
+package userio
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"go-starter-template/internal/config/validation"
+	"go-starter-template/internal/dto"
+)
+
+func TestReadUsers_CSV(t *testing.T) {
+	body := "Email,Name,Password,Department\n" +
+		"alice@example.com,Alice,secret123,HR\n" +
+		"not-an-email,Bob\n" +
+		"\"carol@example.com\",\"Carol, Jr.\",secret123,IT\n"
+
+	ops, err := ReadUsers(strings.NewReader(body), FormatCSV, 0, validation.NewValidation())
+	require.NoError(t, err)
+	require.Len(t, ops, 3)
+
+	require.Equal(t, 2, ops[0].Line)
+	require.Equal(t, "Alice", ops[0].Create.Name)
+	require.Nil(t, ops[0].Errors)
+
+	// Short rows are read and rejected by validation, not the parser
+	require.Equal(t, 3, ops[1].Line)
+	require.Contains(t, ops[1].Errors, "email")
+	require.Contains(t, ops[1].Errors, "password")
+
+	require.Equal(t, "Carol, Jr.", ops[2].Create.Name)
+}
+
+func TestReadUsers_NDJSON(t *testing.T) {
+	body := `{"name":"Alice","email":"alice@example.com","password":"secret123"}
+
+not json
+{"name":"Bob","email":"bob@example.com","password":"secret123"}
+`
+	ops, err := ReadUsers(strings.NewReader(body), FormatNDJSON, 0, validation.NewValidation())
+	require.NoError(t, err)
+	require.Len(t, ops, 3)
+	require.Nil(t, ops[0].Errors)
+	require.Equal(t, 3, ops[1].Line)
+	require.Contains(t, ops[1].Errors, "row")
+	require.Equal(t, 4, ops[2].Line)
+}
+
+func TestReadUsers_Errors(t *testing.T) {
+	v := validation.NewValidation()
+
+	_, err := ReadUsers(strings.NewReader("name,email\nAlice,a@example.com\n"), FormatCSV, 0, v)
+	require.ErrorIs(t, err, ErrMalformed)
+
+	_, err = ReadUsers(strings.NewReader("name,email,password\n\"unterminated\n"), FormatCSV, 0, v)
+	require.ErrorIs(t, err, ErrMalformed)
+
+	_, err = ReadUsers(strings.NewReader("name,email,password\na,b,c\nd,e,f\n"), FormatCSV, 1, v)
+	require.ErrorIs(t, err, ErrTooManyRows)
+
+	_, err = ReadUsers(strings.NewReader(""), "xlsx", 0, v)
+	require.ErrorIs(t, err, ErrUnsupportedFormat)
+}
+
+func TestWriter(t *testing.T) {
+	users := []*dto.UserResponse{
+		{UUID: "u1", Name: "Alice", Email: "alice@example.com", Status: "active", CreatedAt: 1700000000},
+		{UUID: "u2", Name: "=HYPERLINK(\"x\")", Email: "bob@example.com", Phone: "+15551234567", Status: "suspended"},
+	}
+
+	var csvOut bytes.Buffer
+	w, err := NewWriter(&csvOut, FormatCSV)
+	require.NoError(t, err)
+	for _, user := range users {
+		require.NoError(t, w.Write(user))
+	}
+	require.NoError(t, w.Flush())
+	require.Equal(t, "uuid,name,email,phone,status,created_at,updated_at,deleted_at\n"+
+		"u1,Alice,alice@example.com,,active,2023-11-14T22:13:20Z,,\n"+
+		"u2,\"'=HYPERLINK(\"\"x\"\")\",bob@example.com,'+15551234567,suspended,,,\n", csvOut.String())
+
+	var empty bytes.Buffer
+	w, _ = NewWriter(&empty, FormatCSV)
+	require.NoError(t, w.Flush())
+	require.Equal(t, "uuid,name,email,phone,status,created_at,updated_at,deleted_at\n", empty.String())
+
+	var ndjsonOut bytes.Buffer
+	w, err = NewWriter(&ndjsonOut, FormatNDJSON)
+	require.NoError(t, err)
+	require.NoError(t, w.Write(users[0]))
+	require.NoError(t, w.Flush())
+	require.Equal(t, 1, strings.Count(ndjsonOut.String(), "\n"))
+	require.Contains(t, ndjsonOut.String(), `"uuid":"u1"`)
+
+	_, err = NewWriter(&ndjsonOut, "xml")
+	require.ErrorIs(t, err, ErrUnsupportedFormat)
+}
+
+func TestFormatFor(t *testing.T) {
+	require.Equal(t, FormatNDJSON, FormatFor("NDJSON", "text/csv"))
+	require.Equal(t, FormatNDJSON, FormatFor("", "application/x-ndjson; charset=utf-8"))
+	require.Equal(t, FormatCSV, FormatFor("", "text/plain"))
+}